@@ -0,0 +1,280 @@
+// Command admin performs administrative tasks against the platform database,
+// such as migrating the schema and minting the first API key of a fresh
+// install.
+//
+// Usage:
+//
+//	admin migrate up
+//	admin migrate down [-steps N]
+//	admin migrate status
+//	admin apikey create -name NAME [-org ORG_ID] [-permissions p1,p2] [-expires-in 720h]
+//	                    [-rate-limit N] [-daily-quota N] [-monthly-quota N]
+//	admin apikey list [-org ORG_ID]
+//	admin apikey revoke -id KEY_ID [-org ORG_ID] [-reason TEXT]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/config"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/database"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+const usage = `Usage: admin <command> <subcommand> [flags]
+
+Commands:
+  migrate up      Apply pending database migrations
+  migrate down    Roll back database migrations
+  migrate status  Show applied and pending migrations
+  apikey create   Create an API key and print it once
+  apikey list     List API keys of an organization
+  apikey revoke   Revoke an API key
+`
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf("Failed to load configuration: %v", err)
+	}
+
+	db, err := database.NewConnection(cfg.GetDatabaseDriver(), cfg.GetDatabaseDSN(), gormlogger.Silent)
+	if err != nil {
+		fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.CloseConnection(db)
+
+	command, args := os.Args[1]+" "+os.Args[2], os.Args[3:]
+	if os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2], args); err != nil {
+			fatalf("%v", err)
+		}
+		return
+	}
+
+	if _, err := database.PrepareSchema(db, cfg.Database.AutoMigrate); err != nil {
+		fatalf("Database schema is not usable: %v", err)
+	}
+
+	authService, err := newAuthService(cfg, db)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	switch command {
+	case "apikey create":
+		err = createAPIKey(authService, args)
+	case "apikey list":
+		err = listAPIKeys(authService, args)
+	case "apikey revoke":
+		err = revokeAPIKey(authService, args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fatalf("%v", err)
+	}
+}
+
+// runMigrate runs a migrate subcommand
+func runMigrate(db *gorm.DB, subcommand string, args []string) error {
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	switch subcommand {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+		_, err = database.EnsureSearchIndex(db)
+		return err
+
+	case "down":
+		fs := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := fs.Int("steps", 1, "number of migrations to roll back")
+		fs.Parse(args)
+
+		rolledBack, err := migrator.Down(*steps)
+		for _, m := range rolledBack {
+			fmt.Printf("Rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(rolledBack) == 0 {
+			fmt.Println("No migrations to roll back")
+		}
+		return err
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			status, appliedAt := "pending", ""
+			if s.Applied {
+				status = "applied"
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			if s.Version > migrator.Latest() {
+				status = "unknown"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+		}
+		return w.Flush()
+	}
+
+	fmt.Fprint(os.Stderr, usage)
+	os.Exit(2)
+	return nil
+}
+
+// newAuthService wires the auth service and makes sure the default
+// organization exists so a fresh install can mint its first key
+func newAuthService(cfg *config.Config, db *gorm.DB) (*services.AuthService, error) {
+	orgRepo := repositories.NewOrganizationRepository(db)
+	authService := services.NewAuthService(
+		repositories.NewAPIKeyRepository(db),
+		orgRepo,
+		cfg.Security.APIKeySalt,
+		cfg.Security.APIKeyCacheTTL,
+	)
+
+	orgService := services.NewOrganizationService(
+		orgRepo,
+		whatsapp.NewClientPool(whatsapp.Config{Logger: zap.NewNop()}),
+		authService,
+	)
+	if _, err := orgService.EnsureDefault(context.Background(), services.DefaultOrganizationFromConfig(cfg.WhatsApp)); err != nil {
+		authService.Close()
+		return nil, fmt.Errorf("failed to initialize default organization: %w", err)
+	}
+
+	return authService, nil
+}
+
+func createAPIKey(authService *services.AuthService, args []string) error {
+	fs := flag.NewFlagSet("apikey create", flag.ExitOnError)
+	name := fs.String("name", "", "name of the key (required)")
+	orgID := fs.String("org", models.DefaultOrganizationID, "organization the key belongs to")
+	permissions := fs.String("permissions", models.PermissionAll, "comma-separated permissions")
+	expiresIn := fs.Duration("expires-in", 0, "key lifetime, e.g. 720h (default: never expires)")
+	var limits models.APIKeyLimits
+	fs.IntVar(&limits.RateLimitPerMinute, "rate-limit", 0, "requests per minute (default: server default)")
+	fs.IntVar(&limits.DailyQuota, "daily-quota", 0, "messages per day (default: unlimited)")
+	fs.IntVar(&limits.MonthlyQuota, "monthly-quota", 0, "messages per month (default: unlimited)")
+	fs.Parse(args)
+
+	if *name == "" {
+		fs.Usage()
+		return fmt.Errorf("-name is required")
+	}
+
+	var expiresAt *time.Time
+	if *expiresIn > 0 {
+		t := time.Now().UTC().Add(*expiresIn)
+		expiresAt = &t
+	}
+
+	apiKey, rawKey, err := authService.CreateAPIKey(context.Background(), *orgID, *name, splitList(*permissions), limits, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("API key created\n\n")
+	fmt.Printf("  ID:           %s\n", apiKey.ID)
+	fmt.Printf("  Organization: %s\n", apiKey.OrganizationID)
+	fmt.Printf("  Permissions:  %s\n", strings.Join(apiKey.Permissions, ","))
+	fmt.Printf("  Key:          %s\n\n", rawKey)
+	fmt.Println("Store the key now; it cannot be shown again.")
+	return nil
+}
+
+func listAPIKeys(authService *services.AuthService, args []string) error {
+	fs := flag.NewFlagSet("apikey list", flag.ExitOnError)
+	orgID := fs.String("org", models.DefaultOrganizationID, "organization to list keys for")
+	fs.Parse(args)
+
+	apiKeys, err := authService.ListAPIKeys(context.Background(), *orgID)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tPREFIX\tPERMISSIONS\tSTATUS")
+	for _, k := range apiKeys {
+		status := "active"
+		switch {
+		case k.IsRevoked():
+			status = "revoked"
+		case k.IsExpired():
+			status = "expired"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.KeyPrefix, strings.Join(k.Permissions, ","), status)
+	}
+	return w.Flush()
+}
+
+func revokeAPIKey(authService *services.AuthService, args []string) error {
+	fs := flag.NewFlagSet("apikey revoke", flag.ExitOnError)
+	keyID := fs.String("id", "", "ID of the key to revoke (required)")
+	orgID := fs.String("org", models.DefaultOrganizationID, "organization the key belongs to")
+	reason := fs.String("reason", "revoked from command line", "reason recorded with the revocation")
+	fs.Parse(args)
+
+	if *keyID == "" {
+		fs.Usage()
+		return fmt.Errorf("-id is required")
+	}
+
+	if err := authService.RevokeAPIKey(context.Background(), *orgID, *keyID, *reason); err != nil {
+		return err
+	}
+
+	fmt.Printf("API key %s revoked\n", *keyID)
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
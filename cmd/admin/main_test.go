@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/config"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/database"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm/logger"
+)
+
+func TestAPIKeyCommands(t *testing.T) {
+	db, err := database.NewConnection("sqlite", filepath.Join(t.TempDir(), "admin.db"), logger.Silent)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.CloseConnection(db)
+
+	if err := runMigrate(db, "up", nil); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	if err := runMigrate(db, "status", nil); err != nil {
+		t.Fatalf("migrate status: %v", err)
+	}
+
+	cfg := &config.Config{Security: config.SecurityConfig{APIKeySalt: "salt", APIKeyCacheTTL: time.Minute}}
+	authService, err := newAuthService(cfg, db)
+	if err != nil {
+		t.Fatalf("newAuthService: %v", err)
+	}
+	defer authService.Close()
+
+	if err := createAPIKey(authService, nil); err == nil {
+		t.Error("create without -name should fail")
+	}
+	if err := createAPIKey(authService, []string{"-name", "ci", "-permissions", "messages:send, messages:read", "-daily-quota", "10"}); err != nil {
+		t.Fatalf("apikey create: %v", err)
+	}
+
+	ctx := context.Background()
+	keys, _ := authService.ListAPIKeys(ctx, models.DefaultOrganizationID)
+	if len(keys) != 1 || !reflect.DeepEqual([]string(keys[0].Permissions), []string{models.PermissionMessagesSend, models.PermissionMessagesRead}) || keys[0].DailyQuota != 10 {
+		t.Fatalf("the key should belong to the default organization with the given permissions: %+v", keys)
+	}
+	if err := listAPIKeys(authService, nil); err != nil {
+		t.Errorf("apikey list: %v", err)
+	}
+
+	if err := revokeAPIKey(authService, []string{"-id", keys[0].ID, "-reason", "test"}); err != nil {
+		t.Fatalf("apikey revoke: %v", err)
+	}
+	revoked, _ := authService.GetAPIKey(ctx, models.DefaultOrganizationID, keys[0].ID)
+	if !revoked.IsRevoked() || revoked.RevokedReason != "test" {
+		t.Errorf("apikey revoke did not apply: %+v", revoked)
+	}
+	if err := revokeAPIKey(authService, []string{"-id", keys[0].ID, "-org", "org_other"}); err == nil {
+		t.Error("revoking a key of another organization should fail")
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if got := splitList(" a, ,b,"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("splitList = %q", got)
+	}
+	if got := splitList(""); got != nil {
+		t.Errorf("splitList(\"\") = %q", got)
+	}
+}
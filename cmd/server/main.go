@@ -54,18 +54,19 @@ func main() {
 		zap.String("driver", cfg.GetDatabaseDriver()),
 	)
 
-	// Run auto migrations
-	if err := database.AutoMigrate(db); err != nil {
-		log.Fatal("Failed to run database migrations", zap.Error(err))
+	// Bring the schema up to date. A schema newer than this build means a
+	// newer release has migrated the database; refuse to run against it.
+	applied, err := database.PrepareSchema(db, cfg.Database.AutoMigrate)
+	if err != nil {
+		log.Fatal("Database schema is not usable", zap.Error(err))
 	}
-	log.Info("Database migrations completed")
-
-	// Create indexes and triggers
-	if err := database.CreateIndexes(db); err != nil {
-		log.Warn("Failed to create some indexes", zap.Error(err))
+	for _, m := range applied {
+		log.Info("Applied database migration", zap.Int("version", m.Version), zap.String("name", m.Name))
 	}
-	if err := database.CreateTriggers(db); err != nil {
-		log.Warn("Failed to create triggers", zap.Error(err))
+	if fullText, err := database.EnsureSearchIndex(db); err != nil {
+		log.Fatal("Failed to create search index", zap.Error(err))
+	} else if !fullText {
+		log.Warn("SQLite was built without FTS5; message search falls back to substring matching")
 	}
 
 	// Health check
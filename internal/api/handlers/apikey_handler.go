@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultRotationGracePeriod = 24 * time.Hour
+	maxRotationGracePeriod     = 7 * 24 * time.Hour
+)
+
+// APIKeyHandler handles API key management requests
+type APIKeyHandler struct {
+	authService *services.AuthService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(authService *services.AuthService) *APIKeyHandler {
+	return &APIKeyHandler{
+		authService: authService,
+	}
+}
+
+// CreateAPIKeyRequest represents the request body for creating an API key
+type CreateAPIKeyRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	Permissions []string   `json:"permissions" binding:"required"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+
+	models.APIKeyLimits
+}
+
+// UpdateAPIKeyLimitsRequest represents the request body for changing the
+// limits of an API key. Omitted fields keep their current value.
+type UpdateAPIKeyLimitsRequest struct {
+	RateLimitPerMinute *int `json:"rate_limit_per_minute"`
+	DailyQuota         *int `json:"daily_quota"`
+	MonthlyQuota       *int `json:"monthly_quota"`
+}
+
+// RotateAPIKeyRequest represents the request body for rotating an API key
+type RotateAPIKeyRequest struct {
+	// GracePeriod is how long the old key keeps working, e.g. "24h"
+	GracePeriod string `json:"grace_period"`
+}
+
+// APIKeyWithSecret is returned when a key is created or rotated. The raw key
+// is only ever shown in this response.
+type APIKeyWithSecret struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey handles POST /api/v1/api-keys
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	// A key can only hand out permissions it holds itself
+	caller := c.MustGet("api_key").(*models.APIKey)
+	for _, permission := range req.Permissions {
+		if !caller.CanGrant(permission) {
+			utils.ErrorJSON(c, errors.NewForbidden("Cannot grant a permission the API key does not hold").
+				WithDetail("permission", permission))
+			return
+		}
+	}
+
+	apiKey, rawKey, err := h.authService.CreateAPIKey(c.Request.Context(), organizationID(c), req.Name, req.Permissions, req.APIKeyLimits, req.ExpiresAt)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.CreatedJSON(c, APIKeyWithSecret{APIKey: apiKey, Key: rawKey})
+}
+
+// ListAPIKeys handles GET /api/v1/api-keys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	apiKeys, err := h.authService.ListAPIKeys(c.Request.Context(), organizationID(c))
+	if err != nil {
+		utils.ErrorJSON(c, errors.NewInternalError(err))
+		return
+	}
+
+	utils.SuccessJSON(c, 200, apiKeys)
+}
+
+// GetAPIKey handles GET /api/v1/api-keys/:id
+func (h *APIKeyHandler) GetAPIKey(c *gin.Context) {
+	keyID := c.Param("id")
+
+	apiKey, err := h.authService.GetAPIKey(c.Request.Context(), organizationID(c), keyID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, apiKey)
+}
+
+// UpdateAPIKeyLimits handles PATCH /api/v1/api-keys/:id
+func (h *APIKeyHandler) UpdateAPIKeyLimits(c *gin.Context) {
+	keyID := c.Param("id")
+
+	var req UpdateAPIKeyLimitsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	apiKey, ok := h.managedKey(c, keyID)
+	if !ok {
+		return
+	}
+	// Raising its own limits would let a key lift the quotas put on it
+	if caller := c.MustGet("api_key").(*models.APIKey); apiKey.ID == caller.ID && !caller.CanGrant(models.PermissionAll) {
+		utils.ErrorJSON(c, errors.NewForbidden("Changing the limits of the calling API key requires the * permission"))
+		return
+	}
+
+	limits := apiKey.APIKeyLimits
+	if req.RateLimitPerMinute != nil {
+		limits.RateLimitPerMinute = *req.RateLimitPerMinute
+	}
+	if req.DailyQuota != nil {
+		limits.DailyQuota = *req.DailyQuota
+	}
+	if req.MonthlyQuota != nil {
+		limits.MonthlyQuota = *req.MonthlyQuota
+	}
+
+	apiKey, err := h.authService.UpdateAPIKeyLimits(c.Request.Context(), organizationID(c), keyID, limits)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, apiKey)
+}
+
+// RotateAPIKey handles POST /api/v1/api-keys/:id/rotate
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	keyID := c.Param("id")
+
+	var req RotateAPIKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+			return
+		}
+	}
+
+	gracePeriod := defaultRotationGracePeriod
+	if req.GracePeriod != "" {
+		d, err := time.ParseDuration(req.GracePeriod)
+		if err != nil || d < 0 || d > maxRotationGracePeriod {
+			utils.ErrorJSON(c, errors.NewBadRequest("grace_period must be a duration between 0s and 168h"))
+			return
+		}
+		gracePeriod = d
+	}
+
+	if _, ok := h.managedKey(c, keyID); !ok {
+		return
+	}
+
+	apiKey, rawKey, err := h.authService.RotateAPIKey(c.Request.Context(), organizationID(c), keyID, gracePeriod)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.CreatedJSON(c, APIKeyWithSecret{APIKey: apiKey, Key: rawKey})
+}
+
+// RevokeAPIKey handles DELETE /api/v1/api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	keyID := c.Param("id")
+
+	if _, ok := h.managedKey(c, keyID); !ok {
+		return
+	}
+
+	if err := h.authService.RevokeAPIKey(c.Request.Context(), organizationID(c), keyID, c.Query("reason")); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.NoContentJSON(c)
+}
+
+// managedKey loads the key keyID of the caller's organization and checks the
+// calling key holds every permission of it. It writes the error response and
+// returns false if the key is missing or the caller may not manage it.
+func (h *APIKeyHandler) managedKey(c *gin.Context, keyID string) (*models.APIKey, bool) {
+	apiKey, err := h.authService.GetAPIKey(c.Request.Context(), organizationID(c), keyID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return nil, false
+	}
+
+	caller := c.MustGet("api_key").(*models.APIKey)
+	if !caller.CanManage(apiKey) {
+		utils.ErrorJSON(c, errors.NewForbidden("Cannot manage an API key with permissions the calling key does not hold"))
+		return nil, false
+	}
+	return apiKey, true
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/database"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB opens a migrated SQLite database in a temporary directory
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.NewConnection("sqlite", filepath.Join(t.TempDir(), "test.db"), logger.Silent)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { database.CloseConnection(db) })
+	if _, err := database.PrepareSchema(db, true); err != nil {
+		t.Fatalf("PrepareSchema: %v", err)
+	}
+	return db
+}
+
+type apiKeyHandlerFixture struct {
+	auth   *services.AuthService
+	router *gin.Engine
+	caller *models.APIKey
+}
+
+// newAPIKeyHandlerFixture serves the API key routes as the key f.caller of
+// org_a. The caller can be swapped between requests.
+func newAPIKeyHandlerFixture(t *testing.T) *apiKeyHandlerFixture {
+	t.Helper()
+	db := openTestDB(t)
+	f := &apiKeyHandlerFixture{
+		auth: services.NewAuthService(repositories.NewAPIKeyRepository(db), repositories.NewOrganizationRepository(db), "salt", time.Minute),
+	}
+	t.Cleanup(func() { f.auth.Close() })
+
+	handler := NewAPIKeyHandler(f.auth)
+	gin.SetMode(gin.TestMode)
+	f.router = gin.New()
+	f.router.Use(func(c *gin.Context) {
+		c.Set("api_key", f.caller)
+		c.Set("organization_id", f.caller.OrganizationID)
+	})
+	f.router.POST("/api-keys", handler.CreateAPIKey)
+	f.router.PATCH("/api-keys/:id", handler.UpdateAPIKeyLimits)
+	f.router.POST("/api-keys/:id/rotate", handler.RotateAPIKey)
+	f.router.DELETE("/api-keys/:id", handler.RevokeAPIKey)
+	return f
+}
+
+// createKey creates a key of org_a with the given permissions
+func (f *apiKeyHandlerFixture) createKey(t *testing.T, permissions ...string) *models.APIKey {
+	t.Helper()
+	apiKey, _, err := f.auth.CreateAPIKey(context.Background(), "org_a", "test", permissions, models.APIKeyLimits{DailyQuota: 100}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	return apiKey
+}
+
+func (f *apiKeyHandlerFixture) do(method, path string, body interface{}) *httptest.ResponseRecorder {
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIKeyHandlerCannotEscalate(t *testing.T) {
+	f := newAPIKeyHandlerFixture(t)
+	ctx := context.Background()
+	f.caller = f.createKey(t, models.PermissionKeysAdmin, models.PermissionMessagesSend)
+	wildcard := f.createKey(t, models.PermissionAll)
+
+	if rec := f.do(http.MethodPost, "/api-keys", map[string]interface{}{"name": "new", "permissions": []string{models.PermissionAll}}); rec.Code != http.StatusForbidden {
+		t.Errorf("creating a key with a permission the caller lacks: %d", rec.Code)
+	}
+	if rec := f.do(http.MethodPost, "/api-keys/"+wildcard.ID+"/rotate", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("rotating a wildcard key should be forbidden, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := f.do(http.MethodPatch, "/api-keys/"+wildcard.ID, map[string]int{"daily_quota": 1}); rec.Code != http.StatusForbidden {
+		t.Errorf("changing the limits of a wildcard key should be forbidden, got %d", rec.Code)
+	}
+	if rec := f.do(http.MethodDelete, "/api-keys/"+wildcard.ID, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("revoking a wildcard key should be forbidden, got %d", rec.Code)
+	}
+	if rec := f.do(http.MethodPatch, "/api-keys/"+f.caller.ID, map[string]int{"daily_quota": 0}); rec.Code != http.StatusForbidden {
+		t.Errorf("a key should not lift its own quotas, got %d", rec.Code)
+	}
+
+	keys, _ := f.auth.ListAPIKeys(ctx, "org_a")
+	for _, k := range keys {
+		if k.IsRevoked() || k.ExpiresAt != nil || k.DailyQuota != 100 {
+			t.Errorf("rejected calls should change nothing: %+v", k)
+		}
+	}
+	if len(keys) != 2 {
+		t.Errorf("rejected calls should create no keys, got %d", len(keys))
+	}
+}
+
+func TestAPIKeyHandlerManagesKeysWithinItsPermissions(t *testing.T) {
+	f := newAPIKeyHandlerFixture(t)
+	ctx := context.Background()
+	f.caller = f.createKey(t, models.PermissionKeysAdmin, models.PermissionMessagesSend)
+	sender := f.createKey(t, models.PermissionMessagesSend)
+
+	if rec := f.do(http.MethodPatch, "/api-keys/"+sender.ID, map[string]int{"daily_quota": 5}); rec.Code != http.StatusOK {
+		t.Errorf("UpdateAPIKeyLimits: %d %s", rec.Code, rec.Body)
+	}
+
+	rec := f.do(http.MethodPost, "/api-keys/"+sender.ID+"/rotate", map[string]string{"grace_period": "1h"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("RotateAPIKey: %d %s", rec.Code, rec.Body)
+	}
+	var rotated struct {
+		Data struct {
+			ID          string   `json:"id"`
+			Key         string   `json:"key"`
+			Permissions []string `json:"permissions"`
+			DailyQuota  int      `json:"daily_quota"`
+		} `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &rotated)
+	if rotated.Data.Key == "" || len(rotated.Data.Permissions) != 1 || rotated.Data.DailyQuota != 5 {
+		t.Errorf("the replacement should keep permissions and limits: %+v", rotated.Data)
+	}
+	old, _ := f.auth.GetAPIKey(ctx, "org_a", sender.ID)
+	if old.ExpiresAt == nil || old.ExpiresAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("the old key should expire after the grace period: %+v", old.ExpiresAt)
+	}
+
+	if rec := f.do(http.MethodPost, "/api-keys/"+sender.ID+"/rotate", map[string]string{"grace_period": "1000h"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("a grace period over a week should be rejected, got %d", rec.Code)
+	}
+	if rec := f.do(http.MethodDelete, "/api-keys/"+rotated.Data.ID, nil); rec.Code != http.StatusNoContent {
+		t.Errorf("RevokeAPIKey: %d %s", rec.Code, rec.Body)
+	}
+	if rec := f.do(http.MethodDelete, "/api-keys/"+rotated.Data.ID, nil); rec.Code != http.StatusConflict {
+		t.Errorf("revoking twice should conflict, got %d", rec.Code)
+	}
+
+	// Keys of other organizations are not found at all
+	f.caller = &models.APIKey{ID: "key_other", OrganizationID: "org_b", Permissions: models.JSONArray{models.PermissionAll}}
+	if rec := f.do(http.MethodDelete, "/api-keys/"+f.createKey(t, models.PermissionMessagesSend).ID, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("another organization's key should not be found, got %d", rec.Code)
+	}
+
+	// A wildcard key may change its own limits
+	f.caller = f.createKey(t, models.PermissionAll)
+	if rec := f.do(http.MethodPatch, "/api-keys/"+f.caller.ID, map[string]int{"daily_quota": 0}); rec.Code != http.StatusOK {
+		t.Errorf("a wildcard key should change its own limits: %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AttributeHandler handles custom contact attribute definitions
+type AttributeHandler struct {
+	attributeService *services.AttributeService
+}
+
+// NewAttributeHandler creates a new attribute handler
+func NewAttributeHandler(attributeService *services.AttributeService) *AttributeHandler {
+	return &AttributeHandler{
+		attributeService: attributeService,
+	}
+}
+
+// CreateAttribute handles POST /api/v1/contacts/attributes
+func (h *AttributeHandler) CreateAttribute(c *gin.Context) {
+	var attribute models.AttributeDefinition
+	if err := c.ShouldBindJSON(&attribute); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	if err := h.attributeService.CreateAttribute(c.Request.Context(), organizationID(c), &attribute); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.CreatedJSON(c, attribute)
+}
+
+// GetAttribute handles GET /api/v1/contacts/attributes/:id
+func (h *AttributeHandler) GetAttribute(c *gin.Context) {
+	attribute, err := h.attributeService.GetAttribute(c.Request.Context(), organizationID(c), c.Param("id"))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, attribute)
+}
+
+// ListAttributes handles GET /api/v1/contacts/attributes
+func (h *AttributeHandler) ListAttributes(c *gin.Context) {
+	attributes, err := h.attributeService.ListAttributes(c.Request.Context(), organizationID(c))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, attributes)
+}
+
+// UpdateAttribute handles PATCH /api/v1/contacts/attributes/:id
+func (h *AttributeHandler) UpdateAttribute(c *gin.Context) {
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	attribute, err := h.attributeService.UpdateAttribute(c.Request.Context(), organizationID(c), c.Param("id"), updates)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, attribute)
+}
+
+// DeleteAttribute handles DELETE /api/v1/contacts/attributes/:id
+func (h *AttributeHandler) DeleteAttribute(c *gin.Context) {
+	if err := h.attributeService.DeleteAttribute(c.Request.Context(), organizationID(c), c.Param("id")); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.NoContentJSON(c)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLogHandler handles audit log requests
+type AuditLogHandler struct {
+	auditService *services.AuditLogService
+}
+
+// NewAuditLogHandler creates a new audit log handler
+func NewAuditLogHandler(auditService *services.AuditLogService) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditService: auditService,
+	}
+}
+
+// ListAuditLogs handles GET /api/v1/audit-logs
+func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	pagination := utils.NewPagination(limit, offset)
+
+	filters := map[string]interface{}{
+		"action":      c.Query("action"),
+		"resource_id": c.Query("resource_id"),
+	}
+	entries, err := h.auditService.ListAuditLogs(c.Request.Context(), organizationID(c), filters, pagination)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.ListJSON(c, entries, pagination)
+}
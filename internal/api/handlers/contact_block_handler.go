@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ContactBlockHandler handles the contact blocklist
+type ContactBlockHandler struct {
+	blockService *services.ContactBlockService
+}
+
+// NewContactBlockHandler creates a new contact block handler
+func NewContactBlockHandler(blockService *services.ContactBlockService) *ContactBlockHandler {
+	return &ContactBlockHandler{
+		blockService: blockService,
+	}
+}
+
+// BlockContactRequest represents the request body for blocking a contact
+type BlockContactRequest struct {
+	Reason string `json:"reason"`
+}
+
+// BlockContact handles POST /api/v1/contacts/:id/block. The body is
+// optional.
+func (h *ContactBlockHandler) BlockContact(c *gin.Context) {
+	var req BlockContactRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+			return
+		}
+	}
+
+	result, err := h.blockService.BlockContact(c.Request.Context(), organizationID(c), c.Param("id"), req.Reason, c.GetString("api_key_id"))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, result)
+}
+
+// UnblockContact handles DELETE /api/v1/contacts/:id/block
+func (h *ContactBlockHandler) UnblockContact(c *gin.Context) {
+	result, err := h.blockService.UnblockContact(c.Request.Context(), organizationID(c), c.Param("id"), c.GetString("api_key_id"))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, result)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ContactDataHandler handles data subject requests: contact data exports,
+// deletions and erasures
+type ContactDataHandler struct {
+	dataService *services.ContactDataService
+}
+
+// NewContactDataHandler creates a new contact data handler
+func NewContactDataHandler(dataService *services.ContactDataService) *ContactDataHandler {
+	return &ContactDataHandler{
+		dataService: dataService,
+	}
+}
+
+// ExportContactData handles GET /api/v1/contacts/:id/export. It streams a
+// ZIP archive of everything stored about the contact.
+func (h *ContactDataHandler) ExportContactData(c *gin.Context) {
+	contactID := c.Param("id")
+	w := &exportWriter{
+		c:           c,
+		contentType: "application/zip",
+		filename:    "contact-" + contactID + ".zip",
+		controller:  http.NewResponseController(c.Writer),
+	}
+
+	err := h.dataService.ExportContactData(untimedContext(c), organizationID(c), contactID, w)
+	if err != nil && !w.started {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+	if err != nil {
+		// The status was sent with the first file; the client sees a
+		// truncated archive
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// DeleteContact handles DELETE /api/v1/contacts/:id. With erase=true the
+// contact is erased with its messages, calls and media, and the erasure
+// tombstone is returned.
+func (h *ContactDataHandler) DeleteContact(c *gin.Context) {
+	erase := false
+	if value := c.Query("erase"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.ErrorJSON(c, errors.NewBadRequest("erase must be true or false"))
+			return
+		}
+		erase = parsed
+	}
+
+	if !erase {
+		if err := h.dataService.DeleteContact(c.Request.Context(), organizationID(c), c.Param("id"), c.GetString("api_key_id")); err != nil {
+			if appErr, ok := err.(*errors.AppError); ok {
+				utils.ErrorJSON(c, appErr)
+			} else {
+				utils.ErrorJSON(c, errors.NewInternalError(err))
+			}
+			return
+		}
+		utils.NoContentJSON(c)
+		return
+	}
+
+	result, err := h.dataService.EraseContact(c.Request.Context(), organizationID(c), c.Param("id"), c.GetString("api_key_id"))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, result)
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
@@ -11,13 +14,15 @@ import (
 
 // ContactHandler handles contact-related requests
 type ContactHandler struct {
-	contactService *services.ContactService
+	contactService      *services.ContactService
+	conversationService *services.ConversationService
 }
 
 // NewContactHandler creates a new contact handler
-func NewContactHandler(contactService *services.ContactService) *ContactHandler {
+func NewContactHandler(contactService *services.ContactService, conversationService *services.ConversationService) *ContactHandler {
 	return &ContactHandler{
-		contactService: contactService,
+		contactService:      contactService,
+		conversationService: conversationService,
 	}
 }
 
@@ -25,7 +30,7 @@ func NewContactHandler(contactService *services.ContactService) *ContactHandler
 func (h *ContactHandler) GetContact(c *gin.Context) {
 	contactID := c.Param("id")
 
-	contact, err := h.contactService.GetContact(contactID)
+	contact, err := h.contactService.GetContact(c.Request.Context(), organizationID(c), contactID)
 	if err != nil {
 		if appErr, ok := err.(*errors.AppError); ok {
 			utils.ErrorJSON(c, appErr)
@@ -40,10 +45,23 @@ func (h *ContactHandler) GetContact(c *gin.Context) {
 
 // ListContacts handles GET /api/v1/contacts
 func (h *ContactHandler) ListContacts(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-	pagination := utils.NewPagination(limit, offset)
+	pagination := cursorPagination(c, 50)
+
+	contacts, err := h.contactService.ListContacts(c.Request.Context(), organizationID(c), contactFilters(c), pagination)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.ListJSON(c, contacts, pagination)
+}
 
+// contactFilters reads the contact listing filters from the query string
+func contactFilters(c *gin.Context) map[string]interface{} {
 	filters := make(map[string]interface{})
 	if sort := c.Query("sort"); sort != "" {
 		filters["sort"] = sort
@@ -51,14 +69,98 @@ func (h *ContactHandler) ListContacts(c *gin.Context) {
 	if order := c.Query("order"); order != "" {
 		filters["order"] = order
 	}
+	if tag := c.Query("tag"); tag != "" {
+		filters["tag"] = tag
+	}
+	if segmentID := c.Query("segment_id"); segmentID != "" {
+		filters["segment_id"] = segmentID
+	}
+	if filter := c.Query("filter"); filter != "" {
+		filters["filter"] = filter
+	}
+	if blocked, err := strconv.ParseBool(c.Query("blocked")); err == nil {
+		filters["blocked"] = blocked
+	}
+	return filters
+}
 
-	contacts, err := h.contactService.ListContacts(filters, pagination)
+// ExportContacts handles GET /api/v1/contacts/export. It takes the filters
+// of ListContacts and streams every matching contact.
+func (h *ContactHandler) ExportContacts(c *gin.Context) {
+	format := c.DefaultQuery("format", models.ImportFormatCSV)
+	contentType := "text/csv; charset=utf-8"
+	if format == models.ImportFormatJSONL {
+		contentType = "application/x-ndjson"
+	}
+	w := &exportWriter{
+		c:           c,
+		contentType: contentType,
+		filename:    "contacts." + format,
+		controller:  http.NewResponseController(c.Writer),
+	}
+
+	err := h.contactService.ExportContacts(untimedContext(c), organizationID(c), format, contactFilters(c), w)
+	if err != nil && !w.started {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
 	if err != nil {
-		utils.ErrorJSON(c, errors.NewInternalError(err))
+		// The status was sent with the first page; the client sees a
+		// truncated file
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// exportWriteTimeout is how long an export waits for the client to accept
+// each write
+const exportWriteTimeout = 30 * time.Second
+
+// exportWriter streams an export. The headers are sent with the first
+// write, so errors found before any contact is written are still sent as
+// JSON.
+type exportWriter struct {
+	c           *gin.Context
+	contentType string
+	filename    string
+	controller  *http.ResponseController
+	started     bool
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", w.contentType)
+		w.c.Header("Content-Disposition", `attachment; filename="`+w.filename+`"`)
+		w.c.Status(200)
+	}
+	// Exports outlast the server write timeout; each write gets its own.
+	// Writers that cannot set deadlines have no timeout to extend.
+	_ = w.controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	n, err := w.c.Writer.Write(p)
+	w.c.Writer.Flush()
+	return n, err
+}
+
+// GetContactMessages handles GET /api/v1/contacts/:id/messages
+func (h *ContactHandler) GetContactMessages(c *gin.Context) {
+	pagination := cursorPagination(c, 50)
+
+	conversation, err := h.conversationService.GetConversation(c.Request.Context(), organizationID(c), c.Param("id"), pagination)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
 		return
 	}
 
-	utils.ListJSON(c, contacts, pagination)
+	utils.ListJSON(c, conversation, pagination)
 }
 
 // UpdateContact handles PATCH /api/v1/contacts/:id
@@ -71,7 +173,7 @@ func (h *ContactHandler) UpdateContact(c *gin.Context) {
 		return
 	}
 
-	contact, err := h.contactService.UpdateContact(contactID, updates)
+	contact, err := h.contactService.UpdateContact(c.Request.Context(), organizationID(c), contactID, updates)
 	if err != nil {
 		if appErr, ok := err.(*errors.AppError); ok {
 			utils.ErrorJSON(c, appErr)
@@ -96,7 +198,7 @@ func (h *ContactHandler) SearchContacts(c *gin.Context) {
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 	pagination := utils.NewPagination(limit, offset)
 
-	contacts, err := h.contactService.SearchContacts(query, pagination)
+	contacts, err := h.contactService.SearchContacts(c.Request.Context(), organizationID(c), query, pagination)
 	if err != nil {
 		utils.ErrorJSON(c, errors.NewInternalError(err))
 		return
@@ -104,3 +206,89 @@ func (h *ContactHandler) SearchContacts(c *gin.Context) {
 
 	utils.ListJSON(c, contacts, pagination)
 }
+
+// TagsRequest represents the request body for tagging a contact
+type TagsRequest struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+// AddTags handles POST /api/v1/contacts/:id/tags
+func (h *ContactHandler) AddTags(c *gin.Context) {
+	var req TagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	contact, err := h.contactService.AddTags(c.Request.Context(), organizationID(c), c.Param("id"), req.Tags)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, contact)
+}
+
+// RemoveTag handles DELETE /api/v1/contacts/:id/tags/:tag
+func (h *ContactHandler) RemoveTag(c *gin.Context) {
+	contact, err := h.contactService.RemoveTag(c.Request.Context(), organizationID(c), c.Param("id"), c.Param("tag"))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, contact)
+}
+
+// BulkTagRequest represents the request body for tagging many contacts.
+// It names either the contacts or a segment.
+type BulkTagRequest struct {
+	ContactIDs []string `json:"contact_ids"`
+	SegmentID  string   `json:"segment_id"`
+	Add        []string `json:"add"`
+	Remove     []string `json:"remove"`
+}
+
+// TagContacts handles POST /api/v1/contacts/tags
+func (h *ContactHandler) TagContacts(c *gin.Context) {
+	var req BulkTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	count, err := h.contactService.TagContacts(c.Request.Context(), organizationID(c), req.ContactIDs, req.SegmentID, req.Add, req.Remove)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, gin.H{"contacts": count})
+}
+
+// ListTags handles GET /api/v1/contacts/tags
+func (h *ContactHandler) ListTags(c *gin.Context) {
+	tags, err := h.contactService.ListTags(c.Request.Context(), organizationID(c))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, tags)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ContactImportHandler handles bulk contact imports
+type ContactImportHandler struct {
+	importService *services.ContactImportService
+}
+
+// NewContactImportHandler creates a new contact import handler
+func NewContactImportHandler(importService *services.ContactImportService) *ContactImportHandler {
+	return &ContactImportHandler{
+		importService: importService,
+	}
+}
+
+// ImportContacts handles POST /api/v1/contacts/import. The file is sent as
+// the multipart form field "file", with optional "format", "mapping" and
+// "country" fields. The import runs in the background.
+func (h *ContactImportHandler) ImportContacts(c *gin.Context) {
+	// Leave room for the other form fields
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxImportFileSize+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("The import file is required as the multipart form field \"file\": "+err.Error()))
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Failed to read the import file: "+err.Error()))
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, services.MaxImportFileSize+1))
+	if err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Failed to read the import file: "+err.Error()))
+		return
+	}
+
+	format := strings.ToLower(c.PostForm("format"))
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".csv":
+			format = models.ImportFormatCSV
+		case ".jsonl", ".ndjson":
+			format = models.ImportFormatJSONL
+		}
+	}
+
+	var mapping map[string]string
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			utils.ErrorJSON(c, errors.NewBadRequest("mapping must be a JSON object of column names to fields: "+err.Error()))
+			return
+		}
+	}
+
+	job, err := h.importService.StartImport(c.Request.Context(), organizationID(c), format, strings.ToUpper(c.PostForm("country")), mapping, data)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, http.StatusAccepted, job)
+}
+
+// GetImport handles GET /api/v1/contacts/imports/:id
+func (h *ContactImportHandler) GetImport(c *gin.Context) {
+	job, err := h.importService.GetImport(c.Request.Context(), organizationID(c), c.Param("id"))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, job)
+}
+
+// ListImports handles GET /api/v1/contacts/imports
+func (h *ContactImportHandler) ListImports(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	pagination := utils.NewPagination(limit, offset)
+
+	imports, err := h.importService.ListImports(c.Request.Context(), organizationID(c), pagination)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.ListJSON(c, imports, pagination)
+}
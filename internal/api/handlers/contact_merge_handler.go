@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// ContactMergeHandler handles duplicate contact detection and merges
+type ContactMergeHandler struct {
+	mergeService *services.ContactMergeService
+}
+
+// NewContactMergeHandler creates a new contact merge handler
+func NewContactMergeHandler(mergeService *services.ContactMergeService) *ContactMergeHandler {
+	return &ContactMergeHandler{
+		mergeService: mergeService,
+	}
+}
+
+// FindDuplicates handles GET /api/v1/contacts/duplicates
+func (h *ContactMergeHandler) FindDuplicates(c *gin.Context) {
+	report, err := h.mergeService.FindDuplicates(c.Request.Context(), organizationID(c), strings.ToUpper(c.Query("country")))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, report)
+}
+
+// MergeContactsRequest represents the request body for merging duplicates
+// into a contact
+type MergeContactsRequest struct {
+	DuplicateIDs []string `json:"duplicate_ids" binding:"required"`
+	Rule         string   `json:"rule"`
+	Country      string   `json:"country"`
+}
+
+// MergeContacts handles POST /api/v1/contacts/:id/merge
+func (h *ContactMergeHandler) MergeContacts(c *gin.Context) {
+	var req MergeContactsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	result, err := h.mergeService.MergeContacts(c.Request.Context(), organizationID(c), c.Param("id"), req.DuplicateIDs, req.Rule, strings.ToUpper(req.Country), c.GetString("api_key_id"))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, result)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// organizationID returns the organization of the authenticated API key
+func organizationID(c *gin.Context) string {
+	return c.GetString("organization_id")
+}
+
+// untimedContext returns the request context without the request timeout,
+// for responses streamed for as long as the client reads them. It is still
+// cancelled when the client disconnects.
+func untimedContext(c *gin.Context) context.Context {
+	if ctx, ok := c.Value("untimed_context").(context.Context); ok {
+		return ctx
+	}
+	return c.Request.Context()
+}
+
+// cursorPagination reads the pagination of a listing that supports cursors
+// from the query string. A cursor replaces the offset. The total is counted
+// for offset pages unless include_total=false, and for cursor pages only
+// with include_total=true.
+func cursorPagination(c *gin.Context, defaultLimit int) *utils.Pagination {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	pagination := utils.NewPagination(limit, offset)
+
+	pagination.Cursor = c.Query("cursor")
+	includeTotal, err := strconv.ParseBool(c.Query("include_total"))
+	if err != nil {
+		includeTotal = pagination.Cursor == ""
+	}
+	pagination.SkipTotal = !includeTotal
+	return pagination
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
 	"github.com/gin-gonic/gin"
@@ -33,6 +34,11 @@ type SendMessageRequest struct {
 	TemplateName     string   `json:"template_name"`
 	TemplateLanguage string   `json:"template_language"`
 	Parameters       []string `json:"parameters"`
+	// ReplyTo is the ID of the message to quote, or to react to
+	ReplyTo  string                 `json:"reply_to"`
+	Emoji    string                 `json:"emoji"`
+	Location *whatsapp.Location     `json:"location"`
+	Contacts []whatsapp.ContactCard `json:"contacts"`
 }
 
 // SendMessage handles POST /api/v1/messages
@@ -48,13 +54,29 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 
 	switch req.Type {
 	case "text":
-		message, err = h.messageService.SendTextMessage(req.Phone, req.Content)
+		message, err = h.messageService.SendTextMessage(c.Request.Context(), organizationID(c), req.Phone, req.Content, req.ReplyTo)
 
-	case "image", "video", "audio", "document":
-		message, err = h.messageService.SendMediaMessage(req.Phone, req.MediaURL, req.Caption, req.Type)
+	case "image", "video", "audio", "document", "sticker":
+		message, err = h.messageService.SendMediaMessage(c.Request.Context(), organizationID(c), req.Phone, req.MediaURL, req.Caption, req.Type, req.ReplyTo)
 
 	case "template":
-		message, err = h.messageService.SendTemplateMessage(req.Phone, req.TemplateName, req.TemplateLanguage, req.Parameters)
+		message, err = h.messageService.SendTemplateMessage(c.Request.Context(), organizationID(c), req.Phone, req.TemplateName, req.TemplateLanguage, req.Parameters, req.ReplyTo)
+
+	case "location":
+		if req.Location == nil {
+			utils.ErrorJSON(c, errors.NewBadRequest("location is required"))
+			return
+		}
+		message, err = h.messageService.SendLocationMessage(c.Request.Context(), organizationID(c), req.Phone, *req.Location, req.ReplyTo)
+
+	case "location_request":
+		message, err = h.messageService.SendLocationRequest(c.Request.Context(), organizationID(c), req.Phone, req.Content, req.ReplyTo)
+
+	case "contacts":
+		message, err = h.messageService.SendContactsMessage(c.Request.Context(), organizationID(c), req.Phone, req.Contacts, req.ReplyTo)
+
+	case "reaction":
+		message, err = h.messageService.SendReaction(c.Request.Context(), organizationID(c), req.Phone, req.ReplyTo, req.Emoji)
 
 	default:
 		utils.ErrorJSON(c, errors.NewBadRequest("Invalid message type: "+req.Type))
@@ -77,7 +99,7 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 func (h *MessageHandler) GetMessage(c *gin.Context) {
 	messageID := c.Param("id")
 
-	message, err := h.messageService.GetMessage(messageID)
+	message, err := h.messageService.GetMessage(c.Request.Context(), organizationID(c), messageID)
 	if err != nil {
 		if appErr, ok := err.(*errors.AppError); ok {
 			utils.ErrorJSON(c, appErr)
@@ -90,43 +112,47 @@ func (h *MessageHandler) GetMessage(c *gin.Context) {
 	utils.SuccessJSON(c, 200, message)
 }
 
-// ListMessages handles GET /api/v1/messages
-func (h *MessageHandler) ListMessages(c *gin.Context) {
-	// Parse query parameters
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-
-	pagination := utils.NewPagination(limit, offset)
-
-	// Build filters
-	filters := make(map[string]interface{})
+// MarkAsReadRequest represents the optional request body for marking a
+// message as read
+type MarkAsReadRequest struct {
+	Typing bool `json:"typing"`
+}
 
-	if phone := c.Query("phone"); phone != "" {
-		filters["phone"] = phone
-	}
-	if direction := c.Query("direction"); direction != "" {
-		filters["direction"] = direction
-	}
-	if msgType := c.Query("type"); msgType != "" {
-		filters["type"] = msgType
-	}
-	if status := c.Query("status"); status != "" {
-		filters["status"] = status
-	}
-	if startDate := c.Query("start_date"); startDate != "" {
-		if t, err := time.Parse(time.RFC3339, startDate); err == nil {
-			filters["start_date"] = t
+// MarkAsRead handles POST /api/v1/messages/:id/read
+func (h *MessageHandler) MarkAsRead(c *gin.Context) {
+	var req MarkAsReadRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+			return
 		}
 	}
-	if endDate := c.Query("end_date"); endDate != "" {
-		if t, err := time.Parse(time.RFC3339, endDate); err == nil {
-			filters["end_date"] = t
+
+	message, err := h.messageService.MarkAsRead(c.Request.Context(), organizationID(c), c.Param("id"), req.Typing)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
 		}
+		return
 	}
 
-	messages, err := h.messageService.ListMessages(filters, pagination)
+	utils.SuccessJSON(c, 200, message)
+}
+
+// ListMessages handles GET /api/v1/messages
+func (h *MessageHandler) ListMessages(c *gin.Context) {
+	pagination := cursorPagination(c, 20)
+	filters := messageFilters(c)
+
+	messages, err := h.messageService.ListMessages(c.Request.Context(), organizationID(c), filters, pagination)
 	if err != nil {
-		utils.ErrorJSON(c, errors.NewInternalError(err))
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
 		return
 	}
 
@@ -145,12 +171,7 @@ func (h *MessageHandler) SearchMessages(c *gin.Context) {
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 	pagination := utils.NewPagination(limit, offset)
 
-	filters := make(map[string]interface{})
-	if phone := c.Query("phone"); phone != "" {
-		filters["phone"] = phone
-	}
-
-	messages, err := h.messageService.SearchMessages(query, filters, pagination)
+	messages, err := h.messageService.SearchMessages(c.Request.Context(), organizationID(c), query, messageFilters(c), pagination)
 	if err != nil {
 		utils.ErrorJSON(c, errors.NewInternalError(err))
 		return
@@ -158,3 +179,33 @@ func (h *MessageHandler) SearchMessages(c *gin.Context) {
 
 	utils.ListJSON(c, messages, pagination)
 }
+
+// messageFilters reads the filters shared by listing and searching messages
+// from the query string. Dates that are not RFC 3339 are ignored.
+func messageFilters(c *gin.Context) map[string]interface{} {
+	filters := make(map[string]interface{})
+
+	if phone := c.Query("phone"); phone != "" {
+		filters["phone"] = phone
+	}
+	if direction := c.Query("direction"); direction != "" {
+		filters["direction"] = direction
+	}
+	if msgType := c.Query("type"); msgType != "" {
+		filters["type"] = msgType
+	}
+	if status := c.Query("status"); status != "" {
+		filters["status"] = status
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		if t, err := time.Parse(time.RFC3339, startDate); err == nil {
+			filters["start_date"] = t
+		}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		if t, err := time.Parse(time.RFC3339, endDate); err == nil {
+			filters["end_date"] = t
+		}
+	}
+	return filters
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// OrganizationHandler handles tenant administration requests
+type OrganizationHandler struct {
+	orgService *services.OrganizationService
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(orgService *services.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService: orgService,
+	}
+}
+
+// CreateOrganizationRequest represents the request body for creating an organization
+type CreateOrganizationRequest struct {
+	Name               string         `json:"name" binding:"required"`
+	PhoneNumberID      string         `json:"phone_number_id"`
+	BusinessAccountID  string         `json:"business_account_id"`
+	AccessToken        string         `json:"access_token"`
+	WebhookVerifyToken string         `json:"webhook_verify_token"`
+	WebhookSecret      string         `json:"webhook_secret"`
+	Metadata           models.JSONMap `json:"metadata"`
+}
+
+// CreateOrganization handles POST /api/v1/organizations
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	org := &models.Organization{
+		Name:               req.Name,
+		PhoneNumberID:      req.PhoneNumberID,
+		BusinessAccountID:  req.BusinessAccountID,
+		AccessToken:        req.AccessToken,
+		WebhookVerifyToken: req.WebhookVerifyToken,
+		WebhookSecret:      req.WebhookSecret,
+		Metadata:           req.Metadata,
+	}
+
+	if err := h.orgService.CreateOrganization(c.Request.Context(), organizationID(c), org); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.CreatedJSON(c, org)
+}
+
+// GetOrganization handles GET /api/v1/organizations/:id
+func (h *OrganizationHandler) GetOrganization(c *gin.Context) {
+	orgID := c.Param("id")
+
+	org, err := h.orgService.GetOrganizationAs(c.Request.Context(), organizationID(c), orgID)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, org)
+}
+
+// GetCurrentOrganization handles GET /api/v1/organization
+func (h *OrganizationHandler) GetCurrentOrganization(c *gin.Context) {
+	org, err := h.orgService.GetOrganization(c.Request.Context(), organizationID(c))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, org)
+}
+
+// ListOrganizations handles GET /api/v1/organizations
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	pagination := utils.NewPagination(limit, offset)
+
+	orgs, err := h.orgService.ListOrganizations(c.Request.Context(), organizationID(c), pagination)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.ListJSON(c, orgs, pagination)
+}
+
+// UpdateOrganization handles PATCH /api/v1/organizations/:id
+func (h *OrganizationHandler) UpdateOrganization(c *gin.Context) {
+	orgID := c.Param("id")
+
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	org, err := h.orgService.UpdateOrganization(c.Request.Context(), organizationID(c), orgID, updates)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, org)
+}
+
+// DeleteOrganization handles DELETE /api/v1/organizations/:id
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	orgID := c.Param("id")
+
+	if err := h.orgService.DeleteOrganization(c.Request.Context(), organizationID(c), orgID); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.NoContentJSON(c)
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// RetentionHandler handles message retention policies and runs
+type RetentionHandler struct {
+	retentionService *services.RetentionService
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(retentionService *services.RetentionService) *RetentionHandler {
+	return &RetentionHandler{
+		retentionService: retentionService,
+	}
+}
+
+// SetRetentionPolicyRequest represents the request body for setting a
+// retention policy
+type SetRetentionPolicyRequest struct {
+	Action          string `json:"action"`
+	RetainDays      int    `json:"retain_days"`
+	MediaRetainDays int    `json:"media_retain_days"`
+}
+
+// ListPolicies handles GET /api/v1/retention/policies
+func (h *RetentionHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.retentionService.ListPolicies(c.Request.Context(), organizationID(c))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, policies)
+}
+
+// SetPolicy handles PUT /api/v1/retention/policies/:message_type
+func (h *RetentionHandler) SetPolicy(c *gin.Context) {
+	var req SetRetentionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	policy, err := h.retentionService.SetPolicy(c.Request.Context(), organizationID(c), &models.RetentionPolicy{
+		MessageType:     c.Param("message_type"),
+		Action:          req.Action,
+		RetainDays:      req.RetainDays,
+		MediaRetainDays: req.MediaRetainDays,
+	})
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, policy)
+}
+
+// DeletePolicy handles DELETE /api/v1/retention/policies/:message_type
+func (h *RetentionHandler) DeletePolicy(c *gin.Context) {
+	if err := h.retentionService.DeletePolicy(c.Request.Context(), organizationID(c), c.Param("message_type")); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.NoContentJSON(c)
+}
+
+// Report handles GET /api/v1/retention/report, a dry run of the policies
+func (h *RetentionHandler) Report(c *gin.Context) {
+	report, err := h.retentionService.Report(c.Request.Context(), organizationID(c))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, report)
+}
+
+// Run handles POST /api/v1/retention/run
+func (h *RetentionHandler) Run(c *gin.Context) {
+	report, err := h.retentionService.Run(c.Request.Context(), organizationID(c))
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, report)
+}
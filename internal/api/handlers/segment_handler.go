@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SegmentHandler handles segment-related requests
+type SegmentHandler struct {
+	segmentService *services.SegmentService
+}
+
+// NewSegmentHandler creates a new segment handler
+func NewSegmentHandler(segmentService *services.SegmentService) *SegmentHandler {
+	return &SegmentHandler{
+		segmentService: segmentService,
+	}
+}
+
+// CreateSegment handles POST /api/v1/segments
+func (h *SegmentHandler) CreateSegment(c *gin.Context) {
+	var segment models.Segment
+	if err := c.ShouldBindJSON(&segment); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	if err := h.segmentService.CreateSegment(c.Request.Context(), organizationID(c), &segment); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.CreatedJSON(c, segment)
+}
+
+// GetSegment handles GET /api/v1/segments/:id
+func (h *SegmentHandler) GetSegment(c *gin.Context) {
+	segment, err := h.segmentService.GetSegment(c.Request.Context(), organizationID(c), c.Param("id"), true)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, segment)
+}
+
+// ListSegments handles GET /api/v1/segments
+func (h *SegmentHandler) ListSegments(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	pagination := utils.NewPagination(limit, offset)
+
+	segments, err := h.segmentService.ListSegments(c.Request.Context(), organizationID(c), pagination)
+	if err != nil {
+		utils.ErrorJSON(c, errors.NewInternalError(err))
+		return
+	}
+
+	utils.ListJSON(c, segments, pagination)
+}
+
+// UpdateSegment handles PATCH /api/v1/segments/:id
+func (h *SegmentHandler) UpdateSegment(c *gin.Context) {
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		utils.ErrorJSON(c, errors.NewBadRequest("Invalid request body: "+err.Error()))
+		return
+	}
+
+	segment, err := h.segmentService.UpdateSegment(c.Request.Context(), organizationID(c), c.Param("id"), updates)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.SuccessJSON(c, 200, segment)
+}
+
+// DeleteSegment handles DELETE /api/v1/segments/:id
+func (h *SegmentHandler) DeleteSegment(c *gin.Context) {
+	if err := h.segmentService.DeleteSegment(c.Request.Context(), organizationID(c), c.Param("id")); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.NoContentJSON(c)
+}
+
+// ListSegmentContacts handles GET /api/v1/segments/:id/contacts
+func (h *SegmentHandler) ListSegmentContacts(c *gin.Context) {
+	pagination := cursorPagination(c, 50)
+
+	filters := make(map[string]interface{})
+	if sort := c.Query("sort"); sort != "" {
+		filters["sort"] = sort
+	}
+	if order := c.Query("order"); order != "" {
+		filters["order"] = order
+	}
+
+	contacts, err := h.segmentService.ListSegmentContacts(c.Request.Context(), organizationID(c), c.Param("id"), filters, pagination)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			utils.ErrorJSON(c, appErr)
+		} else {
+			utils.ErrorJSON(c, errors.NewInternalError(err))
+		}
+		return
+	}
+
+	utils.ListJSON(c, contacts, pagination)
+}
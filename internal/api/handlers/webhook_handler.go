@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"context"
 	"io"
 
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
@@ -14,6 +16,7 @@ import (
 // WebhookHandler handles webhook-related requests
 type WebhookHandler struct {
 	messageService *services.MessageService
+	orgService     *services.OrganizationService
 	verifyToken    string
 	webhookSecret  string
 	logger         *zap.Logger
@@ -22,12 +25,14 @@ type WebhookHandler struct {
 // NewWebhookHandler creates a new webhook handler
 func NewWebhookHandler(
 	messageService *services.MessageService,
+	orgService *services.OrganizationService,
 	verifyToken string,
 	webhookSecret string,
 	logger *zap.Logger,
 ) *WebhookHandler {
 	return &WebhookHandler{
 		messageService: messageService,
+		orgService:     orgService,
 		verifyToken:    verifyToken,
 		webhookSecret:  webhookSecret,
 		logger:         logger,
@@ -40,7 +45,8 @@ func (h *WebhookHandler) VerifyWebhook(c *gin.Context) {
 	token := c.Query("hub.verify_token")
 	challenge := c.Query("hub.challenge")
 
-	if mode == "subscribe" && token == h.verifyToken {
+	validToken := (h.verifyToken != "" && token == h.verifyToken) || h.orgService.IsWebhookVerifyToken(c.Request.Context(), token)
+	if mode == "subscribe" && validToken {
 		h.logger.Info("Webhook verified successfully")
 		c.String(200, challenge)
 		return
@@ -63,14 +69,6 @@ func (h *WebhookHandler) ReceiveWebhook(c *gin.Context) {
 		return
 	}
 
-	// Verify signature
-	signature := c.GetHeader("X-Hub-Signature-256")
-	if signature != "" && !whatsapp.VerifySignature(body, signature, h.webhookSecret) {
-		h.logger.Warn("Webhook signature verification failed")
-		utils.ErrorJSON(c, errors.NewUnauthorized("Invalid signature"))
-		return
-	}
-
 	// Parse webhook payload
 	payload, err := whatsapp.ParseWebhook(body)
 	if err != nil {
@@ -79,13 +77,40 @@ func (h *WebhookHandler) ReceiveWebhook(c *gin.Context) {
 		return
 	}
 
+	// Resolve the organization of every entry by WABA ID
+	orgs := h.resolveOrganizations(c.Request.Context(), payload)
+
+	// Verify signature against the secret of each organization in the
+	// payload. Unsigned payloads are only accepted while no secret is set up,
+	// since the WABA ID in the body alone picks the tenant.
+	signature := c.GetHeader("X-Hub-Signature-256")
+	if signature == "" && h.requiresSignature(orgs) {
+		h.logger.Warn("Rejecting unsigned webhook")
+		utils.ErrorJSON(c, errors.NewUnauthorized("Missing signature"))
+		return
+	}
+	if signature != "" && !h.verifySignature(body, signature, orgs) {
+		h.logger.Warn("Webhook signature verification failed")
+		utils.ErrorJSON(c, errors.NewUnauthorized("Invalid signature"))
+		return
+	}
+
 	// Process message events
 	messageEvents, err := whatsapp.ParseMessageEvent(payload)
 	if err != nil {
 		h.logger.Error("Failed to parse message events", zap.Error(err))
 	} else {
 		for _, event := range messageEvents {
-			if err := h.messageService.ProcessIncomingMessage(event); err != nil {
+			org := orgs[event.BusinessAccountID]
+			if org == nil {
+				h.logger.Warn("Dropping message for unknown organization",
+					zap.String("business_account_id", event.BusinessAccountID),
+					zap.String("phone_number_id", event.PhoneNumberID),
+					zap.String("message_id", event.MessageID),
+				)
+				continue
+			}
+			if err := h.messageService.ProcessIncomingMessage(c.Request.Context(), org.ID, event); err != nil {
 				h.logger.Error("Failed to process incoming message",
 					zap.Error(err),
 					zap.String("message_id", event.MessageID),
@@ -100,7 +125,19 @@ func (h *WebhookHandler) ReceiveWebhook(c *gin.Context) {
 		h.logger.Error("Failed to parse status events", zap.Error(err))
 	} else {
 		for _, event := range statusEvents {
-			if err := h.messageService.UpdateMessageStatus(event.MessageID, event.Status); err != nil {
+			org := orgs[event.BusinessAccountID]
+			if org == nil {
+				h.logger.Warn("Dropping status for unknown organization",
+					zap.String("business_account_id", event.BusinessAccountID),
+					zap.String("phone_number_id", event.PhoneNumberID),
+					zap.String("message_id", event.MessageID),
+				)
+				continue
+			}
+			if event.ErrorCode != 0 {
+				h.orgService.ReportDeliveryError(event.PhoneNumberID, event.RecipientID, event.ErrorCode)
+			}
+			if err := h.messageService.UpdateMessageStatus(c.Request.Context(), org.ID, event); err != nil {
 				h.logger.Error("Failed to update message status",
 					zap.Error(err),
 					zap.String("message_id", event.MessageID),
@@ -112,3 +149,61 @@ func (h *WebhookHandler) ReceiveWebhook(c *gin.Context) {
 	// Return success
 	c.JSON(200, gin.H{"status": "received"})
 }
+
+// resolveOrganizations maps the WABA ID of each webhook entry to its organization
+func (h *WebhookHandler) resolveOrganizations(ctx context.Context, payload *whatsapp.WebhookPayload) map[string]*models.Organization {
+	orgs := make(map[string]*models.Organization)
+	for _, entry := range payload.Entry {
+		if _, seen := orgs[entry.ID]; seen {
+			continue
+		}
+		phoneNumberID := ""
+		if len(entry.Changes) > 0 {
+			phoneNumberID = entry.Changes[0].Value.Metadata.PhoneNumberID
+		}
+		org, err := h.orgService.ResolveWebhookOrganization(ctx, entry.ID, phoneNumberID)
+		if err != nil {
+			orgs[entry.ID] = nil
+			continue
+		}
+		orgs[entry.ID] = org
+	}
+	return orgs
+}
+
+// requiresSignature returns true if the global webhook secret or the secret
+// of any organization in the payload is set
+func (h *WebhookHandler) requiresSignature(orgs map[string]*models.Organization) bool {
+	if h.webhookSecret != "" {
+		return true
+	}
+	for _, org := range orgs {
+		if org != nil && org.WebhookSecret != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// verifySignature checks the signature with the webhook secret of every
+// organization in the payload, falling back to the global secret
+func (h *WebhookHandler) verifySignature(body []byte, signature string, orgs map[string]*models.Organization) bool {
+	secrets := make(map[string]bool)
+	for _, org := range orgs {
+		if org != nil && org.WebhookSecret != "" {
+			secrets[org.WebhookSecret] = true
+		} else {
+			secrets[h.webhookSecret] = true
+		}
+	}
+	if len(secrets) == 0 {
+		secrets[h.webhookSecret] = true
+	}
+
+	for secret := range secrets {
+		if !whatsapp.VerifySignature(body, signature, secret) {
+			return false
+		}
+	}
+	return true
+}
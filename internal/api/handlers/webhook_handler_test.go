@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type webhookHandlerFixture struct {
+	messages *repositories.MessageRepository
+	router   *gin.Engine
+}
+
+// newWebhookHandlerFixture serves the webhook with the given global secret.
+// Organization org_a (WABA waba_a) signs with secret_a, org_b (waba_b) with
+// secret_b and org_c (waba_c) has no secret of its own.
+func newWebhookHandlerFixture(t *testing.T, globalSecret string) *webhookHandlerFixture {
+	t.Helper()
+	db := openTestDB(t)
+	orgRepo := repositories.NewOrganizationRepository(db)
+	auth := services.NewAuthService(repositories.NewAPIKeyRepository(db), orgRepo, "salt", time.Minute)
+	t.Cleanup(func() { auth.Close() })
+	orgService := services.NewOrganizationService(orgRepo, whatsapp.NewClientPool(whatsapp.Config{Logger: zap.NewNop()}), auth)
+
+	for _, org := range []*models.Organization{
+		{ID: "org_a", Name: "A", BusinessAccountID: "waba_a", PhoneNumberID: "phone_a", WebhookSecret: "secret_a"},
+		{ID: "org_b", Name: "B", BusinessAccountID: "waba_b", PhoneNumberID: "phone_b", WebhookSecret: "secret_b"},
+		{ID: "org_c", Name: "C", BusinessAccountID: "waba_c", PhoneNumberID: "phone_c"},
+	} {
+		if err := orgRepo.Create(org); err != nil {
+			t.Fatalf("create organization: %v", err)
+		}
+	}
+
+	f := &webhookHandlerFixture{messages: repositories.NewMessageRepository(db)}
+	messageService := services.NewMessageService(
+		f.messages,
+		repositories.NewContactRepository(db),
+		repositories.NewReactionRepository(db),
+		services.NewAttributeService(repositories.NewAttributeRepository(db)),
+		orgService,
+		zap.NewNop(),
+	)
+	handler := NewWebhookHandler(messageService, orgService, "", globalSecret, zap.NewNop())
+	gin.SetMode(gin.TestMode)
+	f.router = gin.New()
+	f.router.POST("/webhooks/whatsapp", handler.ReceiveWebhook)
+	return f
+}
+
+// messagePayload is a webhook with one inbound text message for a WABA
+func messagePayload(wabaID, phoneNumberID, messageID string) []byte {
+	return []byte(fmt.Sprintf(`{"object":"whatsapp_business_account","entry":[{"id":%q,"changes":[{"field":"messages","value":{
+		"messaging_product":"whatsapp","metadata":{"display_phone_number":"15550000000","phone_number_id":%q},
+		"messages":[{"from":"15551234567","id":%q,"timestamp":"1700000000","type":"text","text":{"body":"hi"}}]}}]}]}`,
+		wabaID, phoneNumberID, messageID))
+}
+
+// post delivers body, signed with secret unless it is empty
+func (f *webhookHandlerFixture) post(body []byte, secret string) int {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/whatsapp", bytes.NewReader(body))
+	if secret != "" {
+		req.Header.Set("X-Hub-Signature-256", utils.ComputeHMAC(body, []byte(secret)))
+	}
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func (f *webhookHandlerFixture) stored(orgID, messageID string) bool {
+	found, err := f.messages.ForOrganization(orgID).FindByWhatsAppMessageIDs([]string{messageID})
+	return err == nil && len(found) == 1
+}
+
+func TestWebhookRejectsUnsignedAndForeignPayloads(t *testing.T) {
+	f := newWebhookHandlerFixture(t, "")
+
+	if code := f.post(messagePayload("waba_a", "phone_a", "wamid.unsigned"), ""); code != http.StatusUnauthorized {
+		t.Errorf("an unsigned payload for an organization with a secret should be rejected, got %d", code)
+	}
+	if f.stored("org_a", "wamid.unsigned") {
+		t.Error("the unsigned message was stored")
+	}
+
+	// Signed by tenant A but addressed to tenant B
+	if code := f.post(messagePayload("waba_b", "phone_b", "wamid.foreign"), "secret_a"); code != http.StatusUnauthorized {
+		t.Errorf("a payload signed with another organization's secret should be rejected, got %d", code)
+	}
+	if f.stored("org_b", "wamid.foreign") {
+		t.Error("the foreign message was stored")
+	}
+
+	if code := f.post(messagePayload("waba_a", "phone_a", "wamid.signed"), "secret_a"); code != http.StatusOK {
+		t.Fatalf("a correctly signed payload should be accepted, got %d", code)
+	}
+	if !f.stored("org_a", "wamid.signed") || f.stored("org_b", "wamid.signed") {
+		t.Error("the signed message should be stored for its organization only")
+	}
+
+	// Without any secret configured, unsigned payloads are still accepted
+	if code := f.post(messagePayload("waba_c", "phone_c", "wamid.open"), ""); code != http.StatusOK || !f.stored("org_c", "wamid.open") {
+		t.Errorf("an unsigned payload without any secret configured should be accepted, got %d", code)
+	}
+}
+
+func TestWebhookGlobalSecretRequiresSignature(t *testing.T) {
+	f := newWebhookHandlerFixture(t, "global")
+
+	if code := f.post(messagePayload("waba_c", "phone_c", "wamid.unsigned"), ""); code != http.StatusUnauthorized {
+		t.Errorf("an unsigned payload should be rejected once the global secret is set, got %d", code)
+	}
+	if code := f.post(messagePayload("waba_c", "phone_c", "wamid.signed"), "global"); code != http.StatusOK {
+		t.Errorf("a payload signed with the global secret should be accepted, got %d", code)
+	}
+	if !f.stored("org_c", "wamid.signed") || f.stored("org_c", "wamid.unsigned") {
+		t.Error("only the signed message should be stored")
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"strings"
 
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
@@ -36,7 +37,7 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 		}
 
 		// Validate API key
-		keyInfo, err := authService.ValidateAPIKey(apiKey)
+		keyInfo, err := authService.ValidateAPIKey(c.Request.Context(), apiKey)
 		if err != nil {
 			utils.ErrorJSON(c, err.(*errors.AppError))
 			c.Abort()
@@ -46,6 +47,43 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 		// Store API key info in context
 		c.Set("api_key", keyInfo)
 		c.Set("api_key_id", keyInfo.ID)
+		c.Set("organization_id", keyInfo.OrganizationID)
+
+		c.Next()
+	}
+}
+
+// RequirePermission rejects requests whose API key lacks the given permission
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keyInfo, exists := c.Get("api_key")
+		if !exists {
+			utils.ErrorJSON(c, errors.NewUnauthorized(""))
+			c.Abort()
+			return
+		}
+
+		if !keyInfo.(*models.APIKey).HasPermission(permission) {
+			utils.ErrorJSON(c, errors.NewForbidden("API key lacks the required permission").
+				WithDetail("required_permission", permission))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePlatformOrganization rejects requests whose API key does not belong
+// to the default organization, which administers the other tenants. A
+// tenant's own keys never grant access, whatever their permissions.
+func RequirePlatformOrganization() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("organization_id") != models.DefaultOrganizationID {
+			utils.ErrorJSON(c, errors.NewForbidden("Only API keys of the platform organization can administer organizations"))
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -41,7 +41,13 @@ func LoggingMiddleware(logger *zap.Logger) gin.HandlerFunc {
 			fields = append(fields, zap.String("api_key_id", apiKeyID.(string)))
 		}
 
-		if status >= 500 {
+		// Handlers record errors found after the response started, like a
+		// failed export
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("errors", c.Errors.String()))
+		}
+
+		if status >= 500 || len(c.Errors) > 0 {
 			logger.Error("Request failed", fields...)
 		} else if status >= 400 {
 			logger.Warn("Request error", fields...)
@@ -1,72 +1,128 @@
 package middleware
 
 import (
-	"sync"
+	"context"
+	"math"
+	"strconv"
 	"time"
 
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/ratelimit"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
+	"go.uber.org/zap"
 )
 
-// RateLimiter manages rate limiting
-type RateLimiter struct {
-	limiters map[string]*rate.Limiter
-	mu       sync.RWMutex
-	rate     rate.Limit
-	burst    int
-}
+// RateLimitMiddleware applies per-minute rate limits per API key. Keys
+// without a limit of their own get defaultPerMinute.
+func RateLimitMiddleware(limiter *ratelimit.Limiter, defaultPerMinute int, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject := "ip:" + c.ClientIP()
+		limit := defaultPerMinute
+		if keyInfo, exists := c.Get("api_key"); exists {
+			apiKey := keyInfo.(*models.APIKey)
+			subject = "key:" + apiKey.ID
+			if apiKey.RateLimitPerMinute > 0 {
+				limit = apiKey.RateLimitPerMinute
+			}
+		}
 
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(requestsPerMinute int) *RateLimiter {
-	return &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rate:     rate.Limit(requestsPerMinute) / 60, // Convert to per second
-		burst:    requestsPerMinute / 10,             // 10% burst capacity
-	}
-}
+		result, err := limiter.Allow(c.Request.Context(), subject, ratelimit.Minute, limit)
+		if err != nil {
+			// Fail open: an unavailable counter store must not take the API down
+			logger.Warn("Rate limit check failed", zap.String("subject", subject), zap.Error(err))
+			c.Next()
+			return
+		}
 
-// getLimiter gets or creates a limiter for a key
-func (rl *RateLimiter) getLimiter(key string) *rate.Limiter {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
+		setLimitHeaders(c, "X-RateLimit", result)
+		if !result.Allowed {
+			rejectLimited(c, result, errors.NewRateLimitError())
+			return
+		}
 
-	limiter, exists := rl.limiters[key]
-	if !exists {
-		limiter = rate.NewLimiter(rl.rate, rl.burst)
-		rl.limiters[key] = limiter
+		c.Next()
 	}
-
-	return limiter
 }
 
-// RateLimitMiddleware applies rate limiting per API key
-func RateLimitMiddleware(requestsPerMinute int) gin.HandlerFunc {
-	limiter := NewRateLimiter(requestsPerMinute)
-
+// SendQuotaMiddleware enforces the daily and monthly send quotas of the
+// calling API key. Only accepted sends count: both quotas are claimed
+// before the request goes on and given back if either is exhausted or the
+// send fails. The middleware belongs on routes that send messages.
+func SendQuotaMiddleware(limiter *ratelimit.Limiter, logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get API key ID from context (set by auth middleware)
-		apiKeyID, exists := c.Get("api_key_id")
+		keyInfo, exists := c.Get("api_key")
 		if !exists {
-			// If not authenticated, use IP as key
-			apiKeyID = c.ClientIP()
+			c.Next()
+			return
 		}
+		apiKey := keyInfo.(*models.APIKey)
 
-		key := apiKeyID.(string)
-		rateLimiter := limiter.getLimiter(key)
+		quotas := []struct {
+			window ratelimit.Window
+			limit  int
+			header string
+		}{
+			{ratelimit.Day, apiKey.DailyQuota, "X-Quota-Daily"},
+			{ratelimit.Month, apiKey.MonthlyQuota, "X-Quota-Monthly"},
+		}
+
+		// The refund must go through even if the request timed out
+		ctx := context.WithoutCancel(c.Request.Context())
+		claimed := make(map[string]ratelimit.Result)
+		refund := func() {
+			for _, result := range claimed {
+				if err := limiter.Refund(ctx, result); err != nil {
+					logger.Warn("Quota refund failed", zap.String("api_key_id", apiKey.ID), zap.Error(err))
+				}
+			}
+		}
 
-		if !rateLimiter.Allow() {
-			// Set rate limit headers
-			c.Header("X-RateLimit-Limit", string(rune(requestsPerMinute)))
-			c.Header("X-RateLimit-Remaining", "0")
-			c.Header("X-RateLimit-Reset", string(rune(time.Now().Add(time.Minute).Unix())))
+		for _, quota := range quotas {
+			if quota.limit <= 0 {
+				continue
+			}
 
-			utils.ErrorJSON(c, errors.NewRateLimitError())
-			c.Abort()
-			return
+			result, err := limiter.Allow(ctx, "quota:"+apiKey.ID, quota.window, quota.limit)
+			if err != nil {
+				logger.Warn("Quota check failed", zap.String("api_key_id", apiKey.ID), zap.Error(err))
+				continue
+			}
+			claimed[quota.header] = result
+
+			if !result.Allowed {
+				refund()
+				setLimitHeaders(c, quota.header, result)
+				rejectLimited(c, result, errors.NewQuotaExceededError(quota.window.String()))
+				return
+			}
 		}
 
+		for header, result := range claimed {
+			setLimitHeaders(c, header, result)
+		}
 		c.Next()
+
+		if c.Writer.Status() >= 400 {
+			refund()
+		}
 	}
 }
+
+// setLimitHeaders sets the <prefix>-Limit, -Remaining and -Reset headers.
+// Reset is a Unix timestamp in seconds.
+func setLimitHeaders(c *gin.Context, prefix string, result ratelimit.Result) {
+	c.Header(prefix+"-Limit", strconv.Itoa(result.Limit))
+	c.Header(prefix+"-Remaining", strconv.Itoa(result.Remaining))
+	c.Header(prefix+"-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+}
+
+// rejectLimited aborts the request with 429 and a Retry-After header
+func rejectLimited(c *gin.Context, result ratelimit.Result, appErr *errors.AppError) {
+	retryAfter := int(math.Ceil(result.RetryAfter(time.Now()).Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+	utils.ErrorJSON(c, appErr.WithDetail("retry_after", retryAfter))
+	c.Abort()
+}
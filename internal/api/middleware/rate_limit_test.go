@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// quotaRouter serves a send route behind SendQuotaMiddleware for apiKey. The
+// route answers with the status *status holds.
+func quotaRouter(limiter *ratelimit.Limiter, apiKey *models.APIKey, status *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/send", func(c *gin.Context) {
+		c.Set("api_key", apiKey)
+	}, SendQuotaMiddleware(limiter, zap.NewNop()), func(c *gin.Context) {
+		c.Status(*status)
+	})
+	return router
+}
+
+func send(router *gin.Engine) int {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/send", nil))
+	return rec.Code
+}
+
+func TestSendQuotaRejectionCountsNothing(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore())
+	apiKey := &models.APIKey{ID: "key_1", APIKeyLimits: models.APIKeyLimits{DailyQuota: 2, MonthlyQuota: 1}}
+	status := http.StatusOK
+	router := quotaRouter(limiter, apiKey, &status)
+
+	if code := send(router); code != http.StatusOK {
+		t.Fatalf("first send: %d", code)
+	}
+	if code := send(router); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the monthly quota to reject, got %d", code)
+	}
+
+	// The rejected send must not have used up the daily quota
+	result, _ := limiter.Allow(context.Background(), "quota:key_1", ratelimit.Day, 2)
+	if !result.Allowed {
+		t.Error("a send rejected by the monthly quota was counted against the daily quota")
+	}
+}
+
+func TestSendQuotaCountsOnlyAcceptedSends(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore())
+	apiKey := &models.APIKey{ID: "key_1", APIKeyLimits: models.APIKeyLimits{DailyQuota: 1}}
+	status := http.StatusBadRequest
+	router := quotaRouter(limiter, apiKey, &status)
+
+	for i := 0; i < 3; i++ {
+		if code := send(router); code != http.StatusBadRequest {
+			t.Fatalf("failed send %d: %d", i, code)
+		}
+	}
+
+	status = http.StatusOK
+	if code := send(router); code != http.StatusOK {
+		t.Fatalf("failed sends should not use up the quota, got %d", code)
+	}
+	if code := send(router); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the daily quota to reject, got %d", code)
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -14,8 +15,10 @@ func RequestIDMiddleware() gin.HandlerFunc {
 			requestID = uuid.New().String()
 		}
 
-		// Set request ID in context and response header
+		// Set request ID in context and response header. The request context
+		// carries it on to services and the WhatsApp client.
 		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), requestID))
 		c.Writer.Header().Set("X-Request-ID", requestID)
 
 		c.Next()
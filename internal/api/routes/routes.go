@@ -1,8 +1,12 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/api/handlers"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/api/middleware"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/ratelimit"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -13,10 +17,23 @@ func SetupRoutes(
 	router *gin.Engine,
 	messageHandler *handlers.MessageHandler,
 	contactHandler *handlers.ContactHandler,
+	segmentHandler *handlers.SegmentHandler,
+	attributeHandler *handlers.AttributeHandler,
+	contactImportHandler *handlers.ContactImportHandler,
+	contactMergeHandler *handlers.ContactMergeHandler,
+	contactBlockHandler *handlers.ContactBlockHandler,
+	contactDataHandler *handlers.ContactDataHandler,
+	auditLogHandler *handlers.AuditLogHandler,
+	retentionHandler *handlers.RetentionHandler,
 	templateHandler *handlers.TemplateHandler,
 	webhookHandler *handlers.WebhookHandler,
+	organizationHandler *handlers.OrganizationHandler,
+	apiKeyHandler *handlers.APIKeyHandler,
 	healthHandler *handlers.HealthHandler,
 	authService *services.AuthService,
+	rateLimiter *ratelimit.Limiter,
+	defaultRateLimit int,
+	requestTimeout time.Duration,
 	logger *zap.Logger,
 ) {
 	// Global middleware
@@ -24,6 +41,7 @@ func SetupRoutes(
 	router.Use(middleware.LoggingMiddleware(logger))
 	router.Use(middleware.RecoveryMiddleware(logger))
 	router.Use(middleware.CORSMiddleware())
+	router.Use(middleware.TimeoutMiddleware(requestTimeout))
 
 	// Public routes (no auth required)
 	router.GET("/health", healthHandler.HealthCheck)
@@ -38,34 +56,106 @@ func SetupRoutes(
 	// API v1 routes (auth required)
 	v1 := router.Group("/api/v1")
 	v1.Use(middleware.AuthMiddleware(authService))
-	v1.Use(middleware.RateLimitMiddleware(1000)) // 1000 requests per minute
+	v1.Use(middleware.RateLimitMiddleware(rateLimiter, defaultRateLimit, logger))
 	{
 		// Messages
 		messages := v1.Group("/messages")
 		{
-			messages.POST("", messageHandler.SendMessage)
-			messages.GET("", messageHandler.ListMessages)
-			messages.GET("/search", messageHandler.SearchMessages)
-			messages.GET("/:id", messageHandler.GetMessage)
+			messages.POST("", middleware.RequirePermission(models.PermissionMessagesSend), middleware.SendQuotaMiddleware(rateLimiter, logger), messageHandler.SendMessage)
+			messages.GET("", middleware.RequirePermission(models.PermissionMessagesRead), messageHandler.ListMessages)
+			messages.GET("/search", middleware.RequirePermission(models.PermissionMessagesRead), messageHandler.SearchMessages)
+			messages.GET("/:id", middleware.RequirePermission(models.PermissionMessagesRead), messageHandler.GetMessage)
+			messages.POST("/:id/read", middleware.RequirePermission(models.PermissionMessagesSend), messageHandler.MarkAsRead)
 		}
 
 		// Contacts
 		contacts := v1.Group("/contacts")
 		{
-			contacts.GET("", contactHandler.ListContacts)
-			contacts.GET("/search", contactHandler.SearchContacts)
-			contacts.GET("/:id", contactHandler.GetContact)
-			contacts.PATCH("/:id", contactHandler.UpdateContact)
+			contacts.GET("", middleware.RequirePermission(models.PermissionContactsRead), contactHandler.ListContacts)
+			contacts.GET("/search", middleware.RequirePermission(models.PermissionContactsRead), contactHandler.SearchContacts)
+			contacts.GET("/export", middleware.RequirePermission(models.PermissionContactsRead), contactHandler.ExportContacts)
+			contacts.POST("/import", middleware.RequirePermission(models.PermissionContactsWrite), contactImportHandler.ImportContacts)
+			contacts.GET("/imports", middleware.RequirePermission(models.PermissionContactsRead), contactImportHandler.ListImports)
+			contacts.GET("/imports/:id", middleware.RequirePermission(models.PermissionContactsRead), contactImportHandler.GetImport)
+			contacts.GET("/duplicates", middleware.RequirePermission(models.PermissionContactsRead), contactMergeHandler.FindDuplicates)
+			contacts.GET("/tags", middleware.RequirePermission(models.PermissionContactsRead), contactHandler.ListTags)
+			contacts.POST("/tags", middleware.RequirePermission(models.PermissionContactsWrite), contactHandler.TagContacts)
+			contacts.GET("/attributes", middleware.RequirePermission(models.PermissionContactsRead), attributeHandler.ListAttributes)
+			contacts.POST("/attributes", middleware.RequirePermission(models.PermissionContactsWrite), attributeHandler.CreateAttribute)
+			contacts.GET("/attributes/:id", middleware.RequirePermission(models.PermissionContactsRead), attributeHandler.GetAttribute)
+			contacts.PATCH("/attributes/:id", middleware.RequirePermission(models.PermissionContactsWrite), attributeHandler.UpdateAttribute)
+			contacts.DELETE("/attributes/:id", middleware.RequirePermission(models.PermissionContactsWrite), attributeHandler.DeleteAttribute)
+			contacts.GET("/:id", middleware.RequirePermission(models.PermissionContactsRead), contactHandler.GetContact)
+			contacts.GET("/:id/messages", middleware.RequirePermission(models.PermissionContactsRead), middleware.RequirePermission(models.PermissionMessagesRead), contactHandler.GetContactMessages)
+			contacts.GET("/:id/export", middleware.RequirePermission(models.PermissionContactsRead), middleware.RequirePermission(models.PermissionMessagesRead), contactDataHandler.ExportContactData)
+			contacts.PATCH("/:id", middleware.RequirePermission(models.PermissionContactsWrite), contactHandler.UpdateContact)
+			contacts.DELETE("/:id", middleware.RequirePermission(models.PermissionContactsWrite), contactDataHandler.DeleteContact)
+			contacts.POST("/:id/merge", middleware.RequirePermission(models.PermissionContactsWrite), contactMergeHandler.MergeContacts)
+			contacts.POST("/:id/block", middleware.RequirePermission(models.PermissionContactsWrite), contactBlockHandler.BlockContact)
+			contacts.DELETE("/:id/block", middleware.RequirePermission(models.PermissionContactsWrite), contactBlockHandler.UnblockContact)
+			contacts.POST("/:id/tags", middleware.RequirePermission(models.PermissionContactsWrite), contactHandler.AddTags)
+			contacts.DELETE("/:id/tags/:tag", middleware.RequirePermission(models.PermissionContactsWrite), contactHandler.RemoveTag)
+		}
+
+		// Segments
+		segments := v1.Group("/segments")
+		{
+			segments.GET("", middleware.RequirePermission(models.PermissionContactsRead), segmentHandler.ListSegments)
+			segments.POST("", middleware.RequirePermission(models.PermissionContactsWrite), segmentHandler.CreateSegment)
+			segments.GET("/:id", middleware.RequirePermission(models.PermissionContactsRead), segmentHandler.GetSegment)
+			segments.GET("/:id/contacts", middleware.RequirePermission(models.PermissionContactsRead), segmentHandler.ListSegmentContacts)
+			segments.PATCH("/:id", middleware.RequirePermission(models.PermissionContactsWrite), segmentHandler.UpdateSegment)
+			segments.DELETE("/:id", middleware.RequirePermission(models.PermissionContactsWrite), segmentHandler.DeleteSegment)
 		}
 
 		// Templates
 		templates := v1.Group("/templates")
 		{
-			templates.GET("", templateHandler.ListTemplates)
-			templates.POST("", templateHandler.CreateTemplate)
-			templates.GET("/:id", templateHandler.GetTemplate)
-			templates.PATCH("/:id", templateHandler.UpdateTemplate)
-			templates.DELETE("/:id", templateHandler.DeleteTemplate)
+			templates.GET("", middleware.RequirePermission(models.PermissionTemplatesRead), templateHandler.ListTemplates)
+			templates.POST("", middleware.RequirePermission(models.PermissionTemplatesAdmin), templateHandler.CreateTemplate)
+			templates.GET("/:id", middleware.RequirePermission(models.PermissionTemplatesRead), templateHandler.GetTemplate)
+			templates.PATCH("/:id", middleware.RequirePermission(models.PermissionTemplatesAdmin), templateHandler.UpdateTemplate)
+			templates.DELETE("/:id", middleware.RequirePermission(models.PermissionTemplatesAdmin), templateHandler.DeleteTemplate)
+		}
+
+		// API keys
+		apiKeys := v1.Group("/api-keys")
+		apiKeys.Use(middleware.RequirePermission(models.PermissionKeysAdmin))
+		{
+			apiKeys.GET("", apiKeyHandler.ListAPIKeys)
+			apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+			apiKeys.GET("/:id", apiKeyHandler.GetAPIKey)
+			apiKeys.PATCH("/:id", apiKeyHandler.UpdateAPIKeyLimits)
+			apiKeys.POST("/:id/rotate", apiKeyHandler.RotateAPIKey)
+			apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+		}
+
+		// Audit log
+		v1.GET("/audit-logs", middleware.RequirePermission(models.PermissionAuditRead), auditLogHandler.ListAuditLogs)
+
+		// Message retention
+		retention := v1.Group("/retention")
+		retention.Use(middleware.RequirePermission(models.PermissionRetentionAdmin))
+		{
+			retention.GET("/policies", retentionHandler.ListPolicies)
+			retention.PUT("/policies/:message_type", retentionHandler.SetPolicy)
+			retention.DELETE("/policies/:message_type", retentionHandler.DeletePolicy)
+			retention.GET("/report", retentionHandler.Report)
+			retention.POST("/run", retentionHandler.Run)
+		}
+
+		// Current organization
+		v1.GET("/organization", organizationHandler.GetCurrentOrganization)
+
+		// Organizations (tenant administration)
+		organizations := v1.Group("/organizations")
+		organizations.Use(middleware.RequirePlatformOrganization(), middleware.RequirePermission(models.PermissionOrganizationsAdmin))
+		{
+			organizations.GET("", organizationHandler.ListOrganizations)
+			organizations.POST("", organizationHandler.CreateOrganization)
+			organizations.GET("/:id", organizationHandler.GetOrganization)
+			organizations.PATCH("/:id", organizationHandler.UpdateOrganization)
+			organizations.DELETE("/:id", organizationHandler.DeleteOrganization)
 		}
 	}
 }
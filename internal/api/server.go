@@ -3,14 +3,17 @@ package api
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/api/handlers"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/api/routes"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/config"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/ratelimit"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/services"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/storage"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -19,10 +22,14 @@ import (
 
 // Server represents the API server
 type Server struct {
-	router     *gin.Engine
-	httpServer *http.Server
-	config     *config.Config
-	logger     *zap.Logger
+	router           *gin.Engine
+	httpServer       *http.Server
+	cancel           context.CancelFunc
+	authService      *services.AuthService
+	importService    *services.ContactImportService
+	retentionService *services.RetentionService
+	config           *config.Config
+	logger           *zap.Logger
 }
 
 // NewServer creates a new API server
@@ -35,40 +42,84 @@ func NewServer(cfg *config.Config, db *gorm.DB, logger *zap.Logger) (*Server, er
 	// Create Gin router
 	router := gin.New()
 
-	// Initialize WhatsApp client
-	waClient, err := whatsapp.NewClient(whatsapp.Config{
-		APIToken:      cfg.WhatsApp.APIToken,
-		PhoneNumberID: cfg.WhatsApp.PhoneNumberID,
-		APIBaseURL:    cfg.WhatsApp.APIBaseURL,
-		APIVersion:    cfg.WhatsApp.APIVersion,
-		Logger:        logger,
+	// Initialize WhatsApp client pool (one client per tenant phone number)
+	waClients := whatsapp.NewClientPool(whatsapp.Config{
+		APIBaseURL: cfg.WhatsApp.APIBaseURL,
+		APIVersion: cfg.WhatsApp.APIVersion,
+		Logger:     logger,
+		Throughput: whatsapp.GovernorConfig{
+			MessagesPerSecond: cfg.WhatsApp.ThroughputPerSecond,
+			Tier:              cfg.WhatsApp.MessagingTier,
+			MaxWait:           cfg.WhatsApp.MaxSendWait,
+		},
 	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create WhatsApp client: %w", err)
-	}
 
 	// Initialize repositories
+	orgRepo := repositories.NewOrganizationRepository(db)
 	messageRepo := repositories.NewMessageRepository(db)
 	contactRepo := repositories.NewContactRepository(db)
+	reactionRepo := repositories.NewReactionRepository(db)
+	segmentRepo := repositories.NewSegmentRepository(db)
+	attributeRepo := repositories.NewAttributeRepository(db)
+	importRepo := repositories.NewContactImportRepository(db)
+	auditRepo := repositories.NewAuditLogRepository(db)
+	callRepo := repositories.NewCallRepository(db)
+	retentionRepo := repositories.NewRetentionPolicyRepository(db)
 	templateRepo := repositories.NewTemplateRepository(db)
 	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	leaseRepo := repositories.NewLeaseRepository(db)
 
 	// Initialize services
-	messageService := services.NewMessageService(messageRepo, contactRepo, waClient, logger)
-	contactService := services.NewContactService(contactRepo)
+	authService := services.NewAuthService(apiKeyRepo, orgRepo, cfg.Security.APIKeySalt, cfg.Security.APIKeyCacheTTL)
+	orgService := services.NewOrganizationService(orgRepo, waClients, authService)
+	if _, err := orgService.EnsureDefault(context.Background(), services.DefaultOrganizationFromConfig(cfg.WhatsApp)); err != nil {
+		authService.Close()
+		return nil, fmt.Errorf("failed to initialize default organization: %w", err)
+	}
+
+	attributeService := services.NewAttributeService(attributeRepo)
+	messageService := services.NewMessageService(messageRepo, contactRepo, reactionRepo, attributeService, orgService, logger)
+	messageService.SetAutoMarkRead(cfg.WhatsApp.AutoMarkRead)
+	segmentService := services.NewSegmentService(segmentRepo, contactRepo, attributeService)
+	contactService := services.NewContactService(contactRepo, segmentService, attributeService)
+	importService := services.NewContactImportService(importRepo, contactRepo, attributeService, logger)
+	if err := importService.FailInterrupted(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to recover contact imports: %w", err)
+	}
+	mergeService := services.NewContactMergeService(contactRepo, logger)
+	blockService := services.NewContactBlockService(contactRepo, orgService, logger)
+	media := mediaStores(cfg.Storage)
+	var archive *services.MessageArchive
+	if len(media) > 0 {
+		archive = services.NewMessageArchive(media[0])
+	}
+	dataService := services.NewContactDataService(contactRepo, messageRepo, reactionRepo, callRepo, auditRepo, media, archive, logger)
+	retentionService := services.NewRetentionService(retentionRepo, messageRepo, reactionRepo, leaseRepo, media, archive, logger)
+	auditService := services.NewAuditLogService(auditRepo)
+	conversationService := services.NewConversationService(messageRepo, contactRepo, reactionRepo)
 	templateService := services.NewTemplateService(templateRepo)
-	authService := services.NewAuthService(apiKeyRepo)
 
 	// Initialize handlers
 	messageHandler := handlers.NewMessageHandler(messageService)
-	contactHandler := handlers.NewContactHandler(contactService)
+	contactHandler := handlers.NewContactHandler(contactService, conversationService)
+	segmentHandler := handlers.NewSegmentHandler(segmentService)
+	attributeHandler := handlers.NewAttributeHandler(attributeService)
+	contactImportHandler := handlers.NewContactImportHandler(importService)
+	contactMergeHandler := handlers.NewContactMergeHandler(mergeService)
+	contactBlockHandler := handlers.NewContactBlockHandler(blockService)
+	contactDataHandler := handlers.NewContactDataHandler(dataService)
+	auditLogHandler := handlers.NewAuditLogHandler(auditService)
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
 	templateHandler := handlers.NewTemplateHandler(templateService)
 	webhookHandler := handlers.NewWebhookHandler(
 		messageService,
+		orgService,
 		cfg.WhatsApp.WebhookVerifyToken,
 		cfg.WhatsApp.WebhookSecret,
 		logger,
 	)
+	organizationHandler := handlers.NewOrganizationHandler(orgService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(authService)
 	healthHandler := handlers.NewHealthHandler(db)
 
 	// Setup routes
@@ -76,13 +127,30 @@ func NewServer(cfg *config.Config, db *gorm.DB, logger *zap.Logger) (*Server, er
 		router,
 		messageHandler,
 		contactHandler,
+		segmentHandler,
+		attributeHandler,
+		contactImportHandler,
+		contactMergeHandler,
+		contactBlockHandler,
+		contactDataHandler,
+		auditLogHandler,
+		retentionHandler,
 		templateHandler,
 		webhookHandler,
+		organizationHandler,
+		apiKeyHandler,
 		healthHandler,
 		authService,
+		newRateLimiter(cfg.RateLimit, db),
+		cfg.RateLimit.DefaultPerMinute,
+		cfg.Server.RequestTimeout,
 		logger,
 	)
 
+	// Request contexts derive from baseCtx, so cancelling it on shutdown
+	// aborts Graph calls and queries still running after the grace period
+	baseCtx, cancel := context.WithCancel(context.Background())
+
 	// Create HTTP server
 	httpServer := &http.Server{
 		Addr:           fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
@@ -90,16 +158,41 @@ func NewServer(cfg *config.Config, db *gorm.DB, logger *zap.Logger) (*Server, er
 		ReadTimeout:    30 * time.Second,
 		WriteTimeout:   30 * time.Second,
 		MaxHeaderBytes: 1 << 20, // 1 MB
+		BaseContext:    func(net.Listener) context.Context { return baseCtx },
 	}
 
+	// Apply message retention policies in the background
+	retentionService.Start(cfg.Retention.Interval)
+
 	return &Server{
-		router:     router,
-		httpServer: httpServer,
-		config:     cfg,
-		logger:     logger,
+		router:           router,
+		httpServer:       httpServer,
+		cancel:           cancel,
+		authService:      authService,
+		importService:    importService,
+		retentionService: retentionService,
+		config:           cfg,
+		logger:           logger,
 	}, nil
 }
 
+// newRateLimiter creates the rate limiter on the configured counter store
+func newRateLimiter(cfg config.RateLimitConfig, db *gorm.DB) *ratelimit.Limiter {
+	if cfg.Backend == "database" {
+		return ratelimit.NewLimiter(repositories.NewRateLimitRepository(db))
+	}
+	return ratelimit.NewLimiter(ratelimit.NewMemoryStore())
+}
+
+// mediaStores returns the stores media files and call recordings are kept
+// in, media first. Remote storage has no store yet.
+func mediaStores(cfg config.StorageConfig) []storage.MediaStore {
+	if cfg.Type != "local" {
+		return nil
+	}
+	return []storage.MediaStore{storage.NewLocalStore(cfg.MediaPath), storage.NewLocalStore(cfg.RecordingsPath)}
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	s.logger.Info("Starting HTTP server",
@@ -118,10 +211,25 @@ func (s *Server) Start() error {
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down HTTP server...")
 
-	if err := s.httpServer.Shutdown(ctx); err != nil {
-		return fmt.Errorf("server shutdown failed: %w", err)
+	err := s.httpServer.Shutdown(ctx)
+	// Cancel requests that outlived the shutdown timeout. The services below
+	// are stopped even then so their state is not lost.
+	s.cancel()
+
+	// Stop running imports; they are recorded as interrupted
+	s.importService.Close()
+
+	// Stop retention runs between batches
+	s.retentionService.Close()
+
+	// Write API key usage collected since the last flush
+	if err := s.authService.Close(); err != nil {
+		s.logger.Error("Failed to flush API key usage", zap.Error(err))
 	}
 
+	if err != nil {
+		return fmt.Errorf("server shutdown failed: %w", err)
+	}
 	s.logger.Info("HTTP server stopped")
 	return nil
 }
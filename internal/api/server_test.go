@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/config"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/database"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"go.uber.org/zap"
+	"gorm.io/gorm/logger"
+)
+
+func TestShutdownStopsServicesAfterTimeout(t *testing.T) {
+	db, err := database.NewConnection("sqlite", filepath.Join(t.TempDir(), "server.db"), logger.Silent)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	defer database.CloseConnection(db)
+	if _, err := database.PrepareSchema(db, true); err != nil {
+		t.Fatalf("PrepareSchema: %v", err)
+	}
+
+	cfg := &config.Config{
+		Security:  config.SecurityConfig{APIKeySalt: "salt", APIKeyCacheTTL: time.Minute},
+		RateLimit: config.RateLimitConfig{DefaultPerMinute: 100},
+		Retention: config.RetentionConfig{Interval: time.Hour},
+	}
+	server, err := NewServer(cfg, db, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	ctx := context.Background()
+	apiKey, rawKey, err := server.authService.CreateAPIKey(ctx, models.DefaultOrganizationID, "test", []string{models.PermissionAll}, models.APIKeyLimits{}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/api-keys", nil)
+	req.Header.Set("Authorization", "Bearer "+rawKey)
+	rec := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("authenticated request: %d %s", rec.Code, rec.Body)
+	}
+
+	// A connection that never finishes its request outlives the grace period
+	connected := make(chan struct{}, 1)
+	server.httpServer.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connected <- struct{}{}
+		}
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go server.httpServer.Serve(ln)
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	<-connected
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err == nil {
+		t.Fatal("Shutdown should report the grace period running out")
+	}
+
+	var stored models.APIKey
+	if err := repositories.NewAPIKeyRepository(db).FindByID(apiKey.ID, &stored); err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("the last-used time should be flushed even when the shutdown times out")
+	}
+}
@@ -10,13 +10,15 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	WhatsApp WhatsAppConfig
-	Security SecurityConfig
-	Logging  LoggingConfig
-	Metrics  MetricsConfig
-	Storage  StorageConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	WhatsApp  WhatsAppConfig
+	Security  SecurityConfig
+	RateLimit RateLimitConfig
+	Logging   LoggingConfig
+	Metrics   MetricsConfig
+	Storage   StorageConfig
+	Retention RetentionConfig
 }
 
 // ServerConfig holds server configuration
@@ -26,6 +28,7 @@ type ServerConfig struct {
 	Environment     string // development, staging, production
 	BaseURL         string
 	ShutdownTimeout time.Duration
+	RequestTimeout  time.Duration // deadline for a request's Graph calls and queries
 }
 
 // DatabaseConfig holds database configuration
@@ -41,6 +44,7 @@ type DatabaseConfig struct {
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
 	SQLitePath      string // Path to SQLite database file
+	AutoMigrate     bool   // Apply pending migrations on startup
 }
 
 // WhatsAppConfig holds WhatsApp API configuration
@@ -52,12 +56,31 @@ type WhatsAppConfig struct {
 	WebhookSecret       string
 	APIBaseURL          string
 	APIVersion          string
+
+	// Outbound throughput governor, per phone number
+	ThroughputPerSecond float64       // Messages per second; Meta's default is 80
+	MessagingTier       int           // Unique recipients per 24h; 0 is unlimited
+	MaxSendWait         time.Duration // Longest a send waits for capacity
+
+	// AutoMarkRead marks the customer's latest message as read when we reply
+	AutoMarkRead bool
 }
 
 // SecurityConfig holds security configuration
 type SecurityConfig struct {
 	APIKeySalt    string
 	SessionSecret string
+	// APIKeyCacheTTL is how long verified API keys are cached in memory.
+	// Replicas poll the database every few seconds for keys revoked
+	// elsewhere; if that fails, a revoked key stays valid on other replicas
+	// for up to the TTL, so keep it short.
+	APIKeyCacheTTL time.Duration
+}
+
+// RateLimitConfig holds API rate limiting configuration
+type RateLimitConfig struct {
+	DefaultPerMinute int    // Requests per minute for keys without their own limit
+	Backend          string // memory or database; database shares limits across replicas
 }
 
 // LoggingConfig holds logging configuration
@@ -85,6 +108,11 @@ type StorageConfig struct {
 	RecordingsPath    string
 }
 
+// RetentionConfig holds message retention configuration
+type RetentionConfig struct {
+	Interval time.Duration // How often retention policies are applied
+}
+
 // LoadConfig loads configuration from environment variables and .env file
 func LoadConfig() (*Config, error) {
 	viper.SetConfigName(".env")
@@ -106,6 +134,7 @@ func LoadConfig() (*Config, error) {
 			Environment:     viper.GetString("ENV"),
 			BaseURL:         viper.GetString("SERVER_BASE_URL"),
 			ShutdownTimeout: viper.GetDuration("SERVER_SHUTDOWN_TIMEOUT"),
+			RequestTimeout:  viper.GetDuration("SERVER_REQUEST_TIMEOUT"),
 		},
 		Database: DatabaseConfig{
 			Driver:          viper.GetString("DB_DRIVER"),
@@ -119,6 +148,7 @@ func LoadConfig() (*Config, error) {
 			MaxOpenConns:    viper.GetInt("DB_MAX_OPEN_CONNS"),
 			MaxIdleConns:    viper.GetInt("DB_MAX_IDLE_CONNS"),
 			ConnMaxLifetime: viper.GetDuration("DB_CONN_MAX_LIFETIME"),
+			AutoMigrate:     !viper.IsSet("DB_AUTO_MIGRATE") || viper.GetBool("DB_AUTO_MIGRATE"),
 		},
 		WhatsApp: WhatsAppConfig{
 			APIToken:            viper.GetString("WHATSAPP_ACCESS_TOKEN"),
@@ -128,10 +158,19 @@ func LoadConfig() (*Config, error) {
 			WebhookSecret:       viper.GetString("WHATSAPP_WEBHOOK_SECRET"),
 			APIBaseURL:          viper.GetString("WHATSAPP_API_BASE_URL"),
 			APIVersion:          viper.GetString("WHATSAPP_API_VERSION"),
+			ThroughputPerSecond: viper.GetFloat64("WHATSAPP_THROUGHPUT_PER_SECOND"),
+			MessagingTier:       viper.GetInt("WHATSAPP_MESSAGING_TIER"),
+			MaxSendWait:         viper.GetDuration("WHATSAPP_MAX_SEND_WAIT"),
+			AutoMarkRead:        viper.GetBool("WHATSAPP_AUTO_MARK_READ"),
 		},
 		Security: SecurityConfig{
-			APIKeySalt:    viper.GetString("API_KEY_SALT"),
-			SessionSecret: viper.GetString("SESSION_SECRET"),
+			APIKeySalt:     viper.GetString("API_KEY_SALT"),
+			SessionSecret:  viper.GetString("SESSION_SECRET"),
+			APIKeyCacheTTL: viper.GetDuration("API_KEY_CACHE_TTL"),
+		},
+		RateLimit: RateLimitConfig{
+			DefaultPerMinute: viper.GetInt("RATE_LIMIT_PER_MINUTE"),
+			Backend:          viper.GetString("RATE_LIMIT_BACKEND"),
 		},
 		Logging: LoggingConfig{
 			Level:      viper.GetString("LOG_LEVEL"),
@@ -152,6 +191,9 @@ func LoadConfig() (*Config, error) {
 			MediaPath:      viper.GetString("MEDIA_STORAGE_PATH"),
 			RecordingsPath: viper.GetString("RECORDINGS_STORAGE_PATH"),
 		},
+		Retention: RetentionConfig{
+			Interval: viper.GetDuration("RETENTION_INTERVAL"),
+		},
 	}
 
 	// Set defaults
@@ -179,6 +221,9 @@ func setDefaults(config *Config) {
 	if config.Server.ShutdownTimeout == 0 {
 		config.Server.ShutdownTimeout = 30 * time.Second
 	}
+	if config.Server.RequestTimeout == 0 {
+		config.Server.RequestTimeout = 25 * time.Second
+	}
 
 	if config.Database.Driver == "" {
 		config.Database.Driver = "sqlite" // Default to SQLite for development
@@ -208,6 +253,26 @@ func setDefaults(config *Config) {
 	if config.WhatsApp.APIVersion == "" {
 		config.WhatsApp.APIVersion = "v18.0"
 	}
+	if config.WhatsApp.ThroughputPerSecond == 0 {
+		config.WhatsApp.ThroughputPerSecond = 80
+	}
+	if config.WhatsApp.MaxSendWait == 0 {
+		config.WhatsApp.MaxSendWait = 5 * time.Second
+	}
+
+	if config.Security.APIKeySalt == "" && config.Server.Environment != "production" {
+		config.Security.APIKeySalt = "insecure-development-salt"
+	}
+	if config.Security.APIKeyCacheTTL == 0 {
+		config.Security.APIKeyCacheTTL = time.Minute
+	}
+
+	if config.RateLimit.DefaultPerMinute == 0 {
+		config.RateLimit.DefaultPerMinute = 1000
+	}
+	if config.RateLimit.Backend == "" {
+		config.RateLimit.Backend = "memory"
+	}
 
 	if config.Logging.Level == "" {
 		config.Logging.Level = "info"
@@ -229,6 +294,10 @@ func setDefaults(config *Config) {
 	if config.Storage.RecordingsPath == "" {
 		config.Storage.RecordingsPath = "./storage/recordings"
 	}
+
+	if config.Retention.Interval <= 0 {
+		config.Retention.Interval = time.Hour
+	}
 }
 
 // Validate validates the configuration
@@ -237,6 +306,10 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
+	if c.RateLimit.Backend != "memory" && c.RateLimit.Backend != "database" {
+		return fmt.Errorf("invalid rate limit backend: %s", c.RateLimit.Backend)
+	}
+
 	if c.Server.Environment == "production" {
 		if c.WhatsApp.APIToken == "" {
 			return fmt.Errorf("WHATSAPP_ACCESS_TOKEN is required in production")
@@ -247,6 +320,9 @@ func (c *Config) Validate() error {
 		if c.Database.Password == "" {
 			return fmt.Errorf("DB_PASSWORD is required in production")
 		}
+		if c.Security.APIKeySalt == "" {
+			return fmt.Errorf("API_KEY_SALT is required in production")
+		}
 	}
 
 	return nil
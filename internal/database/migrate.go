@@ -1,102 +1,450 @@
 package database
 
 import (
+	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
 	"gorm.io/gorm"
 )
 
-// AutoMigrate runs auto migrations for all models
-func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&models.Message{},
-		&models.Contact{},
-		&models.Template{},
-		&models.APIKey{},
-		&models.Call{},
-		&models.Transcript{},
-		&models.TranscriptSegment{},
-	)
-}
-
-// DropAllTables drops all tables (use with caution!)
-func DropAllTables(db *gorm.DB) error {
-	return db.Migrator().DropTable(
-		&models.Message{},
-		&models.Contact{},
-		&models.Template{},
-		&models.APIKey{},
-		&models.Call{},
-		&models.Transcript{},
-		&models.TranscriptSegment{},
-	)
-}
-
-// CreateIndexes creates custom indexes
-func CreateIndexes(db *gorm.DB) error {
-	// Messages indexes
-	if err := db.Exec(`
-		CREATE INDEX IF NOT EXISTS idx_messages_phone_timestamp
-		ON messages(from_number, timestamp DESC);
-	`).Error; err != nil {
-		return fmt.Errorf("failed to create messages index: %w", err)
+// Migrations are plain SQL files embedded in the binary, one directory per
+// dialect:
+//
+//	migrations/<dialect>/<version>_<name>.up.sql
+//	migrations/<dialect>/<version>_<name>.down.sql
+//
+// Every dialect must provide the same versions. Applied versions are recorded
+// in the schema_migrations table.
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+var (
+	// ErrSchemaTooNew means the database was migrated by a newer build
+	ErrSchemaTooNew = errors.New("database schema is newer than this build")
+	// ErrPendingMigrations means the database needs `migrate up`
+	ErrPendingMigrations = errors.New("database has pending migrations")
+)
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+const (
+	// migrationLockID is the Postgres advisory lock key held while migrating
+	migrationLockID = 727362
+	// migrationLockTimeout is how long SQLite waits for another migrator
+	migrationLockTimeout = time.Minute
+)
+
+// schemaMigration is a row of the schema_migrations table
+type schemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrator applies and rolls back migrations
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the dialect of the connection
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	migrations, err := LoadMigrations(dialect)
+	if err != nil {
+		return nil, err
 	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
 
-	if err := db.Exec(`
-		CREATE INDEX IF NOT EXISTS idx_messages_status_created
-		ON messages(status, created_at DESC);
-	`).Error; err != nil {
-		return fmt.Errorf("failed to create messages status index: %w", err)
+// LoadMigrations returns the embedded migrations of a dialect, ordered by
+// version
+func LoadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for dialect %q", dialect)
 	}
 
-	// Contacts indexes
-	if err := db.Exec(`
-		CREATE INDEX IF NOT EXISTS idx_contacts_last_message
-		ON contacts(last_message_at DESC NULLS LAST);
-	`).Error; err != nil {
-		return fmt.Errorf("failed to create contacts index: %w", err)
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		versionPart, migrationName, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionPart)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %s", name)
+		}
+
+		sql, err := fs.ReadFile(migrationFiles, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: migrationName}
+			byVersion[version] = m
+		} else if m.Name != migrationName {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, m.Name, migrationName)
+		}
+		if direction == "up" {
+			m.Up = string(sql)
+		} else {
+			m.Down = string(sql)
+		}
 	}
 
-	// Full-text search index for message content
-	if err := db.Exec(`
-		CREATE INDEX IF NOT EXISTS idx_messages_content_search
-		ON messages USING gin(to_tsvector('english', content));
-	`).Error; err != nil {
-		return fmt.Errorf("failed to create full-text search index: %w", err)
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
 	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrations returns the migrations known to this build
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
 
+// Latest returns the newest version known to this build
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Version returns the newest applied version, 0 for an empty database
+func (m *Migrator) Version() (int, error) {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return 0, err
+	}
+	return newestVersion(applied), nil
+}
+
+// Status lists every known migration and whether it has been applied.
+// Applied versions unknown to this build are listed as well.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: row.Version, Name: row.Name, Applied: true, AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Check returns ErrSchemaTooNew when the database has migrations this build
+// does not know about, and ErrPendingMigrations when it is behind
+func (m *Migrator) Check() error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version > m.Latest() {
+		return fmt.Errorf("%w: database is at version %d, this build knows up to %d", ErrSchemaTooNew, version, m.Latest())
+	}
+
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d to apply, starting with %04d_%s", ErrPendingMigrations, len(pending), pending[0].Version, pending[0].Name)
+	}
 	return nil
 }
 
-// CreateTriggers creates database triggers
-func CreateTriggers(db *gorm.DB) error {
-	// Trigger to automatically update updated_at timestamp
+// Pending returns the migrations that have not been applied yet
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+	return m.pending(applied), nil
+}
+
+// pending returns the known migrations missing from applied
+func (m *Migrator) pending(applied map[int]schemaMigration) []Migration {
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// Up applies all pending migrations and returns the ones it applied.
+//
+// Replicas started with auto-migrate may run Up at the same time, so the
+// pending list is read and applied under a lock: a session advisory lock on
+// Postgres, where each migration still runs in its own transaction, and a
+// single BEGIN IMMEDIATE transaction on SQLite, which applies the pending
+// migrations all or nothing.
+func (m *Migrator) Up() ([]Migration, error) {
+	var done []Migration
+	err := m.db.Connection(func(conn *gorm.DB) error {
+		if m.dialect == "postgres" {
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+				return fmt.Errorf("failed to lock migrations: %w", err)
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID)
+
+			var err error
+			done, err = m.up(conn, func(fn func(tx *gorm.DB) error) error {
+				return conn.Transaction(fn)
+			})
+			return err
+		}
+
+		// gorm must not open transactions of its own on the pinned connection
+		conn = conn.Session(&gorm.Session{SkipDefaultTransaction: true})
+		if err := conn.Exec(fmt.Sprintf("PRAGMA busy_timeout = %d", migrationLockTimeout.Milliseconds())).Error; err != nil {
+			return err
+		}
+		if err := conn.Exec("BEGIN IMMEDIATE").Error; err != nil {
+			return fmt.Errorf("failed to lock migrations: %w", err)
+		}
+		applied, err := m.up(conn, func(fn func(tx *gorm.DB) error) error {
+			return fn(conn)
+		})
+		if err != nil {
+			conn.Exec("ROLLBACK")
+			return err
+		}
+		if err := conn.Exec("COMMIT").Error; err != nil {
+			return fmt.Errorf("failed to commit migrations: %w", err)
+		}
+		done = applied
+		return nil
+	})
+	return done, err
+}
+
+// up applies the migrations pending on db, which holds the migration lock,
+// running each one through transaction
+func (m *Migrator) up(db *gorm.DB, transaction func(fn func(tx *gorm.DB) error) error) ([]Migration, error) {
+	applied, err := m.applied(db)
+	if err != nil {
+		return nil, err
+	}
+	if version := newestVersion(applied); version > m.Latest() {
+		return nil, fmt.Errorf("%w: database is at version %d, this build knows up to %d", ErrSchemaTooNew, version, m.Latest())
+	}
+
+	var done []Migration
+	for _, migration := range m.pending(applied) {
+		err := transaction(func(tx *gorm.DB) error {
+			if migration.Version == 1 {
+				if err := adoptLegacyColumns(tx, m.dialect); err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back the given number of applied migrations, newest first, and
+// returns the ones it rolled back
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("rollback of %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// legacyColumn is a column the initial migration expects on a table that
+// may have been created by the former AutoMigrate
+type legacyColumn struct {
+	Table      string
+	Column     string
+	Definition string
+}
+
+// legacyColumns lists the columns that tables created by the former
+// AutoMigrate lack, depending on the build that created them
+func legacyColumns(dialect string) []legacyColumn {
+	timestamp := "DATETIME"
+	if dialect == "postgres" {
+		timestamp = "TIMESTAMPTZ"
+	}
+	return []legacyColumn{
+		{"messages", "organization_id", "VARCHAR(100)"},
+		{"messages", "error_category", "VARCHAR(50)"},
+		{"messages", "error_retryable", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"messages", "error_trace_id", "VARCHAR(100)"},
+		{"contacts", "organization_id", "VARCHAR(100)"},
+		{"templates", "organization_id", "VARCHAR(100)"},
+		{"api_keys", "organization_id", "VARCHAR(100)"},
+		{"api_keys", "revoked_at", timestamp},
+		{"api_keys", "revoked_reason", "VARCHAR(255)"},
+		{"api_keys", "replaced_by_id", "VARCHAR(100)"},
+		{"api_keys", "rate_limit_per_minute", "INTEGER NOT NULL DEFAULT 0"},
+		{"api_keys", "daily_quota", "INTEGER NOT NULL DEFAULT 0"},
+		{"api_keys", "monthly_quota", "INTEGER NOT NULL DEFAULT 0"},
+	}
+}
+
+// adoptLegacyColumns adds the legacy columns missing from existing tables,
+// so the initial migration can create its indexes on databases created by
+// the former AutoMigrate. Tables that don't exist yet are left to the
+// migration.
+func adoptLegacyColumns(tx *gorm.DB, dialect string) error {
+	for _, c := range legacyColumns(dialect) {
+		if !tx.Migrator().HasTable(c.Table) || tx.Migrator().HasColumn(c.Table, c.Column) {
+			continue
+		}
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.Table, c.Column, c.Definition)).Error; err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", c.Table, c.Column, err)
+		}
+	}
+	return nil
+}
+
+// applied returns the rows of schema_migrations by version, creating the
+// table on first use
+func (m *Migrator) applied(db *gorm.DB) (map[int]schemaMigration, error) {
 	if err := db.Exec(`
-		CREATE OR REPLACE FUNCTION update_updated_at_column()
-		RETURNS TRIGGER AS $$
-		BEGIN
-			NEW.updated_at = CURRENT_TIMESTAMP;
-			RETURN NEW;
-		END;
-		$$ language 'plpgsql';
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    INTEGER PRIMARY KEY,
+			name       VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
 	`).Error; err != nil {
-		return fmt.Errorf("failed to create trigger function: %w", err)
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
 	}
 
-	// Apply trigger to all tables
-	tables := []string{"messages", "contacts", "templates", "api_keys", "calls", "transcripts", "transcript_segments"}
-	for _, table := range tables {
-		if err := db.Exec(fmt.Sprintf(`
-			DROP TRIGGER IF EXISTS update_%s_updated_at ON %s;
-			CREATE TRIGGER update_%s_updated_at
-			BEFORE UPDATE ON %s
-			FOR EACH ROW
-			EXECUTE FUNCTION update_updated_at_column();
-		`, table, table, table, table)).Error; err != nil {
-			return fmt.Errorf("failed to create trigger for %s: %w", table, err)
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	applied := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// newestVersion returns the newest version in applied, 0 when it is empty
+func newestVersion(applied map[int]schemaMigration) int {
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
 		}
 	}
+	return version
+}
 
-	return nil
+// PrepareSchema is run on startup. It refuses to run against a schema newer
+// than this build and applies pending migrations when autoMigrate is set;
+// otherwise pending migrations are an error. It returns the migrations it
+// applied.
+func PrepareSchema(db *gorm.DB, autoMigrate bool) ([]Migration, error) {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+
+	err = migrator.Check()
+	switch {
+	case err == nil:
+		return nil, nil
+	case errors.Is(err, ErrPendingMigrations) && autoMigrate:
+		return migrator.Up()
+	default:
+		return nil, err
+	}
 }
@@ -0,0 +1,284 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := NewConnection("sqlite", filepath.Join(t.TempDir(), "test.db"), logger.Silent)
+	if err != nil {
+		t.Fatalf("NewConnection: %v", err)
+	}
+	t.Cleanup(func() { CloseConnection(db) })
+	return db
+}
+
+// schemaModels are the models whose tables the migrations create
+var schemaModels = []interface{}{
+	&models.Organization{},
+	&models.Message{},
+	&models.Contact{},
+	&models.Reaction{},
+	&models.ContactTag{},
+	&models.Segment{},
+	&models.AttributeDefinition{},
+	&models.ContactImport{},
+	&models.AuditLog{},
+	&models.ContactErasure{},
+	&models.RetentionPolicy{},
+	&models.Template{},
+	&models.APIKey{},
+	&models.RateLimitCounter{},
+	&models.Lease{},
+	&models.Call{},
+	&models.Transcript{},
+	&models.TranscriptSegment{},
+}
+
+func TestMigrateUpCreatesModelColumns(t *testing.T) {
+	db := newTestDB(t)
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(applied) != len(migrator.Migrations()) {
+		t.Fatalf("applied %d of %d migrations", len(applied), len(migrator.Migrations()))
+	}
+
+	// Every field the models map must exist, or queries will fail at runtime
+	for _, model := range schemaModels {
+		s, err := schema.Parse(model, &sync.Map{}, db.NamingStrategy)
+		if err != nil {
+			t.Fatalf("parse %T: %v", model, err)
+		}
+		for _, field := range s.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !db.Migrator().HasColumn(s.Table, field.DBName) {
+				t.Errorf("%s.%s is missing", s.Table, field.DBName)
+			}
+		}
+	}
+
+	if err := migrator.Check(); err != nil {
+		t.Errorf("Check after Up: %v", err)
+	}
+	if again, err := migrator.Up(); err != nil || len(again) != 0 {
+		t.Errorf("second Up should be a no-op, got %v %v", again, err)
+	}
+}
+
+// baselineSchema is the schema the first release created with AutoMigrate,
+// before organizations, error details and key management were added
+const baselineSchema = `
+CREATE TABLE messages (
+    id VARCHAR(100) PRIMARY KEY, whatsapp_message_id VARCHAR(255), from_number VARCHAR(50) NOT NULL,
+    to_number VARCHAR(50) NOT NULL, direction VARCHAR(20) NOT NULL, message_type VARCHAR(50) NOT NULL,
+    content TEXT, media_url VARCHAR(500), media_mime_type VARCHAR(100), status VARCHAR(50) NOT NULL,
+    error_code VARCHAR(100), error_message TEXT, metadata JSONB, timestamp DATETIME NOT NULL,
+    created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL);
+CREATE UNIQUE INDEX idx_messages_whatsapp_message_id ON messages(whatsapp_message_id);
+CREATE TABLE contacts (
+    id VARCHAR(100) PRIMARY KEY, phone_number VARCHAR(50) NOT NULL, name VARCHAR(255), profile_url VARCHAR(500),
+    last_message_at DATETIME, message_count INTEGER DEFAULT 0, unread_count INTEGER DEFAULT 0, metadata JSONB,
+    created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL);
+CREATE UNIQUE INDEX idx_contacts_phone_number ON contacts(phone_number);
+CREATE TABLE templates (
+    id VARCHAR(100) PRIMARY KEY, name VARCHAR(255) NOT NULL, language VARCHAR(10) NOT NULL,
+    category VARCHAR(50) NOT NULL, status VARCHAR(50) NOT NULL, content TEXT NOT NULL, parameters JSONB,
+    metadata JSONB, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL);
+CREATE TABLE api_keys (
+    id VARCHAR(100) PRIMARY KEY, name VARCHAR(255) NOT NULL, key_hash VARCHAR(255) NOT NULL, key_prefix VARCHAR(20),
+    permissions JSONB, expires_at DATETIME, last_used_at DATETIME, created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL);
+CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys(key_hash);
+CREATE TABLE calls (
+    id TEXT PRIMARY KEY, from_number TEXT, to_number TEXT, direction TEXT, status TEXT, duration INTEGER,
+    recording_url TEXT, transcript_id TEXT, started_at DATETIME, ended_at DATETIME, created_at DATETIME,
+    updated_at DATETIME);
+CREATE TABLE transcripts (
+    id TEXT PRIMARY KEY, call_id TEXT, content TEXT, language TEXT, provider TEXT, confidence REAL,
+    processed_at DATETIME, created_at DATETIME, updated_at DATETIME);
+CREATE TABLE transcript_segments (
+    id TEXT PRIMARY KEY, transcript_id TEXT, speaker TEXT, content TEXT, start_time REAL, end_time REAL,
+    confidence REAL, created_at DATETIME);
+INSERT INTO messages (id, whatsapp_message_id, from_number, to_number, direction, message_type, content, status, timestamp, created_at, updated_at)
+    VALUES ('msg_legacy', 'wamid.legacy', '14155550100', '15550000000', 'inbound', 'text', 'hello', 'received',
+    '2024-01-01 00:00:00', '2024-01-01 00:00:00', '2024-01-01 00:00:00');
+INSERT INTO api_keys (id, name, key_hash, key_prefix, created_at, updated_at)
+    VALUES ('key_legacy', 'legacy', 'hash', 'wa_legacy', '2024-01-01 00:00:00', '2024-01-01 00:00:00');
+`
+
+func TestMigrateUpAdoptsBaselineSchema(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.Exec(baselineSchema).Error; err != nil {
+		t.Fatalf("create baseline schema: %v", err)
+	}
+
+	migrator, _ := NewMigrator(db)
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up on a baseline schema: %v", err)
+	}
+
+	for _, model := range schemaModels {
+		s, err := schema.Parse(model, &sync.Map{}, db.NamingStrategy)
+		if err != nil {
+			t.Fatalf("parse %T: %v", model, err)
+		}
+		for _, field := range s.Fields {
+			if field.DBName != "" && !db.Migrator().HasColumn(s.Table, field.DBName) {
+				t.Errorf("%s.%s is missing", s.Table, field.DBName)
+			}
+		}
+	}
+
+	var message models.Message
+	if err := db.First(&message, "id = ?", "msg_legacy").Error; err != nil || message.Content != "hello" || message.ErrorRetryable {
+		t.Errorf("existing messages should be kept: %+v %v", message, err)
+	}
+	var apiKey models.APIKey
+	if err := db.First(&apiKey, "id = ?", "key_legacy").Error; err != nil || apiKey.IsRevoked() || apiKey.DailyQuota != 0 {
+		t.Errorf("existing keys should be kept: %+v %v", apiKey, err)
+	}
+}
+
+func TestConcurrentMigrateUpAppliesEachMigrationOnce(t *testing.T) {
+	// Two replicas starting at once, each with its own connection pool
+	path := filepath.Join(t.TempDir(), "shared.db")
+	var migrators []*Migrator
+	for i := 0; i < 2; i++ {
+		db, err := NewConnection("sqlite", path, logger.Silent)
+		if err != nil {
+			t.Fatalf("NewConnection: %v", err)
+		}
+		t.Cleanup(func() { CloseConnection(db) })
+		migrator, err := NewMigrator(db)
+		if err != nil {
+			t.Fatalf("NewMigrator: %v", err)
+		}
+		migrators = append(migrators, migrator)
+	}
+
+	var wg sync.WaitGroup
+	applied := make([][]Migration, len(migrators))
+	errs := make([]error, len(migrators))
+	for i, migrator := range migrators {
+		wg.Add(1)
+		go func(i int, migrator *Migrator) {
+			defer wg.Done()
+			applied[i], errs[i] = migrator.Up()
+		}(i, migrator)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Up %d: %v", i, err)
+		}
+	}
+	if total := len(applied[0]) + len(applied[1]); total != len(migrators[0].Migrations()) {
+		t.Errorf("each migration should be applied once, got %d and %d of %d", len(applied[0]), len(applied[1]), len(migrators[0].Migrations()))
+	}
+	if err := migrators[1].Check(); err != nil {
+		t.Errorf("Check after concurrent Up: %v", err)
+	}
+}
+
+func TestMigrateStatusAndDown(t *testing.T) {
+	db := newTestDB(t)
+	migrator, _ := NewMigrator(db)
+
+	if err := migrator.Check(); !errors.Is(err, ErrPendingMigrations) {
+		t.Fatalf("expected pending migrations on an empty database, got %v", err)
+	}
+
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, s := range statuses {
+		if !s.Applied || s.AppliedAt == nil {
+			t.Errorf("migration %d should be applied: %+v", s.Version, s)
+		}
+	}
+
+	rolledBack, err := migrator.Down(len(migrator.Migrations()))
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if len(rolledBack) != len(migrator.Migrations()) {
+		t.Errorf("rolled back %d of %d migrations", len(rolledBack), len(migrator.Migrations()))
+	}
+	if db.Migrator().HasTable("messages") {
+		t.Error("messages table should have been dropped")
+	}
+	if version, _ := migrator.Version(); version != 0 {
+		t.Errorf("expected version 0 after rolling back everything, got %d", version)
+	}
+}
+
+func TestPrepareSchemaRefusesNewerSchema(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := PrepareSchema(db, true); err != nil {
+		t.Fatalf("PrepareSchema: %v", err)
+	}
+
+	migrator, _ := NewMigrator(db)
+	future := schemaMigration{Version: migrator.Latest() + 1, Name: "from_the_future", AppliedAt: time.Now().UTC()}
+	if err := db.Create(&future).Error; err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	if _, err := PrepareSchema(db, true); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+	if _, err := migrator.Up(); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("Up should refuse a newer schema, got %v", err)
+	}
+}
+
+func TestPrepareSchemaWithoutAutoMigrate(t *testing.T) {
+	db := newTestDB(t)
+	if _, err := PrepareSchema(db, false); !errors.Is(err, ErrPendingMigrations) {
+		t.Fatalf("expected ErrPendingMigrations, got %v", err)
+	}
+}
+
+func TestDialectsHaveSameVersions(t *testing.T) {
+	sqlite, err := LoadMigrations("sqlite")
+	if err != nil {
+		t.Fatalf("sqlite: %v", err)
+	}
+	postgres, err := LoadMigrations("postgres")
+	if err != nil {
+		t.Fatalf("postgres: %v", err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("sqlite has %d migrations, postgres %d", len(sqlite), len(postgres))
+	}
+	for i := range sqlite {
+		if sqlite[i].Version != postgres[i].Version || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("migration %d differs: %d_%s vs %d_%s", i,
+				sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+-- Initial schema. Tables use IF NOT EXISTS so databases created by the
+-- former AutoMigrate are adopted as they are; the migrator adds the columns
+-- older builds did not create before running it (see legacyColumns).
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id                   VARCHAR(100) PRIMARY KEY,
+    name                 VARCHAR(255) NOT NULL,
+    status               VARCHAR(50)  NOT NULL,
+    phone_number_id      VARCHAR(100),
+    business_account_id  VARCHAR(100),
+    access_token         TEXT,
+    webhook_verify_token VARCHAR(255),
+    webhook_secret       VARCHAR(255),
+    metadata             JSONB,
+    created_at           TIMESTAMPTZ NOT NULL,
+    updated_at           TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_organizations_status ON organizations(status);
+CREATE INDEX IF NOT EXISTS idx_organizations_phone_number_id ON organizations(phone_number_id);
+CREATE INDEX IF NOT EXISTS idx_organizations_business_account_id ON organizations(business_account_id);
+CREATE INDEX IF NOT EXISTS idx_organizations_created_at ON organizations(created_at);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id                  VARCHAR(100) PRIMARY KEY,
+    organization_id     VARCHAR(100),
+    whatsapp_message_id VARCHAR(255),
+    from_number         VARCHAR(50)  NOT NULL,
+    to_number           VARCHAR(50)  NOT NULL,
+    direction           VARCHAR(20)  NOT NULL,
+    message_type        VARCHAR(50)  NOT NULL,
+    content             TEXT,
+    media_url           VARCHAR(500),
+    media_mime_type     VARCHAR(100),
+    status              VARCHAR(50)  NOT NULL,
+    error_code          VARCHAR(100),
+    error_message       TEXT,
+    error_category      VARCHAR(50),
+    error_retryable     BOOLEAN NOT NULL DEFAULT FALSE,
+    error_trace_id      VARCHAR(100),
+    metadata            JSONB,
+    timestamp           TIMESTAMPTZ NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL,
+    updated_at          TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_messages_organization_id ON messages(organization_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_whatsapp_message_id ON messages(whatsapp_message_id);
+CREATE INDEX IF NOT EXISTS idx_messages_from_number ON messages(from_number);
+CREATE INDEX IF NOT EXISTS idx_messages_to_number ON messages(to_number);
+CREATE INDEX IF NOT EXISTS idx_messages_status ON messages(status);
+CREATE INDEX IF NOT EXISTS idx_messages_error_category ON messages(error_category);
+CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages(timestamp);
+CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages(created_at);
+CREATE INDEX IF NOT EXISTS idx_messages_phone_timestamp ON messages(from_number, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_status_created ON messages(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_content_search ON messages USING gin(to_tsvector('english', content));
+
+CREATE TABLE IF NOT EXISTS contacts (
+    id              VARCHAR(100) PRIMARY KEY,
+    organization_id VARCHAR(100),
+    phone_number    VARCHAR(50) NOT NULL,
+    name            VARCHAR(255),
+    profile_url     VARCHAR(500),
+    last_message_at TIMESTAMPTZ,
+    message_count   BIGINT DEFAULT 0,
+    unread_count    BIGINT DEFAULT 0,
+    metadata        JSONB,
+    created_at      TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL
+);
+-- Contacts used to be unique per phone number; they are unique per
+-- organization and phone number
+DROP INDEX IF EXISTS idx_contacts_phone_number;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contacts_org_phone ON contacts(organization_id, phone_number);
+CREATE INDEX IF NOT EXISTS idx_contacts_last_message_at ON contacts(last_message_at);
+CREATE INDEX IF NOT EXISTS idx_contacts_created_at ON contacts(created_at);
+CREATE INDEX IF NOT EXISTS idx_contacts_last_message ON contacts(last_message_at DESC NULLS LAST);
+
+CREATE TABLE IF NOT EXISTS templates (
+    id              VARCHAR(100) PRIMARY KEY,
+    organization_id VARCHAR(100),
+    name            VARCHAR(255) NOT NULL,
+    language        VARCHAR(10)  NOT NULL,
+    category        VARCHAR(50)  NOT NULL,
+    status          VARCHAR(50)  NOT NULL,
+    content         TEXT NOT NULL,
+    parameters      JSONB,
+    metadata        JSONB,
+    created_at      TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_templates_organization_id ON templates(organization_id);
+CREATE INDEX IF NOT EXISTS idx_templates_name ON templates(name);
+CREATE INDEX IF NOT EXISTS idx_templates_status ON templates(status);
+CREATE INDEX IF NOT EXISTS idx_templates_created_at ON templates(created_at);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id                    VARCHAR(100) PRIMARY KEY,
+    organization_id       VARCHAR(100),
+    name                  VARCHAR(255) NOT NULL,
+    key_hash              VARCHAR(255) NOT NULL,
+    key_prefix            VARCHAR(20),
+    permissions           JSONB,
+    expires_at            TIMESTAMPTZ,
+    last_used_at          TIMESTAMPTZ,
+    revoked_at            TIMESTAMPTZ,
+    revoked_reason        VARCHAR(255),
+    replaced_by_id        VARCHAR(100),
+    created_at            TIMESTAMPTZ NOT NULL,
+    updated_at            TIMESTAMPTZ NOT NULL,
+    rate_limit_per_minute BIGINT NOT NULL DEFAULT 0,
+    daily_quota           BIGINT NOT NULL DEFAULT 0,
+    monthly_quota         BIGINT NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_organization_id ON api_keys(organization_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_key_prefix ON api_keys(key_prefix);
+CREATE INDEX IF NOT EXISTS idx_api_keys_expires_at ON api_keys(expires_at);
+CREATE INDEX IF NOT EXISTS idx_api_keys_last_used_at ON api_keys(last_used_at);
+CREATE INDEX IF NOT EXISTS idx_api_keys_revoked_at ON api_keys(revoked_at);
+CREATE INDEX IF NOT EXISTS idx_api_keys_created_at ON api_keys(created_at);
+
+CREATE TABLE IF NOT EXISTS rate_limit_counters (
+    bucket     VARCHAR(255) PRIMARY KEY,
+    count      BIGINT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_counters_expires_at ON rate_limit_counters(expires_at);
+
+CREATE TABLE IF NOT EXISTS calls (
+    id            TEXT PRIMARY KEY,
+    from_number   TEXT,
+    to_number     TEXT,
+    direction     TEXT,
+    status        TEXT,
+    duration      BIGINT,
+    recording_url TEXT,
+    transcript_id TEXT,
+    started_at    TIMESTAMPTZ,
+    ended_at      TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_calls_from_number ON calls(from_number);
+CREATE INDEX IF NOT EXISTS idx_calls_to_number ON calls(to_number);
+CREATE INDEX IF NOT EXISTS idx_calls_status ON calls(status);
+CREATE INDEX IF NOT EXISTS idx_calls_started_at ON calls(started_at);
+
+CREATE TABLE IF NOT EXISTS transcripts (
+    id           TEXT PRIMARY KEY,
+    call_id      TEXT,
+    content      TEXT,
+    language     TEXT,
+    provider     TEXT,
+    confidence   DECIMAL,
+    processed_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_transcripts_call_id ON transcripts(call_id);
+
+CREATE TABLE IF NOT EXISTS transcript_segments (
+    id            TEXT PRIMARY KEY,
+    transcript_id TEXT,
+    speaker       TEXT,
+    content       TEXT,
+    start_time    DECIMAL,
+    end_time      DECIMAL,
+    confidence    DECIMAL,
+    created_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_transcript_segments_transcript_id ON transcript_segments(transcript_id);
+
+-- Keep updated_at current on every update
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_organizations_updated_at ON organizations;
+CREATE TRIGGER update_organizations_updated_at BEFORE UPDATE ON organizations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_messages_updated_at ON messages;
+CREATE TRIGGER update_messages_updated_at BEFORE UPDATE ON messages
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_contacts_updated_at ON contacts;
+CREATE TRIGGER update_contacts_updated_at BEFORE UPDATE ON contacts
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_templates_updated_at ON templates;
+CREATE TRIGGER update_templates_updated_at BEFORE UPDATE ON templates
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_api_keys_updated_at ON api_keys;
+CREATE TRIGGER update_api_keys_updated_at BEFORE UPDATE ON api_keys
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_calls_updated_at ON calls;
+CREATE TRIGGER update_calls_updated_at BEFORE UPDATE ON calls
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+DROP TRIGGER IF EXISTS update_transcripts_updated_at ON transcripts;
+CREATE TRIGGER update_transcripts_updated_at BEFORE UPDATE ON transcripts
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
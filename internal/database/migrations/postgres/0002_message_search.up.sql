@@ -0,0 +1,16 @@
+-- Captions and filenames of media messages are stored in their own columns
+-- and searched together with the message text
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS caption TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS filename VARCHAR(255);
+
+-- Punctuation in filenames is replaced so "march_invoice.pdf" is indexed as
+-- separate words
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector('english',
+        coalesce(content, '') || ' ' ||
+        coalesce(caption, '') || ' ' ||
+        translate(coalesce(filename, ''), '._-', '   '))
+) STORED;
+
+DROP INDEX IF EXISTS idx_messages_content_search;
+CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING gin(search_vector);
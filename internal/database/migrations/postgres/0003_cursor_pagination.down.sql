@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_contacts_org_last_message_id;
+DROP INDEX IF EXISTS idx_messages_org_timestamp_id;
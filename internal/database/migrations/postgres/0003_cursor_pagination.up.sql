@@ -0,0 +1,4 @@
+-- Cursor pagination reads listings in (sort column, id) order within an
+-- organization, so these indexes serve each page as a range scan.
+CREATE INDEX IF NOT EXISTS idx_messages_org_timestamp_id ON messages(organization_id, timestamp, id);
+CREATE INDEX IF NOT EXISTS idx_contacts_org_last_message_id ON contacts(organization_id, last_message_at, id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS reactions;
+ALTER TABLE messages DROP COLUMN IF EXISTS reply_to_whatsapp_message_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS reply_to_message_id;
@@ -0,0 +1,24 @@
+-- Messages that quote another message, from the webhook context
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS reply_to_message_id VARCHAR(100);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS reply_to_whatsapp_message_id VARCHAR(255);
+
+-- Emoji reactions, one per sender and message
+CREATE TABLE IF NOT EXISTS reactions (
+    id                         VARCHAR(100) PRIMARY KEY,
+    organization_id            VARCHAR(100),
+    message_id                 VARCHAR(100),
+    target_whatsapp_message_id VARCHAR(255) NOT NULL,
+    whatsapp_message_id        VARCHAR(255),
+    from_number                VARCHAR(50) NOT NULL,
+    direction                  VARCHAR(20) NOT NULL,
+    emoji                      VARCHAR(32) NOT NULL,
+    timestamp                  TIMESTAMPTZ NOT NULL,
+    created_at                 TIMESTAMPTZ NOT NULL,
+    updated_at                 TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_target_sender ON reactions(organization_id, target_whatsapp_message_id, from_number);
+CREATE INDEX IF NOT EXISTS idx_reactions_message_id ON reactions(message_id);
+
+DROP TRIGGER IF EXISTS update_reactions_updated_at ON reactions;
+CREATE TRIGGER update_reactions_updated_at BEFORE UPDATE ON reactions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
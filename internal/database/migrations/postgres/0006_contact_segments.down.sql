@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS segments;
+DROP TABLE IF EXISTS contact_tags;
+ALTER TABLE contacts DROP COLUMN IF EXISTS consent_updated_at;
+ALTER TABLE contacts DROP COLUMN IF EXISTS consent;
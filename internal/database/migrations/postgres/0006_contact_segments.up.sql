@@ -0,0 +1,30 @@
+-- Marketing consent on contacts
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS consent VARCHAR(20);
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS consent_updated_at TIMESTAMPTZ;
+
+-- Contact tags, one row per contact and tag
+CREATE TABLE IF NOT EXISTS contact_tags (
+    organization_id VARCHAR(100),
+    contact_id      VARCHAR(100) NOT NULL,
+    tag             VARCHAR(64) NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (contact_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_contact_tags_org_tag ON contact_tags(organization_id, tag);
+
+-- Saved contact filters
+CREATE TABLE IF NOT EXISTS segments (
+    id              VARCHAR(100) PRIMARY KEY,
+    organization_id VARCHAR(100),
+    name            VARCHAR(255) NOT NULL,
+    description     TEXT,
+    filter          TEXT NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_segments_org_name ON segments(organization_id, name);
+CREATE INDEX IF NOT EXISTS idx_segments_created_at ON segments(created_at);
+
+DROP TRIGGER IF EXISTS update_segments_updated_at ON segments;
+CREATE TRIGGER update_segments_updated_at BEFORE UPDATE ON segments
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,18 @@
+-- Custom contact attribute definitions. Values live in contacts.metadata
+-- under the definition name.
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    id              VARCHAR(100) PRIMARY KEY,
+    organization_id VARCHAR(100),
+    name            VARCHAR(64) NOT NULL,
+    description     TEXT,
+    type            VARCHAR(20) NOT NULL,
+    options         JSONB,
+    required        BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at      TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attribute_definitions_org_name ON attribute_definitions(organization_id, name);
+
+DROP TRIGGER IF EXISTS update_attribute_definitions_updated_at ON attribute_definitions;
+CREATE TRIGGER update_attribute_definitions_updated_at BEFORE UPDATE ON attribute_definitions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,27 @@
+-- Bulk contact imports. Rows are processed in the background and the
+-- import records the progress and the first failed rows.
+CREATE TABLE IF NOT EXISTS contact_imports (
+    id              VARCHAR(100) PRIMARY KEY,
+    organization_id VARCHAR(100),
+    format          VARCHAR(10) NOT NULL,
+    mapping         JSONB,
+    status          VARCHAR(20) NOT NULL,
+    total_rows      INTEGER DEFAULT 0,
+    processed_rows  INTEGER DEFAULT 0,
+    created         INTEGER DEFAULT 0,
+    updated         INTEGER DEFAULT 0,
+    failed          INTEGER DEFAULT 0,
+    errors          JSONB,
+    error           TEXT,
+    started_at      TIMESTAMPTZ,
+    completed_at    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL,
+    updated_at      TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_contact_imports_organization_id ON contact_imports(organization_id);
+CREATE INDEX IF NOT EXISTS idx_contact_imports_status ON contact_imports(status);
+CREATE INDEX IF NOT EXISTS idx_contact_imports_created_at ON contact_imports(created_at);
+
+DROP TRIGGER IF EXISTS update_contact_imports_updated_at ON contact_imports;
+CREATE TRIGGER update_contact_imports_updated_at BEFORE UPDATE ON contact_imports
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
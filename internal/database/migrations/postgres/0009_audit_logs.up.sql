@@ -0,0 +1,15 @@
+-- Audit log of changes that cannot be undone, such as contact merges
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id              VARCHAR(100) PRIMARY KEY,
+    organization_id VARCHAR(100),
+    action          VARCHAR(100) NOT NULL,
+    resource_type   VARCHAR(50) NOT NULL,
+    resource_id     VARCHAR(100) NOT NULL,
+    actor_id        VARCHAR(100),
+    details         JSONB,
+    created_at      TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_organization_id ON audit_logs(organization_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_resource_id ON audit_logs(resource_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
@@ -0,0 +1,5 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS blocked;
+DROP INDEX IF EXISTS idx_contacts_blocked;
+ALTER TABLE contacts DROP COLUMN IF EXISTS block_reason;
+ALTER TABLE contacts DROP COLUMN IF EXISTS blocked_at;
+ALTER TABLE contacts DROP COLUMN IF EXISTS blocked;
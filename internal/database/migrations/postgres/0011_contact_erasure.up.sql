@@ -0,0 +1,37 @@
+-- Calls and their transcripts belong to an organization, so they can be
+-- found and erased with the contact they were with
+ALTER TABLE calls ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100);
+ALTER TABLE transcripts ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100);
+ALTER TABLE transcript_segments ADD COLUMN IF NOT EXISTS organization_id VARCHAR(100);
+CREATE INDEX IF NOT EXISTS idx_calls_organization_id ON calls(organization_id);
+CREATE INDEX IF NOT EXISTS idx_transcripts_organization_id ON transcripts(organization_id);
+CREATE INDEX IF NOT EXISTS idx_transcript_segments_organization_id ON transcript_segments(organization_id);
+
+-- Calls recorded so far predate organizations and belong to the default
+-- organization, like the other rows assigned to it on upgrade. Transcripts
+-- and segments follow their call.
+UPDATE calls SET organization_id = 'org_default'
+    WHERE organization_id IS NULL OR organization_id = '';
+UPDATE transcripts SET organization_id = COALESCE(
+        (SELECT calls.organization_id FROM calls WHERE calls.id = transcripts.call_id), 'org_default')
+    WHERE organization_id IS NULL OR organization_id = '';
+UPDATE transcript_segments SET organization_id = COALESCE(
+        (SELECT transcripts.organization_id FROM transcripts WHERE transcripts.id = transcript_segments.transcript_id), 'org_default')
+    WHERE organization_id IS NULL OR organization_id = '';
+
+-- Tombstones of contacts erased on request. They hold no personal data,
+-- only what was erased, when and by whom.
+CREATE TABLE IF NOT EXISTS contact_erasures (
+    id                 VARCHAR(100) PRIMARY KEY,
+    organization_id    VARCHAR(100),
+    contact_id         VARCHAR(100) NOT NULL,
+    actor_id           VARCHAR(100),
+    messages_erased    INTEGER NOT NULL DEFAULT 0,
+    reactions_erased   INTEGER NOT NULL DEFAULT 0,
+    calls_erased       INTEGER NOT NULL DEFAULT 0,
+    transcripts_erased INTEGER NOT NULL DEFAULT 0,
+    media_files_erased INTEGER NOT NULL DEFAULT 0,
+    created_at         TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_contact_erasures_organization_id ON contact_erasures(organization_id);
+CREATE INDEX IF NOT EXISTS idx_contact_erasures_contact_id ON contact_erasures(contact_id);
@@ -0,0 +1,4 @@
+ALTER TABLE contact_erasures DROP COLUMN IF EXISTS archived_messages_erased;
+ALTER TABLE messages DROP COLUMN IF EXISTS media_removed_at;
+DROP INDEX IF EXISTS idx_messages_retention;
+DROP TABLE IF EXISTS retention_policies;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_contact_imports_active;
+ALTER TABLE contact_imports DROP COLUMN IF EXISTS heartbeat_at;
+ALTER TABLE contact_imports DROP COLUMN IF EXISTS owner;
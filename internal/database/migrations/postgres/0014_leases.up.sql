@@ -0,0 +1,7 @@
+-- Leases give one replica at a time a background job, such as an
+-- organization's retention run
+CREATE TABLE IF NOT EXISTS leases (
+    name       VARCHAR(255) PRIMARY KEY,
+    owner      VARCHAR(100) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS transcript_segments;
+DROP TABLE IF EXISTS transcripts;
+DROP TABLE IF EXISTS calls;
+DROP TABLE IF EXISTS rate_limit_counters;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS templates;
+DROP TABLE IF EXISTS contacts;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS organizations;
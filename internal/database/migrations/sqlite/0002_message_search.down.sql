@@ -0,0 +1,8 @@
+-- The search index triggers reference the columns, so they go first
+DROP TRIGGER IF EXISTS messages_fts_insert;
+DROP TRIGGER IF EXISTS messages_fts_delete;
+DROP TRIGGER IF EXISTS messages_fts_update;
+DROP TABLE IF EXISTS messages_fts;
+
+ALTER TABLE messages DROP COLUMN filename;
+ALTER TABLE messages DROP COLUMN caption;
@@ -0,0 +1,6 @@
+-- Captions and filenames of media messages are stored in their own columns
+-- and searched together with the message text. The FTS5 index over them is
+-- created by database.EnsureSearchIndex, as FTS5 is only available in builds
+-- with the sqlite_fts5 tag.
+ALTER TABLE messages ADD COLUMN caption TEXT;
+ALTER TABLE messages ADD COLUMN filename VARCHAR(255);
@@ -0,0 +1,3 @@
+DELETE FROM reactions WHERE direction = 'outbound';
+DROP INDEX IF EXISTS idx_reactions_target_sender;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_target_sender ON reactions(organization_id, target_whatsapp_message_id, from_number);
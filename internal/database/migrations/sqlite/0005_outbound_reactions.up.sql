@@ -0,0 +1,4 @@
+-- We react to customer messages too, so a message can carry one reaction
+-- from each side of the conversation
+DROP INDEX IF EXISTS idx_reactions_target_sender;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reactions_target_sender ON reactions(organization_id, target_whatsapp_message_id, from_number, direction);
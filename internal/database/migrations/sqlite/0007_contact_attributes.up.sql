@@ -0,0 +1,14 @@
+-- Custom contact attribute definitions. Values live in contacts.metadata
+-- under the definition name.
+CREATE TABLE IF NOT EXISTS attribute_definitions (
+    id              VARCHAR(100) PRIMARY KEY,
+    organization_id VARCHAR(100),
+    name            VARCHAR(64) NOT NULL,
+    description     TEXT,
+    type            VARCHAR(20) NOT NULL,
+    options         TEXT,
+    required        BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at      DATETIME NOT NULL,
+    updated_at      DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attribute_definitions_org_name ON attribute_definitions(organization_id, name);
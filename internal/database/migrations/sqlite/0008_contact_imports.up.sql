@@ -0,0 +1,23 @@
+-- Bulk contact imports. Rows are processed in the background and the
+-- import records the progress and the first failed rows.
+CREATE TABLE IF NOT EXISTS contact_imports (
+    id              VARCHAR(100) PRIMARY KEY,
+    organization_id VARCHAR(100),
+    format          VARCHAR(10) NOT NULL,
+    mapping         TEXT,
+    status          VARCHAR(20) NOT NULL,
+    total_rows      INTEGER DEFAULT 0,
+    processed_rows  INTEGER DEFAULT 0,
+    created         INTEGER DEFAULT 0,
+    updated         INTEGER DEFAULT 0,
+    failed          INTEGER DEFAULT 0,
+    errors          TEXT,
+    error           TEXT,
+    started_at      DATETIME,
+    completed_at    DATETIME,
+    created_at      DATETIME NOT NULL,
+    updated_at      DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_contact_imports_organization_id ON contact_imports(organization_id);
+CREATE INDEX IF NOT EXISTS idx_contact_imports_status ON contact_imports(status);
+CREATE INDEX IF NOT EXISTS idx_contact_imports_created_at ON contact_imports(created_at);
@@ -0,0 +1,5 @@
+ALTER TABLE messages DROP COLUMN blocked;
+DROP INDEX IF EXISTS idx_contacts_blocked;
+ALTER TABLE contacts DROP COLUMN block_reason;
+ALTER TABLE contacts DROP COLUMN blocked_at;
+ALTER TABLE contacts DROP COLUMN blocked;
@@ -0,0 +1,8 @@
+-- Blocked contacts: sends to them are refused and their inbound messages
+-- are stored flagged
+ALTER TABLE contacts ADD COLUMN blocked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE contacts ADD COLUMN blocked_at DATETIME;
+ALTER TABLE contacts ADD COLUMN block_reason VARCHAR(500);
+CREATE INDEX IF NOT EXISTS idx_contacts_blocked ON contacts(organization_id, blocked);
+
+ALTER TABLE messages ADD COLUMN blocked BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS contact_erasures;
+DROP INDEX IF EXISTS idx_transcript_segments_organization_id;
+DROP INDEX IF EXISTS idx_transcripts_organization_id;
+DROP INDEX IF EXISTS idx_calls_organization_id;
+ALTER TABLE transcript_segments DROP COLUMN organization_id;
+ALTER TABLE transcripts DROP COLUMN organization_id;
+ALTER TABLE calls DROP COLUMN organization_id;
@@ -0,0 +1,19 @@
+-- How long each organization keeps messages of a type before archiving or
+-- deleting them, and their media before removing it
+CREATE TABLE IF NOT EXISTS retention_policies (
+    id                VARCHAR(100) PRIMARY KEY,
+    organization_id   VARCHAR(100),
+    message_type      VARCHAR(50) NOT NULL,
+    action            VARCHAR(20) NOT NULL,
+    retain_days       INTEGER NOT NULL DEFAULT 0,
+    media_retain_days INTEGER NOT NULL DEFAULT 0,
+    created_at        DATETIME NOT NULL,
+    updated_at        DATETIME NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_retention_policies_org_type ON retention_policies(organization_id, message_type);
+
+-- Retention runs find messages by type and age
+CREATE INDEX IF NOT EXISTS idx_messages_retention ON messages(organization_id, message_type, timestamp);
+ALTER TABLE messages ADD COLUMN media_removed_at DATETIME;
+
+ALTER TABLE contact_erasures ADD COLUMN archived_messages_erased INTEGER NOT NULL DEFAULT 0;
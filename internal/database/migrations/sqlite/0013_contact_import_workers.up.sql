@@ -0,0 +1,16 @@
+-- Imports record the process running them and when it last reported in, so
+-- a starting replica only fails imports whose worker is gone
+ALTER TABLE contact_imports ADD COLUMN owner VARCHAR(100);
+ALTER TABLE contact_imports ADD COLUMN heartbeat_at DATETIME;
+
+-- Only one import per organization may be active, across all replicas.
+-- Older duplicates left by replicas racing before this index are failed.
+UPDATE contact_imports SET status = 'failed', error = 'The import was interrupted by a restart'
+WHERE status IN ('pending', 'running') AND EXISTS (
+    SELECT 1 FROM contact_imports newer
+    WHERE newer.organization_id = contact_imports.organization_id
+      AND newer.status IN ('pending', 'running')
+      AND newer.created_at > contact_imports.created_at
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contact_imports_active ON contact_imports(organization_id)
+    WHERE status IN ('pending', 'running');
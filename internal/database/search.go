@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SearchIndexTable is the SQLite FTS5 table indexing message text
+const SearchIndexTable = "messages_fts"
+
+// sqliteSearchIndex is an FTS5 table over the text columns of messages,
+// kept in sync by triggers. Rows are keyed on the message ID, stored
+// unindexed, rather than on the rowid of messages, which VACUUM or a dump
+// and restore may renumber as its primary key is a string. The update
+// trigger only fires for the indexed columns so status updates do not touch
+// the index.
+var sqliteSearchIndex = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+		message_id UNINDEXED, content, caption, filename,
+		tokenize='porter unicode61'
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages BEGIN
+		INSERT INTO messages_fts(message_id, content, caption, filename)
+		VALUES (new.id, new.content, new.caption, new.filename);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages BEGIN
+		DELETE FROM messages_fts WHERE message_id = old.id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_update AFTER UPDATE OF id, content, caption, filename ON messages BEGIN
+		DELETE FROM messages_fts WHERE message_id = old.id;
+		INSERT INTO messages_fts(message_id, content, caption, filename)
+		VALUES (new.id, new.content, new.caption, new.filename);
+	END`,
+}
+
+// dropSQLiteSearchIndex removes the search index and its triggers, to
+// replace an index created by an older build
+var dropSQLiteSearchIndex = []string{
+	`DROP TRIGGER IF EXISTS messages_fts_insert`,
+	`DROP TRIGGER IF EXISTS messages_fts_delete`,
+	`DROP TRIGGER IF EXISTS messages_fts_update`,
+	`DROP TABLE IF EXISTS messages_fts`,
+}
+
+// EnsureSearchIndex creates the SQLite full-text index over messages when the
+// driver was built with FTS5 (the sqlite_fts5 build tag) and fills it from
+// existing rows. It reports whether full-text search is available. On
+// Postgres the index is part of the migrations and it always reports true.
+//
+// The schema must be at the latest version.
+func EnsureSearchIndex(db *gorm.DB) (bool, error) {
+	if db.Dialector.Name() != "sqlite" {
+		return true, nil
+	}
+
+	var fts5 bool
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return false, fmt.Errorf("failed to check for FTS5: %w", err)
+	}
+	if !fts5 {
+		return false, nil
+	}
+
+	exists := db.Migrator().HasTable(SearchIndexTable)
+	// Older builds indexed messages by rowid, as an external content table
+	// without the message_id column
+	stale := exists && !db.Migrator().HasColumn(SearchIndexTable, "message_id")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if stale {
+			for _, statement := range dropSQLiteSearchIndex {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+		}
+		for _, statement := range sqliteSearchIndex {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if !exists || stale {
+			return tx.Exec(`INSERT INTO messages_fts(message_id, content, caption, filename)
+				SELECT id, content, caption, filename FROM messages`).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to create search index: %w", err)
+	}
+	return true, nil
+}
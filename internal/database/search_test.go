@@ -0,0 +1,50 @@
+package database
+
+import "testing"
+
+func TestEnsureSearchIndexReplacesRowidIndex(t *testing.T) {
+	db := newTestDB(t)
+	migrator, _ := NewMigrator(db)
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if err := db.Exec(`INSERT INTO messages (id, whatsapp_message_id, from_number, to_number, direction, message_type, content, status, timestamp, created_at, updated_at)
+		VALUES ('msg_1', 'wamid.1', '14155550100', '15550000000', 'inbound', 'text', 'hello world', 'received',
+		'2024-01-01 00:00:00', '2024-01-01 00:00:00', '2024-01-01 00:00:00')`).Error; err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// The index older builds created, keyed on the rowid of messages
+	err := db.Exec(`CREATE VIRTUAL TABLE messages_fts USING fts5(
+		content, caption, filename, content='messages', tokenize='porter unicode61')`).Error
+	if err != nil {
+		t.Skip("SQLite was built without FTS5")
+	}
+	db.Exec(`CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages BEGIN
+		INSERT INTO messages_fts(rowid, content, caption, filename) VALUES (new.rowid, new.content, new.caption, new.filename);
+	END`)
+	db.Exec("INSERT INTO messages_fts(messages_fts) VALUES ('rebuild')")
+
+	if fullText, err := EnsureSearchIndex(db); err != nil || !fullText {
+		t.Fatalf("EnsureSearchIndex: %v %v", fullText, err)
+	}
+	if !db.Migrator().HasColumn(SearchIndexTable, "message_id") {
+		t.Fatal("the rowid index should be replaced")
+	}
+
+	var ids []string
+	db.Raw("SELECT message_id FROM messages_fts WHERE messages_fts MATCH 'hello'").Scan(&ids)
+	if len(ids) != 1 || ids[0] != "msg_1" {
+		t.Errorf("existing messages should be indexed by ID: %v", ids)
+	}
+
+	// Running it again keeps the index as it is
+	if _, err := EnsureSearchIndex(db); err != nil {
+		t.Fatalf("EnsureSearchIndex again: %v", err)
+	}
+	var count int64
+	db.Raw("SELECT COUNT(*) FROM messages_fts").Scan(&count)
+	if count != 1 {
+		t.Errorf("expected 1 indexed message, got %d", count)
+	}
+}
@@ -2,22 +2,105 @@ package models
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// API key permissions. A permission is written as "resource:action"; "*"
+// grants everything and "resource:*" grants every action on a resource.
+const (
+	PermissionAll                = "*"
+	PermissionMessagesSend       = "messages:send"
+	PermissionMessagesRead       = "messages:read"
+	PermissionContactsRead       = "contacts:read"
+	PermissionContactsWrite      = "contacts:write"
+	PermissionTemplatesRead      = "templates:read"
+	PermissionTemplatesAdmin     = "templates:admin"
+	PermissionKeysAdmin          = "keys:admin"
+	PermissionOrganizationsAdmin = "organizations:admin"
+	PermissionAuditRead          = "audit:read"
+	PermissionRetentionAdmin     = "retention:admin"
+)
+
+// Permissions is the catalogue of permissions that can be granted to a key
+var Permissions = []string{
+	PermissionMessagesSend,
+	PermissionMessagesRead,
+	PermissionContactsRead,
+	PermissionContactsWrite,
+	PermissionTemplatesRead,
+	PermissionTemplatesAdmin,
+	PermissionKeysAdmin,
+	PermissionOrganizationsAdmin,
+	PermissionAuditRead,
+	PermissionRetentionAdmin,
+}
+
+// impliedPermissions lists the permissions that also grant a permission,
+// e.g. a key that can write contacts can also read them
+var impliedPermissions = map[string][]string{
+	PermissionContactsRead:  {PermissionContactsWrite},
+	PermissionTemplatesRead: {PermissionTemplatesAdmin},
+	PermissionAuditRead:     {PermissionKeysAdmin},
+}
+
+// IsValidPermission returns true if the permission is in the catalogue or
+// is a wildcard over a known resource
+func IsValidPermission(permission string) bool {
+	if permission == PermissionAll {
+		return true
+	}
+	for _, p := range Permissions {
+		if p == permission || permissionResource(p)+":*" == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionResource returns the resource part of a "resource:action" permission
+func permissionResource(permission string) string {
+	if i := strings.Index(permission, ":"); i >= 0 {
+		return permission[:i]
+	}
+	return permission
+}
+
+// APIKeyLimits holds the request limits and send quotas of an API key. Zero
+// means the server default for the rate limit and unlimited for quotas.
+type APIKeyLimits struct {
+	RateLimitPerMinute int `json:"rate_limit_per_minute" gorm:"not null;default:0"`
+	DailyQuota         int `json:"daily_quota" gorm:"not null;default:0"`
+	MonthlyQuota       int `json:"monthly_quota" gorm:"not null;default:0"`
+}
+
+// Validate checks that no limit is negative
+func (l APIKeyLimits) Validate() error {
+	if l.RateLimitPerMinute < 0 || l.DailyQuota < 0 || l.MonthlyQuota < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
+
 // APIKey represents an API key for authentication
 type APIKey struct {
-	ID          string     `json:"id" gorm:"primaryKey;type:varchar(100)"`
-	Name        string     `json:"name" gorm:"type:varchar(255);not null" validate:"required"`
-	KeyHash     string     `json:"-" gorm:"uniqueIndex;type:varchar(255);not null"`
-	KeyPrefix   string     `json:"key_prefix" gorm:"index;type:varchar(20)"`
-	Permissions JSONArray  `json:"permissions,omitempty" gorm:"type:jsonb"`
-	ExpiresAt   *time.Time `json:"expires_at,omitempty" gorm:"index"`
-	LastUsedAt  *time.Time `json:"last_used_at,omitempty" gorm:"index"`
-	CreatedAt   time.Time  `json:"created_at" gorm:"index;not null"`
-	UpdatedAt   time.Time  `json:"updated_at" gorm:"not null"`
+	ID             string     `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	OrganizationID string     `json:"organization_id" gorm:"index;type:varchar(100)"`
+	Name           string     `json:"name" gorm:"type:varchar(255);not null" validate:"required"`
+	KeyHash        string     `json:"-" gorm:"uniqueIndex;type:varchar(255);not null"`
+	KeyPrefix      string     `json:"key_prefix" gorm:"index;type:varchar(20)"`
+	Permissions    JSONArray  `json:"permissions,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty" gorm:"index"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty" gorm:"index"`
+	RevokedReason  string     `json:"revoked_reason,omitempty" gorm:"type:varchar(255)"`
+	ReplacedByID   string     `json:"replaced_by_id,omitempty" gorm:"type:varchar(100)"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"index;not null"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"not null"`
+
+	APIKeyLimits `gorm:"embedded"`
 }
 
 // TableName specifies the table name for APIKey
@@ -53,7 +136,12 @@ func (a *APIKey) Validate() error {
 	if a.KeyHash == "" {
 		return errors.New("key_hash is required")
 	}
-	return nil
+	return a.APIKeyLimits.Validate()
+}
+
+// SetOrganizationID implements OrganizationScoped
+func (a *APIKey) SetOrganizationID(id string) {
+	a.OrganizationID = id
 }
 
 // IsExpired returns true if the API key has expired
@@ -64,9 +152,14 @@ func (a *APIKey) IsExpired() bool {
 	return time.Now().UTC().After(*a.ExpiresAt)
 }
 
-// IsValid returns true if the API key is valid (not expired)
+// IsRevoked returns true if the API key has been revoked
+func (a *APIKey) IsRevoked() bool {
+	return a.RevokedAt != nil
+}
+
+// IsValid returns true if the API key is valid (not expired or revoked)
 func (a *APIKey) IsValid() bool {
-	return !a.IsExpired()
+	return !a.IsExpired() && !a.IsRevoked()
 }
 
 // UpdateLastUsed updates the last_used_at timestamp
@@ -76,14 +169,44 @@ func (a *APIKey) UpdateLastUsed(tx *gorm.DB) error {
 	return tx.Model(a).Update("last_used_at", now).Error
 }
 
-// HasPermission checks if the API key has a specific permission
+// CanGrant returns true if the key may hand the permission out to a new key.
+// Keys can only grant what they hold; wildcards require the same wildcard.
+func (a *APIKey) CanGrant(permission string) bool {
+	if permission == PermissionAll || strings.HasSuffix(permission, ":*") {
+		for _, p := range a.Permissions {
+			if p == PermissionAll || p == permission {
+				return true
+			}
+		}
+		return false
+	}
+	return a.HasPermission(permission)
+}
+
+// CanManage returns true if the key may rotate, revoke or change the limits
+// of target. That needs every permission target holds, since rotating hands
+// out a new secret with target's permissions.
+func (a *APIKey) CanManage(target *APIKey) bool {
+	for _, permission := range target.Permissions {
+		if !a.CanGrant(permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasPermission checks if the API key has a specific permission, either
+// directly, through a wildcard or through a permission that implies it
 func (a *APIKey) HasPermission(permission string) bool {
 	if a.Permissions == nil {
 		return false
 	}
+	accepted := append([]string{permission, PermissionAll, permissionResource(permission) + ":*"}, impliedPermissions[permission]...)
 	for _, p := range a.Permissions {
-		if p == permission || p == "*" {
-			return true
+		for _, ok := range accepted {
+			if p == ok {
+				return true
+			}
 		}
 	}
 	return false
@@ -0,0 +1,70 @@
+package models
+
+import "testing"
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions JSONArray
+		permission  string
+		want        bool
+	}{
+		{"no permissions", nil, PermissionMessagesRead, false},
+		{"exact match", JSONArray{PermissionMessagesRead}, PermissionMessagesRead, true},
+		{"other permission", JSONArray{PermissionMessagesRead}, PermissionMessagesSend, false},
+		{"global wildcard", JSONArray{PermissionAll}, PermissionKeysAdmin, true},
+		{"resource wildcard", JSONArray{"templates:*"}, PermissionTemplatesAdmin, true},
+		{"resource wildcard other resource", JSONArray{"templates:*"}, PermissionContactsRead, false},
+		{"write implies read", JSONArray{PermissionContactsWrite}, PermissionContactsRead, true},
+		{"read does not imply write", JSONArray{PermissionContactsRead}, PermissionContactsWrite, false},
+		{"admin implies read", JSONArray{PermissionTemplatesAdmin}, PermissionTemplatesRead, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := &APIKey{Permissions: tt.permissions}
+			if got := key.HasPermission(tt.permission); got != tt.want {
+				t.Errorf("HasPermission(%q) = %v, want %v", tt.permission, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanManage(t *testing.T) {
+	admin := &APIKey{Permissions: JSONArray{PermissionKeysAdmin, PermissionMessagesSend}}
+	tests := []struct {
+		name   string
+		target JSONArray
+		want   bool
+	}{
+		{"subset", JSONArray{PermissionMessagesSend}, true},
+		{"implied", JSONArray{PermissionAuditRead}, true},
+		{"global wildcard", JSONArray{PermissionAll}, false},
+		{"resource wildcard", JSONArray{"messages:*"}, false},
+		{"permission not held", JSONArray{PermissionMessagesSend, PermissionContactsWrite}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := admin.CanManage(&APIKey{Permissions: tt.target}); got != tt.want {
+				t.Errorf("CanManage(%v) = %v, want %v", tt.target, got, tt.want)
+			}
+		})
+	}
+	if !(&APIKey{Permissions: JSONArray{PermissionAll}}).CanManage(&APIKey{Permissions: JSONArray{PermissionAll}}) {
+		t.Error("a wildcard key should manage wildcard keys")
+	}
+}
+
+func TestIsValidPermission(t *testing.T) {
+	for _, p := range []string{PermissionAll, PermissionMessagesSend, "contacts:*", PermissionKeysAdmin} {
+		if !IsValidPermission(p) {
+			t.Errorf("IsValidPermission(%q) = false, want true", p)
+		}
+	}
+	for _, p := range []string{"", "messages", "messages:delete", "billing:*"} {
+		if IsValidPermission(p) {
+			t.Errorf("IsValidPermission(%q) = true, want false", p)
+		}
+	}
+}
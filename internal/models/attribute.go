@@ -0,0 +1,157 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Custom attribute types
+const (
+	AttributeTypeString = "string"
+	AttributeTypeNumber = "number"
+	AttributeTypeDate   = "date"
+	AttributeTypeBool   = "bool"
+	AttributeTypeEnum   = "enum"
+)
+
+const (
+	// AttributeDateLayout is the stored form of date attributes. Dates in
+	// this form sort like the days they name.
+	AttributeDateLayout = "2006-01-02"
+
+	// MaxAttributeTextLength is the longest string attribute, in characters
+	MaxAttributeTextLength = 1000
+)
+
+// AttributeDefinition declares a custom contact attribute. Values are
+// stored in the contact metadata under the definition's name.
+type AttributeDefinition struct {
+	ID             string `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	OrganizationID string `json:"organization_id" gorm:"uniqueIndex:idx_attribute_definitions_org_name;type:varchar(100)"`
+	// Name is the metadata key. It cannot change once created.
+	Name        string `json:"name" gorm:"uniqueIndex:idx_attribute_definitions_org_name;type:varchar(64);not null"`
+	Description string `json:"description,omitempty" gorm:"type:text"`
+	Type        string `json:"type" gorm:"type:varchar(20);not null"`
+	// Options are the allowed values of enum attributes
+	Options JSONArray `json:"options,omitempty"`
+	// Required attributes must be set whenever contact metadata is updated
+	Required  bool      `json:"required" gorm:"not null;default:false"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for AttributeDefinition
+func (AttributeDefinition) TableName() string {
+	return "attribute_definitions"
+}
+
+// BeforeCreate hook to generate ID and set timestamps
+func (d *AttributeDefinition) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = GenerateID("attr")
+	}
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+	if d.UpdatedAt.IsZero() {
+		d.UpdatedAt = time.Now().UTC()
+	}
+	if d.Name == "" || d.Type == "" {
+		return errors.New("name and type are required")
+	}
+	return nil
+}
+
+// BeforeUpdate hook
+func (d *AttributeDefinition) BeforeUpdate(tx *gorm.DB) error {
+	d.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// SetOrganizationID implements OrganizationScoped
+func (d *AttributeDefinition) SetOrganizationID(id string) {
+	d.OrganizationID = id
+}
+
+// HasOption reports whether value is one of the options of an enum
+func (d *AttributeDefinition) HasOption(value string) bool {
+	for _, option := range d.Options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeValue checks that a decoded JSON value suits the attribute and
+// returns its stored form. Dates may be given as timestamps and are
+// stored as days.
+func (d *AttributeDefinition) NormalizeValue(value interface{}) (interface{}, error) {
+	switch d.Type {
+	case AttributeTypeNumber:
+		switch n := value.(type) {
+		case float64:
+			return n, nil
+		case int:
+			return float64(n), nil
+		case int64:
+			return float64(n), nil
+		}
+		return nil, fmt.Errorf("%s must be a number", d.Name)
+
+	case AttributeTypeBool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("%s must be true or false", d.Name)
+
+	case AttributeTypeDate:
+		text, _ := value.(string)
+		if t, err := time.Parse(AttributeDateLayout, text); err == nil {
+			return t.Format(AttributeDateLayout), nil
+		}
+		if t, err := time.Parse(time.RFC3339, text); err == nil {
+			return t.UTC().Format(AttributeDateLayout), nil
+		}
+		return nil, fmt.Errorf("%s must be a date like 2025-01-31", d.Name)
+
+	case AttributeTypeEnum:
+		text, ok := value.(string)
+		if !ok || !d.HasOption(text) {
+			return nil, fmt.Errorf("%s must be one of %v", d.Name, []string(d.Options))
+		}
+		return text, nil
+
+	default:
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string", d.Name)
+		}
+		if utf8.RuneCountInString(text) > MaxAttributeTextLength {
+			return nil, fmt.Errorf("%s is longer than %d characters", d.Name, MaxAttributeTextLength)
+		}
+		return text, nil
+	}
+}
+
+// FormatAttributeValue renders a stored attribute value as text, for
+// template parameters
+func FormatAttributeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
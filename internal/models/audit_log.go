@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Audit log actions
+const (
+	AuditActionContactMerge   = "contact.merge"
+	AuditActionContactBlock   = "contact.block"
+	AuditActionContactUnblock = "contact.unblock"
+	AuditActionContactDelete  = "contact.delete"
+	AuditActionContactErase   = "contact.erase"
+)
+
+// AuditLog records a sensitive change made through the API, such as a
+// contact merge, block or erasure
+type AuditLog struct {
+	ID             string `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	OrganizationID string `json:"organization_id" gorm:"index;type:varchar(100)"`
+	Action         string `json:"action" gorm:"type:varchar(100);not null;index"`
+	// ResourceType and ResourceID name what was changed, such as the
+	// surviving contact of a merge
+	ResourceType string `json:"resource_type" gorm:"type:varchar(50);not null"`
+	ResourceID   string `json:"resource_id" gorm:"type:varchar(100);not null;index"`
+	// ActorID is the API key that made the change
+	ActorID   string    `json:"actor_id,omitempty" gorm:"type:varchar(100)"`
+	Details   JSONMap   `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"index;not null"`
+}
+
+// TableName specifies the table name for AuditLog
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// BeforeCreate hook to generate ID and set timestamps
+func (a *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = GenerateID("audit")
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// SetOrganizationID implements OrganizationScoped
+func (a *AuditLog) SetOrganizationID(id string) {
+	a.OrganizationID = id
+}
@@ -0,0 +1,16 @@
+package models
+
+// MaxBlockReasonLength is the longest reason a contact can be blocked for
+const MaxBlockReasonLength = 500
+
+// ContactBlockResult is the outcome of blocking or unblocking a contact.
+// The block list on WhatsApp is updated on a best-effort basis: the
+// contact is blocked here even when WhatsApp could not be updated.
+type ContactBlockResult struct {
+	Contact *Contact `json:"contact"`
+	// WhatsAppUpdated reports whether the number was also blocked, or
+	// unblocked, on WhatsApp. WhatsAppError says why not, when it failed.
+	WhatsAppUpdated bool   `json:"whatsapp_updated"`
+	WhatsAppError   string `json:"whatsapp_error,omitempty"`
+	AuditLogID      string `json:"audit_log_id"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ContactErasure is the tombstone of a contact erased on request. It keeps
+// no personal data: only which contact was erased, what was deleted with
+// it, when, and by whom.
+type ContactErasure struct {
+	ID             string `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	OrganizationID string `json:"organization_id" gorm:"index;type:varchar(100)"`
+	ContactID      string `json:"contact_id" gorm:"type:varchar(100);not null;index"`
+	// ActorID is the API key that requested the erasure
+	ActorID           string `json:"actor_id,omitempty" gorm:"type:varchar(100)"`
+	MessagesErased    int64  `json:"messages_erased" gorm:"default:0"`
+	ReactionsErased   int64  `json:"reactions_erased" gorm:"default:0"`
+	CallsErased       int64  `json:"calls_erased" gorm:"default:0"`
+	TranscriptsErased int64  `json:"transcripts_erased" gorm:"default:0"`
+	MediaFilesErased  int64  `json:"media_files_erased" gorm:"default:0"`
+	// ArchivedMessagesErased counts the messages removed from retention
+	// archives
+	ArchivedMessagesErased int64     `json:"archived_messages_erased" gorm:"default:0"`
+	CreatedAt              time.Time `json:"created_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for ContactErasure
+func (ContactErasure) TableName() string {
+	return "contact_erasures"
+}
+
+// BeforeCreate hook to generate ID and set timestamps
+func (e *ContactErasure) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = GenerateID("erasure")
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// ContactErasureResult is the outcome of erasing a contact
+type ContactErasureResult struct {
+	Erasure    *ContactErasure `json:"erasure"`
+	AuditLogID string          `json:"audit_log_id"`
+}
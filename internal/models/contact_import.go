@@ -0,0 +1,150 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Contact import formats
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+)
+
+// Contact import statuses
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// MaxImportRowErrors is the number of row errors kept on an import. Further
+// failed rows are only counted.
+const MaxImportRowErrors = 100
+
+// ContactImport is a bulk contact import, run in the background
+type ContactImport struct {
+	ID             string `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	OrganizationID string `json:"organization_id" gorm:"index;type:varchar(100)"`
+	Format         string `json:"format" gorm:"type:varchar(10);not null"`
+	// Mapping maps source columns to contact fields
+	Mapping JSONMap `json:"mapping"`
+	Status  string  `json:"status" gorm:"type:varchar(20);not null;index"`
+	// TotalRows is the number of data rows in the file
+	TotalRows     int `json:"total_rows" gorm:"default:0"`
+	ProcessedRows int `json:"processed_rows" gorm:"default:0"`
+	Created       int `json:"created" gorm:"default:0"`
+	Updated       int `json:"updated" gorm:"default:0"`
+	Failed        int `json:"failed" gorm:"default:0"`
+	// Errors are the first failed rows
+	Errors ImportRowErrors `json:"errors"`
+	// Error is why a failed import stopped
+	Error string `json:"error,omitempty" gorm:"type:text"`
+	// Owner identifies the process running the import, which refreshes
+	// HeartbeatAt while it runs
+	Owner       string     `json:"-" gorm:"type:varchar(100)"`
+	HeartbeatAt *time.Time `json:"-"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"index;not null"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for ContactImport
+func (ContactImport) TableName() string {
+	return "contact_imports"
+}
+
+// BeforeCreate hook to generate ID and set timestamps
+func (i *ContactImport) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = GenerateID("import")
+	}
+	if i.CreatedAt.IsZero() {
+		i.CreatedAt = time.Now().UTC()
+	}
+	if i.UpdatedAt.IsZero() {
+		i.UpdatedAt = time.Now().UTC()
+	}
+	if i.Status == "" {
+		i.Status = ImportStatusPending
+	}
+	return nil
+}
+
+// BeforeUpdate hook
+func (i *ContactImport) BeforeUpdate(tx *gorm.DB) error {
+	i.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// IsActive returns true if the import is pending or running
+func (i *ContactImport) IsActive() bool {
+	return i.Status == ImportStatusPending || i.Status == ImportStatusRunning
+}
+
+// SetOrganizationID implements OrganizationScoped
+func (i *ContactImport) SetOrganizationID(id string) {
+	i.OrganizationID = id
+}
+
+// ImportRowError is a row that could not be imported. Row is the line of
+// the row in the file.
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportRowErrors represents row errors stored as JSON
+type ImportRowErrors []ImportRowError
+
+// GormDataType implements schema.GormDataTypeInterface for ImportRowErrors
+func (ImportRowErrors) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType implements schema.GormDBDataTypeInterface for
+// ImportRowErrors
+func (ImportRowErrors) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return jsonDataType(db)
+}
+
+// Value implements the driver.Valuer interface for ImportRowErrors
+func (e ImportRowErrors) Value() (driver.Value, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return json.Marshal(e)
+}
+
+// Scan implements the sql.Scanner interface for ImportRowErrors
+func (e *ImportRowErrors) Scan(value interface{}) error {
+	if value == nil {
+		*e = nil
+		return nil
+	}
+
+	bytes, err := scanBytes(value)
+	if err != nil {
+		return err
+	}
+
+	if len(bytes) == 0 {
+		*e = nil
+		return nil
+	}
+
+	var result []ImportRowError
+	if err := json.Unmarshal(bytes, &result); err != nil {
+		return fmt.Errorf("failed to unmarshal ImportRowErrors: %w", err)
+	}
+
+	*e = result
+	return nil
+}
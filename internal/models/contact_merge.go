@@ -0,0 +1,51 @@
+package models
+
+// Contact merge rules: which contact's name, profile and metadata win when
+// the merged contacts both have them
+const (
+	// MergeRuleSurvivor keeps the surviving contact's values, filling in
+	// the ones it lacks from the duplicates
+	MergeRuleSurvivor = "survivor"
+	// MergeRuleNewest keeps the values of the contact with the latest
+	// message, or the latest update if none has messages
+	MergeRuleNewest = "newest"
+)
+
+// DuplicateGroup is a set of contacts whose phone numbers are the same
+// number written differently
+type DuplicateGroup struct {
+	// PhoneNumber is the number in E.164 form
+	PhoneNumber string     `json:"phone_number"`
+	Contacts    []*Contact `json:"contacts"`
+}
+
+// DuplicateReport lists the duplicate contacts of an organization
+type DuplicateReport struct {
+	Groups []*DuplicateGroup `json:"groups"`
+	// InvalidContacts is the number of contacts whose phone number could
+	// not be parsed, which are left out of the report
+	InvalidContacts int `json:"invalid_contacts"`
+}
+
+// ContactMerge is a merge of duplicate contacts into a surviving contact,
+// applied in one transaction
+type ContactMerge struct {
+	// Survivor holds the merged fields and tags, and the phone number the
+	// merged contacts' messages move to
+	Survivor   *Contact
+	Duplicates []*Contact
+	// PhoneNumbers are the numbers messages and reactions are stored under
+	// for the merged contacts
+	PhoneNumbers []string
+	// Audit is recorded with the merge, with the number of messages moved
+	// added to its details
+	Audit *AuditLog
+}
+
+// ContactMergeResult is the outcome of a contact merge
+type ContactMergeResult struct {
+	Contact          *Contact `json:"contact"`
+	MergedContactIDs []string `json:"merged_contact_ids"`
+	MessagesMoved    int64    `json:"messages_moved"`
+	AuditLogID       string   `json:"audit_log_id"`
+}
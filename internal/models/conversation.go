@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Conversation is a page of the message history with a contact, grouped
+// into sessions. Sessions and the messages in them are newest first.
+type Conversation struct {
+	Contact  *Contact               `json:"contact"`
+	Sessions []*ConversationSession `json:"sessions"`
+}
+
+// ConversationSession is a run of messages without a long silence between
+// them. A session can span pages: HasEarlierMessages and HasLaterMessages
+// report that it continues on the next or previous page, and StartedAt and
+// EndedAt only cover the messages on this one.
+type ConversationSession struct {
+	StartedAt          time.Time              `json:"started_at"`
+	EndedAt            time.Time              `json:"ended_at"`
+	HasEarlierMessages bool                   `json:"has_earlier_messages"`
+	HasLaterMessages   bool                   `json:"has_later_messages"`
+	Messages           []*ConversationMessage `json:"messages"`
+}
+
+// ConversationMessage is a message in a conversation with the message it
+// replies to, when we have it, and its reactions
+type ConversationMessage struct {
+	*Message
+	ReplyTo   *Message    `json:"reply_to,omitempty"`
+	Reactions []*Reaction `json:"reactions"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Lease gives one replica exclusive use of a named resource, such as an
+// organization's retention run, until it expires. The holder renews it
+// while it works.
+type Lease struct {
+	Name      string    `json:"name" gorm:"primaryKey;type:varchar(255)"`
+	Owner     string    `json:"owner" gorm:"type:varchar(100);not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for Lease
+func (Lease) TableName() string {
+	return "leases"
+}
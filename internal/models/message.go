@@ -19,33 +19,54 @@ const (
 
 // Message types
 const (
-	MessageTypeText     = "text"
-	MessageTypeImage    = "image"
-	MessageTypeVideo    = "video"
-	MessageTypeAudio    = "audio"
-	MessageTypeDocument = "document"
-	MessageTypeLocation = "location"
-	MessageTypeTemplate = "template"
+	MessageTypeText            = "text"
+	MessageTypeImage           = "image"
+	MessageTypeVideo           = "video"
+	MessageTypeAudio           = "audio"
+	MessageTypeDocument        = "document"
+	MessageTypeSticker         = "sticker"
+	MessageTypeLocation        = "location"
+	MessageTypeLocationRequest = "location_request"
+	MessageTypeContacts        = "contacts"
+	MessageTypeTemplate        = "template"
+	MessageTypeReaction        = "reaction"
 )
 
 // Message represents a WhatsApp message
 type Message struct {
-	ID                  string    `json:"id" gorm:"primaryKey;type:varchar(100)"`
-	WhatsAppMessageID   string    `json:"whatsapp_message_id" gorm:"uniqueIndex;type:varchar(255)"`
-	FromNumber          string    `json:"from_number" gorm:"index;type:varchar(50);not null" validate:"required,e164"`
-	ToNumber            string    `json:"to_number" gorm:"index;type:varchar(50);not null" validate:"required,e164"`
-	Direction           string    `json:"direction" gorm:"type:varchar(20);not null" validate:"required,oneof=inbound outbound"`
-	MessageType         string    `json:"message_type" gorm:"type:varchar(50);not null" validate:"required"`
-	Content             string    `json:"content" gorm:"type:text"`
-	MediaURL            string    `json:"media_url,omitempty" gorm:"type:varchar(500)"`
-	MediaMimeType       string    `json:"media_mime_type,omitempty" gorm:"type:varchar(100)"`
-	Status              string    `json:"status" gorm:"index;type:varchar(50);not null" validate:"required"`
-	ErrorCode           string    `json:"error_code,omitempty" gorm:"type:varchar(100)"`
-	ErrorMessage        string    `json:"error_message,omitempty" gorm:"type:text"`
-	Metadata            JSONMap   `json:"metadata,omitempty" gorm:"type:jsonb"`
-	Timestamp           time.Time `json:"timestamp" gorm:"index;not null"`
-	CreatedAt           time.Time `json:"created_at" gorm:"index;not null"`
-	UpdatedAt           time.Time `json:"updated_at" gorm:"not null"`
+	ID                string    `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	OrganizationID    string    `json:"organization_id" gorm:"index;type:varchar(100)"`
+	WhatsAppMessageID string    `json:"whatsapp_message_id" gorm:"column:whatsapp_message_id;uniqueIndex;type:varchar(255);default:null"`
+	FromNumber        string    `json:"from_number" gorm:"index;type:varchar(50);not null" validate:"required,e164"`
+	ToNumber          string    `json:"to_number" gorm:"index;type:varchar(50);not null" validate:"required,e164"`
+	Direction         string    `json:"direction" gorm:"type:varchar(20);not null" validate:"required,oneof=inbound outbound"`
+	MessageType       string    `json:"message_type" gorm:"type:varchar(50);not null" validate:"required"`
+	Content           string    `json:"content" gorm:"type:text"`
+	MediaURL          string    `json:"media_url,omitempty" gorm:"type:varchar(500)"`
+	MediaMimeType     string    `json:"media_mime_type,omitempty" gorm:"type:varchar(100)"`
+	Caption           string    `json:"caption,omitempty" gorm:"type:text"`
+	Filename          string    `json:"filename,omitempty" gorm:"type:varchar(255)"`
+	Status            string    `json:"status" gorm:"index;type:varchar(50);not null" validate:"required"`
+	ErrorCode         string    `json:"error_code,omitempty" gorm:"type:varchar(100)"`
+	ErrorMessage      string    `json:"error_message,omitempty" gorm:"type:text"`
+	ErrorCategory     string    `json:"error_category,omitempty" gorm:"index;type:varchar(50)"`
+	ErrorRetryable    bool      `json:"error_retryable,omitempty"`
+	ErrorTraceID      string    `json:"error_trace_id,omitempty" gorm:"type:varchar(100)"`
+	Metadata          JSONMap   `json:"metadata,omitempty"`
+	Timestamp         time.Time `json:"timestamp" gorm:"index;not null"`
+	CreatedAt         time.Time `json:"created_at" gorm:"index;not null"`
+	UpdatedAt         time.Time `json:"updated_at" gorm:"not null"`
+
+	// ReplyToWhatsAppMessageID is the message this one quotes, from the
+	// webhook context. ReplyToMessageID is that message when we have it.
+	ReplyToMessageID         string `json:"reply_to_message_id,omitempty" gorm:"type:varchar(100)"`
+	ReplyToWhatsAppMessageID string `json:"reply_to_whatsapp_message_id,omitempty" gorm:"column:reply_to_whatsapp_message_id;type:varchar(255)"`
+
+	// Blocked is set on inbound messages from blocked contacts
+	Blocked bool `json:"blocked,omitempty"`
+
+	// MediaRemovedAt is set when a retention policy removed the media
+	MediaRemovedAt *time.Time `json:"media_removed_at,omitempty"`
 }
 
 // TableName specifies the table name for Message
@@ -53,6 +74,15 @@ func (Message) TableName() string {
 	return "messages"
 }
 
+// MessageSearchResult is a message matching a search. Snippet is an excerpt
+// of the matching text with the matched words wrapped in <mark> tags; it is
+// not HTML-escaped. Rank orders results by relevance, higher first.
+type MessageSearchResult struct {
+	*Message
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
 // BeforeCreate hook to generate ID and set timestamps
 func (m *Message) BeforeCreate(tx *gorm.DB) error {
 	if m.ID == "" {
@@ -96,6 +126,11 @@ func (m *Message) Validate() error {
 	return nil
 }
 
+// SetOrganizationID implements OrganizationScoped
+func (m *Message) SetOrganizationID(id string) {
+	m.OrganizationID = id
+}
+
 // IsInbound returns true if the message is inbound
 func (m *Message) IsInbound() bool {
 	return m.Direction == "inbound"
@@ -116,18 +151,83 @@ func (m *Message) HasFailed() bool {
 	return m.Status == MessageStatusFailed
 }
 
+// Reaction is an emoji reaction to a message. A sender has at most one
+// reaction per message: reacting again replaces it, and reacting with an
+// empty emoji removes it.
+type Reaction struct {
+	ID             string `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	OrganizationID string `json:"organization_id" gorm:"uniqueIndex:idx_reactions_target_sender;type:varchar(100)"`
+	// MessageID is the reacted-to message, when we have it
+	MessageID               string    `json:"message_id,omitempty" gorm:"index;type:varchar(100)"`
+	TargetWhatsAppMessageID string    `json:"target_whatsapp_message_id" gorm:"column:target_whatsapp_message_id;uniqueIndex:idx_reactions_target_sender;type:varchar(255);not null"`
+	WhatsAppMessageID       string    `json:"whatsapp_message_id,omitempty" gorm:"column:whatsapp_message_id;type:varchar(255)"`
+	FromNumber              string    `json:"from_number" gorm:"uniqueIndex:idx_reactions_target_sender;type:varchar(50);not null"`
+	Direction               string    `json:"direction" gorm:"uniqueIndex:idx_reactions_target_sender;type:varchar(20);not null"`
+	Emoji                   string    `json:"emoji" gorm:"type:varchar(32);not null"`
+	Timestamp               time.Time `json:"timestamp" gorm:"not null"`
+	CreatedAt               time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt               time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for Reaction
+func (Reaction) TableName() string {
+	return "reactions"
+}
+
+// BeforeCreate hook to generate ID and set timestamps
+func (r *Reaction) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = GenerateID("reaction")
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now().UTC()
+	}
+	if r.UpdatedAt.IsZero() {
+		r.UpdatedAt = time.Now().UTC()
+	}
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now().UTC()
+	}
+	if r.TargetWhatsAppMessageID == "" || r.FromNumber == "" || r.Emoji == "" {
+		return errors.New("target_whatsapp_message_id, from_number and emoji are required")
+	}
+	return nil
+}
+
+// SetOrganizationID implements OrganizationScoped
+func (r *Reaction) SetOrganizationID(id string) {
+	r.OrganizationID = id
+}
+
+// Contact consent to receive marketing messages. Contacts that never gave
+// or withdrew consent have none.
+const (
+	ConsentOptedIn  = "opted_in"
+	ConsentOptedOut = "opted_out"
+)
+
 // Contact represents a WhatsApp contact
 type Contact struct {
-	ID            string    `json:"id" gorm:"primaryKey;type:varchar(100)"`
-	PhoneNumber   string    `json:"phone_number" gorm:"uniqueIndex;type:varchar(50);not null" validate:"required,e164"`
-	Name          string    `json:"name" gorm:"type:varchar(255)"`
-	ProfileURL    string    `json:"profile_url,omitempty" gorm:"type:varchar(500)"`
-	LastMessageAt *time.Time `json:"last_message_at,omitempty" gorm:"index"`
-	MessageCount  int       `json:"message_count" gorm:"default:0"`
-	UnreadCount   int       `json:"unread_count" gorm:"default:0"`
-	Metadata      JSONMap   `json:"metadata,omitempty" gorm:"type:jsonb"`
-	CreatedAt     time.Time `json:"created_at" gorm:"index;not null"`
-	UpdatedAt     time.Time `json:"updated_at" gorm:"not null"`
+	ID               string     `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	OrganizationID   string     `json:"organization_id" gorm:"uniqueIndex:idx_contacts_org_phone;type:varchar(100)"`
+	PhoneNumber      string     `json:"phone_number" gorm:"uniqueIndex:idx_contacts_org_phone;type:varchar(50);not null" validate:"required,e164"`
+	Name             string     `json:"name" gorm:"type:varchar(255)"`
+	ProfileURL       string     `json:"profile_url,omitempty" gorm:"type:varchar(500)"`
+	LastMessageAt    *time.Time `json:"last_message_at,omitempty" gorm:"index"`
+	MessageCount     int        `json:"message_count" gorm:"default:0"`
+	UnreadCount      int        `json:"unread_count" gorm:"default:0"`
+	Consent          string     `json:"consent,omitempty" gorm:"type:varchar(20)"`
+	ConsentUpdatedAt *time.Time `json:"consent_updated_at,omitempty"`
+	Metadata         JSONMap    `json:"metadata,omitempty"`
+	// Blocked contacts cannot be messaged; their inbound messages are
+	// stored with Blocked set
+	Blocked     bool       `json:"blocked"`
+	BlockedAt   *time.Time `json:"blocked_at,omitempty"`
+	BlockReason string     `json:"block_reason,omitempty" gorm:"type:varchar(500)"`
+	// Tags are stored in contact_tags and loaded by the contact service
+	Tags      []string  `json:"tags" gorm:"-"`
+	CreatedAt time.Time `json:"created_at" gorm:"index;not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
 }
 
 // TableName specifies the table name for Contact
@@ -155,6 +255,11 @@ func (c *Contact) BeforeUpdate(tx *gorm.DB) error {
 	return nil
 }
 
+// SetOrganizationID implements OrganizationScoped
+func (c *Contact) SetOrganizationID(id string) {
+	c.OrganizationID = id
+}
+
 // Validate performs business logic validation
 func (c *Contact) Validate() error {
 	if c.PhoneNumber == "" {
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultOrganizationID is the organization that owns data created before
+// multi-tenancy was introduced and receives the credentials from the config
+const DefaultOrganizationID = "org_default"
+
+// Organization statuses
+const (
+	OrganizationStatusActive    = "active"
+	OrganizationStatusSuspended = "suspended"
+)
+
+// Organization represents a tenant (business unit) with its own WhatsApp
+// credentials and isolated contacts, messages, templates and API keys
+type Organization struct {
+	ID                 string    `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	Name               string    `json:"name" gorm:"type:varchar(255);not null" validate:"required"`
+	Status             string    `json:"status" gorm:"index;type:varchar(50);not null"`
+	PhoneNumberID      string    `json:"phone_number_id,omitempty" gorm:"index;type:varchar(100)"`
+	BusinessAccountID  string    `json:"business_account_id,omitempty" gorm:"index;type:varchar(100)"`
+	AccessToken        string    `json:"-" gorm:"type:text"`
+	WebhookVerifyToken string    `json:"-" gorm:"type:varchar(255)"`
+	WebhookSecret      string    `json:"-" gorm:"type:varchar(255)"`
+	Metadata           JSONMap   `json:"metadata,omitempty"`
+	CreatedAt          time.Time `json:"created_at" gorm:"index;not null"`
+	UpdatedAt          time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for Organization
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// BeforeCreate hook to generate ID and set timestamps
+func (o *Organization) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == "" {
+		o.ID = GenerateID("org")
+	}
+	if o.Status == "" {
+		o.Status = OrganizationStatusActive
+	}
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = time.Now().UTC()
+	}
+	if o.UpdatedAt.IsZero() {
+		o.UpdatedAt = time.Now().UTC()
+	}
+	return o.Validate()
+}
+
+// BeforeUpdate hook
+func (o *Organization) BeforeUpdate(tx *gorm.DB) error {
+	o.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// Validate performs business logic validation
+func (o *Organization) Validate() error {
+	if o.Name == "" {
+		return errors.New("name is required")
+	}
+	if o.Status != OrganizationStatusActive && o.Status != OrganizationStatusSuspended {
+		return errors.New("invalid status: " + o.Status)
+	}
+	return nil
+}
+
+// IsActive returns true if the organization is active
+func (o *Organization) IsActive() bool {
+	return o.Status == OrganizationStatusActive
+}
+
+// HasWhatsAppCredentials returns true if the organization can send messages
+func (o *Organization) HasWhatsAppCredentials() bool {
+	return o.AccessToken != "" && o.PhoneNumberID != ""
+}
+
+// OrganizationScoped is implemented by models that belong to an organization
+type OrganizationScoped interface {
+	SetOrganizationID(id string)
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RateLimitCounter is a fixed-window counter shared between replicas when
+// rate limits are kept in the database
+type RateLimitCounter struct {
+	Bucket    string    `json:"bucket" gorm:"primaryKey;type:varchar(255)"`
+	Count     int64     `json:"count" gorm:"not null;default:0"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index;not null"`
+}
+
+// TableName specifies the table name for RateLimitCounter
+func (RateLimitCounter) TableName() string {
+	return "rate_limit_counters"
+}
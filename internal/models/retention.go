@@ -0,0 +1,123 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Retention actions: what happens to messages older than a policy keeps
+const (
+	// RetentionActionArchive writes messages to an archive file before
+	// deleting them
+	RetentionActionArchive = "archive"
+	// RetentionActionDelete deletes messages
+	RetentionActionDelete = "delete"
+)
+
+// RetentionDefaultType is the message type of the policy for message types
+// without a policy of their own
+const RetentionDefaultType = "default"
+
+// retentionMessageType matches the message types a policy can name
+var retentionMessageType = regexp.MustCompile(`^[a-z][a-z_]{0,49}$`)
+
+// RetentionPolicy is how long an organization keeps messages of a type.
+// Messages older than RetainDays are archived or deleted, and the media of
+// messages older than MediaRetainDays is removed. Zero keeps them forever.
+type RetentionPolicy struct {
+	ID             string `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	OrganizationID string `json:"organization_id" gorm:"uniqueIndex:idx_retention_policies_org_type;type:varchar(100)"`
+	// MessageType is the type of messages the policy applies to, or
+	// RetentionDefaultType
+	MessageType     string    `json:"message_type" gorm:"uniqueIndex:idx_retention_policies_org_type;type:varchar(50);not null"`
+	Action          string    `json:"action" gorm:"type:varchar(20);not null"`
+	RetainDays      int       `json:"retain_days" gorm:"default:0"`
+	MediaRetainDays int       `json:"media_retain_days" gorm:"default:0"`
+	CreatedAt       time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt       time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for RetentionPolicy
+func (RetentionPolicy) TableName() string {
+	return "retention_policies"
+}
+
+// BeforeCreate hook to generate ID and set timestamps
+func (p *RetentionPolicy) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == "" {
+		p.ID = GenerateID("retention")
+	}
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now().UTC()
+	}
+	if p.UpdatedAt.IsZero() {
+		p.UpdatedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+// BeforeUpdate hook
+func (p *RetentionPolicy) BeforeUpdate(tx *gorm.DB) error {
+	p.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// SetOrganizationID implements OrganizationScoped
+func (p *RetentionPolicy) SetOrganizationID(id string) {
+	p.OrganizationID = id
+}
+
+// Validate checks the policy. Media can only be kept for less time than
+// the messages it belongs to.
+func (p *RetentionPolicy) Validate() error {
+	if !retentionMessageType.MatchString(p.MessageType) {
+		return fmt.Errorf("invalid message type %q", p.MessageType)
+	}
+	if p.Action != RetentionActionArchive && p.Action != RetentionActionDelete {
+		return fmt.Errorf("action must be %s or %s", RetentionActionArchive, RetentionActionDelete)
+	}
+	if p.RetainDays < 0 || p.MediaRetainDays < 0 {
+		return fmt.Errorf("retain_days and media_retain_days cannot be negative")
+	}
+	if p.RetainDays == 0 && p.MediaRetainDays == 0 {
+		return fmt.Errorf("retain_days or media_retain_days is required")
+	}
+	if p.RetainDays > 0 && p.MediaRetainDays > p.RetainDays {
+		return fmt.Errorf("media_retain_days cannot be more than retain_days")
+	}
+	return nil
+}
+
+// ArchivedMessage is a message in a retention archive, with its reactions
+type ArchivedMessage struct {
+	*Message
+	Reactions []*Reaction `json:"reactions,omitempty"`
+}
+
+// RetentionReport is what a retention run removed or, for a dry run, would
+// remove, by policy
+type RetentionReport struct {
+	OrganizationID string                   `json:"organization_id"`
+	DryRun         bool                     `json:"dry_run"`
+	At             time.Time                `json:"at"`
+	Policies       []*RetentionPolicyReport `json:"policies"`
+}
+
+// RetentionPolicyReport is what a retention run removed for a policy
+type RetentionPolicyReport struct {
+	MessageType string `json:"message_type"`
+	Action      string `json:"action"`
+	// MessagesBefore and MediaBefore are the cutoffs: older messages are
+	// archived or deleted, and older media is removed
+	MessagesBefore *time.Time `json:"messages_before,omitempty"`
+	MediaBefore    *time.Time `json:"media_before,omitempty"`
+	// Messages is the number of messages archived or deleted, and Media
+	// the number of messages whose media was removed
+	Messages int64 `json:"messages"`
+	Media    int64 `json:"media"`
+	// Archives are the keys of the archive files written
+	Archives []string `json:"archives,omitempty"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxTagLength is the longest tag, in characters
+const MaxTagLength = 64
+
+// ContactTag is a tag on a contact
+type ContactTag struct {
+	OrganizationID string    `json:"organization_id" gorm:"index:idx_contact_tags_org_tag;type:varchar(100)"`
+	ContactID      string    `json:"contact_id" gorm:"primaryKey;type:varchar(100)"`
+	Tag            string    `json:"tag" gorm:"primaryKey;index:idx_contact_tags_org_tag;type:varchar(64)"`
+	CreatedAt      time.Time `json:"created_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for ContactTag
+func (ContactTag) TableName() string {
+	return "contact_tags"
+}
+
+// TagCount is a tag in use and the number of contacts that have it
+type TagCount struct {
+	Tag      string `json:"tag"`
+	Contacts int64  `json:"contacts"`
+}
+
+// NormalizeTag returns the stored form of a tag: trimmed, lower-case and
+// with runs of spaces collapsed
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// Segment is a saved contact filter. Segments are dynamic: the contacts in
+// a segment are the ones matching the filter when it is read.
+type Segment struct {
+	ID             string `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	OrganizationID string `json:"organization_id" gorm:"uniqueIndex:idx_segments_org_name;type:varchar(100)"`
+	Name           string `json:"name" gorm:"uniqueIndex:idx_segments_org_name;type:varchar(255);not null"`
+	Description    string `json:"description,omitempty" gorm:"type:text"`
+	// Filter is the filter expression, see the segment filter syntax in
+	// the API reference
+	Filter string `json:"filter" gorm:"type:text;not null"`
+	// ContactCount is the number of matching contacts, set when a single
+	// segment is read
+	ContactCount *int64    `json:"contact_count,omitempty" gorm:"-"`
+	CreatedAt    time.Time `json:"created_at" gorm:"index;not null"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// TableName specifies the table name for Segment
+func (Segment) TableName() string {
+	return "segments"
+}
+
+// BeforeCreate hook to generate ID and set timestamps
+func (s *Segment) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = GenerateID("seg")
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now().UTC()
+	}
+	if s.UpdatedAt.IsZero() {
+		s.UpdatedAt = time.Now().UTC()
+	}
+	if s.Name == "" || s.Filter == "" {
+		return errors.New("name and filter are required")
+	}
+	return nil
+}
+
+// BeforeUpdate hook
+func (s *Segment) BeforeUpdate(tx *gorm.DB) error {
+	s.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// SetOrganizationID implements OrganizationScoped
+func (s *Segment) SetOrganizationID(id string) {
+	s.OrganizationID = id
+}
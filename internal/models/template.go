@@ -18,23 +18,24 @@ const (
 
 // Template categories
 const (
-	TemplateCategoryMarketing         = "marketing"
-	TemplateCategoryUtility           = "utility"
-	TemplateCategoryAuthentication    = "authentication"
+	TemplateCategoryMarketing      = "marketing"
+	TemplateCategoryUtility        = "utility"
+	TemplateCategoryAuthentication = "authentication"
 )
 
 // Template represents a WhatsApp message template
 type Template struct {
-	ID          string     `json:"id" gorm:"primaryKey;type:varchar(100)"`
-	Name        string     `json:"name" gorm:"index;type:varchar(255);not null" validate:"required"`
-	Language    string     `json:"language" gorm:"type:varchar(10);not null" validate:"required"`
-	Category    string     `json:"category" gorm:"type:varchar(50);not null" validate:"required"`
-	Status      string     `json:"status" gorm:"index;type:varchar(50);not null" validate:"required"`
-	Content     string     `json:"content" gorm:"type:text;not null" validate:"required"`
-	Parameters  JSONArray  `json:"parameters,omitempty" gorm:"type:jsonb"`
-	Metadata    JSONMap    `json:"metadata,omitempty" gorm:"type:jsonb"`
-	CreatedAt   time.Time  `json:"created_at" gorm:"index;not null"`
-	UpdatedAt   time.Time  `json:"updated_at" gorm:"not null"`
+	ID             string    `json:"id" gorm:"primaryKey;type:varchar(100)"`
+	OrganizationID string    `json:"organization_id" gorm:"index;type:varchar(100)"`
+	Name           string    `json:"name" gorm:"index;type:varchar(255);not null" validate:"required"`
+	Language       string    `json:"language" gorm:"type:varchar(10);not null" validate:"required"`
+	Category       string    `json:"category" gorm:"type:varchar(50);not null" validate:"required"`
+	Status         string    `json:"status" gorm:"index;type:varchar(50);not null" validate:"required"`
+	Content        string    `json:"content" gorm:"type:text;not null" validate:"required"`
+	Parameters     JSONArray `json:"parameters,omitempty"`
+	Metadata       JSONMap   `json:"metadata,omitempty"`
+	CreatedAt      time.Time `json:"created_at" gorm:"index;not null"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"not null"`
 }
 
 // TableName specifies the table name for Template
@@ -95,6 +96,11 @@ func (t *Template) Validate() error {
 	return nil
 }
 
+// SetOrganizationID implements OrganizationScoped
+func (t *Template) SetOrganizationID(id string) {
+	t.OrganizationID = id
+}
+
 // IsApproved returns true if the template is approved
 func (t *Template) IsApproved() bool {
 	return t.Status == TemplateStatusApproved
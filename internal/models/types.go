@@ -3,13 +3,26 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
-	"errors"
 	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
-// JSONMap represents a JSON object stored as JSONB in PostgreSQL
+// JSONMap represents a JSON object stored as JSONB in PostgreSQL and as
+// TEXT in SQLite
 type JSONMap map[string]interface{}
 
+// GormDataType implements schema.GormDataTypeInterface for JSONMap
+func (JSONMap) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType implements schema.GormDBDataTypeInterface for JSONMap
+func (JSONMap) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return jsonDataType(db)
+}
+
 // Value implements the driver.Valuer interface for JSONMap
 func (j JSONMap) Value() (driver.Value, error) {
 	if j == nil {
@@ -25,9 +38,9 @@ func (j *JSONMap) Scan(value interface{}) error {
 		return nil
 	}
 
-	bytes, ok := value.([]byte)
-	if !ok {
-		return errors.New("type assertion to []byte failed")
+	bytes, err := scanBytes(value)
+	if err != nil {
+		return err
 	}
 
 	if len(bytes) == 0 {
@@ -44,9 +57,20 @@ func (j *JSONMap) Scan(value interface{}) error {
 	return nil
 }
 
-// JSONArray represents a JSON array stored as JSONB in PostgreSQL
+// JSONArray represents a JSON array stored as JSONB in PostgreSQL and as
+// TEXT in SQLite
 type JSONArray []string
 
+// GormDataType implements schema.GormDataTypeInterface for JSONArray
+func (JSONArray) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType implements schema.GormDBDataTypeInterface for JSONArray
+func (JSONArray) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return jsonDataType(db)
+}
+
 // Value implements the driver.Valuer interface for JSONArray
 func (j JSONArray) Value() (driver.Value, error) {
 	if j == nil {
@@ -62,9 +86,9 @@ func (j *JSONArray) Scan(value interface{}) error {
 		return nil
 	}
 
-	bytes, ok := value.([]byte)
-	if !ok {
-		return errors.New("type assertion to []byte failed")
+	bytes, err := scanBytes(value)
+	if err != nil {
+		return err
 	}
 
 	if len(bytes) == 0 {
@@ -80,3 +104,24 @@ func (j *JSONArray) Scan(value interface{}) error {
 	*j = result
 	return nil
 }
+
+// jsonDataType returns the column type JSON values are stored in
+func jsonDataType(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return "jsonb"
+	}
+	return "text"
+}
+
+// scanBytes returns the raw JSON of a scanned column. Postgres returns
+// []byte; SQLite returns string for TEXT columns.
+func scanBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("cannot scan %T into a JSON value", value)
+	}
+}
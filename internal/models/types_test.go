@@ -0,0 +1,24 @@
+package models
+
+import "testing"
+
+func TestJSONScan(t *testing.T) {
+	// Postgres returns []byte, SQLite returns string for TEXT columns
+	for _, raw := range []interface{}{[]byte(`{"plan":"pro"}`), `{"plan":"pro"}`} {
+		var m JSONMap
+		if err := m.Scan(raw); err != nil || m["plan"] != "pro" {
+			t.Errorf("JSONMap.Scan(%T) = %v, %v", raw, m, err)
+		}
+	}
+	for _, raw := range []interface{}{[]byte(`["a","b"]`), `["a","b"]`} {
+		var a JSONArray
+		if err := a.Scan(raw); err != nil || len(a) != 2 || a[1] != "b" {
+			t.Errorf("JSONArray.Scan(%T) = %v, %v", raw, a, err)
+		}
+	}
+
+	var m JSONMap
+	if err := m.Scan(42); err == nil {
+		t.Error("scanning a number should fail")
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired counters are evicted from memory
+const sweepInterval = time.Minute
+
+// MemoryStore keeps counters in process memory. Limits are per replica.
+type MemoryStore struct {
+	counters  map[string]memoryCounter
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+type memoryCounter struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an in-memory counter store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters:  make(map[string]memoryCounter),
+		lastSweep: time.Now(),
+	}
+}
+
+// Increment implements Store
+func (s *MemoryStore) Increment(ctx context.Context, key string, expiresAt time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	// Keys are unique per window, so a counter never needs resetting; it
+	// only has to be evicted once its window is over
+	counter := s.counters[key]
+	counter.count++
+	counter.expiresAt = expiresAt
+	s.counters[key] = counter
+
+	return counter.count, nil
+}
+
+// Decrement implements Store
+func (s *MemoryStore) Decrement(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if counter, ok := s.counters[key]; ok && counter.count > 0 {
+		counter.count--
+		s.counters[key] = counter
+	}
+	return nil
+}
+
+// Len returns the number of counters held in memory
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.counters)
+}
+
+// sweep drops expired counters so idle subjects don't accumulate
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, counter := range s.counters {
+		if now.After(counter.expiresAt) {
+			delete(s.counters, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,124 @@
+// Package ratelimit implements fixed-window request limits and quotas on top
+// of a pluggable counter store, so limits can be kept in process or shared
+// between replicas.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Store keeps the counters behind the limits
+type Store interface {
+	// Increment adds one to the counter for key and returns the new value.
+	// The counter may be discarded once expiresAt has passed.
+	Increment(ctx context.Context, key string, expiresAt time.Time) (int64, error)
+	// Decrement takes one off the counter for key, if it is above zero
+	Decrement(ctx context.Context, key string) error
+}
+
+// Window describes how a limit's counting period is aligned
+type Window int
+
+const (
+	// Minute resets at the start of every minute
+	Minute Window = iota
+	// Day resets at midnight UTC
+	Day
+	// Month resets at midnight UTC on the first day of the month
+	Month
+)
+
+// String returns the window name used in counter keys and headers
+func (w Window) String() string {
+	switch w {
+	case Day:
+		return "daily"
+	case Month:
+		return "monthly"
+	default:
+		return "minute"
+	}
+}
+
+// bounds returns the start and end of the window containing t
+func (w Window) bounds(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	switch w {
+	case Day:
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1)
+	case Month:
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		start := t.Truncate(time.Minute)
+		return start, start.Add(time.Minute)
+	}
+}
+
+// Result is the outcome of a limit check
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	ResetAt   time.Time
+
+	key string // the counter the hit was counted on, for Refund
+}
+
+// RetryAfter returns how long the caller should wait before trying again
+func (r Result) RetryAfter(now time.Time) time.Duration {
+	if d := r.ResetAt.Sub(now); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Limiter checks limits against a Store
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+// NewLimiter creates a limiter backed by the given store
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Allow counts one hit for subject in the current window and reports whether
+// it is within limit. Rejected hits are counted as well.
+func (l *Limiter) Allow(ctx context.Context, subject string, window Window, limit int) (Result, error) {
+	start, end := window.bounds(l.now())
+	key := fmt.Sprintf("%s:%s:%d", subject, window, start.Unix())
+
+	count, err := l.store.Increment(ctx, key, end)
+	if err != nil {
+		return Result{}, err
+	}
+
+	remaining := limit - int(count)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Result{
+		Allowed:   count <= int64(limit),
+		Limit:     limit,
+		Remaining: remaining,
+		ResetAt:   end,
+		key:       key,
+	}, nil
+}
+
+// Refund takes back the hit counted by Allow, e.g. for a request that was
+// rejected or failed and should not count against a quota
+func (l *Limiter) Refund(ctx context.Context, result Result) error {
+	if result.key == "" {
+		return nil
+	}
+	return l.store.Decrement(ctx, result.key)
+}
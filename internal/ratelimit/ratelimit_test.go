@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore())
+	limiter.now = func() time.Time { return time.Date(2024, 3, 15, 10, 30, 20, 0, time.UTC) }
+
+	for i := 1; i <= 3; i++ {
+		result, err := limiter.Allow(context.Background(), "key_1", Minute, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Allowed || result.Remaining != 3-i {
+			t.Fatalf("hit %d: got allowed=%v remaining=%d", i, result.Allowed, result.Remaining)
+		}
+	}
+
+	result, _ := limiter.Allow(context.Background(), "key_1", Minute, 3)
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected fourth hit to be rejected, got %+v", result)
+	}
+	if want := time.Date(2024, 3, 15, 10, 31, 0, 0, time.UTC); !result.ResetAt.Equal(want) {
+		t.Fatalf("ResetAt = %v, want %v", result.ResetAt, want)
+	}
+
+	// Other subjects have their own counters
+	if result, _ := limiter.Allow(context.Background(), "key_2", Minute, 3); !result.Allowed {
+		t.Fatal("expected other subject to be allowed")
+	}
+}
+
+func TestLimiterRefund(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore())
+	ctx := context.Background()
+
+	first, _ := limiter.Allow(ctx, "key_1", Day, 1)
+	if err := limiter.Refund(ctx, first); err != nil {
+		t.Fatalf("Refund: %v", err)
+	}
+	if result, _ := limiter.Allow(ctx, "key_1", Day, 1); !result.Allowed {
+		t.Fatal("expected a refunded hit not to count")
+	}
+	if result, _ := limiter.Allow(ctx, "key_1", Day, 1); result.Allowed {
+		t.Fatal("expected the limit to apply again")
+	}
+	if err := limiter.Refund(ctx, Result{}); err != nil {
+		t.Fatalf("refunding an empty result should be a no-op: %v", err)
+	}
+}
+
+func TestWindowBounds(t *testing.T) {
+	now := time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)
+
+	tests := []struct {
+		window     Window
+		start, end time.Time
+	}{
+		{Minute, time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Day, time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Month, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		start, end := tt.window.bounds(now)
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("%s: got [%v, %v), want [%v, %v)", tt.window, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestMemoryStoreEvictsExpiredCounters(t *testing.T) {
+	store := NewMemoryStore()
+	past := time.Now().Add(-time.Second)
+
+	store.Increment(context.Background(), "idle", past)
+	store.lastSweep = time.Now().Add(-sweepInterval)
+	store.Increment(context.Background(), "active", time.Now().Add(time.Minute))
+
+	if n := store.Len(); n != 1 {
+		t.Fatalf("expected idle counter to be evicted, %d counters left", n)
+	}
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"time"
 
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
@@ -19,6 +20,16 @@ func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
 	}
 }
 
+// ForOrganization returns a repository scoped to the given organization
+func (r *APIKeyRepository) ForOrganization(orgID string) APIKeyStore {
+	return &APIKeyRepository{BaseRepository: r.BaseRepository.ForOrganization(orgID)}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *APIKeyRepository) WithContext(ctx context.Context) APIKeyStore {
+	return &APIKeyRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
 // FindByKeyHash finds an API key by its hash
 func (r *APIKeyRepository) FindByKeyHash(keyHash string) (*models.APIKey, error) {
 	var apiKey models.APIKey
@@ -40,10 +51,32 @@ func (r *APIKeyRepository) UpdateLastUsed(id string) error {
 		Update("last_used_at", time.Now().UTC()).Error
 }
 
-// FindValid finds all valid (not expired) API keys
+// UpdateLastUsedBatch writes last_used_at for several keys in one transaction
+func (r *APIKeyRepository) UpdateLastUsedBatch(lastUsed map[string]time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for id, usedAt := range lastUsed {
+			if err := tx.Model(&models.APIKey{}).
+				Where("id = ?", id).
+				UpdateColumn("last_used_at", usedAt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateKeyHash replaces the stored hash of a key
+func (r *APIKeyRepository) UpdateKeyHash(id, keyHash string) error {
+	return r.DB.Model(&models.APIKey{}).
+		Where("id = ?", id).
+		UpdateColumn("key_hash", keyHash).Error
+}
+
+// FindValid finds all valid (not expired or revoked) API keys
 func (r *APIKeyRepository) FindValid() ([]*models.APIKey, error) {
 	var apiKeys []*models.APIKey
 	err := r.DB.Where("expires_at IS NULL OR expires_at > ?", time.Now().UTC()).
+		Where("revoked_at IS NULL").
 		Find(&apiKeys).Error
 	return apiKeys, err
 }
@@ -56,9 +89,39 @@ func (r *APIKeyRepository) FindExpired() ([]*models.APIKey, error) {
 	return apiKeys, err
 }
 
+// FindUpdatedSince finds the API keys changed after since, e.g. revoked
+// or rotated. Last-used updates do not count as changes.
+func (r *APIKeyRepository) FindUpdatedSince(since time.Time) ([]*models.APIKey, error) {
+	var apiKeys []*models.APIKey
+	err := r.DB.Where("updated_at > ?", since).Find(&apiKeys).Error
+	return apiKeys, err
+}
+
 // ListAll lists all API keys
 func (r *APIKeyRepository) ListAll() ([]*models.APIKey, error) {
 	var apiKeys []*models.APIKey
 	err := r.DB.Order("created_at DESC").Find(&apiKeys).Error
 	return apiKeys, err
 }
+
+// Revoke marks an API key as revoked. The row is kept for auditing.
+func (r *APIKeyRepository) Revoke(id, reason string) error {
+	return r.DB.Model(&models.APIKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now().UTC(),
+			"revoked_reason": reason,
+		}).Error
+}
+
+// RevokeAll revokes every key that is not revoked yet, e.g. all keys of an
+// organization that is deleted, and returns how many were revoked
+func (r *APIKeyRepository) RevokeAll(reason string) (int64, error) {
+	result := r.DB.Model(&models.APIKey{}).
+		Where("revoked_at IS NULL").
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now().UTC(),
+			"revoked_reason": reason,
+		})
+	return result.RowsAffected, result.Error
+}
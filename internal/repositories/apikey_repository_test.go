@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestAPIKeyRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewAPIKeyRepository(db).ForOrganization("org_a").(*APIKeyRepository)
+		other := NewAPIKeyRepository(db).ForOrganization("org_b")
+
+		past := time.Now().UTC().Add(-time.Hour)
+		active := &models.APIKey{Name: "active", KeyHash: "hash-active", KeyPrefix: "wa_abc",
+			Permissions: models.JSONArray{models.PermissionMessagesSend}}
+		expired := &models.APIKey{Name: "expired", KeyHash: "hash-expired", KeyPrefix: "wa_abc", ExpiresAt: &past}
+		for _, k := range []*models.APIKey{active, expired} {
+			if err := repo.Create(k); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		other.Create(&models.APIKey{Name: "other", KeyHash: "hash-other", KeyPrefix: "wa_abc"})
+
+		found, err := repo.FindByKeyHash("hash-active")
+		if err != nil || found.ID != active.ID || !found.HasPermission(models.PermissionMessagesSend) {
+			t.Errorf("FindByKeyHash: %+v %v", found, err)
+		}
+		if _, err := repo.FindByKeyHash("hash-other"); err == nil {
+			t.Error("another organization's key must not be found")
+		}
+
+		byPrefix, err := repo.FindByKeyPrefix("wa_abc")
+		if err != nil || len(byPrefix) != 2 {
+			t.Errorf("FindByKeyPrefix: %d %v", len(byPrefix), err)
+		}
+
+		valid, err := repo.FindValid()
+		if err != nil || len(valid) != 1 || valid[0].ID != active.ID {
+			t.Errorf("FindValid: %d %v", len(valid), err)
+		}
+		expiredKeys, err := repo.FindExpired()
+		if err != nil || len(expiredKeys) != 1 || expiredKeys[0].ID != expired.ID {
+			t.Errorf("FindExpired: %d %v", len(expiredKeys), err)
+		}
+
+		if err := repo.UpdateLastUsed(active.ID); err != nil {
+			t.Fatalf("UpdateLastUsed: %v", err)
+		}
+		usedAt := time.Now().UTC().Add(time.Minute).Truncate(time.Second)
+		if err := repo.UpdateLastUsedBatch(map[string]time.Time{active.ID: usedAt, expired.ID: usedAt}); err != nil {
+			t.Fatalf("UpdateLastUsedBatch: %v", err)
+		}
+		if err := repo.UpdateKeyHash(active.ID, "hash-rotated"); err != nil {
+			t.Fatalf("UpdateKeyHash: %v", err)
+		}
+		found, err = repo.FindByKeyHash("hash-rotated")
+		if err != nil || found.LastUsedAt == nil || !found.LastUsedAt.Equal(usedAt) {
+			t.Errorf("after updates: %+v %v", found, err)
+		}
+
+		since := time.Now().UTC()
+		time.Sleep(10 * time.Millisecond)
+		if err := repo.Revoke(active.ID, "leaked"); err != nil {
+			t.Fatalf("Revoke: %v", err)
+		}
+		found, _ = repo.FindByKeyHash("hash-rotated")
+		if !found.IsRevoked() || found.RevokedReason != "leaked" {
+			t.Errorf("Revoke did not apply: %+v", found)
+		}
+		changed, err := NewAPIKeyRepository(db).FindUpdatedSince(since)
+		if err != nil || len(changed) != 1 || changed[0].ID != active.ID {
+			t.Errorf("FindUpdatedSince should find only the revoked key: %d %v", len(changed), err)
+		}
+
+		all, err := repo.ListAll()
+		if err != nil || len(all) != 2 {
+			t.Errorf("ListAll: %d %v", len(all), err)
+		}
+
+		revoked, err := repo.RevokeAll("organization deleted")
+		if err != nil || revoked != 1 {
+			t.Errorf("RevokeAll should revoke only the unrevoked key: %d %v", revoked, err)
+		}
+		found, _ = repo.FindByKeyHash("hash-rotated")
+		if found.RevokedReason != "leaked" {
+			t.Errorf("RevokeAll should keep earlier revocations: %+v", found)
+		}
+		if key, _ := NewAPIKeyRepository(db).FindByKeyHash("hash-other"); key.IsRevoked() {
+			t.Error("RevokeAll should not revoke keys of other organizations")
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// AttributeRepository handles custom attribute definitions
+type AttributeRepository struct {
+	*BaseRepository
+}
+
+// NewAttributeRepository creates a new attribute repository
+func NewAttributeRepository(db *gorm.DB) *AttributeRepository {
+	return &AttributeRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// ForOrganization returns a repository scoped to the given organization
+func (r *AttributeRepository) ForOrganization(orgID string) AttributeStore {
+	return &AttributeRepository{BaseRepository: r.BaseRepository.ForOrganization(orgID)}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *AttributeRepository) WithContext(ctx context.Context) AttributeStore {
+	return &AttributeRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
+// FindByName finds an attribute definition by name
+func (r *AttributeRepository) FindByName(name string) (*models.AttributeDefinition, error) {
+	var definition models.AttributeDefinition
+	err := r.DB.Where("name = ?", name).First(&definition).Error
+	return &definition, err
+}
+
+// ListAll lists all attribute definitions by name. Organizations have few
+// of them, so they are not paginated.
+func (r *AttributeRepository) ListAll() ([]*models.AttributeDefinition, error) {
+	return listAttributeDefinitions(r.DB)
+}
+
+func listAttributeDefinitions(db *gorm.DB) ([]*models.AttributeDefinition, error) {
+	var definitions []*models.AttributeDefinition
+	err := db.Model(&models.AttributeDefinition{}).Order("name ASC").Find(&definitions).Error
+	return definitions, err
+}
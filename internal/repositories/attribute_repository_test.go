@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestAttributeRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewAttributeRepository(db).ForOrganization("org_a")
+		other := NewAttributeRepository(db).ForOrganization("org_b")
+
+		plan := &models.AttributeDefinition{Name: "plan", Type: models.AttributeTypeEnum, Options: models.JSONArray{"free", "pro"}, Required: true}
+		seats := &models.AttributeDefinition{Name: "seats", Type: models.AttributeTypeNumber}
+		for _, attribute := range []*models.AttributeDefinition{seats, plan} {
+			if err := repo.Create(attribute); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		if err := repo.Create(&models.AttributeDefinition{Name: "plan", Type: models.AttributeTypeString}); err == nil {
+			t.Error("attribute names should be unique per organization")
+		}
+		if err := other.Create(&models.AttributeDefinition{Name: "plan", Type: models.AttributeTypeString}); err != nil {
+			t.Errorf("the same name in another organization: %v", err)
+		}
+
+		found, err := repo.FindByName("plan")
+		if err != nil || found.ID != plan.ID || !found.Required || len(found.Options) != 2 || found.Options[1] != "pro" {
+			t.Errorf("FindByName: %+v %v", found, err)
+		}
+
+		all, err := repo.ListAll()
+		if err != nil || len(all) != 2 || all[0].Name != "plan" {
+			t.Errorf("ListAll should sort by name: %d %v", len(all), err)
+		}
+
+		if err := repo.UpdateFields(plan.ID, plan, map[string]interface{}{"options": models.JSONArray{"free"}, "required": false}); err != nil {
+			t.Fatalf("UpdateFields: %v", err)
+		}
+		if found, _ := repo.FindByName("plan"); len(found.Options) != 1 || found.Required {
+			t.Errorf("UpdateFields did not apply: %+v", found)
+		}
+
+		if err := repo.Delete(plan); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.FindByName("plan"); err == nil {
+			t.Error("deleted attribute should be gone")
+		}
+		if _, err := other.FindByName("plan"); err != nil {
+			t.Errorf("attribute of another organization should remain: %v", err)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// AuditLogRepository handles audit log data access
+type AuditLogRepository struct {
+	*BaseRepository
+}
+
+// NewAuditLogRepository creates a new audit log repository
+func NewAuditLogRepository(db *gorm.DB) *AuditLogRepository {
+	return &AuditLogRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// ForOrganization returns a repository scoped to the given organization
+func (r *AuditLogRepository) ForOrganization(orgID string) AuditLogStore {
+	return &AuditLogRepository{BaseRepository: r.BaseRepository.ForOrganization(orgID)}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *AuditLogRepository) WithContext(ctx context.Context) AuditLogStore {
+	return &AuditLogRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
+// ListWithFilters lists audit log entries, newest first, with pagination.
+// filters["action"] and filters["resource_id"] restrict the entries when
+// they are set.
+func (r *AuditLogRepository) ListWithFilters(filters map[string]interface{}, pagination *utils.Pagination) ([]*models.AuditLog, error) {
+	var entries []*models.AuditLog
+
+	query := r.DB.Model(&models.AuditLog{})
+	if action, ok := filters["action"].(string); ok && action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if resourceID, ok := filters["resource_id"].(string); ok && resourceID != "" {
+		query = query.Where("resource_id = ?", resourceID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	pagination.SetTotal(total)
+
+	err := pagination.ApplyToQuery(query.Order("created_at DESC").Order("id DESC")).Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+func TestAuditLogRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewAuditLogRepository(db).ForOrganization("org_a")
+		other := NewAuditLogRepository(db).ForOrganization("org_b")
+
+		at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		for i, entry := range []*models.AuditLog{
+			{Action: models.AuditActionContactMerge, ResourceType: "contact", ResourceID: "contact_1", ActorID: "key_1", Details: models.JSONMap{"merged_contact_ids": []string{"contact_2"}}},
+			{Action: models.AuditActionContactMerge, ResourceType: "contact", ResourceID: "contact_3"},
+			{Action: "contact.delete", ResourceType: "contact", ResourceID: "contact_1"},
+		} {
+			entry.CreatedAt = at.Add(time.Duration(i) * time.Minute)
+			if err := repo.Create(entry); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		if err := other.Create(&models.AuditLog{Action: models.AuditActionContactMerge, ResourceType: "contact", ResourceID: "contact_1"}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		pagination := utils.NewPagination(10, 0)
+		entries, err := repo.ListWithFilters(map[string]interface{}{}, pagination)
+		if err != nil || len(entries) != 3 || pagination.Total != 3 {
+			t.Fatalf("ListWithFilters: %+v %v", entries, err)
+		}
+		if entries[0].Action != "contact.delete" || entries[2].ActorID != "key_1" || entries[2].OrganizationID != "org_a" {
+			t.Errorf("expected the newest entry first: %+v", entries)
+		}
+		if ids, _ := entries[2].Details["merged_contact_ids"].([]interface{}); len(ids) != 1 || ids[0] != "contact_2" {
+			t.Errorf("unexpected details %v", entries[2].Details)
+		}
+
+		entries, _ = repo.ListWithFilters(map[string]interface{}{"action": models.AuditActionContactMerge, "resource_id": "contact_1"}, utils.NewPagination(10, 0))
+		if len(entries) != 1 || entries[0].ResourceID != "contact_1" || entries[0].Action != models.AuditActionContactMerge {
+			t.Errorf("filters not applied: %+v", entries)
+		}
+	})
+}
@@ -1,6 +1,9 @@
 package repositories
 
 import (
+	"context"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
 	"gorm.io/gorm"
 )
@@ -8,15 +11,50 @@ import (
 // BaseRepository provides common CRUD operations
 type BaseRepository struct {
 	DB *gorm.DB
+
+	// OrganizationID is set on repositories scoped to a single tenant. Every
+	// query issued through DB is then filtered by organization_id.
+	OrganizationID string
+
+	// Dialect builds the SQL that differs between databases
+	Dialect Dialect
 }
 
 // NewBaseRepository creates a new base repository
 func NewBaseRepository(db *gorm.DB) *BaseRepository {
-	return &BaseRepository{DB: db}
+	return &BaseRepository{DB: db, Dialect: DialectFor(db)}
+}
+
+// ForOrganization returns a copy of the repository whose queries are
+// restricted to the given organization
+func (r *BaseRepository) ForOrganization(orgID string) *BaseRepository {
+	root := r.DB
+	if r.OrganizationID != "" {
+		// Re-scoping an already scoped repository must not stack conditions
+		root = r.DB.Session(&gorm.Session{NewDB: true})
+	}
+	return &BaseRepository{
+		DB:             root.Where("organization_id = ?", orgID).Session(&gorm.Session{}),
+		OrganizationID: orgID,
+		Dialect:        r.Dialect,
+	}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx,
+// so they are cancelled together with the request
+func (r *BaseRepository) WithContext(ctx context.Context) *BaseRepository {
+	return &BaseRepository{
+		DB:             r.DB.WithContext(ctx),
+		OrganizationID: r.OrganizationID,
+		Dialect:        r.Dialect,
+	}
 }
 
 // Create creates a new record
 func (r *BaseRepository) Create(model interface{}) error {
+	if scoped, ok := model.(models.OrganizationScoped); ok && r.OrganizationID != "" {
+		scoped.SetOrganizationID(r.OrganizationID)
+	}
 	return r.DB.Create(model).Error
 }
 
@@ -32,6 +70,11 @@ func (r *BaseRepository) Update(model interface{}) error {
 
 // UpdateFields updates specific fields
 func (r *BaseRepository) UpdateFields(id string, model interface{}, updates map[string]interface{}) error {
+	if r.OrganizationID != "" {
+		// Rows can never be moved to another organization
+		delete(updates, "organization_id")
+		delete(updates, "OrganizationID")
+	}
 	return r.DB.Model(model).Where("id = ?", id).Updates(updates).Error
 }
 
@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// CallRepository handles call and transcript data access
+type CallRepository struct {
+	*BaseRepository
+}
+
+// NewCallRepository creates a new call repository
+func NewCallRepository(db *gorm.DB) *CallRepository {
+	return &CallRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// ForOrganization returns a repository scoped to the given organization
+func (r *CallRepository) ForOrganization(orgID string) CallStore {
+	return &CallRepository{BaseRepository: r.BaseRepository.ForOrganization(orgID)}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *CallRepository) WithContext(ctx context.Context) CallStore {
+	return &CallRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
+// FindByPhone finds the calls from or to a phone number, with or without
+// its +, newest first
+func (r *CallRepository) FindByPhone(phone string) ([]*models.Call, error) {
+	var calls []*models.Call
+	phones := phoneForms(phone)
+	err := r.DB.Where("from_number IN ? OR to_number IN ?", phones, phones).
+		Order("started_at DESC").
+		Find(&calls).Error
+	return calls, err
+}
+
+// FindTranscripts finds the transcripts of the calls with the given IDs
+func (r *CallRepository) FindTranscripts(callIDs []string) ([]*models.Transcript, error) {
+	var transcripts []*models.Transcript
+	if len(callIDs) == 0 {
+		return transcripts, nil
+	}
+	err := r.DB.Where("call_id IN ?", callIDs).Order("processed_at ASC").Find(&transcripts).Error
+	return transcripts, err
+}
+
+// FindSegments finds the segments of the transcripts with the given IDs,
+// in the order they were spoken
+func (r *CallRepository) FindSegments(transcriptIDs []string) ([]*models.TranscriptSegment, error) {
+	var segments []*models.TranscriptSegment
+	if len(transcriptIDs) == 0 {
+		return segments, nil
+	}
+	err := r.DB.Where("transcript_id IN ?", transcriptIDs).
+		Order("transcript_id ASC").Order("start_time ASC").
+		Find(&segments).Error
+	return segments, err
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestCallRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewCallRepository(db).ForOrganization("org_a")
+
+		at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		for _, row := range []interface{}{
+			&models.Call{ID: "call_1", OrganizationID: "org_a", FromNumber: "+447700900123", ToNumber: "15550000000", StartedAt: at},
+			&models.Call{ID: "call_2", OrganizationID: "org_a", FromNumber: "15550000000", ToNumber: "447700900123", StartedAt: at.Add(time.Hour)},
+			&models.Call{ID: "call_3", OrganizationID: "org_a", FromNumber: "447700900124", ToNumber: "15550000000", StartedAt: at},
+			&models.Call{ID: "call_4", OrganizationID: "org_b", FromNumber: "447700900123", ToNumber: "15550000001", StartedAt: at},
+			&models.Transcript{ID: "transcript_1", OrganizationID: "org_a", CallID: "call_1", ProcessedAt: at},
+			&models.TranscriptSegment{ID: "segment_2", OrganizationID: "org_a", TranscriptID: "transcript_1", StartTime: 4},
+			&models.TranscriptSegment{ID: "segment_1", OrganizationID: "org_a", TranscriptID: "transcript_1", StartTime: 0},
+		} {
+			if err := db.Create(row).Error; err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		calls, err := repo.FindByPhone("447700900123")
+		if err != nil || len(calls) != 2 || calls[0].ID != "call_2" || calls[1].ID != "call_1" {
+			t.Fatalf("expected the organization's calls with the number, newest first: %+v %v", calls, err)
+		}
+		transcripts, err := repo.FindTranscripts([]string{"call_1", "call_2"})
+		if err != nil || len(transcripts) != 1 || transcripts[0].ID != "transcript_1" {
+			t.Fatalf("unexpected transcripts %+v %v", transcripts, err)
+		}
+		segments, err := repo.FindSegments([]string{"transcript_1"})
+		if err != nil || len(segments) != 2 || segments[0].ID != "segment_1" {
+			t.Errorf("segments should be in the order they were spoken: %+v %v", segments, err)
+		}
+		if none, err := repo.FindTranscripts(nil); err != nil || len(none) != 0 {
+			t.Errorf("expected no transcripts without calls: %+v %v", none, err)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// ContactImportRepository handles contact import data access
+type ContactImportRepository struct {
+	*BaseRepository
+}
+
+// NewContactImportRepository creates a new contact import repository
+func NewContactImportRepository(db *gorm.DB) *ContactImportRepository {
+	return &ContactImportRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// ForOrganization returns a repository scoped to the given organization
+func (r *ContactImportRepository) ForOrganization(orgID string) ContactImportStore {
+	return &ContactImportRepository{BaseRepository: r.BaseRepository.ForOrganization(orgID)}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *ContactImportRepository) WithContext(ctx context.Context) ContactImportStore {
+	return &ContactImportRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
+// ListAll lists imports, newest first, with pagination
+func (r *ContactImportRepository) ListAll(pagination *utils.Pagination) ([]*models.ContactImport, error) {
+	var imports []*models.ContactImport
+
+	query := r.DB.Model(&models.ContactImport{}).Order("created_at DESC")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	pagination.SetTotal(total)
+
+	err := pagination.ApplyToQuery(query).Find(&imports).Error
+	return imports, err
+}
+
+// FindByStatus finds the imports with any of the statuses
+func (r *ContactImportRepository) FindByStatus(statuses ...string) ([]*models.ContactImport, error) {
+	var imports []*models.ContactImport
+	err := r.DB.Where("status IN ?", statuses).Order("created_at ASC").Find(&imports).Error
+	return imports, err
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+func TestContactImportRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactImportRepository(db).ForOrganization("org_a")
+		other := NewContactImportRepository(db).ForOrganization("org_b")
+
+		done := &models.ContactImport{Format: models.ImportFormatCSV, Status: models.ImportStatusCompleted, Mapping: models.JSONMap{"Phone": "phone_number"}}
+		running := &models.ContactImport{Format: models.ImportFormatJSONL, Status: models.ImportStatusRunning}
+		for _, job := range []*models.ContactImport{done, running} {
+			if err := repo.Create(job); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		if err := other.Create(&models.ContactImport{Format: models.ImportFormatCSV, Status: models.ImportStatusPending}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		rowErrors := models.ImportRowErrors{{Row: 3, Error: "invalid phone number"}}
+		if err := repo.UpdateFields(done.ID, done, map[string]interface{}{"failed": 1, "errors": rowErrors}); err != nil {
+			t.Fatalf("UpdateFields: %v", err)
+		}
+		var found models.ContactImport
+		if err := repo.FindByID(done.ID, &found); err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if found.Failed != 1 || len(found.Errors) != 1 || found.Errors[0].Row != 3 || found.Mapping["Phone"] != "phone_number" {
+			t.Errorf("unexpected import %+v", found)
+		}
+
+		active, err := repo.FindByStatus(models.ImportStatusPending, models.ImportStatusRunning)
+		if err != nil || len(active) != 1 || active[0].ID != running.ID {
+			t.Errorf("FindByStatus should find the organization's running import: %v %v", active, err)
+		}
+		all, err := NewContactImportRepository(db).FindByStatus(models.ImportStatusPending, models.ImportStatusRunning)
+		if err != nil || len(all) != 2 {
+			t.Errorf("unscoped FindByStatus should find every organization's imports: %d %v", len(all), err)
+		}
+
+		pagination := utils.NewPagination(10, 0)
+		imports, err := repo.ListAll(pagination)
+		if err != nil || len(imports) != 2 || pagination.Total != 2 {
+			t.Errorf("ListAll: %d %v", len(imports), err)
+		}
+	})
+}
+
+func TestContactImportOneActivePerOrganization(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactImportRepository(db).ForOrganization("org_a")
+
+		running := &models.ContactImport{Format: models.ImportFormatCSV, Status: models.ImportStatusRunning}
+		if err := repo.Create(running); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := repo.Create(&models.ContactImport{Format: models.ImportFormatCSV, Status: models.ImportStatusPending}); err == nil {
+			t.Error("a second active import of the organization should be rejected")
+		}
+		if err := NewContactImportRepository(db).ForOrganization("org_b").Create(&models.ContactImport{Format: models.ImportFormatCSV, Status: models.ImportStatusPending}); err != nil {
+			t.Errorf("other organizations should not be blocked: %v", err)
+		}
+
+		if err := repo.UpdateFields(running.ID, running, map[string]interface{}{"status": models.ImportStatusCompleted}); err != nil {
+			t.Fatalf("UpdateFields: %v", err)
+		}
+		if err := repo.Create(&models.ContactImport{Format: models.ImportFormatCSV, Status: models.ImportStatusPending}); err != nil {
+			t.Errorf("finished imports should not block new ones: %v", err)
+		}
+	})
+}
@@ -1,14 +1,28 @@
 package repositories
 
 import (
+	"context"
+	"strings"
 	"time"
 
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
+// contactSortFields are the columns contacts can be listed by, with the
+// kind of their values in cursors
+var contactSortFields = map[string]keyKind{
+	"name":            keyString,
+	"phone_number":    keyString,
+	"last_message_at": keyTime,
+	"message_count":   keyInt,
+	"unread_count":    keyInt,
+	"created_at":      keyTime,
+}
+
 // ContactRepository handles contact data access
 type ContactRepository struct {
 	*BaseRepository
@@ -21,20 +35,40 @@ func NewContactRepository(db *gorm.DB) *ContactRepository {
 	}
 }
 
-// FindByPhone finds a contact by phone number
+// ForOrganization returns a repository scoped to the given organization
+func (r *ContactRepository) ForOrganization(orgID string) ContactStore {
+	return &ContactRepository{BaseRepository: r.BaseRepository.ForOrganization(orgID)}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *ContactRepository) WithContext(ctx context.Context) ContactStore {
+	return &ContactRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
+// phoneForms returns the forms a phone number may be stored in. Contacts
+// are stored under their WhatsApp ID, but older contacts may have kept the
+// + they were first sent to.
+func phoneForms(phone string) []string {
+	digits := strings.TrimPrefix(phone, "+")
+	return []string{digits, "+" + digits}
+}
+
+// FindByPhone finds a contact by phone number, with or without its +
 func (r *ContactRepository) FindByPhone(phone string) (*models.Contact, error) {
 	var contact models.Contact
-	err := r.DB.Where("phone_number = ?", phone).First(&contact).Error
+	err := r.DB.Where("phone_number IN ?", phoneForms(phone)).Order("created_at ASC").First(&contact).Error
 	return &contact, err
 }
 
-// GetOrCreate gets an existing contact or creates a new one
+// GetOrCreate gets the contact with the phone number, with or without its
+// +, or creates one stored under phone
 func (r *ContactRepository) GetOrCreate(phone string) (*models.Contact, error) {
 	var contact models.Contact
 
 	// Use upsert to handle race conditions
-	result := r.DB.Where("phone_number = ?", phone).
-		Attrs(models.Contact{PhoneNumber: phone}).
+	result := r.DB.Where("phone_number IN ?", phoneForms(phone)).
+		Order("created_at ASC").
+		Attrs(models.Contact{PhoneNumber: phone, OrganizationID: r.OrganizationID}).
 		FirstOrCreate(&contact)
 
 	return &contact, result.Error
@@ -44,8 +78,9 @@ func (r *ContactRepository) GetOrCreate(phone string) (*models.Contact, error) {
 func (r *ContactRepository) Search(query string, pagination *utils.Pagination) ([]*models.Contact, error) {
 	var contacts []*models.Contact
 
-	dbQuery := r.DB.Where("name ILIKE ? OR phone_number ILIKE ?", "%"+query+"%", "%"+query+"%").
-		Order("last_message_at DESC NULLS LAST")
+	pattern := LikePattern(query)
+	dbQuery := r.DB.Where(r.Dialect.ContainsFold("name")+" OR "+r.Dialect.ContainsFold("phone_number"), pattern, pattern).
+		Order(r.Dialect.OrderNullsLast("last_message_at", "DESC"))
 
 	// Get total count
 	var total int64
@@ -62,7 +97,7 @@ func (r *ContactRepository) Search(query string, pagination *utils.Pagination) (
 // UpdateLastMessage updates the last message timestamp for a contact
 func (r *ContactRepository) UpdateLastMessage(phone string, timestamp time.Time) error {
 	return r.DB.Model(&models.Contact{}).
-		Where("phone_number = ?", phone).
+		Where("phone_number IN ?", phoneForms(phone)).
 		Updates(map[string]interface{}{
 			"last_message_at": timestamp,
 			"updated_at":      time.Now().UTC(),
@@ -72,21 +107,21 @@ func (r *ContactRepository) UpdateLastMessage(phone string, timestamp time.Time)
 // IncrementMessageCount increments the message count for a contact
 func (r *ContactRepository) IncrementMessageCount(phone string, delta int) error {
 	return r.DB.Model(&models.Contact{}).
-		Where("phone_number = ?", phone).
+		Where("phone_number IN ?", phoneForms(phone)).
 		UpdateColumn("message_count", gorm.Expr("message_count + ?", delta)).Error
 }
 
 // UpdateUnreadCount updates the unread count for a contact
 func (r *ContactRepository) UpdateUnreadCount(phone string, delta int) error {
 	return r.DB.Model(&models.Contact{}).
-		Where("phone_number = ?", phone).
-		UpdateColumn("unread_count", gorm.Expr("GREATEST(unread_count + ?, 0)", delta)).Error
+		Where("phone_number IN ?", phoneForms(phone)).
+		UpdateColumn("unread_count", gorm.Expr(r.Dialect.Greatest("unread_count + ?", "0"), delta)).Error
 }
 
 // ResetUnreadCount resets the unread count to zero
 func (r *ContactRepository) ResetUnreadCount(phone string) error {
 	return r.DB.Model(&models.Contact{}).
-		Where("phone_number = ?", phone).
+		Where("phone_number IN ?", phoneForms(phone)).
 		Update("unread_count", 0).Error
 }
 
@@ -102,40 +137,433 @@ func (r *ContactRepository) FindActive(limit int, pagination *utils.Pagination)
 	return contacts, err
 }
 
-// ListWithFilters lists contacts with filters
+// ListWithFilters lists contacts with filters. It supports cursor
+// pagination.
 func (r *ContactRepository) ListWithFilters(filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Contact, error) {
-	var contacts []*models.Contact
+	query, err := r.applyContactFilters(r.DB.Model(&models.Contact{}), filters)
+	if err != nil {
+		return nil, err
+	}
 
-	query := r.DB.Model(&models.Contact{})
+	// Apply sorting. Both values end up in the SQL, so only known columns
+	// and directions are accepted.
+	key := keyset{table: "contacts", column: "last_message_at", kind: keyTime, desc: true}
+	if sf, ok := filters["sort"].(string); ok {
+		if kind, known := contactSortFields[sf]; known {
+			key.column, key.kind = sf, kind
+		}
+	}
+	// Contacts that never exchanged a message have no last_message_at
+	key.nullable = key.column == "last_message_at"
+	if so, ok := filters["order"].(string); ok && strings.EqualFold(so, "asc") {
+		key.desc = false
+	}
 
-	// Apply sorting
-	sortField := "last_message_at"
-	sortOrder := "DESC"
-	if sf, ok := filters["sort"].(string); ok && sf != "" {
-		sortField = sf
+	return paginateKeyset(query, r.Dialect, key, pagination, func(c *models.Contact) (interface{}, string) {
+		return contactSortValue(c, key.column), c.ID
+	})
+}
+
+// applyContactFilters restricts a contact query to the contacts with the
+// IDs in filters["ids"], the tag in filters["tag"], blocked or not as
+// filters["blocked"] says and matching the segment filter expression in
+// filters["segment"], when they are set
+func (r *ContactRepository) applyContactFilters(query *gorm.DB, filters map[string]interface{}) (*gorm.DB, error) {
+	now := time.Now().UTC()
+	if ids, ok := filters["ids"].([]string); ok {
+		query = query.Where("contacts.id IN ?", ids)
+	}
+	if blocked, ok := filters["blocked"].(bool); ok {
+		query = query.Where("contacts.blocked = ?", blocked)
 	}
-	if so, ok := filters["order"].(string); ok && so != "" {
-		sortOrder = so
+	if tag, ok := filters["tag"].(string); ok && tag != "" {
+		sql, args := segmentCondition{field: "tag", op: "=", text: models.NormalizeTag(tag)}.render(r.Dialect, now)
+		query = query.Where(sql, args...)
 	}
+	if filter, ok := filters["segment"].(string); ok && filter != "" {
+		attributes, err := listAttributeDefinitions(r.DB)
+		if err != nil {
+			return nil, err
+		}
+		// Saved segments may no longer suit changed attribute definitions
+		node, err := parseSegmentFilter(filter, attributes)
+		if err != nil {
+			return nil, errors.NewBadRequest("Invalid filter: " + err.Error())
+		}
+		sql, args := node.render(r.Dialect, now)
+		query = query.Where(sql, args...)
+	}
+	return query, nil
+}
 
-	query = query.Order(sortField + " " + sortOrder + " NULLS LAST")
+// CountWithFilters counts the contacts ListWithFilters would return
+func (r *ContactRepository) CountWithFilters(filters map[string]interface{}) (int64, error) {
+	query, err := r.applyContactFilters(r.DB.Model(&models.Contact{}), filters)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = query.Count(&count).Error
+	return count, err
+}
 
-	// Get total count
-	var total int64
-	if err := query.Count(&total).Error; err != nil {
+// FindIDsWithFilters returns the IDs of the contacts ListWithFilters would
+// return, in no particular order
+func (r *ContactRepository) FindIDsWithFilters(filters map[string]interface{}) ([]string, error) {
+	query, err := r.applyContactFilters(r.DB.Model(&models.Contact{}), filters)
+	if err != nil {
 		return nil, err
 	}
-	pagination.SetTotal(total)
+	var ids []string
+	err = query.Pluck("contacts.id", &ids).Error
+	return ids, err
+}
 
-	// Apply pagination
-	err := pagination.ApplyToQuery(query).Find(&contacts).Error
-	return contacts, err
+// AddTags tags contacts. Contacts of other organizations and tags the
+// contacts already have are skipped.
+func (r *ContactRepository) AddTags(contactIDs, tags []string) error {
+	if len(contactIDs) == 0 || len(tags) == 0 {
+		return nil
+	}
+	var contacts []*models.Contact
+	if err := r.DB.Select("id", "organization_id").Where("id IN ?", contactIDs).Find(&contacts).Error; err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	rows := make([]*models.ContactTag, 0, len(contacts)*len(tags))
+	for _, contact := range contacts {
+		for _, tag := range tags {
+			rows = append(rows, &models.ContactTag{OrganizationID: contact.OrganizationID, ContactID: contact.ID, Tag: tag, CreatedAt: now})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return r.DB.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(rows, 500).Error
+}
+
+// RemoveTags removes tags from contacts
+func (r *ContactRepository) RemoveTags(contactIDs, tags []string) error {
+	if len(contactIDs) == 0 || len(tags) == 0 {
+		return nil
+	}
+	return r.DB.Where("contact_id IN ? AND tag IN ?", contactIDs, tags).Delete(&models.ContactTag{}).Error
+}
+
+// LoadTags sets the tags of contacts, sorted by name
+func (r *ContactRepository) LoadTags(contacts []*models.Contact) error {
+	if len(contacts) == 0 {
+		return nil
+	}
+	byID := make(map[string]*models.Contact, len(contacts))
+	ids := make([]string, 0, len(contacts))
+	for _, contact := range contacts {
+		contact.Tags = []string{}
+		byID[contact.ID] = contact
+		ids = append(ids, contact.ID)
+	}
+
+	var rows []*models.ContactTag
+	if err := r.DB.Where("contact_id IN ?", ids).Order("tag").Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if contact, ok := byID[row.ContactID]; ok {
+			contact.Tags = append(contact.Tags, row.Tag)
+		}
+	}
+	return nil
 }
 
-// UpsertContact creates or updates a contact
+// ListTags returns the tags in use with the number of contacts that have
+// them, sorted by name
+func (r *ContactRepository) ListTags() ([]*models.TagCount, error) {
+	counts := []*models.TagCount{}
+	err := r.DB.Model(&models.ContactTag{}).
+		Select("tag, COUNT(*) AS contacts").
+		Group("tag").
+		Order("tag").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// contactSortValue returns the value of a contact's sort column
+func contactSortValue(c *models.Contact, column string) interface{} {
+	switch column {
+	case "name":
+		return c.Name
+	case "phone_number":
+		return c.PhoneNumber
+	case "message_count":
+		return c.MessageCount
+	case "unread_count":
+		return c.UnreadCount
+	case "created_at":
+		return c.CreatedAt
+	default:
+		return c.LastMessageAt
+	}
+}
+
+// UpsertContact creates a contact, or updates the name, profile, consent
+// and metadata of the contact with its phone number
 func (r *ContactRepository) UpsertContact(contact *models.Contact) error {
+	if r.OrganizationID != "" {
+		contact.OrganizationID = r.OrganizationID
+	}
 	return r.DB.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "phone_number"}},
-		DoUpdates: clause.AssignmentColumns([]string{"name", "profile_url", "updated_at"}),
+		Columns:   []clause.Column{{Name: "organization_id"}, {Name: "phone_number"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "profile_url", "consent", "consent_updated_at", "metadata", "updated_at"}),
 	}).Create(contact).Error
 }
+
+// ListPhoneNumbers returns every contact with only its ID and phone number
+// loaded, oldest first
+func (r *ContactRepository) ListPhoneNumbers() ([]*models.Contact, error) {
+	var contacts []*models.Contact
+	err := r.DB.Select("id", "phone_number", "created_at").Order("created_at ASC").Order("id ASC").Find(&contacts).Error
+	return contacts, err
+}
+
+// Merge applies a contact merge in one transaction: it deletes the
+// duplicates, saves the survivor with the merged tags, moves the messages
+// and reactions stored under merge.PhoneNumbers to the survivor's number
+// and records merge.Audit. It returns the number of messages moved.
+func (r *ContactRepository) Merge(merge *models.ContactMerge) (int64, error) {
+	survivor := merge.Survivor
+	duplicateIDs := make([]string, 0, len(merge.Duplicates))
+	for _, duplicate := range merge.Duplicates {
+		duplicateIDs = append(duplicateIDs, duplicate.ID)
+	}
+
+	var moved int64
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// The duplicates go first, as one of them may hold the survivor's
+		// new phone number
+		if err := tx.Where("contact_id IN ?", duplicateIDs).Delete(&models.ContactTag{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id IN ?", duplicateIDs).Delete(&models.Contact{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(duplicateIDs)) {
+			return errors.NewConflict("The contacts changed during the merge")
+		}
+
+		if err := tx.Model(&models.Contact{}).Where("id = ?", survivor.ID).Updates(map[string]interface{}{
+			"phone_number":       survivor.PhoneNumber,
+			"name":               survivor.Name,
+			"profile_url":        survivor.ProfileURL,
+			"consent":            survivor.Consent,
+			"consent_updated_at": survivor.ConsentUpdatedAt,
+			"metadata":           survivor.Metadata,
+			"last_message_at":    survivor.LastMessageAt,
+			"message_count":      survivor.MessageCount,
+			"unread_count":       survivor.UnreadCount,
+			"blocked":            survivor.Blocked,
+			"blocked_at":         survivor.BlockedAt,
+			"block_reason":       survivor.BlockReason,
+			"updated_at":         time.Now().UTC(),
+		}).Error; err != nil {
+			return err
+		}
+		if len(survivor.Tags) > 0 {
+			rows := make([]*models.ContactTag, 0, len(survivor.Tags))
+			for _, tag := range survivor.Tags {
+				rows = append(rows, &models.ContactTag{OrganizationID: survivor.OrganizationID, ContactID: survivor.ID, Tag: tag, CreatedAt: time.Now().UTC()})
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(rows).Error; err != nil {
+				return err
+			}
+		}
+
+		// Customers' numbers are the sender of inbound messages and the
+		// recipient of outbound ones
+		for _, column := range []struct{ direction, name string }{{"inbound", "from_number"}, {"outbound", "to_number"}} {
+			result := tx.Model(&models.Message{}).
+				Where("direction = ? AND "+column.name+" IN ? AND "+column.name+" <> ?", column.direction, merge.PhoneNumbers, survivor.PhoneNumber).
+				UpdateColumn(column.name, survivor.PhoneNumber)
+			if result.Error != nil {
+				return result.Error
+			}
+			moved += result.RowsAffected
+		}
+		if err := mergeReactions(tx, merge.PhoneNumbers, survivor.PhoneNumber); err != nil {
+			return err
+		}
+
+		if merge.Audit != nil {
+			merge.Audit.OrganizationID = survivor.OrganizationID
+			if merge.Audit.Details == nil {
+				merge.Audit.Details = models.JSONMap{}
+			}
+			merge.Audit.Details["messages_moved"] = moved
+			if err := tx.Create(merge.Audit).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return moved, err
+}
+
+// SetBlocked saves whether contact is blocked, when and why, and records
+// audit in the same transaction
+func (r *ContactRepository) SetBlocked(contact *models.Contact, audit *models.AuditLog) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Contact{}).Where("id = ?", contact.ID).Updates(map[string]interface{}{
+			"blocked":      contact.Blocked,
+			"blocked_at":   contact.BlockedAt,
+			"block_reason": contact.BlockReason,
+			"updated_at":   time.Now().UTC(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		audit.OrganizationID = contact.OrganizationID
+		return tx.Create(audit).Error
+	})
+}
+
+// DeleteContact deletes contact and its tags, and records audit in the same
+// transaction. Messages and calls with the contact's number are kept.
+func (r *ContactRepository) DeleteContact(contact *models.Contact, audit *models.AuditLog) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteContact(tx, contact.ID); err != nil {
+			return err
+		}
+		audit.OrganizationID = contact.OrganizationID
+		return tx.Create(audit).Error
+	})
+}
+
+// Erase deletes contact with everything stored about its phone number:
+// the messages to and from it, the reactions by it and to those messages,
+// and the calls with it and their transcripts. Earlier audit entries about
+// the contact keep their action and actor but lose their details. erasure
+// gets the counts of what was deleted and is recorded, with audit, in the
+// same transaction.
+func (r *ContactRepository) Erase(contact *models.Contact, erasure *models.ContactErasure, audit *models.AuditLog) error {
+	phones := phoneForms(contact.PhoneNumber)
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := deleteContact(tx, contact.ID); err != nil {
+			return err
+		}
+
+		// Reactions go first, as they are found through the messages
+		messages := tx.Model(&models.Message{}).Select("whatsapp_message_id").
+			Where("(from_number IN ? OR to_number IN ?)", phones, phones)
+		result := tx.Where("(from_number IN ? OR target_whatsapp_message_id IN (?))", phones, messages).Delete(&models.Reaction{})
+		if result.Error != nil {
+			return result.Error
+		}
+		erasure.ReactionsErased = result.RowsAffected
+		result = tx.Where("(from_number IN ? OR to_number IN ?)", phones, phones).Delete(&models.Message{})
+		if result.Error != nil {
+			return result.Error
+		}
+		erasure.MessagesErased = result.RowsAffected
+
+		var callIDs, transcriptIDs []string
+		if err := tx.Model(&models.Call{}).Where("(from_number IN ? OR to_number IN ?)", phones, phones).Pluck("id", &callIDs).Error; err != nil {
+			return err
+		}
+		if len(callIDs) > 0 {
+			if err := tx.Model(&models.Transcript{}).Where("call_id IN ?", callIDs).Pluck("id", &transcriptIDs).Error; err != nil {
+				return err
+			}
+		}
+		if len(transcriptIDs) > 0 {
+			if err := tx.Where("transcript_id IN ?", transcriptIDs).Delete(&models.TranscriptSegment{}).Error; err != nil {
+				return err
+			}
+			result = tx.Where("id IN ?", transcriptIDs).Delete(&models.Transcript{})
+			if result.Error != nil {
+				return result.Error
+			}
+			erasure.TranscriptsErased = result.RowsAffected
+		}
+		if len(callIDs) > 0 {
+			result = tx.Where("id IN ?", callIDs).Delete(&models.Call{})
+			if result.Error != nil {
+				return result.Error
+			}
+			erasure.CallsErased = result.RowsAffected
+		}
+
+		if err := tx.Model(&models.AuditLog{}).
+			Where("resource_type = ? AND resource_id = ?", "contact", contact.ID).
+			UpdateColumn("details", models.JSONMap{"erased": true}).Error; err != nil {
+			return err
+		}
+
+		erasure.OrganizationID = contact.OrganizationID
+		erasure.ContactID = contact.ID
+		if err := tx.Create(erasure).Error; err != nil {
+			return err
+		}
+		audit.OrganizationID = contact.OrganizationID
+		if audit.Details == nil {
+			audit.Details = models.JSONMap{}
+		}
+		audit.Details["erasure_id"] = erasure.ID
+		return tx.Create(audit).Error
+	})
+}
+
+// deleteContact deletes the contact with ID contactID and its tags
+func deleteContact(tx *gorm.DB, contactID string) error {
+	if err := tx.Where("contact_id = ?", contactID).Delete(&models.ContactTag{}).Error; err != nil {
+		return err
+	}
+	result := tx.Where("id = ?", contactID).Delete(&models.Contact{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// mergeReactions moves the customer reactions stored under phones to
+// phone. A customer has one reaction per message, so only the latest of
+// the merged contacts' reactions to a message is kept.
+func mergeReactions(tx *gorm.DB, phones []string, phone string) error {
+	var reactions []*models.Reaction
+	if err := tx.Where("direction = ? AND from_number IN ?", "inbound", phones).
+		Order("timestamp DESC").Order("id DESC").
+		Find(&reactions).Error; err != nil {
+		return err
+	}
+
+	kept := map[string]bool{}
+	var stale, moved []string
+	for _, reaction := range reactions {
+		switch {
+		case kept[reaction.TargetWhatsAppMessageID]:
+			stale = append(stale, reaction.ID)
+		case reaction.FromNumber != phone:
+			moved = append(moved, reaction.ID)
+			fallthrough
+		default:
+			kept[reaction.TargetWhatsAppMessageID] = true
+		}
+	}
+
+	if len(stale) > 0 {
+		if err := tx.Where("id IN ?", stale).Delete(&models.Reaction{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(moved) > 0 {
+		return tx.Model(&models.Reaction{}).Where("id IN ?", moved).UpdateColumn("from_number", phone).Error
+	}
+	return nil
+}
@@ -0,0 +1,721 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/database"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+func TestContactRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactRepository(db).ForOrganization("org_a").(*ContactRepository)
+		other := NewContactRepository(db).ForOrganization("org_b")
+
+		ada, err := repo.GetOrCreate("14155550100")
+		if err != nil || ada.ID == "" || ada.OrganizationID != "org_a" {
+			t.Fatalf("GetOrCreate: %+v %v", ada, err)
+		}
+		again, err := repo.GetOrCreate("14155550100")
+		if err != nil || again.ID != ada.ID {
+			t.Errorf("GetOrCreate should return the existing contact: %+v %v", again, err)
+		}
+		// The same phone number is a separate contact in another organization
+		otherAda, err := other.GetOrCreate("14155550100")
+		if err != nil || otherAda.ID == ada.ID {
+			t.Errorf("GetOrCreate in another organization: %+v %v", otherAda, err)
+		}
+
+		if err := repo.UpdateFields(ada.ID, &models.Contact{}, map[string]interface{}{"name": "Ada Lovelace", "organization_id": "org_b"}); err != nil {
+			t.Fatalf("UpdateFields: %v", err)
+		}
+		found, err := repo.FindByPhone("14155550100")
+		if err != nil || found.Name != "Ada Lovelace" || found.OrganizationID != "org_a" {
+			t.Errorf("FindByPhone after update: %+v %v", found, err)
+		}
+
+		now := time.Now().UTC().Truncate(time.Second)
+		if err := repo.UpdateLastMessage("14155550100", now); err != nil {
+			t.Fatalf("UpdateLastMessage: %v", err)
+		}
+		if err := repo.IncrementMessageCount("14155550100", 2); err != nil {
+			t.Fatalf("IncrementMessageCount: %v", err)
+		}
+		if err := repo.UpdateUnreadCount("14155550100", 1); err != nil {
+			t.Fatalf("UpdateUnreadCount: %v", err)
+		}
+		// Unread counts never go negative
+		if err := repo.UpdateUnreadCount("14155550100", -5); err != nil {
+			t.Fatalf("UpdateUnreadCount: %v", err)
+		}
+		found, _ = repo.FindByPhone("14155550100")
+		if found.MessageCount != 2 || found.UnreadCount != 0 || found.LastMessageAt == nil || !found.LastMessageAt.Equal(now) {
+			t.Errorf("counters not updated: %+v", found)
+		}
+
+		repo.UpdateUnreadCount("14155550100", 3)
+		if err := repo.ResetUnreadCount("14155550100"); err != nil {
+			t.Fatalf("ResetUnreadCount: %v", err)
+		}
+		found, _ = repo.FindByPhone("14155550100")
+		if found.UnreadCount != 0 {
+			t.Errorf("ResetUnreadCount left %d", found.UnreadCount)
+		}
+
+		otherFound, _ := other.FindByPhone("14155550100")
+		if otherFound.MessageCount != 0 || otherFound.Name != "" {
+			t.Errorf("updates leaked into another organization: %+v", otherFound)
+		}
+
+		// Contacts stored with a + are found without it, and the other way round
+		legacy, _ := repo.GetOrCreate("+14155550199")
+		if found, err := repo.GetOrCreate("14155550199"); err != nil || found.ID != legacy.ID {
+			t.Errorf("GetOrCreate should match the number with its +: %+v %v", found, err)
+		}
+		repo.IncrementMessageCount("14155550199", 1)
+		if found, err := repo.FindByPhone("14155550199"); err != nil || found.ID != legacy.ID || found.MessageCount != 1 {
+			t.Errorf("FindByPhone should match the number with its +: %+v %v", found, err)
+		}
+		if found, err := repo.FindByPhone("+14155550100"); err != nil || found.ID != ada.ID {
+			t.Errorf("FindByPhone should match the number without its +: %+v %v", found, err)
+		}
+	})
+}
+
+func TestContactRepositoryMerge(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactRepository(db).ForOrganization("org_a").(*ContactRepository)
+		messages := NewMessageRepository(db).ForOrganization("org_a")
+		reactions := NewReactionRepository(db).ForOrganization("org_a")
+		audits := NewAuditLogRepository(db).ForOrganization("org_a")
+
+		survivor, _ := repo.GetOrCreate("+447700900123")
+		// Written before numbers were matched with and without their +
+		duplicate := &models.Contact{PhoneNumber: "447700900123"}
+		if err := repo.Create(duplicate); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		bystander, _ := repo.GetOrCreate("447700900124")
+		repo.AddTags([]string{survivor.ID}, []string{"lead"})
+		repo.AddTags([]string{duplicate.ID, bystander.ID}, []string{"vip"})
+		if contacts, err := repo.ListPhoneNumbers(); err != nil || len(contacts) != 3 || contacts[0].ID != survivor.ID || contacts[0].Name != "" {
+			t.Fatalf("ListPhoneNumbers: %+v %v", contacts, err)
+		}
+
+		at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		for i, m := range []*models.Message{
+			{FromNumber: "15550000000", ToNumber: "+447700900123", Direction: "outbound"},
+			{FromNumber: "447700900123", ToNumber: "15550000000", Direction: "inbound"},
+			{FromNumber: "447700900124", ToNumber: "15550000000", Direction: "inbound"},
+		} {
+			m.WhatsAppMessageID, m.MessageType, m.Status, m.Timestamp = fmt.Sprintf("wamid.%d", i), "text", models.MessageStatusDelivered, at
+			if err := messages.Create(m); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		for _, r := range []*models.Reaction{
+			{TargetWhatsAppMessageID: "wamid.0", FromNumber: "+447700900123", Direction: "inbound", Emoji: "👍", Timestamp: at},
+			{TargetWhatsAppMessageID: "wamid.0", FromNumber: "447700900123", Direction: "inbound", Emoji: "❤️", Timestamp: at.Add(time.Minute)},
+			{TargetWhatsAppMessageID: "wamid.1", FromNumber: "447700900123", Direction: "inbound", Emoji: "😂", Timestamp: at},
+		} {
+			if err := reactions.Upsert(r); err != nil {
+				t.Fatalf("Upsert: %v", err)
+			}
+		}
+
+		merged := *survivor
+		merged.PhoneNumber = "447700900123"
+		merged.Name = "Ada"
+		merged.MessageCount = 2
+		merged.Tags = []string{"lead", "vip"}
+		audit := &models.AuditLog{Action: models.AuditActionContactMerge, ResourceType: "contact", ResourceID: survivor.ID, Details: models.JSONMap{"rule": "survivor"}}
+		moved, err := repo.Merge(&models.ContactMerge{
+			Survivor:     &merged,
+			Duplicates:   []*models.Contact{duplicate},
+			PhoneNumbers: []string{"447700900123", "+447700900123"},
+			Audit:        audit,
+		})
+		if err != nil {
+			t.Fatalf("Merge: %v", err)
+		}
+		if moved != 1 {
+			t.Errorf("expected the outbound message to move, moved %d", moved)
+		}
+
+		found, err := repo.FindByPhone("447700900123")
+		if err != nil || found.ID != survivor.ID || found.PhoneNumber != "447700900123" || found.Name != "Ada" || found.MessageCount != 2 {
+			t.Errorf("unexpected survivor %+v %v", found, err)
+		}
+		var gone models.Contact
+		if err := repo.FindByID(duplicate.ID, &gone); err == nil {
+			t.Error("the duplicate should be deleted")
+		}
+		contacts := []*models.Contact{found, bystander}
+		repo.LoadTags(contacts)
+		if strings.Join(found.Tags, ",") != "lead,vip" || strings.Join(bystander.Tags, ",") != "vip" {
+			t.Errorf("unexpected tags %v %v", found.Tags, bystander.Tags)
+		}
+
+		conversation, _ := messages.FindByPhone("447700900123", utils.NewPagination(10, 0))
+		for _, m := range conversation {
+			if m.FromNumber != "447700900123" && m.ToNumber != "447700900123" {
+				t.Errorf("message not moved: %+v", m)
+			}
+		}
+		if len(conversation) != 2 {
+			t.Errorf("expected 2 messages in the conversation, got %d", len(conversation))
+		}
+
+		// The customer keeps one reaction per message, the latest
+		kept, _ := reactions.FindByTargets([]string{"wamid.0", "wamid.1"})
+		if len(kept) != 2 || kept[0].Emoji != "😂" || kept[1].Emoji != "❤️" || kept[0].FromNumber != "447700900123" || kept[1].FromNumber != "447700900123" {
+			t.Errorf("unexpected reactions %+v", kept)
+		}
+
+		entries, err := audits.ListWithFilters(map[string]interface{}{"resource_id": survivor.ID}, utils.NewPagination(10, 0))
+		if err != nil || len(entries) != 1 || entries[0].ID != audit.ID || entries[0].OrganizationID != "org_a" {
+			t.Fatalf("the merge should be audited: %+v %v", entries, err)
+		}
+		if entries[0].Details["rule"] != "survivor" || entries[0].Details["messages_moved"] != float64(1) {
+			t.Errorf("unexpected audit details %v", entries[0].Details)
+		}
+
+		// A duplicate deleted meanwhile fails the whole merge
+		if _, err := repo.Merge(&models.ContactMerge{Survivor: found, Duplicates: []*models.Contact{duplicate}, PhoneNumbers: []string{"447700900123"}}); err == nil {
+			t.Error("expected a conflict")
+		}
+	})
+}
+
+func TestContactRepositorySetBlocked(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactRepository(db).ForOrganization("org_a")
+		audits := NewAuditLogRepository(db).ForOrganization("org_a")
+		spammer, _ := repo.GetOrCreate("14155550100")
+		repo.GetOrCreate("14155550101")
+
+		at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		spammer.Blocked, spammer.BlockedAt, spammer.BlockReason = true, &at, "spam"
+		audit := &models.AuditLog{Action: models.AuditActionContactBlock, ResourceType: "contact", ResourceID: spammer.ID}
+		if err := repo.SetBlocked(spammer, audit); err != nil {
+			t.Fatalf("SetBlocked: %v", err)
+		}
+
+		found, _ := repo.FindByPhone("14155550100")
+		if !found.Blocked || found.BlockedAt == nil || !found.BlockedAt.Equal(at) || found.BlockReason != "spam" {
+			t.Errorf("unexpected contact %+v", found)
+		}
+		blocked, err := repo.ListWithFilters(map[string]interface{}{"blocked": true}, utils.NewPagination(10, 0))
+		if err != nil || len(blocked) != 1 || blocked[0].ID != spammer.ID {
+			t.Errorf("unexpected blocked contacts %+v %v", blocked, err)
+		}
+		if count, _ := repo.CountWithFilters(map[string]interface{}{"blocked": false}); count != 1 {
+			t.Errorf("expected one contact that is not blocked, got %d", count)
+		}
+		entries, _ := audits.ListWithFilters(map[string]interface{}{"action": models.AuditActionContactBlock}, utils.NewPagination(10, 0))
+		if len(entries) != 1 || entries[0].ID != audit.ID || entries[0].OrganizationID != "org_a" {
+			t.Errorf("the block should be audited: %+v", entries)
+		}
+
+		// Contacts of other organizations cannot be blocked
+		if err := NewContactRepository(db).ForOrganization("org_b").SetBlocked(spammer, &models.AuditLog{Action: models.AuditActionContactBlock, ResourceType: "contact", ResourceID: spammer.ID}); err == nil {
+			t.Error("expected contacts of other organizations to be out of reach")
+		}
+	})
+}
+
+func TestContactRepositoryErase(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactRepository(db).ForOrganization("org_a")
+		messages := NewMessageRepository(db).ForOrganization("org_a")
+		reactions := NewReactionRepository(db).ForOrganization("org_a")
+		calls := NewCallRepository(db).ForOrganization("org_a")
+		audits := NewAuditLogRepository(db).ForOrganization("org_a")
+
+		contact, _ := repo.GetOrCreate("447700900123")
+		bystander, _ := repo.GetOrCreate("447700900124")
+		other, _ := NewContactRepository(db).ForOrganization("org_b").GetOrCreate("447700900123")
+		repo.AddTags([]string{contact.ID, bystander.ID}, []string{"vip"})
+		repo.SetBlocked(contact, &models.AuditLog{Action: models.AuditActionContactBlock, ResourceType: "contact", ResourceID: contact.ID, Details: models.JSONMap{"phone_number": "447700900123"}})
+
+		at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		for i, m := range []*models.Message{
+			{FromNumber: "15550000000", ToNumber: "+447700900123", Direction: "outbound", Content: "hello"},
+			{FromNumber: "447700900123", ToNumber: "15550000000", Direction: "inbound", Content: "my address is"},
+			{FromNumber: "447700900124", ToNumber: "15550000000", Direction: "inbound", Content: "hi"},
+		} {
+			m.WhatsAppMessageID, m.MessageType, m.Status, m.Timestamp = fmt.Sprintf("wamid.%d", i), "text", models.MessageStatusDelivered, at
+			if err := messages.Create(m); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		NewMessageRepository(db).ForOrganization("org_b").Create(&models.Message{
+			WhatsAppMessageID: "wamid.b", FromNumber: "447700900123", ToNumber: "15550000001", Direction: "inbound",
+			MessageType: "text", Status: models.MessageStatusDelivered, Timestamp: at,
+		})
+		for _, r := range []*models.Reaction{
+			{TargetWhatsAppMessageID: "wamid.0", FromNumber: "447700900123", Direction: "inbound", Emoji: "👍", Timestamp: at},
+			{TargetWhatsAppMessageID: "wamid.1", FromNumber: "15550000000", Direction: "outbound", Emoji: "❤️", Timestamp: at},
+			{TargetWhatsAppMessageID: "wamid.2", FromNumber: "15550000000", Direction: "outbound", Emoji: "😂", Timestamp: at},
+		} {
+			if err := reactions.Upsert(r); err != nil {
+				t.Fatalf("Upsert: %v", err)
+			}
+		}
+		for _, row := range []interface{}{
+			&models.Call{ID: "call_1", OrganizationID: "org_a", FromNumber: "+447700900123", ToNumber: "15550000000", StartedAt: at},
+			&models.Call{ID: "call_2", OrganizationID: "org_a", FromNumber: "447700900124", ToNumber: "15550000000", StartedAt: at},
+			&models.Transcript{ID: "transcript_1", OrganizationID: "org_a", CallID: "call_1", Content: "my address is", ProcessedAt: at},
+			&models.TranscriptSegment{ID: "segment_1", OrganizationID: "org_a", TranscriptID: "transcript_1", Content: "my address is"},
+		} {
+			if err := db.Create(row).Error; err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		erasure := &models.ContactErasure{ActorID: "key_1"}
+		audit := &models.AuditLog{Action: models.AuditActionContactErase, ResourceType: "contact", ResourceID: contact.ID}
+		if err := repo.Erase(contact, erasure, audit); err != nil {
+			t.Fatalf("Erase: %v", err)
+		}
+		if erasure.ID == "" || erasure.ContactID != contact.ID || erasure.OrganizationID != "org_a" || erasure.MessagesErased != 2 ||
+			erasure.ReactionsErased != 2 || erasure.CallsErased != 1 || erasure.TranscriptsErased != 1 {
+			t.Errorf("unexpected erasure %+v", erasure)
+		}
+
+		var gone models.Contact
+		if err := repo.FindByID(contact.ID, &gone); err == nil {
+			t.Error("the contact should be deleted")
+		}
+		if left, _ := messages.FindByPhone("447700900123", utils.NewPagination(10, 0)); len(left) != 0 {
+			t.Errorf("the contact's messages should be deleted: %+v", left)
+		}
+		if left, _ := reactions.FindByTargets([]string{"wamid.0", "wamid.1", "wamid.2"}); len(left) != 1 || left[0].Emoji != "😂" {
+			t.Errorf("only the reaction to the bystander should be left: %+v", left)
+		}
+		if left, _ := calls.FindByPhone("447700900123"); len(left) != 0 {
+			t.Errorf("the contact's calls should be deleted: %+v", left)
+		}
+		var transcripts, segments int64
+		db.Model(&models.Transcript{}).Count(&transcripts)
+		db.Model(&models.TranscriptSegment{}).Count(&segments)
+		if transcripts != 0 || segments != 0 {
+			t.Errorf("the transcripts should be deleted, %d and %d segments left", transcripts, segments)
+		}
+		kept := []*models.Contact{bystander}
+		repo.LoadTags(kept)
+		if len(kept[0].Tags) != 1 {
+			t.Errorf("the bystander should keep its tags: %v", kept[0].Tags)
+		}
+		if left, _ := NewMessageRepository(db).ForOrganization("org_b").FindByPhone("447700900123", utils.NewPagination(10, 0)); len(left) != 1 {
+			t.Error("other organizations' messages with the number should be kept")
+		}
+		if found, err := NewContactRepository(db).ForOrganization("org_b").FindByPhone("447700900123"); err != nil || found.ID != other.ID {
+			t.Error("other organizations' contacts with the number should be kept")
+		}
+
+		entries, _ := audits.ListWithFilters(map[string]interface{}{"resource_id": contact.ID}, utils.NewPagination(10, 0))
+		if len(entries) != 2 || entries[0].ID != audit.ID || entries[0].Details["erasure_id"] != erasure.ID {
+			t.Fatalf("the erasure should be audited: %+v", entries)
+		}
+		if entries[1].Action != models.AuditActionContactBlock || entries[1].Details["phone_number"] != nil || entries[1].Details["erased"] != true {
+			t.Errorf("earlier entries should lose their details: %+v", entries[1])
+		}
+
+		if err := repo.Erase(contact, &models.ContactErasure{}, &models.AuditLog{Action: models.AuditActionContactErase, ResourceType: "contact", ResourceID: contact.ID}); err == nil {
+			t.Error("erasing an erased contact should fail")
+		}
+	})
+}
+
+func TestContactRepositoryDeleteContact(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactRepository(db).ForOrganization("org_a")
+		messages := NewMessageRepository(db).ForOrganization("org_a")
+		contact, _ := repo.GetOrCreate("447700900123")
+		repo.AddTags([]string{contact.ID}, []string{"vip"})
+		messages.Create(&models.Message{
+			FromNumber: "447700900123", ToNumber: "15550000000", Direction: "inbound",
+			MessageType: "text", Status: models.MessageStatusDelivered, Timestamp: time.Now().UTC(),
+		})
+
+		if err := NewContactRepository(db).ForOrganization("org_b").DeleteContact(contact, &models.AuditLog{Action: models.AuditActionContactDelete, ResourceType: "contact", ResourceID: contact.ID}); err == nil {
+			t.Error("expected contacts of other organizations to be out of reach")
+		}
+		audit := &models.AuditLog{Action: models.AuditActionContactDelete, ResourceType: "contact", ResourceID: contact.ID}
+		if err := repo.DeleteContact(contact, audit); err != nil {
+			t.Fatalf("DeleteContact: %v", err)
+		}
+		var gone models.Contact
+		if err := repo.FindByID(contact.ID, &gone); err == nil {
+			t.Error("the contact should be deleted")
+		}
+		if tags, _ := repo.ListTags(); len(tags) != 0 {
+			t.Errorf("the contact's tags should be deleted: %+v", tags)
+		}
+		if left, _ := messages.FindByPhone("447700900123", utils.NewPagination(10, 0)); len(left) != 1 {
+			t.Error("messages should be kept")
+		}
+		entries, _ := NewAuditLogRepository(db).ForOrganization("org_a").ListWithFilters(map[string]interface{}{"action": models.AuditActionContactDelete}, utils.NewPagination(10, 0))
+		if len(entries) != 1 || entries[0].ID != audit.ID {
+			t.Errorf("the deletion should be audited: %+v", entries)
+		}
+	})
+}
+
+func TestContactRepositoryListing(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactRepository(db).ForOrganization("org_a").(*ContactRepository)
+
+		recent := time.Now().UTC().Truncate(time.Second)
+		for _, c := range []*models.Contact{
+			{PhoneNumber: "14155550100", Name: "Ada", LastMessageAt: &recent},
+			{PhoneNumber: "14155550101", Name: "Grace"},
+			{PhoneNumber: "14155550102", Name: "Alan", LastMessageAt: ptrTime(recent.Add(-time.Hour))},
+		} {
+			if err := repo.Create(c); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		NewContactRepository(db).ForOrganization("org_b").GetOrCreate("14155550103")
+
+		pagination := utils.NewPagination(10, 0)
+		listed, err := repo.ListWithFilters(map[string]interface{}{}, pagination)
+		if err != nil {
+			t.Fatalf("ListWithFilters: %v", err)
+		}
+		if names := contactNames(listed); len(listed) != 3 || names[0] != "Ada" || names[1] != "Alan" || names[2] != "Grace" || pagination.Total != 3 {
+			t.Errorf("contacts without messages should sort last: %v", names)
+		}
+
+		listed, _ = repo.ListWithFilters(map[string]interface{}{"sort": "last_message_at", "order": "asc"}, utils.NewPagination(10, 0))
+		if names := contactNames(listed); names[0] != "Alan" || names[2] != "Grace" {
+			t.Errorf("ascending order should still sort contacts without messages last: %v", names)
+		}
+
+		listed, _ = repo.ListWithFilters(map[string]interface{}{"sort": "name", "order": "asc"}, utils.NewPagination(10, 0))
+		if names := contactNames(listed); names[0] != "Ada" || names[1] != "Alan" || names[2] != "Grace" {
+			t.Errorf("sort by name: %v", names)
+		}
+
+		// Unknown sort fields fall back to the default instead of reaching SQL
+		listed, err = repo.ListWithFilters(map[string]interface{}{"sort": "name; DROP TABLE contacts", "order": "desc"}, utils.NewPagination(10, 0))
+		if err != nil || len(listed) != 3 {
+			t.Errorf("unknown sort field: %v %v", contactNames(listed), err)
+		}
+
+		found, err := repo.Search("AL", utils.NewPagination(10, 0))
+		if err != nil || len(found) != 1 || found[0].Name != "Alan" {
+			t.Errorf("Search by name: %v %v", contactNames(found), err)
+		}
+		found, _ = repo.Search("0101", utils.NewPagination(10, 0))
+		if len(found) != 1 || found[0].Name != "Grace" {
+			t.Errorf("Search by phone: %v", contactNames(found))
+		}
+
+		active, err := repo.FindActive(10, nil)
+		if err != nil || len(active) != 2 || active[0].Name != "Ada" {
+			t.Errorf("FindActive: %v %v", contactNames(active), err)
+		}
+	})
+}
+
+func TestContactRepositoryCursorPagination(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactRepository(db).ForOrganization("org_a").(*ContactRepository)
+
+		// Two contacts without messages sort last and are paged by ID
+		recent := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		for i, last := range []*time.Time{&recent, nil, ptrTime(recent.Add(-time.Hour)), &recent, nil} {
+			c := &models.Contact{PhoneNumber: fmt.Sprintf("1415555010%d", i), Name: fmt.Sprintf("c%d", i), LastMessageAt: last, MessageCount: i % 2}
+			if err := repo.Create(c); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		for _, filters := range []map[string]interface{}{
+			{},
+			{"order": "asc"},
+			{"sort": "message_count", "order": "asc"},
+			{"sort": "name"},
+		} {
+			all, err := repo.ListWithFilters(filters, utils.NewPagination(10, 0))
+			if err != nil || len(all) != 5 {
+				t.Fatalf("%v: ListWithFilters: %v %v", filters, contactNames(all), err)
+			}
+
+			var forward []*models.Contact
+			pagination := &utils.Pagination{Limit: 2, SkipTotal: true}
+			for {
+				page, err := repo.ListWithFilters(filters, pagination)
+				if err != nil {
+					t.Fatalf("%v: %v", filters, err)
+				}
+				forward = append(forward, page...)
+				if pagination.NextCursor == "" {
+					break
+				}
+				pagination = &utils.Pagination{Limit: 2, SkipTotal: true, Cursor: pagination.NextCursor}
+			}
+			if got, want := strings.Join(contactNames(forward), ","), strings.Join(contactNames(all), ","); got != want {
+				t.Errorf("%v: forward walk = %s, want %s", filters, got, want)
+			}
+
+			// And back from the last page to the first
+			var backward []*models.Contact
+			for pagination.Cursor != "" {
+				page, err := repo.ListWithFilters(filters, pagination)
+				if err != nil {
+					t.Fatalf("%v: %v", filters, err)
+				}
+				backward = append(page, backward...)
+				pagination = &utils.Pagination{Limit: 2, SkipTotal: true, Cursor: pagination.PrevCursor}
+			}
+			if got, want := strings.Join(contactNames(backward), ","), strings.Join(contactNames(all), ","); got != want {
+				t.Errorf("%v: backward walk = %s, want %s", filters, got, want)
+			}
+		}
+	})
+}
+
+func TestContactRepositoryUpsert(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactRepository(db).ForOrganization("org_a").(*ContactRepository)
+
+		if err := repo.UpsertContact(&models.Contact{PhoneNumber: "14155550100", Name: "Ada"}); err != nil {
+			t.Fatalf("UpsertContact: %v", err)
+		}
+		first, _ := repo.FindByPhone("14155550100")
+		now := time.Now().UTC()
+		updated := &models.Contact{PhoneNumber: "14155550100", Name: "Ada Lovelace", Consent: models.ConsentOptedIn, ConsentUpdatedAt: &now, Metadata: models.JSONMap{"plan": "pro"}}
+		if err := repo.UpsertContact(updated); err != nil {
+			t.Fatalf("UpsertContact: %v", err)
+		}
+
+		contacts, _ := repo.ListWithFilters(map[string]interface{}{}, utils.NewPagination(10, 0))
+		if len(contacts) != 1 || contacts[0].Name != "Ada Lovelace" {
+			t.Errorf("UpsertContact should update the existing contact: %v", contactNames(contacts))
+		}
+		if c := contacts[0]; c.ID != first.ID || c.Consent != models.ConsentOptedIn || c.ConsentUpdatedAt == nil || c.Metadata["plan"] != "pro" {
+			t.Errorf("UpsertContact should keep the ID and update consent and metadata: %+v", c)
+		}
+	})
+}
+
+func TestContactRepositoryTags(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactRepository(db).ForOrganization("org_a").(*ContactRepository)
+		other := NewContactRepository(db).ForOrganization("org_b")
+
+		ada, _ := repo.GetOrCreate("14155550100")
+		alan, _ := repo.GetOrCreate("14155550101")
+		eve, _ := other.GetOrCreate("14155550102")
+
+		if err := repo.AddTags([]string{ada.ID, alan.ID, eve.ID}, []string{"vip", "beta"}); err != nil {
+			t.Fatalf("AddTags: %v", err)
+		}
+		// Tagging again is a no-op
+		if err := repo.AddTags([]string{ada.ID}, []string{"vip"}); err != nil {
+			t.Fatalf("AddTags again: %v", err)
+		}
+		if err := repo.RemoveTags([]string{alan.ID, eve.ID}, []string{"beta"}); err != nil {
+			t.Fatalf("RemoveTags: %v", err)
+		}
+
+		contacts := []*models.Contact{ada, alan, eve}
+		if err := repo.LoadTags(contacts); err != nil {
+			t.Fatalf("LoadTags: %v", err)
+		}
+		if strings.Join(ada.Tags, ",") != "beta,vip" || strings.Join(alan.Tags, ",") != "vip" || len(eve.Tags) != 0 {
+			t.Errorf("unexpected tags %v %v %v", ada.Tags, alan.Tags, eve.Tags)
+		}
+
+		counts, err := repo.ListTags()
+		if err != nil || len(counts) != 2 || counts[0].Tag != "beta" || counts[0].Contacts != 1 || counts[1].Contacts != 2 {
+			t.Errorf("ListTags: %+v %v", counts, err)
+		}
+		if counts, _ := other.ListTags(); len(counts) != 0 {
+			t.Errorf("contacts of another organization must not be tagged: %+v", counts)
+		}
+
+		tagged, err := repo.ListWithFilters(map[string]interface{}{"tag": "Beta"}, utils.NewPagination(10, 0))
+		if err != nil || len(tagged) != 1 || tagged[0].ID != ada.ID {
+			t.Errorf("tag filter: %v %v", contactNames(tagged), err)
+		}
+	})
+}
+
+func TestContactRepositorySegments(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactRepository(db).ForOrganization("org_a").(*ContactRepository)
+
+		now := time.Now().UTC()
+		for _, c := range []*models.Contact{
+			{PhoneNumber: "14155550100", Name: "Ada", MessageCount: 12, LastMessageAt: ptrTime(now.Add(-time.Hour)), Consent: models.ConsentOptedIn,
+				Metadata: models.JSONMap{"plan": "pro", "seats": 25, "trial": false}},
+			{PhoneNumber: "14155550101", Name: "Alan", MessageCount: 3, LastMessageAt: ptrTime(now.Add(-60 * 24 * time.Hour)), Consent: models.ConsentOptedOut,
+				Metadata: models.JSONMap{"plan": "free", "seats": "many", "trial": true}},
+			{PhoneNumber: "14155550102", Name: "Grace"},
+		} {
+			if err := repo.Create(c); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		ada, _ := repo.FindByPhone("14155550100")
+		grace, _ := repo.FindByPhone("14155550102")
+		repo.AddTags([]string{ada.ID, grace.ID}, []string{"vip"})
+
+		// The same contact in another organization never matches
+		other := NewContactRepository(db).ForOrganization("org_b")
+		twin, _ := other.GetOrCreate("14155550100")
+		other.AddTags([]string{twin.ID}, []string{"vip"})
+
+		for filter, want := range map[string]string{
+			"tag = vip":                                 "Ada,Grace",
+			"tag != vip":                                "Alan",
+			"consent = opted_in":                        "Ada",
+			"consent = unknown":                         "Grace",
+			"consent != opted_out":                      "Ada,Grace",
+			"last_message_at > -30d":                    "Ada",
+			"NOT last_message_at > -30d":                "Alan,Grace",
+			"message_count >= 3 AND message_count < 12": "Alan",
+			"attributes.plan = pro":                     "Ada",
+			"attributes.plan != pro":                    "Alan,Grace",
+			"attributes.seats > 10":                     "Ada",
+			`attributes.seats = "many"`:                 "Alan",
+			"attributes.trial = true":                   "Alan",
+			"attributes.trial = false":                  "Ada",
+			"attributes.plan ~ RO":                      "Ada",
+			"name ~ al":                                 "Alan",
+			"tag = vip AND (consent = opted_in OR message_count = 0)": "Ada,Grace",
+			"created_at > 2000-01-01":                                 "Ada,Alan,Grace",
+		} {
+			filters := map[string]interface{}{"segment": filter, "sort": "name", "order": "asc"}
+			listed, err := repo.ListWithFilters(filters, utils.NewPagination(10, 0))
+			if err != nil {
+				t.Errorf("%s: %v", filter, err)
+				continue
+			}
+			if got := strings.Join(contactNames(listed), ","); got != want {
+				t.Errorf("%s: got %s, want %s", filter, got, want)
+			}
+
+			count, err := repo.CountWithFilters(filters)
+			ids, _ := repo.FindIDsWithFilters(filters)
+			if err != nil || int(count) != len(listed) || len(ids) != len(listed) {
+				t.Errorf("%s: count %d and %d ids for %d contacts (%v)", filter, count, len(ids), len(listed), err)
+			}
+		}
+
+		if _, err := repo.ListWithFilters(map[string]interface{}{"segment": "tag >"}, utils.NewPagination(10, 0)); err == nil {
+			t.Error("an invalid filter should fail")
+		}
+	})
+}
+
+func TestContactRepositoryDeclaredAttributes(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewContactRepository(db).ForOrganization("org_a").(*ContactRepository)
+		attributes := NewAttributeRepository(db).ForOrganization("org_a")
+		for _, attribute := range []*models.AttributeDefinition{
+			{Name: "zip", Type: models.AttributeTypeString},
+			{Name: "renewal", Type: models.AttributeTypeDate},
+		} {
+			if err := attributes.Create(attribute); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		// Another organization declaring zip as a number changes nothing
+		NewAttributeRepository(db).ForOrganization("org_b").Create(&models.AttributeDefinition{Name: "zip", Type: models.AttributeTypeNumber})
+
+		soon := time.Now().UTC().AddDate(0, 0, 3).Format(models.AttributeDateLayout)
+		for _, c := range []*models.Contact{
+			{PhoneNumber: "14155550100", Name: "Ada", Metadata: models.JSONMap{"zip": "10115", "renewal": "2020-01-31"}},
+			{PhoneNumber: "14155550101", Name: "Alan", Metadata: models.JSONMap{"zip": "02134", "renewal": soon}},
+			{PhoneNumber: "14155550102", Name: "Grace"},
+		} {
+			if err := repo.Create(c); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		for filter, want := range map[string]string{
+			"attributes.zip = 10115":           "Ada",
+			"attributes.zip ~ 021":             "Alan",
+			"attributes.renewal < 2021-01-01":  "Ada",
+			"attributes.renewal > -1d":         "Alan",
+			"attributes.renewal != 2020-01-31": "Alan,Grace",
+		} {
+			listed, err := repo.ListWithFilters(map[string]interface{}{"segment": filter, "sort": "name", "order": "asc"}, utils.NewPagination(10, 0))
+			if err != nil {
+				t.Errorf("%s: %v", filter, err)
+				continue
+			}
+			if got := strings.Join(contactNames(listed), ","); got != want {
+				t.Errorf("%s: got %s, want %s", filter, got, want)
+			}
+		}
+
+		_, err := repo.ListWithFilters(map[string]interface{}{"segment": "attributes.zip > 10000"}, utils.NewPagination(10, 0))
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+			t.Errorf("a filter that no longer suits the attributes should be a bad request, got %v", err)
+		}
+	})
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
+
+func contactNames(contacts []*models.Contact) []string {
+	names := make([]string, len(contacts))
+	for i, c := range contacts {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func TestContactRepositoryEraseCallsBeforeOrganizations(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		// Roll back to before calls had an organization and record a call
+		// with a transcript the way older builds did
+		migrator, _ := database.NewMigrator(db)
+		if _, err := migrator.Down(migrator.Latest() - 10); err != nil {
+			t.Fatalf("Down: %v", err)
+		}
+		at := "2025-01-01 12:00:00"
+		for _, statement := range []string{
+			"INSERT INTO calls (id, from_number, to_number, started_at) VALUES ('call_1', '447700900123', '15550000000', '" + at + "')",
+			"INSERT INTO transcripts (id, call_id, content, processed_at) VALUES ('transcript_1', 'call_1', 'my address is', '" + at + "')",
+			"INSERT INTO transcript_segments (id, transcript_id, content) VALUES ('segment_1', 'transcript_1', 'my address is')",
+		} {
+			if err := db.Exec(statement).Error; err != nil {
+				t.Fatalf("insert: %v", err)
+			}
+		}
+		if _, err := migrator.Up(); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+
+		repo := NewContactRepository(db).ForOrganization(models.DefaultOrganizationID)
+		contact, _ := repo.GetOrCreate("447700900123")
+		erasure := &models.ContactErasure{}
+		if err := repo.Erase(contact, erasure, &models.AuditLog{Action: models.AuditActionContactErase, ResourceType: "contact", ResourceID: contact.ID}); err != nil {
+			t.Fatalf("Erase: %v", err)
+		}
+		if erasure.CallsErased != 1 || erasure.TranscriptsErased != 1 {
+			t.Errorf("the call recorded before organizations should be erased: %+v", erasure)
+		}
+		var left int64
+		db.Model(&models.TranscriptSegment{}).Count(&left)
+		if left != 0 {
+			t.Errorf("the transcript segments should be erased, %d left", left)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"strings"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/database"
+	"gorm.io/gorm"
+)
+
+// Dialect produces the SQL fragments that differ between the supported
+// databases. Repositories build queries through it instead of writing
+// Postgres-only syntax, so they run on SQLite as well.
+type Dialect interface {
+	// Name is the gorm dialector name, "postgres" or "sqlite"
+	Name() string
+
+	// ContainsFold returns a condition matching rows where column contains
+	// the single placeholder argument, ignoring case. Pass the argument
+	// through LikePattern.
+	ContainsFold(column string) string
+
+	// OrderNullsLast returns an ORDER BY expression that sorts NULLs after
+	// all other values regardless of direction
+	OrderNullsLast(column, direction string) string
+
+	// OrderNullsFirst returns an ORDER BY expression that sorts NULLs
+	// before all other values regardless of direction
+	OrderNullsFirst(column, direction string) string
+
+	// Greatest returns the larger of two SQL expressions
+	Greatest(a, b string) string
+
+	// FullTextSearch reports whether messages have a full-text index.
+	// SQLite only has one in builds with FTS5.
+	FullTextSearch() bool
+
+	// JSONValue returns an expression for the value of key in a JSON object
+	// column, or NULL when it is missing or not of the given kind. The key
+	// is written into the SQL, so it must only hold letters, digits and
+	// underscores.
+	JSONValue(column, key string, kind jsonKind) string
+}
+
+// jsonKind is the type of a value read from a JSON column
+type jsonKind int
+
+const (
+	jsonString jsonKind = iota
+	jsonNumber
+	jsonBool
+)
+
+// DialectFor returns the dialect of a connection. Unknown drivers get the
+// SQLite dialect, which sticks to standard SQL.
+func DialectFor(db *gorm.DB) Dialect {
+	if db.Dialector != nil && db.Dialector.Name() == "postgres" {
+		return postgresDialect{}
+	}
+	return sqliteDialect{fullText: db.Dialector != nil && db.Migrator().HasTable(database.SearchIndexTable)}
+}
+
+// LikePattern escapes the LIKE wildcards in s and wraps it in %, for use
+// with ContainsFold
+func LikePattern(s string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+	return "%" + escaped + "%"
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) ContainsFold(column string) string {
+	return column + ` ILIKE ? ESCAPE '\'`
+}
+
+func (postgresDialect) OrderNullsLast(column, direction string) string {
+	return column + " " + direction + " NULLS LAST"
+}
+
+func (postgresDialect) OrderNullsFirst(column, direction string) string {
+	return column + " " + direction + " NULLS FIRST"
+}
+
+func (postgresDialect) Greatest(a, b string) string {
+	return "GREATEST(" + a + ", " + b + ")"
+}
+
+func (postgresDialect) FullTextSearch() bool { return true }
+
+func (postgresDialect) JSONValue(column, key string, kind jsonKind) string {
+	value := column + "->'" + key + "'"
+	text := column + "->>'" + key + "'"
+	switch kind {
+	case jsonNumber:
+		return "CASE WHEN jsonb_typeof(" + value + ") = 'number' THEN (" + text + ")::numeric END"
+	case jsonBool:
+		return "CASE WHEN jsonb_typeof(" + value + ") = 'boolean' THEN (" + text + ")::boolean END"
+	default:
+		return "CASE WHEN jsonb_typeof(" + value + ") = 'string' THEN " + text + " END"
+	}
+}
+
+type sqliteDialect struct {
+	fullText bool
+}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+// SQLite's LIKE is case-insensitive for ASCII already
+func (sqliteDialect) ContainsFold(column string) string {
+	return column + ` LIKE ? ESCAPE '\'`
+}
+
+func (sqliteDialect) OrderNullsLast(column, direction string) string {
+	return column + " IS NULL, " + column + " " + direction
+}
+
+func (sqliteDialect) OrderNullsFirst(column, direction string) string {
+	return column + " IS NOT NULL, " + column + " " + direction
+}
+
+// SQLite's multi-argument MAX is a scalar function
+func (sqliteDialect) Greatest(a, b string) string {
+	return "MAX(" + a + ", " + b + ")"
+}
+
+func (d sqliteDialect) FullTextSearch() bool { return d.fullText }
+
+// SQLite has no boolean type: JSON true and false read as 1 and 0, like
+// the driver writes Go booleans
+func (sqliteDialect) JSONValue(column, key string, kind jsonKind) string {
+	path := "'$." + key + "'"
+	valueType := "json_type(" + column + ", " + path + ")"
+	switch kind {
+	case jsonNumber:
+		return "CASE WHEN " + valueType + " IN ('integer', 'real') THEN json_extract(" + column + ", " + path + ") END"
+	case jsonBool:
+		return "CASE " + valueType + " WHEN 'true' THEN 1 WHEN 'false' THEN 0 END"
+	default:
+		return "CASE WHEN " + valueType + " = 'text' THEN json_extract(" + column + ", " + path + ") END"
+	}
+}
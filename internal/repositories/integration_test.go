@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/database"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// The integration suite runs every repository test against a migrated SQLite
+// database in a temporary directory and, when TEST_POSTGRES_DSN is set,
+// against Postgres as well, e.g.
+//
+//	TEST_POSTGRES_DSN="host=localhost user=postgres password=postgres dbname=postgres sslmode=disable" go test ./internal/repositories/
+//
+// Every Postgres test runs in a schema of its own that is dropped afterwards.
+
+// forEachDialect runs fn as a subtest per available database
+func forEachDialect(t *testing.T, fn func(t *testing.T, db *gorm.DB)) {
+	t.Helper()
+
+	t.Run("sqlite", func(t *testing.T) {
+		fn(t, openSQLite(t))
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("TEST_POSTGRES_DSN")
+		if dsn == "" {
+			t.Skip("TEST_POSTGRES_DSN is not set")
+		}
+		fn(t, openPostgres(t, dsn))
+	})
+}
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.NewConnection("sqlite", filepath.Join(t.TempDir(), "test.db"), logger.Silent)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { database.CloseConnection(db) })
+	migrate(t, db)
+	return db
+}
+
+func openPostgres(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+	admin, err := database.NewConnection("postgres", dsn, logger.Silent)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		database.CloseConnection(admin)
+	})
+
+	db, err := database.NewConnection("postgres", dsn+" search_path="+schema, logger.Silent)
+	if err != nil {
+		t.Fatalf("open postgres schema: %v", err)
+	}
+	t.Cleanup(func() { database.CloseConnection(db) })
+	migrate(t, db)
+	return db
+}
+
+func migrate(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := database.EnsureSearchIndex(db); err != nil {
+		t.Fatalf("EnsureSearchIndex: %v", err)
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	if got := LikePattern(`50%_off\`); got != `%50\%\_off\\%` {
+		t.Errorf("LikePattern = %q", got)
+	}
+}
+
+func TestDialectQueries(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		dialect := DialectFor(db)
+
+		var greatest int
+		if err := db.Raw("SELECT "+dialect.Greatest("?", "0")+" AS v", -3).Scan(&greatest).Error; err != nil {
+			t.Fatalf("Greatest: %v", err)
+		}
+		if greatest != 0 {
+			t.Errorf("Greatest(-3, 0) = %d", greatest)
+		}
+
+		var matched int64
+		query := "SELECT COUNT(*) FROM (SELECT 'Hello World' AS v) t WHERE " + dialect.ContainsFold("t.v")
+		if err := db.Raw(query, LikePattern("hello")).Scan(&matched).Error; err != nil {
+			t.Fatalf("ContainsFold: %v", err)
+		}
+		if matched != 1 {
+			t.Error("ContainsFold should ignore case")
+		}
+		if err := db.Raw(query, LikePattern("o_W")).Scan(&matched).Error; err != nil {
+			t.Fatalf("ContainsFold: %v", err)
+		}
+		if matched != 0 {
+			t.Error("LIKE wildcards in the argument must match literally")
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// keyKind is the Go type of a keyset column, which cursor values are
+// converted back to before they are compared in SQL
+type keyKind int
+
+const (
+	keyTime keyKind = iota
+	keyString
+	keyInt
+)
+
+// keyset orders a listing by a column and then by ID, which gives every row
+// a unique position that cursors can point at. Pages after a cursor are
+// found with a range condition instead of OFFSET, so they stay fast deep
+// into a table and do not shift when rows are added.
+type keyset struct {
+	table    string
+	column   string
+	kind     keyKind
+	desc     bool
+	nullable bool
+}
+
+// name identifies the sort order in cursors, so a cursor cannot be used
+// with a different one
+func (k keyset) name() string {
+	if k.desc {
+		return k.column + ":desc"
+	}
+	return k.column + ":asc"
+}
+
+func (k keyset) qualified(column string) string {
+	return k.table + "." + column
+}
+
+// order returns the ORDER BY clause. Backward pages are read in reverse and
+// flipped afterwards. NULLs sort last going forward.
+func (k keyset) order(dialect Dialect, backward bool) string {
+	direction := "ASC"
+	if k.desc != backward {
+		direction = "DESC"
+	}
+	column, id := k.qualified(k.column), k.qualified("id")
+	switch {
+	case !k.nullable:
+		return column + " " + direction + ", " + id + " " + direction
+	case backward:
+		return dialect.OrderNullsFirst(column, direction) + ", " + id + " " + direction
+	default:
+		return dialect.OrderNullsLast(column, direction) + ", " + id + " " + direction
+	}
+}
+
+// after returns the condition selecting the rows past the cursor in the
+// direction it points
+func (k keyset) after(cursor *utils.Cursor) (string, []interface{}, error) {
+	op := ">"
+	if k.desc != cursor.Backward {
+		op = "<"
+	}
+	column, id := k.qualified(k.column), k.qualified("id")
+
+	if cursor.Value == nil {
+		if !k.nullable {
+			return "", nil, utils.ErrInvalidCursor()
+		}
+		if cursor.Backward {
+			return "(" + column + " IS NOT NULL OR " + id + " " + op + " ?)", []interface{}{cursor.ID}, nil
+		}
+		return column + " IS NULL AND " + id + " " + op + " ?", []interface{}{cursor.ID}, nil
+	}
+
+	value, err := k.parse(*cursor.Value)
+	if err != nil {
+		return "", nil, utils.ErrInvalidCursor()
+	}
+	condition := "(" + column + ", " + id + ") " + op + " (?, ?)"
+	if k.nullable && !cursor.Backward {
+		condition = "(" + condition + " OR " + column + " IS NULL)"
+	}
+	return condition, []interface{}{value, cursor.ID}, nil
+}
+
+func (k keyset) parse(value string) (interface{}, error) {
+	switch k.kind {
+	case keyTime:
+		return time.Parse(time.RFC3339Nano, value)
+	case keyInt:
+		return strconv.Atoi(value)
+	default:
+		return value, nil
+	}
+}
+
+// cursor returns the cursor for the row with the given sort value and ID
+func (k keyset) cursor(value interface{}, id string, backward bool) string {
+	cursor := utils.Cursor{Key: k.name(), ID: id, Backward: backward}
+	var text string
+	switch v := value.(type) {
+	case *time.Time:
+		if v == nil {
+			return cursor.Encode()
+		}
+		text = v.UTC().Format(time.RFC3339Nano)
+	case time.Time:
+		text = v.UTC().Format(time.RFC3339Nano)
+	default:
+		text = fmt.Sprint(v)
+	}
+	cursor.Value = &text
+	return cursor.Encode()
+}
+
+// paginateKeyset runs a listing query one page at a time. With a cursor it
+// returns the page next to the cursor's row, otherwise the page at the
+// offset. The total is counted unless pagination.SkipTotal is set, and the
+// next and previous cursors are set on pagination. position returns the sort
+// value and ID of a row.
+func paginateKeyset[T any](query *gorm.DB, dialect Dialect, key keyset, pagination *utils.Pagination, position func(T) (interface{}, string)) ([]T, error) {
+	var cursor *utils.Cursor
+	if pagination.Cursor != "" {
+		decoded, err := utils.DecodeCursor(pagination.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if decoded.Key != key.name() {
+			return nil, utils.ErrInvalidCursor()
+		}
+		cursor = decoded
+	}
+
+	if !pagination.SkipTotal {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		pagination.SetTotal(total)
+	}
+
+	backward := cursor != nil && cursor.Backward
+	page := query.Session(&gorm.Session{})
+	if cursor != nil {
+		condition, args, err := key.after(cursor)
+		if err != nil {
+			return nil, err
+		}
+		page = page.Where(condition, args...)
+	} else if pagination.Offset > 0 {
+		page = page.Offset(pagination.Offset)
+	}
+
+	// One extra row tells whether there is another page
+	var rows []T
+	err := page.Order(key.order(dialect, backward)).Limit(pagination.Limit + 1).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	more := len(rows) > pagination.Limit
+	if more {
+		rows = rows[:pagination.Limit]
+	}
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	// Going backward there is always a next page, the one the cursor came
+	// from; going forward there is a previous one unless this is the start
+	hasNext, hasPrev := more, cursor != nil || pagination.Offset > 0
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	pagination.HasMore = hasNext
+	pagination.NextCursor, pagination.PrevCursor = "", ""
+	if len(rows) > 0 {
+		if hasNext {
+			value, id := position(rows[len(rows)-1])
+			pagination.NextCursor = key.cursor(value, id, false)
+		}
+		if hasPrev {
+			value, id := position(rows[0])
+			pagination.PrevCursor = key.cursor(value, id, true)
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LeaseRepository stores leases in the database so they hold across
+// replicas
+type LeaseRepository struct {
+	*BaseRepository
+}
+
+// NewLeaseRepository creates a new lease repository
+func NewLeaseRepository(db *gorm.DB) *LeaseRepository {
+	return &LeaseRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *LeaseRepository) WithContext(ctx context.Context) LeaseStore {
+	return &LeaseRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
+// Acquire takes the lease for ttl if it is free, expired or already held by
+// owner, which renews it. It reports whether owner holds the lease.
+func (r *LeaseRepository) Acquire(name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	result := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"owner", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			gorm.Expr("leases.owner = ? OR leases.expires_at < ?", owner, now),
+		}},
+	}).Create(&models.Lease{Name: name, Owner: owner, ExpiresAt: now.Add(ttl)})
+	return result.RowsAffected > 0, result.Error
+}
+
+// Release gives up the lease if owner holds it
+func (r *LeaseRepository) Release(name, owner string) error {
+	return r.DB.Where("name = ? AND owner = ?", name, owner).Delete(&models.Lease{}).Error
+}
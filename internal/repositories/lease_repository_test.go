@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestLeaseRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewLeaseRepository(db)
+
+		if ok, err := repo.Acquire("job", "worker_a", time.Minute); err != nil || !ok {
+			t.Fatalf("a free lease should be acquired: %v %v", ok, err)
+		}
+		if ok, err := repo.Acquire("job", "worker_b", time.Minute); err != nil || ok {
+			t.Errorf("a held lease should not be acquired by another owner: %v %v", ok, err)
+		}
+		if ok, err := repo.Acquire("job", "worker_a", time.Minute); err != nil || !ok {
+			t.Errorf("the holder should renew its lease: %v %v", ok, err)
+		}
+		if ok, _ := repo.Acquire("other", "worker_b", time.Minute); !ok {
+			t.Error("leases should be independent")
+		}
+
+		// Another owner's release is ignored
+		repo.Release("job", "worker_b")
+		if ok, _ := repo.Acquire("job", "worker_b", time.Minute); ok {
+			t.Error("only the holder should release a lease")
+		}
+		if err := repo.Release("job", "worker_a"); err != nil {
+			t.Fatalf("Release: %v", err)
+		}
+		if ok, _ := repo.Acquire("job", "worker_b", -time.Second); !ok {
+			t.Error("a released lease should be free")
+		}
+
+		// worker_b's lease has expired already
+		if ok, _ := repo.Acquire("job", "worker_a", time.Minute); !ok {
+			t.Error("an expired lease should be taken over")
+		}
+	})
+}
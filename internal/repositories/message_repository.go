@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"context"
+	"strings"
 	"time"
 
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
@@ -8,6 +10,13 @@ import (
 	"gorm.io/gorm"
 )
 
+// messageKeyset orders message listings newest first
+var messageKeyset = keyset{table: "messages", column: "timestamp", kind: keyTime, desc: true}
+
+func messagePosition(m *models.Message) (interface{}, string) {
+	return m.Timestamp, m.ID
+}
+
 // MessageRepository handles message data access
 type MessageRepository struct {
 	*BaseRepository
@@ -20,23 +29,25 @@ func NewMessageRepository(db *gorm.DB) *MessageRepository {
 	}
 }
 
-// FindByPhone finds messages by phone number with pagination
-func (r *MessageRepository) FindByPhone(phone string, pagination *utils.Pagination) ([]*models.Message, error) {
-	var messages []*models.Message
-
-	query := r.DB.Where("from_number = ? OR to_number = ?", phone, phone).
-		Order("timestamp DESC")
+// ForOrganization returns a repository scoped to the given organization
+func (r *MessageRepository) ForOrganization(orgID string) MessageStore {
+	return &MessageRepository{BaseRepository: r.BaseRepository.ForOrganization(orgID)}
+}
 
-	// Get total count
-	var total int64
-	if err := query.Model(&models.Message{}).Count(&total).Error; err != nil {
-		return nil, err
-	}
-	pagination.SetTotal(total)
+// WithContext returns a repository whose queries run with ctx
+func (r *MessageRepository) WithContext(ctx context.Context) MessageStore {
+	return &MessageRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
 
-	// Apply pagination and fetch
-	err := pagination.ApplyToQuery(query).Find(&messages).Error
-	return messages, err
+// FindByPhone finds messages to or from a phone number, newest first. It
+// supports cursor pagination. Webhooks report senders by WhatsApp ID, which
+// is the number without the leading +, so both forms match.
+func (r *MessageRepository) FindByPhone(phone string, pagination *utils.Pagination) ([]*models.Message, error) {
+	digits := strings.TrimPrefix(phone, "+")
+	phones := []string{digits, "+" + digits}
+	query := r.DB.Model(&models.Message{}).
+		Where("from_number IN ? OR to_number IN ?", phones, phones)
+	return paginateKeyset(query, r.Dialect, messageKeyset, pagination, messagePosition)
 }
 
 // FindByDateRange finds messages within a date range
@@ -84,35 +95,138 @@ func (r *MessageRepository) FindByWhatsAppMessageID(waMessageID string) (*models
 	return &message, err
 }
 
-// Search performs full-text search on message content
-func (r *MessageRepository) Search(query string, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Message, error) {
+// FindByWhatsAppMessageIDs finds the messages with the given WhatsApp
+// message IDs. IDs without a message are skipped.
+func (r *MessageRepository) FindByWhatsAppMessageIDs(whatsappMessageIDs []string) ([]*models.Message, error) {
 	var messages []*models.Message
+	if len(whatsappMessageIDs) == 0 {
+		return messages, nil
+	}
+	err := r.DB.Where("whatsapp_message_id IN ?", whatsappMessageIDs).Find(&messages).Error
+	return messages, err
+}
 
-	dbQuery := r.DB.Where("content ILIKE ?", "%"+query+"%")
+// Search performs full-text search on message text, captions and
+// filenames, using the web search syntax described in search_query.go.
+// Results are ordered by relevance, then by recency.
+func (r *MessageRepository) Search(query string, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.MessageSearchResult, error) {
+	parsed := parseSearchQuery(query)
+	if parsed.empty() {
+		pagination.SetTotal(0)
+		return []*models.MessageSearchResult{}, nil
+	}
 
-	// Apply filters
-	if phone, ok := filters["phone"].(string); ok && phone != "" {
-		dbQuery = dbQuery.Where("from_number = ? OR to_number = ?", phone, phone)
+	switch {
+	case r.Dialect.Name() == "postgres":
+		return r.searchPostgres(parsed, filters, pagination)
+	case r.Dialect.FullTextSearch():
+		return r.searchFTS5(parsed, filters, pagination)
+	default:
+		return r.searchLike(parsed, filters, pagination)
 	}
-	if direction, ok := filters["direction"].(string); ok && direction != "" {
-		dbQuery = dbQuery.Where("direction = ?", direction)
+}
+
+// searchRow is a message with the rank and snippet computed by the database
+type searchRow struct {
+	models.Message
+	Rank    float64 `gorm:"column:search_rank"`
+	Snippet string  `gorm:"column:search_snippet"`
+}
+
+func (r *MessageRepository) searchPostgres(q searchQuery, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.MessageSearchResult, error) {
+	tsquery, arg := "websearch_to_tsquery('english', ?)", q.websearch()
+	if q.hasPrefix() {
+		tsquery, arg = "to_tsquery('english', ?)", q.tsquery()
 	}
-	if msgType, ok := filters["type"].(string); ok && msgType != "" {
-		dbQuery = dbQuery.Where("message_type = ?", msgType)
+
+	base := applyMessageFilters(r.DB.Model(&models.Message{}).
+		Joins("CROSS JOIN (SELECT "+tsquery+" AS query) search", arg).
+		Where("messages.search_vector @@ search.query"), filters)
+
+	return r.runSearch(base, pagination, `messages.*,
+		ts_rank(messages.search_vector, search.query) AS search_rank,
+		ts_headline('english', concat_ws(' ', messages.content, messages.caption, messages.filename), search.query,
+			'StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=8, MaxFragments=2, FragmentDelimiter=" … "') AS search_snippet`,
+		"search_rank DESC")
+}
+
+func (r *MessageRepository) searchFTS5(q searchQuery, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.MessageSearchResult, error) {
+	base := applyMessageFilters(r.DB.Model(&models.Message{}).
+		Joins("JOIN messages_fts ON messages_fts.message_id = messages.id").
+		Where("messages_fts MATCH ?", q.fts5()), filters)
+
+	// bm25 is lower for better matches; it is negated so higher ranks first
+	// like on Postgres
+	return r.runSearch(base, pagination, `messages.*,
+		-bm25(messages_fts) AS search_rank,
+		snippet(messages_fts, -1, '<mark>', '</mark>', '…', 16) AS search_snippet`,
+		"search_rank DESC")
+}
+
+// searchLike is used on SQLite builds without FTS5. It matches substrings,
+// does not rank and builds snippets in Go.
+func (r *MessageRepository) searchLike(q searchQuery, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.MessageSearchResult, error) {
+	var groups []string
+	var args []interface{}
+	for _, group := range q.groups {
+		if positives(group) == 0 {
+			continue
+		}
+		var conditions []string
+		for _, clause := range group {
+			pattern := LikePattern(strings.Join(clause.words, " "))
+			condition := "(" + r.Dialect.ContainsFold("messages.content") +
+				" OR " + r.Dialect.ContainsFold("messages.caption") +
+				" OR " + r.Dialect.ContainsFold("messages.filename") + ")"
+			if clause.negate {
+				condition = "NOT COALESCE(" + condition + ", FALSE)"
+			}
+			conditions = append(conditions, condition)
+			args = append(args, pattern, pattern, pattern)
+		}
+		groups = append(groups, "("+strings.Join(conditions, " AND ")+")")
 	}
 
-	dbQuery = dbQuery.Order("timestamp DESC")
+	base := applyMessageFilters(r.DB.Model(&models.Message{}).
+		Where(strings.Join(groups, " OR "), args...), filters)
 
-	// Get total count
+	results, err := r.runSearch(base, pagination, "messages.*, 0 AS search_rank, '' AS search_snippet", "")
+	if err != nil {
+		return nil, err
+	}
+	terms := q.terms()
+	for _, result := range results {
+		for _, text := range []string{result.Content, result.Caption, result.Filename} {
+			if result.Snippet = highlight(text, terms); result.Snippet != "" {
+				break
+			}
+		}
+	}
+	return results, nil
+}
+
+// runSearch counts and fetches a page of search results
+func (r *MessageRepository) runSearch(base *gorm.DB, pagination *utils.Pagination, columns, order string) ([]*models.MessageSearchResult, error) {
 	var total int64
-	if err := dbQuery.Model(&models.Message{}).Count(&total).Error; err != nil {
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
 		return nil, err
 	}
 	pagination.SetTotal(total)
 
-	// Apply pagination
-	err := pagination.ApplyToQuery(dbQuery).Find(&messages).Error
-	return messages, err
+	query := base.Select(columns)
+	if order != "" {
+		query = query.Order(order)
+	}
+	var rows []searchRow
+	if err := pagination.ApplyToQuery(query.Order("messages.timestamp DESC")).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]*models.MessageSearchResult, len(rows))
+	for i := range rows {
+		results[i] = &models.MessageSearchResult{Message: &rows[i].Message, Rank: rows[i].Rank, Snippet: rows[i].Snippet}
+	}
+	return results, nil
 }
 
 // CountByPhone counts messages for a phone number
@@ -124,49 +238,122 @@ func (r *MessageRepository) CountByPhone(phone string) (int64, error) {
 	return count, err
 }
 
-// ListWithFilters lists messages with various filters
+// ListWithFilters lists messages with various filters, newest first. It
+// supports cursor pagination.
 func (r *MessageRepository) ListWithFilters(filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Message, error) {
+	query := applyMessageFilters(r.DB.Model(&models.Message{}), filters)
+	return paginateKeyset(query, r.Dialect, messageKeyset, pagination, messagePosition)
+}
+
+// UpdateStatus updates the status of a message
+func (r *MessageRepository) UpdateStatus(whatsappMessageID, status string) error {
+	return r.DB.Model(&models.Message{}).
+		Where("whatsapp_message_id = ?", whatsappMessageID).
+		Update("status", status).Error
+}
+
+// UpdateByWhatsAppMessageID updates fields of the message with the given WhatsApp message ID
+func (r *MessageRepository) UpdateByWhatsAppMessageID(whatsappMessageID string, updates map[string]interface{}) error {
+	return r.DB.Model(&models.Message{}).
+		Where("whatsapp_message_id = ?", whatsappMessageID).
+		Updates(updates).Error
+}
+
+// MessageAgeFilter selects the messages a retention policy applies to
+type MessageAgeFilter struct {
+	// Before selects messages sent before it
+	Before time.Time
+	// Types, when set, selects messages of these types only, and
+	// ExcludeTypes leaves messages of these types out
+	Types        []string
+	ExcludeTypes []string
+	// WithMedia selects messages with media only
+	WithMedia bool
+}
+
+// apply restricts a message query to the messages the filter selects
+func (f MessageAgeFilter) apply(query *gorm.DB) *gorm.DB {
+	query = query.Where("timestamp < ?", f.Before)
+	if len(f.Types) > 0 {
+		query = query.Where("message_type IN ?", f.Types)
+	}
+	if len(f.ExcludeTypes) > 0 {
+		query = query.Where("message_type NOT IN ?", f.ExcludeTypes)
+	}
+	if f.WithMedia {
+		query = query.Where("media_url IS NOT NULL AND media_url <> ''")
+	}
+	return query
+}
+
+// FindAged finds up to limit messages the filter selects, oldest first
+func (r *MessageRepository) FindAged(filter MessageAgeFilter, limit int) ([]*models.Message, error) {
 	var messages []*models.Message
+	err := filter.apply(r.DB.Model(&models.Message{})).
+		Order("timestamp ASC").Order("id ASC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
 
-	query := r.DB.Model(&models.Message{})
+// CountAged counts the messages the filter selects
+func (r *MessageRepository) CountAged(filter MessageAgeFilter) (int64, error) {
+	var count int64
+	err := filter.apply(r.DB.Model(&models.Message{})).Count(&count).Error
+	return count, err
+}
 
-	// Apply filters
+// DeleteMessages deletes the messages with the given IDs and the reactions
+// to them, and returns how many messages were deleted
+func (r *MessageRepository) DeleteMessages(ids []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	var deleted int64
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		targets := tx.Model(&models.Message{}).Select("whatsapp_message_id").Where("id IN ?", ids)
+		if err := tx.Where("target_whatsapp_message_id IN (?)", targets).Delete(&models.Reaction{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id IN ?", ids).Delete(&models.Message{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
+
+// RemoveMedia clears the media of the messages with the given IDs, marking
+// it removed at at, and returns how many messages changed
+func (r *MessageRepository) RemoveMedia(ids []string, at time.Time) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.DB.Model(&models.Message{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{"media_url": "", "media_mime_type": "", "media_removed_at": at})
+	return result.RowsAffected, result.Error
+}
+
+// applyMessageFilters restricts a message query by the list and search
+// filters. Columns are qualified as search queries join other tables.
+func applyMessageFilters(query *gorm.DB, filters map[string]interface{}) *gorm.DB {
 	if phone, ok := filters["phone"].(string); ok && phone != "" {
-		query = query.Where("from_number = ? OR to_number = ?", phone, phone)
+		query = query.Where("messages.from_number = ? OR messages.to_number = ?", phone, phone)
 	}
 	if direction, ok := filters["direction"].(string); ok && direction != "" {
-		query = query.Where("direction = ?", direction)
+		query = query.Where("messages.direction = ?", direction)
 	}
 	if msgType, ok := filters["type"].(string); ok && msgType != "" {
-		query = query.Where("message_type = ?", msgType)
+		query = query.Where("messages.message_type = ?", msgType)
 	}
 	if status, ok := filters["status"].(string); ok && status != "" {
-		query = query.Where("status = ?", status)
+		query = query.Where("messages.status = ?", status)
 	}
 	if startDate, ok := filters["start_date"].(time.Time); ok && !startDate.IsZero() {
-		query = query.Where("timestamp >= ?", startDate)
+		query = query.Where("messages.timestamp >= ?", startDate)
 	}
 	if endDate, ok := filters["end_date"].(time.Time); ok && !endDate.IsZero() {
-		query = query.Where("timestamp <= ?", endDate)
-	}
-
-	query = query.Order("timestamp DESC")
-
-	// Get total count
-	var total int64
-	if err := query.Count(&total).Error; err != nil {
-		return nil, err
+		query = query.Where("messages.timestamp <= ?", endDate)
 	}
-	pagination.SetTotal(total)
-
-	// Apply pagination
-	err := pagination.ApplyToQuery(query).Find(&messages).Error
-	return messages, err
-}
-
-// UpdateStatus updates the status of a message
-func (r *MessageRepository) UpdateStatus(whatsappMessageID, status string) error {
-	return r.DB.Model(&models.Message{}).
-		Where("whatsapp_message_id = ?", whatsappMessageID).
-		Update("status", status).Error
+	return query
 }
@@ -0,0 +1,488 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+func seedMessages(t *testing.T, repo MessageStore) []*models.Message {
+	t.Helper()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	messages := []*models.Message{
+		{WhatsAppMessageID: "wamid.1", FromNumber: "15550000000", ToNumber: "14155550100", Direction: "outbound", MessageType: "text", Content: "Hello Ada", Status: models.MessageStatusSent, Timestamp: base},
+		{WhatsAppMessageID: "wamid.2", FromNumber: "14155550100", ToNumber: "15550000000", Direction: "inbound", MessageType: "text", Content: "hello back, 100% sure", Status: models.MessageStatusDelivered, Timestamp: base.Add(time.Minute)},
+		{WhatsAppMessageID: "wamid.3", FromNumber: "15550000000", ToNumber: "14155550199", Direction: "outbound", MessageType: "image", Content: "a photo", Status: models.MessageStatusSent, Timestamp: base.Add(2 * time.Minute),
+			Metadata: models.JSONMap{"caption": "sunset"}},
+	}
+	for _, m := range messages {
+		if err := repo.Create(m); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	return messages
+}
+
+func TestMessageRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		root := NewMessageRepository(db)
+		repo := root.WithContext(context.Background()).ForOrganization("org_a").(*MessageRepository)
+		other := root.ForOrganization("org_b")
+
+		seeded := seedMessages(t, repo)
+		seedMessages(t, prefixed(other, "b"))
+
+		var found models.Message
+		if err := repo.FindByID(seeded[2].ID, &found); err != nil {
+			t.Fatalf("FindByID: %v", err)
+		}
+		if found.OrganizationID != "org_a" || found.Metadata["caption"] != "sunset" {
+			t.Errorf("unexpected message %+v", found)
+		}
+		if err := other.FindByID(seeded[2].ID, &found); err == nil {
+			t.Error("message must not be visible to another organization")
+		}
+
+		byPhone, err := repo.FindByPhone("14155550100", utils.NewPagination(10, 0))
+		if err != nil || len(byPhone) != 2 || byPhone[0].WhatsAppMessageID != "wamid.2" {
+			t.Errorf("FindByPhone: %v %v", messageIDs(byPhone), err)
+		}
+
+		count, err := repo.CountByPhone("14155550100")
+		if err != nil || count != 2 {
+			t.Errorf("CountByPhone = %d, %v", count, err)
+		}
+
+		start := seeded[0].Timestamp.Add(30 * time.Second)
+		byDate, err := repo.FindByDateRange(start, start.Add(time.Hour), utils.NewPagination(10, 0))
+		if err != nil || len(byDate) != 2 {
+			t.Errorf("FindByDateRange: %v %v", messageIDs(byDate), err)
+		}
+
+		byStatus, err := repo.FindByStatus(models.MessageStatusSent, utils.NewPagination(10, 0))
+		if err != nil || len(byStatus) != 2 {
+			t.Errorf("FindByStatus: %v %v", messageIDs(byStatus), err)
+		}
+
+		byWAID, err := repo.FindByWhatsAppMessageID("wamid.2")
+		if err != nil || byWAID.ID != seeded[1].ID {
+			t.Errorf("FindByWhatsAppMessageID: %+v %v", byWAID, err)
+		}
+	})
+}
+
+func TestMessageRepositoryStopsWithRequestContext(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		root := NewMessageRepository(db)
+		seedMessages(t, root.ForOrganization("org_a"))
+
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+		found, err := root.WithContext(cancelled).ForOrganization("org_a").FindByWhatsAppMessageIDs([]string{"wamid.1"})
+		if err != context.Canceled || len(found) != 0 {
+			t.Errorf("a query with a cancelled context should fail with context.Canceled, got %d messages and %v", len(found), err)
+		}
+
+		expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		if err := root.WithContext(expired).ForOrganization("org_a").UpdateByWhatsAppMessageID("wamid.1", map[string]interface{}{"status": models.MessageStatusRead}); err != context.DeadlineExceeded {
+			t.Errorf("an update past the deadline should fail with context.DeadlineExceeded, got %v", err)
+		}
+
+		found, err = root.ForOrganization("org_a").FindByWhatsAppMessageIDs([]string{"wamid.1"})
+		if err != nil || len(found) != 1 || found[0].Status != models.MessageStatusSent {
+			t.Errorf("the update past the deadline should not apply: %+v %v", found, err)
+		}
+	})
+}
+
+func TestMessageRepositorySearch(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewMessageRepository(db).ForOrganization("org_a")
+		seeded := seedMessages(t, repo)
+		seedMessages(t, prefixed(NewMessageRepository(db).ForOrganization("org_b"), "b"))
+
+		pagination := utils.NewPagination(10, 0)
+		results, err := repo.Search("HELLO", nil, pagination)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if len(results) != 2 || pagination.Total != 2 {
+			t.Errorf("case-insensitive search found %v (total %d)", resultIDs(results), pagination.Total)
+		}
+		for _, r := range results {
+			if !strings.Contains(r.Snippet, "<mark>") || r.OrganizationID != "org_a" {
+				t.Errorf("unexpected result %q in %s", r.Snippet, r.OrganizationID)
+			}
+		}
+
+		// % is not a wildcard
+		results, _ = repo.Search("100%", nil, utils.NewPagination(10, 0))
+		if len(results) != 1 {
+			t.Errorf("search for 100%% found %v", resultIDs(results))
+		}
+
+		// The phone filter is an OR condition; it must not escape the
+		// organization scope
+		results, _ = repo.Search("hello", map[string]interface{}{"phone": "14155550100", "direction": "inbound"}, utils.NewPagination(10, 0))
+		if len(results) != 1 || results[0].ID != seeded[1].ID {
+			t.Errorf("filtered search found %v", resultIDs(results))
+		}
+
+		results, _ = repo.Search("hello", map[string]interface{}{
+			"status":     models.MessageStatusSent,
+			"start_date": seeded[0].Timestamp,
+			"end_date":   seeded[0].Timestamp,
+		}, utils.NewPagination(10, 0))
+		if len(results) != 1 || results[0].ID != seeded[0].ID {
+			t.Errorf("status and date filters found %v", resultIDs(results))
+		}
+
+		if results, _ := repo.Search("   ", nil, utils.NewPagination(10, 0)); len(results) != 0 {
+			t.Errorf("blank query found %v", resultIDs(results))
+		}
+	})
+}
+
+func TestMessageRepositorySearchSyntax(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewMessageRepository(db).ForOrganization("org_a")
+		base := time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)
+		messages := map[string]*models.Message{
+			"late":     {Content: "Your order is running late, sorry about the delay"},
+			"shipped":  {Content: "The order shipped today and is not late"},
+			"invoice":  {MessageType: "document", Caption: "Here is the invoice", Filename: "march_invoice.pdf"},
+			"receipt":  {MessageType: "document", Filename: "receipt-2026.pdf"},
+			"sunset":   {MessageType: "image", Caption: "Sunset over the bay"},
+			"reminder": {Content: "Friendly reminder: your appointment is tomorrow"},
+		}
+		i := 0
+		for key, m := range messages {
+			m.WhatsAppMessageID = "wamid." + key
+			m.FromNumber, m.ToNumber, m.Direction, m.Status = "15550000000", "14155550100", "outbound", models.MessageStatusSent
+			if m.MessageType == "" {
+				m.MessageType = "text"
+			}
+			m.Timestamp = base.Add(time.Duration(i) * time.Minute)
+			i++
+			if err := repo.Create(m); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+
+		cases := []struct {
+			query string
+			want  []string
+		}{
+			{"order", []string{"wamid.late", "wamid.shipped"}},
+			{`"running late"`, []string{"wamid.late"}},
+			{"order -shipped", []string{"wamid.late"}},
+			{"invoice OR sunset", []string{"wamid.invoice", "wamid.sunset"}},
+			{"remind*", []string{"wamid.reminder"}},
+			{"receipt", []string{"wamid.receipt"}},
+			{"march", []string{"wamid.invoice"}},
+			{"-late", nil},
+		}
+		for _, tc := range cases {
+			results, err := repo.Search(tc.query, nil, utils.NewPagination(10, 0))
+			if err != nil {
+				t.Errorf("Search(%q): %v", tc.query, err)
+				continue
+			}
+			got := resultIDs(results)
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("Search(%q) = %v, want %v", tc.query, got, tc.want)
+			}
+		}
+
+		results, _ := repo.Search("invoice", nil, utils.NewPagination(10, 0))
+		if len(results) != 1 || !strings.Contains(strings.ToLower(results[0].Snippet), "<mark>invoice</mark>") {
+			t.Errorf("caption was not highlighted: %+v", results)
+		}
+	})
+}
+
+func TestMessageRepositoryListAndUpdate(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewMessageRepository(db).ForOrganization("org_a").(*MessageRepository)
+		seeded := seedMessages(t, repo)
+		seedMessages(t, prefixed(NewMessageRepository(db).ForOrganization("org_b"), "b"))
+
+		pagination := utils.NewPagination(2, 0)
+		listed, err := repo.ListWithFilters(map[string]interface{}{}, pagination)
+		if err != nil {
+			t.Fatalf("ListWithFilters: %v", err)
+		}
+		if len(listed) != 2 || pagination.Total != 3 || !pagination.HasMore || listed[0].ID != seeded[2].ID {
+			t.Errorf("ListWithFilters: %v total %d", messageIDs(listed), pagination.Total)
+		}
+
+		listed, _ = repo.ListWithFilters(map[string]interface{}{
+			"phone":      "14155550100",
+			"status":     models.MessageStatusSent,
+			"start_date": seeded[0].Timestamp,
+			"end_date":   seeded[2].Timestamp,
+		}, utils.NewPagination(10, 0))
+		if len(listed) != 1 || listed[0].ID != seeded[0].ID {
+			t.Errorf("filtered ListWithFilters: %v", messageIDs(listed))
+		}
+
+		if err := repo.UpdateStatus("wamid.1", models.MessageStatusDelivered); err != nil {
+			t.Fatalf("UpdateStatus: %v", err)
+		}
+		if err := repo.UpdateByWhatsAppMessageID("wamid.3", map[string]interface{}{
+			"status":          models.MessageStatusFailed,
+			"error_code":      "131026",
+			"error_retryable": true,
+		}); err != nil {
+			t.Fatalf("UpdateByWhatsAppMessageID: %v", err)
+		}
+
+		var delivered, failed models.Message
+		repo.FindByID(seeded[0].ID, &delivered)
+		repo.FindByID(seeded[2].ID, &failed)
+		if delivered.Status != models.MessageStatusDelivered {
+			t.Errorf("UpdateStatus did not apply: %s", delivered.Status)
+		}
+		if failed.Status != models.MessageStatusFailed || failed.ErrorCode != "131026" || !failed.ErrorRetryable {
+			t.Errorf("UpdateByWhatsAppMessageID did not apply: %+v", failed)
+		}
+
+		// The other organization's copies are untouched
+		otherMessage, err := NewMessageRepository(db).ForOrganization("org_b").(*MessageRepository).FindByWhatsAppMessageID("b.wamid.3")
+		if err != nil || otherMessage.Status != models.MessageStatusSent {
+			t.Errorf("update leaked into another organization: %+v %v", otherMessage, err)
+		}
+	})
+}
+
+func TestMessageRepositoryCursorPagination(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewMessageRepository(db).ForOrganization("org_a")
+		base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		// Pairs of messages share a timestamp, so pages must break ties by ID
+		for i := 0; i < 7; i++ {
+			m := &models.Message{WhatsAppMessageID: fmt.Sprintf("wamid.%d", i), FromNumber: "14155550100", ToNumber: "15550000000", Direction: "inbound", MessageType: "text",
+				Content: "message", Status: models.MessageStatusDelivered, Timestamp: base.Add(time.Duration(i/2) * time.Minute)}
+			if err := repo.Create(m); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		all, err := repo.ListWithFilters(map[string]interface{}{}, utils.NewPagination(100, 0))
+		if err != nil || len(all) != 7 {
+			t.Fatalf("ListWithFilters: %v %v", messageIDs(all), err)
+		}
+
+		// Walk forward three at a time without counting
+		var walked []*models.Message
+		var pages []*utils.Pagination
+		pagination := &utils.Pagination{Limit: 3, SkipTotal: true}
+		for {
+			page, err := repo.ListWithFilters(map[string]interface{}{}, pagination)
+			if err != nil {
+				t.Fatalf("page %d: %v", len(pages), err)
+			}
+			if pagination.Counted() {
+				t.Error("SkipTotal should not count the rows")
+			}
+			walked = append(walked, page...)
+			pages = append(pages, pagination)
+			if (len(pages) == 1) != (pagination.PrevCursor == "") {
+				t.Errorf("page %d prev cursor %q", len(pages), pagination.PrevCursor)
+			}
+			if !pagination.HasMore {
+				break
+			}
+			if len(page) != 3 || pagination.NextCursor == "" {
+				t.Fatalf("page %d: %v next %q", len(pages), messageIDs(page), pagination.NextCursor)
+			}
+			// New messages arriving meanwhile do not shift later pages
+			if len(pages) == 1 {
+				repo.Create(&models.Message{WhatsAppMessageID: "wamid.new", FromNumber: "+14155550100", ToNumber: "+14155550100", Direction: "outbound", MessageType: "text",
+					Status: models.MessageStatusDelivered, Timestamp: base.Add(time.Hour)})
+			}
+			pagination = &utils.Pagination{Limit: 3, SkipTotal: true, Cursor: pagination.NextCursor}
+		}
+		if got, want := strings.Join(messageIDs(walked), ","), strings.Join(messageIDs(all), ","); got != want {
+			t.Errorf("forward walk = %s, want %s", got, want)
+		}
+		if len(pages) != 3 || pages[2].NextCursor != "" {
+			t.Errorf("expected 3 pages, the last without a next cursor: %d", len(pages))
+		}
+
+		// The previous cursor of the last page returns the middle page
+		back := &utils.Pagination{Limit: 3, Cursor: pages[2].PrevCursor}
+		page, err := repo.ListWithFilters(map[string]interface{}{}, back)
+		if err != nil || strings.Join(messageIDs(page), ",") != strings.Join(messageIDs(all[3:6]), ",") {
+			t.Errorf("backward page = %v, want %v (%v)", messageIDs(page), messageIDs(all[3:6]), err)
+		}
+		if !back.HasMore || back.NextCursor == "" || back.PrevCursor == "" || back.Total != 8 {
+			t.Errorf("backward page pagination: %+v", back)
+		}
+		back = &utils.Pagination{Limit: 3, Cursor: back.PrevCursor}
+		page, _ = repo.ListWithFilters(map[string]interface{}{}, back)
+		if strings.Join(messageIDs(page), ",") != strings.Join(messageIDs(all[:3]), ",") || back.PrevCursor == "" {
+			t.Errorf("first page walking backward: %v prev %q", messageIDs(page), back.PrevCursor)
+		}
+		// Before it is only the message added during the walk
+		back = &utils.Pagination{Limit: 3, Cursor: back.PrevCursor}
+		page, _ = repo.ListWithFilters(map[string]interface{}{}, back)
+		if len(page) != 1 || page[0].WhatsAppMessageID != "wamid.new" || back.PrevCursor != "" {
+			t.Errorf("newest page walking backward: %v prev %q", messageIDs(page), back.PrevCursor)
+		}
+
+		// Outbound messages store the number with a +, inbound ones without
+		byPhone := &utils.Pagination{Limit: 5, SkipTotal: true}
+		page, err = repo.(*MessageRepository).FindByPhone("14155550100", byPhone)
+		if err != nil || len(page) != 5 || byPhone.NextCursor == "" {
+			t.Fatalf("FindByPhone: %v %v", messageIDs(page), err)
+		}
+		byPhone = &utils.Pagination{Limit: 5, SkipTotal: true, Cursor: byPhone.NextCursor}
+		page, _ = repo.(*MessageRepository).FindByPhone("14155550100", byPhone)
+		if len(page) != 3 || byPhone.HasMore {
+			t.Errorf("FindByPhone second page: %v", messageIDs(page))
+		}
+
+		contactCursor := utils.Cursor{Key: "name:asc", ID: "ct_1"}
+		for _, cursor := range []string{"not a cursor", contactCursor.Encode()} {
+			_, err := repo.ListWithFilters(map[string]interface{}{}, &utils.Pagination{Limit: 3, Cursor: cursor})
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.StatusCode != 400 {
+				t.Errorf("cursor %q should be rejected, got %v", cursor, err)
+			}
+		}
+	})
+}
+
+// prefixed returns a store that prefixes WhatsApp message IDs, so the same
+// seed can be used for several organizations
+func prefixed(store MessageStore, prefix string) MessageStore {
+	return prefixedMessages{MessageStore: store, prefix: prefix}
+}
+
+type prefixedMessages struct {
+	MessageStore
+	prefix string
+}
+
+func (p prefixedMessages) Create(model interface{}) error {
+	m := model.(*models.Message)
+	m.WhatsAppMessageID = p.prefix + "." + m.WhatsAppMessageID
+	return p.MessageStore.Create(m)
+}
+
+func resultIDs(results []*models.MessageSearchResult) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.WhatsAppMessageID
+	}
+	return ids
+}
+
+func messageIDs(messages []*models.Message) []string {
+	ids := make([]string, len(messages))
+	for i, m := range messages {
+		ids[i] = m.WhatsAppMessageID
+	}
+	return ids
+}
+
+func TestMessageRepositoryRetention(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		root := NewMessageRepository(db)
+		repo := root.ForOrganization("org_a")
+		other := root.ForOrganization("org_b")
+		reactions := NewReactionRepository(db).ForOrganization("org_a")
+
+		seeded := seedMessages(t, repo)
+		base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		other.Create(&models.Message{FromNumber: "15550000001", ToNumber: "14155550100", Direction: "outbound", MessageType: "text", Status: models.MessageStatusSent, Timestamp: base})
+		photo := &models.Message{WhatsAppMessageID: "wamid.4", FromNumber: "15550000000", ToNumber: "14155550100", Direction: "outbound", MessageType: "image",
+			MediaURL: "photo.jpg", MediaMimeType: "image/jpeg", Status: models.MessageStatusSent, Timestamp: base.Add(3 * time.Minute)}
+		repo.Create(photo)
+		reactions.Upsert(&models.Reaction{TargetWhatsAppMessageID: "wamid.1", FromNumber: "14155550100", Direction: "inbound", Emoji: "👍", Timestamp: base})
+		reactions.Upsert(&models.Reaction{TargetWhatsAppMessageID: "wamid.4", FromNumber: "14155550100", Direction: "inbound", Emoji: "❤️", Timestamp: base})
+
+		aged, err := repo.FindAged(MessageAgeFilter{Before: base.Add(3 * time.Minute)}, 2)
+		if err != nil || len(aged) != 2 || aged[0].ID != seeded[0].ID || aged[1].ID != seeded[1].ID {
+			t.Errorf("FindAged should return the oldest messages of the organization first: %v %v", aged, err)
+		}
+		if count, err := repo.CountAged(MessageAgeFilter{Before: base.Add(time.Hour), Types: []string{"image"}}); err != nil || count != 2 {
+			t.Errorf("CountAged by type: %d %v", count, err)
+		}
+		if count, _ := repo.CountAged(MessageAgeFilter{Before: base.Add(time.Hour), ExcludeTypes: []string{"image"}}); count != 2 {
+			t.Errorf("CountAged excluding a type: %d", count)
+		}
+		media, err := repo.FindAged(MessageAgeFilter{Before: base.Add(time.Hour), WithMedia: true}, 10)
+		if err != nil || len(media) != 1 || media[0].ID != photo.ID {
+			t.Errorf("FindAged with media: %v %v", media, err)
+		}
+
+		removedAt := base.Add(24 * time.Hour)
+		if n, err := repo.RemoveMedia([]string{photo.ID}, removedAt); err != nil || n != 1 {
+			t.Fatalf("RemoveMedia: %d %v", n, err)
+		}
+		var found models.Message
+		repo.FindByID(photo.ID, &found)
+		if found.MediaURL != "" || found.MediaMimeType != "" || found.MediaRemovedAt == nil || !found.MediaRemovedAt.Equal(removedAt) {
+			t.Errorf("the media should be removed: %+v", found)
+		}
+		if count, _ := repo.CountAged(MessageAgeFilter{Before: base.Add(time.Hour), WithMedia: true}); count != 0 {
+			t.Errorf("messages without media should not be selected, got %d", count)
+		}
+
+		if n, err := repo.DeleteMessages([]string{seeded[0].ID, photo.ID}); err != nil || n != 2 {
+			t.Fatalf("DeleteMessages: %d %v", n, err)
+		}
+		if left, _ := reactions.FindByTargets([]string{"wamid.1", "wamid.4"}); len(left) != 0 {
+			t.Errorf("reactions to deleted messages should be deleted: %v", left)
+		}
+		if count, _ := repo.CountAged(MessageAgeFilter{Before: base.Add(time.Hour)}); count != 2 {
+			t.Errorf("expected 2 messages left, got %d", count)
+		}
+		if count, _ := other.CountAged(MessageAgeFilter{Before: base.Add(time.Hour)}); count != 1 {
+			t.Errorf("messages of other organizations should be kept, got %d", count)
+		}
+	})
+}
+
+// VACUUM and dump and restore may renumber the rowids of messages, whose
+// primary key is a string; search must keep finding the right messages
+func TestMessageRepositorySearchAfterRowidChange(t *testing.T) {
+	db := openSQLite(t)
+	repo := NewMessageRepository(db).ForOrganization("org_a")
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var created []*models.Message
+	for i, content := range []string{"alpha", "bravo", "charlie", "delta"} {
+		m := &models.Message{WhatsAppMessageID: fmt.Sprintf("wamid.%d", i), FromNumber: "14155550100", ToNumber: "15550000000",
+			Direction: "inbound", MessageType: "text", Status: models.MessageStatusDelivered, Content: content, Timestamp: at}
+		if err := repo.Create(m); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		created = append(created, m)
+	}
+	if _, err := repo.DeleteMessages([]string{created[0].ID, created[1].ID}); err != nil {
+		t.Fatalf("DeleteMessages: %v", err)
+	}
+	if err := db.Exec("UPDATE messages SET rowid = rowid - 2").Error; err != nil {
+		t.Fatalf("renumber: %v", err)
+	}
+
+	for i, query := range []string{"charlie", "delta"} {
+		results, err := repo.Search(query, nil, utils.NewPagination(10, 0))
+		if err != nil || len(results) != 1 || results[0].ID != created[i+2].ID {
+			t.Errorf("search for %s after renumbering found %v %v", query, resultIDs(results), err)
+		}
+	}
+	if results, _ := repo.Search("alpha", nil, utils.NewPagination(10, 0)); len(results) != 0 {
+		t.Errorf("deleted messages should not be found: %v", resultIDs(results))
+	}
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// OrganizationRepository handles organization data access
+type OrganizationRepository struct {
+	*BaseRepository
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *gorm.DB) *OrganizationRepository {
+	return &OrganizationRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *OrganizationRepository) WithContext(ctx context.Context) OrganizationStore {
+	return &OrganizationRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
+// FindByBusinessAccountID finds an organization by its WhatsApp Business Account (WABA) ID
+func (r *OrganizationRepository) FindByBusinessAccountID(wabaID string) (*models.Organization, error) {
+	var org models.Organization
+	err := r.DB.Where("business_account_id = ?", wabaID).First(&org).Error
+	return &org, err
+}
+
+// FindByPhoneNumberID finds an organization by its WhatsApp phone number ID
+func (r *OrganizationRepository) FindByPhoneNumberID(phoneNumberID string) (*models.Organization, error) {
+	var org models.Organization
+	err := r.DB.Where("phone_number_id = ?", phoneNumberID).First(&org).Error
+	return &org, err
+}
+
+// FindByWebhookVerifyToken finds an organization by its webhook verify token
+func (r *OrganizationRepository) FindByWebhookVerifyToken(token string) (*models.Organization, error) {
+	var org models.Organization
+	err := r.DB.Where("webhook_verify_token = ?", token).First(&org).Error
+	return &org, err
+}
+
+// FindUpdatedSince finds the organizations changed after since, e.g.
+// suspended
+func (r *OrganizationRepository) FindUpdatedSince(since time.Time) ([]*models.Organization, error) {
+	var orgs []*models.Organization
+	err := r.DB.Where("updated_at > ?", since).Find(&orgs).Error
+	return orgs, err
+}
+
+// ListAll lists all organizations with pagination
+func (r *OrganizationRepository) ListAll(pagination *utils.Pagination) ([]*models.Organization, error) {
+	var orgs []*models.Organization
+
+	query := r.DB.Model(&models.Organization{}).Order("created_at ASC")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	pagination.SetTotal(total)
+
+	err := pagination.ApplyToQuery(query).Find(&orgs).Error
+	return orgs, err
+}
+
+// AssignOrphans moves rows that have no organization yet to the given
+// organization. Used once when upgrading a single-tenant install.
+func (r *OrganizationRepository) AssignOrphans(orgID string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{
+			&models.Message{},
+			&models.Contact{},
+			&models.Template{},
+			&models.APIKey{},
+			&models.Call{},
+			&models.Transcript{},
+			&models.TranscriptSegment{},
+		} {
+			if err := tx.Model(model).
+				Where("organization_id = ? OR organization_id IS NULL", "").
+				UpdateColumn("organization_id", orgID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+func TestOrganizationRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewOrganizationRepository(db)
+
+		org := &models.Organization{
+			Name:               "Acme",
+			PhoneNumberID:      "100000000000002",
+			BusinessAccountID:  "100000000000001",
+			WebhookVerifyToken: "verify",
+			Metadata:           models.JSONMap{"plan": "pro"},
+		}
+		if err := repo.Create(org); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		repo.Create(&models.Organization{Name: "Globex", PhoneNumberID: "200", BusinessAccountID: "201"})
+
+		found, err := repo.FindByBusinessAccountID("100000000000001")
+		if err != nil || found.ID != org.ID || found.Metadata["plan"] != "pro" {
+			t.Errorf("FindByBusinessAccountID: %+v %v", found, err)
+		}
+		if found, err := repo.FindByPhoneNumberID("100000000000002"); err != nil || found.ID != org.ID {
+			t.Errorf("FindByPhoneNumberID: %+v %v", found, err)
+		}
+		if found, err := repo.FindByWebhookVerifyToken("verify"); err != nil || found.ID != org.ID {
+			t.Errorf("FindByWebhookVerifyToken: %+v %v", found, err)
+		}
+
+		pagination := utils.NewPagination(10, 0)
+		orgs, err := repo.ListAll(pagination)
+		if err != nil || len(orgs) != 2 || pagination.Total != 2 {
+			t.Errorf("ListAll: %d %v", len(orgs), err)
+		}
+
+		since := time.Now().UTC()
+		time.Sleep(10 * time.Millisecond)
+		if err := repo.UpdateFields(org.ID, &models.Organization{}, map[string]interface{}{"status": models.OrganizationStatusSuspended}); err != nil {
+			t.Fatalf("UpdateFields: %v", err)
+		}
+		var updated models.Organization
+		if err := repo.FindByID(org.ID, &updated); err != nil || updated.IsActive() {
+			t.Errorf("UpdateFields did not apply: %+v %v", updated, err)
+		}
+		changed, err := repo.FindUpdatedSince(since)
+		if err != nil || len(changed) != 1 || changed[0].ID != org.ID {
+			t.Errorf("FindUpdatedSince should find only the suspended organization: %d %v", len(changed), err)
+		}
+
+		if err := repo.Delete(&updated); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := repo.FindByID(org.ID, &models.Organization{}); err == nil {
+			t.Error("deleted organization is still found")
+		}
+	})
+}
+
+func TestAssignOrphans(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		messages := NewMessageRepository(db)
+		contacts := NewContactRepository(db)
+
+		// Rows written before organizations existed have no organization
+		messages.Create(&models.Message{FromNumber: "1", ToNumber: "2", Direction: "inbound", MessageType: "text", Status: "received"})
+		contacts.Create(&models.Contact{PhoneNumber: "1"})
+		db.Create(&models.Call{ID: "call_1", FromNumber: "1"})
+		NewMessageRepository(db).ForOrganization("org_b").Create(&models.Message{FromNumber: "3", ToNumber: "4", Direction: "inbound", MessageType: "text", Status: "received"})
+
+		if err := NewOrganizationRepository(db).AssignOrphans("org_a"); err != nil {
+			t.Fatalf("AssignOrphans: %v", err)
+		}
+
+		var count int64
+		db.Model(&models.Message{}).Where("organization_id = ?", "org_a").Count(&count)
+		if count != 1 {
+			t.Errorf("expected 1 adopted message, got %d", count)
+		}
+		db.Model(&models.Message{}).Where("organization_id = ?", "org_b").Count(&count)
+		if count != 1 {
+			t.Errorf("messages of other organizations must stay, got %d", count)
+		}
+		if _, err := contacts.ForOrganization("org_a").FindByPhone("1"); err != nil {
+			t.Errorf("contact was not adopted: %v", err)
+		}
+		if calls, _ := NewCallRepository(db).ForOrganization("org_a").FindByPhone("1"); len(calls) != 1 {
+			t.Errorf("call was not adopted: %v", calls)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// rateLimitSweepInterval is how often expired counters are deleted
+const rateLimitSweepInterval = time.Minute
+
+// RateLimitRepository stores rate limit counters in the database so limits
+// hold across replicas. It implements ratelimit.Store.
+type RateLimitRepository struct {
+	*BaseRepository
+
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// NewRateLimitRepository creates a new rate limit repository
+func NewRateLimitRepository(db *gorm.DB) *RateLimitRepository {
+	return &RateLimitRepository{
+		BaseRepository: NewBaseRepository(db),
+		lastSweep:      time.Now(),
+	}
+}
+
+// Increment adds one to a counter and returns the new value
+func (r *RateLimitRepository) Increment(ctx context.Context, bucket string, expiresAt time.Time) (int64, error) {
+	r.sweepExpired(ctx)
+
+	counter := models.RateLimitCounter{Bucket: bucket}
+	err := r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "bucket"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"count": gorm.Expr("rate_limit_counters.count + 1"),
+			}),
+		}).Create(&models.RateLimitCounter{Bucket: bucket, Count: 1, ExpiresAt: expiresAt}).Error; err != nil {
+			return err
+		}
+		return tx.First(&counter, "bucket = ?", bucket).Error
+	})
+	return counter.Count, err
+}
+
+// Decrement takes one off a counter, if it is above zero
+func (r *RateLimitRepository) Decrement(ctx context.Context, bucket string) error {
+	return r.DB.WithContext(ctx).Model(&models.RateLimitCounter{}).
+		Where("bucket = ? AND count > 0", bucket).
+		UpdateColumn("count", gorm.Expr("count - 1")).Error
+}
+
+// DeleteExpired deletes counters whose window has ended
+func (r *RateLimitRepository) DeleteExpired(ctx context.Context) error {
+	return r.DB.WithContext(ctx).
+		Where("expires_at < ?", time.Now().UTC()).
+		Delete(&models.RateLimitCounter{}).Error
+}
+
+// sweepExpired deletes expired counters at most once per sweep interval
+func (r *RateLimitRepository) sweepExpired(ctx context.Context) {
+	r.mu.Lock()
+	due := time.Since(r.lastSweep) >= rateLimitSweepInterval
+	if due {
+		r.lastSweep = time.Now()
+	}
+	r.mu.Unlock()
+
+	if due {
+		// Best effort: a failed sweep is retried on the next interval
+		_ = r.DeleteExpired(ctx)
+	}
+}
@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestRateLimitRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewRateLimitRepository(db)
+		ctx := context.Background()
+		expiresAt := time.Now().UTC().Add(time.Minute)
+
+		for want := int64(1); want <= 3; want++ {
+			count, err := repo.Increment(ctx, "key_1:minute", expiresAt)
+			if err != nil {
+				t.Fatalf("Increment: %v", err)
+			}
+			if count != want {
+				t.Errorf("Increment = %d, want %d", count, want)
+			}
+		}
+		if count, _ := repo.Increment(ctx, "key_2:minute", expiresAt); count != 1 {
+			t.Errorf("buckets must be counted separately, got %d", count)
+		}
+
+		if err := repo.Decrement(ctx, "key_1:minute"); err != nil {
+			t.Fatalf("Decrement: %v", err)
+		}
+		if count, _ := repo.Increment(ctx, "key_1:minute", expiresAt); count != 3 {
+			t.Errorf("Increment after Decrement = %d, want 3", count)
+		}
+		repo.Decrement(ctx, "key_2:minute")
+		repo.Decrement(ctx, "key_2:minute")
+		if count, _ := repo.Increment(ctx, "key_2:minute", expiresAt); count != 1 {
+			t.Errorf("counters must not go below zero, got %d", count)
+		}
+
+		repo.Increment(ctx, "key_1:old", time.Now().UTC().Add(-time.Minute))
+		if err := repo.DeleteExpired(ctx); err != nil {
+			t.Fatalf("DeleteExpired: %v", err)
+		}
+		var remaining int64
+		db.Model(&models.RateLimitCounter{}).Count(&remaining)
+		if remaining != 2 {
+			t.Errorf("expected 2 live counters, got %d", remaining)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReactionRepository handles reaction data access
+type ReactionRepository struct {
+	*BaseRepository
+}
+
+// NewReactionRepository creates a new reaction repository
+func NewReactionRepository(db *gorm.DB) *ReactionRepository {
+	return &ReactionRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// ForOrganization returns a repository scoped to the given organization
+func (r *ReactionRepository) ForOrganization(orgID string) ReactionStore {
+	return &ReactionRepository{BaseRepository: r.BaseRepository.ForOrganization(orgID)}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *ReactionRepository) WithContext(ctx context.Context) ReactionStore {
+	return &ReactionRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
+// Upsert stores a reaction, replacing the sender's earlier reaction in the
+// same direction to the same message
+func (r *ReactionRepository) Upsert(reaction *models.Reaction) error {
+	if r.OrganizationID != "" {
+		reaction.OrganizationID = r.OrganizationID
+	}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organization_id"}, {Name: "target_whatsapp_message_id"}, {Name: "from_number"}, {Name: "direction"}},
+		DoUpdates: clause.AssignmentColumns([]string{"message_id", "whatsapp_message_id", "emoji", "timestamp", "updated_at"}),
+	}).Create(reaction).Error
+}
+
+// Remove deletes the sender's reaction in a direction to a message
+func (r *ReactionRepository) Remove(targetWhatsAppMessageID, fromNumber, direction string) error {
+	return r.DB.Where("target_whatsapp_message_id = ? AND from_number = ? AND direction = ?", targetWhatsAppMessageID, fromNumber, direction).
+		Delete(&models.Reaction{}).Error
+}
+
+// FindByTargets returns the reactions to the messages with the given
+// WhatsApp message IDs, oldest first
+func (r *ReactionRepository) FindByTargets(targetWhatsAppMessageIDs []string) ([]*models.Reaction, error) {
+	var reactions []*models.Reaction
+	if len(targetWhatsAppMessageIDs) == 0 {
+		return reactions, nil
+	}
+	err := r.DB.Where("target_whatsapp_message_id IN ?", targetWhatsAppMessageIDs).
+		Order("timestamp ASC").
+		Find(&reactions).Error
+	return reactions, err
+}
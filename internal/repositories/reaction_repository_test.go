@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestReactionRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewReactionRepository(db).ForOrganization("org_a")
+		other := NewReactionRepository(db).ForOrganization("org_b")
+
+		at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		for _, r := range []*models.Reaction{
+			{TargetWhatsAppMessageID: "wamid.1", FromNumber: "14155550100", Direction: "inbound", Emoji: "👍", Timestamp: at},
+			{TargetWhatsAppMessageID: "wamid.1", FromNumber: "15550000000", Direction: "outbound", Emoji: "🙏", Timestamp: at.Add(time.Minute)},
+			// Reacting again replaces the sender's reaction
+			{TargetWhatsAppMessageID: "wamid.1", FromNumber: "14155550100", Direction: "inbound", Emoji: "❤️", Timestamp: at.Add(2 * time.Minute)},
+			{TargetWhatsAppMessageID: "wamid.2", FromNumber: "14155550100", Direction: "inbound", Emoji: "😂", Timestamp: at},
+			// Our reaction is kept apart from the customer's
+			{TargetWhatsAppMessageID: "wamid.2", FromNumber: "14155550100", Direction: "outbound", Emoji: "✅", Timestamp: at.Add(time.Minute)},
+		} {
+			if err := repo.Upsert(r); err != nil {
+				t.Fatalf("Upsert: %v", err)
+			}
+		}
+		if err := other.Upsert(&models.Reaction{TargetWhatsAppMessageID: "wamid.1", FromNumber: "14155550100", Direction: "inbound", Emoji: "👀"}); err != nil {
+			t.Fatalf("Upsert in another organization: %v", err)
+		}
+
+		reactions, err := repo.FindByTargets([]string{"wamid.1"})
+		if err != nil || len(reactions) != 2 {
+			t.Fatalf("FindByTargets: %+v %v", reactions, err)
+		}
+		if reactions[0].Emoji != "🙏" || reactions[1].Emoji != "❤️" || reactions[1].OrganizationID != "org_a" {
+			t.Errorf("expected the replaced reaction last: %s %s", reactions[0].Emoji, reactions[1].Emoji)
+		}
+
+		if err := repo.Remove("wamid.1", "14155550100", "inbound"); err != nil {
+			t.Fatalf("Remove: %v", err)
+		}
+		reactions, _ = repo.FindByTargets([]string{"wamid.1", "wamid.2"})
+		if len(reactions) != 3 {
+			t.Errorf("Remove should only delete the sender's reaction: %+v", reactions)
+		}
+		reactions, _ = other.FindByTargets([]string{"wamid.1"})
+		if len(reactions) != 1 || reactions[0].Emoji != "👀" {
+			t.Errorf("reactions leaked across organizations: %+v", reactions)
+		}
+
+		if reactions, err := repo.FindByTargets(nil); err != nil || len(reactions) != 0 {
+			t.Errorf("FindByTargets(nil): %+v %v", reactions, err)
+		}
+	})
+}
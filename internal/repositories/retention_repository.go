@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+// RetentionPolicyRepository handles message retention policies
+type RetentionPolicyRepository struct {
+	*BaseRepository
+}
+
+// NewRetentionPolicyRepository creates a new retention policy repository
+func NewRetentionPolicyRepository(db *gorm.DB) *RetentionPolicyRepository {
+	return &RetentionPolicyRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// ForOrganization returns a repository scoped to the given organization
+func (r *RetentionPolicyRepository) ForOrganization(orgID string) RetentionPolicyStore {
+	return &RetentionPolicyRepository{BaseRepository: r.BaseRepository.ForOrganization(orgID)}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *RetentionPolicyRepository) WithContext(ctx context.Context) RetentionPolicyStore {
+	return &RetentionPolicyRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
+// ListAll lists the retention policies by organization and message type.
+// Unscoped, it lists the policies of every organization.
+func (r *RetentionPolicyRepository) ListAll() ([]*models.RetentionPolicy, error) {
+	var policies []*models.RetentionPolicy
+	err := r.DB.Model(&models.RetentionPolicy{}).
+		Order("organization_id ASC").Order("message_type ASC").
+		Find(&policies).Error
+	return policies, err
+}
+
+// FindByType finds the retention policy for a message type
+func (r *RetentionPolicyRepository) FindByType(messageType string) (*models.RetentionPolicy, error) {
+	var policy models.RetentionPolicy
+	err := r.DB.Where("message_type = ?", messageType).First(&policy).Error
+	return &policy, err
+}
+
+// Upsert stores the policy, replacing the organization's policy for the
+// same message type
+func (r *RetentionPolicyRepository) Upsert(policy *models.RetentionPolicy) error {
+	if r.OrganizationID != "" {
+		policy.OrganizationID = r.OrganizationID
+	}
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.RetentionPolicy
+		err := tx.Where("message_type = ?", policy.MessageType).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(policy).Error
+		}
+		if err != nil {
+			return err
+		}
+		policy.ID, policy.CreatedAt = existing.ID, existing.CreatedAt
+		return tx.Model(&existing).Updates(map[string]interface{}{
+			"action":            policy.Action,
+			"retain_days":       policy.RetainDays,
+			"media_retain_days": policy.MediaRetainDays,
+		}).Error
+	})
+}
+
+// DeleteByType deletes the retention policy for a message type. It returns
+// gorm.ErrRecordNotFound when there is none.
+func (r *RetentionPolicyRepository) DeleteByType(messageType string) error {
+	result := r.DB.Where("message_type = ?", messageType).Delete(&models.RetentionPolicy{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestRetentionPolicyRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		root := NewRetentionPolicyRepository(db)
+		repo := root.ForOrganization("org_a")
+		other := root.ForOrganization("org_b")
+
+		image := &models.RetentionPolicy{MessageType: "image", Action: models.RetentionActionDelete, RetainDays: 90, MediaRetainDays: 30}
+		if err := repo.Upsert(image); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+		if image.ID == "" || image.OrganizationID != "org_a" {
+			t.Errorf("the policy should be created in the organization: %+v", image)
+		}
+		repo.Upsert(&models.RetentionPolicy{MessageType: models.RetentionDefaultType, Action: models.RetentionActionArchive, RetainDays: 365})
+		other.Upsert(&models.RetentionPolicy{MessageType: "image", Action: models.RetentionActionArchive, RetainDays: 10})
+
+		replaced := &models.RetentionPolicy{MessageType: "image", Action: models.RetentionActionArchive, RetainDays: 60}
+		if err := repo.Upsert(replaced); err != nil {
+			t.Fatalf("Upsert of an existing type: %v", err)
+		}
+		if replaced.ID != image.ID {
+			t.Errorf("the existing policy should be replaced, got ID %s", replaced.ID)
+		}
+		found, err := repo.FindByType("image")
+		if err != nil || found.Action != models.RetentionActionArchive || found.RetainDays != 60 || found.MediaRetainDays != 0 {
+			t.Errorf("FindByType: %+v %v", found, err)
+		}
+
+		policies, err := repo.ListAll()
+		if err != nil || len(policies) != 2 || policies[0].MessageType != models.RetentionDefaultType {
+			t.Errorf("ListAll should list the organization's policies by type: %v %v", policies, err)
+		}
+		if all, _ := root.ListAll(); len(all) != 3 {
+			t.Errorf("unscoped, ListAll should list every organization's policies, got %d", len(all))
+		}
+
+		if err := repo.DeleteByType("image"); err != nil {
+			t.Fatalf("DeleteByType: %v", err)
+		}
+		if err := repo.DeleteByType("image"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("deleting a missing policy should not be found, got %v", err)
+		}
+		if _, err := other.FindByType("image"); err != nil {
+			t.Errorf("policies of other organizations should be kept: %v", err)
+		}
+	})
+}
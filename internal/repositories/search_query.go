@@ -0,0 +1,276 @@
+package repositories
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Message search queries use web search syntax:
+//
+//	refund order          both words
+//	"late delivery"       the phrase
+//	invoice OR receipt    either word
+//	-cancelled            without the word
+//	invo*                 words starting with "invo"
+//
+// AND binds tighter than OR, like in Postgres' websearch_to_tsquery. The
+// parsed query is rendered for each search backend.
+
+// searchQuery is a parsed search query: any group must match, and a group
+// matches when all its clauses do
+type searchQuery struct {
+	groups [][]searchClause
+}
+
+// searchClause is a word, or a phrase when it has several words
+type searchClause struct {
+	words  []string
+	prefix bool
+	negate bool
+}
+
+// parseSearchQuery parses a search query. Characters other than letters and
+// digits separate words, so "e-mail" is the phrase "e mail".
+func parseSearchQuery(input string) searchQuery {
+	var q searchQuery
+	var group []searchClause
+
+	for _, token := range tokenizeSearch(input) {
+		if token == "OR" {
+			if len(group) > 0 {
+				q.groups = append(q.groups, group)
+				group = nil
+			}
+			continue
+		}
+
+		var clause searchClause
+		if strings.HasPrefix(token, "-") {
+			clause.negate = true
+			token = token[1:]
+		}
+		if strings.HasSuffix(token, "*") {
+			clause.prefix = true
+			token = strings.TrimRight(token, "*")
+		}
+		clause.words = searchWords(strings.Trim(token, `"`))
+		if len(clause.words) > 0 {
+			group = append(group, clause)
+		}
+	}
+	if len(group) > 0 {
+		q.groups = append(q.groups, group)
+	}
+	return q
+}
+
+// tokenizeSearch splits a query on spaces outside of quotes
+func tokenizeSearch(input string) []string {
+	var tokens []string
+	var current strings.Builder
+	quoted := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range input {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// searchWords splits text into lower-case words of letters and digits
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// empty reports whether the query can match anything. Groups that only
+// exclude words are dropped by every backend.
+func (q searchQuery) empty() bool {
+	for _, group := range q.groups {
+		if positives(group) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (q searchQuery) hasPrefix() bool {
+	for _, group := range q.groups {
+		for _, clause := range group {
+			if clause.prefix {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func positives(group []searchClause) int {
+	n := 0
+	for _, clause := range group {
+		if !clause.negate {
+			n++
+		}
+	}
+	return n
+}
+
+// websearch renders the query for websearch_to_tsquery, which has no prefix
+// syntax
+func (q searchQuery) websearch() string {
+	groups := make([]string, 0, len(q.groups))
+	for _, group := range q.groups {
+		clauses := make([]string, 0, len(group))
+		for _, clause := range group {
+			text := strings.Join(clause.words, " ")
+			if len(clause.words) > 1 {
+				text = `"` + text + `"`
+			}
+			if clause.negate {
+				text = "-" + text
+			}
+			clauses = append(clauses, text)
+		}
+		groups = append(groups, strings.Join(clauses, " "))
+	}
+	return strings.Join(groups, " OR ")
+}
+
+// tsquery renders the query in to_tsquery syntax, used when it has prefix
+// clauses
+func (q searchQuery) tsquery() string {
+	groups := make([]string, 0, len(q.groups))
+	for _, group := range q.groups {
+		clauses := make([]string, 0, len(group))
+		for _, clause := range group {
+			text := strings.Join(clause.words, " <-> ")
+			if clause.prefix {
+				text += ":*"
+			}
+			if len(clause.words) > 1 {
+				text = "(" + text + ")"
+			}
+			if clause.negate {
+				text = "!" + text
+			}
+			clauses = append(clauses, text)
+		}
+		groups = append(groups, "("+strings.Join(clauses, " & ")+")")
+	}
+	return strings.Join(groups, " | ")
+}
+
+// fts5 renders the query as an SQLite FTS5 query. FTS5's NOT is binary, so
+// excluded clauses follow the group's other clauses.
+func (q searchQuery) fts5() string {
+	var groups []string
+	for _, group := range q.groups {
+		if positives(group) == 0 {
+			continue
+		}
+		var include, exclude []string
+		for _, clause := range group {
+			text := `"` + strings.Join(clause.words, " ") + `"`
+			if clause.prefix {
+				text += "*"
+			}
+			if clause.negate {
+				exclude = append(exclude, text)
+			} else {
+				include = append(include, text)
+			}
+		}
+		rendered := strings.Join(include, " AND ")
+		for _, text := range exclude {
+			rendered += " NOT " + text
+		}
+		groups = append(groups, "("+rendered+")")
+	}
+	return strings.Join(groups, " OR ")
+}
+
+// terms returns the words and phrases the query looks for, for highlighting
+func (q searchQuery) terms() []string {
+	var terms []string
+	for _, group := range q.groups {
+		for _, clause := range group {
+			if !clause.negate {
+				terms = append(terms, strings.Join(clause.words, " "))
+			}
+		}
+	}
+	return terms
+}
+
+// Snippets built outside the database use the same markers and length as
+// the full-text backends
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+	snippetRadius  = 60
+)
+
+// highlight returns an excerpt of text around the first matching term, with
+// every match wrapped in highlight markers. Matching ignores case.
+func highlight(text string, terms []string) string {
+	if text == "" || len(terms) == 0 {
+		return ""
+	}
+
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		// Phrase words may be separated by any punctuation in the text
+		words := strings.Fields(term)
+		for j, word := range words {
+			words[j] = regexp.QuoteMeta(word)
+		}
+		quoted[i] = strings.Join(words, `[^\p{L}\p{N}]+`)
+	}
+	pattern := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+
+	first := pattern.FindStringIndex(text)
+	if first == nil {
+		return ""
+	}
+
+	start, end := first[0]-snippetRadius, first[1]+snippetRadius
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(text) {
+		end, suffix = len(text), ""
+	}
+	// Do not cut through a multi-byte character
+	for start > 0 && !isRuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !isRuneStart(text[end]) {
+		end++
+	}
+
+	excerpt := pattern.ReplaceAllStringFunc(text[start:end], func(match string) string {
+		return highlightStart + match + highlightEnd
+	})
+	return prefix + excerpt + suffix
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import "testing"
+
+func TestSearchQueryRendering(t *testing.T) {
+	cases := []struct {
+		input     string
+		websearch string
+		tsquery   string
+		fts5      string
+	}{
+		{
+			input:     "refund order",
+			websearch: "refund order",
+			tsquery:   "(refund & order)",
+			fts5:      `("refund" AND "order")`,
+		},
+		{
+			input:     `"late delivery" -cancelled`,
+			websearch: `"late delivery" -cancelled`,
+			tsquery:   "((late <-> delivery) & !cancelled)",
+			fts5:      `("late delivery" NOT "cancelled")`,
+		},
+		{
+			input:     "invoice OR invo* receipt",
+			websearch: "invoice OR invo receipt",
+			tsquery:   "(invoice) | (invo:* & receipt)",
+			fts5:      `("invoice") OR ("invo"* AND "receipt")`,
+		},
+		{
+			// Punctuation separates words; FTS5 operators are quoted away
+			input:     `e-mail NEAR(x) "`,
+			websearch: `"e mail" "near x"`,
+			tsquery:   "((e <-> mail) & (near <-> x))",
+			fts5:      `("e mail" AND "near x")`,
+		},
+	}
+
+	for _, tc := range cases {
+		q := parseSearchQuery(tc.input)
+		if got := q.websearch(); got != tc.websearch {
+			t.Errorf("%q websearch = %q, want %q", tc.input, got, tc.websearch)
+		}
+		if got := q.tsquery(); got != tc.tsquery {
+			t.Errorf("%q tsquery = %q, want %q", tc.input, got, tc.tsquery)
+		}
+		if got := q.fts5(); got != tc.fts5 {
+			t.Errorf("%q fts5 = %q, want %q", tc.input, got, tc.fts5)
+		}
+	}
+
+	for _, input := range []string{"", "  ", "OR", "-late", `""`, "***"} {
+		if !parseSearchQuery(input).empty() {
+			t.Errorf("%q should be an empty query", input)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	cases := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"Your order is late", []string{"order"}, "Your <mark>order</mark> is late"},
+		{"ORDER and order", []string{"order"}, "<mark>ORDER</mark> and <mark>order</mark>"},
+		{"running-late again", []string{"running late"}, "<mark>running-late</mark> again"},
+		{"nothing here", []string{"order"}, ""},
+		{
+			"This sentence is long enough that the snippet has to start somewhere in the middle of it, around the word refund and stop before the end of the text",
+			[]string{"refund"},
+			"…has to start somewhere in the middle of it, around the word <mark>refund</mark> and stop before the end of the text",
+		},
+	}
+	for _, tc := range cases {
+		if got := highlight(tc.text, tc.terms); got != tc.want {
+			t.Errorf("highlight(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
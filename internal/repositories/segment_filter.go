@@ -0,0 +1,551 @@
+package repositories
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+)
+
+// Segment filters select contacts with conditions joined by AND, OR, NOT
+// and parentheses:
+//
+//	tag = vip AND NOT tag = churned
+//	consent = opted_in AND last_message_at > -30d
+//	attributes.plan = "pro" OR message_count >= 10
+//	name ~ ada
+//
+// Fields are tag, consent (opted_in, opted_out or unknown), name,
+// phone_number, message_count, unread_count, last_message_at, created_at and
+// attributes.<key>, a key of the contact metadata. Declared attributes
+// compare as their type; on other keys unquoted numbers and booleans compare
+// as such and anything else as text. Times are dates, RFC 3339 timestamps
+// or durations before now such as -12h, -30d or -2w. ~ matches text
+// containing the value, ignoring case. != also matches contacts without a
+// value. AND binds tighter than OR, and adjacent conditions without an
+// operator are ANDed like in message search.
+
+const (
+	// maxSegmentFilterLength and maxSegmentConditions bound the SQL a
+	// filter renders to
+	maxSegmentFilterLength = 2000
+	maxSegmentConditions   = 50
+)
+
+// ValidateSegmentFilter checks that a segment filter parses, and that its
+// conditions on declared attributes suit their types
+func ValidateSegmentFilter(filter string, attributes []*models.AttributeDefinition) error {
+	_, err := parseSegmentFilter(filter, attributes)
+	return err
+}
+
+// segmentNode is a node of a parsed segment filter
+type segmentNode interface {
+	// render returns the SQL condition on the contacts table
+	render(dialect Dialect, now time.Time) (string, []interface{})
+}
+
+type segmentAnd struct{ left, right segmentNode }
+
+type segmentOr struct{ left, right segmentNode }
+
+type segmentNot struct{ node segmentNode }
+
+func (n segmentAnd) render(dialect Dialect, now time.Time) (string, []interface{}) {
+	left, leftArgs := n.left.render(dialect, now)
+	right, rightArgs := n.right.render(dialect, now)
+	return "(" + left + " AND " + right + ")", append(leftArgs, rightArgs...)
+}
+
+func (n segmentOr) render(dialect Dialect, now time.Time) (string, []interface{}) {
+	left, leftArgs := n.left.render(dialect, now)
+	right, rightArgs := n.right.render(dialect, now)
+	return "(" + left + " OR " + right + ")", append(leftArgs, rightArgs...)
+}
+
+// NOT of a condition that is NULL must hold, so it is coalesced to false
+func (n segmentNot) render(dialect Dialect, now time.Time) (string, []interface{}) {
+	sql, args := n.node.render(dialect, now)
+	return "NOT COALESCE(" + sql + ", FALSE)", args
+}
+
+// segmentValueKind is the type of a filter value
+type segmentValueKind int
+
+const (
+	segmentText segmentValueKind = iota
+	segmentNumber
+	segmentBool
+	segmentTime
+)
+
+// segmentCondition compares a field with a value
+type segmentCondition struct {
+	field string
+	// key is the metadata key of attributes fields
+	key   string
+	op    string
+	kind  segmentValueKind
+	text  string
+	num   float64
+	bool  bool
+	at    time.Time
+	since time.Duration
+}
+
+// segmentFields maps the plain fields to their columns and value kinds
+var segmentFields = map[string]struct {
+	column string
+	kind   segmentValueKind
+}{
+	"name":            {"contacts.name", segmentText},
+	"phone_number":    {"contacts.phone_number", segmentText},
+	"consent":         {"contacts.consent", segmentText},
+	"message_count":   {"contacts.message_count", segmentNumber},
+	"unread_count":    {"contacts.unread_count", segmentNumber},
+	"last_message_at": {"contacts.last_message_at", segmentTime},
+	"created_at":      {"contacts.created_at", segmentTime},
+}
+
+var (
+	attributeKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	relativeTimePattern = regexp.MustCompile(`^-(\d+)([mhdw])$`)
+)
+
+func (c segmentCondition) render(dialect Dialect, now time.Time) (string, []interface{}) {
+	var value interface{}
+	switch c.kind {
+	case segmentNumber:
+		value = c.num
+		if c.field != "attributes" {
+			// Counts are integer columns
+			value = int64(c.num)
+		}
+	case segmentBool:
+		value = c.bool
+	case segmentTime:
+		at := c.at
+		if c.since > 0 {
+			at = now.Add(-c.since)
+		}
+		value = at
+		if c.field == "attributes" {
+			// Date attributes are stored as text
+			value = at.UTC().Format(models.AttributeDateLayout)
+		}
+	default:
+		value = c.text
+	}
+
+	var column string
+	switch {
+	case c.field == "tag":
+		sql := "EXISTS (SELECT 1 FROM contact_tags WHERE contact_tags.contact_id = contacts.id AND contact_tags.tag = ?)"
+		if c.op == "!=" {
+			sql = "NOT " + sql
+		}
+		return sql, []interface{}{value}
+	case c.field == "consent" && c.text == "":
+		// Contacts that never gave or withdrew consent
+		if c.op == "!=" {
+			return "COALESCE(contacts.consent, '') <> ''", nil
+		}
+		return "COALESCE(contacts.consent, '') = ''", nil
+	case c.field == "attributes":
+		column = dialect.JSONValue("contacts.metadata", c.key, jsonKindOf(c.kind))
+	default:
+		column = segmentFields[c.field].column
+	}
+
+	switch c.op {
+	case "~":
+		return dialect.ContainsFold(column), []interface{}{LikePattern(c.text)}
+	case "!=":
+		return "(" + column + " IS NULL OR " + column + " <> ?)", []interface{}{value}
+	default:
+		return column + " " + c.op + " ?", []interface{}{value}
+	}
+}
+
+func jsonKindOf(kind segmentValueKind) jsonKind {
+	switch kind {
+	case segmentNumber:
+		return jsonNumber
+	case segmentBool:
+		return jsonBool
+	default:
+		return jsonString
+	}
+}
+
+// parseSegmentFilter parses a segment filter. attributes are the declared
+// attributes of the organization.
+func parseSegmentFilter(input string, attributes []*models.AttributeDefinition) (segmentNode, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, fmt.Errorf("filter is empty")
+	}
+	if len(input) > maxSegmentFilterLength {
+		return nil, fmt.Errorf("filter is longer than %d characters", maxSegmentFilterLength)
+	}
+	tokens, err := tokenizeSegmentFilter(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &segmentParser{tokens: tokens, attributes: make(map[string]*models.AttributeDefinition, len(attributes))}
+	for _, attribute := range attributes {
+		p.attributes[attribute.Name] = attribute
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	return node, nil
+}
+
+// segmentToken is a word, quoted string, operator or parenthesis
+type segmentToken struct {
+	text   string
+	quoted bool
+}
+
+func (t segmentToken) keyword(word string) bool {
+	return !t.quoted && strings.EqualFold(t.text, word)
+}
+
+func (t segmentToken) operator() bool {
+	if t.quoted {
+		return false
+	}
+	switch t.text {
+	case "=", "!=", "<", "<=", ">", ">=", "~":
+		return true
+	}
+	return false
+}
+
+// tokenizeSegmentFilter splits a filter into tokens
+func tokenizeSegmentFilter(input string) ([]segmentToken, error) {
+	var tokens []segmentToken
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == '=' || r == '~':
+			tokens = append(tokens, segmentToken{text: string(r)})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, segmentToken{text: string(runes[i : i+2])})
+				i += 2
+			} else if r == '!' {
+				return nil, fmt.Errorf("unexpected %q", "!")
+			} else {
+				tokens = append(tokens, segmentToken{text: string(r)})
+				i++
+			}
+		case r == '"':
+			var text strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("unterminated quoted value")
+			}
+			tokens = append(tokens, segmentToken{text: text.String(), quoted: true})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()=~!<>"`, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, segmentToken{text: string(runes[start:i])})
+		}
+	}
+	return tokens, nil
+}
+
+// segmentParser is a recursive descent parser over the tokens of a filter
+type segmentParser struct {
+	tokens     []segmentToken
+	pos        int
+	conditions int
+	attributes map[string]*models.AttributeDefinition
+}
+
+func (p *segmentParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *segmentParser) peek() segmentToken {
+	if p.done() {
+		return segmentToken{}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *segmentParser) next() (segmentToken, error) {
+	if p.done() {
+		return segmentToken{}, fmt.Errorf("unexpected end of filter")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+// parseOr parses conditions joined by OR
+func (p *segmentParser) parseOr() (segmentNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = segmentOr{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses conditions joined by AND or by nothing
+func (p *segmentParser) parseAnd() (segmentNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for !p.done() && !p.peek().keyword("OR") && !(p.peek().text == ")" && !p.peek().quoted) {
+		if p.peek().keyword("AND") {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = segmentAnd{left, right}
+	}
+	return left, nil
+}
+
+// parseUnary parses a negation, a parenthesized filter or a condition
+func (p *segmentParser) parseUnary() (segmentNode, error) {
+	token, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case token.keyword("NOT"):
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return segmentNot{node}, nil
+	case token.text == "(" && !token.quoted:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, err := p.next(); err != nil || closing.text != ")" || closing.quoted {
+			return nil, fmt.Errorf("missing )")
+		}
+		return node, nil
+	default:
+		p.pos--
+		return p.parseCondition()
+	}
+}
+
+// parseCondition parses a field, an operator and a value
+func (p *segmentParser) parseCondition() (segmentNode, error) {
+	p.conditions++
+	if p.conditions > maxSegmentConditions {
+		return nil, fmt.Errorf("filter has more than %d conditions", maxSegmentConditions)
+	}
+
+	field, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if field.quoted || field.operator() || field.text == "(" || field.text == ")" ||
+		field.keyword("AND") || field.keyword("OR") || field.keyword("NOT") {
+		return nil, fmt.Errorf("expected a field, got %q", field.text)
+	}
+	op, err := p.next()
+	if err != nil {
+		return nil, fmt.Errorf("%s: expected an operator", field.text)
+	}
+	if !op.operator() {
+		return nil, fmt.Errorf("%s: expected an operator, got %q", field.text, op.text)
+	}
+	value, err := p.next()
+	if err != nil || (!value.quoted && (value.operator() || value.text == "(" || value.text == ")")) {
+		return nil, fmt.Errorf("%s: expected a value", field.text)
+	}
+
+	return newSegmentCondition(field.text, op.text, value, p.attributes)
+}
+
+// newSegmentCondition checks that the operator and value suit the field
+func newSegmentCondition(field, op string, value segmentToken, attributes map[string]*models.AttributeDefinition) (segmentCondition, error) {
+	c := segmentCondition{field: field, op: op, text: value.text}
+	equality := op == "=" || op == "!="
+
+	switch {
+	case field == "tag":
+		if !equality {
+			return c, fmt.Errorf("tag only supports = and !=")
+		}
+		c.text = models.NormalizeTag(value.text)
+		return c, nil
+
+	case field == "consent":
+		if !equality {
+			return c, fmt.Errorf("consent only supports = and !=")
+		}
+		switch value.text {
+		case "opted_in", "opted_out":
+		case "unknown":
+			c.text = ""
+		default:
+			return c, fmt.Errorf("consent must be opted_in, opted_out or unknown")
+		}
+		return c, nil
+
+	case strings.HasPrefix(field, "attributes."):
+		c.field, c.key = "attributes", strings.TrimPrefix(field, "attributes.")
+		if !attributeKeyPattern.MatchString(c.key) {
+			return c, fmt.Errorf("%s: attribute keys may only contain letters, digits and underscores", field)
+		}
+		if attribute, ok := attributes[c.key]; ok {
+			return c, c.parseAttribute(attribute, value.text)
+		}
+		// Unquoted numbers and booleans compare as such, anything else
+		// as text
+		if !value.quoted {
+			if n, err := strconv.ParseFloat(value.text, 64); err == nil {
+				c.kind, c.num = segmentNumber, n
+			} else if value.text == "true" || value.text == "false" {
+				c.kind, c.bool = segmentBool, value.text == "true"
+			}
+		}
+		switch {
+		case op == "~" && c.kind != segmentText:
+			return c, fmt.Errorf("%s: ~ needs a text value", field)
+		case !equality && op != "~" && c.kind != segmentNumber:
+			return c, fmt.Errorf("%s: %s needs a number", field, op)
+		}
+		return c, nil
+	}
+
+	spec, ok := segmentFields[field]
+	if !ok {
+		return c, fmt.Errorf("unknown field %q", field)
+	}
+	c.kind = spec.kind
+	switch spec.kind {
+	case segmentText:
+		if op != "~" && !equality {
+			return c, fmt.Errorf("%s only supports =, != and ~", field)
+		}
+	case segmentNumber:
+		if op == "~" {
+			return c, fmt.Errorf("%s does not support ~", field)
+		}
+		n, err := strconv.ParseInt(value.text, 10, 64)
+		if err != nil {
+			return c, fmt.Errorf("%s: %q is not a whole number", field, value.text)
+		}
+		c.num = float64(n)
+	case segmentTime:
+		if op == "~" {
+			return c, fmt.Errorf("%s does not support ~", field)
+		}
+		if err := c.parseTime(value.text); err != nil {
+			return c, fmt.Errorf("%s: %v", field, err)
+		}
+	}
+	return c, nil
+}
+
+// parseAttribute checks the operator and value of a condition on a declared
+// attribute against the attribute type
+func (c *segmentCondition) parseAttribute(attribute *models.AttributeDefinition, value string) error {
+	field := "attributes." + c.key
+	equality := c.op == "=" || c.op == "!="
+
+	switch attribute.Type {
+	case models.AttributeTypeNumber:
+		if c.op == "~" {
+			return fmt.Errorf("%s does not support ~", field)
+		}
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", field, value)
+		}
+		c.kind, c.num = segmentNumber, n
+	case models.AttributeTypeBool:
+		if !equality {
+			return fmt.Errorf("%s only supports = and !=", field)
+		}
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%s must be true or false", field)
+		}
+		c.kind, c.bool = segmentBool, value == "true"
+	case models.AttributeTypeDate:
+		if c.op == "~" {
+			return fmt.Errorf("%s does not support ~", field)
+		}
+		c.kind = segmentTime
+		if err := c.parseTime(value); err != nil {
+			return fmt.Errorf("%s: %v", field, err)
+		}
+	case models.AttributeTypeEnum:
+		if !equality {
+			return fmt.Errorf("%s only supports = and !=", field)
+		}
+		if !attribute.HasOption(value) {
+			return fmt.Errorf("%s must be one of %v", field, []string(attribute.Options))
+		}
+	default:
+		if c.op != "~" && !equality {
+			return fmt.Errorf("%s only supports =, != and ~", field)
+		}
+	}
+	return nil
+}
+
+// parseTime reads a date, an RFC 3339 timestamp or a duration before now
+func (c *segmentCondition) parseTime(text string) error {
+	if m := relativeTimePattern.FindStringSubmatch(text); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return fmt.Errorf("%q is not a time", text)
+		}
+		unit := map[string]time.Duration{"m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[m[2]]
+		c.since = time.Duration(n) * unit
+		if c.since <= 0 {
+			return fmt.Errorf("%q is not a time", text)
+		}
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, text); err == nil {
+		c.at = t.UTC()
+		return nil
+	}
+	if t, err := time.Parse("2006-01-02", text); err == nil {
+		c.at = t
+		return nil
+	}
+	return fmt.Errorf("%q is not a date, timestamp or duration like -30d", text)
+}
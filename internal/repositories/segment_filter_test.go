@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+)
+
+func TestSegmentFilterParsing(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		filter string
+		sql    string
+		args   []interface{}
+	}{
+		{
+			filter: "tag = VIP",
+			sql:    "EXISTS (SELECT 1 FROM contact_tags WHERE contact_tags.contact_id = contacts.id AND contact_tags.tag = ?)",
+			args:   []interface{}{"vip"},
+		},
+		{
+			filter: `tag = vip consent = opted_in OR message_count >= 10`,
+			sql:    "((EXISTS (SELECT 1 FROM contact_tags WHERE contact_tags.contact_id = contacts.id AND contact_tags.tag = ?) AND contacts.consent = ?) OR contacts.message_count >= ?)",
+			args:   []interface{}{"vip", "opted_in", int64(10)},
+		},
+		{
+			filter: "not (consent = unknown or last_message_at < -2w)",
+			sql:    "NOT COALESCE((COALESCE(contacts.consent, '') = '' OR contacts.last_message_at < ?), FALSE)",
+			args:   []interface{}{now.Add(-14 * 24 * time.Hour)},
+		},
+		{
+			filter: `name != "Ada \"The\" Countess" AND created_at >= 2025-01-01`,
+			sql:    "((contacts.name IS NULL OR contacts.name <> ?) AND contacts.created_at >= ?)",
+			args:   []interface{}{`Ada "The" Countess`, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			filter: "tag != churned",
+			sql:    "NOT EXISTS (SELECT 1 FROM contact_tags WHERE contact_tags.contact_id = contacts.id AND contact_tags.tag = ?)",
+			args:   []interface{}{"churned"},
+		},
+	}
+	for _, tt := range tests {
+		node, err := parseSegmentFilter(tt.filter, nil)
+		if err != nil {
+			t.Errorf("parse %q: %v", tt.filter, err)
+			continue
+		}
+		sql, args := node.render(sqliteDialect{}, now)
+		if sql != tt.sql {
+			t.Errorf("%q rendered\n  %s\nwant\n  %s", tt.filter, sql, tt.sql)
+		}
+		if len(args) != len(tt.args) {
+			t.Errorf("%q args = %v, want %v", tt.filter, args, tt.args)
+			continue
+		}
+		for i := range args {
+			if at, ok := args[i].(time.Time); ok {
+				if !at.Equal(tt.args[i].(time.Time)) {
+					t.Errorf("%q arg %d = %v, want %v", tt.filter, i, at, tt.args[i])
+				}
+			} else if args[i] != tt.args[i] {
+				t.Errorf("%q arg %d = %v, want %v", tt.filter, i, args[i], tt.args[i])
+			}
+		}
+	}
+}
+
+func TestSegmentFilterErrors(t *testing.T) {
+	for filter, want := range map[string]string{
+		"":                              "empty",
+		"tag":                           "expected an operator",
+		"tag =":                         "expected a value",
+		"tag > vip":                     "only supports = and !=",
+		"plan = pro":                    "unknown field",
+		"consent = yes":                 "consent must be",
+		"message_count = lots":          "not a whole number",
+		"unread_count > 1.5":            "not a whole number",
+		"last_message_at > yesterday":   "not a date",
+		"attributes.plan > pro":         "needs a number",
+		"attributes.a-b = 1":            "attribute keys",
+		"(tag = vip":                    "missing )",
+		"tag = vip)":                    `unexpected ")"`,
+		`name = "open`:                  "unterminated",
+		"tag = vip AND OR tag = beta":   "expected a field",
+		"tag ! vip":                     "unexpected",
+		strings.Repeat("tag = a ", 51):  "more than 50 conditions",
+		"x" + strings.Repeat(" ", 2000): "longer than",
+	} {
+		err := ValidateSegmentFilter(filter, nil)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%.40q: got %v, want an error containing %q", filter, err, want)
+		}
+	}
+}
+
+func TestSegmentFilterDeclaredAttributes(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	attributes := []*models.AttributeDefinition{
+		{Name: "zip", Type: models.AttributeTypeString},
+		{Name: "seats", Type: models.AttributeTypeNumber},
+		{Name: "renewal", Type: models.AttributeTypeDate},
+		{Name: "trial", Type: models.AttributeTypeBool},
+		{Name: "plan", Type: models.AttributeTypeEnum, Options: models.JSONArray{"free", "pro"}},
+	}
+
+	tests := []struct {
+		filter string
+		sql    string
+		arg    interface{}
+	}{
+		// Unquoted numbers compare as text on string attributes
+		{"attributes.zip = 10115", "CASE WHEN json_type(contacts.metadata, '$.zip') = 'text' THEN json_extract(contacts.metadata, '$.zip') END = ?", "10115"},
+		{"attributes.seats >= 10", "CASE WHEN json_type(contacts.metadata, '$.seats') IN ('integer', 'real') THEN json_extract(contacts.metadata, '$.seats') END >= ?", float64(10)},
+		{"attributes.renewal < -2w", "CASE WHEN json_type(contacts.metadata, '$.renewal') = 'text' THEN json_extract(contacts.metadata, '$.renewal') END < ?", "2025-05-18"},
+		{"attributes.renewal >= 2025-01-31T23:00:00-02:00", "CASE WHEN json_type(contacts.metadata, '$.renewal') = 'text' THEN json_extract(contacts.metadata, '$.renewal') END >= ?", "2025-02-01"},
+		{"attributes.plan = pro", "CASE WHEN json_type(contacts.metadata, '$.plan') = 'text' THEN json_extract(contacts.metadata, '$.plan') END = ?", "pro"},
+	}
+	for _, tt := range tests {
+		node, err := parseSegmentFilter(tt.filter, attributes)
+		if err != nil {
+			t.Errorf("parse %q: %v", tt.filter, err)
+			continue
+		}
+		sql, args := node.render(sqliteDialect{}, now)
+		if sql != tt.sql || len(args) != 1 || args[0] != tt.arg {
+			t.Errorf("%q rendered\n  %s %v\nwant\n  %s [%v]", tt.filter, sql, args, tt.sql, tt.arg)
+		}
+	}
+
+	for filter, want := range map[string]string{
+		"attributes.seats ~ 1":          "does not support ~",
+		"attributes.seats = many":       "not a number",
+		"attributes.renewal = tomorrow": "not a date",
+		"attributes.trial > true":       "only supports = and !=",
+		"attributes.trial = yes":        "true or false",
+		"attributes.plan = gold":        "must be one of",
+		"attributes.zip > 10000":        "only supports =, != and ~",
+	} {
+		err := ValidateSegmentFilter(filter, attributes)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want an error containing %q", filter, err, want)
+		}
+	}
+}
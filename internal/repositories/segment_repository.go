@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// SegmentRepository handles segment data access
+type SegmentRepository struct {
+	*BaseRepository
+}
+
+// NewSegmentRepository creates a new segment repository
+func NewSegmentRepository(db *gorm.DB) *SegmentRepository {
+	return &SegmentRepository{
+		BaseRepository: NewBaseRepository(db),
+	}
+}
+
+// ForOrganization returns a repository scoped to the given organization
+func (r *SegmentRepository) ForOrganization(orgID string) SegmentStore {
+	return &SegmentRepository{BaseRepository: r.BaseRepository.ForOrganization(orgID)}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *SegmentRepository) WithContext(ctx context.Context) SegmentStore {
+	return &SegmentRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
+// FindByName finds a segment by name
+func (r *SegmentRepository) FindByName(name string) (*models.Segment, error) {
+	var segment models.Segment
+	err := r.DB.Where("name = ?", name).First(&segment).Error
+	return &segment, err
+}
+
+// ListAll lists all segments by name with pagination
+func (r *SegmentRepository) ListAll(pagination *utils.Pagination) ([]*models.Segment, error) {
+	var segments []*models.Segment
+
+	query := r.DB.Model(&models.Segment{}).Order("name ASC")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	pagination.SetTotal(total)
+
+	err := pagination.ApplyToQuery(query).Find(&segments).Error
+	return segments, err
+}
@@ -0,0 +1,51 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+func TestSegmentRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewSegmentRepository(db).ForOrganization("org_a")
+		other := NewSegmentRepository(db).ForOrganization("org_b")
+
+		vips := &models.Segment{Name: "VIPs", Filter: "tag = vip"}
+		active := &models.Segment{Name: "Active", Filter: "last_message_at > -7d"}
+		for _, segment := range []*models.Segment{vips, active} {
+			if err := repo.Create(segment); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		if err := repo.Create(&models.Segment{Name: "VIPs", Filter: "tag = gold"}); err == nil {
+			t.Error("segment names should be unique per organization")
+		}
+		if err := other.Create(&models.Segment{Name: "VIPs", Filter: "tag = gold"}); err != nil {
+			t.Errorf("the same name in another organization: %v", err)
+		}
+
+		found, err := repo.FindByName("VIPs")
+		if err != nil || found.ID != vips.ID || found.Filter != "tag = vip" {
+			t.Errorf("FindByName: %+v %v", found, err)
+		}
+
+		pagination := utils.NewPagination(10, 0)
+		all, err := repo.ListAll(pagination)
+		if err != nil || len(all) != 2 || all[0].Name != "Active" || pagination.Total != 2 {
+			t.Errorf("ListAll should sort by name: %d %v", len(all), err)
+		}
+
+		if err := repo.Delete(vips); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if _, err := repo.FindByName("VIPs"); err == nil {
+			t.Error("deleted segment should be gone")
+		}
+		if _, err := other.FindByName("VIPs"); err != nil {
+			t.Errorf("segment of another organization should remain: %v", err)
+		}
+	})
+}
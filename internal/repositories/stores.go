@@ -0,0 +1,212 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+)
+
+// The store interfaces describe the data access the services depend on, so
+// services can be tested against in-memory implementations. The repositories
+// in this package are the database implementations. WithContext and
+// ForOrganization return a store bound to a request context and tenant, like
+// on the repositories.
+
+// MessageStore stores messages
+type MessageStore interface {
+	WithContext(ctx context.Context) MessageStore
+	ForOrganization(orgID string) MessageStore
+
+	Create(model interface{}) error
+	FindByID(id string, model interface{}) error
+	FindByPhone(phone string, pagination *utils.Pagination) ([]*models.Message, error)
+	FindByWhatsAppMessageIDs(whatsappMessageIDs []string) ([]*models.Message, error)
+	ListWithFilters(filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Message, error)
+	Search(query string, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.MessageSearchResult, error)
+	UpdateByWhatsAppMessageID(whatsappMessageID string, updates map[string]interface{}) error
+	FindAged(filter MessageAgeFilter, limit int) ([]*models.Message, error)
+	CountAged(filter MessageAgeFilter) (int64, error)
+	DeleteMessages(ids []string) (int64, error)
+	RemoveMedia(ids []string, at time.Time) (int64, error)
+}
+
+// ReactionStore stores reactions to messages
+type ReactionStore interface {
+	WithContext(ctx context.Context) ReactionStore
+	ForOrganization(orgID string) ReactionStore
+
+	Upsert(reaction *models.Reaction) error
+	Remove(targetWhatsAppMessageID, fromNumber, direction string) error
+	FindByTargets(targetWhatsAppMessageIDs []string) ([]*models.Reaction, error)
+}
+
+// ContactStore stores contacts
+type ContactStore interface {
+	WithContext(ctx context.Context) ContactStore
+	ForOrganization(orgID string) ContactStore
+
+	FindByID(id string, model interface{}) error
+	FindByPhone(phone string) (*models.Contact, error)
+	GetOrCreate(phone string) (*models.Contact, error)
+	UpdateFields(id string, model interface{}, updates map[string]interface{}) error
+	ListWithFilters(filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Contact, error)
+	Search(query string, pagination *utils.Pagination) ([]*models.Contact, error)
+	UpdateLastMessage(phone string, timestamp time.Time) error
+	IncrementMessageCount(phone string, delta int) error
+	UpdateUnreadCount(phone string, delta int) error
+	ResetUnreadCount(phone string) error
+	CountWithFilters(filters map[string]interface{}) (int64, error)
+	FindIDsWithFilters(filters map[string]interface{}) ([]string, error)
+	AddTags(contactIDs, tags []string) error
+	RemoveTags(contactIDs, tags []string) error
+	LoadTags(contacts []*models.Contact) error
+	ListTags() ([]*models.TagCount, error)
+	UpsertContact(contact *models.Contact) error
+	ListPhoneNumbers() ([]*models.Contact, error)
+	Merge(merge *models.ContactMerge) (int64, error)
+	SetBlocked(contact *models.Contact, audit *models.AuditLog) error
+	DeleteContact(contact *models.Contact, audit *models.AuditLog) error
+	Erase(contact *models.Contact, erasure *models.ContactErasure, audit *models.AuditLog) error
+}
+
+// CallStore stores voice calls and their transcripts
+type CallStore interface {
+	WithContext(ctx context.Context) CallStore
+	ForOrganization(orgID string) CallStore
+
+	FindByPhone(phone string) ([]*models.Call, error)
+	FindTranscripts(callIDs []string) ([]*models.Transcript, error)
+	FindSegments(transcriptIDs []string) ([]*models.TranscriptSegment, error)
+}
+
+// SegmentStore stores saved contact segments
+type SegmentStore interface {
+	WithContext(ctx context.Context) SegmentStore
+	ForOrganization(orgID string) SegmentStore
+
+	Create(model interface{}) error
+	FindByID(id string, model interface{}) error
+	FindByName(name string) (*models.Segment, error)
+	ListAll(pagination *utils.Pagination) ([]*models.Segment, error)
+	UpdateFields(id string, model interface{}, updates map[string]interface{}) error
+	Delete(model interface{}) error
+}
+
+// AttributeStore stores custom contact attribute definitions
+type AttributeStore interface {
+	WithContext(ctx context.Context) AttributeStore
+	ForOrganization(orgID string) AttributeStore
+
+	Create(model interface{}) error
+	FindByID(id string, model interface{}) error
+	FindByName(name string) (*models.AttributeDefinition, error)
+	ListAll() ([]*models.AttributeDefinition, error)
+	UpdateFields(id string, model interface{}, updates map[string]interface{}) error
+	Delete(model interface{}) error
+}
+
+// ContactImportStore stores bulk contact imports
+type ContactImportStore interface {
+	WithContext(ctx context.Context) ContactImportStore
+	ForOrganization(orgID string) ContactImportStore
+
+	Create(model interface{}) error
+	FindByID(id string, model interface{}) error
+	ListAll(pagination *utils.Pagination) ([]*models.ContactImport, error)
+	FindByStatus(statuses ...string) ([]*models.ContactImport, error)
+	UpdateFields(id string, model interface{}, updates map[string]interface{}) error
+}
+
+// AuditLogStore stores the audit log
+type AuditLogStore interface {
+	WithContext(ctx context.Context) AuditLogStore
+	ForOrganization(orgID string) AuditLogStore
+
+	Create(model interface{}) error
+	ListWithFilters(filters map[string]interface{}, pagination *utils.Pagination) ([]*models.AuditLog, error)
+}
+
+// RetentionPolicyStore stores message retention policies
+type RetentionPolicyStore interface {
+	WithContext(ctx context.Context) RetentionPolicyStore
+	ForOrganization(orgID string) RetentionPolicyStore
+
+	ListAll() ([]*models.RetentionPolicy, error)
+	FindByType(messageType string) (*models.RetentionPolicy, error)
+	Upsert(policy *models.RetentionPolicy) error
+	DeleteByType(messageType string) error
+}
+
+// TemplateStore stores message templates
+type TemplateStore interface {
+	WithContext(ctx context.Context) TemplateStore
+	ForOrganization(orgID string) TemplateStore
+
+	Create(model interface{}) error
+	FindByID(id string, model interface{}) error
+	FindByName(name, language string) (*models.Template, error)
+	ListAll(pagination *utils.Pagination) ([]*models.Template, error)
+	UpdateFields(id string, model interface{}, updates map[string]interface{}) error
+	Delete(model interface{}) error
+}
+
+// OrganizationStore stores organizations. Organizations are not tenant
+// scoped.
+type OrganizationStore interface {
+	WithContext(ctx context.Context) OrganizationStore
+
+	Create(model interface{}) error
+	FindByID(id string, model interface{}) error
+	FindByBusinessAccountID(wabaID string) (*models.Organization, error)
+	FindByPhoneNumberID(phoneNumberID string) (*models.Organization, error)
+	FindByWebhookVerifyToken(token string) (*models.Organization, error)
+	FindUpdatedSince(since time.Time) ([]*models.Organization, error)
+	ListAll(pagination *utils.Pagination) ([]*models.Organization, error)
+	UpdateFields(id string, model interface{}, updates map[string]interface{}) error
+	Delete(model interface{}) error
+	AssignOrphans(orgID string) error
+}
+
+// APIKeyStore stores API keys
+type APIKeyStore interface {
+	WithContext(ctx context.Context) APIKeyStore
+	ForOrganization(orgID string) APIKeyStore
+
+	Create(model interface{}) error
+	FindByID(id string, model interface{}) error
+	FindByKeyHash(keyHash string) (*models.APIKey, error)
+	FindByKeyPrefix(prefix string) ([]*models.APIKey, error)
+	FindUpdatedSince(since time.Time) ([]*models.APIKey, error)
+	ListAll() ([]*models.APIKey, error)
+	UpdateFields(id string, model interface{}, updates map[string]interface{}) error
+	UpdateKeyHash(id, keyHash string) error
+	UpdateLastUsedBatch(lastUsed map[string]time.Time) error
+	Revoke(id, reason string) error
+	RevokeAll(reason string) (int64, error)
+}
+
+// LeaseStore stores leases on background jobs
+type LeaseStore interface {
+	WithContext(ctx context.Context) LeaseStore
+
+	Acquire(name, owner string, ttl time.Duration) (bool, error)
+	Release(name, owner string) error
+}
+
+var (
+	_ MessageStore         = (*MessageRepository)(nil)
+	_ ReactionStore        = (*ReactionRepository)(nil)
+	_ ContactStore         = (*ContactRepository)(nil)
+	_ CallStore            = (*CallRepository)(nil)
+	_ SegmentStore         = (*SegmentRepository)(nil)
+	_ AttributeStore       = (*AttributeRepository)(nil)
+	_ ContactImportStore   = (*ContactImportRepository)(nil)
+	_ AuditLogStore        = (*AuditLogRepository)(nil)
+	_ RetentionPolicyStore = (*RetentionPolicyRepository)(nil)
+	_ TemplateStore        = (*TemplateRepository)(nil)
+	_ OrganizationStore    = (*OrganizationRepository)(nil)
+	_ APIKeyStore          = (*APIKeyRepository)(nil)
+	_ LeaseStore           = (*LeaseRepository)(nil)
+)
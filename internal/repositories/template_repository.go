@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"context"
+
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
 	"gorm.io/gorm"
@@ -18,6 +20,16 @@ func NewTemplateRepository(db *gorm.DB) *TemplateRepository {
 	}
 }
 
+// ForOrganization returns a repository scoped to the given organization
+func (r *TemplateRepository) ForOrganization(orgID string) TemplateStore {
+	return &TemplateRepository{BaseRepository: r.BaseRepository.ForOrganization(orgID)}
+}
+
+// WithContext returns a repository whose queries run with ctx
+func (r *TemplateRepository) WithContext(ctx context.Context) TemplateStore {
+	return &TemplateRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
 // FindByName finds a template by name and language
 func (r *TemplateRepository) FindByName(name, language string) (*models.Template, error) {
 	var template models.Template
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"testing"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+func TestTemplateRepository(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewTemplateRepository(db).ForOrganization("org_a").(*TemplateRepository)
+		other := NewTemplateRepository(db).ForOrganization("org_b")
+
+		welcome := &models.Template{Name: "welcome", Language: "en_US", Category: models.TemplateCategoryUtility, Status: models.TemplateStatusApproved,
+			Content: "Hi {{1}}", Parameters: models.JSONArray{"name"}}
+		promo := &models.Template{Name: "promo", Language: "en_US", Category: models.TemplateCategoryMarketing, Status: models.TemplateStatusPending, Content: "Sale"}
+		for _, tmpl := range []*models.Template{welcome, promo} {
+			if err := repo.Create(tmpl); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+		}
+		other.Create(&models.Template{Name: "welcome", Language: "en_US", Category: models.TemplateCategoryUtility, Status: models.TemplateStatusApproved, Content: "Hello"})
+
+		found, err := repo.FindByName("welcome", "en_US")
+		if err != nil || found.ID != welcome.ID || len(found.Parameters) != 1 || found.Parameters[0] != "name" {
+			t.Errorf("FindByName: %+v %v", found, err)
+		}
+		if _, err := repo.FindByName("welcome", "de"); err == nil {
+			t.Error("FindByName should match the language")
+		}
+
+		pagination := utils.NewPagination(10, 0)
+		all, err := repo.ListAll(pagination)
+		if err != nil || len(all) != 2 || pagination.Total != 2 {
+			t.Errorf("ListAll: %d %v", len(all), err)
+		}
+
+		byCategory, err := repo.FindByCategory(models.TemplateCategoryMarketing, utils.NewPagination(10, 0))
+		if err != nil || len(byCategory) != 1 || byCategory[0].ID != promo.ID {
+			t.Errorf("FindByCategory: %d %v", len(byCategory), err)
+		}
+
+		approved, err := repo.FindApproved(utils.NewPagination(10, 0))
+		if err != nil || len(approved) != 1 || approved[0].ID != welcome.ID {
+			t.Errorf("FindApproved: %d %v", len(approved), err)
+		}
+
+		if err := repo.UpdateFields(promo.ID, &models.Template{}, map[string]interface{}{"status": models.TemplateStatusApproved}); err != nil {
+			t.Fatalf("UpdateFields: %v", err)
+		}
+		approved, _ = repo.FindByStatus(models.TemplateStatusApproved, utils.NewPagination(10, 0))
+		if len(approved) != 2 {
+			t.Errorf("FindByStatus after update: %d", len(approved))
+		}
+
+		if err := repo.Delete(welcome); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := repo.FindByID(welcome.ID, &models.Template{}); err == nil {
+			t.Error("deleted template is still found")
+		}
+		if _, err := other.FindByName("welcome", "en_US"); err != nil {
+			t.Errorf("delete affected another organization: %v", err)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+)
+
+// apiKeyCache keeps recently verified API keys in memory, keyed by digest,
+// so authenticated requests don't hit the database
+type apiKeyCache struct {
+	ttl     time.Duration
+	entries map[string]apiKeyCacheEntry
+	digests map[string]string // key ID -> digest, for invalidation
+	mu      sync.RWMutex
+}
+
+type apiKeyCacheEntry struct {
+	apiKey    *models.APIKey
+	expiresAt time.Time
+}
+
+func newAPIKeyCache(ttl time.Duration) *apiKeyCache {
+	return &apiKeyCache{
+		ttl:     ttl,
+		entries: make(map[string]apiKeyCacheEntry),
+		digests: make(map[string]string),
+	}
+}
+
+// get returns the cached key for a digest if it has not expired
+func (c *apiKeyCache) get(digest string) (*models.APIKey, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[digest]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.apiKey, true
+}
+
+// put caches a verified key
+func (c *apiKeyCache) put(digest string, apiKey *models.APIKey) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop expired entries while we hold the lock so the map stays bounded
+	// by the number of keys used within one TTL
+	now := time.Now()
+	for d, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, d)
+			delete(c.digests, entry.apiKey.ID)
+		}
+	}
+
+	c.entries[digest] = apiKeyCacheEntry{apiKey: apiKey, expiresAt: now.Add(c.ttl)}
+	c.digests[apiKey.ID] = digest
+}
+
+// invalidate removes a key from the cache, e.g. after it was revoked
+func (c *apiKeyCache) invalidate(keyID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if digest, ok := c.digests[keyID]; ok {
+		delete(c.entries, digest)
+		delete(c.digests, keyID)
+	}
+}
+
+// invalidateOrganization removes all keys of an organization from the cache,
+// e.g. after it was suspended
+func (c *apiKeyCache) invalidateOrganization(orgID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for digest, entry := range c.entries {
+		if entry.apiKey.OrganizationID == orgID {
+			delete(c.entries, digest)
+			delete(c.digests, entry.apiKey.ID)
+		}
+	}
+}
+
+// lastUsedRecorder collects API key usage in memory and writes it to the
+// database in batches instead of once per request
+type lastUsedRecorder struct {
+	pending map[string]time.Time
+	mu      sync.Mutex
+}
+
+func newLastUsedRecorder() *lastUsedRecorder {
+	return &lastUsedRecorder{pending: make(map[string]time.Time)}
+}
+
+// record notes that a key was used now
+func (r *lastUsedRecorder) record(keyID string) {
+	r.mu.Lock()
+	r.pending[keyID] = time.Now().UTC()
+	r.mu.Unlock()
+}
+
+// drain returns and clears the pending usage
+func (r *lastUsedRecorder) drain() map[string]time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending := r.pending
+	r.pending = make(map[string]time.Time)
+	return pending
+}
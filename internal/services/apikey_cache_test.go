@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+)
+
+func TestAPIKeyCache(t *testing.T) {
+	cache := newAPIKeyCache(time.Minute)
+	apiKey := &models.APIKey{ID: "key_1"}
+
+	if _, ok := cache.get("digest"); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+
+	cache.put("digest", apiKey)
+	if got, ok := cache.get("digest"); !ok || got != apiKey {
+		t.Fatal("expected hit after put")
+	}
+
+	cache.invalidate("key_1")
+	if _, ok := cache.get("digest"); ok {
+		t.Fatal("expected miss after invalidate")
+	}
+}
+
+func TestAPIKeyCacheInvalidateOrganization(t *testing.T) {
+	cache := newAPIKeyCache(time.Minute)
+	cache.put("digest_a", &models.APIKey{ID: "key_a", OrganizationID: "org_a"})
+	cache.put("digest_b", &models.APIKey{ID: "key_b", OrganizationID: "org_b"})
+
+	cache.invalidateOrganization("org_a")
+	if _, ok := cache.get("digest_a"); ok {
+		t.Error("expected miss for the invalidated organization")
+	}
+	if _, ok := cache.get("digest_b"); !ok {
+		t.Error("expected hit for other organizations")
+	}
+}
+
+func TestAPIKeyCacheExpiry(t *testing.T) {
+	cache := newAPIKeyCache(time.Millisecond)
+	cache.put("digest", &models.APIKey{ID: "key_1"})
+
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.get("digest"); ok {
+		t.Fatal("expected miss after TTL")
+	}
+}
+
+func TestLastUsedRecorderDrain(t *testing.T) {
+	recorder := newLastUsedRecorder()
+	recorder.record("key_1")
+	recorder.record("key_1")
+	recorder.record("key_2")
+
+	if pending := recorder.drain(); len(pending) != 2 {
+		t.Fatalf("expected 2 pending keys, got %d", len(pending))
+	}
+	if pending := recorder.drain(); len(pending) != 0 {
+		t.Fatalf("expected drain to clear pending keys, got %d", len(pending))
+	}
+}
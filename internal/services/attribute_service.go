@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+)
+
+const (
+	// MaxAttributeDefinitions is the most custom attributes an
+	// organization can declare
+	MaxAttributeDefinitions = 100
+
+	// maxEnumOptions bounds the values of an enum attribute
+	maxEnumOptions = 100
+)
+
+// attributeNamePattern matches attribute names. Names are metadata keys and
+// appear in segment filters and template parameters, so they are kept to
+// lower-case identifiers.
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// templateParameterPattern matches template parameters filled in from the
+// recipient contact
+var templateParameterPattern = regexp.MustCompile(`^\{\{\s*([A-Za-z0-9_.]+)\s*\}\}$`)
+
+// AttributeService handles the custom contact attribute schema. Contact
+// metadata may only hold declared attributes, with values of their type.
+type AttributeService struct {
+	attributeRepo repositories.AttributeStore
+}
+
+// NewAttributeService creates a new attribute service
+func NewAttributeService(attributeRepo repositories.AttributeStore) *AttributeService {
+	return &AttributeService{
+		attributeRepo: attributeRepo,
+	}
+}
+
+// CreateAttribute declares an attribute. Names are unique per organization.
+func (s *AttributeService) CreateAttribute(ctx context.Context, orgID string, attribute *models.AttributeDefinition) error {
+	attribute.Name = strings.TrimSpace(attribute.Name)
+	if !attributeNamePattern.MatchString(attribute.Name) {
+		return errors.NewBadRequest("name must start with a lower-case letter and contain only lower-case letters, digits and underscores, up to 64 characters")
+	}
+	switch attribute.Type {
+	case models.AttributeTypeString, models.AttributeTypeNumber, models.AttributeTypeDate, models.AttributeTypeBool, models.AttributeTypeEnum:
+	default:
+		return errors.NewBadRequest(fmt.Sprintf("type must be %s, %s, %s, %s or %s",
+			models.AttributeTypeString, models.AttributeTypeNumber, models.AttributeTypeDate, models.AttributeTypeBool, models.AttributeTypeEnum))
+	}
+	if err := validateAttributeOptions(attribute.Type, attribute.Options); err != nil {
+		return err
+	}
+
+	attributeRepo := s.attributeRepo.WithContext(ctx).ForOrganization(orgID)
+	if _, err := attributeRepo.FindByName(attribute.Name); err == nil {
+		return errors.NewConflict("An attribute named " + attribute.Name + " already exists")
+	}
+	attributes, err := attributeRepo.ListAll()
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	if len(attributes) >= MaxAttributeDefinitions {
+		return errors.NewBadRequest(fmt.Sprintf("At most %d attributes can be declared", MaxAttributeDefinitions))
+	}
+
+	if err := attributeRepo.Create(attribute); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// validateAttributeOptions checks that enum attributes, and only they, have
+// options
+func validateAttributeOptions(attributeType string, options []string) error {
+	if attributeType != models.AttributeTypeEnum {
+		if len(options) > 0 {
+			return errors.NewBadRequest("options are only allowed on enum attributes")
+		}
+		return nil
+	}
+	if len(options) == 0 {
+		return errors.NewBadRequest("enum attributes need options")
+	}
+	if len(options) > maxEnumOptions {
+		return errors.NewBadRequest(fmt.Sprintf("enum attributes can have at most %d options", maxEnumOptions))
+	}
+	seen := make(map[string]bool, len(options))
+	for _, option := range options {
+		if strings.TrimSpace(option) == "" || len(option) > 255 {
+			return errors.NewBadRequest("options must be non-empty and at most 255 characters")
+		}
+		if seen[option] {
+			return errors.NewBadRequest(fmt.Sprintf("option %q is listed twice", option))
+		}
+		seen[option] = true
+	}
+	return nil
+}
+
+// GetAttribute gets an attribute definition by ID
+func (s *AttributeService) GetAttribute(ctx context.Context, orgID, attributeID string) (*models.AttributeDefinition, error) {
+	var attribute models.AttributeDefinition
+	if err := s.attributeRepo.WithContext(ctx).ForOrganization(orgID).FindByID(attributeID, &attribute); err != nil {
+		return nil, errors.NewNotFound("Attribute", attributeID)
+	}
+	return &attribute, nil
+}
+
+// ListAttributes lists the declared attributes by name
+func (s *AttributeService) ListAttributes(ctx context.Context, orgID string) ([]*models.AttributeDefinition, error) {
+	attributes, err := s.attributeRepo.WithContext(ctx).ForOrganization(orgID).ListAll()
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return attributes, nil
+}
+
+// UpdateAttribute updates the description, options or required flag of an
+// attribute. Names and types cannot change, as contacts store values under
+// the name in the type's form.
+func (s *AttributeService) UpdateAttribute(ctx context.Context, orgID, attributeID string, updates map[string]interface{}) (*models.AttributeDefinition, error) {
+	attribute, err := s.GetAttribute(ctx, orgID, attributeID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]interface{})
+	for field, value := range updates {
+		switch field {
+		case "description":
+			description, ok := value.(string)
+			if !ok {
+				return nil, errors.NewBadRequest("description must be a string")
+			}
+			allowed[field] = description
+		case "required":
+			required, ok := value.(bool)
+			if !ok {
+				return nil, errors.NewBadRequest("required must be true or false")
+			}
+			allowed[field] = required
+		case "options":
+			values, ok := value.([]interface{})
+			if !ok {
+				return nil, errors.NewBadRequest("options must be a list of strings")
+			}
+			options := make(models.JSONArray, 0, len(values))
+			for _, v := range values {
+				option, ok := v.(string)
+				if !ok {
+					return nil, errors.NewBadRequest("options must be a list of strings")
+				}
+				options = append(options, option)
+			}
+			if err := validateAttributeOptions(attribute.Type, options); err != nil {
+				return nil, err
+			}
+			allowed[field] = options
+		case "name", "type":
+			return nil, errors.NewBadRequest(field + " cannot be changed")
+		default:
+			return nil, errors.NewBadRequest(field + " cannot be updated")
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, errors.NewBadRequest("description, options or required is required")
+	}
+
+	if err := s.attributeRepo.WithContext(ctx).ForOrganization(orgID).UpdateFields(attributeID, attribute, allowed); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return s.GetAttribute(ctx, orgID, attributeID)
+}
+
+// DeleteAttribute deletes an attribute definition. Values already stored
+// on contacts are kept, but can no longer be set.
+func (s *AttributeService) DeleteAttribute(ctx context.Context, orgID, attributeID string) error {
+	attribute, err := s.GetAttribute(ctx, orgID, attributeID)
+	if err != nil {
+		return err
+	}
+	if err := s.attributeRepo.WithContext(ctx).ForOrganization(orgID).Delete(attribute); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// definitions returns the declared attributes by name
+func (s *AttributeService) definitions(ctx context.Context, orgID string) (map[string]*models.AttributeDefinition, error) {
+	attributes, err := s.ListAttributes(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*models.AttributeDefinition, len(attributes))
+	for _, attribute := range attributes {
+		byName[attribute.Name] = attribute
+	}
+	return byName, nil
+}
+
+// ApplyAttributes merges attribute updates into a contact's metadata. Each
+// key must be a declared attribute and each value must suit its type; null
+// removes the attribute. Required attributes must be set afterwards.
+func (s *AttributeService) ApplyAttributes(ctx context.Context, orgID string, metadata models.JSONMap, updates map[string]interface{}) (models.JSONMap, error) {
+	definitions, err := s.definitions(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return applyAttributes(definitions, metadata, updates)
+}
+
+// applyAttributes is ApplyAttributes with the declared attributes already
+// loaded, for callers updating many contacts
+func applyAttributes(definitions map[string]*models.AttributeDefinition, metadata models.JSONMap, updates map[string]interface{}) (models.JSONMap, error) {
+	merged := make(models.JSONMap, len(metadata)+len(updates))
+	for key, value := range metadata {
+		merged[key] = value
+	}
+	for key, value := range updates {
+		definition, ok := definitions[key]
+		if !ok {
+			return nil, errors.NewBadRequest(fmt.Sprintf("Unknown attribute %q", key))
+		}
+		if value == nil {
+			delete(merged, key)
+			continue
+		}
+		normalized, err := definition.NormalizeValue(value)
+		if err != nil {
+			return nil, errors.NewBadRequest(err.Error())
+		}
+		merged[key] = normalized
+	}
+
+	for name, definition := range definitions {
+		if _, ok := merged[name]; definition.Required && !ok {
+			return nil, errors.NewBadRequest(fmt.Sprintf("Attribute %q is required", name))
+		}
+	}
+	return merged, nil
+}
+
+// ValidateFilter checks a segment filter against the declared attributes
+func (s *AttributeService) ValidateFilter(ctx context.Context, orgID, filter string) error {
+	attributes, err := s.ListAttributes(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if err := repositories.ValidateSegmentFilter(filter, attributes); err != nil {
+		return errors.NewBadRequest("Invalid filter: " + err.Error())
+	}
+	return nil
+}
+
+// usesContactParameters reports whether any template parameter is filled in
+// from the contact
+func usesContactParameters(params []string) bool {
+	for _, param := range params {
+		if templateParameterPattern.MatchString(param) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveTemplateParameters fills in template parameters written as
+// {{name}}, {{phone_number}} or {{attributes.<name>}} from the contact.
+// Other parameters are sent as they are.
+func (s *AttributeService) ResolveTemplateParameters(ctx context.Context, orgID string, contact *models.Contact, params []string) ([]string, error) {
+	var definitions map[string]*models.AttributeDefinition
+	resolved := make([]string, len(params))
+	for i, param := range params {
+		m := templateParameterPattern.FindStringSubmatch(param)
+		if m == nil {
+			resolved[i] = param
+			continue
+		}
+
+		source := m[1]
+		var value string
+		switch {
+		case source == "name":
+			value = contact.Name
+		case source == "phone_number":
+			value = contact.PhoneNumber
+		case strings.HasPrefix(source, "attributes."):
+			if definitions == nil {
+				var err error
+				if definitions, err = s.definitions(ctx, orgID); err != nil {
+					return nil, err
+				}
+			}
+			name := strings.TrimPrefix(source, "attributes.")
+			if _, ok := definitions[name]; !ok {
+				return nil, errors.NewBadRequest(fmt.Sprintf("Parameter %d: unknown attribute %q", i+1, name))
+			}
+			value = models.FormatAttributeValue(contact.Metadata[name])
+		default:
+			return nil, errors.NewBadRequest(fmt.Sprintf("Parameter %d: unknown source %q, use name, phone_number or attributes.<name>", i+1, source))
+		}
+
+		if value == "" {
+			return nil, errors.NewBadRequest(fmt.Sprintf("Parameter %d: the contact has no %s", i+1, source))
+		}
+		resolved[i] = value
+	}
+	return resolved, nil
+}
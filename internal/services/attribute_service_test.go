@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+)
+
+func TestAttributeService(t *testing.T) {
+	service := NewAttributeService(newMemAttributeStore())
+	ctx := context.Background()
+
+	plan := &models.AttributeDefinition{Name: "plan", Type: models.AttributeTypeEnum, Options: models.JSONArray{"free", "pro"}}
+	if err := service.CreateAttribute(ctx, "org_a", plan); err != nil {
+		t.Fatalf("CreateAttribute: %v", err)
+	}
+	err := service.CreateAttribute(ctx, "org_a", &models.AttributeDefinition{Name: "plan", Type: models.AttributeTypeString})
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrConflict {
+		t.Errorf("expected a conflict for a duplicate name, got %v", err)
+	}
+	if err := service.CreateAttribute(ctx, "org_b", &models.AttributeDefinition{Name: "plan", Type: models.AttributeTypeString}); err != nil {
+		t.Errorf("names are unique per organization: %v", err)
+	}
+
+	for name, attribute := range map[string]*models.AttributeDefinition{
+		"bad name":         {Name: "Plan Name", Type: models.AttributeTypeString},
+		"unknown type":     {Name: "color", Type: "colour"},
+		"enum no options":  {Name: "tier", Type: models.AttributeTypeEnum},
+		"duplicate option": {Name: "tier", Type: models.AttributeTypeEnum, Options: models.JSONArray{"a", "a"}},
+		"options on text":  {Name: "city", Type: models.AttributeTypeString, Options: models.JSONArray{"Paris"}},
+	} {
+		err := service.CreateAttribute(ctx, "org_a", attribute)
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+			t.Errorf("%s: expected bad request, got %v", name, err)
+		}
+	}
+
+	updated, err := service.UpdateAttribute(ctx, "org_a", plan.ID, map[string]interface{}{"options": []interface{}{"free", "pro", "team"}, "required": true})
+	if err != nil || len(updated.Options) != 3 || !updated.Required {
+		t.Errorf("UpdateAttribute: %+v %v", updated, err)
+	}
+	for _, field := range []string{"name", "type"} {
+		if _, err := service.UpdateAttribute(ctx, "org_a", plan.ID, map[string]interface{}{field: "x"}); err == nil {
+			t.Errorf("%s should not be changeable", field)
+		}
+	}
+	if _, err := service.GetAttribute(ctx, "org_b", plan.ID); err == nil {
+		t.Error("attribute should not be visible to another organization")
+	}
+
+	if err := service.DeleteAttribute(ctx, "org_a", plan.ID); err != nil {
+		t.Fatalf("DeleteAttribute: %v", err)
+	}
+	if attributes, _ := service.ListAttributes(ctx, "org_a"); len(attributes) != 0 {
+		t.Errorf("deleted attribute is still listed: %+v", attributes)
+	}
+}
+
+func TestApplyAttributes(t *testing.T) {
+	service := NewAttributeService(newMemAttributeStore())
+	ctx := context.Background()
+	for _, attribute := range []*models.AttributeDefinition{
+		{Name: "city", Type: models.AttributeTypeString},
+		{Name: "seats", Type: models.AttributeTypeNumber},
+		{Name: "renewal", Type: models.AttributeTypeDate},
+		{Name: "trial", Type: models.AttributeTypeBool},
+		{Name: "plan", Type: models.AttributeTypeEnum, Options: models.JSONArray{"free", "pro"}, Required: true},
+	} {
+		if err := service.CreateAttribute(ctx, "org_a", attribute); err != nil {
+			t.Fatalf("CreateAttribute %s: %v", attribute.Name, err)
+		}
+	}
+
+	current := models.JSONMap{"plan": "free", "legacy": "kept"}
+	metadata, err := service.ApplyAttributes(ctx, "org_a", current, map[string]interface{}{
+		"city":    "Lisbon",
+		"seats":   float64(3),
+		"renewal": "2025-03-01T23:30:00-02:00",
+		"trial":   true,
+		"plan":    "pro",
+	})
+	if err != nil {
+		t.Fatalf("ApplyAttributes: %v", err)
+	}
+	if metadata["renewal"] != "2025-03-02" || metadata["plan"] != "pro" || metadata["legacy"] != "kept" || metadata["trial"] != true {
+		t.Errorf("unexpected metadata %v", metadata)
+	}
+	if current["plan"] != "free" {
+		t.Error("ApplyAttributes must not change the current metadata")
+	}
+
+	for name, updates := range map[string]map[string]interface{}{
+		"undeclared":       {"color": "red"},
+		"number as text":   {"seats": "3"},
+		"bad date":         {"renewal": "next week"},
+		"bool as text":     {"trial": "yes"},
+		"unknown option":   {"plan": "gold"},
+		"required removed": {"plan": nil},
+		"text too long":    {"city": strings.Repeat("x", models.MaxAttributeTextLength+1)},
+	} {
+		_, err := service.ApplyAttributes(ctx, "org_a", current, updates)
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+			t.Errorf("%s: expected bad request, got %v", name, err)
+		}
+	}
+	if _, err := service.ApplyAttributes(ctx, "org_a", nil, map[string]interface{}{"city": "Porto"}); err == nil {
+		t.Error("required attributes must be set")
+	}
+}
+
+func TestResolveTemplateParameters(t *testing.T) {
+	service := NewAttributeService(newMemAttributeStore())
+	ctx := context.Background()
+	service.CreateAttribute(ctx, "org_a", &models.AttributeDefinition{Name: "seats", Type: models.AttributeTypeNumber})
+	service.CreateAttribute(ctx, "org_a", &models.AttributeDefinition{Name: "city", Type: models.AttributeTypeString})
+	contact := &models.Contact{Name: "Ada", PhoneNumber: "14155550100", Metadata: models.JSONMap{"seats": float64(12.5)}}
+
+	params, err := service.ResolveTemplateParameters(ctx, "org_a", contact, []string{"{{name}}", "{{ attributes.seats }}", "{{phone_number}}", "literal {{name}}"})
+	if err != nil {
+		t.Fatalf("ResolveTemplateParameters: %v", err)
+	}
+	if strings.Join(params, "|") != "Ada|12.5|14155550100|literal {{name}}" {
+		t.Errorf("unexpected parameters %q", params)
+	}
+
+	for _, param := range []string{"{{attributes.city}}", "{{attributes.color}}", "{{email}}"} {
+		if _, err := service.ResolveTemplateParameters(ctx, "org_a", contact, []string{param}); err == nil {
+			t.Errorf("%s should be rejected", param)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+)
+
+// AuditLogService reads the audit log. Entries are written by the services
+// making the audited changes.
+type AuditLogService struct {
+	auditRepo repositories.AuditLogStore
+}
+
+// NewAuditLogService creates a new audit log service
+func NewAuditLogService(auditRepo repositories.AuditLogStore) *AuditLogService {
+	return &AuditLogService{
+		auditRepo: auditRepo,
+	}
+}
+
+// ListAuditLogs lists the organization's audit log, newest first. filters
+// may restrict it by "action" and "resource_id".
+func (s *AuditLogService) ListAuditLogs(ctx context.Context, orgID string, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.AuditLog, error) {
+	entries, err := s.auditRepo.WithContext(ctx).ForOrganization(orgID).ListWithFilters(filters, pagination)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return entries, nil
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
@@ -9,42 +11,150 @@ import (
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
 )
 
+// lastUsedFlushInterval is how often batched last_used_at updates are written
+const lastUsedFlushInterval = 30 * time.Second
+
+// revocationSyncInterval is how often keys revoked, rotated or suspended on
+// other replicas are dropped from the local cache. revocationSyncOverlap
+// widens each poll to tolerate clock skew between replicas and the database.
+const (
+	revocationSyncInterval = 5 * time.Second
+	revocationSyncOverlap  = time.Minute
+)
+
 // AuthService handles authentication business logic
 type AuthService struct {
-	apiKeyRepo *repositories.APIKeyRepository
+	apiKeyRepo repositories.APIKeyStore
+	orgRepo    repositories.OrganizationStore
+	salt       string
+	cache      *apiKeyCache
+	lastUsed   *lastUsedRecorder
+	syncMu     sync.Mutex
+	lastSync   time.Time
+	stop       chan struct{}
+	done       chan struct{}
 }
 
-// NewAuthService creates a new auth service
-func NewAuthService(apiKeyRepo *repositories.APIKeyRepository) *AuthService {
-	return &AuthService{
+// NewAuthService creates a new auth service. Verified keys are cached for
+// cacheTTL; a background goroutine flushes last-used timestamps and drops
+// keys changed by other replicas from the cache until Close is called.
+func NewAuthService(apiKeyRepo repositories.APIKeyStore, orgRepo repositories.OrganizationStore, salt string, cacheTTL time.Duration) *AuthService {
+	s := &AuthService{
 		apiKeyRepo: apiKeyRepo,
+		orgRepo:    orgRepo,
+		salt:       salt,
+		cache:      newAPIKeyCache(cacheTTL),
+		lastUsed:   newLastUsedRecorder(),
+		lastSync:   time.Now().UTC(),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
 	}
+	go s.backgroundLoop()
+	return s
 }
 
-// CreateAPIKey creates a new API key
-func (s *AuthService) CreateAPIKey(name string, permissions []string, expiresAt *time.Time) (*models.APIKey, string, error) {
-	// Generate API key
-	rawKey, err := utils.GenerateAPIKey()
+// Close stops the background loop and writes pending last-used timestamps
+func (s *AuthService) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.flushLastUsed()
+}
+
+// backgroundLoop periodically writes batched last-used timestamps and syncs
+// revocations made on other replicas
+func (s *AuthService) backgroundLoop() {
+	defer close(s.done)
+
+	flushTicker := time.NewTicker(lastUsedFlushInterval)
+	defer flushTicker.Stop()
+	syncTicker := time.NewTicker(revocationSyncInterval)
+	defer syncTicker.Stop()
+
+	for {
+		select {
+		case <-flushTicker.C:
+			s.flushLastUsed()
+		case <-syncTicker.C:
+			s.syncRevocations()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// flushLastUsed writes the pending last-used timestamps in one batch
+func (s *AuthService) flushLastUsed() error {
+	pending := s.lastUsed.drain()
+	if len(pending) == 0 {
+		return nil
+	}
+	return s.apiKeyRepo.UpdateLastUsedBatch(pending)
+}
+
+// syncRevocations drops keys and organizations changed in the shared store
+// since the last sync from the cache, so revoking, rotating or suspending on
+// one replica takes effect on every replica within revocationSyncInterval.
+// If the store is unreachable the next sync covers the missed window; the
+// cache TTL bounds how long a change can go unnoticed.
+func (s *AuthService) syncRevocations() error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	now := time.Now().UTC()
+	since := s.lastSync.Add(-revocationSyncOverlap)
+
+	apiKeys, err := s.apiKeyRepo.FindUpdatedSince(since)
 	if err != nil {
-		return nil, "", errors.NewInternalError(err)
+		return err
+	}
+	orgs, err := s.orgRepo.FindUpdatedSince(since)
+	if err != nil {
+		return err
+	}
+
+	for _, apiKey := range apiKeys {
+		s.cache.invalidate(apiKey.ID)
+	}
+	for _, org := range orgs {
+		s.cache.invalidateOrganization(org.ID)
+	}
+	s.lastSync = now
+	return nil
+}
+
+// CreateAPIKey creates a new API key
+func (s *AuthService) CreateAPIKey(ctx context.Context, orgID, name string, permissions []string, limits models.APIKeyLimits, expiresAt *time.Time) (*models.APIKey, string, error) {
+	// Reject permissions outside the catalogue so typos don't silently
+	// produce keys that can do nothing
+	for _, permission := range permissions {
+		if !models.IsValidPermission(permission) {
+			return nil, "", errors.NewBadRequestWithDetails("Unknown permission: "+permission, map[string]interface{}{
+				"permission":  permission,
+				"permissions": models.Permissions,
+			})
+		}
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, "", errors.NewBadRequest(err.Error())
 	}
 
-	// Hash the key
-	keyHash, err := utils.HashAPIKey(rawKey)
+	// Generate API key
+	rawKey, err := utils.GenerateAPIKey()
 	if err != nil {
 		return nil, "", errors.NewInternalError(err)
 	}
 
 	// Create API key model
 	apiKey := &models.APIKey{
-		Name:        name,
-		KeyHash:     keyHash,
-		KeyPrefix:   utils.GetKeyPrefix(rawKey),
-		Permissions: permissions,
-		ExpiresAt:   expiresAt,
+		Name:         name,
+		KeyHash:      utils.DigestAPIKey(rawKey, s.salt),
+		KeyPrefix:    utils.GetKeyPrefix(rawKey),
+		Permissions:  permissions,
+		ExpiresAt:    expiresAt,
+		APIKeyLimits: limits,
 	}
 
-	if err := s.apiKeyRepo.Create(apiKey); err != nil {
+	if err := s.apiKeyRepo.WithContext(ctx).ForOrganization(orgID).Create(apiKey); err != nil {
 		return nil, "", errors.NewDatabaseError(err)
 	}
 
@@ -52,46 +162,170 @@ func (s *AuthService) CreateAPIKey(name string, permissions []string, expiresAt
 	return apiKey, rawKey, nil
 }
 
-// ValidateAPIKey validates an API key and returns the key info
-func (s *AuthService) ValidateAPIKey(rawKey string) (*models.APIKey, error) {
-	// Get key prefix for optimization
-	prefix := utils.GetKeyPrefix(rawKey)
+// ValidateAPIKey validates an API key and returns the key info. Keys are
+// looked up across all organizations; the returned key identifies the tenant.
+// Keys of organizations that are suspended or deleted are rejected.
+func (s *AuthService) ValidateAPIKey(ctx context.Context, rawKey string) (*models.APIKey, error) {
+	digest := utils.DigestAPIKey(rawKey, s.salt)
 
-	// Find keys with matching prefix
-	apiKeys, err := s.apiKeyRepo.FindByKeyPrefix(prefix)
-	if err != nil {
-		return nil, errors.NewUnauthorized("Invalid API key")
+	apiKey, ok := s.cache.get(digest)
+	if !ok {
+		var err error
+		apiKey, err = s.findAPIKey(ctx, rawKey, digest)
+		if err != nil {
+			return nil, errors.NewUnauthorized("Invalid API key")
+		}
+
+		// Only keys of active organizations are cached; suspending or
+		// deleting an organization invalidates its cached keys
+		var org models.Organization
+		if err := s.orgRepo.WithContext(ctx).FindByID(apiKey.OrganizationID, &org); err != nil {
+			return nil, errors.NewUnauthorized("Invalid API key")
+		}
+		if !org.IsActive() {
+			return nil, errors.NewForbidden("Organization is suspended")
+		}
+		s.cache.put(digest, apiKey)
 	}
 
-	// Check each key with matching prefix
-	for _, apiKey := range apiKeys {
-		if utils.CompareAPIKey(apiKey.KeyHash, rawKey) {
-			// Check if expired
-			if apiKey.IsExpired() {
-				return nil, errors.NewAppError(errors.ErrAPIKeyExpired, "API key has expired", 401)
-			}
+	// Check if revoked or expired
+	if apiKey.IsRevoked() {
+		return nil, errors.NewAppError(errors.ErrAPIKeyRevoked, "API key has been revoked", 401)
+	}
+	if apiKey.IsExpired() {
+		return nil, errors.NewAppError(errors.ErrAPIKeyExpired, "API key has expired", 401)
+	}
+
+	s.lastUsed.record(apiKey.ID)
+
+	return apiKey, nil
+}
 
-			// Update last used
-			s.apiKeyRepo.UpdateLastUsed(apiKey.ID)
+// findAPIKey loads a key by its digest. Keys created before digests were
+// introduced are stored as bcrypt hashes; they are found by prefix, verified
+// once with bcrypt and then upgraded to a digest.
+func (s *AuthService) findAPIKey(ctx context.Context, rawKey, digest string) (*models.APIKey, error) {
+	apiKey, err := s.apiKeyRepo.WithContext(ctx).FindByKeyHash(digest)
+	if err == nil {
+		return apiKey, nil
+	}
 
-			return apiKey, nil
+	candidates, err := s.apiKeyRepo.WithContext(ctx).FindByKeyPrefix(utils.GetKeyPrefix(rawKey))
+	if err != nil {
+		return nil, err
+	}
+	for _, candidate := range candidates {
+		if utils.IsBcryptHash(candidate.KeyHash) && utils.CompareAPIKey(candidate.KeyHash, rawKey) {
+			if err := s.apiKeyRepo.WithContext(ctx).UpdateKeyHash(candidate.ID, digest); err != nil {
+				return nil, err
+			}
+			candidate.KeyHash = digest
+			return candidate, nil
 		}
 	}
 
 	return nil, errors.NewUnauthorized("Invalid API key")
 }
 
-// RevokeAPIKey revokes an API key
-func (s *AuthService) RevokeAPIKey(keyID string) error {
+// GetAPIKey gets an API key of an organization by ID
+func (s *AuthService) GetAPIKey(ctx context.Context, orgID, keyID string) (*models.APIKey, error) {
 	var apiKey models.APIKey
-	if err := s.apiKeyRepo.FindByID(keyID, &apiKey); err != nil {
-		return errors.NewNotFound("API Key", keyID)
+	if err := s.apiKeyRepo.WithContext(ctx).ForOrganization(orgID).FindByID(keyID, &apiKey); err != nil {
+		return nil, errors.NewNotFound("API Key", keyID)
 	}
+	return &apiKey, nil
+}
+
+// RotateAPIKey issues a replacement for an API key. The old key keeps working
+// until the grace period ends so clients can switch over without downtime.
+func (s *AuthService) RotateAPIKey(ctx context.Context, orgID, keyID string, gracePeriod time.Duration) (*models.APIKey, string, error) {
+	oldKey, err := s.GetAPIKey(ctx, orgID, keyID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !oldKey.IsValid() {
+		return nil, "", errors.NewConflict("Only active API keys can be rotated")
+	}
+
+	newKey, rawKey, err := s.CreateAPIKey(ctx, orgID, oldKey.Name, oldKey.Permissions, oldKey.APIKeyLimits, oldKey.ExpiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	graceEnd := time.Now().UTC().Add(gracePeriod)
+	if oldKey.ExpiresAt != nil && oldKey.ExpiresAt.Before(graceEnd) {
+		graceEnd = *oldKey.ExpiresAt
+	}
+	if err := s.apiKeyRepo.WithContext(ctx).ForOrganization(orgID).UpdateFields(oldKey.ID, oldKey, map[string]interface{}{
+		"expires_at":     graceEnd,
+		"replaced_by_id": newKey.ID,
+	}); err != nil {
+		return nil, "", errors.NewDatabaseError(err)
+	}
+	s.cache.invalidate(oldKey.ID)
+
+	return newKey, rawKey, nil
+}
+
+// UpdateAPIKeyLimits replaces the rate limit and quotas of an API key
+func (s *AuthService) UpdateAPIKeyLimits(ctx context.Context, orgID, keyID string, limits models.APIKeyLimits) (*models.APIKey, error) {
+	if err := limits.Validate(); err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+
+	apiKey, err := s.GetAPIKey(ctx, orgID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.apiKeyRepo.WithContext(ctx).ForOrganization(orgID).UpdateFields(apiKey.ID, apiKey, map[string]interface{}{
+		"rate_limit_per_minute": limits.RateLimitPerMinute,
+		"daily_quota":           limits.DailyQuota,
+		"monthly_quota":         limits.MonthlyQuota,
+	}); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	s.cache.invalidate(apiKey.ID)
+
+	return s.GetAPIKey(ctx, orgID, keyID)
+}
+
+// RevokeAPIKey revokes an API key. Revoked keys are kept so the revocation
+// stays on record.
+func (s *AuthService) RevokeAPIKey(ctx context.Context, orgID, keyID, reason string) error {
+	apiKey, err := s.GetAPIKey(ctx, orgID, keyID)
+	if err != nil {
+		return err
+	}
+	if apiKey.IsRevoked() {
+		return errors.NewConflict("API key is already revoked")
+	}
+
+	if err := s.apiKeyRepo.WithContext(ctx).ForOrganization(orgID).Revoke(apiKey.ID, reason); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	s.cache.invalidate(apiKey.ID)
+	return nil
+}
+
+// RevokeOrganizationKeys revokes all API keys of an organization, e.g. when
+// it is deleted
+func (s *AuthService) RevokeOrganizationKeys(ctx context.Context, orgID, reason string) error {
+	if _, err := s.apiKeyRepo.WithContext(ctx).ForOrganization(orgID).RevokeAll(reason); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	s.cache.invalidateOrganization(orgID)
+	return nil
+}
 
-	return s.apiKeyRepo.Delete(&apiKey)
+// InvalidateOrganization drops the cached keys of an organization so they
+// are checked against the organization's status again, e.g. after it was
+// suspended
+func (s *AuthService) InvalidateOrganization(orgID string) {
+	s.cache.invalidateOrganization(orgID)
 }
 
-// ListAPIKeys lists all API keys
-func (s *AuthService) ListAPIKeys() ([]*models.APIKey, error) {
-	return s.apiKeyRepo.ListAll()
+// ListAPIKeys lists all API keys of an organization
+func (s *AuthService) ListAPIKeys(ctx context.Context, orgID string) ([]*models.APIKey, error) {
+	return s.apiKeyRepo.WithContext(ctx).ForOrganization(orgID).ListAll()
 }
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ContactBlockService blocks and unblocks contacts. MessageService refuses
+// sends to blocked contacts and flags their inbound messages.
+type ContactBlockService struct {
+	contactRepo repositories.ContactStore
+	senders     SenderResolver
+	logger      *zap.Logger
+}
+
+// NewContactBlockService creates a new contact block service. The
+// organization's sender blocks the number on WhatsApp too, when it can.
+func NewContactBlockService(contactRepo repositories.ContactStore, senders SenderResolver, logger *zap.Logger) *ContactBlockService {
+	return &ContactBlockService{
+		contactRepo: contactRepo,
+		senders:     senders,
+		logger:      logger,
+	}
+}
+
+// BlockContact blocks the contact with ID contactID for reason, on behalf
+// of the API key actorID. Blocking a blocked contact retries the WhatsApp
+// block and replaces the reason, when one is given.
+func (s *ContactBlockService) BlockContact(ctx context.Context, orgID, contactID, reason, actorID string) (*models.ContactBlockResult, error) {
+	reason = strings.TrimSpace(reason)
+	if utf8.RuneCountInString(reason) > models.MaxBlockReasonLength {
+		return nil, errors.NewBadRequest(fmt.Sprintf("reason must be at most %d characters", models.MaxBlockReasonLength))
+	}
+	return s.setBlocked(ctx, orgID, contactID, true, reason, actorID)
+}
+
+// UnblockContact lets the contact with ID contactID be messaged again
+func (s *ContactBlockService) UnblockContact(ctx context.Context, orgID, contactID, actorID string) (*models.ContactBlockResult, error) {
+	return s.setBlocked(ctx, orgID, contactID, false, "", actorID)
+}
+
+func (s *ContactBlockService) setBlocked(ctx context.Context, orgID, contactID string, blocked bool, reason, actorID string) (*models.ContactBlockResult, error) {
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+
+	var contact models.Contact
+	if err := contactRepo.FindByID(contactID, &contact); err != nil {
+		return nil, errors.NewNotFound("Contact", contactID)
+	}
+
+	contact.Blocked = blocked
+	if !blocked {
+		contact.BlockedAt, contact.BlockReason = nil, ""
+	} else {
+		if contact.BlockedAt == nil {
+			now := time.Now().UTC()
+			contact.BlockedAt = &now
+		}
+		if reason != "" {
+			contact.BlockReason = reason
+		}
+	}
+
+	result := &models.ContactBlockResult{}
+	if err := s.updateWhatsApp(ctx, orgID, contact.PhoneNumber, blocked); err != nil {
+		result.WhatsAppError = err.Error()
+		logger.FromContext(ctx, s.logger).Warn("Failed to update the WhatsApp block list",
+			zap.String("contact_id", contactID),
+			zap.Bool("blocked", blocked),
+			zap.Error(err),
+		)
+	} else {
+		result.WhatsAppUpdated = true
+	}
+
+	action := models.AuditActionContactUnblock
+	if blocked {
+		action = models.AuditActionContactBlock
+	}
+	audit := &models.AuditLog{
+		Action:       action,
+		ResourceType: "contact",
+		ResourceID:   contact.ID,
+		ActorID:      actorID,
+		Details: models.JSONMap{
+			"phone_number":     contact.PhoneNumber,
+			"whatsapp_updated": result.WhatsAppUpdated,
+		},
+	}
+	if reason != "" {
+		audit.Details["reason"] = reason
+	}
+	if result.WhatsAppError != "" {
+		audit.Details["whatsapp_error"] = result.WhatsAppError
+	}
+	if err := contactRepo.SetBlocked(&contact, audit); err != nil {
+		logger.FromContext(ctx, s.logger).Error("Failed to save contact block", zap.String("contact_id", contactID), zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	var saved models.Contact
+	if err := contactRepo.FindByID(contactID, &saved); err != nil {
+		return nil, errors.NewNotFound("Contact", contactID)
+	}
+	if err := contactRepo.LoadTags([]*models.Contact{&saved}); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	logger.FromContext(ctx, s.logger).Info("Contact block updated",
+		zap.String("contact_id", contactID),
+		zap.Bool("blocked", blocked),
+		zap.Bool("whatsapp_updated", result.WhatsAppUpdated),
+	)
+	result.Contact = &saved
+	result.AuditLogID = audit.ID
+	return result, nil
+}
+
+// updateWhatsApp blocks or unblocks phone on WhatsApp, when the
+// organization's sender supports it
+func (s *ContactBlockService) updateWhatsApp(ctx context.Context, orgID, phone string, blocked bool) error {
+	sender, err := s.senders.Sender(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	blocker, ok := sender.(UserBlocker)
+	if !ok {
+		return fmt.Errorf("the WhatsApp sender cannot block users")
+	}
+	if blocked {
+		return blocker.BlockUsers(ctx, []string{phone})
+	}
+	return blocker.UnblockUsers(ctx, []string{phone})
+}
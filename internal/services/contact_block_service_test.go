@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func TestBlockContact(t *testing.T) {
+	f := newMessageServiceFixture()
+	blocks := NewContactBlockService(f.contacts, f.senders, zap.NewNop())
+	ctx := context.Background()
+
+	question := &whatsapp.MessageEvent{MessageID: "wamid.in1", From: "14155550100", Type: "text", Content: "hi", Timestamp: time.Now().UTC()}
+	if err := f.service.ProcessIncomingMessage(ctx, "org_a", question); err != nil {
+		t.Fatalf("ProcessIncomingMessage: %v", err)
+	}
+	contact, _ := f.contacts.ForOrganization("org_a").FindByPhone("14155550100")
+	f.contacts.ForOrganization("org_a").ResetUnreadCount(contact.PhoneNumber)
+
+	result, err := blocks.BlockContact(ctx, "org_a", contact.ID, " spam ", "key_1")
+	if err != nil {
+		t.Fatalf("BlockContact: %v", err)
+	}
+	if !result.Contact.Blocked || result.Contact.BlockedAt == nil || result.Contact.BlockReason != "spam" || !result.WhatsAppUpdated {
+		t.Errorf("unexpected result %+v %+v", result, result.Contact)
+	}
+	if len(f.sender.sends) != 1 || f.sender.sends[0].kind != "block" || f.sender.sends[0].to != "14155550100" {
+		t.Errorf("the number should be blocked on WhatsApp, sent %+v", f.sender.sends)
+	}
+	audits := f.contacts.data.audits
+	if len(audits) != 1 || audits[0].ID != result.AuditLogID || audits[0].Action != models.AuditActionContactBlock ||
+		audits[0].ActorID != "key_1" || audits[0].Details["reason"] != "spam" {
+		t.Errorf("the block should be audited: %+v", audits)
+	}
+
+	// Every kind of send is refused before reaching WhatsApp
+	stored, _ := f.messages.ForOrganization("org_a").FindByWhatsAppMessageIDs([]string{"wamid.in1"})
+	_, textErr := f.service.SendTextMessage(ctx, "org_a", "+14155550100", "hello", "")
+	_, templateErr := f.service.SendTemplateMessage(ctx, "org_a", "+14155550100", "welcome", "en", nil, "")
+	_, reactionErr := f.service.SendReaction(ctx, "org_a", "+14155550100", stored[0].ID, "👍")
+	for _, err := range []error{textErr, templateErr, reactionErr} {
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrContactBlocked || appErr.StatusCode != 403 {
+			t.Errorf("sends to a blocked contact should be refused, got %v", err)
+		}
+	}
+	if len(f.sender.sends) != 1 {
+		t.Errorf("refused sends must not reach WhatsApp: %+v", f.sender.sends)
+	}
+
+	// Inbound messages are kept, flagged, and do not wait for a reply
+	spam := &whatsapp.MessageEvent{MessageID: "wamid.in2", From: "14155550100", Type: "text", Content: "buy now", Timestamp: time.Now().UTC()}
+	if err := f.service.ProcessIncomingMessage(ctx, "org_a", spam); err != nil {
+		t.Fatalf("ProcessIncomingMessage: %v", err)
+	}
+	stored, _ = f.messages.ForOrganization("org_a").FindByWhatsAppMessageIDs([]string{"wamid.in2"})
+	if len(stored) != 1 || !stored[0].Blocked {
+		t.Errorf("the message should be stored flagged: %+v", stored)
+	}
+	contact, _ = f.contacts.ForOrganization("org_a").FindByPhone("14155550100")
+	if contact.UnreadCount != 0 || contact.MessageCount != 2 {
+		t.Errorf("blocked messages should not be unread: %+v", contact)
+	}
+
+	result, err = blocks.UnblockContact(ctx, "org_a", contact.ID, "key_1")
+	if err != nil {
+		t.Fatalf("UnblockContact: %v", err)
+	}
+	if result.Contact.Blocked || result.Contact.BlockedAt != nil || result.Contact.BlockReason != "" {
+		t.Errorf("the contact should be unblocked: %+v", result.Contact)
+	}
+	if f.sender.sends[1].kind != "unblock" || f.contacts.data.audits[1].Action != models.AuditActionContactUnblock {
+		t.Errorf("the unblock should reach WhatsApp and be audited")
+	}
+
+	// Auto mark-read skips the flagged message
+	f.service.SetAutoMarkRead(true)
+	if _, err := f.service.SendTextMessage(ctx, "org_a", "+14155550100", "hello again", ""); err != nil {
+		t.Fatalf("SendTextMessage after unblocking: %v", err)
+	}
+	for _, send := range f.sender.sends {
+		if send.kind == "read" {
+			t.Errorf("messages from blocked contacts should not be marked as read: %+v", send)
+		}
+	}
+}
+
+func TestBlockContactWithoutWhatsApp(t *testing.T) {
+	f := newMessageServiceFixture()
+	blocks := NewContactBlockService(f.contacts, f.senders, zap.NewNop())
+	ctx := context.Background()
+	contact, _ := f.contacts.ForOrganization("org_a").GetOrCreate("14155550100")
+	other, _ := f.contacts.ForOrganization("org_b").GetOrCreate("14155550100")
+
+	f.senders.err = errors.NewForbidden("Organization is suspended")
+	result, err := blocks.BlockContact(ctx, "org_a", contact.ID, "spam", "")
+	if err != nil {
+		t.Fatalf("BlockContact: %v", err)
+	}
+	if !result.Contact.Blocked || result.WhatsAppUpdated || result.WhatsAppError == "" {
+		t.Errorf("the contact should be blocked here only: %+v", result)
+	}
+	if f.contacts.data.audits[0].Details["whatsapp_error"] == nil {
+		t.Errorf("the WhatsApp failure should be audited: %v", f.contacts.data.audits[0].Details)
+	}
+
+	// Blocking again retries WhatsApp and keeps the reason and time
+	f.senders.err = nil
+	again, err := blocks.BlockContact(ctx, "org_a", contact.ID, "", "")
+	if err != nil || !again.WhatsAppUpdated || again.Contact.BlockReason != "spam" || !again.Contact.BlockedAt.Equal(*result.Contact.BlockedAt) {
+		t.Errorf("unexpected second block %+v %v", again, err)
+	}
+
+	if _, err := blocks.BlockContact(ctx, "org_a", contact.ID, strings.Repeat("x", models.MaxBlockReasonLength+1), ""); err == nil {
+		t.Error("overlong reasons should be rejected")
+	}
+	_, err = blocks.BlockContact(ctx, "org_a", other.ID, "", "")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound {
+		t.Errorf("contacts of other organizations should not be found, got %v", err)
+	}
+	if blocked, _ := f.contacts.ForOrganization("org_b").FindByPhone("14155550100"); blocked.Blocked {
+		t.Error("blocks are per organization")
+	}
+}
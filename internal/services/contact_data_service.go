@@ -0,0 +1,531 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/storage"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/logger"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ContactDataService exports, deletes and erases contacts, answering the
+// access and erasure requests of data subjects
+type ContactDataService struct {
+	contactRepo  repositories.ContactStore
+	messageRepo  repositories.MessageStore
+	reactionRepo repositories.ReactionStore
+	callRepo     repositories.CallStore
+	auditRepo    repositories.AuditLogStore
+	media        []storage.MediaStore
+	archive      *MessageArchive
+	logger       *zap.Logger
+}
+
+// NewContactDataService creates a new contact data service. media are the
+// stores media files and call recordings are kept in: the files in them
+// that a contact's messages and calls refer to are exported and erased with
+// the contact, as are the contact's messages in archive. archive is nil
+// when messages are not archived.
+func NewContactDataService(
+	contactRepo repositories.ContactStore,
+	messageRepo repositories.MessageStore,
+	reactionRepo repositories.ReactionStore,
+	callRepo repositories.CallStore,
+	auditRepo repositories.AuditLogStore,
+	media []storage.MediaStore,
+	archive *MessageArchive,
+	logger *zap.Logger,
+) *ContactDataService {
+	return &ContactDataService{
+		contactRepo:  contactRepo,
+		messageRepo:  messageRepo,
+		reactionRepo: reactionRepo,
+		callRepo:     callRepo,
+		auditRepo:    auditRepo,
+		media:        media,
+		archive:      archive,
+		logger:       logger,
+	}
+}
+
+// exportedCall is a call in a contact export, with its transcripts
+type exportedCall struct {
+	*models.Call
+	Transcripts []*exportedTranscript `json:"transcripts"`
+}
+
+// exportedTranscript is a transcript in a contact export, with its segments
+type exportedTranscript struct {
+	*models.Transcript
+	Segments []*models.TranscriptSegment `json:"segments"`
+}
+
+// exportedMedia is an entry of the media manifest of a contact export.
+// File is the path of the media in the archive, when it is stored here;
+// other media is only referred to.
+type exportedMedia struct {
+	MessageID string `json:"message_id,omitempty"`
+	CallID    string `json:"call_id,omitempty"`
+	Reference string `json:"reference"`
+	MimeType  string `json:"mime_type,omitempty"`
+	Filename  string `json:"filename,omitempty"`
+	File      string `json:"file,omitempty"`
+
+	stored *storedFile
+}
+
+// storedFile is a media file in one of the media stores
+type storedFile struct {
+	store storage.MediaStore
+	key   string
+}
+
+// ExportContactData writes a ZIP archive of everything stored about the
+// contact with ID contactID to w:
+//
+//	contact.json     the contact, with its tags and attributes
+//	consent.json     the contact's consent and when it last changed
+//	messages.jsonl           the messages to and from the contact, newest first
+//	reactions.jsonl          the reactions to those messages
+//	archived_messages.jsonl  the archived messages to and from the contact,
+//	                         with their reactions
+//	calls.jsonl              the calls with the contact, with their transcripts
+//	audit_log.jsonl          the audit log entries about the contact
+//	media.json               the media of the messages and calls
+//	media/                   the media files stored here
+//
+// Nothing is written when the contact is not found.
+func (s *ContactDataService) ExportContactData(ctx context.Context, orgID, contactID string, w io.Writer) error {
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+
+	var contact models.Contact
+	if err := contactRepo.FindByID(contactID, &contact); err != nil {
+		return errors.NewNotFound("Contact", contactID)
+	}
+	if err := contactRepo.LoadTags([]*models.Contact{&contact}); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+
+	archive := zip.NewWriter(w)
+	if err := writeJSONEntry(archive, "contact.json", &contact); err != nil {
+		return err
+	}
+	consent := map[string]interface{}{
+		"consent":            contact.Consent,
+		"consent_updated_at": contact.ConsentUpdatedAt,
+	}
+	if err := writeJSONEntry(archive, "consent.json", consent); err != nil {
+		return err
+	}
+
+	media, err := s.exportMessages(ctx, archive, orgID, contact.PhoneNumber)
+	if err != nil {
+		return err
+	}
+	if err := s.exportArchivedMessages(archive, orgID, contact.PhoneNumber); err != nil {
+		return err
+	}
+	callMedia, err := s.exportCalls(ctx, archive, orgID, contact.PhoneNumber)
+	if err != nil {
+		return err
+	}
+	media = append(media, callMedia...)
+	if err := s.exportAuditLog(ctx, archive, orgID, contact.ID); err != nil {
+		return err
+	}
+
+	for _, item := range media {
+		if item.stored == nil {
+			continue
+		}
+		if err := copyFileEntry(archive, item.File, item.stored); err != nil {
+			return err
+		}
+	}
+	if media == nil {
+		media = []*exportedMedia{}
+	}
+	if err := writeJSONEntry(archive, "media.json", media); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// exportMessages writes the messages to and from phone, then the reactions
+// to them, and returns the media they refer to. A ZIP archive is written one
+// file at a time, so the reactions are held until the messages are written.
+func (s *ContactDataService) exportMessages(ctx context.Context, archive *zip.Writer, orgID, phone string) ([]*exportedMedia, error) {
+	messageRepo := s.messageRepo.WithContext(ctx).ForOrganization(orgID)
+	reactionRepo := s.reactionRepo.WithContext(ctx).ForOrganization(orgID)
+
+	messagesFile, err := archive.Create("messages.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	messages := json.NewEncoder(messagesFile)
+	var reactions bytes.Buffer
+	var media []*exportedMedia
+
+	pagination := utils.NewPagination(100, 0)
+	pagination.SkipTotal = true
+	for {
+		page, err := messageRepo.FindByPhone(phone, pagination)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		var targets []string
+		for _, message := range page {
+			if err := messages.Encode(message); err != nil {
+				return nil, err
+			}
+			if message.WhatsAppMessageID != "" {
+				targets = append(targets, message.WhatsAppMessageID)
+			}
+			if message.MediaURL != "" {
+				item := &exportedMedia{MessageID: message.ID, Reference: message.MediaURL, MimeType: message.MediaMimeType, Filename: message.Filename}
+				if stored, ok := s.mediaFile(message.MediaURL); ok {
+					item.stored, item.File = stored, "media/"+message.ID+path.Ext(stored.key)
+				}
+				media = append(media, item)
+			}
+		}
+		pageReactions, err := reactionRepo.FindByTargets(targets)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		for _, reaction := range pageReactions {
+			if err := json.NewEncoder(&reactions).Encode(reaction); err != nil {
+				return nil, err
+			}
+		}
+
+		if !pagination.HasMore || pagination.NextCursor == "" {
+			break
+		}
+		pagination.Cursor = pagination.NextCursor
+	}
+
+	reactionsFile, err := archive.Create("reactions.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := reactions.WriteTo(reactionsFile); err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+// exportArchivedMessages writes the archived messages to and from phone
+func (s *ContactDataService) exportArchivedMessages(archive *zip.Writer, orgID, phone string) error {
+	file, err := archive.Create("archived_messages.jsonl")
+	if err != nil {
+		return err
+	}
+	if s.archive == nil {
+		return nil
+	}
+	encoder := json.NewEncoder(file)
+	err = s.archive.Scan(orgID, func(message *models.ArchivedMessage) error {
+		if !samePhone(message.FromNumber, phone) && !samePhone(message.ToNumber, phone) {
+			return nil
+		}
+		return encoder.Encode(message)
+	})
+	if err != nil {
+		return errors.NewInternalError(fmt.Errorf("failed to read message archives: %w", err))
+	}
+	return nil
+}
+
+// exportCalls writes the calls with phone and their transcripts, and
+// returns their recordings
+func (s *ContactDataService) exportCalls(ctx context.Context, archive *zip.Writer, orgID, phone string) ([]*exportedMedia, error) {
+	calls, transcripts, segments, err := s.findCalls(ctx, orgID, phone)
+	if err != nil {
+		return nil, err
+	}
+
+	bySegments := map[string][]*models.TranscriptSegment{}
+	for _, segment := range segments {
+		bySegments[segment.TranscriptID] = append(bySegments[segment.TranscriptID], segment)
+	}
+	byCall := map[string][]*exportedTranscript{}
+	for _, transcript := range transcripts {
+		byCall[transcript.CallID] = append(byCall[transcript.CallID], &exportedTranscript{
+			Transcript: transcript,
+			Segments:   append([]*models.TranscriptSegment{}, bySegments[transcript.ID]...),
+		})
+	}
+
+	file, err := archive.Create("calls.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(file)
+	var media []*exportedMedia
+	for _, call := range calls {
+		exported := &exportedCall{Call: call, Transcripts: append([]*exportedTranscript{}, byCall[call.ID]...)}
+		if err := encoder.Encode(exported); err != nil {
+			return nil, err
+		}
+		if call.RecordingURL != "" {
+			item := &exportedMedia{CallID: call.ID, Reference: call.RecordingURL}
+			if stored, ok := s.mediaFile(call.RecordingURL); ok {
+				item.stored, item.File = stored, "media/"+call.ID+path.Ext(stored.key)
+			}
+			media = append(media, item)
+		}
+	}
+	return media, nil
+}
+
+// findCalls finds the calls with phone, their transcripts and the
+// transcripts' segments
+func (s *ContactDataService) findCalls(ctx context.Context, orgID, phone string) ([]*models.Call, []*models.Transcript, []*models.TranscriptSegment, error) {
+	callRepo := s.callRepo.WithContext(ctx).ForOrganization(orgID)
+
+	calls, err := callRepo.FindByPhone(phone)
+	if err != nil {
+		return nil, nil, nil, errors.NewDatabaseError(err)
+	}
+	callIDs := make([]string, 0, len(calls))
+	for _, call := range calls {
+		callIDs = append(callIDs, call.ID)
+	}
+	transcripts, err := callRepo.FindTranscripts(callIDs)
+	if err != nil {
+		return nil, nil, nil, errors.NewDatabaseError(err)
+	}
+	transcriptIDs := make([]string, 0, len(transcripts))
+	for _, transcript := range transcripts {
+		transcriptIDs = append(transcriptIDs, transcript.ID)
+	}
+	segments, err := callRepo.FindSegments(transcriptIDs)
+	if err != nil {
+		return nil, nil, nil, errors.NewDatabaseError(err)
+	}
+	return calls, transcripts, segments, nil
+}
+
+// exportAuditLog writes the audit log entries about the contact with ID
+// contactID, newest first
+func (s *ContactDataService) exportAuditLog(ctx context.Context, archive *zip.Writer, orgID, contactID string) error {
+	auditRepo := s.auditRepo.WithContext(ctx).ForOrganization(orgID)
+
+	file, err := archive.Create("audit_log.jsonl")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	filters := map[string]interface{}{"resource_id": contactID}
+	for offset := 0; ; {
+		entries, err := auditRepo.ListWithFilters(filters, utils.NewPagination(100, offset))
+		if err != nil {
+			return errors.NewDatabaseError(err)
+		}
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		if len(entries) < 100 {
+			return nil
+		}
+		offset += len(entries)
+	}
+}
+
+// DeleteContact deletes the contact with ID contactID and its tags on
+// behalf of the API key actorID. Its messages and calls are kept, and a new
+// contact is created when the number messages again; EraseContact removes
+// them too.
+func (s *ContactDataService) DeleteContact(ctx context.Context, orgID, contactID, actorID string) error {
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+
+	var contact models.Contact
+	if err := contactRepo.FindByID(contactID, &contact); err != nil {
+		return errors.NewNotFound("Contact", contactID)
+	}
+	audit := &models.AuditLog{
+		Action:       models.AuditActionContactDelete,
+		ResourceType: "contact",
+		ResourceID:   contact.ID,
+		ActorID:      actorID,
+		Details:      models.JSONMap{"phone_number": contact.PhoneNumber},
+	}
+	if err := contactRepo.DeleteContact(&contact, audit); err != nil {
+		logger.FromContext(ctx, s.logger).Error("Failed to delete contact", zap.String("contact_id", contactID), zap.Error(err))
+		return errors.NewDatabaseError(err)
+	}
+
+	logger.FromContext(ctx, s.logger).Info("Contact deleted", zap.String("contact_id", contactID))
+	return nil
+}
+
+// EraseContact deletes the contact with ID contactID with everything
+// stored about its number: messages, archived messages, reactions, calls,
+// transcripts and media files. Earlier audit log entries about the contact
+// lose their details. A tombstone without personal data records the
+// erasure and the API key actorID that requested it.
+//
+// Media files go first, then archived messages: when they cannot be
+// deleted nothing else is, and the erasure can be retried.
+func (s *ContactDataService) EraseContact(ctx context.Context, orgID, contactID, actorID string) (*models.ContactErasureResult, error) {
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+
+	var contact models.Contact
+	if err := contactRepo.FindByID(contactID, &contact); err != nil {
+		return nil, errors.NewNotFound("Contact", contactID)
+	}
+
+	files, err := s.storedMedia(ctx, orgID, contact.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := file.store.Delete(file.key); err != nil {
+			logger.FromContext(ctx, s.logger).Error("Failed to delete media file", zap.String("contact_id", contactID), zap.Error(err))
+			return nil, errors.NewInternalError(fmt.Errorf("failed to delete media file: %w", err))
+		}
+	}
+	archived, err := s.eraseArchivedMessages(orgID, contact.PhoneNumber)
+	if err != nil {
+		logger.FromContext(ctx, s.logger).Error("Failed to erase archived messages", zap.String("contact_id", contactID), zap.Error(err))
+		return nil, errors.NewInternalError(fmt.Errorf("failed to erase archived messages: %w", err))
+	}
+
+	erasure := &models.ContactErasure{
+		ActorID:                actorID,
+		MediaFilesErased:       int64(len(files)),
+		ArchivedMessagesErased: archived,
+	}
+	audit := &models.AuditLog{
+		Action:       models.AuditActionContactErase,
+		ResourceType: "contact",
+		ResourceID:   contact.ID,
+		ActorID:      actorID,
+	}
+	if err := contactRepo.Erase(&contact, erasure, audit); err != nil {
+		logger.FromContext(ctx, s.logger).Error("Failed to erase contact", zap.String("contact_id", contactID), zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	logger.FromContext(ctx, s.logger).Info("Contact erased",
+		zap.String("contact_id", contactID),
+		zap.String("erasure_id", erasure.ID),
+		zap.Int64("messages", erasure.MessagesErased),
+		zap.Int64("archived_messages", erasure.ArchivedMessagesErased),
+		zap.Int64("calls", erasure.CallsErased),
+		zap.Int64("media_files", erasure.MediaFilesErased),
+	)
+	return &models.ContactErasureResult{Erasure: erasure, AuditLogID: audit.ID}, nil
+}
+
+// eraseArchivedMessages removes the archived messages to and from phone,
+// and the reactions phone sent to other archived messages
+func (s *ContactDataService) eraseArchivedMessages(orgID, phone string) (int64, error) {
+	if s.archive == nil {
+		return 0, nil
+	}
+	return s.archive.Filter(orgID, func(message *models.ArchivedMessage) bool {
+		if samePhone(message.FromNumber, phone) || samePhone(message.ToNumber, phone) {
+			return false
+		}
+		reactions := message.Reactions[:0]
+		for _, reaction := range message.Reactions {
+			if !samePhone(reaction.FromNumber, phone) {
+				reactions = append(reactions, reaction)
+			}
+		}
+		message.Reactions = reactions
+		return true
+	})
+}
+
+// storedMedia returns the media files stored here that the messages and
+// calls with phone refer to
+func (s *ContactDataService) storedMedia(ctx context.Context, orgID, phone string) ([]*storedFile, error) {
+	var references []string
+
+	messageRepo := s.messageRepo.WithContext(ctx).ForOrganization(orgID)
+	pagination := utils.NewPagination(100, 0)
+	pagination.SkipTotal = true
+	for {
+		page, err := messageRepo.FindByPhone(phone, pagination)
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		for _, message := range page {
+			references = append(references, message.MediaURL)
+		}
+		if !pagination.HasMore || pagination.NextCursor == "" {
+			break
+		}
+		pagination.Cursor = pagination.NextCursor
+	}
+	calls, _, _, err := s.findCalls(ctx, orgID, phone)
+	if err != nil {
+		return nil, err
+	}
+	for _, call := range calls {
+		references = append(references, call.RecordingURL)
+	}
+
+	seen := map[storedFile]bool{}
+	var files []*storedFile
+	for _, reference := range references {
+		if file, ok := s.mediaFile(reference); ok && !seen[*file] {
+			seen[*file] = true
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// mediaFile returns the stored file a media reference names, when it is
+// in one of the media stores
+func (s *ContactDataService) mediaFile(reference string) (*storedFile, bool) {
+	for _, store := range s.media {
+		if key, ok := store.Key(reference); ok {
+			return &storedFile{store: store, key: key}, true
+		}
+	}
+	return nil, false
+}
+
+// writeJSONEntry writes v to the archive as an indented JSON file
+func writeJSONEntry(archive *zip.Writer, name string, v interface{}) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// copyFileEntry copies a stored file into the archive as name
+func copyFileEntry(archive *zip.Writer, name string, stored *storedFile) error {
+	source, err := stored.store.Open(stored.key)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, source)
+	return err
+}
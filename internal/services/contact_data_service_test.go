@@ -0,0 +1,217 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/storage"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type contactDataFixture struct {
+	*messageServiceFixture
+	calls   *memCallStore
+	archive *MessageArchive
+	service *ContactDataService
+	// mediaDir and recordingsDir are the media directories
+	mediaDir      string
+	recordingsDir string
+	contact       *models.Contact
+}
+
+// newContactDataFixture stores a contact with messages, a reaction, a call
+// and archived messages, with media stored in both media directories and a
+// link
+func newContactDataFixture(t *testing.T) *contactDataFixture {
+	t.Helper()
+	f := &contactDataFixture{
+		messageServiceFixture: newMessageServiceFixture(),
+		calls:                 newMemCallStore(),
+		mediaDir:              t.TempDir(),
+		recordingsDir:         t.TempDir(),
+	}
+	media := storage.NewLocalStore(f.mediaDir)
+	f.archive = NewMessageArchive(media)
+	f.service = NewContactDataService(f.contacts, f.messages, f.reactions, f.calls, &memAuditLogStore{data: f.contacts.data},
+		[]storage.MediaStore{media, storage.NewLocalStore(f.recordingsDir)}, f.archive, zap.NewNop())
+
+	os.WriteFile(filepath.Join(f.mediaDir, "photo.jpg"), []byte("jpeg"), 0o644)
+	os.WriteFile(filepath.Join(f.recordingsDir, "call_1.ogg"), []byte("ogg"), 0o644)
+	os.WriteFile(filepath.Join(filepath.Dir(f.mediaDir), "outside.jpg"), []byte("not ours"), 0o644)
+
+	contacts := f.contacts.ForOrganization("org_a")
+	f.contact, _ = contacts.GetOrCreate("14155550100")
+	contacts.UpdateFields(f.contact.ID, &models.Contact{}, map[string]interface{}{"name": "Ada", "consent": "opted_in"})
+	contacts.AddTags([]string{f.contact.ID}, []string{"vip"})
+
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	messages := f.messages.ForOrganization("org_a")
+	for _, m := range []*models.Message{
+		{WhatsAppMessageID: "wamid.1", FromNumber: "14155550100", ToNumber: "15550000000", Direction: "inbound", MessageType: "image", MediaURL: "photo.jpg", MediaMimeType: "image/jpeg", Timestamp: at},
+		{WhatsAppMessageID: "wamid.2", FromNumber: "15550000000", ToNumber: "+14155550100", Direction: "outbound", MessageType: "image", MediaURL: "https://example.com/a.png", Timestamp: at.Add(time.Minute)},
+		{WhatsAppMessageID: "wamid.3", FromNumber: "14155550100", ToNumber: "15550000000", Direction: "inbound", MessageType: "image", MediaURL: "../outside.jpg", Timestamp: at.Add(2 * time.Minute)},
+		{WhatsAppMessageID: "wamid.4", FromNumber: "14155550101", ToNumber: "15550000000", Direction: "inbound", MessageType: "text", Content: "someone else", Timestamp: at},
+	} {
+		messages.Create(m)
+	}
+	f.reactions.ForOrganization("org_a").Upsert(&models.Reaction{TargetWhatsAppMessageID: "wamid.2", FromNumber: "14155550100", Direction: "inbound", Emoji: "👍", Timestamp: at})
+	f.calls.data.calls = append(f.calls.data.calls,
+		&models.Call{ID: "call_1", OrganizationID: "org_a", FromNumber: "+14155550100", ToNumber: "15550000000", RecordingURL: filepath.Join(f.recordingsDir, "call_1.ogg"), StartedAt: at},
+		&models.Call{ID: "call_2", OrganizationID: "org_b", FromNumber: "14155550100", ToNumber: "15550000001", StartedAt: at},
+	)
+	f.calls.data.transcripts = append(f.calls.data.transcripts, &models.Transcript{ID: "transcript_1", OrganizationID: "org_a", CallID: "call_1", Content: "hello"})
+	f.calls.data.segments = append(f.calls.data.segments, &models.TranscriptSegment{ID: "segment_1", OrganizationID: "org_a", TranscriptID: "transcript_1", Content: "hello"})
+	f.contacts.data.audits = append(f.contacts.data.audits, &models.AuditLog{
+		ID: "audit_1", OrganizationID: "org_a", Action: models.AuditActionContactBlock, ResourceType: "contact", ResourceID: f.contact.ID,
+		Details: models.JSONMap{"phone_number": "14155550100"},
+	})
+	f.archive.Write("org_a", []*models.ArchivedMessage{
+		{Message: &models.Message{ID: "msg_old", FromNumber: "+14155550100", ToNumber: "15550000000", MessageType: "text", Content: "archived hello", Timestamp: at.AddDate(-1, 0, 0)}},
+		{Message: &models.Message{ID: "msg_other", FromNumber: "15550000000", ToNumber: "14155550101", MessageType: "text", Content: "archived other", Timestamp: at.AddDate(-1, 0, 0)},
+			Reactions: []*models.Reaction{{TargetWhatsAppMessageID: "wamid.0", FromNumber: "14155550100", Emoji: "👍"}}},
+	})
+	return f
+}
+
+func TestExportContactData(t *testing.T) {
+	f := newContactDataFixture(t)
+
+	var buf bytes.Buffer
+	if err := f.service.ExportContactData(context.Background(), "org_a", f.contact.ID, &buf); err != nil {
+		t.Fatalf("ExportContactData: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("the export should be a ZIP archive: %v", err)
+	}
+	files := map[string]string{}
+	for _, file := range archive.File {
+		r, _ := file.Open()
+		content, _ := io.ReadAll(r)
+		r.Close()
+		files[file.Name] = string(content)
+	}
+
+	var contact models.Contact
+	if err := json.Unmarshal([]byte(files["contact.json"]), &contact); err != nil || contact.Name != "Ada" || len(contact.Tags) != 1 {
+		t.Errorf("unexpected contact.json %s", files["contact.json"])
+	}
+	if !strings.Contains(files["consent.json"], `"consent": "opted_in"`) {
+		t.Errorf("unexpected consent.json %s", files["consent.json"])
+	}
+	if lines := strings.Count(files["messages.jsonl"], "\n"); lines != 3 || strings.Contains(files["messages.jsonl"], "someone else") {
+		t.Errorf("expected the contact's 3 messages, got %s", files["messages.jsonl"])
+	}
+	if strings.Count(files["reactions.jsonl"], "\n") != 1 {
+		t.Errorf("expected the reaction, got %s", files["reactions.jsonl"])
+	}
+	if archived := files["archived_messages.jsonl"]; strings.Count(archived, "\n") != 1 || !strings.Contains(archived, "archived hello") {
+		t.Errorf("expected the contact's archived message, got %s", archived)
+	}
+	var call exportedCall
+	if err := json.Unmarshal([]byte(files["calls.jsonl"]), &call); err != nil || call.ID != "call_1" ||
+		len(call.Transcripts) != 1 || len(call.Transcripts[0].Segments) != 1 {
+		t.Errorf("expected the organization's call with its transcript, got %s", files["calls.jsonl"])
+	}
+	if !strings.Contains(files["audit_log.jsonl"], models.AuditActionContactBlock) {
+		t.Errorf("expected the audit log entries, got %s", files["audit_log.jsonl"])
+	}
+
+	var media []*exportedMedia
+	if err := json.Unmarshal([]byte(files["media.json"]), &media); err != nil || len(media) != 4 {
+		t.Fatalf("expected 4 media, got %s", files["media.json"])
+	}
+	included := map[string]string{}
+	for _, item := range media {
+		included[item.Reference] = item.File
+	}
+	photo, recording := included["photo.jpg"], included[filepath.Join(f.recordingsDir, "call_1.ogg")]
+	if files[photo] != "jpeg" || files[recording] != "ogg" {
+		t.Errorf("stored media should be included: %v", included)
+	}
+	if included["https://example.com/a.png"] != "" || included["../outside.jpg"] != "" {
+		t.Errorf("media not stored in the media directories should only be listed: %v", included)
+	}
+
+	err = f.service.ExportContactData(context.Background(), "org_b", f.contact.ID, io.Discard)
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound {
+		t.Errorf("contacts of other organizations should not be found, got %v", err)
+	}
+}
+
+func TestEraseContact(t *testing.T) {
+	f := newContactDataFixture(t)
+	ctx := context.Background()
+
+	result, err := f.service.EraseContact(ctx, "org_a", f.contact.ID, "key_1")
+	if err != nil {
+		t.Fatalf("EraseContact: %v", err)
+	}
+	erasure := result.Erasure
+	if erasure.ID == "" || erasure.ContactID != f.contact.ID || erasure.ActorID != "key_1" || erasure.MediaFilesErased != 2 ||
+		erasure.ArchivedMessagesErased != 1 {
+		t.Errorf("unexpected erasure %+v", erasure)
+	}
+	var archived []*models.ArchivedMessage
+	f.archive.Scan("org_a", func(message *models.ArchivedMessage) error {
+		archived = append(archived, message)
+		return nil
+	})
+	if len(archived) != 1 || archived[0].ID != "msg_other" || len(archived[0].Reactions) != 0 {
+		t.Errorf("the contact's archived messages and reactions should be erased, got %+v", archived)
+	}
+	for _, path := range []string{filepath.Join(f.mediaDir, "photo.jpg"), filepath.Join(f.recordingsDir, "call_1.ogg")} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should be deleted", path)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(f.mediaDir), "outside.jpg")); err != nil {
+		t.Error("files outside the media directories must not be deleted")
+	}
+
+	if _, err := f.contacts.ForOrganization("org_a").FindByPhone("14155550100"); err == nil {
+		t.Error("the contact should be deleted")
+	}
+	audits := f.contacts.data.audits
+	if len(audits) != 2 || audits[0].Details["phone_number"] != nil || audits[1].ID != result.AuditLogID ||
+		audits[1].Action != models.AuditActionContactErase || audits[1].ActorID != "key_1" {
+		t.Errorf("unexpected audit log %+v", audits)
+	}
+
+	_, err = f.service.EraseContact(ctx, "org_a", f.contact.ID, "key_1")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound {
+		t.Errorf("erased contacts should not be found, got %v", err)
+	}
+}
+
+func TestDeleteContact(t *testing.T) {
+	f := newContactDataFixture(t)
+	ctx := context.Background()
+
+	err := f.service.DeleteContact(ctx, "org_b", f.contact.ID, "key_1")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound {
+		t.Errorf("contacts of other organizations should not be found, got %v", err)
+	}
+	if err := f.service.DeleteContact(ctx, "org_a", f.contact.ID, "key_1"); err != nil {
+		t.Fatalf("DeleteContact: %v", err)
+	}
+	if _, err := f.contacts.ForOrganization("org_a").FindByPhone("14155550100"); err == nil {
+		t.Error("the contact should be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(f.mediaDir, "photo.jpg")); err != nil {
+		t.Error("deleting a contact keeps its media")
+	}
+	if last := f.contacts.data.audits[len(f.contacts.data.audits)-1]; last.Action != models.AuditActionContactDelete || last.ActorID != "key_1" {
+		t.Errorf("the deletion should be audited: %+v", last)
+	}
+}
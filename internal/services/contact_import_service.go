@@ -0,0 +1,689 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/phonenumber"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxImportFileSize is the largest file a contact import accepts
+	MaxImportFileSize = 10 << 20
+
+	// MaxImportRows is the most rows a contact import accepts
+	MaxImportRows = 50000
+
+	// importProgressInterval is how many rows are processed between
+	// progress updates
+	importProgressInterval = 100
+
+	// importHeartbeatInterval is how often a running import records that
+	// its process is still alive
+	importHeartbeatInterval = 30 * time.Second
+
+	// importStaleAfter is how long an import may go without a heartbeat
+	// before it is considered interrupted
+	importStaleAfter = 2 * time.Minute
+)
+
+// Import mapping targets. Columns can also be mapped to
+// "attributes.<name>", a declared attribute.
+const (
+	importTargetPhoneNumber = "phone_number"
+	importTargetName        = "name"
+	importTargetConsent     = "consent"
+	importTargetTags        = "tags"
+	importTargetMetadata    = "metadata"
+	importTargetAttribute   = "attributes."
+)
+
+// ContactImportService imports contacts from CSV or JSONL files. Files are
+// parsed when the import is started and their rows processed in the
+// background.
+type ContactImportService struct {
+	importRepo       repositories.ContactImportStore
+	contactRepo      repositories.ContactStore
+	attributeService *AttributeService
+	logger           *zap.Logger
+
+	// owner identifies this process on the imports it runs
+	owner string
+
+	// Imports run with ctx, which Close cancels
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewContactImportService creates a new contact import service. Imports
+// run until they finish or Close is called.
+func NewContactImportService(importRepo repositories.ContactImportStore, contactRepo repositories.ContactStore, attributeService *AttributeService, logger *zap.Logger) *ContactImportService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ContactImportService{
+		importRepo:       importRepo,
+		contactRepo:      contactRepo,
+		attributeService: attributeService,
+		logger:           logger,
+		owner:            utils.GenerateID("worker"),
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+}
+
+// importRow is a row of an import file, keyed by column. Rows that could
+// not be read have an error instead.
+type importRow struct {
+	line   int
+	values map[string]interface{}
+	err    error
+}
+
+// StartImport parses an import file and processes its rows in the
+// background. mapping maps file columns to contact fields; without one,
+// columns named like a contact field are imported. Phone numbers without a
+// calling code are read as numbers of country, when it is set. Only one
+// import per organization runs at a time, across all replicas.
+func (s *ContactImportService) StartImport(ctx context.Context, orgID, format, country string, mapping map[string]string, data []byte) (*models.ContactImport, error) {
+	if len(data) > MaxImportFileSize {
+		return nil, errors.NewBadRequest(fmt.Sprintf("Import files can be at most %d MB", MaxImportFileSize>>20))
+	}
+	if country != "" && !phonenumber.SupportedRegion(country) {
+		return nil, errors.NewBadRequest(fmt.Sprintf("Unknown country %q", country))
+	}
+
+	var columns []string
+	var rows []importRow
+	var err error
+	switch format {
+	case models.ImportFormatCSV:
+		columns, rows, err = parseImportCSV(data)
+	case models.ImportFormatJSONL:
+		columns, rows, err = parseImportJSONL(data)
+	default:
+		return nil, errors.NewBadRequest(fmt.Sprintf("format must be %s or %s", models.ImportFormatCSV, models.ImportFormatJSONL))
+	}
+	if err != nil {
+		return nil, errors.NewBadRequest("Invalid import file: " + err.Error())
+	}
+	if len(rows) == 0 {
+		return nil, errors.NewBadRequest("The import file has no rows")
+	}
+	if len(rows) > MaxImportRows {
+		return nil, errors.NewBadRequest(fmt.Sprintf("Import files can have at most %d rows", MaxImportRows))
+	}
+
+	definitions, err := s.attributeService.definitions(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	mapping, err = resolveImportMapping(columns, mapping, definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	importRepo := s.importRepo.WithContext(ctx).ForOrganization(orgID)
+	active, err := importRepo.FindByStatus(models.ImportStatusPending, models.ImportStatusRunning)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	// An import whose process died would block the organization for good
+	active, err = s.failStale(ctx, active)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	if len(active) > 0 {
+		return nil, errors.NewConflict("Import " + active[0].ID + " is still running")
+	}
+
+	stored := make(models.JSONMap, len(mapping))
+	for column, target := range mapping {
+		stored[column] = target
+	}
+	now := time.Now().UTC()
+	job := &models.ContactImport{
+		Format:      format,
+		Mapping:     stored,
+		Status:      models.ImportStatusPending,
+		TotalRows:   len(rows),
+		Owner:       s.owner,
+		HeartbeatAt: &now,
+	}
+	if err := importRepo.Create(job); err != nil {
+		// The database allows one active import per organization, so this
+		// fails when another replica started one since the check above
+		if active, findErr := importRepo.FindByStatus(models.ImportStatusPending, models.ImportStatusRunning); findErr == nil && len(active) > 0 {
+			return nil, errors.NewConflict("Import " + active[0].ID + " is still running")
+		}
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(orgID, country, job, mapping, definitions, rows)
+	}()
+	return job, nil
+}
+
+// GetImport gets an import by ID
+func (s *ContactImportService) GetImport(ctx context.Context, orgID, importID string) (*models.ContactImport, error) {
+	var job models.ContactImport
+	if err := s.importRepo.WithContext(ctx).ForOrganization(orgID).FindByID(importID, &job); err != nil {
+		return nil, errors.NewNotFound("Import", importID)
+	}
+	return &job, nil
+}
+
+// ListImports lists imports, newest first
+func (s *ContactImportService) ListImports(ctx context.Context, orgID string, pagination *utils.Pagination) ([]*models.ContactImport, error) {
+	imports, err := s.importRepo.WithContext(ctx).ForOrganization(orgID).ListAll(pagination)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return imports, nil
+}
+
+// FailInterrupted marks the pending or running imports whose process
+// stopped sending heartbeats as failed, e.g. after a crash or restart.
+// Imports other replicas are still running are left alone. Rows the failed
+// imports imported are kept.
+func (s *ContactImportService) FailInterrupted(ctx context.Context) error {
+	active, err := s.importRepo.WithContext(ctx).FindByStatus(models.ImportStatusPending, models.ImportStatusRunning)
+	if err != nil {
+		return err
+	}
+	_, err = s.failStale(ctx, active)
+	return err
+}
+
+// failStale fails the imports among active that have not had a heartbeat
+// for importStaleAfter and returns the others
+func (s *ContactImportService) failStale(ctx context.Context, active []*models.ContactImport) ([]*models.ContactImport, error) {
+	importRepo := s.importRepo.WithContext(ctx)
+	now := time.Now().UTC()
+	var live []*models.ContactImport
+	for _, job := range active {
+		if job.HeartbeatAt != nil && now.Sub(*job.HeartbeatAt) < importStaleAfter {
+			live = append(live, job)
+			continue
+		}
+		err := importRepo.UpdateFields(job.ID, job, map[string]interface{}{
+			"status":       models.ImportStatusFailed,
+			"error":        "The import was interrupted by a restart",
+			"completed_at": now,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return live, nil
+}
+
+// heartbeat records that the import is still running until stop is closed
+func (s *ContactImportService) heartbeat(importRepo repositories.ContactImportStore, importID string, stop <-chan struct{}) {
+	ticker := time.NewTicker(importHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := importRepo.UpdateFields(importID, &models.ContactImport{}, map[string]interface{}{"heartbeat_at": time.Now().UTC()}); err != nil {
+				s.logger.Warn("Failed to record contact import heartbeat", zap.String("import_id", importID), zap.Error(err))
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Close stops running imports and waits for them to record where they
+// stopped
+func (s *ContactImportService) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// importResult counts the processed rows of an import
+type importResult struct {
+	processed, created, updated, failed int
+	errors                              models.ImportRowErrors
+}
+
+func (r *importResult) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"processed_rows": r.processed,
+		"created":        r.created,
+		"updated":        r.updated,
+		"failed":         r.failed,
+		"errors":         r.errors,
+	}
+}
+
+// run processes the rows of an import, recording its progress
+func (s *ContactImportService) run(orgID, country string, job *models.ContactImport, mapping map[string]string, definitions map[string]*models.AttributeDefinition, rows []importRow) {
+	ctx := s.ctx
+	importRepo := s.importRepo.WithContext(ctx).ForOrganization(orgID)
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+	log := s.logger.With(zap.String("import_id", job.ID), zap.String("organization_id", orgID))
+
+	started := time.Now().UTC()
+	if err := importRepo.UpdateFields(job.ID, job, map[string]interface{}{"status": models.ImportStatusRunning, "started_at": started}); err != nil {
+		log.Error("Failed to start contact import", zap.Error(err))
+	}
+
+	stopHeartbeat := make(chan struct{})
+	defer close(stopHeartbeat)
+	go s.heartbeat(importRepo, job.ID, stopHeartbeat)
+
+	var result importResult
+	for i, row := range rows {
+		if ctx.Err() != nil {
+			break
+		}
+
+		err := row.err
+		created := false
+		if err == nil {
+			created, err = s.importRow(ctx, contactRepo, country, mapping, definitions, row.values)
+		}
+		result.processed++
+		switch {
+		case err != nil:
+			result.failed++
+			if len(result.errors) < models.MaxImportRowErrors {
+				result.errors = append(result.errors, models.ImportRowError{Row: row.line, Error: importErrorMessage(err)})
+			}
+		case created:
+			result.created++
+		default:
+			result.updated++
+		}
+
+		if (i+1)%importProgressInterval == 0 {
+			if err := importRepo.UpdateFields(job.ID, job, result.fields()); err != nil {
+				log.Error("Failed to record contact import progress", zap.Error(err))
+			}
+		}
+	}
+
+	fields := result.fields()
+	fields["status"] = models.ImportStatusCompleted
+	fields["completed_at"] = time.Now().UTC()
+	if ctx.Err() != nil {
+		// The service is closing; record the progress without its context
+		ctx = context.Background()
+		importRepo = s.importRepo.WithContext(ctx).ForOrganization(orgID)
+		fields["status"] = models.ImportStatusFailed
+		fields["error"] = "The import was interrupted by a shutdown"
+	}
+	if err := importRepo.UpdateFields(job.ID, job, fields); err != nil {
+		log.Error("Failed to complete contact import", zap.Error(err))
+		return
+	}
+	log.Info("Contact import finished",
+		zap.Int("created", result.created),
+		zap.Int("updated", result.updated),
+		zap.Int("failed", result.failed),
+	)
+}
+
+// importRow creates or updates the contact of a row. It reports whether
+// the contact was created.
+func (s *ContactImportService) importRow(ctx context.Context, contactRepo repositories.ContactStore, country string, mapping map[string]string, definitions map[string]*models.AttributeDefinition, values map[string]interface{}) (bool, error) {
+	fields := make(map[string]interface{}, len(mapping))
+	for column, target := range mapping {
+		if value, ok := values[column]; ok {
+			fields[target] = value
+		}
+	}
+
+	raw := importText(fields[importTargetPhoneNumber])
+	number, err := phonenumber.Parse(raw, country)
+	if err != nil && country != "" {
+		// Numbers with their calling code but no +, such as WhatsApp IDs
+		number, err = phonenumber.Parse(raw, "")
+	}
+	if err != nil {
+		return false, fmt.Errorf("phone_number %q is not a valid phone number", raw)
+	}
+
+	// Contacts are stored with numbers as webhooks report them, without a
+	// leading +, unless the contact already exists with one
+	existing, err := contactRepo.FindByPhone(number.WhatsAppID())
+	if err != nil {
+		existing = nil
+	}
+	contact := &models.Contact{PhoneNumber: number.WhatsAppID()}
+	if existing != nil {
+		contact = &models.Contact{
+			PhoneNumber:      existing.PhoneNumber,
+			Name:             existing.Name,
+			ProfileURL:       existing.ProfileURL,
+			Consent:          existing.Consent,
+			ConsentUpdatedAt: existing.ConsentUpdatedAt,
+			Metadata:         existing.Metadata,
+		}
+	}
+
+	if name := importText(fields[importTargetName]); name != "" {
+		if utf8.RuneCountInString(name) > 255 {
+			return false, fmt.Errorf("name is longer than 255 characters")
+		}
+		contact.Name = name
+	}
+
+	if consent := strings.ToLower(importText(fields[importTargetConsent])); consent != "" {
+		if consent != models.ConsentOptedIn && consent != models.ConsentOptedOut {
+			return false, fmt.Errorf("consent must be %q or %q", models.ConsentOptedIn, models.ConsentOptedOut)
+		}
+		if consent != contact.Consent {
+			now := time.Now().UTC()
+			contact.Consent = consent
+			contact.ConsentUpdatedAt = &now
+		}
+	}
+
+	updates, err := importAttributes(fields, definitions)
+	if err != nil {
+		return false, err
+	}
+	if len(updates) > 0 {
+		if contact.Metadata, err = applyAttributes(definitions, contact.Metadata, updates); err != nil {
+			return false, err
+		}
+	}
+
+	tags, err := importTags(fields[importTargetTags])
+	if err != nil {
+		return false, err
+	}
+
+	if err := contactRepo.UpsertContact(contact); err != nil {
+		return false, err
+	}
+
+	if len(tags) > 0 {
+		contactID := ""
+		if existing != nil {
+			contactID = existing.ID
+		} else {
+			// The upsert may have updated a contact created meanwhile
+			stored, err := contactRepo.FindByPhone(contact.PhoneNumber)
+			if err != nil {
+				return false, err
+			}
+			contactID = stored.ID
+		}
+		if err := contactRepo.AddTags([]string{contactID}, tags); err != nil {
+			return false, err
+		}
+	}
+	return existing == nil, nil
+}
+
+// importAttributes collects the attribute updates of a row from its
+// "metadata" object and "attributes.<name>" columns. Text cells are
+// converted to the attribute type and blank ones skipped.
+func importAttributes(fields map[string]interface{}, definitions map[string]*models.AttributeDefinition) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
+	switch metadata := fields[importTargetMetadata].(type) {
+	case nil:
+	case map[string]interface{}:
+		for key, value := range metadata {
+			updates[key] = value
+		}
+	case string:
+		if strings.TrimSpace(metadata) != "" {
+			var decoded map[string]interface{}
+			if err := json.Unmarshal([]byte(metadata), &decoded); err != nil {
+				return nil, fmt.Errorf("metadata must be a JSON object")
+			}
+			for key, value := range decoded {
+				updates[key] = value
+			}
+		}
+	default:
+		return nil, fmt.Errorf("metadata must be an object")
+	}
+
+	for target, value := range fields {
+		name, ok := strings.CutPrefix(target, importTargetAttribute)
+		if !ok {
+			continue
+		}
+		text, isText := value.(string)
+		if !isText {
+			updates[name] = value
+			continue
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		switch definitions[name].Type {
+		case models.AttributeTypeNumber:
+			n, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a number", name)
+			}
+			updates[name] = n
+		case models.AttributeTypeBool:
+			b, err := strconv.ParseBool(text)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be true or false", name)
+			}
+			updates[name] = b
+		default:
+			updates[name] = text
+		}
+	}
+	return updates, nil
+}
+
+// importTags reads the tags of a row, a comma-separated cell or a list
+func importTags(value interface{}) ([]string, error) {
+	var names []string
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		for _, name := range strings.Split(v, ",") {
+			if strings.TrimSpace(name) != "" {
+				names = append(names, name)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("tags must be a list of strings")
+			}
+			names = append(names, name)
+		}
+	default:
+		return nil, fmt.Errorf("tags must be a list of strings")
+	}
+	return normalizeTags(names)
+}
+
+// importText renders a cell as trimmed text. JSONL files may hold phone
+// numbers as numbers.
+func importText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// importErrorMessage returns the message of a row error, without the code
+// of application errors
+func importErrorMessage(err error) string {
+	if appErr, ok := err.(*errors.AppError); ok {
+		return appErr.Message
+	}
+	return err.Error()
+}
+
+// resolveImportMapping checks a column mapping against the file columns
+// and the declared attributes. Without a mapping, columns named like a
+// target are mapped to it.
+func resolveImportMapping(columns []string, mapping map[string]string, definitions map[string]*models.AttributeDefinition) (map[string]string, error) {
+	isTarget := func(target string) bool {
+		switch target {
+		case importTargetPhoneNumber, importTargetName, importTargetConsent, importTargetTags, importTargetMetadata:
+			return true
+		}
+		name, ok := strings.CutPrefix(target, importTargetAttribute)
+		return ok && definitions[name] != nil
+	}
+
+	if len(mapping) == 0 {
+		mapping = make(map[string]string)
+		for _, column := range columns {
+			if isTarget(column) {
+				mapping[column] = column
+			}
+		}
+	}
+
+	known := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		known[column] = true
+	}
+	mapped := make(map[string]string, len(mapping))
+	for column, target := range mapping {
+		if !known[column] {
+			return nil, errors.NewBadRequest(fmt.Sprintf("Column %q is not in the file", column))
+		}
+		if !isTarget(target) {
+			return nil, errors.NewBadRequest(fmt.Sprintf("Column %q is mapped to %q; map columns to phone_number, name, consent, tags, metadata or attributes.<name> of a declared attribute", column, target))
+		}
+		if other, ok := mapped[target]; ok {
+			return nil, errors.NewBadRequest(fmt.Sprintf("Columns %q and %q are both mapped to %s", other, column, target))
+		}
+		mapped[target] = column
+	}
+	if _, ok := mapped[importTargetPhoneNumber]; !ok {
+		return nil, errors.NewBadRequest("A column must be mapped to phone_number")
+	}
+	return mapping, nil
+}
+
+// parseImportCSV reads a CSV file with a header row. Rows are numbered by
+// line, and rows with the wrong number of cells are kept as errors.
+func parseImportCSV(data []byte) ([]string, []importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("the file is empty")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[string]bool, len(header))
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			return nil, nil, fmt.Errorf("column %d has no name", i+1)
+		}
+		if seen[column] {
+			return nil, nil, fmt.Errorf("column %q appears twice", column)
+		}
+		seen[column] = true
+		header[i] = column
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows) >= MaxImportRows {
+			// One extra row tells that the file is too long
+			rows = append(rows, importRow{line: line})
+			break
+		}
+
+		if len(record) != len(header) {
+			rows = append(rows, importRow{line: line, err: fmt.Errorf("expected %d cells, found %d", len(header), len(record))})
+			continue
+		}
+		values := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			values[column] = record[i]
+		}
+		rows = append(rows, importRow{line: line, values: values})
+	}
+	return header, rows, nil
+}
+
+// parseImportJSONL reads a file with a JSON object per line. Its columns
+// are the keys of all objects. Blank lines are skipped and lines that are
+// not objects kept as errors.
+func parseImportJSONL(data []byte) ([]string, []importRow, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxImportFileSize+1)
+
+	seen := make(map[string]bool)
+	var rows []importRow
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		if len(rows) >= MaxImportRows {
+			rows = append(rows, importRow{line: line})
+			break
+		}
+
+		var values map[string]interface{}
+		if err := json.Unmarshal(text, &values); err != nil || values == nil {
+			rows = append(rows, importRow{line: line, err: fmt.Errorf("the line is not a JSON object")})
+			continue
+		}
+		for key := range values {
+			seen[key] = true
+		}
+		rows = append(rows, importRow{line: line, values: values})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	columns := make([]string, 0, len(seen))
+	for key := range seen {
+		columns = append(columns, key)
+	}
+	sort.Strings(columns)
+	return columns, rows, nil
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func newImportServiceFixture() (*ContactImportService, *ContactService, *memContactStore) {
+	contacts := newMemContactStore()
+	attributes := NewAttributeService(newMemAttributeStore())
+	segments := NewSegmentService(newMemSegmentStore(), contacts, attributes)
+	imports := NewContactImportService(newMemContactImportStore(), contacts, attributes, zap.NewNop())
+	return imports, NewContactService(contacts, segments, attributes), contacts
+}
+
+// waitForImport waits for an import to finish and returns it
+func waitForImport(t *testing.T, service *ContactImportService, orgID, importID string) *models.ContactImport {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := service.GetImport(context.Background(), orgID, importID)
+		if err != nil {
+			t.Fatalf("GetImport: %v", err)
+		}
+		if job.Status == models.ImportStatusCompleted || job.Status == models.ImportStatusFailed {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("import still %s", job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestImportContactsCSV(t *testing.T) {
+	imports, contacts, store := newImportServiceFixture()
+	ctx := context.Background()
+	contacts.attributeService.CreateAttribute(ctx, "org_a", &models.AttributeDefinition{Name: "plan", Type: models.AttributeTypeEnum, Options: models.JSONArray{"free", "pro"}})
+	contacts.attributeService.CreateAttribute(ctx, "org_a", &models.AttributeDefinition{Name: "seats", Type: models.AttributeTypeNumber})
+	ada, _ := store.ForOrganization("org_a").GetOrCreate("14155550100")
+	contacts.UpdateContact(ctx, "org_a", ada.ID, map[string]interface{}{"name": "Ada"})
+
+	file := "Phone,Full Name,Plan,Seats,Labels,Consent,Notes\n" +
+		"+1 (415) 555-0100,,pro,12,\"VIP, beta\",opted_in,kept out\n" +
+		"14155550101,Bob,free,,lead,,\n" +
+		"not-a-phone,Eve,,,,,\n" +
+		"14155550102,Carol,gold,,,,\n" +
+		"14155550103,Dan\n"
+	mapping := map[string]string{
+		"Phone":     "phone_number",
+		"Full Name": "name",
+		"Plan":      "attributes.plan",
+		"Seats":     "attributes.seats",
+		"Labels":    "tags",
+		"Consent":   "consent",
+	}
+	job, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", mapping, []byte(file))
+	if err != nil {
+		t.Fatalf("StartImport: %v", err)
+	}
+	if job.TotalRows != 5 {
+		t.Errorf("expected 5 rows, got %d", job.TotalRows)
+	}
+
+	job = waitForImport(t, imports, "org_a", job.ID)
+	if job.Status != models.ImportStatusCompleted || job.ProcessedRows != 5 || job.Created != 1 || job.Updated != 1 || job.Failed != 3 {
+		t.Fatalf("unexpected result %+v", job)
+	}
+	var rows []int
+	for _, rowErr := range job.Errors {
+		rows = append(rows, rowErr.Row)
+	}
+	if len(rows) != 3 || rows[0] != 4 || rows[1] != 5 || rows[2] != 6 {
+		t.Errorf("errors should name the failed lines: %+v", job.Errors)
+	}
+
+	ada, err = contacts.GetContact(ctx, "org_a", ada.ID)
+	if err != nil {
+		t.Fatalf("GetContact: %v", err)
+	}
+	if ada.Name != "Ada" || ada.Consent != models.ConsentOptedIn || ada.ConsentUpdatedAt == nil {
+		t.Errorf("blank cells should keep the contact's fields: %+v", ada)
+	}
+	if ada.Metadata["plan"] != "pro" || ada.Metadata["seats"] != float64(12) || ada.Metadata["Notes"] != nil {
+		t.Errorf("unexpected metadata %v", ada.Metadata)
+	}
+	if strings.Join(ada.Tags, ",") != "beta,vip" {
+		t.Errorf("unexpected tags %v", ada.Tags)
+	}
+
+	bob, err := contacts.GetContactByPhone(ctx, "org_a", "14155550101")
+	if err != nil || bob.Name != "Bob" || strings.Join(bob.Tags, ",") != "lead" || bob.Metadata["plan"] != "free" {
+		t.Errorf("unexpected new contact %+v %v", bob, err)
+	}
+	if _, err := contacts.GetContactByPhone(ctx, "org_a", "14155550102"); err == nil {
+		t.Error("a row with an invalid attribute must not be imported")
+	}
+}
+
+func TestImportContactsJSONL(t *testing.T) {
+	imports, contacts, store := newImportServiceFixture()
+	ctx := context.Background()
+	contacts.attributeService.CreateAttribute(ctx, "org_a", &models.AttributeDefinition{Name: "seats", Type: models.AttributeTypeNumber})
+	plus, _ := store.ForOrganization("org_a").GetOrCreate("+14155550104")
+
+	file := `{"phone_number": 14155550100, "name": "Ada", "tags": ["VIP"], "metadata": {"seats": 3}}` + "\n" +
+		"\n" +
+		"not json\n" +
+		`{"phone_number": "+14155550104", "name": "Plus", "attributes.seats": 5}` + "\n" +
+		`{"phone_number": "14155550105", "metadata": {"color": "red"}}` + "\n"
+	job, err := imports.StartImport(ctx, "org_a", models.ImportFormatJSONL, "", nil, []byte(file))
+	if err != nil {
+		t.Fatalf("StartImport: %v", err)
+	}
+	if job.Mapping["name"] != "name" || job.Mapping["attributes.seats"] != "attributes.seats" {
+		t.Errorf("columns named like fields should be mapped: %v", job.Mapping)
+	}
+
+	job = waitForImport(t, imports, "org_a", job.ID)
+	if job.Created != 1 || job.Updated != 1 || job.Failed != 2 {
+		t.Fatalf("unexpected result %+v", job)
+	}
+	if len(job.Errors) != 2 || job.Errors[0].Row != 3 || job.Errors[1].Row != 5 || !strings.Contains(job.Errors[1].Error, "color") {
+		t.Errorf("unexpected errors %+v", job.Errors)
+	}
+
+	ada, err := contacts.GetContactByPhone(ctx, "org_a", "14155550100")
+	if err != nil || ada.Name != "Ada" || ada.Metadata["seats"] != float64(3) || strings.Join(ada.Tags, ",") != "vip" {
+		t.Errorf("numeric phone numbers should be imported: %+v %v", ada, err)
+	}
+	if updated, _ := contacts.GetContact(ctx, "org_a", plus.ID); updated.Name != "Plus" || updated.Metadata["seats"] != float64(5) {
+		t.Errorf("contacts stored with a + should be updated: %+v", updated)
+	}
+	if _, err := contacts.GetContactByPhone(ctx, "org_b", "14155550100"); err == nil {
+		t.Error("contacts must be imported into the organization only")
+	}
+}
+
+func TestImportContactsWithCountry(t *testing.T) {
+	imports, contacts, store := newImportServiceFixture()
+	ctx := context.Background()
+	existing, _ := store.ForOrganization("org_a").GetOrCreate("447700900123")
+
+	file := "phone_number,name\n" +
+		"07700 900123,Ada\n" +
+		"07700 900124,Bob\n" +
+		"14155550100,Carol\n" +
+		"0770090,Dan\n"
+	job, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "GB", nil, []byte(file))
+	if err != nil {
+		t.Fatalf("StartImport: %v", err)
+	}
+	job = waitForImport(t, imports, "org_a", job.ID)
+	if job.Created != 2 || job.Updated != 1 || job.Failed != 1 {
+		t.Fatalf("unexpected result %+v", job)
+	}
+
+	if ada, _ := contacts.GetContact(ctx, "org_a", existing.ID); ada.Name != "Ada" {
+		t.Errorf("national numbers should match the contact's WhatsApp ID: %+v", ada)
+	}
+	if bob, err := contacts.GetContactByPhone(ctx, "org_a", "+447700900124"); err != nil || bob.PhoneNumber != "447700900124" {
+		t.Errorf("contacts should be stored under their WhatsApp ID: %+v %v", bob, err)
+	}
+	if carol, err := contacts.GetContactByPhone(ctx, "org_a", "14155550100"); err != nil || carol.Name != "Carol" {
+		t.Errorf("numbers with a calling code should still be read: %+v %v", carol, err)
+	}
+}
+
+func TestStartImportRejectsInvalidFiles(t *testing.T) {
+	imports, contacts, _ := newImportServiceFixture()
+	ctx := context.Background()
+	contacts.attributeService.CreateAttribute(ctx, "org_a", &models.AttributeDefinition{Name: "plan", Type: models.AttributeTypeString})
+
+	csvFile := []byte("phone,plan\n14155550100,pro\n")
+	for name, call := range map[string]func() error{
+		"unknown format": func() error {
+			_, err := imports.StartImport(ctx, "org_a", "xlsx", "", nil, csvFile)
+			return err
+		},
+		"no phone column": func() error {
+			_, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", map[string]string{"plan": "attributes.plan"}, csvFile)
+			return err
+		},
+		"missing column": func() error {
+			_, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", map[string]string{"mobile": "phone_number"}, csvFile)
+			return err
+		},
+		"unknown field": func() error {
+			_, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", map[string]string{"phone": "phone_number", "plan": "message_count"}, csvFile)
+			return err
+		},
+		"undeclared attribute": func() error {
+			_, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", map[string]string{"phone": "phone_number", "plan": "attributes.tier"}, csvFile)
+			return err
+		},
+		"field mapped twice": func() error {
+			_, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", map[string]string{"phone": "phone_number", "plan": "phone_number"}, csvFile)
+			return err
+		},
+		"header only": func() error {
+			_, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", nil, []byte("phone_number,name\n"))
+			return err
+		},
+		"duplicate columns": func() error {
+			_, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", nil, []byte("phone_number,phone_number\n1,2\n"))
+			return err
+		},
+		"unknown country": func() error {
+			_, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "XX", nil, csvFile)
+			return err
+		},
+		"too large": func() error {
+			_, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", nil, make([]byte, MaxImportFileSize+1))
+			return err
+		},
+	} {
+		err := call()
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+			t.Errorf("%s: expected bad request, got %v", name, err)
+		}
+	}
+}
+
+func TestImportsRunOnePerOrganization(t *testing.T) {
+	imports, _, _ := newImportServiceFixture()
+	ctx := context.Background()
+	// An import another replica is running
+	now := time.Now().UTC()
+	running := &models.ContactImport{Format: models.ImportFormatCSV, Status: models.ImportStatusRunning, Owner: "worker_other", HeartbeatAt: &now}
+	imports.importRepo.ForOrganization("org_a").Create(running)
+
+	file := []byte("phone_number\n14155550100\n")
+	_, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", nil, file)
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrConflict {
+		t.Errorf("expected conflict, got %v", err)
+	}
+	other, err := imports.StartImport(ctx, "org_b", models.ImportFormatCSV, "", nil, file)
+	if err != nil {
+		t.Fatalf("other organizations should not wait: %v", err)
+	}
+	waitForImport(t, imports, "org_b", other.ID)
+
+	// A restart leaves imports alone while their process sends heartbeats
+	if err := imports.FailInterrupted(ctx); err != nil {
+		t.Fatalf("FailInterrupted: %v", err)
+	}
+	if job, _ := imports.GetImport(ctx, "org_a", running.ID); job.Status != models.ImportStatusRunning {
+		t.Errorf("an import of a live replica should keep running: %+v", job)
+	}
+
+	// and fails them once the heartbeats stop
+	imports.importRepo.UpdateFields(running.ID, running, map[string]interface{}{"heartbeat_at": now.Add(-importStaleAfter)})
+	if err := imports.FailInterrupted(ctx); err != nil {
+		t.Fatalf("FailInterrupted: %v", err)
+	}
+	if job, _ := imports.GetImport(ctx, "org_a", running.ID); job.Status != models.ImportStatusFailed || job.Error == "" {
+		t.Errorf("interrupted import should fail: %+v", job)
+	}
+	if job, _ := imports.GetImport(ctx, "org_b", other.ID); job.Status != models.ImportStatusCompleted {
+		t.Errorf("finished imports should be kept: %+v", job)
+	}
+	if _, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", nil, file); err != nil {
+		t.Errorf("the organization should be able to import again: %v", err)
+	}
+	imports.Close()
+}
+
+func TestStartImportFailsStaleImport(t *testing.T) {
+	imports, _, _ := newImportServiceFixture()
+	defer imports.Close()
+	ctx := context.Background()
+
+	// An import whose replica crashed without a restart of this one
+	lastSeen := time.Now().UTC().Add(-importStaleAfter - time.Minute)
+	stale := &models.ContactImport{Format: models.ImportFormatCSV, Status: models.ImportStatusRunning, Owner: "worker_gone", HeartbeatAt: &lastSeen}
+	imports.importRepo.ForOrganization("org_a").Create(stale)
+
+	job, err := imports.StartImport(ctx, "org_a", models.ImportFormatCSV, "", nil, []byte("phone_number\n14155550100\n"))
+	if err != nil {
+		t.Fatalf("a stale import should not block the organization: %v", err)
+	}
+	if job := waitForImport(t, imports, "org_a", job.ID); job.Status != models.ImportStatusCompleted {
+		t.Errorf("the new import should complete: %+v", job)
+	}
+	if found, _ := imports.GetImport(ctx, "org_a", stale.ID); found.Status != models.ImportStatusFailed {
+		t.Errorf("the stale import should be failed: %+v", found)
+	}
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/logger"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/phonenumber"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// MaxMergeDuplicates is the number of contacts that can be merged into a
+// contact at once
+const MaxMergeDuplicates = 50
+
+// duplicateLoadBatch is the number of contacts the duplicate report loads
+// per query
+const duplicateLoadBatch = 500
+
+// ContactMergeService finds contacts stored under the same phone number
+// written differently, and merges them
+type ContactMergeService struct {
+	contactRepo repositories.ContactStore
+	logger      *zap.Logger
+}
+
+// NewContactMergeService creates a new contact merge service
+func NewContactMergeService(contactRepo repositories.ContactStore, logger *zap.Logger) *ContactMergeService {
+	return &ContactMergeService{
+		contactRepo: contactRepo,
+		logger:      logger,
+	}
+}
+
+// FindDuplicates groups the organization's contacts whose phone numbers
+// are the same number. Numbers without a calling code are read as numbers
+// of country, when it is set. Groups are sorted by number and their
+// contacts oldest first.
+func (s *ContactMergeService) FindDuplicates(ctx context.Context, orgID, country string) (*models.DuplicateReport, error) {
+	if country != "" && !phonenumber.SupportedRegion(country) {
+		return nil, errors.NewBadRequest(fmt.Sprintf("Unknown country %q", country))
+	}
+
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+	phones, err := contactRepo.ListPhoneNumbers()
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	report := &models.DuplicateReport{Groups: []*models.DuplicateGroup{}}
+	byNumber := map[string][]string{}
+	for _, contact := range phones {
+		number, err := parseStoredPhone(contact.PhoneNumber, country)
+		if err != nil {
+			report.InvalidContacts++
+			continue
+		}
+		byNumber[number.E164()] = append(byNumber[number.E164()], contact.ID)
+	}
+
+	var ids []string
+	groups := map[string]*models.DuplicateGroup{}
+	for number, contactIDs := range byNumber {
+		if len(contactIDs) < 2 {
+			continue
+		}
+		group := &models.DuplicateGroup{PhoneNumber: number, Contacts: make([]*models.Contact, 0, len(contactIDs))}
+		report.Groups = append(report.Groups, group)
+		for _, id := range contactIDs {
+			groups[id] = group
+		}
+		ids = append(ids, contactIDs...)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].PhoneNumber < report.Groups[j].PhoneNumber
+	})
+
+	for start := 0; start < len(ids); start += duplicateLoadBatch {
+		batch := ids[start:min(start+duplicateLoadBatch, len(ids))]
+		filters := map[string]interface{}{"ids": batch, "sort": "created_at", "order": "asc"}
+		contacts, err := contactRepo.ListWithFilters(filters, &utils.Pagination{Limit: len(batch), SkipTotal: true})
+		if err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		if err := contactRepo.LoadTags(contacts); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		for _, contact := range contacts {
+			groups[contact.ID].Contacts = append(groups[contact.ID].Contacts, contact)
+		}
+	}
+	for _, group := range report.Groups {
+		sort.SliceStable(group.Contacts, func(i, j int) bool {
+			return group.Contacts[i].CreatedAt.Before(group.Contacts[j].CreatedAt)
+		})
+	}
+	return report, nil
+}
+
+// MergeContacts merges the duplicates into the contact with ID
+// survivorID. All the contacts must have the same phone number, read as
+// numbers of country when they lack a calling code. The survivor takes the
+// name, profile and metadata of the contacts in the order rule gives, the
+// tags of all of them, their message and unread counts and the most
+// restrictive consent; it is blocked when any of them is. The duplicates' messages move to the survivor and
+// the duplicates are deleted. actorID, the API key making the merge, is
+// recorded in the audit log.
+func (s *ContactMergeService) MergeContacts(ctx context.Context, orgID, survivorID string, duplicateIDs []string, rule, country, actorID string) (*models.ContactMergeResult, error) {
+	if rule == "" {
+		rule = models.MergeRuleSurvivor
+	}
+	if rule != models.MergeRuleSurvivor && rule != models.MergeRuleNewest {
+		return nil, errors.NewBadRequest(fmt.Sprintf("rule must be %s or %s", models.MergeRuleSurvivor, models.MergeRuleNewest))
+	}
+	if country != "" && !phonenumber.SupportedRegion(country) {
+		return nil, errors.NewBadRequest(fmt.Sprintf("Unknown country %q", country))
+	}
+	duplicateIDs = uniqueStrings(duplicateIDs)
+	if len(duplicateIDs) == 0 {
+		return nil, errors.NewBadRequest("duplicate_ids must name at least one contact")
+	}
+	if len(duplicateIDs) > MaxMergeDuplicates {
+		return nil, errors.NewBadRequest(fmt.Sprintf("At most %d contacts can be merged at once", MaxMergeDuplicates))
+	}
+
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+	contacts := make([]*models.Contact, 0, len(duplicateIDs)+1)
+	for _, id := range append([]string{survivorID}, duplicateIDs...) {
+		if id == survivorID && len(contacts) > 0 {
+			return nil, errors.NewBadRequest("A contact cannot be merged into itself")
+		}
+		var contact models.Contact
+		if err := contactRepo.FindByID(id, &contact); err != nil {
+			return nil, errors.NewNotFound("Contact", id)
+		}
+		contacts = append(contacts, &contact)
+	}
+	if err := contactRepo.LoadTags(contacts); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	number, err := parseStoredPhone(contacts[0].PhoneNumber, country)
+	if err != nil {
+		return nil, errors.NewBadRequest(fmt.Sprintf("Contact %s has an invalid phone number: %v", survivorID, err))
+	}
+	for _, duplicate := range contacts[1:] {
+		other, err := parseStoredPhone(duplicate.PhoneNumber, country)
+		if err != nil || other.E164() != number.E164() {
+			return nil, errors.NewBadRequest(fmt.Sprintf("Contact %s has phone number %s, which is not %s", duplicate.ID, duplicate.PhoneNumber, number.E164()))
+		}
+	}
+
+	survivor := mergeContacts(contacts, rule)
+	survivor.PhoneNumber = number.WhatsAppID()
+
+	var phones []string
+	for _, contact := range contacts {
+		digits := strings.TrimPrefix(contact.PhoneNumber, "+")
+		phones = append(phones, digits, "+"+digits)
+	}
+	phones = uniqueStrings(append(phones, survivor.PhoneNumber, "+"+survivor.PhoneNumber))
+
+	duplicates := contacts[1:]
+	audit := &models.AuditLog{
+		Action:       models.AuditActionContactMerge,
+		ResourceType: "contact",
+		ResourceID:   survivor.ID,
+		ActorID:      actorID,
+		Details: models.JSONMap{
+			"rule":               rule,
+			"phone_number":       survivor.PhoneNumber,
+			"merged_contact_ids": duplicateIDs,
+			"merged_contacts":    duplicates,
+		},
+	}
+	moved, err := contactRepo.Merge(&models.ContactMerge{
+		Survivor:     survivor,
+		Duplicates:   duplicates,
+		PhoneNumbers: phones,
+		Audit:        audit,
+	})
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			return nil, appErr
+		}
+		logger.FromContext(ctx, s.logger).Error("Failed to merge contacts", zap.String("contact_id", survivorID), zap.Error(err))
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	merged, err := s.getContact(ctx, orgID, survivor.ID)
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(ctx, s.logger).Info("Contacts merged",
+		zap.String("contact_id", survivor.ID),
+		zap.Strings("merged_contact_ids", duplicateIDs),
+		zap.Int64("messages_moved", moved),
+	)
+	return &models.ContactMergeResult{
+		Contact:          merged,
+		MergedContactIDs: duplicateIDs,
+		MessagesMoved:    moved,
+		AuditLogID:       audit.ID,
+	}, nil
+}
+
+// getContact loads a contact with its tags
+func (s *ContactMergeService) getContact(ctx context.Context, orgID, contactID string) (*models.Contact, error) {
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+
+	var contact models.Contact
+	if err := contactRepo.FindByID(contactID, &contact); err != nil {
+		return nil, errors.NewNotFound("Contact", contactID)
+	}
+	if err := contactRepo.LoadTags([]*models.Contact{&contact}); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return &contact, nil
+}
+
+// mergeContacts returns the survivor, contacts[0], with the fields of the
+// contacts merged in
+func mergeContacts(contacts []*models.Contact, rule string) *models.Contact {
+	survivor := *contacts[0]
+
+	// The contact values are taken from, most preferred first
+	ordered := append([]*models.Contact(nil), contacts...)
+	if rule == models.MergeRuleNewest {
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return newerContact(ordered[i], ordered[j])
+		})
+	}
+
+	survivor.Name, survivor.ProfileURL = "", ""
+	survivor.Metadata = models.JSONMap{}
+	survivor.Consent, survivor.ConsentUpdatedAt = "", nil
+	survivor.MessageCount, survivor.UnreadCount = 0, 0
+	survivor.Blocked, survivor.BlockedAt, survivor.BlockReason = false, nil, ""
+	tags := map[string]bool{}
+	for i := len(ordered) - 1; i >= 0; i-- {
+		contact := ordered[i]
+		if contact.Name != "" {
+			survivor.Name = contact.Name
+		}
+		if contact.ProfileURL != "" {
+			survivor.ProfileURL = contact.ProfileURL
+		}
+		for key, value := range contact.Metadata {
+			survivor.Metadata[key] = value
+		}
+		// An opt-out is never overridden by a merge
+		if (contact.Consent != "" && survivor.Consent != models.ConsentOptedOut) || contact.Consent == models.ConsentOptedOut {
+			survivor.Consent, survivor.ConsentUpdatedAt = contact.Consent, contact.ConsentUpdatedAt
+		}
+		// So is a block
+		if contact.Blocked {
+			survivor.Blocked, survivor.BlockedAt, survivor.BlockReason = true, contact.BlockedAt, contact.BlockReason
+		}
+		survivor.MessageCount += contact.MessageCount
+		survivor.UnreadCount += contact.UnreadCount
+		if contact.LastMessageAt != nil && (survivor.LastMessageAt == nil || contact.LastMessageAt.After(*survivor.LastMessageAt)) {
+			survivor.LastMessageAt = contact.LastMessageAt
+		}
+		for _, tag := range contact.Tags {
+			tags[tag] = true
+		}
+	}
+
+	survivor.Tags = make([]string, 0, len(tags))
+	for tag := range tags {
+		survivor.Tags = append(survivor.Tags, tag)
+	}
+	sort.Strings(survivor.Tags)
+	return &survivor
+}
+
+// newerContact reports whether a messaged more recently than b, or was
+// updated more recently when neither has messages
+func newerContact(a, b *models.Contact) bool {
+	switch {
+	case a.LastMessageAt != nil && b.LastMessageAt != nil:
+		return a.LastMessageAt.After(*b.LastMessageAt)
+	case a.LastMessageAt != nil || b.LastMessageAt != nil:
+		return a.LastMessageAt != nil
+	default:
+		return a.UpdatedAt.After(b.UpdatedAt)
+	}
+}
+
+// uniqueStrings returns values without repeats, in their first order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
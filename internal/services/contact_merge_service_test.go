@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"go.uber.org/zap"
+)
+
+func newMergeServiceFixture() (*ContactMergeService, *ContactService, *memContactStore) {
+	service, store := newContactServiceFixture()
+	return NewContactMergeService(store, zap.NewNop()), service, store
+}
+
+func TestFindDuplicates(t *testing.T) {
+	merges, _, store := newMergeServiceFixture()
+	ctx := context.Background()
+	contacts := store.ForOrganization("org_a")
+	first, _ := contacts.GetOrCreate("14155550100")
+	contacts.UpsertContact(&models.Contact{PhoneNumber: "+1 (415) 555-0100"})
+	contacts.UpsertContact(&models.Contact{PhoneNumber: "(415) 555-0100"})
+	contacts.GetOrCreate("447700900123")
+	contacts.UpsertContact(&models.Contact{PhoneNumber: "+44 07700 900123"})
+	contacts.GetOrCreate("12345")
+	store.ForOrganization("org_b").GetOrCreate("+14155550100")
+
+	report, err := merges.FindDuplicates(ctx, "org_a", "")
+	if err != nil {
+		t.Fatalf("FindDuplicates: %v", err)
+	}
+	if len(report.Groups) != 2 || report.InvalidContacts != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Groups[0].PhoneNumber != "+14155550100" || len(report.Groups[0].Contacts) != 2 || report.Groups[0].Contacts[0].ID != first.ID {
+		t.Errorf("unexpected group %+v", report.Groups[0])
+	}
+	if report.Groups[1].PhoneNumber != "+447700900123" || len(report.Groups[1].Contacts) != 2 {
+		t.Errorf("a national zero after the calling code should match: %+v", report.Groups[1])
+	}
+
+	// National numbers only match with a country
+	report, err = merges.FindDuplicates(ctx, "org_a", "US")
+	if err != nil {
+		t.Fatalf("FindDuplicates: %v", err)
+	}
+	if len(report.Groups[0].Contacts) != 3 || report.InvalidContacts != 1 {
+		t.Errorf("unexpected report with a country %+v", report)
+	}
+
+	if _, err := merges.FindDuplicates(ctx, "org_a", "XX"); err == nil {
+		t.Error("unknown countries should be rejected")
+	}
+}
+
+func TestMergeContacts(t *testing.T) {
+	merges, contacts, store := newMergeServiceFixture()
+	ctx := context.Background()
+	repo := store.ForOrganization("org_a")
+	older := time.Now().Add(-time.Hour).UTC()
+	newer := time.Now().UTC()
+
+	survivor, _ := repo.GetOrCreate("14155550100")
+	repo.UpsertContact(&models.Contact{PhoneNumber: "14155550100", Name: "Ada", Consent: models.ConsentOptedIn, Metadata: models.JSONMap{"plan": "free", "city": "Paris"}})
+	repo.UpdateLastMessage("14155550100", older)
+	repo.IncrementMessageCount("14155550100", 2)
+	repo.AddTags([]string{survivor.ID}, []string{"lead"})
+
+	duplicate := &models.Contact{PhoneNumber: "+1 415 555 0100", Name: "Ada Lovelace", ProfileURL: "https://example.com/ada.png", Consent: models.ConsentOptedOut, Metadata: models.JSONMap{"plan": "pro"}}
+	repo.UpsertContact(duplicate)
+	duplicate, _ = repo.FindByPhone("+1 415 555 0100")
+	repo.UpdateLastMessage(duplicate.PhoneNumber, newer)
+	repo.IncrementMessageCount(duplicate.PhoneNumber, 3)
+	repo.UpdateUnreadCount(duplicate.PhoneNumber, 1)
+	repo.AddTags([]string{duplicate.ID}, []string{"vip"})
+
+	result, err := merges.MergeContacts(ctx, "org_a", survivor.ID, []string{duplicate.ID, duplicate.ID}, "", "", "key_1")
+	if err != nil {
+		t.Fatalf("MergeContacts: %v", err)
+	}
+	merged := result.Contact
+	if merged.ID != survivor.ID || merged.PhoneNumber != "14155550100" || len(result.MergedContactIDs) != 1 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if merged.Name != "Ada" || merged.ProfileURL != "https://example.com/ada.png" {
+		t.Errorf("the survivor's values should win and gaps be filled: %+v", merged)
+	}
+	if merged.Metadata["plan"] != "free" || merged.Metadata["city"] != "Paris" {
+		t.Errorf("unexpected metadata %v", merged.Metadata)
+	}
+	if merged.Consent != models.ConsentOptedOut {
+		t.Errorf("an opt-out must survive the merge, got %q", merged.Consent)
+	}
+	if merged.MessageCount != 5 || merged.UnreadCount != 1 || merged.LastMessageAt == nil || !merged.LastMessageAt.Equal(newer) {
+		t.Errorf("counts should be combined: %+v", merged)
+	}
+	if strings.Join(merged.Tags, ",") != "lead,vip" {
+		t.Errorf("tags should be combined, got %v", merged.Tags)
+	}
+	if _, err := contacts.GetContact(ctx, "org_a", duplicate.ID); err == nil {
+		t.Error("the duplicate should be deleted")
+	}
+
+	audits := store.data.audits
+	if len(audits) != 1 || audits[0].ID != result.AuditLogID || audits[0].Action != models.AuditActionContactMerge ||
+		audits[0].ResourceID != survivor.ID || audits[0].ActorID != "key_1" || audits[0].OrganizationID != "org_a" {
+		t.Fatalf("the merge should be audited: %+v", audits)
+	}
+	if ids, _ := audits[0].Details["merged_contact_ids"].([]string); len(ids) != 1 || ids[0] != duplicate.ID {
+		t.Errorf("unexpected audit details %v", audits[0].Details)
+	}
+}
+
+func TestMergeContactsNewestRule(t *testing.T) {
+	merges, _, store := newMergeServiceFixture()
+	ctx := context.Background()
+	repo := store.ForOrganization("org_a")
+
+	repo.UpsertContact(&models.Contact{PhoneNumber: "447700900123", Name: "Old", Metadata: models.JSONMap{"plan": "free", "city": "Leeds"}})
+	survivor, _ := repo.FindByPhone("447700900123")
+	repo.UpsertContact(&models.Contact{PhoneNumber: "07700 900123", Name: "New", Metadata: models.JSONMap{"plan": "pro"}})
+	duplicate, _ := repo.FindByPhone("07700 900123")
+	repo.UpdateLastMessage(duplicate.PhoneNumber, time.Now().UTC())
+
+	if _, err := merges.MergeContacts(ctx, "org_a", survivor.ID, []string{duplicate.ID}, models.MergeRuleNewest, "", ""); err == nil {
+		t.Fatal("national numbers should only match with a country")
+	}
+	result, err := merges.MergeContacts(ctx, "org_a", survivor.ID, []string{duplicate.ID}, models.MergeRuleNewest, "GB", "")
+	if err != nil {
+		t.Fatalf("MergeContacts: %v", err)
+	}
+	if result.Contact.Name != "New" || result.Contact.Metadata["plan"] != "pro" || result.Contact.Metadata["city"] != "Leeds" {
+		t.Errorf("the newest contact's values should win: %+v", result.Contact)
+	}
+}
+
+func TestMergeContactsRejectsInvalidMerges(t *testing.T) {
+	merges, _, store := newMergeServiceFixture()
+	ctx := context.Background()
+	ada, _ := store.ForOrganization("org_a").GetOrCreate("14155550100")
+	bob, _ := store.ForOrganization("org_a").GetOrCreate("14155550101")
+	other, _ := store.ForOrganization("org_b").GetOrCreate("+14155550100")
+
+	for name, test := range map[string]struct {
+		duplicates []string
+		rule       string
+		code       string
+	}{
+		"no duplicates":      {nil, "", errors.ErrInvalidRequest},
+		"itself":             {[]string{ada.ID}, "", errors.ErrInvalidRequest},
+		"different number":   {[]string{bob.ID}, "", errors.ErrInvalidRequest},
+		"unknown rule":       {[]string{bob.ID}, "oldest", errors.ErrInvalidRequest},
+		"other organization": {[]string{other.ID}, "", errors.ErrNotFound},
+		"unknown contact":    {[]string{"contact_missing"}, "", errors.ErrNotFound},
+	} {
+		_, err := merges.MergeContacts(ctx, "org_a", ada.ID, test.duplicates, test.rule, "", "")
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != test.code {
+			t.Errorf("%s: expected %s, got %v", name, test.code, err)
+		}
+	}
+	if len(store.data.contacts) != 3 || len(store.data.audits) != 0 {
+		t.Error("rejected merges must not change contacts")
+	}
+}
@@ -1,72 +1,479 @@
 package services
 
 import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/phonenumber"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
 )
 
+const (
+	// MaxBulkTagContacts is the most contacts a bulk tagging request can
+	// name. Segments are tagged in batches of this size.
+	MaxBulkTagContacts = 1000
+
+	// maxTagsPerRequest bounds the tags added or removed at once
+	maxTagsPerRequest = 50
+)
+
 // ContactService handles contact business logic
 type ContactService struct {
-	contactRepo *repositories.ContactRepository
+	contactRepo      repositories.ContactStore
+	segmentService   *SegmentService
+	attributeService *AttributeService
 }
 
-// NewContactService creates a new contact service
-func NewContactService(contactRepo *repositories.ContactRepository) *ContactService {
+// NewContactService creates a new contact service. Segments are used as
+// contact filters and bulk tagging audiences, and metadata updates are
+// checked against the declared attributes.
+func NewContactService(contactRepo repositories.ContactStore, segmentService *SegmentService, attributeService *AttributeService) *ContactService {
 	return &ContactService{
-		contactRepo: contactRepo,
+		contactRepo:      contactRepo,
+		segmentService:   segmentService,
+		attributeService: attributeService,
 	}
 }
 
 // GetContact gets a contact by ID
-func (s *ContactService) GetContact(contactID string) (*models.Contact, error) {
+func (s *ContactService) GetContact(ctx context.Context, orgID, contactID string) (*models.Contact, error) {
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+
 	var contact models.Contact
-	if err := s.contactRepo.FindByID(contactID, &contact); err != nil {
+	if err := contactRepo.FindByID(contactID, &contact); err != nil {
 		return nil, errors.NewNotFound("Contact", contactID)
 	}
+	if err := contactRepo.LoadTags([]*models.Contact{&contact}); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
 	return &contact, nil
 }
 
 // GetContactByPhone gets a contact by phone number
-func (s *ContactService) GetContactByPhone(phone string) (*models.Contact, error) {
-	contact, err := s.contactRepo.FindByPhone(phone)
+func (s *ContactService) GetContactByPhone(ctx context.Context, orgID, phone string) (*models.Contact, error) {
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+
+	contact, err := contactRepo.FindByPhone(contactPhoneNumber(phone))
 	if err != nil {
 		return nil, errors.NewNotFound("Contact", phone)
 	}
+	if err := contactRepo.LoadTags([]*models.Contact{contact}); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
 	return contact, nil
 }
 
-// ListContacts lists all contacts with pagination and filters
-func (s *ContactService) ListContacts(filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Contact, error) {
-	return s.contactRepo.ListWithFilters(filters, pagination)
+// ListContacts lists all contacts with pagination and filters. Besides
+// the repository filters it takes "segment_id", the ID of a saved segment,
+// and "filter", a segment filter expression; contacts must match both.
+func (s *ContactService) ListContacts(ctx context.Context, orgID string, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Contact, error) {
+	if err := s.resolveSegmentFilters(ctx, orgID, filters); err != nil {
+		return nil, err
+	}
+
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+	contacts, err := contactRepo.ListWithFilters(filters, pagination)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			return nil, appErr
+		}
+		return nil, errors.NewDatabaseError(err)
+	}
+	if err := contactRepo.LoadTags(contacts); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return contacts, nil
+}
+
+// resolveSegmentFilters replaces the "segment_id" and "filter" filters with
+// the "segment" filter expression the repository understands
+func (s *ContactService) resolveSegmentFilters(ctx context.Context, orgID string, filters map[string]interface{}) error {
+	var expressions []string
+	if segmentID, ok := filters["segment_id"].(string); ok && segmentID != "" {
+		segment, err := s.segmentService.GetSegment(ctx, orgID, segmentID, false)
+		if err != nil {
+			return err
+		}
+		expressions = append(expressions, segment.Filter)
+	}
+	if filter, ok := filters["filter"].(string); ok && filter != "" {
+		if err := s.attributeService.ValidateFilter(ctx, orgID, filter); err != nil {
+			return err
+		}
+		expressions = append(expressions, filter)
+	}
+	delete(filters, "segment_id")
+	delete(filters, "filter")
+
+	switch len(expressions) {
+	case 1:
+		filters["segment"] = expressions[0]
+	case 2:
+		filters["segment"] = "(" + expressions[0] + ") AND (" + expressions[1] + ")"
+	}
+	return nil
+}
+
+// ExportContacts writes the contacts ListContacts would return for the
+// filters to w, as CSV or JSONL. Contacts are read and written a page at a
+// time, so exports of any size stream; nothing is written before the first
+// page is read. CSV files have a column for each declared attribute, JSONL
+// lines hold the whole contact.
+func (s *ContactService) ExportContacts(ctx context.Context, orgID, format string, filters map[string]interface{}, w io.Writer) error {
+	if format != models.ImportFormatCSV && format != models.ImportFormatJSONL {
+		return errors.NewBadRequest(fmt.Sprintf("format must be %s or %s", models.ImportFormatCSV, models.ImportFormatJSONL))
+	}
+	if err := s.resolveSegmentFilters(ctx, orgID, filters); err != nil {
+		return err
+	}
+	attributes, err := s.attributeService.ListAttributes(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	// The buffer holds the CSV header until the first page is read
+	buffered := bufio.NewWriterSize(w, 32<<10)
+	csvWriter := csv.NewWriter(buffered)
+	encoder := json.NewEncoder(buffered)
+	if format == models.ImportFormatCSV {
+		header := []string{"id", "phone_number", "name", "consent", "tags", "message_count", "last_message_at", "created_at"}
+		for _, attribute := range attributes {
+			header = append(header, "attributes."+attribute.Name)
+		}
+		csvWriter.Write(header)
+	}
+
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+	pagination := utils.NewPagination(100, 0)
+	pagination.SkipTotal = true
+	for {
+		contacts, err := contactRepo.ListWithFilters(filters, pagination)
+		if err != nil {
+			if appErr, ok := err.(*errors.AppError); ok {
+				return appErr
+			}
+			return errors.NewDatabaseError(err)
+		}
+		if err := contactRepo.LoadTags(contacts); err != nil {
+			return errors.NewDatabaseError(err)
+		}
+
+		for _, contact := range contacts {
+			if format == models.ImportFormatCSV {
+				csvWriter.Write(exportRecord(contact, attributes))
+			} else if err := encoder.Encode(contact); err != nil {
+				return err
+			}
+		}
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return err
+		}
+		if err := buffered.Flush(); err != nil {
+			return err
+		}
+
+		if !pagination.HasMore || pagination.NextCursor == "" {
+			return nil
+		}
+		pagination.Cursor = pagination.NextCursor
+	}
+}
+
+// exportRecord renders a contact as a CSV export row
+func exportRecord(contact *models.Contact, attributes []*models.AttributeDefinition) []string {
+	lastMessageAt := ""
+	if contact.LastMessageAt != nil {
+		lastMessageAt = contact.LastMessageAt.UTC().Format(time.RFC3339)
+	}
+	record := []string{
+		contact.ID,
+		contact.PhoneNumber,
+		contact.Name,
+		contact.Consent,
+		strings.Join(contact.Tags, ","),
+		strconv.Itoa(contact.MessageCount),
+		lastMessageAt,
+		contact.CreatedAt.UTC().Format(time.RFC3339),
+	}
+	for _, attribute := range attributes {
+		record = append(record, models.FormatAttributeValue(contact.Metadata[attribute.Name]))
+	}
+	return record
 }
 
 // SearchContacts searches contacts by name or phone
-func (s *ContactService) SearchContacts(query string, pagination *utils.Pagination) ([]*models.Contact, error) {
-	return s.contactRepo.Search(query, pagination)
+func (s *ContactService) SearchContacts(ctx context.Context, orgID, query string, pagination *utils.Pagination) ([]*models.Contact, error) {
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+
+	contacts, err := contactRepo.Search(query, pagination)
+	if err != nil {
+		return nil, err
+	}
+	if err := contactRepo.LoadTags(contacts); err != nil {
+		return nil, err
+	}
+	return contacts, nil
 }
 
-// UpdateContact updates contact information
-func (s *ContactService) UpdateContact(contactID string, updates map[string]interface{}) (*models.Contact, error) {
+// UpdateContact updates contact information. Only name, consent, metadata
+// and tags can be updated: "metadata" sets or, with null, removes declared
+// attributes, "tags" replaces the contact's tags, and "consent" records
+// when it changed.
+func (s *ContactService) UpdateContact(ctx context.Context, orgID, contactID string, updates map[string]interface{}) (*models.Contact, error) {
+	for field := range updates {
+		switch field {
+		case "name", "consent", "metadata", "tags":
+		default:
+			return nil, errors.NewBadRequest(field + " cannot be updated")
+		}
+	}
+	if name, ok := updates["name"]; ok {
+		if _, ok := name.(string); !ok {
+			return nil, errors.NewBadRequest("name must be a string")
+		}
+	}
+
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+
 	var contact models.Contact
-	if err := s.contactRepo.FindByID(contactID, &contact); err != nil {
+	if err := contactRepo.FindByID(contactID, &contact); err != nil {
 		return nil, errors.NewNotFound("Contact", contactID)
 	}
 
-	if err := s.contactRepo.UpdateFields(contactID, &contact, updates); err != nil {
+	var tags []string
+	replaceTags := false
+	if raw, ok := updates["tags"]; ok {
+		values, ok := raw.([]interface{})
+		if !ok && raw != nil {
+			return nil, errors.NewBadRequest("tags must be a list of strings")
+		}
+		names := make([]string, 0, len(values))
+		for _, value := range values {
+			name, ok := value.(string)
+			if !ok {
+				return nil, errors.NewBadRequest("tags must be a list of strings")
+			}
+			names = append(names, name)
+		}
+		var err error
+		if tags, err = normalizeTags(names); err != nil {
+			return nil, err
+		}
+		replaceTags = true
+		delete(updates, "tags")
+	}
+
+	if raw, ok := updates["metadata"]; ok {
+		attributes, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, errors.NewBadRequest("metadata must be an object")
+		}
+		metadata, err := s.attributeService.ApplyAttributes(ctx, orgID, contact.Metadata, attributes)
+		if err != nil {
+			return nil, err
+		}
+		updates["metadata"] = metadata
+	}
+
+	if raw, ok := updates["consent"]; ok {
+		consent, _ := raw.(string)
+		if raw != nil && consent != models.ConsentOptedIn && consent != models.ConsentOptedOut && consent != "" {
+			return nil, errors.NewBadRequest(fmt.Sprintf("consent must be %q, %q or null", models.ConsentOptedIn, models.ConsentOptedOut))
+		}
+		if consent != contact.Consent {
+			updates["consent"] = consent
+			updates["consent_updated_at"] = time.Now().UTC()
+		} else {
+			delete(updates, "consent")
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := contactRepo.UpdateFields(contactID, &contact, updates); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+	}
+
+	if replaceTags {
+		var current models.Contact
+		current.ID = contactID
+		if err := contactRepo.LoadTags([]*models.Contact{&current}); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		if err := contactRepo.RemoveTags([]string{contactID}, current.Tags); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+		if err := contactRepo.AddTags([]string{contactID}, tags); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+	}
+
+	return s.GetContact(ctx, orgID, contactID)
+}
+
+// GetOrCreateContact gets an existing contact or creates a new one
+func (s *ContactService) GetOrCreateContact(ctx context.Context, orgID, phone string) (*models.Contact, error) {
+	return s.contactRepo.WithContext(ctx).ForOrganization(orgID).GetOrCreate(contactPhoneNumber(phone))
+}
+
+// contactPhoneNumber returns the number a contact with phone is stored
+// under: its WhatsApp ID, the E.164 form without the +. Numbers that cannot
+// be parsed are kept as given, without the +.
+func contactPhoneNumber(phone string) string {
+	number, err := phonenumber.Parse(phone, "")
+	if err != nil {
+		return strings.TrimPrefix(strings.TrimSpace(phone), "+")
+	}
+	return number.WhatsAppID()
+}
+
+// parseStoredPhone reads the phone number of a stored contact. Contacts are
+// stored under their WhatsApp ID, so numbers are read as international and
+// only as national numbers of country when that fails.
+func parseStoredPhone(phone, country string) (*phonenumber.Number, error) {
+	number, err := phonenumber.Parse(phone, "")
+	if err != nil && country != "" {
+		return phonenumber.Parse(phone, country)
+	}
+	return number, err
+}
+
+// AddTags tags a contact
+func (s *ContactService) AddTags(ctx context.Context, orgID, contactID string, tags []string) (*models.Contact, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, errors.NewBadRequest("tags are required")
+	}
+
+	contact, err := s.GetContact(ctx, orgID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.contactRepo.WithContext(ctx).ForOrganization(orgID).AddTags([]string{contact.ID}, tags); err != nil {
 		return nil, errors.NewDatabaseError(err)
 	}
+	return s.GetContact(ctx, orgID, contactID)
+}
 
-	// Fetch updated contact
-	if err := s.contactRepo.FindByID(contactID, &contact); err != nil {
+// RemoveTag removes a tag from a contact. Removing a tag the contact does
+// not have is not an error.
+func (s *ContactService) RemoveTag(ctx context.Context, orgID, contactID, tag string) (*models.Contact, error) {
+	contact, err := s.GetContact(ctx, orgID, contactID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.contactRepo.WithContext(ctx).ForOrganization(orgID).RemoveTags([]string{contact.ID}, []string{models.NormalizeTag(tag)}); err != nil {
 		return nil, errors.NewDatabaseError(err)
 	}
+	return s.GetContact(ctx, orgID, contactID)
+}
 
-	return &contact, nil
+// TagContacts adds and removes tags on many contacts at once: the contacts
+// with the given IDs, or the contacts in a segment. It returns the number
+// of contacts found.
+func (s *ContactService) TagContacts(ctx context.Context, orgID string, contactIDs []string, segmentID string, add, remove []string) (int, error) {
+	add, err := normalizeTags(add)
+	if err != nil {
+		return 0, err
+	}
+	remove, err = normalizeTags(remove)
+	if err != nil {
+		return 0, err
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return 0, errors.NewBadRequest("add or remove is required")
+	}
+
+	filters := map[string]interface{}{}
+	switch {
+	case (len(contactIDs) > 0) == (segmentID != ""):
+		return 0, errors.NewBadRequest("Either contact_ids or segment_id is required")
+	case len(contactIDs) > MaxBulkTagContacts:
+		return 0, errors.NewBadRequest(fmt.Sprintf("At most %d contact_ids can be tagged at once", MaxBulkTagContacts))
+	case segmentID != "":
+		filters["segment_id"] = segmentID
+		if err := s.resolveSegmentFilters(ctx, orgID, filters); err != nil {
+			return 0, err
+		}
+	default:
+		filters["ids"] = contactIDs
+	}
+
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+	ids, err := contactRepo.FindIDsWithFilters(filters)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			return 0, appErr
+		}
+		return 0, errors.NewDatabaseError(err)
+	}
+	for start := 0; start < len(ids); start += MaxBulkTagContacts {
+		batch := ids[start:min(start+MaxBulkTagContacts, len(ids))]
+		if err := contactRepo.RemoveTags(batch, remove); err != nil {
+			return 0, errors.NewDatabaseError(err)
+		}
+		if err := contactRepo.AddTags(batch, add); err != nil {
+			return 0, errors.NewDatabaseError(err)
+		}
+	}
+	return len(ids), nil
 }
 
-// GetOrCreateContact gets an existing contact or creates a new one
-func (s *ContactService) GetOrCreateContact(phone string) (*models.Contact, error) {
-	return s.contactRepo.GetOrCreate(phone)
+// ListTags returns the tags in use with their number of contacts
+func (s *ContactService) ListTags(ctx context.Context, orgID string) ([]*models.TagCount, error) {
+	tags, err := s.contactRepo.WithContext(ctx).ForOrganization(orgID).ListTags()
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return tags, nil
+}
+
+// normalizeTags normalizes tags, drops duplicates and checks their length
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) > maxTagsPerRequest {
+		return nil, errors.NewBadRequest(fmt.Sprintf("At most %d tags can be changed at once", maxTagsPerRequest))
+	}
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = models.NormalizeTag(tag)
+		switch {
+		case tag == "":
+			return nil, errors.NewBadRequest("Tags cannot be empty")
+		case utf8.RuneCountInString(tag) > models.MaxTagLength:
+			return nil, errors.NewBadRequest(fmt.Sprintf("Tag %q is longer than %d characters", tag, models.MaxTagLength))
+		case containsControl(tag):
+			return nil, errors.NewBadRequest(fmt.Sprintf("Tag %q contains control characters", tag))
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+func containsControl(s string) bool {
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+)
+
+func newContactServiceFixture() (*ContactService, *memContactStore) {
+	contacts := newMemContactStore()
+	attributes := NewAttributeService(newMemAttributeStore())
+	segments := NewSegmentService(newMemSegmentStore(), contacts, attributes)
+	return NewContactService(contacts, segments, attributes), contacts
+}
+
+func TestContactTags(t *testing.T) {
+	service, store := newContactServiceFixture()
+	ctx := context.Background()
+	ada, _ := store.ForOrganization("org_a").GetOrCreate("14155550100")
+
+	contact, err := service.AddTags(ctx, "org_a", ada.ID, []string{" VIP ", "vip", "Early  Adopter"})
+	if err != nil {
+		t.Fatalf("AddTags: %v", err)
+	}
+	if strings.Join(contact.Tags, ",") != "early adopter,vip" {
+		t.Errorf("tags should be normalized and deduplicated, got %q", contact.Tags)
+	}
+
+	contact, err = service.RemoveTag(ctx, "org_a", ada.ID, "VIP")
+	if err != nil || strings.Join(contact.Tags, ",") != "early adopter" {
+		t.Errorf("RemoveTag: %+v %v", contact, err)
+	}
+	if _, err := service.AddTags(ctx, "org_b", ada.ID, []string{"vip"}); err == nil {
+		t.Error("contacts of another organization must not be tagged")
+	}
+
+	for name, tags := range map[string][]string{
+		"empty":    {" "},
+		"too long": {strings.Repeat("x", models.MaxTagLength+1)},
+		"control":  {"a\tb\x00"},
+		"none":     {},
+	} {
+		if _, err := service.AddTags(ctx, "org_a", ada.ID, tags); err == nil {
+			t.Errorf("%s tag should be rejected", name)
+		} else if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+			t.Errorf("%s tag: expected bad request, got %v", name, err)
+		}
+	}
+
+	contact, err = service.UpdateContact(ctx, "org_a", ada.ID, map[string]interface{}{"tags": []interface{}{"Lead", "beta"}})
+	if err != nil || strings.Join(contact.Tags, ",") != "beta,lead" {
+		t.Errorf("UpdateContact should replace the tags: %+v %v", contact, err)
+	}
+}
+
+func TestTagContacts(t *testing.T) {
+	service, store := newContactServiceFixture()
+	ctx := context.Background()
+	ada, _ := store.ForOrganization("org_a").GetOrCreate("14155550100")
+	bob, _ := store.ForOrganization("org_a").GetOrCreate("14155550101")
+	eve, _ := store.ForOrganization("org_b").GetOrCreate("14155550102")
+	service.AddTags(ctx, "org_a", bob.ID, []string{"lead"})
+
+	count, err := service.TagContacts(ctx, "org_a", []string{ada.ID, bob.ID, eve.ID}, "", []string{"Customer"}, []string{"lead"})
+	if err != nil {
+		t.Fatalf("TagContacts: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("only the organization's contacts should be counted, got %d", count)
+	}
+	tags, _ := service.ListTags(ctx, "org_a")
+	if len(tags) != 1 || tags[0].Tag != "customer" || tags[0].Contacts != 2 {
+		t.Errorf("unexpected tags %+v", tags)
+	}
+	if other, _ := service.GetContact(ctx, "org_b", eve.ID); len(other.Tags) != 0 {
+		t.Errorf("contact of another organization was tagged: %v", other.Tags)
+	}
+
+	filtered, err := service.ListContacts(ctx, "org_a", map[string]interface{}{"tag": "customer"}, nil)
+	if err != nil || len(filtered) != 2 {
+		t.Errorf("tag filter: %d %v", len(filtered), err)
+	}
+
+	for name, call := range map[string]func() (int, error){
+		"no tags": func() (int, error) { return service.TagContacts(ctx, "org_a", []string{ada.ID}, "", nil, nil) },
+		"no contacts": func() (int, error) {
+			return service.TagContacts(ctx, "org_a", nil, "", []string{"vip"}, nil)
+		},
+		"contacts and segment": func() (int, error) {
+			return service.TagContacts(ctx, "org_a", []string{ada.ID}, "seg_1", []string{"vip"}, nil)
+		},
+		"too many contacts": func() (int, error) {
+			return service.TagContacts(ctx, "org_a", make([]string, MaxBulkTagContacts+1), "", []string{"vip"}, nil)
+		},
+	} {
+		if _, err := call(); err == nil {
+			t.Errorf("%s should be rejected", name)
+		}
+	}
+	if _, err := service.TagContacts(ctx, "org_a", nil, "seg_missing", []string{"vip"}, nil); err == nil {
+		t.Error("tagging an unknown segment should fail")
+	} else if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound {
+		t.Errorf("expected not found, got %v", err)
+	}
+}
+
+func TestUpdateContactConsent(t *testing.T) {
+	service, store := newContactServiceFixture()
+	ctx := context.Background()
+	ada, _ := store.ForOrganization("org_a").GetOrCreate("14155550100")
+
+	contact, err := service.UpdateContact(ctx, "org_a", ada.ID, map[string]interface{}{"consent": models.ConsentOptedIn})
+	if err != nil || contact.Consent != models.ConsentOptedIn || contact.ConsentUpdatedAt == nil {
+		t.Fatalf("UpdateContact consent: %+v %v", contact, err)
+	}
+
+	if _, err := service.UpdateContact(ctx, "org_a", ada.ID, map[string]interface{}{"consent": "maybe"}); err == nil {
+		t.Error("unknown consent should be rejected")
+	}
+
+	contact, err = service.UpdateContact(ctx, "org_a", ada.ID, map[string]interface{}{"consent": nil})
+	if err != nil || contact.Consent != "" {
+		t.Errorf("null should clear the consent: %+v %v", contact, err)
+	}
+}
+
+func TestUpdateContactAttributes(t *testing.T) {
+	service, store := newContactServiceFixture()
+	ctx := context.Background()
+	ada, _ := store.ForOrganization("org_a").GetOrCreate("14155550100")
+	service.attributeService.CreateAttribute(ctx, "org_a", &models.AttributeDefinition{Name: "plan", Type: models.AttributeTypeEnum, Options: models.JSONArray{"free", "pro"}})
+	service.attributeService.CreateAttribute(ctx, "org_a", &models.AttributeDefinition{Name: "seats", Type: models.AttributeTypeNumber})
+
+	contact, err := service.UpdateContact(ctx, "org_a", ada.ID, map[string]interface{}{"metadata": map[string]interface{}{"plan": "pro", "seats": float64(12)}})
+	if err != nil || contact.Metadata["plan"] != "pro" || contact.Metadata["seats"] != float64(12) {
+		t.Fatalf("UpdateContact metadata: %+v %v", contact, err)
+	}
+	contact, err = service.UpdateContact(ctx, "org_a", ada.ID, map[string]interface{}{"metadata": map[string]interface{}{"seats": nil}})
+	if err != nil || contact.Metadata["plan"] != "pro" || contact.Metadata["seats"] != nil {
+		t.Errorf("metadata updates should merge, and null remove: %+v %v", contact.Metadata, err)
+	}
+
+	for name, updates := range map[string]map[string]interface{}{
+		"not an object":   {"metadata": "pro"},
+		"undeclared key":  {"metadata": map[string]interface{}{"color": "red"}},
+		"wrong type":      {"metadata": map[string]interface{}{"seats": "twelve"}},
+		"unknown option":  {"metadata": map[string]interface{}{"plan": "gold"}},
+		"core column":     {"message_count": float64(0)},
+		"other org field": {"organization_id": "org_b"},
+		"name not text":   {"name": float64(1)},
+	} {
+		_, err := service.UpdateContact(ctx, "org_a", ada.ID, updates)
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+			t.Errorf("%s: expected bad request, got %v", name, err)
+		}
+	}
+	if contact, _ := service.GetContact(ctx, "org_a", ada.ID); contact.MessageCount != 0 || contact.OrganizationID != "org_a" {
+		t.Errorf("rejected updates changed the contact: %+v", contact)
+	}
+}
+
+func TestListContactsRejectsInvalidFilter(t *testing.T) {
+	service, _ := newContactServiceFixture()
+
+	_, err := service.ListContacts(context.Background(), "org_a", map[string]interface{}{"filter": "tag > vip"}, nil)
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+		t.Errorf("expected bad request, got %v", err)
+	}
+}
+
+func TestExportContacts(t *testing.T) {
+	service, store := newContactServiceFixture()
+	ctx := context.Background()
+	service.attributeService.CreateAttribute(ctx, "org_a", &models.AttributeDefinition{Name: "seats", Type: models.AttributeTypeNumber})
+	ada, _ := store.ForOrganization("org_a").GetOrCreate("14155550100")
+	store.ForOrganization("org_a").GetOrCreate("14155550101")
+	store.ForOrganization("org_b").GetOrCreate("14155550102")
+	service.UpdateContact(ctx, "org_a", ada.ID, map[string]interface{}{"name": "Ada, Lovelace", "tags": []interface{}{"vip", "beta"}, "metadata": map[string]interface{}{"seats": float64(12)}})
+
+	var out strings.Builder
+	if err := service.ExportContacts(ctx, "org_a", models.ImportFormatCSV, map[string]interface{}{"tag": "vip"}, &out); err != nil {
+		t.Fatalf("ExportContacts: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected the header and one contact, got %q", out.String())
+	}
+	if lines[0] != "id,phone_number,name,consent,tags,message_count,last_message_at,created_at,attributes.seats" {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], ada.ID+`,14155550100,"Ada, Lovelace",,"beta,vip",0,,`) || !strings.HasSuffix(lines[1], ",12") {
+		t.Errorf("unexpected row %q", lines[1])
+	}
+
+	out.Reset()
+	if err := service.ExportContacts(ctx, "org_a", models.ImportFormatJSONL, map[string]interface{}{}, &out); err != nil {
+		t.Fatalf("ExportContacts: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); len(lines) != 2 || !strings.Contains(out.String(), `"seats":12`) {
+		t.Errorf("expected a line per contact of the organization, got %q", out.String())
+	}
+
+	out.Reset()
+	err := service.ExportContacts(ctx, "org_a", "xml", map[string]interface{}{}, &out)
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest || out.Len() != 0 {
+		t.Errorf("expected bad request before writing, got %v %q", err, out.String())
+	}
+	err = service.ExportContacts(ctx, "org_a", models.ImportFormatCSV, map[string]interface{}{"segment_id": "seg_missing"}, &out)
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound || out.Len() != 0 {
+		t.Errorf("expected not found before writing, got %v %q", err, out.String())
+	}
+}
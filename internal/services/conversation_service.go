@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+)
+
+// ConversationSessionGap is the silence after which the next message starts
+// a new conversation session. It matches WhatsApp's 24 hour customer
+// service window.
+const ConversationSessionGap = 24 * time.Hour
+
+// ConversationService builds the message history with a contact
+type ConversationService struct {
+	messageRepo  repositories.MessageStore
+	contactRepo  repositories.ContactStore
+	reactionRepo repositories.ReactionStore
+}
+
+// NewConversationService creates a new conversation service
+func NewConversationService(
+	messageRepo repositories.MessageStore,
+	contactRepo repositories.ContactStore,
+	reactionRepo repositories.ReactionStore,
+) *ConversationService {
+	return &ConversationService{
+		messageRepo:  messageRepo,
+		contactRepo:  contactRepo,
+		reactionRepo: reactionRepo,
+	}
+}
+
+// GetConversation returns a page of the inbound and outbound messages with a
+// contact, newest first, grouped into sessions. Messages carry the message
+// they reply to and their reactions. Pages are paginated with cursors.
+func (s *ConversationService) GetConversation(ctx context.Context, orgID, contactID string, pagination *utils.Pagination) (*models.Conversation, error) {
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+	var contact models.Contact
+	if err := contactRepo.FindByID(contactID, &contact); err != nil {
+		return nil, errors.NewNotFound("Contact", contactID)
+	}
+	if err := contactRepo.LoadTags([]*models.Contact{&contact}); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	messageRepo := s.messageRepo.WithContext(ctx).ForOrganization(orgID)
+	messages, err := messageRepo.FindByPhone(contact.PhoneNumber, pagination)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			return nil, appErr
+		}
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	thread, err := s.attachContext(ctx, orgID, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	conversation := &models.Conversation{Contact: &contact, Sessions: []*models.ConversationSession{}}
+	if len(thread) == 0 {
+		return conversation, nil
+	}
+
+	// The messages just past each end of the page tell whether the first
+	// and last sessions continue on the neighbouring pages
+	newer := s.neighbour(messageRepo, contact.PhoneNumber, pagination.PrevCursor)
+	older := s.neighbour(messageRepo, contact.PhoneNumber, pagination.NextCursor)
+
+	var session *models.ConversationSession
+	for i, message := range thread {
+		if session == nil || thread[i-1].Timestamp.Sub(message.Timestamp) > ConversationSessionGap {
+			session = &models.ConversationSession{EndedAt: message.Timestamp}
+			conversation.Sessions = append(conversation.Sessions, session)
+		}
+		session.StartedAt = message.Timestamp
+		session.Messages = append(session.Messages, message)
+	}
+
+	first, last := conversation.Sessions[0], conversation.Sessions[len(conversation.Sessions)-1]
+	first.HasLaterMessages = newer != nil && newer.Timestamp.Sub(first.EndedAt) <= ConversationSessionGap
+	last.HasEarlierMessages = older != nil && last.StartedAt.Sub(older.Timestamp) <= ConversationSessionGap
+	return conversation, nil
+}
+
+// attachContext wraps messages with the messages they reply to and their
+// reactions, loading both in one query each
+func (s *ConversationService) attachContext(ctx context.Context, orgID string, messages []*models.Message) ([]*models.ConversationMessage, error) {
+	var quotedIDs, targetIDs []string
+	for _, message := range messages {
+		if message.ReplyToWhatsAppMessageID != "" {
+			quotedIDs = append(quotedIDs, message.ReplyToWhatsAppMessageID)
+		}
+		if message.WhatsAppMessageID != "" {
+			targetIDs = append(targetIDs, message.WhatsAppMessageID)
+		}
+	}
+
+	quoted, err := s.messageRepo.WithContext(ctx).ForOrganization(orgID).FindByWhatsAppMessageIDs(quotedIDs)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	quotedByID := make(map[string]*models.Message, len(quoted))
+	for _, message := range quoted {
+		quotedByID[message.WhatsAppMessageID] = message
+	}
+
+	reactions, err := s.reactionRepo.WithContext(ctx).ForOrganization(orgID).FindByTargets(targetIDs)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	reactionsByTarget := make(map[string][]*models.Reaction)
+	for _, reaction := range reactions {
+		reactionsByTarget[reaction.TargetWhatsAppMessageID] = append(reactionsByTarget[reaction.TargetWhatsAppMessageID], reaction)
+	}
+
+	thread := make([]*models.ConversationMessage, len(messages))
+	for i, message := range messages {
+		thread[i] = &models.ConversationMessage{Message: message, Reactions: []*models.Reaction{}}
+		if message.ReplyToWhatsAppMessageID != "" {
+			thread[i].ReplyTo = quotedByID[message.ReplyToWhatsAppMessageID]
+		}
+		if message.WhatsAppMessageID != "" && reactionsByTarget[message.WhatsAppMessageID] != nil {
+			thread[i].Reactions = reactionsByTarget[message.WhatsAppMessageID]
+		}
+	}
+	return thread, nil
+}
+
+// neighbour returns the message a page cursor points past, or nil when
+// there is none
+func (s *ConversationService) neighbour(messageRepo repositories.MessageStore, phone, cursor string) *models.Message {
+	if cursor == "" {
+		return nil
+	}
+	messages, err := messageRepo.FindByPhone(phone, &utils.Pagination{Limit: 1, Cursor: cursor, SkipTotal: true})
+	if err != nil || len(messages) == 0 {
+		return nil
+	}
+	return messages[0]
+}
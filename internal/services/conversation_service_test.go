@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+)
+
+func TestGetConversation(t *testing.T) {
+	messages, contacts, reactions := newMemMessageStore(), newMemContactStore(), newMemReactionStore()
+	service := NewConversationService(messages, contacts, reactions)
+	ctx := context.Background()
+
+	contact, _ := contacts.ForOrganization("org_a").GetOrCreate("14155550100")
+	other, _ := contacts.ForOrganization("org_a").GetOrCreate("14155550199")
+
+	// Two sessions: a question and answer two days ago, and a reply today
+	// quoting the answer
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	store := messages.ForOrganization("org_a")
+	for _, m := range []*models.Message{
+		{WhatsAppMessageID: "wamid.1", FromNumber: "14155550100", ToNumber: "15550000000", Direction: "inbound", Content: "Where is my order?", Timestamp: now.Add(-49 * time.Hour)},
+		{WhatsAppMessageID: "wamid.2", FromNumber: "+14155550100", ToNumber: "+14155550100", Direction: "outbound", Content: "It ships tomorrow", Timestamp: now.Add(-48 * time.Hour)},
+		{WhatsAppMessageID: "wamid.3", FromNumber: "14155550100", ToNumber: "15550000000", Direction: "inbound", Content: "It arrived, thanks", Timestamp: now,
+			ReplyToWhatsAppMessageID: "wamid.2"},
+		{WhatsAppMessageID: "wamid.4", FromNumber: "14155550199", ToNumber: "15550000000", Direction: "inbound", Content: "someone else", Timestamp: now},
+	} {
+		m.MessageType = models.MessageTypeText
+		if err := store.Create(m); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	reactions.ForOrganization("org_a").Upsert(&models.Reaction{TargetWhatsAppMessageID: "wamid.2", FromNumber: "14155550100", Direction: "inbound", Emoji: "👍"})
+
+	conversation, err := service.GetConversation(ctx, "org_a", contact.ID, utils.NewPagination(50, 0))
+	if err != nil {
+		t.Fatalf("GetConversation: %v", err)
+	}
+	if conversation.Contact.ID != contact.ID || len(conversation.Sessions) != 2 {
+		t.Fatalf("expected 2 sessions for the contact, got %+v", conversation.Sessions)
+	}
+
+	latest, earlier := conversation.Sessions[0], conversation.Sessions[1]
+	if len(latest.Messages) != 1 || latest.Messages[0].WhatsAppMessageID != "wamid.3" || !latest.StartedAt.Equal(now) {
+		t.Errorf("unexpected latest session %+v", latest)
+	}
+	if reply := latest.Messages[0].ReplyTo; reply == nil || reply.WhatsAppMessageID != "wamid.2" {
+		t.Errorf("reply should carry the quoted message, got %+v", reply)
+	}
+
+	if len(earlier.Messages) != 2 || earlier.Messages[0].WhatsAppMessageID != "wamid.2" || earlier.Messages[1].WhatsAppMessageID != "wamid.1" {
+		t.Fatalf("unexpected earlier session %+v", earlier)
+	}
+	if !earlier.StartedAt.Equal(now.Add(-49*time.Hour)) || !earlier.EndedAt.Equal(now.Add(-48*time.Hour)) {
+		t.Errorf("earlier session spans %v to %v", earlier.StartedAt, earlier.EndedAt)
+	}
+	if r := earlier.Messages[0].Reactions; len(r) != 1 || r[0].Emoji != "👍" {
+		t.Errorf("reaction should be attached to its message, got %+v", r)
+	}
+	if earlier.HasEarlierMessages || latest.HasLaterMessages {
+		t.Error("a single page has no neighbouring messages")
+	}
+
+	empty, err := service.GetConversation(ctx, "org_a", other.ID, utils.NewPagination(50, 0))
+	if err != nil || len(empty.Sessions) != 1 {
+		t.Errorf("conversation with another contact: %+v %v", empty, err)
+	}
+
+	_, err = service.GetConversation(ctx, "org_b", contact.ID, utils.NewPagination(50, 0))
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound {
+		t.Errorf("contact of another organization should not be found, got %v", err)
+	}
+}
@@ -0,0 +1,1697 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// In-memory implementations of the stores and sender used by service tests.
+// Scoped copies share the underlying data, like repositories share a DB.
+
+type memMessageStore struct {
+	data  *memMessages
+	orgID string
+}
+
+type memMessages struct {
+	messages []*models.Message
+	mu       sync.Mutex
+}
+
+func newMemMessageStore() *memMessageStore {
+	return &memMessageStore{data: &memMessages{}}
+}
+
+func (s *memMessageStore) WithContext(ctx context.Context) repositories.MessageStore { return s }
+
+func (s *memMessageStore) ForOrganization(orgID string) repositories.MessageStore {
+	return &memMessageStore{data: s.data, orgID: orgID}
+}
+
+func (s *memMessageStore) Create(model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	message := model.(*models.Message)
+	if message.WhatsAppMessageID != "" {
+		for _, m := range s.data.messages {
+			if m.WhatsAppMessageID == message.WhatsAppMessageID {
+				return fmt.Errorf("duplicate whatsapp_message_id %s", message.WhatsAppMessageID)
+			}
+		}
+	}
+	if message.ID == "" {
+		message.ID = utils.GenerateID("msg")
+	}
+	if s.orgID != "" {
+		message.OrganizationID = s.orgID
+	}
+	stored := *message
+	s.data.messages = append(s.data.messages, &stored)
+	return nil
+}
+
+func (s *memMessageStore) FindByID(id string, model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	for _, m := range s.data.messages {
+		if m.ID == id && s.visible(m) {
+			*model.(*models.Message) = *m
+			return nil
+		}
+	}
+	return fmt.Errorf("record not found")
+}
+
+// FindByPhone returns the messages to or from a number, with or without the
+// leading +, newest first. Cursors are not supported.
+func (s *memMessageStore) FindByPhone(phone string, pagination *utils.Pagination) ([]*models.Message, error) {
+	messages := s.find("", map[string]interface{}{"phone": phone}, pagination)
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].Timestamp.After(messages[j].Timestamp) })
+	return messages, nil
+}
+
+func (s *memMessageStore) FindByWhatsAppMessageIDs(whatsappMessageIDs []string) ([]*models.Message, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.Message
+	for _, m := range s.data.messages {
+		for _, id := range whatsappMessageIDs {
+			if m.WhatsAppMessageID == id && s.visible(m) {
+				copied := *m
+				result = append(result, &copied)
+			}
+		}
+	}
+	return result, nil
+}
+
+// ListWithFilters returns the matching messages newest first. Cursors are
+// not supported.
+func (s *memMessageStore) ListWithFilters(filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Message, error) {
+	messages := s.find("", filters, pagination)
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].Timestamp.After(messages[j].Timestamp) })
+	if pagination != nil && pagination.Limit > 0 && len(messages) > pagination.Limit {
+		messages = messages[:pagination.Limit]
+	}
+	return messages, nil
+}
+
+func (s *memMessageStore) Search(query string, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.MessageSearchResult, error) {
+	var results []*models.MessageSearchResult
+	for _, m := range s.find(query, filters, pagination) {
+		results = append(results, &models.MessageSearchResult{Message: m, Snippet: m.Content})
+	}
+	return results, nil
+}
+
+// find returns copies of the visible messages containing query
+func (s *memMessageStore) find(query string, filters map[string]interface{}, pagination *utils.Pagination) []*models.Message {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.Message
+	for _, m := range s.data.messages {
+		if !s.visible(m) || !strings.Contains(m.Content, query) {
+			continue
+		}
+		if direction, _ := filters["direction"].(string); direction != "" && m.Direction != direction {
+			continue
+		}
+		if phone, _ := filters["phone"].(string); phone != "" && !samePhone(m.FromNumber, phone) && !samePhone(m.ToNumber, phone) {
+			continue
+		}
+		copied := *m
+		result = append(result, &copied)
+	}
+	if pagination != nil {
+		pagination.SetTotal(int64(len(result)))
+	}
+	return result
+}
+
+func (s *memMessageStore) UpdateByWhatsAppMessageID(whatsappMessageID string, updates map[string]interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	for _, m := range s.data.messages {
+		if m.WhatsAppMessageID != whatsappMessageID || !s.visible(m) {
+			continue
+		}
+		for field, value := range updates {
+			switch field {
+			case "status":
+				m.Status = value.(string)
+			case "error_code":
+				m.ErrorCode = value.(string)
+			case "error_message":
+				m.ErrorMessage = value.(string)
+			case "error_category":
+				m.ErrorCategory = value.(string)
+			case "error_retryable":
+				m.ErrorRetryable = value.(bool)
+			default:
+				return fmt.Errorf("unsupported update field %s", field)
+			}
+		}
+	}
+	return nil
+}
+
+// aged returns the visible messages the filter selects, oldest first. The
+// caller must hold the lock.
+func (s *memMessageStore) aged(filter repositories.MessageAgeFilter) []*models.Message {
+	var result []*models.Message
+	for _, m := range s.data.messages {
+		if !s.visible(m) || !m.Timestamp.Before(filter.Before) {
+			continue
+		}
+		if len(filter.Types) > 0 && !containsString(filter.Types, m.MessageType) {
+			continue
+		}
+		if containsString(filter.ExcludeTypes, m.MessageType) || (filter.WithMedia && m.MediaURL == "") {
+			continue
+		}
+		result = append(result, m)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Timestamp.Before(result[j].Timestamp) })
+	return result
+}
+
+func (s *memMessageStore) FindAged(filter repositories.MessageAgeFilter, limit int) ([]*models.Message, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.Message
+	for _, m := range s.aged(filter) {
+		if len(result) == limit {
+			break
+		}
+		copied := *m
+		result = append(result, &copied)
+	}
+	return result, nil
+}
+
+func (s *memMessageStore) CountAged(filter repositories.MessageAgeFilter) (int64, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	return int64(len(s.aged(filter))), nil
+}
+
+// DeleteMessages deletes the messages only: reactions are kept in another
+// store
+func (s *memMessageStore) DeleteMessages(ids []string) (int64, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var deleted int64
+	kept := s.data.messages[:0]
+	for _, m := range s.data.messages {
+		if s.visible(m) && containsString(ids, m.ID) {
+			deleted++
+			continue
+		}
+		kept = append(kept, m)
+	}
+	s.data.messages = kept
+	return deleted, nil
+}
+
+func (s *memMessageStore) RemoveMedia(ids []string, at time.Time) (int64, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var changed int64
+	for _, m := range s.data.messages {
+		if s.visible(m) && containsString(ids, m.ID) {
+			removedAt := at
+			m.MediaURL, m.MediaMimeType, m.MediaRemovedAt = "", "", &removedAt
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// all returns a copy of every stored message, across organizations
+func (s *memMessageStore) all() []models.Message {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	messages := make([]models.Message, len(s.data.messages))
+	for i, m := range s.data.messages {
+		messages[i] = *m
+	}
+	return messages
+}
+
+func (s *memMessageStore) visible(m *models.Message) bool {
+	return s.orgID == "" || m.OrganizationID == s.orgID
+}
+
+type memReactionStore struct {
+	data  *memReactions
+	orgID string
+}
+
+type memReactions struct {
+	reactions []*models.Reaction
+	mu        sync.Mutex
+}
+
+func newMemReactionStore() *memReactionStore {
+	return &memReactionStore{data: &memReactions{}}
+}
+
+func (s *memReactionStore) WithContext(ctx context.Context) repositories.ReactionStore { return s }
+
+func (s *memReactionStore) ForOrganization(orgID string) repositories.ReactionStore {
+	return &memReactionStore{data: s.data, orgID: orgID}
+}
+
+func (s *memReactionStore) Upsert(reaction *models.Reaction) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	reaction.OrganizationID = s.orgID
+	if reaction.ID == "" {
+		reaction.ID = utils.GenerateID("reaction")
+	}
+	stored := *reaction
+	for i, r := range s.data.reactions {
+		if r.OrganizationID == s.orgID && r.TargetWhatsAppMessageID == reaction.TargetWhatsAppMessageID && r.FromNumber == reaction.FromNumber && r.Direction == reaction.Direction {
+			s.data.reactions[i] = &stored
+			return nil
+		}
+	}
+	s.data.reactions = append(s.data.reactions, &stored)
+	return nil
+}
+
+func (s *memReactionStore) Remove(targetWhatsAppMessageID, fromNumber, direction string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	kept := s.data.reactions[:0]
+	for _, r := range s.data.reactions {
+		if r.OrganizationID != s.orgID || r.TargetWhatsAppMessageID != targetWhatsAppMessageID || r.FromNumber != fromNumber || r.Direction != direction {
+			kept = append(kept, r)
+		}
+	}
+	s.data.reactions = kept
+	return nil
+}
+
+func (s *memReactionStore) FindByTargets(targetWhatsAppMessageIDs []string) ([]*models.Reaction, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.Reaction
+	for _, r := range s.data.reactions {
+		for _, id := range targetWhatsAppMessageIDs {
+			if r.OrganizationID == s.orgID && r.TargetWhatsAppMessageID == id {
+				copied := *r
+				result = append(result, &copied)
+			}
+		}
+	}
+	return result, nil
+}
+
+type memContactStore struct {
+	data  *memContacts
+	orgID string
+}
+
+type memContacts struct {
+	contacts []*models.Contact
+	// tags maps contact IDs to their tags
+	tags map[string]map[string]bool
+	// audits are the audit log entries recorded by merges, blocks,
+	// deletions and erasures
+	audits []*models.AuditLog
+	// erasures are the tombstones of erased contacts
+	erasures []*models.ContactErasure
+	mu       sync.Mutex
+}
+
+func newMemContactStore() *memContactStore {
+	return &memContactStore{data: &memContacts{tags: map[string]map[string]bool{}}}
+}
+
+func (s *memContactStore) WithContext(ctx context.Context) repositories.ContactStore { return s }
+
+func (s *memContactStore) ForOrganization(orgID string) repositories.ContactStore {
+	return &memContactStore{data: s.data, orgID: orgID}
+}
+
+// find returns the stored contact with the phone number. The caller must
+// hold the lock.
+func (s *memContactStore) find(phone string) *models.Contact {
+	for _, c := range s.data.contacts {
+		if strings.TrimPrefix(c.PhoneNumber, "+") == strings.TrimPrefix(phone, "+") && (s.orgID == "" || c.OrganizationID == s.orgID) {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *memContactStore) FindByID(id string, model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	for _, c := range s.data.contacts {
+		if c.ID == id && (s.orgID == "" || c.OrganizationID == s.orgID) {
+			*model.(*models.Contact) = *c
+			return nil
+		}
+	}
+	return fmt.Errorf("record not found")
+}
+
+func (s *memContactStore) FindByPhone(phone string) (*models.Contact, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	if c := s.find(phone); c != nil {
+		copied := *c
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("record not found")
+}
+
+func (s *memContactStore) GetOrCreate(phone string) (*models.Contact, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	c := s.find(phone)
+	if c == nil {
+		c = &models.Contact{ID: utils.GenerateID("contact"), OrganizationID: s.orgID, PhoneNumber: phone}
+		s.data.contacts = append(s.data.contacts, c)
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (s *memContactStore) UpdateFields(id string, model interface{}, updates map[string]interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	for _, c := range s.data.contacts {
+		if c.ID != id {
+			continue
+		}
+		for field, value := range updates {
+			switch field {
+			case "name":
+				c.Name = value.(string)
+			case "consent":
+				c.Consent = value.(string)
+			case "metadata":
+				c.Metadata = value.(models.JSONMap)
+			case "consent_updated_at":
+				at := value.(time.Time)
+				c.ConsentUpdatedAt = &at
+			default:
+				return fmt.Errorf("unsupported update field %s", field)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("record not found")
+}
+
+// ListWithFilters supports the ids and tag filters. Segment filters are
+// SQL and only tested against the repositories.
+func (s *memContactStore) ListWithFilters(filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Contact, error) {
+	if _, ok := filters["segment"]; ok {
+		return nil, fmt.Errorf("segment filters need a database")
+	}
+	contacts, _ := s.Search("", pagination)
+
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+	var result []*models.Contact
+	for _, c := range contacts {
+		if ids, ok := filters["ids"].([]string); ok && !containsString(ids, c.ID) {
+			continue
+		}
+		if tag, ok := filters["tag"].(string); ok && !s.data.tags[c.ID][models.NormalizeTag(tag)] {
+			continue
+		}
+		if blocked, ok := filters["blocked"].(bool); ok && c.Blocked != blocked {
+			continue
+		}
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func (s *memContactStore) CountWithFilters(filters map[string]interface{}) (int64, error) {
+	contacts, err := s.ListWithFilters(filters, nil)
+	return int64(len(contacts)), err
+}
+
+func (s *memContactStore) FindIDsWithFilters(filters map[string]interface{}) ([]string, error) {
+	contacts, err := s.ListWithFilters(filters, nil)
+	ids := make([]string, 0, len(contacts))
+	for _, c := range contacts {
+		ids = append(ids, c.ID)
+	}
+	return ids, err
+}
+
+func (s *memContactStore) AddTags(contactIDs, tags []string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	for _, c := range s.data.contacts {
+		if !containsString(contactIDs, c.ID) || (s.orgID != "" && c.OrganizationID != s.orgID) {
+			continue
+		}
+		if s.data.tags[c.ID] == nil {
+			s.data.tags[c.ID] = map[string]bool{}
+		}
+		for _, tag := range tags {
+			s.data.tags[c.ID][tag] = true
+		}
+	}
+	return nil
+}
+
+func (s *memContactStore) RemoveTags(contactIDs, tags []string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	for _, id := range contactIDs {
+		for _, tag := range tags {
+			delete(s.data.tags[id], tag)
+		}
+	}
+	return nil
+}
+
+func (s *memContactStore) LoadTags(contacts []*models.Contact) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	for _, c := range contacts {
+		c.Tags = []string{}
+		for tag := range s.data.tags[c.ID] {
+			c.Tags = append(c.Tags, tag)
+		}
+		sort.Strings(c.Tags)
+	}
+	return nil
+}
+
+func (s *memContactStore) ListTags() ([]*models.TagCount, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	counts := map[string]int64{}
+	for _, c := range s.data.contacts {
+		if s.orgID != "" && c.OrganizationID != s.orgID {
+			continue
+		}
+		for tag := range s.data.tags[c.ID] {
+			counts[tag]++
+		}
+	}
+	result := []*models.TagCount{}
+	for tag, n := range counts {
+		result = append(result, &models.TagCount{Tag: tag, Contacts: n})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Tag < result[j].Tag })
+	return result, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memContactStore) Search(query string, pagination *utils.Pagination) ([]*models.Contact, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.Contact
+	for _, c := range s.data.contacts {
+		if (s.orgID == "" || c.OrganizationID == s.orgID) &&
+			(strings.Contains(c.Name, query) || strings.Contains(c.PhoneNumber, query)) {
+			copied := *c
+			result = append(result, &copied)
+		}
+	}
+	if pagination != nil {
+		pagination.SetTotal(int64(len(result)))
+	}
+	return result, nil
+}
+
+func (s *memContactStore) UpsertContact(contact *models.Contact) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	c := s.find(contact.PhoneNumber)
+	if c == nil {
+		c = &models.Contact{ID: utils.GenerateID("contact"), OrganizationID: s.orgID, PhoneNumber: contact.PhoneNumber}
+		s.data.contacts = append(s.data.contacts, c)
+	}
+	c.Name = contact.Name
+	c.ProfileURL = contact.ProfileURL
+	c.Consent = contact.Consent
+	c.ConsentUpdatedAt = contact.ConsentUpdatedAt
+	c.Metadata = contact.Metadata
+	return nil
+}
+
+func (s *memContactStore) UpdateLastMessage(phone string, timestamp time.Time) error {
+	return s.update(phone, func(c *models.Contact) { c.LastMessageAt = &timestamp })
+}
+
+func (s *memContactStore) IncrementMessageCount(phone string, delta int) error {
+	return s.update(phone, func(c *models.Contact) { c.MessageCount += delta })
+}
+
+func (s *memContactStore) UpdateUnreadCount(phone string, delta int) error {
+	return s.update(phone, func(c *models.Contact) {
+		c.UnreadCount += delta
+		if c.UnreadCount < 0 {
+			c.UnreadCount = 0
+		}
+	})
+}
+
+func (s *memContactStore) ResetUnreadCount(phone string) error {
+	return s.update(phone, func(c *models.Contact) { c.UnreadCount = 0 })
+}
+
+func (s *memContactStore) update(phone string, apply func(*models.Contact)) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	if c := s.find(phone); c != nil {
+		apply(c)
+	}
+	return nil
+}
+
+func (s *memContactStore) ListPhoneNumbers() ([]*models.Contact, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.Contact
+	for _, c := range s.data.contacts {
+		if s.orgID == "" || c.OrganizationID == s.orgID {
+			result = append(result, &models.Contact{ID: c.ID, PhoneNumber: c.PhoneNumber, CreatedAt: c.CreatedAt})
+		}
+	}
+	return result, nil
+}
+
+func (s *memContactStore) Merge(merge *models.ContactMerge) (int64, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	removed := map[string]bool{}
+	for _, duplicate := range merge.Duplicates {
+		removed[duplicate.ID] = true
+		delete(s.data.tags, duplicate.ID)
+	}
+	kept := s.data.contacts[:0]
+	for _, c := range s.data.contacts {
+		switch {
+		case removed[c.ID]:
+		case c.ID == merge.Survivor.ID:
+			survivor := *merge.Survivor
+			survivor.Tags = nil
+			kept = append(kept, &survivor)
+		default:
+			kept = append(kept, c)
+		}
+	}
+	s.data.contacts = kept
+
+	tags := map[string]bool{}
+	for _, tag := range merge.Survivor.Tags {
+		tags[tag] = true
+	}
+	s.data.tags[merge.Survivor.ID] = tags
+	if merge.Audit != nil {
+		merge.Audit.ID = utils.GenerateID("audit")
+		merge.Audit.OrganizationID = merge.Survivor.OrganizationID
+		s.data.audits = append(s.data.audits, merge.Audit)
+	}
+	return 0, nil
+}
+
+func (s *memContactStore) SetBlocked(contact *models.Contact, audit *models.AuditLog) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	for _, c := range s.data.contacts {
+		if c.ID == contact.ID && (s.orgID == "" || c.OrganizationID == s.orgID) {
+			c.Blocked, c.BlockedAt, c.BlockReason = contact.Blocked, contact.BlockedAt, contact.BlockReason
+			audit.ID = utils.GenerateID("audit")
+			audit.OrganizationID = c.OrganizationID
+			s.data.audits = append(s.data.audits, audit)
+			return nil
+		}
+	}
+	return fmt.Errorf("record not found")
+}
+
+func (s *memContactStore) DeleteContact(contact *models.Contact, audit *models.AuditLog) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	if !s.remove(contact.ID) {
+		return fmt.Errorf("record not found")
+	}
+	audit.ID = utils.GenerateID("audit")
+	audit.OrganizationID = contact.OrganizationID
+	s.data.audits = append(s.data.audits, audit)
+	return nil
+}
+
+// Erase deletes the contact and records the erasure. Messages and calls are
+// in other stores and are left alone.
+func (s *memContactStore) Erase(contact *models.Contact, erasure *models.ContactErasure, audit *models.AuditLog) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	if !s.remove(contact.ID) {
+		return fmt.Errorf("record not found")
+	}
+	for _, entry := range s.data.audits {
+		if entry.ResourceType == "contact" && entry.ResourceID == contact.ID {
+			entry.Details = models.JSONMap{"erased": true}
+		}
+	}
+	erasure.ID = utils.GenerateID("erasure")
+	erasure.OrganizationID, erasure.ContactID = contact.OrganizationID, contact.ID
+	s.data.erasures = append(s.data.erasures, erasure)
+	audit.ID = utils.GenerateID("audit")
+	audit.OrganizationID = contact.OrganizationID
+	audit.Details = models.JSONMap{"erasure_id": erasure.ID}
+	s.data.audits = append(s.data.audits, audit)
+	return nil
+}
+
+// remove deletes the contact with the ID and its tags, reporting whether it
+// was found. The caller must hold the lock.
+func (s *memContactStore) remove(id string) bool {
+	for i, c := range s.data.contacts {
+		if c.ID == id && (s.orgID == "" || c.OrganizationID == s.orgID) {
+			s.data.contacts = append(s.data.contacts[:i], s.data.contacts[i+1:]...)
+			delete(s.data.tags, id)
+			return true
+		}
+	}
+	return false
+}
+
+// memAuditLogStore reads and writes the audit log entries of a contact
+// store
+type memAuditLogStore struct {
+	data  *memContacts
+	orgID string
+}
+
+func (s *memAuditLogStore) WithContext(ctx context.Context) repositories.AuditLogStore { return s }
+
+func (s *memAuditLogStore) ForOrganization(orgID string) repositories.AuditLogStore {
+	return &memAuditLogStore{data: s.data, orgID: orgID}
+}
+
+func (s *memAuditLogStore) Create(model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	entry := model.(*models.AuditLog)
+	entry.ID = utils.GenerateID("audit")
+	if s.orgID != "" {
+		entry.OrganizationID = s.orgID
+	}
+	s.data.audits = append(s.data.audits, entry)
+	return nil
+}
+
+// ListWithFilters returns the matching entries newest first, which are the
+// last recorded. Only the first page is returned.
+func (s *memAuditLogStore) ListWithFilters(filters map[string]interface{}, pagination *utils.Pagination) ([]*models.AuditLog, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.AuditLog
+	for i := len(s.data.audits) - 1; i >= 0; i-- {
+		entry := s.data.audits[i]
+		if s.orgID != "" && entry.OrganizationID != s.orgID {
+			continue
+		}
+		if action, _ := filters["action"].(string); action != "" && entry.Action != action {
+			continue
+		}
+		if resourceID, _ := filters["resource_id"].(string); resourceID != "" && entry.ResourceID != resourceID {
+			continue
+		}
+		copied := *entry
+		result = append(result, &copied)
+	}
+	pagination.SetTotal(int64(len(result)))
+	if pagination.Offset > 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+type memCallStore struct {
+	data  *memCalls
+	orgID string
+}
+
+type memCalls struct {
+	calls       []*models.Call
+	transcripts []*models.Transcript
+	segments    []*models.TranscriptSegment
+	mu          sync.Mutex
+}
+
+func newMemCallStore() *memCallStore {
+	return &memCallStore{data: &memCalls{}}
+}
+
+func (s *memCallStore) WithContext(ctx context.Context) repositories.CallStore { return s }
+
+func (s *memCallStore) ForOrganization(orgID string) repositories.CallStore {
+	return &memCallStore{data: s.data, orgID: orgID}
+}
+
+func (s *memCallStore) FindByPhone(phone string) ([]*models.Call, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.Call
+	for _, c := range s.data.calls {
+		if (s.orgID == "" || c.OrganizationID == s.orgID) && (samePhone(c.FromNumber, phone) || samePhone(c.ToNumber, phone)) {
+			copied := *c
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (s *memCallStore) FindTranscripts(callIDs []string) ([]*models.Transcript, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.Transcript
+	for _, t := range s.data.transcripts {
+		if (s.orgID == "" || t.OrganizationID == s.orgID) && containsString(callIDs, t.CallID) {
+			copied := *t
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (s *memCallStore) FindSegments(transcriptIDs []string) ([]*models.TranscriptSegment, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.TranscriptSegment
+	for _, segment := range s.data.segments {
+		if (s.orgID == "" || segment.OrganizationID == s.orgID) && containsString(transcriptIDs, segment.TranscriptID) {
+			copied := *segment
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+// fakeSender records sends and answers them with canned responses
+type fakeSender struct {
+	err   error
+	sends []fakeSend
+	mu    sync.Mutex
+}
+
+type fakeSend struct {
+	kind    string
+	to      string
+	content string
+	replyTo string
+}
+
+func (s *fakeSender) send(ctx context.Context, kind, to, content string, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sends = append(s.sends, fakeSend{kind: kind, to: to, content: content, replyTo: opts.ReplyTo})
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	resp := &whatsapp.MessageResponse{MessagingProduct: "whatsapp"}
+	resp.Contacts = append(resp.Contacts, struct {
+		Input string `json:"input"`
+		WaID  string `json:"wa_id"`
+	}{Input: to, WaID: strings.TrimPrefix(to, "+")})
+	resp.Messages = append(resp.Messages, struct {
+		ID string `json:"id"`
+	}{ID: fmt.Sprintf("wamid.test%d", len(s.sends))})
+	return resp, nil
+}
+
+func (s *fakeSender) SendTextMessage(ctx context.Context, to, text string, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+	return s.send(ctx, "text", to, text, opts)
+}
+
+func (s *fakeSender) SendMediaMessage(ctx context.Context, to, mediaURL, caption string, mediaType whatsapp.MediaType, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+	return s.send(ctx, string(mediaType), to, mediaURL, opts)
+}
+
+func (s *fakeSender) SendTemplateMessage(ctx context.Context, to, templateName, language string, params []string, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+	return s.send(ctx, "template", to, templateName, opts)
+}
+
+func (s *fakeSender) MarkAsRead(ctx context.Context, messageID string, typing bool) error {
+	kind := "read"
+	if typing {
+		kind = "read+typing"
+	}
+	_, err := s.send(ctx, kind, "", messageID, whatsapp.SendOptions{})
+	return err
+}
+
+func (s *fakeSender) SendLocationMessage(ctx context.Context, to string, location whatsapp.Location, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+	return s.send(ctx, "location", to, fmt.Sprintf("%v,%v", location.Latitude, location.Longitude), opts)
+}
+
+func (s *fakeSender) SendLocationRequest(ctx context.Context, to, body string, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+	return s.send(ctx, "location_request", to, body, opts)
+}
+
+func (s *fakeSender) SendContactsMessage(ctx context.Context, to string, contacts []whatsapp.ContactCard, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+	return s.send(ctx, "contacts", to, contacts[0].Name.FormattedName, opts)
+}
+
+func (s *fakeSender) SendReaction(ctx context.Context, to, messageID, emoji string) (*whatsapp.MessageResponse, error) {
+	return s.send(ctx, "reaction", to, messageID+" "+emoji, whatsapp.SendOptions{})
+}
+
+func (s *fakeSender) BlockUsers(ctx context.Context, users []string) error {
+	_, err := s.send(ctx, "block", users[0], "", whatsapp.SendOptions{})
+	return err
+}
+
+func (s *fakeSender) UnblockUsers(ctx context.Context, users []string) error {
+	_, err := s.send(ctx, "unblock", users[0], "", whatsapp.SendOptions{})
+	return err
+}
+
+// fakeSenders resolves every organization to the same sender, or fails
+type fakeSenders struct {
+	sender *fakeSender
+	err    error
+}
+
+func (r *fakeSenders) Sender(ctx context.Context, orgID string) (Sender, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.sender, nil
+}
+
+type memSegmentStore struct {
+	data  *memSegments
+	orgID string
+}
+
+type memSegments struct {
+	segments []*models.Segment
+	mu       sync.Mutex
+}
+
+func newMemSegmentStore() *memSegmentStore {
+	return &memSegmentStore{data: &memSegments{}}
+}
+
+func (s *memSegmentStore) WithContext(ctx context.Context) repositories.SegmentStore { return s }
+
+func (s *memSegmentStore) ForOrganization(orgID string) repositories.SegmentStore {
+	return &memSegmentStore{data: s.data, orgID: orgID}
+}
+
+func (s *memSegmentStore) Create(model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	segment := model.(*models.Segment)
+	if segment.ID == "" {
+		segment.ID = utils.GenerateID("seg")
+	}
+	segment.OrganizationID = s.orgID
+	copied := *segment
+	s.data.segments = append(s.data.segments, &copied)
+	return nil
+}
+
+// find returns the stored segment matching fn. The caller must hold the
+// lock.
+func (s *memSegmentStore) find(fn func(*models.Segment) bool) *models.Segment {
+	for _, segment := range s.data.segments {
+		if segment.OrganizationID == s.orgID && fn(segment) {
+			return segment
+		}
+	}
+	return nil
+}
+
+func (s *memSegmentStore) FindByID(id string, model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	if segment := s.find(func(seg *models.Segment) bool { return seg.ID == id }); segment != nil {
+		*model.(*models.Segment) = *segment
+		return nil
+	}
+	return fmt.Errorf("record not found")
+}
+
+func (s *memSegmentStore) FindByName(name string) (*models.Segment, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	if segment := s.find(func(seg *models.Segment) bool { return seg.Name == name }); segment != nil {
+		copied := *segment
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("record not found")
+}
+
+func (s *memSegmentStore) ListAll(pagination *utils.Pagination) ([]*models.Segment, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.Segment
+	for _, segment := range s.data.segments {
+		if segment.OrganizationID == s.orgID {
+			copied := *segment
+			result = append(result, &copied)
+		}
+	}
+	pagination.SetTotal(int64(len(result)))
+	return result, nil
+}
+
+func (s *memSegmentStore) UpdateFields(id string, model interface{}, updates map[string]interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	segment := s.find(func(seg *models.Segment) bool { return seg.ID == id })
+	if segment == nil {
+		return fmt.Errorf("record not found")
+	}
+	for field, value := range updates {
+		switch field {
+		case "name":
+			segment.Name = value.(string)
+		case "description":
+			segment.Description = value.(string)
+		case "filter":
+			segment.Filter = value.(string)
+		default:
+			return fmt.Errorf("unsupported update field %s", field)
+		}
+	}
+	return nil
+}
+
+func (s *memSegmentStore) Delete(model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	id := model.(*models.Segment).ID
+	for i, segment := range s.data.segments {
+		if segment.ID == id {
+			s.data.segments = append(s.data.segments[:i], s.data.segments[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("record not found")
+}
+
+type memAttributeStore struct {
+	data  *memAttributes
+	orgID string
+}
+
+type memAttributes struct {
+	attributes []*models.AttributeDefinition
+	mu         sync.Mutex
+}
+
+func newMemAttributeStore() *memAttributeStore {
+	return &memAttributeStore{data: &memAttributes{}}
+}
+
+func (s *memAttributeStore) WithContext(ctx context.Context) repositories.AttributeStore { return s }
+
+func (s *memAttributeStore) ForOrganization(orgID string) repositories.AttributeStore {
+	return &memAttributeStore{data: s.data, orgID: orgID}
+}
+
+func (s *memAttributeStore) Create(model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	attribute := model.(*models.AttributeDefinition)
+	if attribute.ID == "" {
+		attribute.ID = utils.GenerateID("attr")
+	}
+	attribute.OrganizationID = s.orgID
+	copied := *attribute
+	s.data.attributes = append(s.data.attributes, &copied)
+	return nil
+}
+
+// find returns the stored definition matching fn. The caller must hold the
+// lock.
+func (s *memAttributeStore) find(fn func(*models.AttributeDefinition) bool) *models.AttributeDefinition {
+	for _, attribute := range s.data.attributes {
+		if attribute.OrganizationID == s.orgID && fn(attribute) {
+			return attribute
+		}
+	}
+	return nil
+}
+
+func (s *memAttributeStore) FindByID(id string, model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	if attribute := s.find(func(a *models.AttributeDefinition) bool { return a.ID == id }); attribute != nil {
+		*model.(*models.AttributeDefinition) = *attribute
+		return nil
+	}
+	return fmt.Errorf("record not found")
+}
+
+func (s *memAttributeStore) FindByName(name string) (*models.AttributeDefinition, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	if attribute := s.find(func(a *models.AttributeDefinition) bool { return a.Name == name }); attribute != nil {
+		copied := *attribute
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("record not found")
+}
+
+func (s *memAttributeStore) ListAll() ([]*models.AttributeDefinition, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.AttributeDefinition
+	for _, attribute := range s.data.attributes {
+		if attribute.OrganizationID == s.orgID {
+			copied := *attribute
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (s *memAttributeStore) UpdateFields(id string, model interface{}, updates map[string]interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	attribute := s.find(func(a *models.AttributeDefinition) bool { return a.ID == id })
+	if attribute == nil {
+		return fmt.Errorf("record not found")
+	}
+	for field, value := range updates {
+		switch field {
+		case "description":
+			attribute.Description = value.(string)
+		case "required":
+			attribute.Required = value.(bool)
+		case "options":
+			attribute.Options = value.(models.JSONArray)
+		default:
+			return fmt.Errorf("unsupported update field %s", field)
+		}
+	}
+	return nil
+}
+
+func (s *memAttributeStore) Delete(model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	id := model.(*models.AttributeDefinition).ID
+	for i, attribute := range s.data.attributes {
+		if attribute.ID == id {
+			s.data.attributes = append(s.data.attributes[:i], s.data.attributes[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("record not found")
+}
+
+type memContactImportStore struct {
+	data  *memContactImports
+	orgID string
+}
+
+type memContactImports struct {
+	imports []*models.ContactImport
+	mu      sync.Mutex
+}
+
+func newMemContactImportStore() *memContactImportStore {
+	return &memContactImportStore{data: &memContactImports{}}
+}
+
+func (s *memContactImportStore) WithContext(ctx context.Context) repositories.ContactImportStore {
+	return s
+}
+
+func (s *memContactImportStore) ForOrganization(orgID string) repositories.ContactImportStore {
+	return &memContactImportStore{data: s.data, orgID: orgID}
+}
+
+func (s *memContactImportStore) Create(model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	job := model.(*models.ContactImport)
+	if job.ID == "" {
+		job.ID = utils.GenerateID("import")
+	}
+	job.OrganizationID = s.orgID
+	// Like the unique index on active imports
+	for _, other := range s.data.imports {
+		if other.OrganizationID == job.OrganizationID && other.IsActive() && job.IsActive() {
+			return fmt.Errorf("UNIQUE constraint failed: contact_imports.organization_id")
+		}
+	}
+	job.CreatedAt = time.Now().UTC()
+	copied := *job
+	s.data.imports = append(s.data.imports, &copied)
+	return nil
+}
+
+// find returns the stored import with the ID. The caller must hold the
+// lock.
+func (s *memContactImportStore) find(id string) *models.ContactImport {
+	for _, job := range s.data.imports {
+		if job.ID == id && (s.orgID == "" || job.OrganizationID == s.orgID) {
+			return job
+		}
+	}
+	return nil
+}
+
+func (s *memContactImportStore) FindByID(id string, model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	if job := s.find(id); job != nil {
+		*model.(*models.ContactImport) = *job
+		return nil
+	}
+	return fmt.Errorf("record not found")
+}
+
+func (s *memContactImportStore) ListAll(pagination *utils.Pagination) ([]*models.ContactImport, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.ContactImport
+	for i := len(s.data.imports) - 1; i >= 0; i-- {
+		if job := s.data.imports[i]; s.orgID == "" || job.OrganizationID == s.orgID {
+			copied := *job
+			result = append(result, &copied)
+		}
+	}
+	pagination.SetTotal(int64(len(result)))
+	return result, nil
+}
+
+func (s *memContactImportStore) FindByStatus(statuses ...string) ([]*models.ContactImport, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.ContactImport
+	for _, job := range s.data.imports {
+		if (s.orgID == "" || job.OrganizationID == s.orgID) && containsString(statuses, job.Status) {
+			copied := *job
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (s *memContactImportStore) UpdateFields(id string, model interface{}, updates map[string]interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	job := s.find(id)
+	if job == nil {
+		return fmt.Errorf("record not found")
+	}
+	for field, value := range updates {
+		switch field {
+		case "status":
+			job.Status = value.(string)
+		case "error":
+			job.Error = value.(string)
+		case "processed_rows":
+			job.ProcessedRows = value.(int)
+		case "created":
+			job.Created = value.(int)
+		case "updated":
+			job.Updated = value.(int)
+		case "failed":
+			job.Failed = value.(int)
+		case "errors":
+			job.Errors = append(models.ImportRowErrors(nil), value.(models.ImportRowErrors)...)
+		case "started_at":
+			at := value.(time.Time)
+			job.StartedAt = &at
+		case "completed_at":
+			at := value.(time.Time)
+			job.CompletedAt = &at
+		case "heartbeat_at":
+			at := value.(time.Time)
+			job.HeartbeatAt = &at
+		default:
+			return fmt.Errorf("unsupported update field %s", field)
+		}
+	}
+	return nil
+}
+
+type memLeaseStore struct {
+	leases map[string]models.Lease
+	mu     sync.Mutex
+}
+
+func newMemLeaseStore() *memLeaseStore {
+	return &memLeaseStore{leases: make(map[string]models.Lease)}
+}
+
+func (s *memLeaseStore) WithContext(ctx context.Context) repositories.LeaseStore { return s }
+
+func (s *memLeaseStore) Acquire(name, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	if lease, ok := s.leases[name]; ok && lease.Owner != owner && lease.ExpiresAt.After(now) {
+		return false, nil
+	}
+	s.leases[name] = models.Lease{Name: name, Owner: owner, ExpiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+func (s *memLeaseStore) Release(name, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leases[name].Owner == owner {
+		delete(s.leases, name)
+	}
+	return nil
+}
+
+type memRetentionPolicyStore struct {
+	data  *memRetentionPolicies
+	orgID string
+}
+
+type memRetentionPolicies struct {
+	policies []*models.RetentionPolicy
+	mu       sync.Mutex
+}
+
+func newMemRetentionPolicyStore() *memRetentionPolicyStore {
+	return &memRetentionPolicyStore{data: &memRetentionPolicies{}}
+}
+
+func (s *memRetentionPolicyStore) WithContext(ctx context.Context) repositories.RetentionPolicyStore {
+	return s
+}
+
+func (s *memRetentionPolicyStore) ForOrganization(orgID string) repositories.RetentionPolicyStore {
+	return &memRetentionPolicyStore{data: s.data, orgID: orgID}
+}
+
+// ListAll lists every organization's policies when unscoped
+func (s *memRetentionPolicyStore) ListAll() ([]*models.RetentionPolicy, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.RetentionPolicy
+	for _, policy := range s.data.policies {
+		if s.orgID == "" || policy.OrganizationID == s.orgID {
+			copied := *policy
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].OrganizationID != result[j].OrganizationID {
+			return result[i].OrganizationID < result[j].OrganizationID
+		}
+		return result[i].MessageType < result[j].MessageType
+	})
+	return result, nil
+}
+
+func (s *memRetentionPolicyStore) FindByType(messageType string) (*models.RetentionPolicy, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	for _, policy := range s.data.policies {
+		if policy.OrganizationID == s.orgID && policy.MessageType == messageType {
+			copied := *policy
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("record not found")
+}
+
+func (s *memRetentionPolicyStore) Upsert(policy *models.RetentionPolicy) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	policy.OrganizationID = s.orgID
+	for i, existing := range s.data.policies {
+		if existing.OrganizationID == s.orgID && existing.MessageType == policy.MessageType {
+			policy.ID, policy.CreatedAt = existing.ID, existing.CreatedAt
+			copied := *policy
+			s.data.policies[i] = &copied
+			return nil
+		}
+	}
+	if policy.ID == "" {
+		policy.ID = utils.GenerateID("retention")
+	}
+	copied := *policy
+	s.data.policies = append(s.data.policies, &copied)
+	return nil
+}
+
+func (s *memRetentionPolicyStore) DeleteByType(messageType string) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	for i, policy := range s.data.policies {
+		if policy.OrganizationID == s.orgID && policy.MessageType == messageType {
+			s.data.policies = append(s.data.policies[:i], s.data.policies[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("record not found")
+}
+
+type memOrganizationStore struct {
+	orgs map[string]*models.Organization
+	mu   sync.Mutex
+}
+
+func newMemOrganizationStore() *memOrganizationStore {
+	return &memOrganizationStore{orgs: make(map[string]*models.Organization)}
+}
+
+func (s *memOrganizationStore) WithContext(ctx context.Context) repositories.OrganizationStore {
+	return s
+}
+
+func (s *memOrganizationStore) Create(model interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	org := model.(*models.Organization)
+	if org.ID == "" {
+		org.ID = utils.GenerateID("org")
+	}
+	if org.Status == "" {
+		org.Status = models.OrganizationStatusActive
+	}
+	copied := *org
+	s.orgs[org.ID] = &copied
+	return nil
+}
+
+func (s *memOrganizationStore) FindByID(id string, model interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	org, ok := s.orgs[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	*model.(*models.Organization) = *org
+	return nil
+}
+
+func (s *memOrganizationStore) find(match func(*models.Organization) bool) (*models.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, org := range s.orgs {
+		if match(org) {
+			copied := *org
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("record not found")
+}
+
+func (s *memOrganizationStore) FindByBusinessAccountID(wabaID string) (*models.Organization, error) {
+	return s.find(func(org *models.Organization) bool { return org.BusinessAccountID == wabaID })
+}
+
+func (s *memOrganizationStore) FindByPhoneNumberID(phoneNumberID string) (*models.Organization, error) {
+	return s.find(func(org *models.Organization) bool { return org.PhoneNumberID == phoneNumberID })
+}
+
+func (s *memOrganizationStore) FindByWebhookVerifyToken(token string) (*models.Organization, error) {
+	return s.find(func(org *models.Organization) bool { return org.WebhookVerifyToken == token })
+}
+
+func (s *memOrganizationStore) ListAll(pagination *utils.Pagination) ([]*models.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []*models.Organization
+	for _, org := range s.orgs {
+		copied := *org
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	pagination.SetTotal(int64(len(result)))
+	return result, nil
+}
+
+// UpdateFields supports the status and name columns
+func (s *memOrganizationStore) UpdateFields(id string, model interface{}, updates map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	org, ok := s.orgs[id]
+	if !ok {
+		return fmt.Errorf("record not found")
+	}
+	if status, ok := updates["status"].(string); ok {
+		org.Status = status
+	}
+	if name, ok := updates["name"].(string); ok {
+		org.Name = name
+	}
+	org.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+func (s *memOrganizationStore) FindUpdatedSince(since time.Time) ([]*models.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []*models.Organization
+	for _, org := range s.orgs {
+		if org.UpdatedAt.After(since) {
+			copied := *org
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (s *memOrganizationStore) Delete(model interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.orgs, model.(*models.Organization).ID)
+	return nil
+}
+
+func (s *memOrganizationStore) AssignOrphans(orgID string) error { return nil }
+
+type memAPIKeyStore struct {
+	data  *memAPIKeys
+	orgID string
+}
+
+type memAPIKeys struct {
+	keys []*models.APIKey
+	mu   sync.Mutex
+}
+
+func newMemAPIKeyStore() *memAPIKeyStore {
+	return &memAPIKeyStore{data: &memAPIKeys{}}
+}
+
+func (s *memAPIKeyStore) WithContext(ctx context.Context) repositories.APIKeyStore { return s }
+
+func (s *memAPIKeyStore) ForOrganization(orgID string) repositories.APIKeyStore {
+	return &memAPIKeyStore{data: s.data, orgID: orgID}
+}
+
+func (s *memAPIKeyStore) visible(apiKey *models.APIKey) bool {
+	return s.orgID == "" || apiKey.OrganizationID == s.orgID
+}
+
+func (s *memAPIKeyStore) Create(model interface{}) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	apiKey := model.(*models.APIKey)
+	apiKey.ID = utils.GenerateID("key")
+	if s.orgID != "" {
+		apiKey.OrganizationID = s.orgID
+	}
+	copied := *apiKey
+	s.data.keys = append(s.data.keys, &copied)
+	return nil
+}
+
+func (s *memAPIKeyStore) find(match func(*models.APIKey) bool) []*models.APIKey {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var result []*models.APIKey
+	for _, apiKey := range s.data.keys {
+		if s.visible(apiKey) && match(apiKey) {
+			copied := *apiKey
+			result = append(result, &copied)
+		}
+	}
+	return result
+}
+
+func (s *memAPIKeyStore) FindByID(id string, model interface{}) error {
+	found := s.find(func(apiKey *models.APIKey) bool { return apiKey.ID == id })
+	if len(found) == 0 {
+		return fmt.Errorf("record not found")
+	}
+	*model.(*models.APIKey) = *found[0]
+	return nil
+}
+
+func (s *memAPIKeyStore) FindByKeyHash(keyHash string) (*models.APIKey, error) {
+	found := s.find(func(apiKey *models.APIKey) bool { return apiKey.KeyHash == keyHash })
+	if len(found) == 0 {
+		return nil, fmt.Errorf("record not found")
+	}
+	return found[0], nil
+}
+
+func (s *memAPIKeyStore) FindByKeyPrefix(prefix string) ([]*models.APIKey, error) {
+	return s.find(func(apiKey *models.APIKey) bool { return apiKey.KeyPrefix == prefix }), nil
+}
+
+func (s *memAPIKeyStore) FindUpdatedSince(since time.Time) ([]*models.APIKey, error) {
+	return s.find(func(apiKey *models.APIKey) bool { return apiKey.UpdatedAt.After(since) }), nil
+}
+
+func (s *memAPIKeyStore) ListAll() ([]*models.APIKey, error) {
+	return s.find(func(*models.APIKey) bool { return true }), nil
+}
+
+// update applies fn to the visible key with the given ID
+func (s *memAPIKeyStore) update(id string, fn func(*models.APIKey)) error {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	for _, apiKey := range s.data.keys {
+		if apiKey.ID == id && s.visible(apiKey) {
+			fn(apiKey)
+			return nil
+		}
+	}
+	return fmt.Errorf("record not found")
+}
+
+// UpdateFields supports the expires_at column
+func (s *memAPIKeyStore) UpdateFields(id string, model interface{}, updates map[string]interface{}) error {
+	return s.update(id, func(apiKey *models.APIKey) {
+		if expiresAt, ok := updates["expires_at"].(time.Time); ok {
+			apiKey.ExpiresAt = &expiresAt
+		}
+		apiKey.UpdatedAt = time.Now().UTC()
+	})
+}
+
+func (s *memAPIKeyStore) UpdateKeyHash(id, keyHash string) error {
+	return s.update(id, func(apiKey *models.APIKey) { apiKey.KeyHash = keyHash })
+}
+
+func (s *memAPIKeyStore) UpdateLastUsedBatch(lastUsed map[string]time.Time) error { return nil }
+
+func (s *memAPIKeyStore) Revoke(id, reason string) error {
+	return s.update(id, func(apiKey *models.APIKey) {
+		now := time.Now().UTC()
+		apiKey.RevokedAt = &now
+		apiKey.RevokedReason = reason
+		apiKey.UpdatedAt = now
+	})
+}
+
+func (s *memAPIKeyStore) RevokeAll(reason string) (int64, error) {
+	s.data.mu.Lock()
+	defer s.data.mu.Unlock()
+
+	var revoked int64
+	now := time.Now().UTC()
+	for _, apiKey := range s.data.keys {
+		if s.visible(apiKey) && apiKey.RevokedAt == nil {
+			apiKey.RevokedAt = &now
+			apiKey.RevokedReason = reason
+			apiKey.UpdatedAt = now
+			revoked++
+		}
+	}
+	return revoked, nil
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/storage"
+	"github.com/google/uuid"
+)
+
+// MessageArchive keeps the messages retention policies archive, as gzipped
+// JSON Lines files in a media store. Each organization's archives are
+// stored under archives/<organization ID>/, one file per batch archived.
+type MessageArchive struct {
+	store storage.MediaStore
+}
+
+// NewMessageArchive creates a message archive in a media store
+func NewMessageArchive(store storage.MediaStore) *MessageArchive {
+	return &MessageArchive{store: store}
+}
+
+// archivePrefix returns the prefix of an organization's archive files
+func archivePrefix(orgID string) (string, error) {
+	if orgID == "" || orgID == "." || orgID == ".." || strings.ContainsAny(orgID, `/\`) {
+		return "", fmt.Errorf("invalid organization ID %q", orgID)
+	}
+	return "archives/" + orgID, nil
+}
+
+// Write stores messages in a new archive file and returns its key
+func (a *MessageArchive) Write(orgID string, messages []*models.ArchivedMessage) (string, error) {
+	prefix, err := archivePrefix(orgID)
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("%s/%s-%s.jsonl.gz", prefix, time.Now().UTC().Format("20060102T150405Z"), uuid.New().String()[:8])
+
+	var lines bytes.Buffer
+	encoder := json.NewEncoder(&lines)
+	for _, message := range messages {
+		if err := encoder.Encode(message); err != nil {
+			return "", err
+		}
+	}
+	if err := a.writeFile(key, lines.Bytes()); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Scan calls fn with each archived message of an organization, file by
+// file in the order they were written. Scanning stops at the first error
+// fn returns.
+func (a *MessageArchive) Scan(orgID string, fn func(*models.ArchivedMessage) error) error {
+	keys, err := a.keys(orgID)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err := a.readFile(key, func(line []byte) error {
+			var message models.ArchivedMessage
+			if err := json.Unmarshal(line, &message); err != nil {
+				return fmt.Errorf("archive %s: %w", key, err)
+			}
+			return fn(&message)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Filter rewrites an organization's archives, keeping the messages keep
+// returns true for, and returns how many were removed. keep may change the
+// messages it keeps, such as to drop some of their reactions. Files left
+// empty are deleted.
+func (a *MessageArchive) Filter(orgID string, keep func(*models.ArchivedMessage) bool) (int64, error) {
+	keys, err := a.keys(orgID)
+	if err != nil {
+		return 0, err
+	}
+	var removed int64
+	for _, key := range keys {
+		var kept bytes.Buffer
+		var dropped int64
+		changed := false
+		err := a.readFile(key, func(line []byte) error {
+			var message models.ArchivedMessage
+			if err := json.Unmarshal(line, &message); err != nil {
+				return fmt.Errorf("archive %s: %w", key, err)
+			}
+			if !keep(&message) {
+				dropped++
+				changed = true
+				return nil
+			}
+			encoded, err := json.Marshal(&message)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(encoded, line) {
+				changed = true
+			}
+			kept.Write(encoded)
+			kept.WriteByte('\n')
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+		if !changed {
+			continue
+		}
+
+		if kept.Len() == 0 {
+			err = a.store.Delete(key)
+		} else {
+			err = a.writeFile(key, kept.Bytes())
+		}
+		if err != nil {
+			return removed, err
+		}
+		removed += dropped
+	}
+	return removed, nil
+}
+
+// keys returns the keys of an organization's archive files
+func (a *MessageArchive) keys(orgID string) ([]string, error) {
+	prefix, err := archivePrefix(orgID)
+	if err != nil {
+		return nil, err
+	}
+	return a.store.List(prefix)
+}
+
+// writeFile stores JSON lines as a gzipped file under key
+func (a *MessageArchive) writeFile(key string, lines []byte) error {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	if _, err := gz.Write(lines); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	file, err := a.store.Create(key)
+	if err != nil {
+		return err
+	}
+	if _, err := compressed.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readFile calls fn with each line of the gzipped file stored under key
+func (a *MessageArchive) readFile(key string, fn func(line []byte) error) error {
+	file, err := a.store.Open(key)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("archive %s: %w", key, err)
+	}
+	reader := bufio.NewReader(compressed)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if fnErr := fn(line); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("archive %s: %w", key, err)
+		}
+	}
+}
@@ -1,13 +1,17 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/logger"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/validator"
 	"go.uber.org/zap"
@@ -15,29 +19,45 @@ import (
 
 // MessageService handles message business logic
 type MessageService struct {
-	messageRepo  *repositories.MessageRepository
-	contactRepo  *repositories.ContactRepository
-	waClient     *whatsapp.Client
-	logger       *zap.Logger
+	messageRepo      repositories.MessageStore
+	contactRepo      repositories.ContactStore
+	reactionRepo     repositories.ReactionStore
+	attributeService *AttributeService
+	senders          SenderResolver
+	logger           *zap.Logger
+	autoMarkRead     bool
 }
 
-// NewMessageService creates a new message service
+// NewMessageService creates a new message service. Template parameters can
+// be filled in from contact attributes.
 func NewMessageService(
-	messageRepo *repositories.MessageRepository,
-	contactRepo *repositories.ContactRepository,
-	waClient *whatsapp.Client,
+	messageRepo repositories.MessageStore,
+	contactRepo repositories.ContactStore,
+	reactionRepo repositories.ReactionStore,
+	attributeService *AttributeService,
+	senders SenderResolver,
 	logger *zap.Logger,
 ) *MessageService {
 	return &MessageService{
-		messageRepo: messageRepo,
-		contactRepo: contactRepo,
-		waClient:    waClient,
-		logger:      logger,
+		messageRepo:      messageRepo,
+		contactRepo:      contactRepo,
+		reactionRepo:     reactionRepo,
+		attributeService: attributeService,
+		senders:          senders,
+		logger:           logger,
 	}
 }
 
-// SendTextMessage sends a text message
-func (s *MessageService) SendTextMessage(phone, content string) (*models.Message, error) {
+// SetAutoMarkRead makes message sends mark the customer's latest message as
+// read, as an agent opening the chat would. Automations replying through the
+// API rely on it.
+func (s *MessageService) SetAutoMarkRead(enabled bool) {
+	s.autoMarkRead = enabled
+}
+
+// SendTextMessage sends a text message, quoting the message with ID replyTo
+// when it is set
+func (s *MessageService) SendTextMessage(ctx context.Context, orgID, phone, content, replyTo string) (*models.Message, error) {
 	// Validate phone number
 	if err := validator.ValidatePhoneNumber(phone); err != nil {
 		return nil, errors.NewInvalidPhoneNumberError(phone)
@@ -48,51 +68,24 @@ func (s *MessageService) SendTextMessage(phone, content string) (*models.Message
 		return nil, errors.NewBadRequest(err.Error())
 	}
 
-	// Get or create contact
-	_, err := s.contactRepo.GetOrCreate(phone)
-	if err != nil {
-		s.logger.Error("Failed to get/create contact", zap.Error(err))
-		return nil, errors.NewDatabaseError(err)
-	}
-
-	// Send message via WhatsApp
-	resp, err := s.waClient.SendTextMessage(phone, content)
+	quoted, err := s.findQuotedMessage(ctx, orgID, phone, replyTo)
 	if err != nil {
-		s.logger.Error("Failed to send WhatsApp message", zap.Error(err))
 		return nil, err
 	}
 
-	// Create message record
 	message := &models.Message{
-		WhatsAppMessageID: resp.Messages[0].ID,
-		FromNumber:        resp.Contacts[0].Input,
-		ToNumber:          phone,
-		Direction:         "outbound",
-		MessageType:       models.MessageTypeText,
-		Content:           content,
-		Status:            models.MessageStatusSent,
-		Timestamp:         time.Now().UTC(),
-	}
-
-	if err := s.messageRepo.Create(message); err != nil {
-		s.logger.Error("Failed to save message", zap.Error(err))
-		return nil, errors.NewDatabaseError(err)
+		MessageType: models.MessageTypeText,
+		Content:     content,
 	}
 
-	// Update contact last message time
-	s.contactRepo.UpdateLastMessage(phone, message.Timestamp)
-	s.contactRepo.IncrementMessageCount(phone, 1)
-
-	s.logger.Info("Message sent successfully",
-		zap.String("message_id", message.ID),
-		zap.String("phone", phone),
-	)
-
-	return message, nil
+	return s.send(ctx, orgID, phone, message, quoted, func(ctx context.Context, sender Sender, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+		return sender.SendTextMessage(ctx, phone, content, opts)
+	})
 }
 
-// SendMediaMessage sends a media message
-func (s *MessageService) SendMediaMessage(phone, mediaURL, caption, mediaType string) (*models.Message, error) {
+// SendMediaMessage sends a media message, quoting the message with ID
+// replyTo when it is set
+func (s *MessageService) SendMediaMessage(ctx context.Context, orgID, phone, mediaURL, caption, mediaType, replyTo string) (*models.Message, error) {
 	// Validate phone number
 	if err := validator.ValidatePhoneNumber(phone); err != nil {
 		return nil, errors.NewInvalidPhoneNumberError(phone)
@@ -103,77 +96,52 @@ func (s *MessageService) SendMediaMessage(phone, mediaURL, caption, mediaType st
 		return nil, errors.NewBadRequest("invalid media URL: " + err.Error())
 	}
 
-	// Validate message type
-	if err := validator.ValidateMessageType(mediaType); err != nil {
+	// Validate media type
+	if err := validator.ValidateMediaType(mediaType); err != nil {
 		return nil, errors.NewBadRequest(err.Error())
 	}
-
-	// Get or create contact
-	_, err := s.contactRepo.GetOrCreate(phone)
-	if err != nil {
-		return nil, errors.NewDatabaseError(err)
+	if mediaType == models.MessageTypeSticker && caption != "" {
+		return nil, errors.NewBadRequest("stickers cannot have a caption")
 	}
 
-	// Send message via WhatsApp
-	resp, err := s.waClient.SendMediaMessage(phone, mediaURL, caption, whatsapp.MediaType(mediaType))
+	quoted, err := s.findQuotedMessage(ctx, orgID, phone, replyTo)
 	if err != nil {
-		s.logger.Error("Failed to send media message", zap.Error(err))
 		return nil, err
 	}
 
-	// Create message record
 	message := &models.Message{
-		WhatsAppMessageID: resp.Messages[0].ID,
-		FromNumber:        resp.Contacts[0].Input,
-		ToNumber:          phone,
-		Direction:         "outbound",
-		MessageType:       mediaType,
-		Content:           caption,
-		MediaURL:          mediaURL,
-		Status:            models.MessageStatusSent,
-		Timestamp:         time.Now().UTC(),
-	}
-
-	if err := s.messageRepo.Create(message); err != nil {
-		return nil, errors.NewDatabaseError(err)
+		MessageType: mediaType,
+		Caption:     caption,
+		MediaURL:    mediaURL,
 	}
 
-	// Update contact
-	s.contactRepo.UpdateLastMessage(phone, message.Timestamp)
-	s.contactRepo.IncrementMessageCount(phone, 1)
-
-	return message, nil
+	return s.send(ctx, orgID, phone, message, quoted, func(ctx context.Context, sender Sender, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+		return sender.SendMediaMessage(ctx, phone, mediaURL, caption, whatsapp.MediaType(mediaType), opts)
+	})
 }
 
-// SendTemplateMessage sends a template message
-func (s *MessageService) SendTemplateMessage(phone, templateName, language string, params []string) (*models.Message, error) {
+// SendTemplateMessage sends a template message, quoting the message with ID
+// replyTo when it is set. Parameters written as {{name}}, {{phone_number}}
+// or {{attributes.<name>}} are filled in from the recipient contact.
+func (s *MessageService) SendTemplateMessage(ctx context.Context, orgID, phone, templateName, language string, params []string, replyTo string) (*models.Message, error) {
 	// Validate inputs
 	if err := validator.ValidatePhoneNumber(phone); err != nil {
 		return nil, errors.NewInvalidPhoneNumberError(phone)
 	}
 
-	// Get or create contact
-	_, err := s.contactRepo.GetOrCreate(phone)
+	params, err := s.resolveTemplateParameters(ctx, orgID, phone, params)
 	if err != nil {
-		return nil, errors.NewDatabaseError(err)
+		return nil, err
 	}
 
-	// Send template message
-	resp, err := s.waClient.SendTemplateMessage(phone, templateName, language, params)
+	quoted, err := s.findQuotedMessage(ctx, orgID, phone, replyTo)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create message record
 	message := &models.Message{
-		WhatsAppMessageID: resp.Messages[0].ID,
-		FromNumber:        resp.Contacts[0].Input,
-		ToNumber:          phone,
-		Direction:         "outbound",
-		MessageType:       models.MessageTypeTemplate,
-		Content:           fmt.Sprintf("Template: %s", templateName),
-		Status:            models.MessageStatusSent,
-		Timestamp:         time.Now().UTC(),
+		MessageType: models.MessageTypeTemplate,
+		Content:     fmt.Sprintf("Template: %s", templateName),
 		Metadata: models.JSONMap{
 			"template_name": templateName,
 			"language":      language,
@@ -181,52 +149,444 @@ func (s *MessageService) SendTemplateMessage(phone, templateName, language strin
 		},
 	}
 
-	if err := s.messageRepo.Create(message); err != nil {
+	return s.send(ctx, orgID, phone, message, quoted, func(ctx context.Context, sender Sender, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+		return sender.SendTemplateMessage(ctx, phone, templateName, language, params, opts)
+	})
+}
+
+// resolveTemplateParameters fills in the template parameters taken from the
+// recipient contact. Recipients without a contact have no name or
+// attributes.
+func (s *MessageService) resolveTemplateParameters(ctx context.Context, orgID, phone string, params []string) ([]string, error) {
+	if !usesContactParameters(params) {
+		return params, nil
+	}
+
+	contact, err := s.contactRepo.WithContext(ctx).ForOrganization(orgID).FindByPhone(contactPhoneNumber(phone))
+	if err != nil {
+		contact = &models.Contact{PhoneNumber: phone}
+	}
+	return s.attributeService.ResolveTemplateParameters(ctx, orgID, contact, params)
+}
+
+// SendLocationMessage sends a location pin, quoting the message with ID
+// replyTo when it is set
+func (s *MessageService) SendLocationMessage(ctx context.Context, orgID, phone string, location whatsapp.Location, replyTo string) (*models.Message, error) {
+	if err := validator.ValidatePhoneNumber(phone); err != nil {
+		return nil, errors.NewInvalidPhoneNumberError(phone)
+	}
+	if err := validator.ValidateCoordinates(location.Latitude, location.Longitude); err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+
+	quoted, err := s.findQuotedMessage(ctx, orgID, phone, replyTo)
+	if err != nil {
+		return nil, err
+	}
+
+	// The name and address are the searchable text of a location
+	var content []string
+	for _, part := range []string{location.Name, location.Address} {
+		if part != "" {
+			content = append(content, part)
+		}
+	}
+
+	message := &models.Message{
+		MessageType: models.MessageTypeLocation,
+		Content:     strings.Join(content, ", "),
+		Metadata: models.JSONMap{
+			"latitude":  location.Latitude,
+			"longitude": location.Longitude,
+			"name":      location.Name,
+			"address":   location.Address,
+		},
+	}
+
+	return s.send(ctx, orgID, phone, message, quoted, func(ctx context.Context, sender Sender, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+		return sender.SendLocationMessage(ctx, phone, location, opts)
+	})
+}
+
+// SendLocationRequest asks the customer to share their location, quoting
+// the message with ID replyTo when it is set
+func (s *MessageService) SendLocationRequest(ctx context.Context, orgID, phone, body, replyTo string) (*models.Message, error) {
+	if err := validator.ValidatePhoneNumber(phone); err != nil {
+		return nil, errors.NewInvalidPhoneNumberError(phone)
+	}
+	if err := validator.ValidateNotEmpty(body, "content"); err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+	if err := validator.ValidateMaxLength(body, "content", whatsapp.MaxInteractiveBodyLength); err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+
+	quoted, err := s.findQuotedMessage(ctx, orgID, phone, replyTo)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &models.Message{
+		MessageType: models.MessageTypeLocationRequest,
+		Content:     body,
+	}
+
+	return s.send(ctx, orgID, phone, message, quoted, func(ctx context.Context, sender Sender, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+		return sender.SendLocationRequest(ctx, phone, body, opts)
+	})
+}
+
+// SendContactsMessage shares contact cards, quoting the message with ID
+// replyTo when it is set
+func (s *MessageService) SendContactsMessage(ctx context.Context, orgID, phone string, contacts []whatsapp.ContactCard, replyTo string) (*models.Message, error) {
+	if err := validator.ValidatePhoneNumber(phone); err != nil {
+		return nil, errors.NewInvalidPhoneNumberError(phone)
+	}
+	if len(contacts) == 0 {
+		return nil, errors.NewBadRequest("contacts is required")
+	}
+
+	names := make([]string, len(contacts))
+	for i, contact := range contacts {
+		if err := validateContactCard(contact); err != nil {
+			return nil, errors.NewBadRequest(fmt.Sprintf("contacts[%d]: %s", i, err.Error()))
+		}
+		names[i] = contact.Name.FormattedName
+	}
+
+	quoted, err := s.findQuotedMessage(ctx, orgID, phone, replyTo)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &models.Message{
+		MessageType: models.MessageTypeContacts,
+		Content:     strings.Join(names, ", "),
+		Metadata: models.JSONMap{
+			"contacts": contacts,
+		},
+	}
+
+	return s.send(ctx, orgID, phone, message, quoted, func(ctx context.Context, sender Sender, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error) {
+		return sender.SendContactsMessage(ctx, phone, contacts, opts)
+	})
+}
+
+// validateContactCard checks the fields WhatsApp needs to render a contact
+func validateContactCard(contact whatsapp.ContactCard) error {
+	if err := validator.ValidateNotEmpty(contact.Name.FormattedName, "name.formatted_name"); err != nil {
+		return err
+	}
+	for _, phone := range contact.Phones {
+		if err := validator.ValidateNotEmpty(phone.Phone, "phones.phone"); err != nil {
+			return err
+		}
+	}
+	for _, email := range contact.Emails {
+		if err := validator.ValidateEmail(email.Email); err != nil {
+			return err
+		}
+	}
+	for _, u := range contact.URLs {
+		if err := validator.ValidateURL(u.URL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send delivers an outbound message to phone through the organization's
+// sender, quoting quoted when it is set, then stores it and updates the
+// contact
+func (s *MessageService) send(ctx context.Context, orgID, phone string, message, quoted *models.Message, deliver func(context.Context, Sender, whatsapp.SendOptions) (*whatsapp.MessageResponse, error)) (*models.Message, error) {
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+	contactPhone := contactPhoneNumber(phone)
+	if err := s.checkNotBlocked(ctx, contactRepo, phone); err != nil {
+		return nil, err
+	}
+
+	sender, err := s.senders.Sender(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	message.ToNumber = phone
+	message.Direction = "outbound"
+
+	var opts whatsapp.SendOptions
+	if quoted != nil {
+		message.ReplyToMessageID = quoted.ID
+		message.ReplyToWhatsAppMessageID = quoted.WhatsAppMessageID
+		opts.ReplyTo = quoted.WhatsAppMessageID
+	}
+
+	// Send message via WhatsApp
+	resp, err := deliver(ctx, sender, opts)
+	if err != nil {
+		logger.FromContext(ctx, s.logger).Error("Failed to send WhatsApp message",
+			zap.String("type", message.MessageType),
+			zap.Error(err),
+		)
+		return nil, s.recordFailedSend(ctx, orgID, message, err)
+	}
+
+	if err := s.recordSent(ctx, orgID, message, resp); err != nil {
+		logger.FromContext(ctx, s.logger).Error("Failed to save message", zap.Error(err))
+		return nil, err
+	}
+
+	// Update contact last message time
+	contactRepo.UpdateLastMessage(contactPhone, message.Timestamp)
+	contactRepo.IncrementMessageCount(contactPhone, 1)
+	s.markConversationRead(ctx, orgID, sender, phone)
+
+	logger.FromContext(ctx, s.logger).Info("Message sent successfully",
+		zap.String("message_id", message.ID),
+		zap.String("organization_id", orgID),
+		zap.String("phone", phone),
+		zap.String("type", message.MessageType),
+	)
+
+	return message, nil
+}
+
+// checkNotBlocked gets or creates the contact of phone, and refuses sends
+// to it when it is blocked. Every send goes through it.
+func (s *MessageService) checkNotBlocked(ctx context.Context, contactRepo repositories.ContactStore, phone string) error {
+	contact, err := contactRepo.GetOrCreate(contactPhoneNumber(phone))
+	if err != nil {
+		logger.FromContext(ctx, s.logger).Error("Failed to get/create contact", zap.Error(err))
+		return errors.NewDatabaseError(err)
+	}
+	if contact.Blocked {
+		logger.FromContext(ctx, s.logger).Warn("Refused send to blocked contact",
+			zap.String("contact_id", contact.ID),
+		)
+		return errors.NewContactBlockedError(phone)
+	}
+	return nil
+}
+
+// SendReaction reacts to the message with ID messageID, replacing our
+// earlier reaction to it. An empty emoji removes the reaction.
+func (s *MessageService) SendReaction(ctx context.Context, orgID, phone, messageID, emoji string) (*models.Reaction, error) {
+	if err := validator.ValidatePhoneNumber(phone); err != nil {
+		return nil, errors.NewInvalidPhoneNumberError(phone)
+	}
+	if messageID == "" {
+		return nil, errors.NewBadRequest("reply_to is required for reactions")
+	}
+
+	target, err := s.findQuotedMessage(ctx, orgID, phone, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkNotBlocked(ctx, s.contactRepo.WithContext(ctx).ForOrganization(orgID), phone); err != nil {
+		return nil, err
+	}
+
+	sender, err := s.senders.Sender(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := sender.SendReaction(ctx, phone, target.WhatsAppMessageID, emoji)
+	if err != nil {
+		logger.FromContext(ctx, s.logger).Error("Failed to send reaction", zap.Error(err))
+		return nil, err
+	}
+
+	reaction := &models.Reaction{
+		OrganizationID:          orgID,
+		MessageID:               target.ID,
+		TargetWhatsAppMessageID: target.WhatsAppMessageID,
+		WhatsAppMessageID:       resp.Messages[0].ID,
+		FromNumber:              resp.Contacts[0].Input,
+		Direction:               "outbound",
+		Emoji:                   emoji,
+		Timestamp:               time.Now().UTC(),
+	}
+
+	reactionRepo := s.reactionRepo.WithContext(ctx).ForOrganization(orgID)
+	if emoji == "" {
+		err = reactionRepo.Remove(reaction.TargetWhatsAppMessageID, reaction.FromNumber, reaction.Direction)
+	} else {
+		err = reactionRepo.Upsert(reaction)
+	}
+	if err != nil {
 		return nil, errors.NewDatabaseError(err)
 	}
+	return reaction, nil
+}
 
-	// Update contact
-	s.contactRepo.UpdateLastMessage(phone, message.Timestamp)
-	s.contactRepo.IncrementMessageCount(phone, 1)
+// MarkAsRead marks an inbound message, and the customer's messages before it,
+// as read on WhatsApp and clears the contact's unread count. With typing set
+// the customer also sees a typing indicator.
+func (s *MessageService) MarkAsRead(ctx context.Context, orgID, messageID string, typing bool) (*models.Message, error) {
+	message, err := s.GetMessage(ctx, orgID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Direction != "inbound" {
+		return nil, errors.NewBadRequest("only inbound messages can be marked as read")
+	}
+
+	sender, err := s.senders.Sender(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
 
+	if err := s.markRead(ctx, orgID, sender, message, typing); err != nil {
+		logger.FromContext(ctx, s.logger).Error("Failed to mark message as read", zap.Error(err))
+		return nil, err
+	}
 	return message, nil
 }
 
+// markRead sends the read receipt of an inbound message and records it
+func (s *MessageService) markRead(ctx context.Context, orgID string, sender Sender, message *models.Message, typing bool) error {
+	if err := sender.MarkAsRead(ctx, message.WhatsAppMessageID, typing); err != nil {
+		return err
+	}
+
+	message.Status = models.MessageStatusRead
+	updates := map[string]interface{}{"status": message.Status}
+	if err := s.messageRepo.WithContext(ctx).ForOrganization(orgID).UpdateByWhatsAppMessageID(message.WhatsAppMessageID, updates); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	if err := s.contactRepo.WithContext(ctx).ForOrganization(orgID).ResetUnreadCount(message.FromNumber); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// markConversationRead marks the customer's latest message as read after we
+// reply to phone, when auto mark-read is on and the message is not from a
+// blocked contact. It never fails the reply.
+func (s *MessageService) markConversationRead(ctx context.Context, orgID string, sender Sender, phone string) {
+	if !s.autoMarkRead {
+		return
+	}
+
+	// Inbound messages are stored under the sender's WhatsApp ID
+	filters := map[string]interface{}{"phone": strings.TrimPrefix(phone, "+"), "direction": "inbound"}
+	latest, err := s.messageRepo.WithContext(ctx).ForOrganization(orgID).ListWithFilters(filters, &utils.Pagination{Limit: 1, SkipTotal: true})
+	// Messages from blocked contacts are never read receipted
+	if err != nil || len(latest) == 0 || latest[0].Status == models.MessageStatusRead || latest[0].Blocked {
+		return
+	}
+	if err := s.markRead(ctx, orgID, sender, latest[0], false); err != nil {
+		logger.FromContext(ctx, s.logger).Warn("Failed to mark conversation as read", zap.Error(err))
+	}
+}
+
+// findQuotedMessage returns the message with ID messageID that a send to
+// phone replies or reacts to, or nil when messageID is empty. The message must
+// belong to the conversation with phone and have reached WhatsApp.
+func (s *MessageService) findQuotedMessage(ctx context.Context, orgID, phone, messageID string) (*models.Message, error) {
+	if messageID == "" {
+		return nil, nil
+	}
+
+	var message models.Message
+	if err := s.messageRepo.WithContext(ctx).ForOrganization(orgID).FindByID(messageID, &message); err != nil {
+		return nil, errors.NewNotFound("Message", messageID)
+	}
+	if !samePhone(message.FromNumber, phone) && !samePhone(message.ToNumber, phone) {
+		return nil, errors.NewBadRequest("message " + messageID + " is not part of the conversation with " + phone)
+	}
+	if message.WhatsAppMessageID == "" {
+		return nil, errors.NewBadRequest("message " + messageID + " was not delivered to WhatsApp")
+	}
+	return &message, nil
+}
+
+// samePhone reports whether two numbers are the same. Webhooks report
+// WhatsApp IDs, which are phone numbers without the leading +.
+func samePhone(a, b string) bool {
+	return strings.TrimPrefix(a, "+") == strings.TrimPrefix(b, "+")
+}
+
+// recordSent stores an outbound message the WhatsApp API accepted
+func (s *MessageService) recordSent(ctx context.Context, orgID string, message *models.Message, resp *whatsapp.MessageResponse) error {
+	message.WhatsAppMessageID = resp.Messages[0].ID
+	message.FromNumber = resp.Contacts[0].Input
+	message.Status = models.MessageStatusSent
+	message.Timestamp = time.Now().UTC()
+
+	if err := s.messageRepo.WithContext(ctx).ForOrganization(orgID).Create(message); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// recordFailedSend stores an outbound message the WhatsApp API rejected, with
+// the classified error, and returns the send error. Sends that never reached
+// the API (throttled, network failures) are not stored.
+func (s *MessageService) recordFailedSend(ctx context.Context, orgID string, message *models.Message, sendErr error) error {
+	apiErr, ok := whatsapp.AsAPIError(sendErr)
+	if !ok {
+		return sendErr
+	}
+
+	message.FromNumber = message.ToNumber
+	message.Status = models.MessageStatusFailed
+	message.Timestamp = time.Now().UTC()
+	message.ErrorCode = strconv.Itoa(apiErr.Code)
+	message.ErrorMessage = apiErr.Message
+	message.ErrorCategory = string(apiErr.Category)
+	message.ErrorRetryable = apiErr.Category.Retryable()
+	message.ErrorTraceID = apiErr.FBTraceID
+
+	if err := s.messageRepo.WithContext(ctx).ForOrganization(orgID).Create(message); err != nil {
+		logger.FromContext(ctx, s.logger).Error("Failed to save failed message", zap.Error(err))
+	}
+	return sendErr
+}
+
 // GetMessage gets a message by ID
-func (s *MessageService) GetMessage(messageID string) (*models.Message, error) {
+func (s *MessageService) GetMessage(ctx context.Context, orgID, messageID string) (*models.Message, error) {
 	var message models.Message
-	if err := s.messageRepo.FindByID(messageID, &message); err != nil {
+	if err := s.messageRepo.WithContext(ctx).ForOrganization(orgID).FindByID(messageID, &message); err != nil {
 		return nil, errors.NewNotFound("Message", messageID)
 	}
 	return &message, nil
 }
 
 // ListMessages lists messages with filters and pagination
-func (s *MessageService) ListMessages(filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Message, error) {
-	return s.messageRepo.ListWithFilters(filters, pagination)
+func (s *MessageService) ListMessages(ctx context.Context, orgID string, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Message, error) {
+	return s.messageRepo.WithContext(ctx).ForOrganization(orgID).ListWithFilters(filters, pagination)
 }
 
-// SearchMessages searches messages by content
-func (s *MessageService) SearchMessages(query string, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Message, error) {
-	return s.messageRepo.Search(query, filters, pagination)
+// SearchMessages searches the text, captions and filenames of messages
+func (s *MessageService) SearchMessages(ctx context.Context, orgID, query string, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.MessageSearchResult, error) {
+	return s.messageRepo.WithContext(ctx).ForOrganization(orgID).Search(query, filters, pagination)
 }
 
 // ProcessIncomingMessage processes an incoming message from webhook
-func (s *MessageService) ProcessIncomingMessage(event *whatsapp.MessageEvent) error {
-	s.logger.Info("Processing incoming message",
+func (s *MessageService) ProcessIncomingMessage(ctx context.Context, orgID string, event *whatsapp.MessageEvent) error {
+	logger.FromContext(ctx, s.logger).Info("Processing incoming message",
+		zap.String("organization_id", orgID),
 		zap.String("from", event.From),
 		zap.String("type", event.Type),
 	)
 
+	if event.Type == models.MessageTypeReaction {
+		return s.processIncomingReaction(ctx, orgID, event)
+	}
+
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+	contactPhone := contactPhoneNumber(event.From)
+
 	// Get or create contact
-	contact, err := s.contactRepo.GetOrCreate(event.From)
+	contact, err := contactRepo.GetOrCreate(contactPhone)
 	if err != nil {
 		return errors.NewDatabaseError(err)
 	}
 
 	// Update contact name if provided
 	if event.ContactName != "" && contact.Name != event.ContactName {
-		s.contactRepo.UpdateFields(contact.ID, contact, map[string]interface{}{
+		contactRepo.UpdateFields(contact.ID, contact, map[string]interface{}{
 			"name": event.ContactName,
 		})
 	}
@@ -235,29 +595,100 @@ func (s *MessageService) ProcessIncomingMessage(event *whatsapp.MessageEvent) er
 	message := &models.Message{
 		WhatsAppMessageID: event.MessageID,
 		FromNumber:        event.From,
-		ToNumber:          "", // Will be set from webhook metadata
+		ToNumber:          event.DisplayPhoneNumber,
 		Direction:         "inbound",
 		MessageType:       event.Type,
 		Content:           event.Content,
 		MediaURL:          event.MediaURL,
 		MediaMimeType:     event.MimeType,
+		Caption:           event.Caption,
+		Filename:          event.Filename,
 		Status:            "received",
 		Timestamp:         event.Timestamp,
+		Blocked:           contact.Blocked,
 	}
 
-	if err := s.messageRepo.Create(message); err != nil {
+	messageRepo := s.messageRepo.WithContext(ctx).ForOrganization(orgID)
+	if event.ReplyTo != "" {
+		message.ReplyToWhatsAppMessageID = event.ReplyTo
+		if quoted := s.findByWhatsAppMessageID(messageRepo, event.ReplyTo); quoted != nil {
+			message.ReplyToMessageID = quoted.ID
+		}
+	}
+
+	if err := messageRepo.Create(message); err != nil {
 		return errors.NewDatabaseError(err)
 	}
 
-	// Update contact
-	s.contactRepo.UpdateLastMessage(event.From, event.Timestamp)
-	s.contactRepo.IncrementMessageCount(event.From, 1)
-	s.contactRepo.UpdateUnreadCount(event.From, 1)
+	// Update contact. Messages from blocked contacts are kept for the
+	// record but do not wait for a reply.
+	contactRepo.UpdateLastMessage(contactPhone, event.Timestamp)
+	contactRepo.IncrementMessageCount(contactPhone, 1)
+	if contact.Blocked {
+		logger.FromContext(ctx, s.logger).Info("Stored message from blocked contact",
+			zap.String("contact_id", contact.ID),
+			zap.String("message_id", message.ID),
+		)
+		return nil
+	}
+	contactRepo.UpdateUnreadCount(contactPhone, 1)
+
+	return nil
+}
+
+// processIncomingReaction records a customer's reaction to a message, or
+// removes it when the emoji is empty. Reactions are not messages, so they do
+// not count towards the contact's messages.
+func (s *MessageService) processIncomingReaction(ctx context.Context, orgID string, event *whatsapp.MessageEvent) error {
+	if event.ReactionTo == "" {
+		return nil
+	}
 
+	reactionRepo := s.reactionRepo.WithContext(ctx).ForOrganization(orgID)
+	if event.Emoji == "" {
+		if err := reactionRepo.Remove(event.ReactionTo, event.From, "inbound"); err != nil {
+			return errors.NewDatabaseError(err)
+		}
+		return nil
+	}
+
+	reaction := &models.Reaction{
+		TargetWhatsAppMessageID: event.ReactionTo,
+		WhatsAppMessageID:       event.MessageID,
+		FromNumber:              event.From,
+		Direction:               "inbound",
+		Emoji:                   event.Emoji,
+		Timestamp:               event.Timestamp,
+	}
+	if target := s.findByWhatsAppMessageID(s.messageRepo.WithContext(ctx).ForOrganization(orgID), event.ReactionTo); target != nil {
+		reaction.MessageID = target.ID
+	}
+	if err := reactionRepo.Upsert(reaction); err != nil {
+		return errors.NewDatabaseError(err)
+	}
 	return nil
 }
 
-// UpdateMessageStatus updates the status of a message
-func (s *MessageService) UpdateMessageStatus(whatsappMessageID, status string) error {
-	return s.messageRepo.UpdateStatus(whatsappMessageID, status)
+// findByWhatsAppMessageID returns the message with a WhatsApp message ID, or
+// nil when it is not stored
+func (s *MessageService) findByWhatsAppMessageID(messageRepo repositories.MessageStore, whatsappMessageID string) *models.Message {
+	messages, err := messageRepo.FindByWhatsAppMessageIDs([]string{whatsappMessageID})
+	if err != nil || len(messages) == 0 {
+		return nil
+	}
+	return messages[0]
+}
+
+// UpdateMessageStatus updates the status of a message from a status webhook,
+// recording the classified error of failed deliveries
+func (s *MessageService) UpdateMessageStatus(ctx context.Context, orgID string, event *whatsapp.StatusEvent) error {
+	updates := map[string]interface{}{"status": event.Status}
+	if event.ErrorCode != 0 {
+		category := whatsapp.ClassifyError(event.ErrorCode, 0)
+		updates["error_code"] = strconv.Itoa(event.ErrorCode)
+		updates["error_message"] = event.ErrorTitle
+		updates["error_category"] = string(category)
+		updates["error_retryable"] = category.Retryable()
+	}
+	return s.messageRepo.WithContext(ctx).ForOrganization(orgID).UpdateByWhatsAppMessageID(event.MessageID, updates)
 }
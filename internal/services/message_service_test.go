@@ -0,0 +1,518 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type messageServiceFixture struct {
+	service    *MessageService
+	messages   *memMessageStore
+	contacts   *memContactStore
+	reactions  *memReactionStore
+	attributes *AttributeService
+	sender     *fakeSender
+	senders    *fakeSenders
+}
+
+func newMessageServiceFixture() *messageServiceFixture {
+	f := &messageServiceFixture{
+		messages:  newMemMessageStore(),
+		contacts:  newMemContactStore(),
+		reactions: newMemReactionStore(),
+		sender:    &fakeSender{},
+	}
+	f.senders = &fakeSenders{sender: f.sender}
+	f.attributes = NewAttributeService(newMemAttributeStore())
+	f.service = NewMessageService(f.messages, f.contacts, f.reactions, f.attributes, f.senders, zap.NewNop())
+	return f
+}
+
+func TestSendTextMessage(t *testing.T) {
+	f := newMessageServiceFixture()
+	ctx := context.Background()
+
+	message, err := f.service.SendTextMessage(ctx, "org_a", "+14155550100", "hello", "")
+	if err != nil {
+		t.Fatalf("SendTextMessage: %v", err)
+	}
+	if message.Status != models.MessageStatusSent || message.WhatsAppMessageID == "" || message.OrganizationID != "org_a" {
+		t.Errorf("unexpected message %+v", message)
+	}
+	if len(f.sender.sends) != 1 || f.sender.sends[0].to != "+14155550100" || f.sender.sends[0].content != "hello" {
+		t.Errorf("unexpected sends %+v", f.sender.sends)
+	}
+
+	stored, err := f.service.GetMessage(ctx, "org_a", message.ID)
+	if err != nil || stored.Content != "hello" {
+		t.Fatalf("GetMessage: %+v %v", stored, err)
+	}
+	if _, err := f.service.GetMessage(ctx, "org_b", message.ID); err == nil {
+		t.Error("message should not be visible to another organization")
+	}
+
+	contact, err := f.contacts.ForOrganization("org_a").FindByPhone("+14155550100")
+	if err != nil {
+		t.Fatalf("contact was not created: %v", err)
+	}
+	if contact.MessageCount != 1 || contact.LastMessageAt == nil || contact.PhoneNumber != "14155550100" {
+		t.Errorf("contact not updated: %+v", contact)
+	}
+
+	// The reply comes from the same contact, stored under the WhatsApp ID
+	event := &whatsapp.MessageEvent{MessageID: "wamid.in1", From: "14155550100", Type: "text", Content: "hi", Timestamp: time.Now().UTC()}
+	if err := f.service.ProcessIncomingMessage(ctx, "org_a", event); err != nil {
+		t.Fatalf("ProcessIncomingMessage: %v", err)
+	}
+	if n := len(f.contacts.data.contacts); n != 1 {
+		t.Errorf("expected one contact, got %d", n)
+	}
+}
+
+func TestSendTextMessageValidation(t *testing.T) {
+	f := newMessageServiceFixture()
+	ctx := context.Background()
+
+	_, err := f.service.SendTextMessage(ctx, "org_a", "not-a-phone", "hello", "")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidPhoneNumber {
+		t.Errorf("expected invalid phone number error, got %v", err)
+	}
+
+	_, err = f.service.SendTextMessage(ctx, "org_a", "+14155550100", "", "")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+		t.Errorf("expected bad request, got %v", err)
+	}
+
+	if len(f.sender.sends) != 0 {
+		t.Errorf("invalid messages must not be sent, got %+v", f.sender.sends)
+	}
+}
+
+func TestSendWithoutSender(t *testing.T) {
+	f := newMessageServiceFixture()
+	f.senders.err = errors.NewForbidden("Organization is suspended")
+
+	_, err := f.service.SendTextMessage(context.Background(), "org_a", "+14155550100", "hello", "")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrForbidden {
+		t.Fatalf("expected forbidden error, got %v", err)
+	}
+	if len(f.messages.all()) != 0 {
+		t.Error("no message should be stored")
+	}
+}
+
+func TestSendRecordsGraphFailure(t *testing.T) {
+	f := newMessageServiceFixture()
+	apiErr := &whatsapp.APIError{
+		Code:       131047,
+		Message:    "Re-engagement message",
+		FBTraceID:  "trace-1",
+		HTTPStatus: 400,
+		Category:   whatsapp.ClassifyError(131047, 400),
+	}
+	f.sender.err = apiErr.AppError()
+
+	_, err := f.service.SendTemplateMessage(context.Background(), "org_a", "+14155550100", "welcome", "en_US", nil, "")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrWhatsAppReengagementRequired {
+		t.Fatalf("expected re-engagement error, got %v", err)
+	}
+
+	stored := f.messages.all()
+	if len(stored) != 1 {
+		t.Fatalf("expected the failed message to be stored, got %d", len(stored))
+	}
+	failed := stored[0]
+	if failed.Status != models.MessageStatusFailed || failed.ErrorCode != "131047" ||
+		failed.ErrorCategory != string(whatsapp.ErrorCategoryReengagement) || failed.ErrorRetryable ||
+		failed.ErrorTraceID != "trace-1" || failed.OrganizationID != "org_a" {
+		t.Errorf("unexpected failed message %+v", failed)
+	}
+}
+
+func TestSendTemplateFromContactAttributes(t *testing.T) {
+	f := newMessageServiceFixture()
+	ctx := context.Background()
+	f.attributes.CreateAttribute(ctx, "org_a", &models.AttributeDefinition{Name: "plan", Type: models.AttributeTypeString})
+	contact, _ := f.contacts.ForOrganization("org_a").GetOrCreate("14155550100")
+	f.contacts.ForOrganization("org_a").UpdateFields(contact.ID, contact, map[string]interface{}{"name": "Ada", "metadata": models.JSONMap{"plan": "pro"}})
+
+	message, err := f.service.SendTemplateMessage(ctx, "org_a", "+14155550100", "renewal", "en_US", []string{"{{name}}", "{{attributes.plan}}", "30 days"}, "")
+	if err != nil {
+		t.Fatalf("SendTemplateMessage: %v", err)
+	}
+	if params := message.Metadata["parameters"].([]string); strings.Join(params, "|") != "Ada|pro|30 days" {
+		t.Errorf("parameters should be filled in from the contact, got %q", params)
+	}
+
+	if _, err := f.service.SendTemplateMessage(ctx, "org_a", "+14155550199", "renewal", "en_US", []string{"{{attributes.plan}}"}, ""); err == nil {
+		t.Error("a recipient without the attribute should be rejected")
+	}
+	if len(f.sender.sends) != 1 {
+		t.Errorf("rejected sends must not reach WhatsApp, got %d sends", len(f.sender.sends))
+	}
+}
+
+func TestSendDoesNotRecordTransportFailure(t *testing.T) {
+	f := newMessageServiceFixture()
+	f.sender.err = errors.NewSendThrottledError("Throughput limit reached", time.Second)
+
+	_, err := f.service.SendMediaMessage(context.Background(), "org_a", "+14155550100", "https://example.com/a.png", "", "image", "")
+	if err == nil {
+		t.Fatal("expected send to fail")
+	}
+	if n := len(f.messages.all()); n != 0 {
+		t.Errorf("sends that never reached Graph must not be stored, got %d", n)
+	}
+}
+
+func TestSendLocationContactsAndSticker(t *testing.T) {
+	f := newMessageServiceFixture()
+	ctx := context.Background()
+
+	location, err := f.service.SendLocationMessage(ctx, "org_a", "+14155550100",
+		whatsapp.Location{Latitude: 37.7749, Longitude: -122.4194, Name: "HQ", Address: "1 Market St"}, "")
+	if err != nil {
+		t.Fatalf("SendLocationMessage: %v", err)
+	}
+	if location.MessageType != models.MessageTypeLocation || location.Content != "HQ, 1 Market St" ||
+		location.Metadata["latitude"] != 37.7749 || location.Metadata["longitude"] != -122.4194 {
+		t.Errorf("unexpected location message %+v", location)
+	}
+
+	request, err := f.service.SendLocationRequest(ctx, "org_a", "+14155550100", "Where should we deliver?", "")
+	if err != nil || request.MessageType != models.MessageTypeLocationRequest || request.Content != "Where should we deliver?" {
+		t.Errorf("SendLocationRequest: %+v %v", request, err)
+	}
+
+	cards := []whatsapp.ContactCard{{
+		Name:   whatsapp.ContactName{FormattedName: "Ada Lovelace", FirstName: "Ada"},
+		Phones: []whatsapp.ContactPhone{{Phone: "+14155550123", Type: "WORK", WaID: "14155550123"}},
+		Emails: []whatsapp.ContactEmail{{Email: "ada@example.com"}},
+	}}
+	contacts, err := f.service.SendContactsMessage(ctx, "org_a", "+14155550100", cards, "")
+	if err != nil {
+		t.Fatalf("SendContactsMessage: %v", err)
+	}
+	if stored, _ := contacts.Metadata["contacts"].([]whatsapp.ContactCard); contacts.Content != "Ada Lovelace" || len(stored) != 1 {
+		t.Errorf("unexpected contacts message %+v", contacts)
+	}
+
+	sticker, err := f.service.SendMediaMessage(ctx, "org_a", "+14155550100", "https://example.com/s.webp", "", "sticker", "")
+	if err != nil || sticker.MessageType != models.MessageTypeSticker {
+		t.Errorf("SendMediaMessage sticker: %+v %v", sticker, err)
+	}
+
+	var kinds []string
+	for _, send := range f.sender.sends {
+		kinds = append(kinds, send.kind)
+	}
+	if want := "location location_request contacts sticker"; strings.Join(kinds, " ") != want {
+		t.Errorf("sent %v, want %s", kinds, want)
+	}
+
+	for name, send := range map[string]func() (*models.Message, error){
+		"latitude out of range": func() (*models.Message, error) {
+			return f.service.SendLocationMessage(ctx, "org_a", "+14155550100", whatsapp.Location{Latitude: 91}, "")
+		},
+		"empty location request": func() (*models.Message, error) {
+			return f.service.SendLocationRequest(ctx, "org_a", "+14155550100", " ", "")
+		},
+		"no contacts": func() (*models.Message, error) {
+			return f.service.SendContactsMessage(ctx, "org_a", "+14155550100", nil, "")
+		},
+		"contact without a name": func() (*models.Message, error) {
+			return f.service.SendContactsMessage(ctx, "org_a", "+14155550100", []whatsapp.ContactCard{{Phones: cards[0].Phones}}, "")
+		},
+		"contact with an invalid email": func() (*models.Message, error) {
+			card := cards[0]
+			card.Emails = []whatsapp.ContactEmail{{Email: "ada"}}
+			return f.service.SendContactsMessage(ctx, "org_a", "+14155550100", []whatsapp.ContactCard{card}, "")
+		},
+		"sticker with a caption": func() (*models.Message, error) {
+			return f.service.SendMediaMessage(ctx, "org_a", "+14155550100", "https://example.com/s.webp", "hi", "sticker", "")
+		},
+		"media type that is not media": func() (*models.Message, error) {
+			return f.service.SendMediaMessage(ctx, "org_a", "+14155550100", "https://example.com/a", "", "location", "")
+		},
+	} {
+		if _, err := send(); err == nil {
+			t.Errorf("%s should be rejected", name)
+		} else if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+			t.Errorf("%s: expected bad request, got %v", name, err)
+		}
+	}
+	if n := len(f.sender.sends); n != 4 {
+		t.Errorf("invalid messages must not be sent, got %d sends", n)
+	}
+}
+
+func TestProcessIncomingMessage(t *testing.T) {
+	f := newMessageServiceFixture()
+	ctx := context.Background()
+
+	event := &whatsapp.MessageEvent{
+		MessageID:          "wamid.in1",
+		From:               "14155550100",
+		DisplayPhoneNumber: "15550000000",
+		Type:               "text",
+		Content:            "hi",
+		ContactName:        "Ada",
+		Timestamp:          time.Now().UTC(),
+	}
+	if err := f.service.ProcessIncomingMessage(ctx, "org_a", event); err != nil {
+		t.Fatalf("ProcessIncomingMessage: %v", err)
+	}
+
+	stored := f.messages.all()
+	if len(stored) != 1 || stored[0].Direction != "inbound" || stored[0].Content != "hi" || stored[0].OrganizationID != "org_a" {
+		t.Fatalf("unexpected stored messages %+v", stored)
+	}
+
+	contact, err := f.contacts.ForOrganization("org_a").FindByPhone("14155550100")
+	if err != nil {
+		t.Fatalf("contact was not created: %v", err)
+	}
+	if contact.Name != "Ada" || contact.UnreadCount != 1 || contact.MessageCount != 1 {
+		t.Errorf("unexpected contact %+v", contact)
+	}
+
+	// Webhooks are retried by Meta; a redelivered message must not be stored twice
+	if err := f.service.ProcessIncomingMessage(ctx, "org_a", event); err == nil {
+		t.Error("expected duplicate delivery to fail")
+	}
+	if n := len(f.messages.all()); n != 1 {
+		t.Errorf("expected 1 stored message, got %d", n)
+	}
+}
+
+func TestProcessIncomingReplyAndReaction(t *testing.T) {
+	f := newMessageServiceFixture()
+	ctx := context.Background()
+
+	sent, err := f.service.SendTextMessage(ctx, "org_a", "+14155550100", "Your order shipped", "")
+	if err != nil {
+		t.Fatalf("SendTextMessage: %v", err)
+	}
+
+	reply := &whatsapp.MessageEvent{MessageID: "wamid.in1", From: "+14155550100", DisplayPhoneNumber: "15550000000",
+		Type: "text", Content: "Thanks!", ReplyTo: sent.WhatsAppMessageID, Timestamp: time.Now().UTC()}
+	if err := f.service.ProcessIncomingMessage(ctx, "org_a", reply); err != nil {
+		t.Fatalf("ProcessIncomingMessage: %v", err)
+	}
+	stored, _ := f.messages.ForOrganization("org_a").FindByWhatsAppMessageIDs([]string{"wamid.in1"})
+	if len(stored) != 1 || stored[0].ReplyToMessageID != sent.ID || stored[0].ReplyToWhatsAppMessageID != sent.WhatsAppMessageID {
+		t.Fatalf("reply context not stored: %+v", stored)
+	}
+
+	react := func(emoji string) {
+		t.Helper()
+		event := &whatsapp.MessageEvent{MessageID: "wamid.r" + emoji, From: "+14155550100", Type: "reaction",
+			ReactionTo: sent.WhatsAppMessageID, Emoji: emoji, Timestamp: time.Now().UTC()}
+		if err := f.service.ProcessIncomingMessage(ctx, "org_a", event); err != nil {
+			t.Fatalf("reaction %q: %v", emoji, err)
+		}
+	}
+	react("👍")
+	react("❤️")
+	reactions, _ := f.reactions.ForOrganization("org_a").FindByTargets([]string{sent.WhatsAppMessageID})
+	if len(reactions) != 1 || reactions[0].Emoji != "❤️" || reactions[0].MessageID != sent.ID || reactions[0].Direction != "inbound" {
+		t.Errorf("a new reaction should replace the sender's earlier one: %+v", reactions)
+	}
+	if n := len(f.messages.all()); n != 2 {
+		t.Errorf("reactions must not be stored as messages, got %d messages", n)
+	}
+	contact, _ := f.contacts.ForOrganization("org_a").FindByPhone("+14155550100")
+	if contact.MessageCount != 2 || contact.UnreadCount != 1 {
+		t.Errorf("reactions must not count as messages: %+v", contact)
+	}
+
+	react("")
+	reactions, _ = f.reactions.ForOrganization("org_a").FindByTargets([]string{sent.WhatsAppMessageID})
+	if len(reactions) != 0 {
+		t.Errorf("an empty emoji should remove the reaction: %+v", reactions)
+	}
+}
+
+func TestSendReplyAndReaction(t *testing.T) {
+	f := newMessageServiceFixture()
+	ctx := context.Background()
+
+	question := &whatsapp.MessageEvent{MessageID: "wamid.in1", From: "14155550100", Type: "text",
+		Content: "Where is my order?", Timestamp: time.Now().UTC()}
+	if err := f.service.ProcessIncomingMessage(ctx, "org_a", question); err != nil {
+		t.Fatalf("ProcessIncomingMessage: %v", err)
+	}
+	stored, _ := f.messages.ForOrganization("org_a").FindByWhatsAppMessageIDs([]string{"wamid.in1"})
+
+	reply, err := f.service.SendTextMessage(ctx, "org_a", "+14155550100", "It ships tomorrow", stored[0].ID)
+	if err != nil {
+		t.Fatalf("SendTextMessage: %v", err)
+	}
+	if reply.ReplyToMessageID != stored[0].ID || reply.ReplyToWhatsAppMessageID != "wamid.in1" {
+		t.Errorf("reply should record the quoted message: %+v", reply)
+	}
+	if send := f.sender.sends[0]; send.replyTo != "wamid.in1" {
+		t.Errorf("reply should be sent with the quoted WhatsApp message ID, got %+v", send)
+	}
+
+	reaction, err := f.service.SendReaction(ctx, "org_a", "+14155550100", stored[0].ID, "👍")
+	if err != nil {
+		t.Fatalf("SendReaction: %v", err)
+	}
+	if reaction.MessageID != stored[0].ID || reaction.Direction != "outbound" || f.sender.sends[1].content != "wamid.in1 👍" {
+		t.Errorf("unexpected reaction %+v, sent %+v", reaction, f.sender.sends[1])
+	}
+	reactions, _ := f.reactions.ForOrganization("org_a").FindByTargets([]string{"wamid.in1"})
+	if len(reactions) != 1 || reactions[0].Emoji != "👍" {
+		t.Errorf("reaction not stored: %+v", reactions)
+	}
+	if _, err := f.service.SendReaction(ctx, "org_a", "+14155550100", stored[0].ID, ""); err != nil {
+		t.Fatalf("SendReaction removal: %v", err)
+	}
+	reactions, _ = f.reactions.ForOrganization("org_a").FindByTargets([]string{"wamid.in1"})
+	if len(reactions) != 0 {
+		t.Errorf("an empty emoji should remove our reaction: %+v", reactions)
+	}
+
+	// Quoted messages must exist in the organization and the conversation
+	_, err = f.service.SendTextMessage(ctx, "org_a", "+14155550100", "hi", "msg_missing")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound {
+		t.Errorf("unknown reply_to should not be found, got %v", err)
+	}
+	_, err = f.service.SendTextMessage(ctx, "org_b", "+14155550100", "hi", stored[0].ID)
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrNotFound {
+		t.Errorf("reply_to of another organization should not be found, got %v", err)
+	}
+	_, err = f.service.SendReaction(ctx, "org_a", "+14155550199", stored[0].ID, "👍")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+		t.Errorf("reacting to another conversation should be rejected, got %v", err)
+	}
+	if n := len(f.sender.sends); n != 3 {
+		t.Errorf("rejected sends must not reach WhatsApp, got %d sends", n)
+	}
+}
+
+func TestMarkAsRead(t *testing.T) {
+	f := newMessageServiceFixture()
+	ctx := context.Background()
+
+	for i, id := range []string{"wamid.in1", "wamid.in2"} {
+		event := &whatsapp.MessageEvent{MessageID: id, From: "14155550100", Type: "text", Content: "hello",
+			Timestamp: time.Now().UTC().Add(time.Duration(i) * time.Second)}
+		if err := f.service.ProcessIncomingMessage(ctx, "org_a", event); err != nil {
+			t.Fatalf("ProcessIncomingMessage: %v", err)
+		}
+	}
+	inbound, _ := f.messages.ForOrganization("org_a").FindByWhatsAppMessageIDs([]string{"wamid.in2"})
+
+	message, err := f.service.MarkAsRead(ctx, "org_a", inbound[0].ID, true)
+	if err != nil {
+		t.Fatalf("MarkAsRead: %v", err)
+	}
+	if message.Status != models.MessageStatusRead || f.sender.sends[0] != (fakeSend{kind: "read+typing", content: "wamid.in2"}) {
+		t.Errorf("unexpected read receipt %+v for %+v", f.sender.sends, message)
+	}
+	stored, _ := f.service.GetMessage(ctx, "org_a", inbound[0].ID)
+	contact, _ := f.contacts.ForOrganization("org_a").FindByPhone("14155550100")
+	if stored.Status != models.MessageStatusRead || contact.UnreadCount != 0 {
+		t.Errorf("expected a read message and no unread messages, got %s and %d", stored.Status, contact.UnreadCount)
+	}
+
+	sent, _ := f.service.SendTextMessage(ctx, "org_a", "+14155550100", "hi", "")
+	if _, err := f.service.MarkAsRead(ctx, "org_a", sent.ID, false); err == nil {
+		t.Error("outbound messages cannot be marked as read")
+	}
+	if _, err := f.service.MarkAsRead(ctx, "org_b", inbound[0].ID, false); err == nil {
+		t.Error("messages of another organization cannot be marked as read")
+	}
+}
+
+func TestAutoMarkRead(t *testing.T) {
+	f := newMessageServiceFixture()
+	ctx := context.Background()
+
+	receive := func(id string) {
+		t.Helper()
+		event := &whatsapp.MessageEvent{MessageID: id, From: "14155550100", Type: "text", Content: "hello", Timestamp: time.Now().UTC()}
+		if err := f.service.ProcessIncomingMessage(ctx, "org_a", event); err != nil {
+			t.Fatalf("ProcessIncomingMessage: %v", err)
+		}
+	}
+	reads := func() (ids []string) {
+		for _, send := range f.sender.sends {
+			if send.kind == "read" {
+				ids = append(ids, send.content)
+			}
+		}
+		return ids
+	}
+
+	receive("wamid.in1")
+	f.service.SendTextMessage(ctx, "org_a", "+14155550100", "hi", "")
+	if got := reads(); len(got) != 0 {
+		t.Fatalf("replies must not mark messages read unless enabled, got %v", got)
+	}
+
+	f.service.SetAutoMarkRead(true)
+	receive("wamid.in2")
+	f.service.SendTextMessage(ctx, "org_a", "+14155550100", "hi again", "")
+	f.service.SendTextMessage(ctx, "org_a", "+14155550100", "anything else?", "")
+	if got := reads(); len(got) != 1 || got[0] != "wamid.in2" {
+		t.Errorf("a reply should mark the latest message read once, got %v", got)
+	}
+	contact, _ := f.contacts.ForOrganization("org_a").FindByPhone("14155550100")
+	if contact.UnreadCount != 0 {
+		t.Errorf("a reply should clear the unread count, got %d", contact.UnreadCount)
+	}
+}
+
+func TestUpdateMessageStatus(t *testing.T) {
+	f := newMessageServiceFixture()
+	ctx := context.Background()
+
+	message, err := f.service.SendTextMessage(ctx, "org_a", "+14155550100", "hello", "")
+	if err != nil {
+		t.Fatalf("SendTextMessage: %v", err)
+	}
+
+	err = f.service.UpdateMessageStatus(ctx, "org_a", &whatsapp.StatusEvent{
+		MessageID: message.WhatsAppMessageID,
+		Status:    "delivered",
+	})
+	if err != nil {
+		t.Fatalf("UpdateMessageStatus: %v", err)
+	}
+	stored, _ := f.service.GetMessage(ctx, "org_a", message.ID)
+	if stored.Status != "delivered" || stored.ErrorCode != "" {
+		t.Errorf("unexpected message after delivery %+v", stored)
+	}
+
+	err = f.service.UpdateMessageStatus(ctx, "org_a", &whatsapp.StatusEvent{
+		MessageID:  message.WhatsAppMessageID,
+		Status:     "failed",
+		ErrorCode:  whatsapp.ErrorCodeThroughputReached,
+		ErrorTitle: "Rate limit hit",
+	})
+	if err != nil {
+		t.Fatalf("UpdateMessageStatus: %v", err)
+	}
+	stored, _ = f.service.GetMessage(ctx, "org_a", message.ID)
+	if stored.Status != "failed" || stored.ErrorCategory != string(whatsapp.ErrorCategoryRateLimit) ||
+		!stored.ErrorRetryable || stored.ErrorMessage != "Rate limit hit" {
+		t.Errorf("unexpected message after failure %+v", stored)
+	}
+
+	// Statuses for another organization's message are ignored
+	f.service.UpdateMessageStatus(ctx, "org_b", &whatsapp.StatusEvent{MessageID: message.WhatsAppMessageID, Status: "read"})
+	stored, _ = f.service.GetMessage(ctx, "org_a", message.ID)
+	if stored.Status != "failed" {
+		t.Errorf("status was changed through another organization: %s", stored.Status)
+	}
+}
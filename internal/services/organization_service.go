@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/config"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// organizationColumns lists the organization columns that may be updated
+// through the API
+var organizationColumns = map[string]bool{
+	"name":                 true,
+	"status":               true,
+	"phone_number_id":      true,
+	"business_account_id":  true,
+	"access_token":         true,
+	"webhook_verify_token": true,
+	"webhook_secret":       true,
+	"metadata":             true,
+}
+
+// OrganizationKeys revokes and invalidates the API keys of organizations
+// that are suspended or deleted; AuthService implements it
+type OrganizationKeys interface {
+	RevokeOrganizationKeys(ctx context.Context, orgID, reason string) error
+	InvalidateOrganization(orgID string)
+}
+
+// OrganizationService handles tenant management and per-tenant WhatsApp clients
+type OrganizationService struct {
+	orgRepo repositories.OrganizationStore
+	clients *whatsapp.ClientPool
+	keys    OrganizationKeys
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(orgRepo repositories.OrganizationStore, clients *whatsapp.ClientPool, keys OrganizationKeys) *OrganizationService {
+	return &OrganizationService{
+		orgRepo: orgRepo,
+		clients: clients,
+		keys:    keys,
+	}
+}
+
+// DefaultOrganizationFromConfig builds the default organization from the
+// single-tenant WhatsApp settings in the config
+func DefaultOrganizationFromConfig(cfg config.WhatsAppConfig) models.Organization {
+	return models.Organization{
+		Name:               "Default",
+		PhoneNumberID:      cfg.PhoneNumberID,
+		BusinessAccountID:  cfg.BusinessAccountID,
+		AccessToken:        cfg.APIToken,
+		WebhookVerifyToken: cfg.WebhookVerifyToken,
+		WebhookSecret:      cfg.WebhookSecret,
+	}
+}
+
+// EnsureDefault creates the default organization from the given template if
+// it does not exist yet and assigns all pre-tenancy rows to it
+func (s *OrganizationService) EnsureDefault(ctx context.Context, defaults models.Organization) (*models.Organization, error) {
+	var org models.Organization
+	err := s.orgRepo.WithContext(ctx).FindByID(models.DefaultOrganizationID, &org)
+	if err != nil && !stderrors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	if stderrors.Is(err, gorm.ErrRecordNotFound) {
+		org = defaults
+		org.ID = models.DefaultOrganizationID
+		if err := s.orgRepo.WithContext(ctx).Create(&org); err != nil {
+			return nil, errors.NewDatabaseError(err)
+		}
+	}
+
+	if err := s.orgRepo.WithContext(ctx).AssignOrphans(org.ID); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+
+	return &org, nil
+}
+
+// authorizeAdmin checks that an organization may administer the other
+// tenants; only the default organization can
+func authorizeAdmin(callerOrgID string) error {
+	if callerOrgID != models.DefaultOrganizationID {
+		return errors.NewForbidden("Only the platform organization can administer organizations")
+	}
+	return nil
+}
+
+// CreateOrganization creates a new organization on behalf of the caller's
+// organization
+func (s *OrganizationService) CreateOrganization(ctx context.Context, callerOrgID string, org *models.Organization) error {
+	if err := authorizeAdmin(callerOrgID); err != nil {
+		return err
+	}
+	if org.Status == "" {
+		org.Status = models.OrganizationStatusActive
+	}
+	if err := org.Validate(); err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+
+	if err := s.orgRepo.WithContext(ctx).Create(org); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// GetOrganization gets an organization by ID
+func (s *OrganizationService) GetOrganization(ctx context.Context, orgID string) (*models.Organization, error) {
+	var org models.Organization
+	if err := s.orgRepo.WithContext(ctx).FindByID(orgID, &org); err != nil {
+		return nil, errors.NewNotFound("Organization", orgID)
+	}
+	return &org, nil
+}
+
+// GetOrganizationAs gets an organization by ID on behalf of the caller's
+// organization
+func (s *OrganizationService) GetOrganizationAs(ctx context.Context, callerOrgID, orgID string) (*models.Organization, error) {
+	if err := authorizeAdmin(callerOrgID); err != nil {
+		return nil, err
+	}
+	return s.GetOrganization(ctx, orgID)
+}
+
+// ListOrganizations lists all organizations on behalf of the caller's
+// organization
+func (s *OrganizationService) ListOrganizations(ctx context.Context, callerOrgID string, pagination *utils.Pagination) ([]*models.Organization, error) {
+	if err := authorizeAdmin(callerOrgID); err != nil {
+		return nil, err
+	}
+	orgs, err := s.orgRepo.WithContext(ctx).ListAll(pagination)
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return orgs, nil
+}
+
+// UpdateOrganization updates an organization on behalf of the caller's
+// organization and drops its cached WhatsApp client
+func (s *OrganizationService) UpdateOrganization(ctx context.Context, callerOrgID, orgID string, updates map[string]interface{}) (*models.Organization, error) {
+	if err := authorizeAdmin(callerOrgID); err != nil {
+		return nil, err
+	}
+
+	org, err := s.GetOrganization(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	for field := range updates {
+		if !organizationColumns[field] {
+			return nil, errors.NewBadRequest("Field cannot be updated: " + field)
+		}
+	}
+	if status, ok := updates["status"].(string); ok &&
+		status != models.OrganizationStatusActive && status != models.OrganizationStatusSuspended {
+		return nil, errors.NewBadRequest("Invalid status: " + status)
+	}
+	if status, ok := updates["status"].(string); ok &&
+		status != models.OrganizationStatusActive && orgID == models.DefaultOrganizationID {
+		return nil, errors.NewConflict("The default organization cannot be suspended")
+	}
+
+	if err := s.orgRepo.WithContext(ctx).UpdateFields(orgID, org, updates); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	s.clients.Evict(org.PhoneNumberID)
+	// Cached keys were verified while the organization was active
+	if _, ok := updates["status"]; ok {
+		s.keys.InvalidateOrganization(orgID)
+	}
+
+	return s.GetOrganization(ctx, orgID)
+}
+
+// DeleteOrganization deletes an organization on behalf of the caller's
+// organization and revokes its API keys. The default organization cannot be
+// deleted. The organization's messages, contacts and other data are kept.
+func (s *OrganizationService) DeleteOrganization(ctx context.Context, callerOrgID, orgID string) error {
+	if err := authorizeAdmin(callerOrgID); err != nil {
+		return err
+	}
+	if orgID == models.DefaultOrganizationID {
+		return errors.NewConflict("The default organization cannot be deleted")
+	}
+
+	org, err := s.GetOrganization(ctx, orgID)
+	if err != nil {
+		return err
+	}
+
+	// Revoke the keys first so a failed delete leaves no usable keys behind
+	if err := s.keys.RevokeOrganizationKeys(ctx, orgID, "organization deleted"); err != nil {
+		return err
+	}
+	if err := s.orgRepo.WithContext(ctx).Delete(org); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	s.clients.Evict(org.PhoneNumberID)
+
+	return nil
+}
+
+// ResolveWebhookOrganization finds the organization a webhook entry belongs
+// to, by WABA ID first and then by phone number ID
+func (s *OrganizationService) ResolveWebhookOrganization(ctx context.Context, businessAccountID, phoneNumberID string) (*models.Organization, error) {
+	if businessAccountID != "" {
+		if org, err := s.orgRepo.WithContext(ctx).FindByBusinessAccountID(businessAccountID); err == nil {
+			return org, nil
+		}
+	}
+	if phoneNumberID != "" {
+		if org, err := s.orgRepo.WithContext(ctx).FindByPhoneNumberID(phoneNumberID); err == nil {
+			return org, nil
+		}
+	}
+	return nil, errors.NewNotFound("Organization", businessAccountID)
+}
+
+// IsWebhookVerifyToken reports whether the token belongs to any organization
+func (s *OrganizationService) IsWebhookVerifyToken(ctx context.Context, token string) bool {
+	if token == "" {
+		return false
+	}
+	_, err := s.orgRepo.WithContext(ctx).FindByWebhookVerifyToken(token)
+	return err == nil
+}
+
+// Client returns the WhatsApp client for an organization
+func (s *OrganizationService) Client(ctx context.Context, orgID string) (*whatsapp.Client, error) {
+	org, err := s.GetOrganization(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !org.IsActive() {
+		return nil, errors.NewForbidden("Organization is suspended")
+	}
+	if !org.HasWhatsAppCredentials() {
+		return nil, errors.NewConflict("Organization has no WhatsApp credentials configured")
+	}
+
+	client, err := s.clients.Get(org.AccessToken, org.PhoneNumberID)
+	if err != nil {
+		return nil, errors.NewInternalError(err)
+	}
+	return client, nil
+}
+
+// Sender returns the WhatsApp sender for an organization
+func (s *OrganizationService) Sender(ctx context.Context, orgID string) (Sender, error) {
+	client, err := s.Client(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// ReportDeliveryError passes a delivery error from a status webhook to the
+// throughput governor of the sending phone number, so throttling reported
+// after the send was accepted still slows the number down
+func (s *OrganizationService) ReportDeliveryError(phoneNumberID, recipient string, code int) {
+	if phoneNumberID == "" || !whatsapp.IsThrottlingErrorCode(code) {
+		return
+	}
+	s.clients.Governor(phoneNumberID).Throttled(code, recipient)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+)
+
+type organizationServiceFixture struct {
+	orgs    *memOrganizationStore
+	keys    *memAPIKeyStore
+	auth    *AuthService
+	service *OrganizationService
+}
+
+func newOrganizationServiceFixture(t *testing.T) *organizationServiceFixture {
+	t.Helper()
+	f := &organizationServiceFixture{
+		orgs: newMemOrganizationStore(),
+		keys: newMemAPIKeyStore(),
+	}
+	f.auth = NewAuthService(f.keys, f.orgs, "salt", time.Minute)
+	t.Cleanup(func() { f.auth.Close() })
+	f.service = NewOrganizationService(f.orgs, whatsapp.NewClientPool(whatsapp.Config{}), f.auth)
+	if _, err := f.service.EnsureDefault(context.Background(), models.Organization{Name: "Default"}); err != nil {
+		t.Fatalf("EnsureDefault: %v", err)
+	}
+	return f
+}
+
+func TestOrganizationAdministrationRequiresPlatform(t *testing.T) {
+	f := newOrganizationServiceFixture(t)
+	service, orgs := f.service, f.orgs
+	ctx := context.Background()
+
+	tenant := &models.Organization{Name: "Tenant"}
+	if err := service.CreateOrganization(ctx, models.DefaultOrganizationID, tenant); err != nil {
+		t.Fatalf("CreateOrganization: %v", err)
+	}
+	other := &models.Organization{Name: "Other"}
+	if err := service.CreateOrganization(ctx, tenant.ID, other); !isForbidden(err) {
+		t.Errorf("a tenant should not create organizations, got %v", err)
+	}
+
+	if _, err := service.ListOrganizations(ctx, tenant.ID, utils.NewPagination(50, 0)); !isForbidden(err) {
+		t.Errorf("a tenant should not list organizations, got %v", err)
+	}
+	if _, err := service.GetOrganizationAs(ctx, tenant.ID, models.DefaultOrganizationID); !isForbidden(err) {
+		t.Errorf("a tenant should not read other organizations, got %v", err)
+	}
+	if _, err := service.UpdateOrganization(ctx, tenant.ID, tenant.ID, map[string]interface{}{"name": "Renamed"}); !isForbidden(err) {
+		t.Errorf("a tenant should not update organizations, got %v", err)
+	}
+	if err := service.DeleteOrganization(ctx, tenant.ID, tenant.ID); !isForbidden(err) {
+		t.Errorf("a tenant should not delete organizations, got %v", err)
+	}
+	if len(orgs.orgs) != 2 || orgs.orgs[tenant.ID].Name != "Tenant" {
+		t.Errorf("rejected calls should change nothing: %+v", orgs.orgs)
+	}
+
+	listed, err := service.ListOrganizations(ctx, models.DefaultOrganizationID, utils.NewPagination(50, 0))
+	if err != nil || len(listed) != 2 {
+		t.Errorf("the platform should list organizations: %v %v", listed, err)
+	}
+	if err := service.DeleteOrganization(ctx, models.DefaultOrganizationID, tenant.ID); err != nil {
+		t.Errorf("the platform should delete organizations: %v", err)
+	}
+}
+
+func TestOrganizationStatusGatesAPIKeys(t *testing.T) {
+	f := newOrganizationServiceFixture(t)
+	ctx := context.Background()
+
+	tenant := &models.Organization{Name: "Tenant"}
+	f.service.CreateOrganization(ctx, models.DefaultOrganizationID, tenant)
+	_, rawKey, err := f.auth.CreateAPIKey(ctx, tenant.ID, "tenant", []string{models.PermissionAll}, models.APIKeyLimits{}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if _, err := f.auth.ValidateAPIKey(ctx, rawKey); err != nil {
+		t.Fatalf("the key of an active organization should be valid: %v", err)
+	}
+
+	// The key is cached now; suspending must still take effect at once
+	if _, err := f.service.UpdateOrganization(ctx, models.DefaultOrganizationID, tenant.ID, map[string]interface{}{"status": models.OrganizationStatusSuspended}); err != nil {
+		t.Fatalf("UpdateOrganization: %v", err)
+	}
+	if _, err := f.auth.ValidateAPIKey(ctx, rawKey); !isForbidden(err) {
+		t.Errorf("the key of a suspended organization should be rejected, got %v", err)
+	}
+	f.service.UpdateOrganization(ctx, models.DefaultOrganizationID, tenant.ID, map[string]interface{}{"status": models.OrganizationStatusActive})
+	if _, err := f.auth.ValidateAPIKey(ctx, rawKey); err != nil {
+		t.Errorf("the key should be valid again once the organization is reactivated: %v", err)
+	}
+
+	if err := f.service.DeleteOrganization(ctx, models.DefaultOrganizationID, tenant.ID); err != nil {
+		t.Fatalf("DeleteOrganization: %v", err)
+	}
+	if _, err := f.auth.ValidateAPIKey(ctx, rawKey); err == nil {
+		t.Error("the key of a deleted organization should be rejected")
+	}
+	keys, _ := f.auth.ListAPIKeys(ctx, tenant.ID)
+	if len(keys) != 1 || !keys[0].IsRevoked() {
+		t.Errorf("deleting an organization should revoke its keys: %+v", keys)
+	}
+
+	// A key whose organization row is gone is rejected even if not revoked
+	_, orphanKey, _ := f.auth.CreateAPIKey(ctx, "org_missing", "orphan", []string{models.PermissionAll}, models.APIKeyLimits{}, nil)
+	if _, err := f.auth.ValidateAPIKey(ctx, orphanKey); err == nil {
+		t.Error("the key of a missing organization should be rejected")
+	}
+
+	_, err = f.service.UpdateOrganization(ctx, models.DefaultOrganizationID, models.DefaultOrganizationID, map[string]interface{}{"status": models.OrganizationStatusSuspended})
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrConflict {
+		t.Errorf("the default organization should not be suspended, got %v", err)
+	}
+}
+
+func TestRevocationsSyncAcrossReplicas(t *testing.T) {
+	f := newOrganizationServiceFixture(t)
+	ctx := context.Background()
+
+	// A second replica shares the stores but not the cache
+	replica := NewAuthService(f.keys, f.orgs, "salt", time.Minute)
+	t.Cleanup(func() { replica.Close() })
+
+	tenant := &models.Organization{Name: "Tenant"}
+	f.service.CreateOrganization(ctx, models.DefaultOrganizationID, tenant)
+	apiKey, rawKey, _ := f.auth.CreateAPIKey(ctx, tenant.ID, "tenant", []string{models.PermissionAll}, models.APIKeyLimits{}, nil)
+	if _, err := replica.ValidateAPIKey(ctx, rawKey); err != nil {
+		t.Fatalf("ValidateAPIKey: %v", err)
+	}
+
+	if err := f.auth.RevokeAPIKey(ctx, tenant.ID, apiKey.ID, "leaked"); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if _, err := replica.ValidateAPIKey(ctx, rawKey); err != nil {
+		t.Fatalf("the replica should serve the key from its cache until it syncs: %v", err)
+	}
+	if err := replica.syncRevocations(); err != nil {
+		t.Fatalf("syncRevocations: %v", err)
+	}
+	if _, err := replica.ValidateAPIKey(ctx, rawKey); err == nil {
+		t.Error("a key revoked on another replica should be rejected after a sync")
+	}
+
+	// Suspending an organization on one replica reaches the others too
+	_, otherKey, _ := f.auth.CreateAPIKey(ctx, tenant.ID, "other", []string{models.PermissionAll}, models.APIKeyLimits{}, nil)
+	if _, err := replica.ValidateAPIKey(ctx, otherKey); err != nil {
+		t.Fatalf("ValidateAPIKey: %v", err)
+	}
+	f.service.UpdateOrganization(ctx, models.DefaultOrganizationID, tenant.ID, map[string]interface{}{"status": models.OrganizationStatusSuspended})
+	if err := replica.syncRevocations(); err != nil {
+		t.Fatalf("syncRevocations: %v", err)
+	}
+	if _, err := replica.ValidateAPIKey(ctx, otherKey); !isForbidden(err) {
+		t.Errorf("a key of an organization suspended on another replica should be rejected, got %v", err)
+	}
+}
+
+func isForbidden(err error) bool {
+	appErr, ok := err.(*errors.AppError)
+	return ok && appErr.Code == errors.ErrForbidden
+}
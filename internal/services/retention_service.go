@@ -0,0 +1,465 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/storage"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/logger"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// retentionMediaBatch is how many messages a run removes the media of
+	// at a time
+	retentionMediaBatch = 500
+	// retentionMessageBatch is how many messages a run archives or deletes
+	// at a time. Each archived batch is one archive file.
+	retentionMessageBatch = 1000
+	// retentionLeaseTTL is how long an organization's run lease lasts. Runs
+	// renew it before every batch.
+	retentionLeaseTTL = 5 * time.Minute
+)
+
+// RetentionService applies the organizations' message retention policies:
+// it removes the media of messages older than a policy keeps media, and
+// archives or deletes messages older than it keeps messages.
+type RetentionService struct {
+	policyRepo   repositories.RetentionPolicyStore
+	messageRepo  repositories.MessageStore
+	reactionRepo repositories.ReactionStore
+	leaseRepo    repositories.LeaseStore
+	media        []storage.MediaStore
+	archive      *MessageArchive
+	logger       *zap.Logger
+
+	// now returns the time runs measure message ages from
+	now func() time.Time
+
+	// owner identifies this process on the leases it holds
+	owner string
+
+	// Scheduled runs use ctx, which Close cancels
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// running is held during a run, so runs of this process never overlap.
+	// Runs of an organization on different replicas are kept apart by a
+	// lease.
+	running sync.Mutex
+}
+
+// NewRetentionService creates a new retention service. media are the
+// stores media files are kept in: the files messages refer to are deleted
+// with their media. Archived messages are written to archive, which is nil
+// when messages cannot be archived. leaseRepo keeps replicas from running
+// an organization's policies at the same time.
+func NewRetentionService(
+	policyRepo repositories.RetentionPolicyStore,
+	messageRepo repositories.MessageStore,
+	reactionRepo repositories.ReactionStore,
+	leaseRepo repositories.LeaseStore,
+	media []storage.MediaStore,
+	archive *MessageArchive,
+	logger *zap.Logger,
+) *RetentionService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RetentionService{
+		policyRepo:   policyRepo,
+		messageRepo:  messageRepo,
+		reactionRepo: reactionRepo,
+		leaseRepo:    leaseRepo,
+		media:        media,
+		archive:      archive,
+		logger:       logger,
+		now:          func() time.Time { return time.Now().UTC() },
+		owner:        utils.GenerateID("worker"),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Start applies every organization's policies now and then every interval,
+// until Close is called
+func (s *RetentionService) Start(interval time.Duration) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.RunAll(s.ctx)
+			select {
+			case <-ticker.C:
+			case <-s.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Close stops scheduled runs, waiting for a running one to stop
+func (s *RetentionService) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// ListPolicies lists the organization's retention policies by message type
+func (s *RetentionService) ListPolicies(ctx context.Context, orgID string) ([]*models.RetentionPolicy, error) {
+	policies, err := s.policyRepo.WithContext(ctx).ForOrganization(orgID).ListAll()
+	if err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return policies, nil
+}
+
+// SetPolicy creates or replaces the organization's policy for the policy's
+// message type. Messages are archived unless the policy says otherwise.
+func (s *RetentionService) SetPolicy(ctx context.Context, orgID string, policy *models.RetentionPolicy) (*models.RetentionPolicy, error) {
+	if policy.Action == "" {
+		policy.Action = models.RetentionActionArchive
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, errors.NewBadRequest(err.Error())
+	}
+	if policy.Action == models.RetentionActionArchive && policy.RetainDays > 0 && s.archive == nil {
+		return nil, errors.NewBadRequest("Messages can only be archived with local storage; use the delete action")
+	}
+
+	if err := s.policyRepo.WithContext(ctx).ForOrganization(orgID).Upsert(policy); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	logger.FromContext(ctx, s.logger).Info("Retention policy set",
+		zap.String("organization_id", orgID),
+		zap.String("message_type", policy.MessageType),
+		zap.String("action", policy.Action),
+		zap.Int("retain_days", policy.RetainDays),
+		zap.Int("media_retain_days", policy.MediaRetainDays),
+	)
+	return policy, nil
+}
+
+// DeletePolicy deletes the organization's policy for a message type.
+// Messages of the type then follow the default policy, if any.
+func (s *RetentionService) DeletePolicy(ctx context.Context, orgID, messageType string) error {
+	policyRepo := s.policyRepo.WithContext(ctx).ForOrganization(orgID)
+	if _, err := policyRepo.FindByType(messageType); err != nil {
+		return errors.NewNotFound("Retention policy", messageType)
+	}
+	if err := policyRepo.DeleteByType(messageType); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	logger.FromContext(ctx, s.logger).Info("Retention policy deleted",
+		zap.String("organization_id", orgID),
+		zap.String("message_type", messageType),
+	)
+	return nil
+}
+
+// Report returns what applying the organization's policies now would
+// remove, without removing anything
+func (s *RetentionService) Report(ctx context.Context, orgID string) (*models.RetentionReport, error) {
+	policies, err := s.ListPolicies(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, orgID, policies, true)
+}
+
+// Run applies the organization's policies now and reports what was removed.
+// It fails with a conflict while another run is in progress, here or on
+// another replica.
+func (s *RetentionService) Run(ctx context.Context, orgID string) (*models.RetentionReport, error) {
+	if !s.running.TryLock() {
+		return nil, errors.NewConflict("A retention run is already in progress")
+	}
+	defer s.running.Unlock()
+
+	if err := s.renewLease(ctx, orgID); err != nil {
+		return nil, err
+	}
+	defer s.releaseLease(orgID)
+
+	policies, err := s.ListPolicies(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(ctx, orgID, policies, false)
+}
+
+// RunAll applies the policies of every organization that has some. An
+// organization whose run fails does not stop the others, and organizations
+// another replica is running are skipped.
+func (s *RetentionService) RunAll(ctx context.Context) {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	policies, err := s.policyRepo.WithContext(ctx).ListAll()
+	if err != nil {
+		s.logger.Error("Failed to list retention policies", zap.Error(err))
+		return
+	}
+	byOrg := map[string][]*models.RetentionPolicy{}
+	var orgIDs []string
+	for _, policy := range policies {
+		if _, ok := byOrg[policy.OrganizationID]; !ok {
+			orgIDs = append(orgIDs, policy.OrganizationID)
+		}
+		byOrg[policy.OrganizationID] = append(byOrg[policy.OrganizationID], policy)
+	}
+
+	for _, orgID := range orgIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.renewLease(ctx, orgID); err != nil {
+			if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrConflict {
+				s.logger.Error("Retention run failed", zap.String("organization_id", orgID), zap.Error(err))
+			}
+			continue
+		}
+		if _, err := s.apply(ctx, orgID, byOrg[orgID], false); err != nil {
+			s.logger.Error("Retention run failed", zap.String("organization_id", orgID), zap.Error(err))
+		}
+		s.releaseLease(orgID)
+	}
+}
+
+// retentionLeaseName names the lease on an organization's retention runs
+func retentionLeaseName(orgID string) string {
+	return "retention:" + orgID
+}
+
+// renewLease takes or extends this process's lease on the organization's
+// retention runs. It fails with a conflict while another replica holds it.
+func (s *RetentionService) renewLease(ctx context.Context, orgID string) error {
+	held, err := s.leaseRepo.WithContext(ctx).Acquire(retentionLeaseName(orgID), s.owner, retentionLeaseTTL)
+	if err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	if !held {
+		return errors.NewConflict("A retention run is already in progress")
+	}
+	return nil
+}
+
+// releaseLease gives up the lease on the organization's retention runs,
+// even when the run was cancelled
+func (s *RetentionService) releaseLease(orgID string) {
+	if err := s.leaseRepo.WithContext(context.Background()).Release(retentionLeaseName(orgID), s.owner); err != nil {
+		s.logger.Warn("Failed to release retention lease", zap.String("organization_id", orgID), zap.Error(err))
+	}
+}
+
+// apply applies an organization's policies, or only counts what they
+// would remove when dryRun is set. The default policy applies to the
+// message types without a policy of their own.
+func (s *RetentionService) apply(ctx context.Context, orgID string, policies []*models.RetentionPolicy, dryRun bool) (*models.RetentionReport, error) {
+	at := s.now()
+	report := &models.RetentionReport{OrganizationID: orgID, DryRun: dryRun, At: at, Policies: []*models.RetentionPolicyReport{}}
+
+	var ownTypes []string
+	for _, policy := range policies {
+		if policy.MessageType != models.RetentionDefaultType {
+			ownTypes = append(ownTypes, policy.MessageType)
+		}
+	}
+	sort.Strings(ownTypes)
+
+	for _, policy := range policies {
+		filter := repositories.MessageAgeFilter{Types: []string{policy.MessageType}}
+		if policy.MessageType == models.RetentionDefaultType {
+			filter = repositories.MessageAgeFilter{ExcludeTypes: ownTypes}
+		}
+		result := &models.RetentionPolicyReport{MessageType: policy.MessageType, Action: policy.Action}
+		report.Policies = append(report.Policies, result)
+
+		// Media goes first, so the messages kept longer lose it, and the
+		// report of a dry run counts what a run would remove
+		if policy.MediaRetainDays > 0 {
+			before := at.AddDate(0, 0, -policy.MediaRetainDays)
+			result.MediaBefore = &before
+			mediaFilter := filter
+			mediaFilter.Before, mediaFilter.WithMedia = before, true
+			if err := s.removeMedia(ctx, orgID, mediaFilter, at, dryRun, result); err != nil {
+				return nil, err
+			}
+		}
+		if policy.RetainDays > 0 {
+			before := at.AddDate(0, 0, -policy.RetainDays)
+			result.MessagesBefore = &before
+			filter.Before = before
+			if err := s.purge(ctx, orgID, filter, policy.Action, dryRun, result); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !dryRun {
+		for _, result := range report.Policies {
+			if result.Messages == 0 && result.Media == 0 {
+				continue
+			}
+			logger.FromContext(ctx, s.logger).Info("Retention policy applied",
+				zap.String("organization_id", orgID),
+				zap.String("message_type", result.MessageType),
+				zap.String("action", result.Action),
+				zap.Int64("messages", result.Messages),
+				zap.Int64("media", result.Media),
+				zap.Strings("archives", result.Archives),
+			)
+		}
+	}
+	return report, nil
+}
+
+// removeMedia removes the media of the messages the filter selects,
+// deleting the files stored here
+func (s *RetentionService) removeMedia(ctx context.Context, orgID string, filter repositories.MessageAgeFilter, at time.Time, dryRun bool, result *models.RetentionPolicyReport) error {
+	messageRepo := s.messageRepo.WithContext(ctx).ForOrganization(orgID)
+	if dryRun {
+		count, err := messageRepo.CountAged(filter)
+		if err != nil {
+			return errors.NewDatabaseError(err)
+		}
+		result.Media = count
+		return nil
+	}
+
+	for {
+		if err := s.renewLease(ctx, orgID); err != nil {
+			return err
+		}
+		messages, err := messageRepo.FindAged(filter, retentionMediaBatch)
+		if err != nil {
+			return errors.NewDatabaseError(err)
+		}
+		if err := s.deleteStoredMedia(messages); err != nil {
+			return err
+		}
+		removed, err := messageRepo.RemoveMedia(messageIDs(messages), at)
+		if err != nil {
+			return errors.NewDatabaseError(err)
+		}
+		result.Media += removed
+		if len(messages) < retentionMediaBatch || removed == 0 {
+			return nil
+		}
+	}
+}
+
+// purge archives or deletes the messages the filter selects, with their
+// reactions and the media files stored here
+func (s *RetentionService) purge(ctx context.Context, orgID string, filter repositories.MessageAgeFilter, action string, dryRun bool, result *models.RetentionPolicyReport) error {
+	messageRepo := s.messageRepo.WithContext(ctx).ForOrganization(orgID)
+	if dryRun {
+		count, err := messageRepo.CountAged(filter)
+		if err != nil {
+			return errors.NewDatabaseError(err)
+		}
+		result.Messages = count
+		return nil
+	}
+	if action == models.RetentionActionArchive && s.archive == nil {
+		return errors.NewInternalError(fmt.Errorf("no media store to archive messages in"))
+	}
+
+	for {
+		if err := s.renewLease(ctx, orgID); err != nil {
+			return err
+		}
+		messages, err := messageRepo.FindAged(filter, retentionMessageBatch)
+		if err != nil {
+			return errors.NewDatabaseError(err)
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+		if action == models.RetentionActionArchive {
+			key, err := s.archiveMessages(ctx, orgID, messages)
+			if err != nil {
+				return err
+			}
+			result.Archives = append(result.Archives, key)
+		}
+		if err := s.deleteStoredMedia(messages); err != nil {
+			return err
+		}
+		deleted, err := messageRepo.DeleteMessages(messageIDs(messages))
+		if err != nil {
+			return errors.NewDatabaseError(err)
+		}
+		result.Messages += deleted
+		if len(messages) < retentionMessageBatch || deleted == 0 {
+			return nil
+		}
+	}
+}
+
+// archiveMessages writes messages and their reactions to a new archive
+// file and returns its key
+func (s *RetentionService) archiveMessages(ctx context.Context, orgID string, messages []*models.Message) (string, error) {
+	var targets []string
+	for _, message := range messages {
+		if message.WhatsAppMessageID != "" {
+			targets = append(targets, message.WhatsAppMessageID)
+		}
+	}
+	reactions, err := s.reactionRepo.WithContext(ctx).ForOrganization(orgID).FindByTargets(targets)
+	if err != nil {
+		return "", errors.NewDatabaseError(err)
+	}
+	byTarget := map[string][]*models.Reaction{}
+	for _, reaction := range reactions {
+		byTarget[reaction.TargetWhatsAppMessageID] = append(byTarget[reaction.TargetWhatsAppMessageID], reaction)
+	}
+
+	archived := make([]*models.ArchivedMessage, len(messages))
+	for i, message := range messages {
+		archived[i] = &models.ArchivedMessage{Message: message}
+		if message.WhatsAppMessageID != "" {
+			archived[i].Reactions = byTarget[message.WhatsAppMessageID]
+		}
+	}
+	key, err := s.archive.Write(orgID, archived)
+	if err != nil {
+		return "", errors.NewInternalError(fmt.Errorf("failed to write message archive: %w", err))
+	}
+	return key, nil
+}
+
+// deleteStoredMedia deletes the media files stored here that messages
+// refer to
+func (s *RetentionService) deleteStoredMedia(messages []*models.Message) error {
+	for _, message := range messages {
+		for _, store := range s.media {
+			key, ok := store.Key(message.MediaURL)
+			if !ok {
+				continue
+			}
+			if err := store.Delete(key); err != nil {
+				return errors.NewInternalError(fmt.Errorf("failed to delete media file: %w", err))
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// messageIDs returns the IDs of messages
+func messageIDs(messages []*models.Message) []string {
+	ids := make([]string, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	return ids
+}
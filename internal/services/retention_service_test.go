@@ -0,0 +1,259 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/storage"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type retentionFixture struct {
+	*messageServiceFixture
+	policies *memRetentionPolicyStore
+	leases   *memLeaseStore
+	archive  *MessageArchive
+	service  *RetentionService
+	mediaDir string
+	now      time.Time
+}
+
+func newRetentionFixture(t *testing.T) *retentionFixture {
+	t.Helper()
+	f := &retentionFixture{
+		messageServiceFixture: newMessageServiceFixture(),
+		policies:              newMemRetentionPolicyStore(),
+		leases:                newMemLeaseStore(),
+		mediaDir:              t.TempDir(),
+		now:                   time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	media := storage.NewLocalStore(f.mediaDir)
+	f.archive = NewMessageArchive(media)
+	f.service = NewRetentionService(f.policies, f.messages, f.reactions, f.leases, []storage.MediaStore{media}, f.archive, zap.NewNop())
+	f.service.now = func() time.Time { return f.now }
+	return f
+}
+
+// message stores a message of type sent days before the fixture's now
+func (f *retentionFixture) message(orgID, messageType string, days int, mediaURL string) *models.Message {
+	message := &models.Message{
+		WhatsAppMessageID: models.GenerateID("wamid"),
+		FromNumber:        "14155550100",
+		ToNumber:          "15550000000",
+		Direction:         "inbound",
+		MessageType:       messageType,
+		Content:           messageType + " message",
+		MediaURL:          mediaURL,
+		Timestamp:         f.now.AddDate(0, 0, -days),
+	}
+	f.messages.ForOrganization(orgID).Create(message)
+	return message
+}
+
+func TestRetentionPolicies(t *testing.T) {
+	f := newRetentionFixture(t)
+	ctx := context.Background()
+
+	policy, err := f.service.SetPolicy(ctx, "org_a", &models.RetentionPolicy{MessageType: models.RetentionDefaultType, RetainDays: 365})
+	if err != nil {
+		t.Fatalf("SetPolicy: %v", err)
+	}
+	if policy.Action != models.RetentionActionArchive || policy.OrganizationID != "org_a" {
+		t.Errorf("messages should be archived by default: %+v", policy)
+	}
+
+	for _, invalid := range []*models.RetentionPolicy{
+		{MessageType: "image"},
+		{MessageType: "image", RetainDays: 30, MediaRetainDays: 60},
+		{MessageType: "image", RetainDays: -1},
+		{MessageType: "Image!", RetainDays: 30},
+		{MessageType: "image", Action: "shred", RetainDays: 30},
+	} {
+		_, err := f.service.SetPolicy(ctx, "org_a", invalid)
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+			t.Errorf("SetPolicy(%+v) should be rejected, got %v", invalid, err)
+		}
+	}
+
+	f.service.SetPolicy(ctx, "org_a", &models.RetentionPolicy{MessageType: "image", Action: models.RetentionActionDelete, MediaRetainDays: 30})
+	policies, err := f.service.ListPolicies(ctx, "org_a")
+	if err != nil || len(policies) != 2 || policies[0].MessageType != models.RetentionDefaultType || policies[1].MessageType != "image" {
+		t.Errorf("ListPolicies: %v %v", policies, err)
+	}
+	if others, _ := f.service.ListPolicies(ctx, "org_b"); len(others) != 0 {
+		t.Errorf("policies of other organizations should not be listed: %v", others)
+	}
+
+	if err := f.service.DeletePolicy(ctx, "org_a", "image"); err != nil {
+		t.Fatalf("DeletePolicy: %v", err)
+	}
+	if err := f.service.DeletePolicy(ctx, "org_a", "image"); !errors.IsNotFound(err) {
+		t.Errorf("deleting a missing policy should not be found, got %v", err)
+	}
+
+	f.service.archive = nil
+	_, err = f.service.SetPolicy(ctx, "org_a", &models.RetentionPolicy{MessageType: "text", RetainDays: 30})
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+		t.Errorf("archiving without an archive should be rejected, got %v", err)
+	}
+	if _, err := f.service.SetPolicy(ctx, "org_a", &models.RetentionPolicy{MessageType: "text", Action: models.RetentionActionDelete, RetainDays: 30}); err != nil {
+		t.Errorf("deleting without an archive should be allowed: %v", err)
+	}
+}
+
+func TestRetentionRun(t *testing.T) {
+	f := newRetentionFixture(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"old.jpg", "recent.jpg"} {
+		os.WriteFile(filepath.Join(f.mediaDir, name), []byte("jpeg"), 0o644)
+	}
+	oldText := f.message("org_a", models.MessageTypeText, 400, "")
+	keptText := f.message("org_a", models.MessageTypeText, 100, "")
+	oldImage := f.message("org_a", models.MessageTypeImage, 400, "old.jpg")
+	agingImage := f.message("org_a", models.MessageTypeImage, 40, "recent.jpg")
+	newImage := f.message("org_a", models.MessageTypeImage, 1, "https://example.com/new.png")
+	otherOrg := f.message("org_b", models.MessageTypeText, 400, "")
+	f.reactions.ForOrganization("org_a").Upsert(&models.Reaction{TargetWhatsAppMessageID: oldText.WhatsAppMessageID, FromNumber: "15550000000", Direction: "outbound", Emoji: "👍"})
+
+	f.service.SetPolicy(ctx, "org_a", &models.RetentionPolicy{MessageType: models.RetentionDefaultType, RetainDays: 365})
+	f.service.SetPolicy(ctx, "org_a", &models.RetentionPolicy{MessageType: models.MessageTypeImage, Action: models.RetentionActionDelete, RetainDays: 90, MediaRetainDays: 30})
+
+	report, err := f.service.Report(ctx, "org_a")
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	byType := map[string]*models.RetentionPolicyReport{}
+	for _, result := range report.Policies {
+		byType[result.MessageType] = result
+	}
+	if !report.DryRun || byType["default"].Messages != 1 || byType["image"].Messages != 1 || byType["image"].Media != 2 {
+		t.Errorf("unexpected dry run %+v %+v %+v", report, byType["default"], byType["image"])
+	}
+	if len(f.messages.all()) != 6 {
+		t.Fatal("a dry run should remove nothing")
+	}
+
+	report, err = f.service.Run(ctx, "org_a")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, result := range report.Policies {
+		byType[result.MessageType] = result
+	}
+	if report.DryRun || byType["default"].Messages != 1 || len(byType["default"].Archives) != 1 ||
+		byType["image"].Messages != 1 || byType["image"].Media != 2 || len(byType["image"].Archives) != 0 {
+		t.Errorf("unexpected run %+v %+v %+v", report, byType["default"], byType["image"])
+	}
+
+	kept := map[string]models.Message{}
+	for _, message := range f.messages.all() {
+		kept[message.ID] = message
+	}
+	if len(kept) != 4 {
+		t.Errorf("expected 4 messages kept, got %d", len(kept))
+	}
+	for _, id := range []string{oldText.ID, oldImage.ID} {
+		if _, ok := kept[id]; ok {
+			t.Errorf("message %s should be removed", id)
+		}
+	}
+	if _, ok := kept[otherOrg.ID]; !ok {
+		t.Error("messages of other organizations should be kept")
+	}
+	if m := kept[keptText.ID]; m.ID == "" {
+		t.Error("messages younger than the policy should be kept")
+	}
+	if m := kept[agingImage.ID]; m.MediaURL != "" || m.MediaRemovedAt == nil || m.Content == "" {
+		t.Errorf("the media of the aging image should be removed and its text kept: %+v", m)
+	}
+	if m := kept[newImage.ID]; m.MediaURL == "" || m.MediaRemovedAt != nil {
+		t.Errorf("recent media should be kept: %+v", m)
+	}
+	for _, name := range []string{"old.jpg", "recent.jpg"} {
+		if _, err := os.Stat(filepath.Join(f.mediaDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be deleted", name)
+		}
+	}
+
+	var archived []*models.ArchivedMessage
+	f.archive.Scan("org_a", func(message *models.ArchivedMessage) error {
+		archived = append(archived, message)
+		return nil
+	})
+	if len(archived) != 1 || archived[0].ID != oldText.ID || archived[0].Content != oldText.Content || len(archived[0].Reactions) != 1 {
+		t.Errorf("the old text should be archived with its reaction: %+v", archived)
+	}
+
+	report, _ = f.service.Run(ctx, "org_a")
+	for _, result := range report.Policies {
+		if result.Messages != 0 || result.Media != 0 {
+			t.Errorf("a second run should remove nothing: %+v", result)
+		}
+	}
+}
+
+func TestRetentionRunAll(t *testing.T) {
+	f := newRetentionFixture(t)
+	ctx := context.Background()
+
+	f.message("org_a", models.MessageTypeText, 40, "")
+	f.message("org_b", models.MessageTypeText, 40, "")
+	f.message("org_c", models.MessageTypeText, 40, "")
+	f.service.SetPolicy(ctx, "org_a", &models.RetentionPolicy{MessageType: models.RetentionDefaultType, RetainDays: 30})
+	f.service.SetPolicy(ctx, "org_b", &models.RetentionPolicy{MessageType: models.MessageTypeText, Action: models.RetentionActionDelete, RetainDays: 30})
+
+	f.service.RunAll(ctx)
+	messages := f.messages.all()
+	if len(messages) != 1 || messages[0].OrganizationID != "org_c" {
+		t.Errorf("only organizations with policies should lose messages: %+v", messages)
+	}
+
+	f.service.running.Lock()
+	_, err := f.service.Run(ctx, "org_a")
+	f.service.running.Unlock()
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrConflict {
+		t.Errorf("runs should not overlap, got %v", err)
+	}
+}
+
+func TestRetentionRunsOneReplicaPerOrganization(t *testing.T) {
+	f := newRetentionFixture(t)
+	ctx := context.Background()
+
+	f.message("org_a", models.MessageTypeText, 40, "")
+	f.message("org_b", models.MessageTypeText, 40, "")
+	for _, orgID := range []string{"org_a", "org_b"} {
+		f.service.SetPolicy(ctx, orgID, &models.RetentionPolicy{MessageType: models.RetentionDefaultType, RetainDays: 30})
+	}
+
+	// Another replica is running org_a
+	f.leases.Acquire(retentionLeaseName("org_a"), "worker_other", time.Minute)
+	_, err := f.service.Run(ctx, "org_a")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrConflict {
+		t.Errorf("a run should not start while another replica runs the organization, got %v", err)
+	}
+	f.service.RunAll(ctx)
+	messages := f.messages.all()
+	if len(messages) != 1 || messages[0].OrganizationID != "org_a" {
+		t.Errorf("RunAll should skip only the organization another replica runs: %+v", messages)
+	}
+	if _, held := f.leases.leases[retentionLeaseName("org_b")]; held {
+		t.Error("the lease should be released after the run")
+	}
+
+	// Once the other replica is done, org_a runs here
+	f.leases.Release(retentionLeaseName("org_a"), "worker_other")
+	report, err := f.service.Run(ctx, "org_a")
+	if err != nil || report.Policies[0].Messages != 1 {
+		t.Errorf("Run: %+v %v", report, err)
+	}
+	if len(f.leases.leases) != 0 {
+		t.Errorf("no lease should be left: %+v", f.leases.leases)
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+)
+
+// SegmentService handles saved contact segments. A segment is a filter
+// expression over contacts; its contacts are resolved whenever it is read,
+// so segments always reflect the current tags and activity.
+type SegmentService struct {
+	segmentRepo      repositories.SegmentStore
+	contactRepo      repositories.ContactStore
+	attributeService *AttributeService
+}
+
+// NewSegmentService creates a new segment service. Filters are checked
+// against the declared contact attributes.
+func NewSegmentService(segmentRepo repositories.SegmentStore, contactRepo repositories.ContactStore, attributeService *AttributeService) *SegmentService {
+	return &SegmentService{
+		segmentRepo:      segmentRepo,
+		contactRepo:      contactRepo,
+		attributeService: attributeService,
+	}
+}
+
+// CreateSegment creates a segment. Names are unique per organization.
+func (s *SegmentService) CreateSegment(ctx context.Context, orgID string, segment *models.Segment) error {
+	segment.Name = strings.TrimSpace(segment.Name)
+	if err := s.validateSegment(ctx, orgID, segment.Name, segment.Filter); err != nil {
+		return err
+	}
+
+	segmentRepo := s.segmentRepo.WithContext(ctx).ForOrganization(orgID)
+	if _, err := segmentRepo.FindByName(segment.Name); err == nil {
+		return errors.NewConflict("A segment named " + segment.Name + " already exists")
+	}
+	if err := segmentRepo.Create(segment); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// GetSegment gets a segment by ID, with its number of contacts when
+// withCount is set
+func (s *SegmentService) GetSegment(ctx context.Context, orgID, segmentID string, withCount bool) (*models.Segment, error) {
+	var segment models.Segment
+	if err := s.segmentRepo.WithContext(ctx).ForOrganization(orgID).FindByID(segmentID, &segment); err != nil {
+		return nil, errors.NewNotFound("Segment", segmentID)
+	}
+
+	if withCount {
+		count, err := s.contactRepo.WithContext(ctx).ForOrganization(orgID).CountWithFilters(map[string]interface{}{"segment": segment.Filter})
+		if err != nil {
+			if appErr, ok := err.(*errors.AppError); ok {
+				return nil, appErr
+			}
+			return nil, errors.NewDatabaseError(err)
+		}
+		segment.ContactCount = &count
+	}
+	return &segment, nil
+}
+
+// ListSegments lists segments by name
+func (s *SegmentService) ListSegments(ctx context.Context, orgID string, pagination *utils.Pagination) ([]*models.Segment, error) {
+	return s.segmentRepo.WithContext(ctx).ForOrganization(orgID).ListAll(pagination)
+}
+
+// UpdateSegment updates the name, description or filter of a segment
+func (s *SegmentService) UpdateSegment(ctx context.Context, orgID, segmentID string, updates map[string]interface{}) (*models.Segment, error) {
+	segmentRepo := s.segmentRepo.WithContext(ctx).ForOrganization(orgID)
+
+	var segment models.Segment
+	if err := segmentRepo.FindByID(segmentID, &segment); err != nil {
+		return nil, errors.NewNotFound("Segment", segmentID)
+	}
+
+	allowed := make(map[string]interface{})
+	for _, field := range []string{"name", "description", "filter"} {
+		value, ok := updates[field]
+		if !ok {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			return nil, errors.NewBadRequest(field + " must be a string")
+		}
+		allowed[field] = strings.TrimSpace(text)
+	}
+	if len(allowed) == 0 {
+		return nil, errors.NewBadRequest("name, description or filter is required")
+	}
+
+	name, filter := segment.Name, segment.Filter
+	if value, ok := allowed["name"].(string); ok {
+		name = value
+	}
+	if value, ok := allowed["filter"].(string); ok {
+		filter = value
+	}
+	if err := s.validateSegment(ctx, orgID, name, filter); err != nil {
+		return nil, err
+	}
+	if name != segment.Name {
+		if existing, err := segmentRepo.FindByName(name); err == nil && existing.ID != segment.ID {
+			return nil, errors.NewConflict("A segment named " + name + " already exists")
+		}
+	}
+
+	if err := segmentRepo.UpdateFields(segmentID, &segment, allowed); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return s.GetSegment(ctx, orgID, segmentID, false)
+}
+
+// DeleteSegment deletes a segment. Its contacts are not affected.
+func (s *SegmentService) DeleteSegment(ctx context.Context, orgID, segmentID string) error {
+	segmentRepo := s.segmentRepo.WithContext(ctx).ForOrganization(orgID)
+
+	var segment models.Segment
+	if err := segmentRepo.FindByID(segmentID, &segment); err != nil {
+		return errors.NewNotFound("Segment", segmentID)
+	}
+	if err := segmentRepo.Delete(&segment); err != nil {
+		return errors.NewDatabaseError(err)
+	}
+	return nil
+}
+
+// ListSegmentContacts lists the contacts currently in a segment, the
+// audience it stands for. It supports cursor pagination and the sort
+// filters of contact listings.
+func (s *SegmentService) ListSegmentContacts(ctx context.Context, orgID, segmentID string, filters map[string]interface{}, pagination *utils.Pagination) ([]*models.Contact, error) {
+	segment, err := s.GetSegment(ctx, orgID, segmentID, false)
+	if err != nil {
+		return nil, err
+	}
+	filters["segment"] = segment.Filter
+
+	contactRepo := s.contactRepo.WithContext(ctx).ForOrganization(orgID)
+	contacts, err := contactRepo.ListWithFilters(filters, pagination)
+	if err != nil {
+		if appErr, ok := err.(*errors.AppError); ok {
+			return nil, appErr
+		}
+		return nil, errors.NewDatabaseError(err)
+	}
+	if err := contactRepo.LoadTags(contacts); err != nil {
+		return nil, errors.NewDatabaseError(err)
+	}
+	return contacts, nil
+}
+
+// validateSegment checks a segment's name and filter
+func (s *SegmentService) validateSegment(ctx context.Context, orgID, name, filter string) error {
+	if name == "" {
+		return errors.NewBadRequest("name is required")
+	}
+	if len(name) > 255 {
+		return errors.NewBadRequest("name is longer than 255 characters")
+	}
+	return s.attributeService.ValidateFilter(ctx, orgID, filter)
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+)
+
+func TestSegmentService(t *testing.T) {
+	service := NewSegmentService(newMemSegmentStore(), newMemContactStore(), NewAttributeService(newMemAttributeStore()))
+	ctx := context.Background()
+
+	segment := &models.Segment{Name: " VIPs ", Filter: "tag = vip AND last_message_at > -30d"}
+	if err := service.CreateSegment(ctx, "org_a", segment); err != nil {
+		t.Fatalf("CreateSegment: %v", err)
+	}
+	if segment.ID == "" || segment.Name != "VIPs" {
+		t.Errorf("unexpected segment %+v", segment)
+	}
+
+	err := service.CreateSegment(ctx, "org_a", &models.Segment{Name: "VIPs", Filter: "tag = vip"})
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrConflict {
+		t.Errorf("expected a conflict for a duplicate name, got %v", err)
+	}
+	if err := service.CreateSegment(ctx, "org_b", &models.Segment{Name: "VIPs", Filter: "tag = vip"}); err != nil {
+		t.Errorf("names are unique per organization: %v", err)
+	}
+
+	for name, s := range map[string]*models.Segment{
+		"no name":       {Filter: "tag = vip"},
+		"no filter":     {Name: "Empty"},
+		"bad filter":    {Name: "Bad", Filter: "tag = vip AND"},
+		"unknown field": {Name: "Unknown", Filter: "plan = pro"},
+	} {
+		err := service.CreateSegment(ctx, "org_a", s)
+		if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrInvalidRequest {
+			t.Errorf("%s: expected bad request, got %v", name, err)
+		}
+	}
+
+	updated, err := service.UpdateSegment(ctx, "org_a", segment.ID, map[string]interface{}{"filter": "tag = vip", "organization_id": "org_b"})
+	if err != nil || updated.Filter != "tag = vip" || updated.OrganizationID != "org_a" {
+		t.Errorf("UpdateSegment: %+v %v", updated, err)
+	}
+	if _, err := service.UpdateSegment(ctx, "org_a", segment.ID, map[string]interface{}{"filter": "message_count ~ 3"}); err == nil {
+		t.Error("invalid filters should be rejected on update")
+	}
+	if _, err := service.GetSegment(ctx, "org_b", segment.ID, false); err == nil {
+		t.Error("segment should not be visible to another organization")
+	}
+
+	segments, _ := service.ListSegments(ctx, "org_a", utils.NewPagination(10, 0))
+	if len(segments) != 1 {
+		t.Errorf("expected 1 segment, got %d", len(segments))
+	}
+	if err := service.DeleteSegment(ctx, "org_a", segment.ID); err != nil {
+		t.Fatalf("DeleteSegment: %v", err)
+	}
+	if _, err := service.GetSegment(ctx, "org_a", segment.ID, false); err == nil {
+		t.Error("deleted segment should be gone")
+	}
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
+)
+
+// Sender sends messages through the WhatsApp Cloud API. *whatsapp.Client
+// implements it.
+type Sender interface {
+	SendTextMessage(ctx context.Context, to, text string, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error)
+	SendMediaMessage(ctx context.Context, to, mediaURL, caption string, mediaType whatsapp.MediaType, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error)
+	SendTemplateMessage(ctx context.Context, to, templateName, language string, params []string, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error)
+	SendLocationMessage(ctx context.Context, to string, location whatsapp.Location, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error)
+	SendLocationRequest(ctx context.Context, to, body string, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error)
+	SendContactsMessage(ctx context.Context, to string, contacts []whatsapp.ContactCard, opts whatsapp.SendOptions) (*whatsapp.MessageResponse, error)
+	SendReaction(ctx context.Context, to, messageID, emoji string) (*whatsapp.MessageResponse, error)
+	MarkAsRead(ctx context.Context, messageID string, typing bool) error
+}
+
+// UserBlocker is implemented by senders that can block users on WhatsApp,
+// so blocked contacts cannot message the business phone number at all
+type UserBlocker interface {
+	BlockUsers(ctx context.Context, users []string) error
+	UnblockUsers(ctx context.Context, users []string) error
+}
+
+// SenderResolver returns the Sender of an organization. OrganizationService
+// implements it.
+type SenderResolver interface {
+	Sender(ctx context.Context, orgID string) (Sender, error)
+}
+
+var (
+	_ Sender         = (*whatsapp.Client)(nil)
+	_ UserBlocker    = (*whatsapp.Client)(nil)
+	_ SenderResolver = (*OrganizationService)(nil)
+)
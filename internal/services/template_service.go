@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/models"
 	"github.com/ashoksahoo/whatsapp-business-platform/internal/repositories"
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
@@ -9,37 +10,37 @@ import (
 
 // TemplateService handles template business logic
 type TemplateService struct {
-	templateRepo *repositories.TemplateRepository
+	templateRepo repositories.TemplateStore
 }
 
 // NewTemplateService creates a new template service
-func NewTemplateService(templateRepo *repositories.TemplateRepository) *TemplateService {
+func NewTemplateService(templateRepo repositories.TemplateStore) *TemplateService {
 	return &TemplateService{
 		templateRepo: templateRepo,
 	}
 }
 
 // CreateTemplate creates a new template
-func (s *TemplateService) CreateTemplate(template *models.Template) error {
+func (s *TemplateService) CreateTemplate(ctx context.Context, orgID string, template *models.Template) error {
 	if err := template.Validate(); err != nil {
 		return errors.NewBadRequest(err.Error())
 	}
 
-	return s.templateRepo.Create(template)
+	return s.templateRepo.WithContext(ctx).ForOrganization(orgID).Create(template)
 }
 
 // GetTemplate gets a template by ID
-func (s *TemplateService) GetTemplate(templateID string) (*models.Template, error) {
+func (s *TemplateService) GetTemplate(ctx context.Context, orgID, templateID string) (*models.Template, error) {
 	var template models.Template
-	if err := s.templateRepo.FindByID(templateID, &template); err != nil {
+	if err := s.templateRepo.WithContext(ctx).ForOrganization(orgID).FindByID(templateID, &template); err != nil {
 		return nil, errors.NewNotFound("Template", templateID)
 	}
 	return &template, nil
 }
 
 // GetTemplateByName gets a template by name and language
-func (s *TemplateService) GetTemplateByName(name, language string) (*models.Template, error) {
-	template, err := s.templateRepo.FindByName(name, language)
+func (s *TemplateService) GetTemplateByName(ctx context.Context, orgID, name, language string) (*models.Template, error) {
+	template, err := s.templateRepo.WithContext(ctx).ForOrganization(orgID).FindByName(name, language)
 	if err != nil {
 		return nil, errors.NewNotFound("Template", name)
 	}
@@ -47,23 +48,25 @@ func (s *TemplateService) GetTemplateByName(name, language string) (*models.Temp
 }
 
 // ListTemplates lists all templates
-func (s *TemplateService) ListTemplates(pagination *utils.Pagination) ([]*models.Template, error) {
-	return s.templateRepo.ListAll(pagination)
+func (s *TemplateService) ListTemplates(ctx context.Context, orgID string, pagination *utils.Pagination) ([]*models.Template, error) {
+	return s.templateRepo.WithContext(ctx).ForOrganization(orgID).ListAll(pagination)
 }
 
 // UpdateTemplate updates a template
-func (s *TemplateService) UpdateTemplate(templateID string, updates map[string]interface{}) (*models.Template, error) {
+func (s *TemplateService) UpdateTemplate(ctx context.Context, orgID, templateID string, updates map[string]interface{}) (*models.Template, error) {
+	templateRepo := s.templateRepo.WithContext(ctx).ForOrganization(orgID)
+
 	var template models.Template
-	if err := s.templateRepo.FindByID(templateID, &template); err != nil {
+	if err := templateRepo.FindByID(templateID, &template); err != nil {
 		return nil, errors.NewNotFound("Template", templateID)
 	}
 
-	if err := s.templateRepo.UpdateFields(templateID, &template, updates); err != nil {
+	if err := templateRepo.UpdateFields(templateID, &template, updates); err != nil {
 		return nil, errors.NewDatabaseError(err)
 	}
 
 	// Fetch updated template
-	if err := s.templateRepo.FindByID(templateID, &template); err != nil {
+	if err := templateRepo.FindByID(templateID, &template); err != nil {
 		return nil, errors.NewDatabaseError(err)
 	}
 
@@ -71,11 +74,13 @@ func (s *TemplateService) UpdateTemplate(templateID string, updates map[string]i
 }
 
 // DeleteTemplate deletes a template
-func (s *TemplateService) DeleteTemplate(templateID string) error {
+func (s *TemplateService) DeleteTemplate(ctx context.Context, orgID, templateID string) error {
+	templateRepo := s.templateRepo.WithContext(ctx).ForOrganization(orgID)
+
 	var template models.Template
-	if err := s.templateRepo.FindByID(templateID, &template); err != nil {
+	if err := templateRepo.FindByID(templateID, &template); err != nil {
 		return errors.NewNotFound("Template", templateID)
 	}
 
-	return s.templateRepo.Delete(&template)
+	return templateRepo.Delete(&template)
 }
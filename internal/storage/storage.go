@@ -0,0 +1,195 @@
+// Package storage stores media files, call recordings and message archives
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MediaStore stores files under slash-separated keys, such as
+// "archives/org_abc/messages.jsonl.gz"
+type MediaStore interface {
+	// Key returns the key of the stored file a media reference names.
+	// Stored media is referred to by its key or, in local stores, its
+	// path, optionally as a file:// URL. Other references, such as links
+	// and WhatsApp media IDs, name no stored file.
+	Key(reference string) (string, bool)
+	// Open opens the file stored under key
+	Open(key string) (io.ReadCloser, error)
+	// Create stores a file under key, replacing any file stored there. The
+	// file only appears once the writer is closed, and not at all when a
+	// write failed.
+	Create(key string) (io.WriteCloser, error)
+	// Delete deletes the file stored under key. Deleting a missing file is
+	// not an error.
+	Delete(key string) error
+	// List returns the keys of the files stored under prefix, sorted
+	List(prefix string) ([]string, error)
+}
+
+// LocalStore is a MediaStore in a local directory
+type LocalStore struct {
+	dir string
+}
+
+var _ MediaStore = (*LocalStore)(nil)
+
+// NewLocalStore creates a media store in dir. The directory is created
+// when the first file is stored.
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{dir: dir}
+}
+
+// Key returns the key of the file a reference names, when the file is in
+// the store's directory
+func (s *LocalStore) Key(reference string) (string, bool) {
+	reference = strings.TrimPrefix(reference, "file://")
+	if reference == "" || strings.Contains(reference, "://") {
+		return "", false
+	}
+	dir, err := filepath.Abs(s.dir)
+	if err != nil {
+		return "", false
+	}
+	file := filepath.FromSlash(reference)
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	rel, err := filepath.Rel(dir, filepath.Clean(file))
+	if err != nil || !validKey(filepath.ToSlash(rel)) {
+		return "", false
+	}
+	if info, err := os.Stat(file); err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Open opens the file stored under key
+func (s *LocalStore) Open(key string) (io.ReadCloser, error) {
+	file, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(file)
+}
+
+// Create writes to a temporary file in the key's directory, renamed to the
+// key when the writer is closed
+func (s *LocalStore) Create(key string) (io.WriteCloser, error) {
+	file, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return nil, err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return nil, err
+	}
+	return &localFile{File: temp, path: file}, nil
+}
+
+// Delete deletes the file stored under key
+func (s *LocalStore) Delete(key string) error {
+	file, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List returns the keys of the files under prefix, which names a directory
+// of the store. Temporary files of unfinished writes are left out.
+func (s *LocalStore) List(prefix string) ([]string, error) {
+	prefix = strings.Trim(prefix, "/")
+	root := s.dir
+	if prefix != "" {
+		dir, err := s.path(prefix)
+		if err != nil {
+			return nil, err
+		}
+		root = dir
+	}
+
+	var keys []string
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, file)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+// path returns the file a key is stored in
+func (s *LocalStore) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("invalid media key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// validKey reports whether key names a file inside a store
+func validKey(key string) bool {
+	return key != "" && key != "." && !path.IsAbs(key) && path.Clean(key) == key &&
+		key != ".." && !strings.HasPrefix(key, "../")
+}
+
+// localFile is a file being written to a LocalStore
+type localFile struct {
+	*os.File
+	path string
+	err  error
+}
+
+// Write writes to the temporary file, remembering the first error
+func (f *localFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	return n, err
+}
+
+// Close moves the written file to its key, or removes it when a write
+// failed
+func (f *localFile) Close() error {
+	if f.err != nil {
+		f.File.Close()
+		os.Remove(f.File.Name())
+		return f.err
+	}
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+	if err := os.Rename(f.File.Name(), f.path); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+	return nil
+}
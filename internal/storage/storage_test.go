@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStore(filepath.Join(dir, "media"))
+
+	if keys, err := store.List("archives"); err != nil || len(keys) != 0 {
+		t.Fatalf("an empty store should list nothing: %v %v", keys, err)
+	}
+
+	w, err := store.Create("archives/org_a/one.jsonl.gz")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	io.WriteString(w, "data")
+	if keys, _ := store.List("archives"); len(keys) != 0 {
+		t.Errorf("files should only appear once closed: %v", keys)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	w, _ = store.Create("photo.jpg")
+	w.Close()
+
+	keys, err := store.List("archives")
+	if err != nil || strings.Join(keys, ",") != "archives/org_a/one.jsonl.gz" {
+		t.Errorf("unexpected keys %v %v", keys, err)
+	}
+	r, err := store.Open("archives/org_a/one.jsonl.gz")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	content, _ := io.ReadAll(r)
+	r.Close()
+	if string(content) != "data" {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	for reference, want := range map[string]string{
+		"photo.jpg": "photo.jpg",
+		"file://" + filepath.Join(dir, "media", "photo.jpg"): "photo.jpg",
+		filepath.Join(dir, "media", "photo.jpg"):             "photo.jpg",
+		"missing.jpg":                                        "",
+		"../outside.jpg":                                     "",
+		"https://example.com/photo.jpg":                      "",
+		"":                                                   "",
+	} {
+		if key, ok := store.Key(reference); key != want || ok != (want != "") {
+			t.Errorf("Key(%q) = %q, %v; want %q", reference, key, ok, want)
+		}
+	}
+
+	if err := store.Delete("photo.jpg"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete("photo.jpg"); err != nil {
+		t.Errorf("deleting a missing file should succeed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "media", "photo.jpg")); !os.IsNotExist(err) {
+		t.Error("the file should be deleted")
+	}
+	for _, key := range []string{"../outside", "/etc/passwd", "a/../b", ""} {
+		if _, err := store.Open(key); err == nil || !strings.Contains(err.Error(), "invalid media key") {
+			t.Errorf("Open(%q) should reject the key, got %v", key, err)
+		}
+	}
+}
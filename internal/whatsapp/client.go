@@ -1,11 +1,14 @@
 package whatsapp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/logger"
 	"github.com/go-resty/resty/v2"
 	"go.uber.org/zap"
 )
@@ -17,6 +20,13 @@ type Config struct {
 	APIBaseURL    string
 	APIVersion    string
 	Logger        *zap.Logger
+
+	// Throughput configures the governor created for the client when
+	// Governor is nil
+	Throughput GovernorConfig
+	// Governor paces outbound sends; clients of the same phone number
+	// should share one
+	Governor *Governor
 }
 
 // Client represents a WhatsApp API client
@@ -24,6 +34,7 @@ type Client struct {
 	httpClient    *resty.Client
 	phoneNumberID string
 	baseURL       string
+	governor      *Governor
 	logger        *zap.Logger
 }
 
@@ -55,16 +66,22 @@ func NewClient(config Config) (*Client, error) {
 	httpClient.SetRetryWaitTime(1 * time.Second)
 	httpClient.SetRetryMaxWaitTime(5 * time.Second)
 
+	governor := config.Governor
+	if governor == nil {
+		governor = NewGovernor(config.Throughput)
+	}
+
 	return &Client{
 		httpClient:    httpClient,
 		phoneNumberID: config.PhoneNumberID,
 		baseURL:       fmt.Sprintf("%s/%s/%s", baseURL, apiVersion, config.PhoneNumberID),
+		governor:      governor,
 		logger:        config.Logger,
 	}, nil
 }
 
 // SendTextMessage sends a text message
-func (c *Client) SendTextMessage(to, text string) (*MessageResponse, error) {
+func (c *Client) SendTextMessage(ctx context.Context, to, text string, opts SendOptions) (*MessageResponse, error) {
 	payload := map[string]interface{}{
 		"messaging_product": "whatsapp",
 		"recipient_type":    "individual",
@@ -75,11 +92,12 @@ func (c *Client) SendTextMessage(to, text string) (*MessageResponse, error) {
 		},
 	}
 
-	return c.sendMessage(payload)
+	return c.sendMessage(ctx, payload, opts)
 }
 
-// SendMediaMessage sends a media message (image, document, audio, video)
-func (c *Client) SendMediaMessage(to, mediaURL, caption string, mediaType MediaType) (*MessageResponse, error) {
+// SendMediaMessage sends a media message (image, document, audio, video,
+// sticker)
+func (c *Client) SendMediaMessage(ctx context.Context, to, mediaURL, caption string, mediaType MediaType, opts SendOptions) (*MessageResponse, error) {
 	mediaObj := map[string]interface{}{
 		"link": mediaURL,
 	}
@@ -96,11 +114,11 @@ func (c *Client) SendMediaMessage(to, mediaURL, caption string, mediaType MediaT
 		string(mediaType):   mediaObj,
 	}
 
-	return c.sendMessage(payload)
+	return c.sendMessage(ctx, payload, opts)
 }
 
 // SendTemplateMessage sends a template message
-func (c *Client) SendTemplateMessage(to, templateName, language string, params []string) (*MessageResponse, error) {
+func (c *Client) SendTemplateMessage(ctx context.Context, to, templateName, language string, params []string, opts SendOptions) (*MessageResponse, error) {
 	components := []map[string]interface{}{}
 
 	if len(params) > 0 {
@@ -132,69 +150,235 @@ func (c *Client) SendTemplateMessage(to, templateName, language string, params [
 		},
 	}
 
-	return c.sendMessage(payload)
+	return c.sendMessage(ctx, payload, opts)
+}
+
+// SendLocationMessage sends a location pin
+func (c *Client) SendLocationMessage(ctx context.Context, to string, location Location, opts SendOptions) (*MessageResponse, error) {
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                to,
+		"type":              "location",
+		"location":          location,
+	}
+
+	return c.sendMessage(ctx, payload, opts)
+}
+
+// SendLocationRequest sends an interactive message with a button asking the
+// customer to share their location
+func (c *Client) SendLocationRequest(ctx context.Context, to, body string, opts SendOptions) (*MessageResponse, error) {
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                to,
+		"type":              "interactive",
+		"interactive": map[string]interface{}{
+			"type": "location_request_message",
+			"body": map[string]string{
+				"text": body,
+			},
+			"action": map[string]string{
+				"name": "send_location",
+			},
+		},
+	}
+
+	return c.sendMessage(ctx, payload, opts)
+}
+
+// SendContactsMessage sends contact cards
+func (c *Client) SendContactsMessage(ctx context.Context, to string, contacts []ContactCard, opts SendOptions) (*MessageResponse, error) {
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                to,
+		"type":              "contacts",
+		"contacts":          contacts,
+	}
+
+	return c.sendMessage(ctx, payload, opts)
+}
+
+// SendReaction reacts to a message with an emoji. An empty emoji removes
+// the reaction.
+func (c *Client) SendReaction(ctx context.Context, to, messageID, emoji string) (*MessageResponse, error) {
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                to,
+		"type":              "reaction",
+		"reaction": map[string]string{
+			"message_id": messageID,
+			"emoji":      emoji,
+		},
+	}
+
+	return c.sendMessage(ctx, payload, SendOptions{})
+}
+
+// SendOptions holds the options common to message sends
+type SendOptions struct {
+	// ReplyTo is the WhatsApp message ID of the message to quote
+	ReplyTo string
 }
 
 // sendMessage sends a message to WhatsApp API
-func (c *Client) sendMessage(payload map[string]interface{}) (*MessageResponse, error) {
+func (c *Client) sendMessage(ctx context.Context, payload map[string]interface{}, opts SendOptions) (*MessageResponse, error) {
 	endpoint := fmt.Sprintf("/%s/messages", c.phoneNumberID)
+	to, _ := payload["to"].(string)
+	log := logger.FromContext(ctx, c.logger)
+
+	if opts.ReplyTo != "" {
+		payload["context"] = map[string]string{"message_id": opts.ReplyTo}
+	}
 
-	c.logger.Debug("Sending message to WhatsApp",
+	// Wait for capacity within Meta's messaging limits
+	if err := c.governor.Acquire(ctx, to); err != nil {
+		log.Warn("Send held back by throughput governor",
+			zap.String("phone_number_id", c.phoneNumberID),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	log.Debug("Sending message to WhatsApp",
 		zap.String("endpoint", endpoint),
 		zap.Any("payload", payload),
 	)
 
-	resp, err := c.httpClient.R().
+	resp, err := c.request(ctx).
 		SetBody(payload).
 		Post(endpoint)
 
 	if err != nil {
-		c.logger.Error("Failed to send message", zap.Error(err))
-		return nil, errors.NewWhatsAppError(err)
+		log.Error("Failed to send message", zap.Error(err))
+		c.governor.Release(to)
+		return nil, transportError(err)
 	}
 
 	if resp.IsError() {
-		var errResp ErrorResponse
-		if err := json.Unmarshal(resp.Body(), &errResp); err == nil {
-			c.logger.Error("WhatsApp API error",
-				zap.Int("code", errResp.Error.Code),
-				zap.String("message", errResp.Error.Message),
-				zap.String("type", errResp.Error.Type),
-			)
-			return nil, errors.NewWhatsAppError(fmt.Errorf("%s: %s", errResp.Error.Type, errResp.Error.Message))
+		apiErr := c.parseError(ctx, resp)
+		if IsThrottlingErrorCode(apiErr.Code) {
+			c.governor.Throttled(apiErr.Code, to)
 		}
-
-		c.logger.Error("WhatsApp API error",
-			zap.Int("status", resp.StatusCode()),
-			zap.String("body", string(resp.Body())),
-		)
-		return nil, errors.NewWhatsAppError(fmt.Errorf("WhatsApp API returned status %d", resp.StatusCode()))
+		c.governor.Release(to)
+		return nil, apiErr.AppError()
 	}
+	c.governor.Succeeded(to)
 
 	var msgResp MessageResponse
 	if err := json.Unmarshal(resp.Body(), &msgResp); err != nil {
-		c.logger.Error("Failed to parse response", zap.Error(err))
+		log.Error("Failed to parse response", zap.Error(err))
 		return nil, errors.NewInternalError(err)
 	}
 
-	c.logger.Info("Message sent successfully",
+	log.Info("Message sent successfully",
 		zap.String("message_id", msgResp.Messages[0].ID),
 	)
 
 	return &msgResp, nil
 }
 
+// MarkAsRead marks an inbound message, and the messages before it, as read
+// so the customer sees blue ticks. With typing set the customer also sees a
+// typing indicator until we reply, for up to 25 seconds.
+func (c *Client) MarkAsRead(ctx context.Context, messageID string, typing bool) error {
+	endpoint := fmt.Sprintf("/%s/messages", c.phoneNumberID)
+
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"status":            "read",
+		"message_id":        messageID,
+	}
+	if typing {
+		payload["typing_indicator"] = map[string]string{"type": "text"}
+	}
+
+	resp, err := c.request(ctx).
+		SetBody(payload).
+		Post(endpoint)
+	if err != nil {
+		return transportError(err)
+	}
+
+	if resp.IsError() {
+		return c.parseError(ctx, resp).AppError()
+	}
+
+	return nil
+}
+
+// BlockUsers blocks WhatsApp users from messaging the business phone
+// number. Users are given as phone numbers or WhatsApp IDs. Blocked users
+// cannot message us and our messages to them fail.
+func (c *Client) BlockUsers(ctx context.Context, users []string) error {
+	return c.blockUsers(ctx, users, true)
+}
+
+// UnblockUsers lets users blocked with BlockUsers message the business
+// phone number again
+func (c *Client) UnblockUsers(ctx context.Context, users []string) error {
+	return c.blockUsers(ctx, users, false)
+}
+
+// blockUsers adds users to, or removes them from, the phone number's block
+// list. Graph reports users it could not block in the response body.
+func (c *Client) blockUsers(ctx context.Context, users []string, block bool) error {
+	endpoint := fmt.Sprintf("/%s/block_users", c.phoneNumberID)
+
+	entries := make([]map[string]string, 0, len(users))
+	for _, user := range users {
+		entries = append(entries, map[string]string{"user": user})
+	}
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"block_users":       entries,
+	}
+
+	req := c.request(ctx).SetBody(payload)
+	var resp *resty.Response
+	var err error
+	if block {
+		resp, err = req.Post(endpoint)
+	} else {
+		resp, err = req.Delete(endpoint)
+	}
+	if err != nil {
+		return transportError(err)
+	}
+
+	if resp.IsError() {
+		return c.parseError(ctx, resp).AppError()
+	}
+
+	var result BlockUsersResponse
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return errors.NewInternalError(err)
+	}
+	if failed := result.BlockUsers.FailedUsers; len(failed) > 0 {
+		message := "could not update the block list"
+		if len(failed[0].Errors) > 0 {
+			message = failed[0].Errors[0].Message
+		}
+		return errors.NewAppError(errors.ErrWhatsAppAPI, message, http.StatusBadGateway).
+			WithDetail("user", failed[0].Input)
+	}
+	return nil
+}
+
 // GetMessageStatus gets the delivery status of a message
-func (c *Client) GetMessageStatus(messageID string) (*MessageStatus, error) {
+func (c *Client) GetMessageStatus(ctx context.Context, messageID string) (*MessageStatus, error) {
 	endpoint := fmt.Sprintf("/%s", messageID)
 
-	resp, err := c.httpClient.R().Get(endpoint)
+	resp, err := c.request(ctx).Get(endpoint)
 	if err != nil {
-		return nil, errors.NewWhatsAppError(err)
+		return nil, transportError(err)
 	}
 
 	if resp.IsError() {
-		return nil, errors.NewWhatsAppError(fmt.Errorf("failed to get message status: %d", resp.StatusCode()))
+		return nil, c.parseError(ctx, resp).AppError()
 	}
 
 	var status MessageStatus
@@ -205,6 +389,51 @@ func (c *Client) GetMessageStatus(messageID string) (*MessageStatus, error) {
 	return &status, nil
 }
 
+// request starts a Graph API request bound to ctx. The request ID carried by
+// ctx is sent along so calls can be correlated with our logs.
+func (c *Client) request(ctx context.Context) *resty.Request {
+	req := c.httpClient.R().SetContext(ctx)
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		req.SetHeader("X-Request-ID", requestID)
+	}
+	return req
+}
+
+// parseError decodes and logs a Graph API error response
+func (c *Client) parseError(ctx context.Context, resp *resty.Response) *APIError {
+	var errResp ErrorResponse
+	if err := json.Unmarshal(resp.Body(), &errResp); err != nil || errResp.Error.Message == "" {
+		errResp.Error.Code = -1
+		errResp.Error.Message = fmt.Sprintf("WhatsApp API returned status %d", resp.StatusCode())
+	}
+	apiErr := newAPIError(&errResp, resp.StatusCode())
+
+	logger.FromContext(ctx, c.logger).Error("WhatsApp API error",
+		zap.Int("status", resp.StatusCode()),
+		zap.Int("code", apiErr.Code),
+		zap.Int("subcode", apiErr.Subcode),
+		zap.String("category", string(apiErr.Category)),
+		zap.String("message", apiErr.Message),
+		zap.String("fbtrace_id", apiErr.FBTraceID),
+	)
+	return apiErr
+}
+
+// transportError wraps a failure to reach the Graph API. These are always
+// worth retrying.
+func transportError(err error) *errors.AppError {
+	appErr := errors.NewWhatsAppError(err)
+	appErr.Code = errors.ErrWhatsAppTransient
+	appErr.StatusCode = http.StatusServiceUnavailable
+	appErr.Retryable = true
+	return appErr.WithDetail("category", string(ErrorCategoryTransient))
+}
+
+// Governor returns the throughput governor of the client's phone number
+func (c *Client) Governor() *Governor {
+	return c.governor
+}
+
 // SetTimeout sets the HTTP client timeout
 func (c *Client) SetTimeout(duration time.Duration) {
 	c.httpClient.SetTimeout(duration)
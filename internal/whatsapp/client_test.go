@@ -0,0 +1,88 @@
+package whatsapp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, log *zap.Logger) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	client, err := NewClient(Config{APIToken: "token", PhoneNumberID: "phone", APIBaseURL: srv.URL, Logger: log})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+func TestClientStopsAtRequestDeadline(t *testing.T) {
+	abandoned := make(chan struct{}, 4)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		// Graph never answers; only the caller giving up ends the request.
+		// The server notices a closed connection once the body is read.
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+		abandoned <- struct{}{}
+	}, zap.NewNop())
+	// Without the request deadline the send would only stop at this timeout
+	client.SetTimeout(5 * time.Second)
+	client.SetRetryPolicy(0, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.SendTextMessage(ctx, "14155550100", "hello", SendOptions{}); err == nil {
+		t.Fatal("a send past the request deadline should fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("the send should stop at the deadline, took %v", elapsed)
+	}
+	select {
+	case <-abandoned:
+	case <-time.After(2 * time.Second):
+		t.Error("the Graph request was not cancelled")
+	}
+}
+
+func TestClientLogsRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("X-Request-ID") != "req-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"missing request ID","code":100}}`))
+			return
+		}
+		w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.1"}]}`))
+	}, zap.New(core))
+
+	ctx := logger.ContextWithRequestID(context.Background(), "req-1")
+	if _, err := client.SendTextMessage(ctx, "14155550100", "hello", SendOptions{}); err != nil {
+		t.Fatalf("SendTextMessage: %v", err)
+	}
+	if _, err := client.SendTextMessage(context.Background(), "14155550100", "hello", SendOptions{}); err == nil {
+		t.Fatal("a send without a request ID should fail against this server")
+	}
+
+	sent := logs.FilterMessage("Message sent successfully").All()
+	if len(sent) != 1 || sent[0].ContextMap()["request_id"] != "req-1" {
+		t.Errorf("the send log should carry the request ID: %+v", sent)
+	}
+	failed := logs.FilterMessage("WhatsApp API error").All()
+	if len(failed) != 1 {
+		t.Fatalf("expected one API error log, got %d", len(failed))
+	}
+	if _, ok := failed[0].ContextMap()["request_id"]; ok {
+		t.Error("logs of sends without a request ID should not carry one")
+	}
+}
@@ -0,0 +1,192 @@
+package whatsapp
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+)
+
+// ErrorCategory groups Graph API error codes by what the caller can do about them
+type ErrorCategory string
+
+const (
+	ErrorCategoryAuth                 ErrorCategory = "auth"
+	ErrorCategoryRateLimit            ErrorCategory = "rate_limit"
+	ErrorCategoryRecipientUnavailable ErrorCategory = "recipient_unavailable"
+	ErrorCategoryReengagement         ErrorCategory = "reengagement_required"
+	ErrorCategoryTemplatePaused       ErrorCategory = "template_paused"
+	ErrorCategoryInvalidParameter     ErrorCategory = "invalid_parameter"
+	ErrorCategoryTransient            ErrorCategory = "transient"
+	ErrorCategoryUnknown              ErrorCategory = "unknown"
+)
+
+// errorCategories maps Graph API error codes to categories. See
+// https://developers.facebook.com/docs/whatsapp/cloud-api/support/error-codes
+var errorCategories = map[int]ErrorCategory{
+	// Authorization and permissions
+	0:      ErrorCategoryAuth, // AuthException
+	3:      ErrorCategoryAuth, // API method
+	10:     ErrorCategoryAuth, // Permission denied
+	190:    ErrorCategoryAuth, // Access token expired
+	131005: ErrorCategoryAuth, // Access denied
+
+	// Throttling
+	ErrorCodeAppRateLimit:      ErrorCategoryRateLimit,
+	ErrorCodeWABARateLimit:     ErrorCategoryRateLimit,
+	ErrorCodeThroughputReached: ErrorCategoryRateLimit,
+	ErrorCodeSpamRateLimit:     ErrorCategoryRateLimit,
+	ErrorCodePairRateLimit:     ErrorCategoryRateLimit,
+	613:                        ErrorCategoryRateLimit, // Calls within one hour exceeded
+
+	// Recipient cannot receive the message
+	131026: ErrorCategoryRecipientUnavailable, // Message undeliverable
+	131030: ErrorCategoryRecipientUnavailable, // Recipient not in allowed list
+
+	// Customer service window closed
+	131047: ErrorCategoryReengagement,
+
+	// Template cannot be used right now
+	132015: ErrorCategoryTemplatePaused, // Template paused
+	132016: ErrorCategoryTemplatePaused, // Template disabled
+
+	// Request problems
+	100:    ErrorCategoryInvalidParameter, // Invalid parameter
+	131008: ErrorCategoryInvalidParameter, // Required parameter missing
+	131009: ErrorCategoryInvalidParameter, // Parameter value invalid
+	131021: ErrorCategoryInvalidParameter, // Recipient cannot be sender
+	131051: ErrorCategoryInvalidParameter, // Unsupported message type
+	131052: ErrorCategoryInvalidParameter, // Media download error
+	131053: ErrorCategoryInvalidParameter, // Media upload error
+	132000: ErrorCategoryInvalidParameter, // Template param count mismatch
+	132001: ErrorCategoryInvalidParameter, // Template does not exist
+	132005: ErrorCategoryInvalidParameter, // Template hydrated text too long
+	132007: ErrorCategoryInvalidParameter, // Template format character policy violated
+	132012: ErrorCategoryInvalidParameter, // Template parameter format mismatch
+
+	// Temporary failures on Meta's side
+	1:      ErrorCategoryTransient, // API unknown
+	2:      ErrorCategoryTransient, // API service
+	131000: ErrorCategoryTransient, // Something went wrong
+	131016: ErrorCategoryTransient, // Service unavailable
+	133004: ErrorCategoryTransient, // Server temporarily unavailable
+}
+
+// ClassifyError returns the category of a Graph API error code. Codes that
+// are not known are classified by HTTP status: 5xx responses are transient.
+func ClassifyError(code, httpStatus int) ErrorCategory {
+	if category, ok := errorCategories[code]; ok {
+		return category
+	}
+	if code >= 200 && code <= 299 {
+		return ErrorCategoryAuth // Permission errors
+	}
+	if httpStatus >= 500 {
+		return ErrorCategoryTransient
+	}
+	return ErrorCategoryUnknown
+}
+
+// Retryable returns true if a request that failed with this category may
+// succeed when sent again later
+func (c ErrorCategory) Retryable() bool {
+	return c == ErrorCategoryRateLimit || c == ErrorCategoryTransient
+}
+
+// appErrorCode returns the AppError code of the category
+func (c ErrorCategory) appErrorCode() string {
+	switch c {
+	case ErrorCategoryAuth:
+		return errors.ErrWhatsAppAuth
+	case ErrorCategoryRateLimit:
+		return errors.ErrWhatsAppRateLimited
+	case ErrorCategoryRecipientUnavailable:
+		return errors.ErrWhatsAppRecipientUnavailable
+	case ErrorCategoryReengagement:
+		return errors.ErrWhatsAppReengagementRequired
+	case ErrorCategoryTemplatePaused:
+		return errors.ErrWhatsAppTemplatePaused
+	case ErrorCategoryInvalidParameter:
+		return errors.ErrWhatsAppInvalidParameter
+	case ErrorCategoryTransient:
+		return errors.ErrWhatsAppTransient
+	default:
+		return errors.ErrWhatsAppAPI
+	}
+}
+
+// statusCode returns the HTTP status returned to our API callers
+func (c ErrorCategory) statusCode() int {
+	switch c {
+	case ErrorCategoryRateLimit:
+		return http.StatusTooManyRequests
+	case ErrorCategoryRecipientUnavailable, ErrorCategoryReengagement,
+		ErrorCategoryTemplatePaused, ErrorCategoryInvalidParameter:
+		return http.StatusUnprocessableEntity
+	case ErrorCategoryTransient:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// APIError is an error returned by the Graph API
+type APIError struct {
+	Code       int
+	Subcode    int
+	Type       string
+	Message    string
+	ErrorData  string
+	FBTraceID  string
+	HTTPStatus int
+	Category   ErrorCategory
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("whatsapp %s error %d: %s", e.Category, e.Code, e.Message)
+}
+
+// newAPIError builds an APIError from a decoded error response
+func newAPIError(resp *ErrorResponse, httpStatus int) *APIError {
+	return &APIError{
+		Code:       resp.Error.Code,
+		Subcode:    resp.Error.ErrorSubcode,
+		Type:       resp.Error.Type,
+		Message:    resp.Error.Message,
+		ErrorData:  resp.Error.ErrorData.Details,
+		FBTraceID:  resp.Error.FBTraceID,
+		HTTPStatus: httpStatus,
+		Category:   ClassifyError(resp.Error.Code, httpStatus),
+	}
+}
+
+// AppError converts the error into the AppError returned to API callers.
+// The Graph error stays available through errors.As.
+func (e *APIError) AppError() *errors.AppError {
+	appErr := errors.NewAppError(e.Category.appErrorCode(), e.Message, e.Category.statusCode()).
+		WithError(e).
+		WithDetails(map[string]interface{}{
+			"category":      string(e.Category),
+			"whatsapp_code": e.Code,
+		})
+	if e.Subcode != 0 {
+		appErr.WithDetail("whatsapp_subcode", e.Subcode)
+	}
+	if e.ErrorData != "" {
+		appErr.WithDetail("error_data", e.ErrorData)
+	}
+	if e.FBTraceID != "" {
+		appErr.WithDetail("fbtrace_id", e.FBTraceID)
+	}
+	appErr.Retryable = e.Category.Retryable()
+	return appErr
+}
+
+// AsAPIError returns the Graph API error wrapped in err, if any
+func AsAPIError(err error) (*APIError, bool) {
+	var apiErr *APIError
+	ok := stderrors.As(err, &apiErr)
+	return apiErr, ok
+}
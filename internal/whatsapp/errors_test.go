@@ -0,0 +1,66 @@
+package whatsapp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		code       int
+		httpStatus int
+		want       ErrorCategory
+		retryable  bool
+	}{
+		{190, 401, ErrorCategoryAuth, false},
+		{200, 403, ErrorCategoryAuth, false},
+		{130429, 400, ErrorCategoryRateLimit, true},
+		{131048, 400, ErrorCategoryRateLimit, true},
+		{131026, 400, ErrorCategoryRecipientUnavailable, false},
+		{131047, 400, ErrorCategoryReengagement, false},
+		{132015, 400, ErrorCategoryTemplatePaused, false},
+		{131009, 400, ErrorCategoryInvalidParameter, false},
+		{131000, 500, ErrorCategoryTransient, true},
+		{-1, 503, ErrorCategoryTransient, true},
+		{-1, 400, ErrorCategoryUnknown, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.code, "/", tt.httpStatus), func(t *testing.T) {
+			got := ClassifyError(tt.code, tt.httpStatus)
+			if got != tt.want {
+				t.Errorf("ClassifyError(%d, %d) = %s, want %s", tt.code, tt.httpStatus, got, tt.want)
+			}
+			if got.Retryable() != tt.retryable {
+				t.Errorf("%s.Retryable() = %v, want %v", got, got.Retryable(), tt.retryable)
+			}
+		})
+	}
+}
+
+func TestAPIErrorAppError(t *testing.T) {
+	var resp ErrorResponse
+	resp.Error.Code = 131047
+	resp.Error.Message = "Re-engagement message"
+	resp.Error.ErrorData.Details = "More than 24 hours have passed"
+	resp.Error.FBTraceID = "AbCdEf123"
+
+	appErr := newAPIError(&resp, 400).AppError()
+
+	if appErr.Code != errors.ErrWhatsAppReengagementRequired {
+		t.Errorf("Code = %s", appErr.Code)
+	}
+	if appErr.Retryable {
+		t.Error("expected re-engagement errors not to be retryable")
+	}
+	if appErr.Details["fbtrace_id"] != "AbCdEf123" || appErr.Details["whatsapp_code"] != 131047 {
+		t.Errorf("unexpected details: %v", appErr.Details)
+	}
+
+	apiErr, ok := AsAPIError(appErr)
+	if !ok || apiErr.Category != ErrorCategoryReengagement {
+		t.Fatal("expected the Graph error to be reachable through the AppError")
+	}
+}
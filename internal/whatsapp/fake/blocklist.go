@@ -0,0 +1,78 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// handleBlockUsers handles POST and DELETE /{phone-number-id}/block_users,
+// which add users to and remove them from the phone number's block list
+func (s *Simulator) handleBlockUsers(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		MessagingProduct string `json:"messaging_product"`
+		BlockUsers       []struct {
+			User string `json:"user"`
+		} `json:"block_users"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, invalidParameter("Invalid JSON payload"))
+		return
+	}
+	if payload.MessagingProduct != "whatsapp" {
+		writeError(w, invalidParameter("The parameter messaging_product is required."))
+		return
+	}
+	if len(payload.BlockUsers) == 0 {
+		writeError(w, invalidParameter("The parameter block_users is required."))
+		return
+	}
+
+	block := r.Method == http.MethodPost
+	var changed, failed []map[string]interface{}
+	s.mu.Lock()
+	for _, entry := range payload.BlockUsers {
+		if !recipientPattern.MatchString(entry.User) {
+			failed = append(failed, map[string]interface{}{
+				"input":  entry.User,
+				"errors": []map[string]interface{}{{"code": 100, "message": "Invalid parameter: user"}},
+			})
+			continue
+		}
+		waID := strings.TrimPrefix(entry.User, "+")
+		if block {
+			s.blocked[waID] = true
+		} else {
+			delete(s.blocked, waID)
+		}
+		changed = append(changed, map[string]interface{}{"input": entry.User, "wa_id": waID})
+	}
+	s.mu.Unlock()
+
+	key := "added_users"
+	if !block {
+		key = "removed_users"
+	}
+	result := map[string]interface{}{key: changed}
+	if len(failed) > 0 {
+		result["failed_users"] = failed
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"block_users":       result,
+	})
+}
+
+// Blocked returns the WhatsApp IDs on the block list, sorted
+func (s *Simulator) Blocked() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := make([]string, 0, len(s.blocked))
+	for user := range s.blocked {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	return users
+}
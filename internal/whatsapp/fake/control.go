@@ -0,0 +1,141 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// controlPrefix is the path of the endpoints that drive the simulator over
+// HTTP, for use with cmd/fakegraph:
+//
+//	GET    /_fake/messages        list accepted messages
+//	DELETE /_fake/messages        reset the simulator
+//	GET    /_fake/reads           list messages marked as read
+//	GET    /_fake/blocked         list blocked WhatsApp IDs
+//	POST   /_fake/errors          fail the next send: {"recipient", "http_status", "code", "message"}
+//	POST   /_fake/inbound         emit an inbound message: {"from", "name", "text", "reply_to"}
+//	                              or reaction: {"from", "react_to", "emoji"}
+//	POST   /_fake/statuses        emit a status: {"message_id", "status", "error_code", "error_title"}
+//	POST   /_fake/templates       register a template: {"name", "language", "category", "status"}
+//	GET    /_fake/media/{id}      download uploaded media
+const controlPrefix = "_fake"
+
+// serveControl routes /_fake requests
+func (s *Simulator) serveControl(w http.ResponseWriter, r *http.Request, segments []string) {
+	if len(segments) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case segments[0] == "messages" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": s.Messages()})
+
+	case segments[0] == "messages" && r.Method == http.MethodDelete:
+		s.Reset()
+		w.WriteHeader(http.StatusNoContent)
+
+	case segments[0] == "reads" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": s.Reads()})
+
+	case segments[0] == "blocked" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": s.Blocked()})
+
+	case segments[0] == "errors" && r.Method == http.MethodPost:
+		var req struct {
+			Recipient string `json:"recipient"`
+			Error
+		}
+		if !decodeControl(w, r, &req) {
+			return
+		}
+		if req.Code == 0 {
+			writeControlError(w, "code is required")
+			return
+		}
+		s.FailNext(req.Recipient, req.Error)
+		w.WriteHeader(http.StatusNoContent)
+
+	case segments[0] == "inbound" && r.Method == http.MethodPost:
+		var req struct {
+			From    string `json:"from"`
+			Name    string `json:"name"`
+			Text    string `json:"text"`
+			Caption string `json:"caption"`
+			ReplyTo string `json:"reply_to"`
+			ReactTo string `json:"react_to"`
+			Emoji   string `json:"emoji"`
+		}
+		if !decodeControl(w, r, &req) {
+			return
+		}
+		if req.From == "" || (req.Text == "" && req.ReactTo == "") {
+			writeControlError(w, "from and text or react_to are required")
+			return
+		}
+		id, err := s.SendInbound(r.Context(), InboundMessage{From: req.From, Name: req.Name, Text: req.Text,
+			ReplyTo: req.ReplyTo, ReactTo: req.ReactTo, Emoji: req.Emoji})
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"id": id})
+
+	case segments[0] == "statuses" && r.Method == http.MethodPost:
+		var req struct {
+			MessageID  string `json:"message_id"`
+			Status     string `json:"status"`
+			ErrorCode  int    `json:"error_code"`
+			ErrorTitle string `json:"error_title"`
+		}
+		if !decodeControl(w, r, &req) {
+			return
+		}
+		var err error
+		if req.ErrorCode != 0 {
+			err = s.SendFailedStatus(r.Context(), req.MessageID, req.ErrorCode, req.ErrorTitle)
+		} else {
+			err = s.SendStatus(r.Context(), req.MessageID, req.Status)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case segments[0] == "templates" && r.Method == http.MethodPost:
+		var req Template
+		if !decodeControl(w, r, &req) {
+			return
+		}
+		if req.Name == "" || req.Language == "" {
+			writeControlError(w, "name and language are required")
+			return
+		}
+		t := s.AddTemplate(req.Name, req.Language, req.Category)
+		if req.Status != "" {
+			s.SetTemplateStatus(t.Name, req.Status)
+			t.Status = req.Status
+		}
+		writeJSON(w, http.StatusOK, t)
+
+	case segments[0] == "media" && len(segments) == 2 && r.Method == http.MethodGet:
+		s.serveMediaDownload(w, r, segments[1])
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// decodeControl decodes a control request body, writing an error on failure
+func decodeControl(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeControlError(w, "invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeControlError(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": message})
+}
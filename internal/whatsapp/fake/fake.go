@@ -0,0 +1,377 @@
+// Package fake simulates the parts of the WhatsApp Cloud (Graph) API the
+// platform uses, so the client, services and handlers can be exercised
+// without reaching graph.facebook.com.
+//
+// The simulator accepts message sends, media uploads, block list changes and
+// template management, returns IDs shaped like Meta's, can be told to fail
+// sends with real Graph error codes, and emits signed webhooks (statuses and
+// inbound messages) to a configured URL. Use NewServer from tests, or run cmd/fakegraph and point
+// WHATSAPP_API_BASE_URL at it.
+package fake
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
+)
+
+// Defaults used when the config leaves a field empty
+const (
+	DefaultBusinessAccountID  = "100000000000001"
+	DefaultPhoneNumberID      = "100000000000002"
+	DefaultDisplayPhoneNumber = "15550000000"
+	DefaultStatusDelay        = 100 * time.Millisecond
+)
+
+// Config configures the simulator
+type Config struct {
+	// AccessToken, when set, must be sent as the bearer token of every
+	// Graph request. Other tokens are rejected with error 190.
+	AccessToken string
+	// BusinessAccountID is the WABA ID used as the webhook entry ID
+	BusinessAccountID string
+	// PhoneNumberID, when set, is the only phone number sends are accepted
+	// for. It is also the sender of simulated inbound messages.
+	PhoneNumberID      string
+	DisplayPhoneNumber string
+
+	// WebhookURL receives the simulated webhooks; none are sent when empty
+	WebhookURL string
+	// AppSecret signs webhooks in X-Hub-Signature-256
+	AppSecret string
+	// AutoStatus emits sent, delivered and read statuses for every accepted
+	// message, StatusDelay apart
+	AutoStatus  bool
+	StatusDelay time.Duration
+
+	// StrictTemplates rejects template sends for templates that were not
+	// created on the simulator
+	StrictTemplates bool
+}
+
+// Message is a message accepted by the simulator
+type Message struct {
+	ID            string                 `json:"id"`
+	PhoneNumberID string                 `json:"phone_number_id"`
+	To            string                 `json:"to"`
+	Type          string                 `json:"type"`
+	Status        string                 `json:"status"`
+	Payload       map[string]interface{} `json:"payload"`
+	RequestID     string                 `json:"request_id,omitempty"`
+	Timestamp     time.Time              `json:"timestamp"`
+}
+
+// ReadReceipt is an inbound message marked as read
+type ReadReceipt struct {
+	MessageID string    `json:"message_id"`
+	Typing    bool      `json:"typing"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Error is a Graph API error the simulator returns for a send
+type Error struct {
+	HTTPStatus int    `json:"http_status"`
+	Code       int    `json:"code"`
+	Subcode    int    `json:"subcode,omitempty"`
+	Type       string `json:"type,omitempty"`
+	Message    string `json:"message"`
+}
+
+// Errors commonly returned by Graph for message sends
+var (
+	ErrInvalidToken         = Error{HTTPStatus: http.StatusUnauthorized, Code: 190, Type: "OAuthException", Message: "Error validating access token: Session has expired"}
+	ErrThroughputReached    = Error{HTTPStatus: http.StatusBadRequest, Code: whatsapp.ErrorCodeThroughputReached, Message: "Rate limit hit"}
+	ErrPairRateLimit        = Error{HTTPStatus: http.StatusBadRequest, Code: whatsapp.ErrorCodePairRateLimit, Message: "Pair rate limit hit"}
+	ErrRecipientUnavailable = Error{HTTPStatus: http.StatusBadRequest, Code: 131026, Message: "Message undeliverable"}
+	ErrReengagement         = Error{HTTPStatus: http.StatusBadRequest, Code: 131047, Message: "Re-engagement message"}
+	ErrTemplatePaused       = Error{HTTPStatus: http.StatusBadRequest, Code: 132015, Message: "Template is Paused"}
+	ErrServiceUnavailable   = Error{HTTPStatus: http.StatusServiceUnavailable, Code: 131016, Message: "Service unavailable"}
+)
+
+// Simulator is an http.Handler serving the simulated Graph API
+type Simulator struct {
+	config     Config
+	httpClient *http.Client
+
+	messages  map[string]*Message
+	order     []string
+	reads     []ReadReceipt
+	blocked   map[string]bool
+	media     map[string]*Media
+	templates map[string]*Template
+	failures  []failure
+	seq       int64
+	mu        sync.Mutex
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type failure struct {
+	recipient string
+	err       Error
+}
+
+// New creates a simulator
+func New(config Config) *Simulator {
+	if config.BusinessAccountID == "" {
+		config.BusinessAccountID = DefaultBusinessAccountID
+	}
+	if config.DisplayPhoneNumber == "" {
+		config.DisplayPhoneNumber = DefaultDisplayPhoneNumber
+	}
+	if config.StatusDelay <= 0 {
+		config.StatusDelay = DefaultStatusDelay
+	}
+
+	return &Simulator{
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		messages:   make(map[string]*Message),
+		blocked:    make(map[string]bool),
+		media:      make(map[string]*Media),
+		templates:  make(map[string]*Template),
+		seq:        1000000000000000,
+		done:       make(chan struct{}),
+	}
+}
+
+// Server is a simulator listening on a local httptest server
+type Server struct {
+	*Simulator
+	HTTP *httptest.Server
+	URL  string
+}
+
+// NewServer starts a simulator on a local port. Close it when done.
+func NewServer(config Config) *Server {
+	sim := New(config)
+	srv := httptest.NewServer(sim)
+	return &Server{Simulator: sim, HTTP: srv, URL: srv.URL}
+}
+
+// ClientConfig returns a WhatsApp client config pointing at the server
+func (s *Server) ClientConfig() whatsapp.Config {
+	token := s.config.AccessToken
+	if token == "" {
+		token = "fake-access-token"
+	}
+	phoneNumberID := s.config.PhoneNumberID
+	if phoneNumberID == "" {
+		phoneNumberID = DefaultPhoneNumberID
+	}
+	return whatsapp.Config{
+		APIToken:      token,
+		PhoneNumberID: phoneNumberID,
+		APIBaseURL:    s.URL,
+	}
+}
+
+// Close stops pending webhooks and shuts the server down
+func (s *Server) Close() {
+	s.Simulator.Close()
+	s.HTTP.Close()
+}
+
+// Close stops pending automatic status webhooks
+func (s *Simulator) Close() {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// FailNext makes the next send to recipient fail with err. An empty
+// recipient matches any send. Failures queue up and are used once each.
+func (s *Simulator) FailNext(recipient string, err Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, failure{recipient: recipient, err: err})
+}
+
+// Messages returns the accepted messages in the order they were sent
+func (s *Simulator) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]Message, 0, len(s.order))
+	for _, id := range s.order {
+		messages = append(messages, *s.messages[id])
+	}
+	return messages
+}
+
+// Message returns an accepted message by its WhatsApp message ID
+func (s *Simulator) Message(id string) (Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msg, ok := s.messages[id]
+	if !ok {
+		return Message{}, false
+	}
+	return *msg, true
+}
+
+// Reads returns the messages marked as read, in the order they were marked
+func (s *Simulator) Reads() []ReadReceipt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ReadReceipt(nil), s.reads...)
+}
+
+// Reset forgets messages, read receipts, blocked users, media, templates and
+// queued failures
+func (s *Simulator) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = make(map[string]*Message)
+	s.order = nil
+	s.reads = nil
+	s.blocked = make(map[string]bool)
+	s.media = make(map[string]*Media)
+	s.templates = make(map[string]*Template)
+	s.failures = nil
+}
+
+var versionPrefix = regexp.MustCompile(`^v\d+\.\d+$`)
+
+// ServeHTTP routes Graph API and control requests
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) > 0 && segments[0] == controlPrefix {
+		s.serveControl(w, r, segments[1:])
+		return
+	}
+	if len(segments) > 0 && versionPrefix.MatchString(segments[0]) {
+		segments = segments[1:]
+	}
+
+	if !s.authorized(r) {
+		writeError(w, ErrInvalidToken)
+		return
+	}
+
+	switch {
+	case len(segments) == 2 && segments[1] == "messages" && r.Method == http.MethodPost:
+		s.handleSendMessage(w, r, segments[0])
+	case len(segments) == 2 && segments[1] == "block_users" && (r.Method == http.MethodPost || r.Method == http.MethodDelete):
+		s.handleBlockUsers(w, r)
+	case len(segments) == 2 && segments[1] == "media" && r.Method == http.MethodPost:
+		s.handleUploadMedia(w, r, segments[0])
+	case len(segments) == 2 && segments[1] == "message_templates":
+		s.handleTemplates(w, r, segments[0])
+	case len(segments) == 1 && segments[0] != "" && r.Method == http.MethodGet:
+		s.handleGetObject(w, r, segments[0])
+	case len(segments) == 1 && segments[0] != "" && r.Method == http.MethodDelete:
+		s.handleDeleteMedia(w, segments[0])
+	default:
+		writeError(w, Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       100,
+			Type:       "GraphMethodException",
+			Message:    fmt.Sprintf("Unsupported %s request", strings.ToLower(r.Method)),
+		})
+	}
+}
+
+// authorized checks the bearer token when the simulator has one configured
+func (s *Simulator) authorized(r *http.Request) bool {
+	if s.config.AccessToken == "" {
+		return true
+	}
+	return r.Header.Get("Authorization") == "Bearer "+s.config.AccessToken
+}
+
+// nextID returns a numeric Graph object ID
+func (s *Simulator) nextID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	return fmt.Sprintf("%d", s.seq)
+}
+
+// newMessageID returns an ID shaped like a WhatsApp message ID
+func newMessageID() string {
+	return "wamid.HBgL" + randomString(30)
+}
+
+// randomString returns n URL-safe random characters
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)[:n]
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes a Graph API error response
+func writeError(w http.ResponseWriter, e Error) {
+	status := e.HTTPStatus
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+	errType := e.Type
+	if errType == "" {
+		errType = "OAuthException"
+	}
+
+	body := map[string]interface{}{
+		"message":    e.Message,
+		"type":       errType,
+		"code":       e.Code,
+		"fbtrace_id": randomString(23),
+	}
+	if e.Subcode != 0 {
+		body["error_subcode"] = e.Subcode
+	}
+	if e.Code >= 130000 {
+		body["error_data"] = map[string]string{
+			"messaging_product": "whatsapp",
+			"details":           e.Message,
+		}
+	}
+	writeJSON(w, status, map[string]interface{}{"error": body})
+}
+
+// invalidParameter returns Graph's error for a bad request parameter
+func invalidParameter(message string) Error {
+	return Error{HTTPStatus: http.StatusBadRequest, Code: 100, Message: "(#100) " + message}
+}
+
+// sleep waits for d unless the simulator is closed or ctx is done
+func (s *Simulator) sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.done:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
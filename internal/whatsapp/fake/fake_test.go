@@ -0,0 +1,445 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/internal/whatsapp"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/logger"
+	"go.uber.org/zap"
+)
+
+func newClient(t *testing.T, srv *Server) *whatsapp.Client {
+	t.Helper()
+	cfg := srv.ClientConfig()
+	cfg.Logger = zap.NewNop()
+	client, err := whatsapp.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	return client
+}
+
+// webhookRecorder collects the webhooks posted by the simulator
+type webhookRecorder struct {
+	srv      *httptest.Server
+	received chan webhook
+}
+
+type webhook struct {
+	body      []byte
+	signature string
+}
+
+func newWebhookRecorder() *webhookRecorder {
+	rec := &webhookRecorder{received: make(chan webhook, 10)}
+	rec.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.received <- webhook{body: body, signature: r.Header.Get("X-Hub-Signature-256")}
+	}))
+	return rec
+}
+
+func (rec *webhookRecorder) next(t *testing.T) *whatsapp.WebhookPayload {
+	t.Helper()
+	select {
+	case wh := <-rec.received:
+		if !whatsapp.VerifySignature(wh.body, wh.signature, "app-secret") {
+			t.Fatalf("webhook signature %q does not verify", wh.signature)
+		}
+		payload, err := whatsapp.ParseWebhook(wh.body)
+		if err != nil {
+			t.Fatalf("ParseWebhook: %v", err)
+		}
+		return payload
+	case <-time.After(2 * time.Second):
+		t.Fatal("no webhook received")
+		return nil
+	}
+}
+
+func TestSendTextMessage(t *testing.T) {
+	srv := NewServer(Config{AccessToken: "token"})
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	ctx := logger.ContextWithRequestID(context.Background(), "req-1")
+	resp, err := client.SendTextMessage(ctx, "+14155550100", "hello", whatsapp.SendOptions{})
+	if err != nil {
+		t.Fatalf("SendTextMessage: %v", err)
+	}
+	if len(resp.Messages) != 1 || resp.Contacts[0].WaID != "14155550100" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	msg, ok := srv.Message(resp.Messages[0].ID)
+	if !ok {
+		t.Fatal("message was not recorded")
+	}
+	if msg.To != "+14155550100" || msg.Type != "text" || msg.RequestID != "req-1" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestSendReplyAndReaction(t *testing.T) {
+	srv := NewServer(Config{})
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	ctx := context.Background()
+	resp, err := client.SendTextMessage(ctx, "14155550100", "It ships tomorrow", whatsapp.SendOptions{ReplyTo: "wamid.quoted"})
+	if err != nil {
+		t.Fatalf("SendTextMessage: %v", err)
+	}
+	msg, _ := srv.Message(resp.Messages[0].ID)
+	if quoted, _ := msg.Payload["context"].(map[string]interface{}); quoted["message_id"] != "wamid.quoted" {
+		t.Errorf("reply should carry the context, got %+v", msg.Payload)
+	}
+
+	resp, err = client.SendReaction(ctx, "14155550100", "wamid.quoted", "👍")
+	if err != nil {
+		t.Fatalf("SendReaction: %v", err)
+	}
+	msg, _ = srv.Message(resp.Messages[0].ID)
+	reaction, _ := msg.Payload["reaction"].(map[string]interface{})
+	if msg.Type != "reaction" || reaction["message_id"] != "wamid.quoted" || reaction["emoji"] != "👍" {
+		t.Errorf("unexpected reaction %+v", msg.Payload)
+	}
+	if _, ok := msg.Payload["context"]; ok {
+		t.Error("reactions must not carry a context")
+	}
+
+	// Options of one send don't carry over to the next
+	resp, err = client.SendTextMessage(ctx, "14155550100", "Anything else?", whatsapp.SendOptions{})
+	if err != nil {
+		t.Fatalf("SendTextMessage: %v", err)
+	}
+	msg, _ = srv.Message(resp.Messages[0].ID)
+	if _, ok := msg.Payload["context"]; ok {
+		t.Error("a send without ReplyTo must not quote")
+	}
+
+	if _, err := client.SendReaction(context.Background(), "14155550100", "", "👍"); err == nil {
+		t.Error("reaction without a message ID should be rejected")
+	}
+}
+
+func TestSendLocationContactsAndSticker(t *testing.T) {
+	srv := NewServer(Config{})
+	defer srv.Close()
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	resp, err := client.SendLocationMessage(ctx, "14155550100", whatsapp.Location{Latitude: 37.7749, Longitude: -122.4194, Name: "HQ"}, whatsapp.SendOptions{})
+	if err != nil {
+		t.Fatalf("SendLocationMessage: %v", err)
+	}
+	msg, _ := srv.Message(resp.Messages[0].ID)
+	location, _ := msg.Payload["location"].(map[string]interface{})
+	if msg.Type != "location" || location["latitude"] != 37.7749 || location["name"] != "HQ" {
+		t.Errorf("unexpected location %+v", msg.Payload)
+	}
+
+	resp, err = client.SendLocationRequest(ctx, "14155550100", "Where should we deliver?", whatsapp.SendOptions{})
+	if err != nil {
+		t.Fatalf("SendLocationRequest: %v", err)
+	}
+	msg, _ = srv.Message(resp.Messages[0].ID)
+	interactive, _ := msg.Payload["interactive"].(map[string]interface{})
+	if msg.Type != "interactive" || interactive["type"] != "location_request_message" {
+		t.Errorf("unexpected location request %+v", msg.Payload)
+	}
+
+	resp, err = client.SendContactsMessage(ctx, "14155550100", []whatsapp.ContactCard{{
+		Name:   whatsapp.ContactName{FormattedName: "Ada Lovelace"},
+		Phones: []whatsapp.ContactPhone{{Phone: "+14155550123", WaID: "14155550123"}},
+	}}, whatsapp.SendOptions{})
+	if err != nil {
+		t.Fatalf("SendContactsMessage: %v", err)
+	}
+	msg, _ = srv.Message(resp.Messages[0].ID)
+	if contacts, _ := msg.Payload["contacts"].([]interface{}); msg.Type != "contacts" || len(contacts) != 1 {
+		t.Errorf("unexpected contacts %+v", msg.Payload)
+	}
+
+	resp, err = client.SendMediaMessage(ctx, "14155550100", "https://example.com/s.webp", "", whatsapp.MediaTypeSticker, whatsapp.SendOptions{})
+	if err != nil {
+		t.Fatalf("SendMediaMessage sticker: %v", err)
+	}
+	msg, _ = srv.Message(resp.Messages[0].ID)
+	if sticker, _ := msg.Payload["sticker"].(map[string]interface{}); msg.Type != "sticker" || sticker["link"] != "https://example.com/s.webp" {
+		t.Errorf("unexpected sticker %+v", msg.Payload)
+	}
+
+	if _, err := client.SendLocationMessage(ctx, "14155550100", whatsapp.Location{Latitude: 120}, whatsapp.SendOptions{}); err == nil {
+		t.Error("out of range location should be rejected")
+	}
+	if _, err := client.SendContactsMessage(ctx, "14155550100", []whatsapp.ContactCard{{}}, whatsapp.SendOptions{}); err == nil {
+		t.Error("contact without a formatted name should be rejected")
+	}
+}
+
+func TestMarkAsRead(t *testing.T) {
+	srv := NewServer(Config{})
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	if err := client.MarkAsRead(context.Background(), "wamid.in1", false); err != nil {
+		t.Fatalf("MarkAsRead: %v", err)
+	}
+	if err := client.MarkAsRead(context.Background(), "wamid.in2", true); err != nil {
+		t.Fatalf("MarkAsRead with typing: %v", err)
+	}
+	reads := srv.Reads()
+	if len(reads) != 2 || reads[0].MessageID != "wamid.in1" || reads[0].Typing || !reads[1].Typing {
+		t.Errorf("unexpected read receipts %+v", reads)
+	}
+	if len(srv.Messages()) != 0 {
+		t.Error("read receipts are not messages")
+	}
+
+	if err := client.MarkAsRead(context.Background(), "", false); err == nil {
+		t.Error("marking without a message ID should be rejected")
+	}
+}
+
+func TestBlockUsers(t *testing.T) {
+	srv := NewServer(Config{})
+	defer srv.Close()
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	if err := client.BlockUsers(ctx, []string{"+14155550100", "447700900123"}); err != nil {
+		t.Fatalf("BlockUsers: %v", err)
+	}
+	if blocked := srv.Blocked(); len(blocked) != 2 || blocked[0] != "14155550100" || blocked[1] != "447700900123" {
+		t.Errorf("unexpected block list %v", blocked)
+	}
+	if err := client.UnblockUsers(ctx, []string{"14155550100"}); err != nil {
+		t.Fatalf("UnblockUsers: %v", err)
+	}
+	if blocked := srv.Blocked(); len(blocked) != 1 || blocked[0] != "447700900123" {
+		t.Errorf("unexpected block list after unblocking %v", blocked)
+	}
+
+	err := client.BlockUsers(ctx, []string{"not-a-phone"})
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrWhatsAppAPI || appErr.Details["user"] != "not-a-phone" {
+		t.Errorf("failed users should be reported, got %v", err)
+	}
+}
+
+func TestSendRejectsInvalidToken(t *testing.T) {
+	srv := NewServer(Config{AccessToken: "token"})
+	defer srv.Close()
+
+	cfg := srv.ClientConfig()
+	cfg.APIToken = "wrong"
+	cfg.Logger = zap.NewNop()
+	client, _ := whatsapp.NewClient(cfg)
+
+	_, err := client.SendTextMessage(context.Background(), "14155550100", "hello", whatsapp.SendOptions{})
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != errors.ErrWhatsAppAuth || appErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected auth error, got %v", err)
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	srv := NewServer(Config{})
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	srv.FailNext("14155550100", ErrReengagement)
+	srv.FailNext("", ErrServiceUnavailable)
+
+	// The recipient-specific failure is skipped for other recipients
+	_, err := client.SendTextMessage(context.Background(), "14155550199", "hello", whatsapp.SendOptions{})
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != errors.ErrWhatsAppTransient || !appErr.Retryable {
+		t.Fatalf("expected transient error, got %v", err)
+	}
+
+	_, err = client.SendTextMessage(context.Background(), "14155550100", "hello", whatsapp.SendOptions{})
+	apiErr, ok := whatsapp.AsAPIError(err)
+	if !ok || apiErr.Code != 131047 || apiErr.Category != whatsapp.ErrorCategoryReengagement || apiErr.FBTraceID == "" {
+		t.Fatalf("expected re-engagement error, got %v", err)
+	}
+
+	if _, err := client.SendTextMessage(context.Background(), "14155550100", "hello", whatsapp.SendOptions{}); err != nil {
+		t.Fatalf("failures should be used once, got %v", err)
+	}
+	if n := len(srv.Messages()); n != 1 {
+		t.Errorf("expected 1 accepted message, got %d", n)
+	}
+}
+
+func TestTemplateSends(t *testing.T) {
+	srv := NewServer(Config{StrictTemplates: true})
+	defer srv.Close()
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	if _, err := client.SendTemplateMessage(ctx, "14155550100", "welcome", "en_US", nil, whatsapp.SendOptions{}); err == nil {
+		t.Fatal("expected unknown template to be rejected")
+	}
+
+	srv.AddTemplate("welcome", "en_US", "UTILITY")
+	if _, err := client.SendTemplateMessage(ctx, "14155550100", "welcome", "en_US", []string{"Ada"}, whatsapp.SendOptions{}); err != nil {
+		t.Fatalf("SendTemplateMessage: %v", err)
+	}
+
+	srv.SetTemplateStatus("welcome", TemplatePaused)
+	_, err := client.SendTemplateMessage(ctx, "14155550100", "welcome", "en_US", nil, whatsapp.SendOptions{})
+	apiErr, ok := whatsapp.AsAPIError(err)
+	if !ok || apiErr.Category != whatsapp.ErrorCategoryTemplatePaused {
+		t.Fatalf("expected template paused error, got %v", err)
+	}
+}
+
+func TestMediaUpload(t *testing.T) {
+	srv := NewServer(Config{})
+	defer srv.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("messaging_product", "whatsapp")
+	form.WriteField("type", "image/png")
+	part, _ := form.CreateFormFile("file", "pixel.png")
+	part.Write([]byte("png-bytes"))
+	form.Close()
+
+	resp, err := http.Post(srv.URL+"/v18.0/"+DefaultPhoneNumberID+"/media", form.FormDataContentType(), &body)
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	var uploaded struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&uploaded)
+	resp.Body.Close()
+	if uploaded.ID == "" {
+		t.Fatal("upload returned no media ID")
+	}
+
+	resp, err = http.Get(srv.URL + "/v18.0/" + uploaded.ID)
+	if err != nil {
+		t.Fatalf("get media: %v", err)
+	}
+	var info struct {
+		URL      string `json:"url"`
+		MimeType string `json:"mime_type"`
+	}
+	json.NewDecoder(resp.Body).Decode(&info)
+	resp.Body.Close()
+	if info.MimeType != "image/png" {
+		t.Errorf("expected image/png, got %q", info.MimeType)
+	}
+
+	resp, err = http.Get(info.URL)
+	if err != nil {
+		t.Fatalf("download: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(data) != "png-bytes" {
+		t.Errorf("downloaded %q", data)
+	}
+
+	// Media sends by link are accepted as well
+	client := newClient(t, srv)
+	if _, err := client.SendMediaMessage(context.Background(), "14155550100", "https://example.com/a.png", "", whatsapp.MediaTypeImage, whatsapp.SendOptions{}); err != nil {
+		t.Fatalf("SendMediaMessage: %v", err)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	rec := newWebhookRecorder()
+	defer rec.srv.Close()
+
+	srv := NewServer(Config{WebhookURL: rec.srv.URL, AppSecret: "app-secret"})
+	defer srv.Close()
+	client := newClient(t, srv)
+	ctx := context.Background()
+
+	resp, err := client.SendTextMessage(ctx, "14155550100", "hello", whatsapp.SendOptions{})
+	if err != nil {
+		t.Fatalf("SendTextMessage: %v", err)
+	}
+	id := resp.Messages[0].ID
+
+	if err := srv.SendFailedStatus(ctx, id, 131026, "Message undeliverable"); err != nil {
+		t.Fatalf("SendFailedStatus: %v", err)
+	}
+	statuses, err := whatsapp.ParseStatusEvent(rec.next(t))
+	if err != nil || len(statuses) != 1 {
+		t.Fatalf("ParseStatusEvent: %v %v", statuses, err)
+	}
+	status := statuses[0]
+	if status.MessageID != id || status.Status != "failed" || status.ErrorCode != 131026 ||
+		status.BusinessAccountID != DefaultBusinessAccountID || status.PhoneNumberID != DefaultPhoneNumberID {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	inboundID, err := srv.SendInbound(ctx, InboundMessage{From: "14155550100", Name: "Ada", Text: "hi there"})
+	if err != nil {
+		t.Fatalf("SendInbound: %v", err)
+	}
+	messages, err := whatsapp.ParseMessageEvent(rec.next(t))
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("ParseMessageEvent: %v %v", messages, err)
+	}
+	inbound := messages[0]
+	if inbound.MessageID != inboundID || inbound.Content != "hi there" || inbound.ContactName != "Ada" {
+		t.Errorf("unexpected inbound message %+v", inbound)
+	}
+
+	if _, err := srv.SendInbound(ctx, InboundMessage{From: "14155550100", Text: "thanks", ReplyTo: id}); err != nil {
+		t.Fatalf("SendInbound reply: %v", err)
+	}
+	messages, _ = whatsapp.ParseMessageEvent(rec.next(t))
+	if len(messages) != 1 || messages[0].ReplyTo != id || messages[0].Content != "thanks" {
+		t.Errorf("unexpected reply %+v", messages)
+	}
+
+	if _, err := srv.SendInbound(ctx, InboundMessage{From: "14155550100", ReactTo: id, Emoji: "👍"}); err != nil {
+		t.Fatalf("SendInbound reaction: %v", err)
+	}
+	messages, _ = whatsapp.ParseMessageEvent(rec.next(t))
+	if len(messages) != 1 || messages[0].Type != "reaction" || messages[0].ReactionTo != id || messages[0].Emoji != "👍" {
+		t.Errorf("unexpected reaction %+v", messages)
+	}
+}
+
+func TestAutoStatus(t *testing.T) {
+	rec := newWebhookRecorder()
+	defer rec.srv.Close()
+
+	srv := NewServer(Config{
+		WebhookURL:  rec.srv.URL,
+		AppSecret:   "app-secret",
+		AutoStatus:  true,
+		StatusDelay: time.Millisecond,
+	})
+	defer srv.Close()
+	client := newClient(t, srv)
+
+	resp, err := client.SendTextMessage(context.Background(), "14155550100", "hello", whatsapp.SendOptions{})
+	if err != nil {
+		t.Fatalf("SendTextMessage: %v", err)
+	}
+
+	for _, want := range []string{"sent", "delivered", "read"} {
+		statuses, _ := whatsapp.ParseStatusEvent(rec.next(t))
+		if len(statuses) != 1 || statuses[0].Status != want || statuses[0].MessageID != resp.Messages[0].ID {
+			t.Fatalf("expected %s status, got %+v", want, statuses)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package fake
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+)
+
+// maxUploadSize is the largest media file the simulator accepts (Meta's
+// limit for documents)
+const maxUploadSize = 100 << 20
+
+// Media is a file uploaded to the simulator
+type Media struct {
+	ID       string
+	MimeType string
+	SHA256   string
+	Data     []byte
+}
+
+// info returns the media object served by GET /{media-id}
+func (m *Media) info(r *http.Request) map[string]interface{} {
+	return map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"id":                m.ID,
+		"url":               "http://" + r.Host + "/" + controlPrefix + "/media/" + m.ID,
+		"mime_type":         m.MimeType,
+		"sha256":            m.SHA256,
+		"file_size":         len(m.Data),
+	}
+}
+
+// AddMedia stores a file as if it was uploaded and returns its media ID,
+// e.g. to reference from a simulated inbound message
+func (s *Simulator) AddMedia(mimeType string, data []byte) string {
+	sum := sha256.Sum256(data)
+	media := &Media{
+		ID:       s.nextID(),
+		MimeType: mimeType,
+		SHA256:   hex.EncodeToString(sum[:]),
+		Data:     data,
+	}
+
+	s.mu.Lock()
+	s.media[media.ID] = media
+	s.mu.Unlock()
+
+	return media.ID
+}
+
+func (s *Simulator) getMedia(id string) (*Media, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	media, ok := s.media[id]
+	return media, ok
+}
+
+func (s *Simulator) hasMedia(id string) bool {
+	_, ok := s.getMedia(id)
+	return ok
+}
+
+// handleUploadMedia handles POST /{phone-number-id}/media (multipart)
+func (s *Simulator) handleUploadMedia(w http.ResponseWriter, r *http.Request, phoneNumberID string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeError(w, invalidParameter("Invalid multipart payload"))
+		return
+	}
+	if r.FormValue("messaging_product") != "whatsapp" {
+		writeError(w, invalidParameter("The parameter messaging_product is required."))
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, invalidParameter("The parameter file is required."))
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, Error{HTTPStatus: http.StatusBadRequest, Code: 131053, Message: "Media upload error"})
+		return
+	}
+
+	mimeType := r.FormValue("type")
+	if mimeType == "" {
+		mimeType = header.Header.Get("Content-Type")
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"id": s.AddMedia(mimeType, data)})
+}
+
+// handleDeleteMedia handles DELETE /{media-id}
+func (s *Simulator) handleDeleteMedia(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	_, ok := s.media[id]
+	delete(s.media, id)
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, invalidParameter("Media "+id+" does not exist"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// serveMediaDownload serves the bytes of uploaded media
+func (s *Simulator) serveMediaDownload(w http.ResponseWriter, r *http.Request, id string) {
+	media, ok := s.getMedia(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", media.MimeType)
+	w.Write(media.Data)
+}
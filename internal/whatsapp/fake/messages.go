@@ -0,0 +1,274 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Message types the Cloud API accepts on /messages
+var messageTypes = map[string]bool{
+	"text":        true,
+	"image":       true,
+	"audio":       true,
+	"video":       true,
+	"document":    true,
+	"sticker":     true,
+	"location":    true,
+	"contacts":    true,
+	"template":    true,
+	"interactive": true,
+	"reaction":    true,
+}
+
+var recipientPattern = regexp.MustCompile(`^\+?\d{7,15}$`)
+
+// handleSendMessage handles POST /{phone-number-id}/messages
+func (s *Simulator) handleSendMessage(w http.ResponseWriter, r *http.Request, phoneNumberID string) {
+	if s.config.PhoneNumberID != "" && phoneNumberID != s.config.PhoneNumberID {
+		writeError(w, Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       100,
+			Subcode:    33,
+			Type:       "GraphMethodException",
+			Message:    "Unsupported post request. Object with ID '" + phoneNumberID + "' does not exist",
+		})
+		return
+	}
+
+	var payload map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeError(w, invalidParameter("Invalid JSON payload"))
+		return
+	}
+
+	if product, _ := payload["messaging_product"].(string); product != "whatsapp" {
+		writeError(w, invalidParameter("The parameter messaging_product is required."))
+		return
+	}
+
+	// Marking an inbound message as read shares the endpoint
+	if status, _ := payload["status"].(string); status != "" {
+		s.handleMarkRead(w, payload)
+		return
+	}
+
+	to, _ := payload["to"].(string)
+	if !recipientPattern.MatchString(to) {
+		writeError(w, invalidParameter("Invalid parameter: to"))
+		return
+	}
+
+	msgType, _ := payload["type"].(string)
+	if msgType == "" {
+		msgType = "text"
+	}
+	if !messageTypes[msgType] {
+		writeError(w, Error{HTTPStatus: http.StatusBadRequest, Code: 131051, Message: "Unsupported message type"})
+		return
+	}
+	if e, ok := s.validateContent(msgType, payload); !ok {
+		writeError(w, e)
+		return
+	}
+
+	if e, ok := s.takeFailure(to); ok {
+		writeError(w, e)
+		return
+	}
+
+	msg := &Message{
+		ID:            newMessageID(),
+		PhoneNumberID: phoneNumberID,
+		To:            to,
+		Type:          msgType,
+		Status:        "accepted",
+		Payload:       payload,
+		RequestID:     r.Header.Get("X-Request-ID"),
+		Timestamp:     time.Now().UTC(),
+	}
+	s.store(msg)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"contacts": []map[string]string{
+			{"input": to, "wa_id": strings.TrimPrefix(to, "+")},
+		},
+		"messages": []map[string]string{
+			{"id": msg.ID},
+		},
+	})
+}
+
+// validateContent checks the object named by the message type
+func (s *Simulator) validateContent(msgType string, payload map[string]interface{}) (Error, bool) {
+	content, ok := payload[msgType]
+	if !ok {
+		return invalidParameter("The parameter " + msgType + " is required."), false
+	}
+
+	switch msgType {
+	case "text":
+		text, _ := content.(map[string]interface{})
+		if body, _ := text["body"].(string); body == "" {
+			return invalidParameter("Param text['body'] is required"), false
+		}
+	case "image", "audio", "video", "document", "sticker":
+		media, _ := content.(map[string]interface{})
+		link, _ := media["link"].(string)
+		id, _ := media["id"].(string)
+		if link == "" && id == "" {
+			return invalidParameter("Param " + msgType + " must have either link or id"), false
+		}
+		if id != "" && !s.hasMedia(id) {
+			return Error{HTTPStatus: http.StatusBadRequest, Code: 131053, Message: "Media upload error"}, false
+		}
+	case "template":
+		template, _ := content.(map[string]interface{})
+		return s.validateTemplateSend(template)
+	case "location":
+		location, _ := content.(map[string]interface{})
+		latitude, latOK := location["latitude"].(float64)
+		longitude, longOK := location["longitude"].(float64)
+		if !latOK || !longOK || latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+			return invalidParameter("Param location must have a valid latitude and longitude"), false
+		}
+	case "contacts":
+		contacts, _ := content.([]interface{})
+		if len(contacts) == 0 {
+			return invalidParameter("Param contacts must be a non-empty array"), false
+		}
+		for _, c := range contacts {
+			contact, _ := c.(map[string]interface{})
+			name, _ := contact["name"].(map[string]interface{})
+			if formatted, _ := name["formatted_name"].(string); formatted == "" {
+				return invalidParameter("Param contacts['name']['formatted_name'] is required"), false
+			}
+		}
+	case "interactive":
+		interactive, _ := content.(map[string]interface{})
+		body, _ := interactive["body"].(map[string]interface{})
+		if text, _ := body["text"].(string); text == "" {
+			return invalidParameter("Param interactive['body']['text'] is required"), false
+		}
+		if kind, _ := interactive["type"].(string); kind == "location_request_message" {
+			action, _ := interactive["action"].(map[string]interface{})
+			if name, _ := action["name"].(string); name != "send_location" {
+				return invalidParameter("Param interactive['action']['name'] must be send_location"), false
+			}
+		}
+	case "reaction":
+		// An empty emoji removes the reaction
+		reaction, _ := content.(map[string]interface{})
+		if id, _ := reaction["message_id"].(string); id == "" {
+			return invalidParameter("Param reaction['message_id'] is required"), false
+		}
+	}
+
+	if quoted, ok := payload["context"]; ok {
+		context, _ := quoted.(map[string]interface{})
+		if id, _ := context["message_id"].(string); id == "" {
+			return invalidParameter("Param context['message_id'] is required"), false
+		}
+	}
+	return Error{}, true
+}
+
+// takeFailure pops the first queued failure matching the recipient
+func (s *Simulator) takeFailure(to string) (Error, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, f := range s.failures {
+		if f.recipient == "" || f.recipient == to {
+			s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			return f.err, true
+		}
+	}
+	return Error{}, false
+}
+
+// store records an accepted message and schedules its automatic statuses
+func (s *Simulator) store(msg *Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages[msg.ID] = msg
+	s.order = append(s.order, msg.ID)
+
+	if !s.config.AutoStatus || s.config.WebhookURL == "" {
+		return
+	}
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	s.wg.Add(1)
+	go func(id string) {
+		defer s.wg.Done()
+		ctx := context.Background()
+		for _, status := range []string{"sent", "delivered", "read"} {
+			if !s.sleep(ctx, s.config.StatusDelay) {
+				return
+			}
+			if err := s.SendStatus(ctx, id, status); err != nil {
+				return
+			}
+		}
+	}(msg.ID)
+}
+
+// handleMarkRead handles {"status": "read", "message_id": ...} sends, with
+// an optional {"typing_indicator": {"type": "text"}}
+func (s *Simulator) handleMarkRead(w http.ResponseWriter, payload map[string]interface{}) {
+	status, _ := payload["status"].(string)
+	messageID, _ := payload["message_id"].(string)
+	if status != "read" || messageID == "" {
+		writeError(w, invalidParameter("Invalid parameter: status"))
+		return
+	}
+	_, typing := payload["typing_indicator"]
+	if typing {
+		indicator, _ := payload["typing_indicator"].(map[string]interface{})
+		if kind, _ := indicator["type"].(string); kind != "text" {
+			writeError(w, invalidParameter("Invalid parameter: typing_indicator"))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	s.reads = append(s.reads, ReadReceipt{MessageID: messageID, Typing: typing, Timestamp: time.Now().UTC()})
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+}
+
+// handleGetObject handles GET /{id} for media and messages
+func (s *Simulator) handleGetObject(w http.ResponseWriter, r *http.Request, id string) {
+	if media, ok := s.getMedia(id); ok {
+		writeJSON(w, http.StatusOK, media.info(r))
+		return
+	}
+
+	if msg, ok := s.Message(id); ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":        msg.ID,
+			"status":    msg.Status,
+			"timestamp": msg.Timestamp,
+		})
+		return
+	}
+
+	writeError(w, Error{
+		HTTPStatus: http.StatusBadRequest,
+		Code:       100,
+		Subcode:    33,
+		Type:       "GraphMethodException",
+		Message:    "Unsupported get request. Object with ID '" + id + "' does not exist",
+	})
+}
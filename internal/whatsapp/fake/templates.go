@@ -0,0 +1,154 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Template statuses used by Meta
+const (
+	TemplateApproved = "APPROVED"
+	TemplatePending  = "PENDING"
+	TemplateRejected = "REJECTED"
+	TemplatePaused   = "PAUSED"
+	TemplateDisabled = "DISABLED"
+)
+
+// Template is a message template created on the simulator
+type Template struct {
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	Language   string        `json:"language"`
+	Category   string        `json:"category"`
+	Status     string        `json:"status"`
+	Components []interface{} `json:"components,omitempty"`
+}
+
+// AddTemplate registers an approved template and returns it
+func (s *Simulator) AddTemplate(name, language, category string) Template {
+	t := &Template{
+		ID:       s.nextID(),
+		Name:     name,
+		Language: language,
+		Category: category,
+		Status:   TemplateApproved,
+	}
+
+	s.mu.Lock()
+	s.templates[name] = t
+	s.mu.Unlock()
+
+	return *t
+}
+
+// SetTemplateStatus changes the status of a template, e.g. to PAUSED to
+// make sends fail with error 132015. It returns false for unknown templates.
+func (s *Simulator) SetTemplateStatus(name, status string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.templates[name]
+	if ok {
+		t.Status = status
+	}
+	return ok
+}
+
+// handleTemplates handles /{waba-id}/message_templates
+func (s *Simulator) handleTemplates(w http.ResponseWriter, r *http.Request, wabaID string) {
+	if wabaID != s.config.BusinessAccountID {
+		writeError(w, Error{
+			HTTPStatus: http.StatusBadRequest,
+			Code:       100,
+			Subcode:    33,
+			Type:       "GraphMethodException",
+			Message:    "Unsupported request. Object with ID '" + wabaID + "' does not exist",
+		})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		templates := make([]Template, 0, len(s.templates))
+		for _, t := range s.templates {
+			if name := r.URL.Query().Get("name"); name != "" && t.Name != name {
+				continue
+			}
+			templates = append(templates, *t)
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": templates, "paging": map[string]interface{}{}})
+
+	case http.MethodPost:
+		var req Template
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, invalidParameter("Invalid JSON payload"))
+			return
+		}
+		if req.Name == "" || req.Language == "" || req.Category == "" {
+			writeError(w, invalidParameter("name, language and category are required"))
+			return
+		}
+		t := s.AddTemplate(req.Name, req.Language, req.Category)
+		if len(req.Components) > 0 {
+			s.mu.Lock()
+			s.templates[t.Name].Components = req.Components
+			s.mu.Unlock()
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"id": t.ID, "status": t.Status, "category": t.Category})
+
+	case http.MethodDelete:
+		name := r.URL.Query().Get("name")
+		s.mu.Lock()
+		_, ok := s.templates[name]
+		delete(s.templates, name)
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, Error{HTTPStatus: http.StatusBadRequest, Code: 100, Subcode: 2388094, Message: "Message template not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"success": true})
+
+	default:
+		writeError(w, invalidParameter("Unsupported method"))
+	}
+}
+
+// validateTemplateSend checks a template send against the registered
+// templates
+func (s *Simulator) validateTemplateSend(template map[string]interface{}) (Error, bool) {
+	name, _ := template["name"].(string)
+	if name == "" {
+		return invalidParameter("Param template['name'] is required"), false
+	}
+	language, _ := template["language"].(map[string]interface{})
+	code, _ := language["code"].(string)
+	if code == "" {
+		return invalidParameter("Param template['language']['code'] is required"), false
+	}
+
+	s.mu.Lock()
+	t, ok := s.templates[name]
+	var registered Template
+	if ok {
+		registered = *t
+	}
+	s.mu.Unlock()
+
+	switch {
+	case !ok && s.config.StrictTemplates:
+		return Error{HTTPStatus: http.StatusNotFound, Code: 132001, Message: "Template name does not exist in the translation"}, false
+	case !ok:
+		return Error{}, true
+	case registered.Language != code:
+		return Error{HTTPStatus: http.StatusNotFound, Code: 132001, Message: "Template name does not exist in the translation"}, false
+	case registered.Status == TemplatePaused:
+		return ErrTemplatePaused, false
+	case registered.Status == TemplateDisabled:
+		return Error{HTTPStatus: http.StatusBadRequest, Code: 132016, Message: "Template is disabled"}, false
+	case registered.Status != TemplateApproved:
+		return Error{HTTPStatus: http.StatusBadRequest, Code: 132001, Message: "Template is not approved"}, false
+	}
+	return Error{}, true
+}
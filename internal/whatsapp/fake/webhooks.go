@@ -0,0 +1,180 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/utils"
+)
+
+// InboundMessage is a message a customer sends to the business
+type InboundMessage struct {
+	From string
+	Name string
+	// Text is the body of a text message
+	Text string
+	// MediaType (image, audio, video, document) and MediaID make a media
+	// message; add the file with AddMedia first
+	MediaType string
+	MediaID   string
+	Caption   string
+	// ReplyTo quotes an earlier message by its WhatsApp message ID
+	ReplyTo string
+	// ReactTo and Emoji make a reaction to a message; an empty Emoji
+	// removes the customer's reaction
+	ReactTo string
+	Emoji   string
+}
+
+// SendStatus emits a status webhook (sent, delivered, read) for an accepted
+// message
+func (s *Simulator) SendStatus(ctx context.Context, messageID, status string) error {
+	return s.sendStatus(ctx, messageID, status, nil)
+}
+
+// SendFailedStatus emits a failed status webhook carrying a Graph error code,
+// as Meta does when delivery fails after the send was accepted
+func (s *Simulator) SendFailedStatus(ctx context.Context, messageID string, code int, title string) error {
+	return s.sendStatus(ctx, messageID, "failed", []map[string]interface{}{
+		{"code": code, "title": title, "message": title},
+	})
+}
+
+func (s *Simulator) sendStatus(ctx context.Context, messageID, status string, errs []map[string]interface{}) error {
+	s.mu.Lock()
+	msg, ok := s.messages[messageID]
+	if ok {
+		msg.Status = status
+	}
+	var to, phoneNumberID string
+	if ok {
+		to, phoneNumberID = msg.To, msg.PhoneNumberID
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unknown message %s", messageID)
+	}
+
+	value := map[string]interface{}{
+		"id":           messageID,
+		"status":       status,
+		"timestamp":    strconv.FormatInt(time.Now().Unix(), 10),
+		"recipient_id": to,
+	}
+	if len(errs) > 0 {
+		value["errors"] = errs
+	}
+
+	return s.postWebhook(ctx, phoneNumberID, map[string]interface{}{
+		"statuses": []interface{}{value},
+	})
+}
+
+// SendInbound emits a webhook for a message from a customer and returns its
+// WhatsApp message ID
+func (s *Simulator) SendInbound(ctx context.Context, msg InboundMessage) (string, error) {
+	id := newMessageID()
+
+	value := map[string]interface{}{
+		"from":      msg.From,
+		"id":        id,
+		"timestamp": strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	if msg.ReplyTo != "" {
+		value["context"] = map[string]string{"from": msg.From, "id": msg.ReplyTo}
+	}
+	if msg.ReactTo != "" {
+		value["type"] = "reaction"
+		value["reaction"] = map[string]string{"message_id": msg.ReactTo, "emoji": msg.Emoji}
+	} else if msg.MediaType != "" {
+		media, ok := s.getMedia(msg.MediaID)
+		if !ok {
+			return "", fmt.Errorf("unknown media %s", msg.MediaID)
+		}
+		value["type"] = msg.MediaType
+		obj := map[string]interface{}{
+			"id":        media.ID,
+			"mime_type": media.MimeType,
+			"sha256":    media.SHA256,
+		}
+		if msg.Caption != "" {
+			obj["caption"] = msg.Caption
+		}
+		value[msg.MediaType] = obj
+	} else {
+		value["type"] = "text"
+		value["text"] = map[string]string{"body": msg.Text}
+	}
+
+	phoneNumberID := s.config.PhoneNumberID
+	if phoneNumberID == "" {
+		phoneNumberID = DefaultPhoneNumberID
+	}
+
+	err := s.postWebhook(ctx, phoneNumberID, map[string]interface{}{
+		"contacts": []interface{}{
+			map[string]interface{}{
+				"profile": map[string]string{"name": msg.Name},
+				"wa_id":   msg.From,
+			},
+		},
+		"messages": []interface{}{value},
+	})
+	return id, err
+}
+
+// postWebhook wraps a change value in a webhook payload, signs it with the
+// app secret and posts it to the webhook URL
+func (s *Simulator) postWebhook(ctx context.Context, phoneNumberID string, value map[string]interface{}) error {
+	if s.config.WebhookURL == "" {
+		return nil
+	}
+
+	value["messaging_product"] = "whatsapp"
+	value["metadata"] = map[string]string{
+		"display_phone_number": s.config.DisplayPhoneNumber,
+		"phone_number_id":      phoneNumberID,
+	}
+	payload := map[string]interface{}{
+		"object": "whatsapp_business_account",
+		"entry": []interface{}{
+			map[string]interface{}{
+				"id": s.config.BusinessAccountID,
+				"changes": []interface{}{
+					map[string]interface{}{"value": value, "field": "messages"},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.AppSecret != "" {
+		req.Header.Set("X-Hub-Signature-256", utils.ComputeHMAC(body, []byte(s.config.AppSecret)))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package whatsapp
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// Graph API error codes that mean the sender is going too fast
+const (
+	ErrorCodeAppRateLimit      = 4
+	ErrorCodeWABARateLimit     = 80007
+	ErrorCodeThroughputReached = 130429
+	ErrorCodeSpamRateLimit     = 131048
+	ErrorCodePairRateLimit     = 131056
+)
+
+const (
+	defaultMessagesPerSecond = 80
+	minMessagesPerSecond     = 1
+	recipientWindow          = 24 * time.Hour
+	// Meta allows roughly one message every 6 seconds to the same user
+	pairRateLimitCooldown = 6 * time.Second
+	maxThrottleBackoff    = time.Minute
+	// Throughput climbs back to the configured rate in this many successful sends
+	recoveryStepsToFullThroughput = 20
+)
+
+// Messaging limit tiers: the number of unique recipients a phone number may
+// start conversations with in a rolling 24 hours
+const (
+	TierUnverified = 250
+	Tier1K         = 1000
+	Tier10K        = 10000
+	Tier100K       = 100000
+	TierUnlimited  = 0
+)
+
+// IsThrottlingErrorCode returns true for Graph error codes that signal the
+// sender should slow down
+func IsThrottlingErrorCode(code int) bool {
+	switch code {
+	case ErrorCodeAppRateLimit, ErrorCodeWABARateLimit, ErrorCodeThroughputReached,
+		ErrorCodeSpamRateLimit, ErrorCodePairRateLimit:
+		return true
+	}
+	return false
+}
+
+// GovernorConfig configures the outbound throughput governor
+type GovernorConfig struct {
+	// MessagesPerSecond is the per-number throughput; Meta's default is 80
+	MessagesPerSecond float64
+	// Tier is the unique-recipient limit per rolling 24 hours; 0 is unlimited
+	Tier int
+	// MaxWait is how long a send may wait for capacity before it is rejected
+	MaxWait time.Duration
+}
+
+// Governor keeps outbound sends for one phone number within Meta's
+// messaging limits. It combines a token bucket for throughput with a rolling
+// count of unique recipients, and slows down when Graph reports throttling.
+// State is kept per process.
+type Governor struct {
+	baseRate float64
+	maxWait  time.Duration
+	limiter  *rate.Limiter
+
+	tier       int
+	recipients map[string]*recipientState
+	order      []recipientEntry // recipients in order of first send
+
+	pausedUntil   time.Time
+	backoff       time.Duration
+	pairCooldowns map[string]time.Time
+	mu            sync.Mutex
+	now           func() time.Time
+}
+
+type recipientEntry struct {
+	recipient string
+	firstSend time.Time
+}
+
+// recipientState is a recipient counted against the tier. A recipient only
+// stays counted once a send to it went through; if every reserved send
+// fails or is cancelled it is forgotten again.
+type recipientState struct {
+	firstSend time.Time
+	pending   int  // reserved sends not reported as succeeded or released
+	confirmed bool // a send went through within the window
+}
+
+// GovernorStats is a snapshot of a governor's state
+type GovernorStats struct {
+	MessagesPerSecond float64   `json:"messages_per_second"`
+	Tier              int       `json:"tier"`
+	UniqueRecipients  int       `json:"unique_recipients"`
+	PausedUntil       time.Time `json:"paused_until,omitempty"`
+}
+
+// NewGovernor creates a governor. Zero config values get Meta's defaults.
+func NewGovernor(config GovernorConfig) *Governor {
+	perSecond := config.MessagesPerSecond
+	if perSecond <= 0 {
+		perSecond = defaultMessagesPerSecond
+	}
+
+	return &Governor{
+		baseRate:      perSecond,
+		maxWait:       config.MaxWait,
+		limiter:       rate.NewLimiter(rate.Limit(perSecond), int(perSecond)),
+		tier:          config.Tier,
+		recipients:    make(map[string]*recipientState),
+		pairCooldowns: make(map[string]time.Time),
+		now:           time.Now,
+	}
+}
+
+// Acquire waits until a message to recipient may be sent. It fails with a
+// send_throttled error instead of waiting longer than the governor's max
+// wait or the context deadline, so callers get back-pressure rather than
+// an unbounded queue. Once acquired, the send must be reported with
+// Succeeded or Release.
+func (g *Governor) Acquire(ctx context.Context, recipient string) error {
+	reservation, delay, err := g.reserve(recipient)
+	if err != nil {
+		return err
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		reservation.CancelAt(g.now())
+		g.release(recipient)
+		g.mu.Unlock()
+		return errors.NewSendThrottledError("Send cancelled while waiting for capacity", delay)
+	}
+}
+
+// reserve claims capacity for one message to recipient. The recipient is
+// only counted against the tier once the capacity is granted.
+func (g *Governor) reserve(recipient string) (*rate.Reservation, time.Duration, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+
+	var delay time.Duration
+	if g.pausedUntil.After(now) {
+		delay = g.pausedUntil.Sub(now)
+	}
+	if until, ok := g.pairCooldowns[recipient]; ok {
+		if until.After(now) && until.Sub(now) > delay {
+			delay = until.Sub(now)
+		} else if !until.After(now) {
+			delete(g.pairCooldowns, recipient)
+		}
+	}
+	if g.maxWait > 0 && delay > g.maxWait {
+		return nil, 0, errors.NewSendThrottledError("Phone number is throttled by WhatsApp", delay)
+	}
+
+	if err := g.checkRecipient(recipient, now); err != nil {
+		return nil, 0, err
+	}
+
+	reservation := g.limiter.ReserveN(now.Add(delay), 1)
+	if !reservation.OK() {
+		return nil, 0, errors.NewSendThrottledError("Throughput limit reached", time.Second)
+	}
+	delay += reservation.DelayFrom(now.Add(delay))
+	if g.maxWait > 0 && delay > g.maxWait {
+		reservation.CancelAt(now)
+		return nil, 0, errors.NewSendThrottledError("Throughput limit reached", delay)
+	}
+
+	g.admitRecipient(recipient, now)
+	return reservation, delay, nil
+}
+
+// checkRecipient returns an error if recipient would exceed the tier.
+// Recipients already counted within the window are free.
+func (g *Governor) checkRecipient(recipient string, now time.Time) error {
+	// Forget recipients whose first send left the rolling window
+	cutoff := now.Add(-recipientWindow)
+	for len(g.order) > 0 && !g.order[0].firstSend.After(cutoff) {
+		entry := g.order[0]
+		g.order = g.order[1:]
+		if state, ok := g.recipients[entry.recipient]; ok && state.firstSend.Equal(entry.firstSend) {
+			delete(g.recipients, entry.recipient)
+		}
+	}
+
+	if _, ok := g.recipients[recipient]; ok {
+		return nil
+	}
+	if g.tier > 0 && len(g.recipients) >= g.tier {
+		retryAfter := recipientWindow
+		for _, entry := range g.order {
+			if state, ok := g.recipients[entry.recipient]; ok && state.firstSend.Equal(entry.firstSend) {
+				retryAfter = entry.firstSend.Add(recipientWindow).Sub(now)
+				break
+			}
+		}
+		return errors.NewSendThrottledError("Messaging limit tier reached", retryAfter).
+			WithDetail("tier", g.tier)
+	}
+	return nil
+}
+
+// admitRecipient counts a reserved send to recipient
+func (g *Governor) admitRecipient(recipient string, now time.Time) {
+	if state, ok := g.recipients[recipient]; ok {
+		state.pending++
+		return
+	}
+	g.recipients[recipient] = &recipientState{firstSend: now, pending: 1}
+	g.order = append(g.order, recipientEntry{recipient: recipient, firstSend: now})
+}
+
+// Release tells the governor a send to recipient it granted capacity for
+// was not made or failed. A recipient no send went through to within the
+// window stops counting against the tier.
+func (g *Governor) Release(recipient string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.release(recipient)
+}
+
+func (g *Governor) release(recipient string) {
+	state, ok := g.recipients[recipient]
+	if !ok {
+		return
+	}
+	if state.pending > 0 {
+		state.pending--
+	}
+	if state.pending == 0 && !state.confirmed {
+		delete(g.recipients, recipient)
+	}
+}
+
+// Throttled tells the governor Graph rejected a send with a throttling error
+// code. Pair rate limits only pause the recipient; anything else halves the
+// throughput and pauses the number with exponential backoff.
+func (g *Governor) Throttled(code int, recipient string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+
+	if code == ErrorCodePairRateLimit {
+		g.pairCooldowns[recipient] = now.Add(pairRateLimitCooldown)
+		return
+	}
+
+	current := float64(g.limiter.Limit())
+	reduced := current / 2
+	if reduced < minMessagesPerSecond {
+		reduced = minMessagesPerSecond
+	}
+	g.limiter.SetLimitAt(now, rate.Limit(reduced))
+
+	if g.backoff == 0 {
+		g.backoff = time.Second
+	} else if g.backoff < maxThrottleBackoff {
+		g.backoff *= 2
+		if g.backoff > maxThrottleBackoff {
+			g.backoff = maxThrottleBackoff
+		}
+	}
+	g.pausedUntil = now.Add(g.backoff)
+}
+
+// Succeeded tells the governor a send to recipient went through, so the
+// recipient counts against the tier for the rest of the window. Throughput
+// recovers additively towards the configured rate.
+func (g *Governor) Succeeded(recipient string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if state, ok := g.recipients[recipient]; ok {
+		state.confirmed = true
+		if state.pending > 0 {
+			state.pending--
+		}
+	}
+	g.backoff = 0
+
+	current := float64(g.limiter.Limit())
+	if current >= g.baseRate {
+		return
+	}
+	increased := current + g.baseRate/recoveryStepsToFullThroughput
+	if increased > g.baseRate {
+		increased = g.baseRate
+	}
+	g.limiter.SetLimitAt(g.now(), rate.Limit(increased))
+}
+
+// SetTier changes the unique-recipient limit, e.g. after Meta upgraded the
+// phone number
+func (g *Governor) SetTier(tier int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tier = tier
+}
+
+// Stats returns a snapshot of the governor's state
+func (g *Governor) Stats() GovernorStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stats := GovernorStats{
+		MessagesPerSecond: float64(g.limiter.Limit()),
+		Tier:              g.tier,
+		UniqueRecipients:  len(g.recipients),
+	}
+	if g.pausedUntil.After(g.now()) {
+		stats.PausedUntil = g.pausedUntil
+	}
+	return stats
+}
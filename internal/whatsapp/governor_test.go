@@ -0,0 +1,164 @@
+package whatsapp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+)
+
+// fakeClock returns a governor clock that only moves when advanced
+func fakeClock(g *Governor) func(time.Duration) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestGovernorThroughput(t *testing.T) {
+	g := NewGovernor(GovernorConfig{MessagesPerSecond: 2, MaxWait: time.Second})
+	fakeClock(g)
+
+	// The bucket starts full
+	for i := 0; i < 2; i++ {
+		if _, delay, err := g.reserve("15550000001"); err != nil || delay != 0 {
+			t.Fatalf("send %d: delay=%v err=%v", i, delay, err)
+		}
+	}
+
+	// The next sends have to wait for tokens
+	_, delay, err := g.reserve("15550000001")
+	if err != nil || delay != 500*time.Millisecond {
+		t.Fatalf("expected 500ms delay, got delay=%v err=%v", delay, err)
+	}
+	g.reserve("15550000001")
+
+	// Waiting longer than MaxWait is rejected
+	_, _, err = g.reserve("15550000001")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrSendThrottled {
+		t.Fatalf("expected send_throttled, got %v", err)
+	}
+}
+
+func TestGovernorTier(t *testing.T) {
+	g := NewGovernor(GovernorConfig{Tier: 2})
+	advance := fakeClock(g)
+
+	g.reserve("a")
+	advance(time.Hour)
+	g.reserve("b")
+
+	// Known recipients do not count again
+	if _, _, err := g.reserve("a"); err != nil {
+		t.Fatalf("expected known recipient to pass, got %v", err)
+	}
+
+	_, _, err := g.reserve("c")
+	appErr, ok := err.(*errors.AppError)
+	if !ok || appErr.Code != errors.ErrSendThrottled {
+		t.Fatalf("expected tier limit error, got %v", err)
+	}
+	if retryAfter := appErr.Details["retry_after"]; retryAfter != int((23 * time.Hour).Seconds()) {
+		t.Fatalf("retry_after = %v, want 23h", retryAfter)
+	}
+
+	// Once "a" leaves the rolling window there is room again
+	advance(23 * time.Hour)
+	if _, _, err := g.reserve("c"); err != nil {
+		t.Fatalf("expected room after window, got %v", err)
+	}
+}
+
+func TestGovernorCountsOnlyGrantedRecipients(t *testing.T) {
+	g := NewGovernor(GovernorConfig{MessagesPerSecond: 1, Tier: 2, MaxWait: 500 * time.Millisecond})
+	advance := fakeClock(g)
+
+	g.reserve("a")
+	// No capacity within the max wait: "b" must not take a tier slot
+	if _, _, err := g.reserve("b"); err == nil {
+		t.Fatal("expected the throughput limit to reject b")
+	}
+	if stats := g.Stats(); stats.UniqueRecipients != 1 {
+		t.Fatalf("rejected recipients should not count, got %d", stats.UniqueRecipients)
+	}
+	advance(time.Second)
+	if _, _, err := g.reserve("c"); err != nil {
+		t.Fatalf("expected room for c, got %v", err)
+	}
+}
+
+func TestGovernorAcquireCancelled(t *testing.T) {
+	g := NewGovernor(GovernorConfig{MessagesPerSecond: 1, Tier: 2, MaxWait: 10 * time.Second})
+	fakeClock(g)
+
+	g.reserve("a")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := g.Acquire(ctx, "b")
+	if appErr, ok := err.(*errors.AppError); !ok || appErr.Code != errors.ErrSendThrottled {
+		t.Fatalf("expected send_throttled, got %v", err)
+	}
+	if stats := g.Stats(); stats.UniqueRecipients != 1 {
+		t.Fatalf("cancelled recipients should not count, got %d", stats.UniqueRecipients)
+	}
+
+	// The cancelled reservation gave its token back
+	if _, delay, err := g.reserve("c"); err != nil || delay != time.Second {
+		t.Fatalf("expected c to wait one token, got delay=%v err=%v", delay, err)
+	}
+}
+
+func TestGovernorReleaseFailedSends(t *testing.T) {
+	g := NewGovernor(GovernorConfig{Tier: 1})
+	fakeClock(g)
+
+	g.reserve("a")
+	g.Release("a")
+	if stats := g.Stats(); stats.UniqueRecipients != 0 {
+		t.Fatalf("recipients of failed sends should not count, got %d", stats.UniqueRecipients)
+	}
+
+	g.reserve("b")
+	g.Succeeded("b")
+	g.reserve("b")
+	g.Release("b")
+	if _, _, err := g.reserve("c"); err == nil {
+		t.Fatal("b was sent to and should keep its tier slot")
+	}
+}
+
+func TestGovernorAdaptsToThrottling(t *testing.T) {
+	g := NewGovernor(GovernorConfig{MessagesPerSecond: 80})
+	advance := fakeClock(g)
+
+	g.Throttled(ErrorCodeThroughputReached, "a")
+	stats := g.Stats()
+	if stats.MessagesPerSecond != 40 {
+		t.Fatalf("expected throughput to halve, got %v", stats.MessagesPerSecond)
+	}
+	if _, delay, _ := g.reserve("a"); delay < time.Second {
+		t.Fatalf("expected sends to pause after throttling, got delay %v", delay)
+	}
+
+	advance(2 * time.Second)
+	for i := 0; i < recoveryStepsToFullThroughput; i++ {
+		g.Succeeded("a")
+	}
+	if stats := g.Stats(); stats.MessagesPerSecond != 80 {
+		t.Fatalf("expected throughput to recover, got %v", stats.MessagesPerSecond)
+	}
+}
+
+func TestGovernorPairRateLimit(t *testing.T) {
+	g := NewGovernor(GovernorConfig{})
+	fakeClock(g)
+
+	g.Throttled(ErrorCodePairRateLimit, "a")
+
+	if _, delay, _ := g.reserve("a"); delay != pairRateLimitCooldown {
+		t.Fatalf("expected recipient cooldown, got %v", delay)
+	}
+	if _, delay, _ := g.reserve("b"); delay != 0 {
+		t.Fatalf("expected other recipients unaffected, got %v", delay)
+	}
+}
@@ -10,8 +10,81 @@ const (
 	MediaTypeDocument MediaType = "document"
 	MediaTypeAudio    MediaType = "audio"
 	MediaTypeVideo    MediaType = "video"
+	MediaTypeSticker  MediaType = "sticker"
 )
 
+// MaxInteractiveBodyLength is the longest body text of an interactive
+// message
+const MaxInteractiveBodyLength = 1024
+
+// Location is a map pin sent as a location message
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+}
+
+// ContactCard is a contact shared in a contacts message. WhatsApp renders it
+// as a vCard.
+type ContactCard struct {
+	Name      ContactName      `json:"name"`
+	Phones    []ContactPhone   `json:"phones,omitempty"`
+	Emails    []ContactEmail   `json:"emails,omitempty"`
+	URLs      []ContactURL     `json:"urls,omitempty"`
+	Addresses []ContactAddress `json:"addresses,omitempty"`
+	Org       *ContactOrg      `json:"org,omitempty"`
+	Birthday  string           `json:"birthday,omitempty"` // YYYY-MM-DD
+}
+
+// ContactName is the name of a contact card; FormattedName is required
+type ContactName struct {
+	FormattedName string `json:"formatted_name"`
+	FirstName     string `json:"first_name,omitempty"`
+	LastName      string `json:"last_name,omitempty"`
+	MiddleName    string `json:"middle_name,omitempty"`
+	Prefix        string `json:"prefix,omitempty"`
+	Suffix        string `json:"suffix,omitempty"`
+}
+
+// ContactPhone is a phone number of a contact card. Setting WaID adds a
+// Message button to the card.
+type ContactPhone struct {
+	Phone string `json:"phone"`
+	Type  string `json:"type,omitempty"` // CELL, MAIN, IPHONE, HOME or WORK
+	WaID  string `json:"wa_id,omitempty"`
+}
+
+// ContactEmail is an email address of a contact card
+type ContactEmail struct {
+	Email string `json:"email"`
+	Type  string `json:"type,omitempty"` // HOME or WORK
+}
+
+// ContactURL is a website of a contact card
+type ContactURL struct {
+	URL  string `json:"url"`
+	Type string `json:"type,omitempty"` // HOME or WORK
+}
+
+// ContactAddress is a postal address of a contact card
+type ContactAddress struct {
+	Street      string `json:"street,omitempty"`
+	City        string `json:"city,omitempty"`
+	State       string `json:"state,omitempty"`
+	Zip         string `json:"zip,omitempty"`
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+	Type        string `json:"type,omitempty"` // HOME or WORK
+}
+
+// ContactOrg is the employer of a contact card
+type ContactOrg struct {
+	Company    string `json:"company,omitempty"`
+	Department string `json:"department,omitempty"`
+	Title      string `json:"title,omitempty"`
+}
+
 // MessageRequest represents a request to send a message
 type MessageRequest struct {
 	Phone            string            `json:"phone"`
@@ -37,6 +110,27 @@ type MessageResponse struct {
 	} `json:"messages"`
 }
 
+// BlockUsersResponse is the response to a block list change. Users are
+// listed as added or removed, or as failed with their errors.
+type BlockUsersResponse struct {
+	MessagingProduct string `json:"messaging_product"`
+	BlockUsers       struct {
+		AddedUsers   []BlockedUser `json:"added_users,omitempty"`
+		RemovedUsers []BlockedUser `json:"removed_users,omitempty"`
+		FailedUsers  []BlockedUser `json:"failed_users,omitempty"`
+	} `json:"block_users"`
+}
+
+// BlockedUser is a user of a block list change
+type BlockedUser struct {
+	Input  string `json:"input"`
+	WaID   string `json:"wa_id,omitempty"`
+	Errors []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors,omitempty"`
+}
+
 // MessageStatus represents the status of a message
 type MessageStatus struct {
 	ID        string    `json:"id"`
@@ -82,9 +176,20 @@ type MessageValue struct {
 	ID        string `json:"id"`
 	Timestamp string `json:"timestamp"`
 	Type      string `json:"type"`
-	Text      *struct {
+	// Context is set on replies and identifies the quoted message
+	Context *struct {
+		From string `json:"from"`
+		ID   string `json:"id"`
+	} `json:"context,omitempty"`
+	Text *struct {
 		Body string `json:"body"`
 	} `json:"text,omitempty"`
+	// Reaction is set on reaction messages; an empty emoji removes the
+	// sender's reaction
+	Reaction *struct {
+		MessageID string `json:"message_id"`
+		Emoji     string `json:"emoji"`
+	} `json:"reaction,omitempty"`
 	Image *struct {
 		Caption  string `json:"caption,omitempty"`
 		MimeType string `json:"mime_type"`
@@ -127,6 +232,12 @@ type StatusValue struct {
 
 // MessageEvent represents a parsed incoming message event
 type MessageEvent struct {
+	// BusinessAccountID is the WABA ID of the webhook entry, used to route
+	// the event to its organization
+	BusinessAccountID  string
+	PhoneNumberID      string
+	DisplayPhoneNumber string
+
 	MessageID   string
 	From        string
 	Timestamp   time.Time
@@ -138,10 +249,20 @@ type MessageEvent struct {
 	Caption     string
 	Filename    string
 	ContactName string
+
+	// ReplyTo is the WhatsApp message ID of the message this one quotes
+	ReplyTo string
+
+	// ReactionTo and Emoji are set on reactions
+	ReactionTo string
+	Emoji      string
 }
 
 // StatusEvent represents a parsed status update event
 type StatusEvent struct {
+	BusinessAccountID string
+	PhoneNumberID     string
+
 	MessageID   string
 	Status      string
 	Timestamp   time.Time
@@ -157,7 +278,10 @@ type ErrorResponse struct {
 		Message      string `json:"message"`
 		Type         string `json:"type"`
 		Code         int    `json:"code"`
-		ErrorData    string `json:"error_data,omitempty"`
+		ErrorData    struct {
+			MessagingProduct string `json:"messaging_product,omitempty"`
+			Details          string `json:"details,omitempty"`
+		} `json:"error_data,omitempty"`
 		ErrorSubcode int    `json:"error_subcode,omitempty"`
 		FBTraceID    string `json:"fbtrace_id,omitempty"`
 	} `json:"error"`
@@ -0,0 +1,82 @@
+package whatsapp
+
+import (
+	"sync"
+)
+
+// ClientPool caches one Client per WhatsApp phone number so tenants with
+// their own credentials can share the same process. Each phone number keeps
+// one throughput governor for the life of the pool, even when its client is
+// recreated.
+type ClientPool struct {
+	base      Config
+	clients   map[string]*pooledClient
+	governors map[string]*Governor
+	mu        sync.Mutex
+}
+
+type pooledClient struct {
+	client   *Client
+	apiToken string
+}
+
+// NewClientPool creates a client pool. The base config supplies the API
+// base URL, version and logger for every client created by the pool.
+func NewClientPool(base Config) *ClientPool {
+	return &ClientPool{
+		base:      base,
+		clients:   make(map[string]*pooledClient),
+		governors: make(map[string]*Governor),
+	}
+}
+
+// Get returns the client for a phone number, creating it on first use or
+// when the access token has changed
+func (p *ClientPool) Get(apiToken, phoneNumberID string) (*Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pc, ok := p.clients[phoneNumberID]; ok && pc.apiToken == apiToken {
+		return pc.client, nil
+	}
+
+	cfg := p.base
+	cfg.APIToken = apiToken
+	cfg.PhoneNumberID = phoneNumberID
+	cfg.Governor = p.governor(phoneNumberID)
+
+	client, err := NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p.clients[phoneNumberID] = &pooledClient{client: client, apiToken: apiToken}
+	return client, nil
+}
+
+// Evict drops the cached client for a phone number
+func (p *ClientPool) Evict(phoneNumberID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.clients, phoneNumberID)
+}
+
+// Governor returns the throughput governor of a phone number
+func (p *ClientPool) Governor(phoneNumberID string) *Governor {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.governor(phoneNumberID)
+}
+
+// governor returns the governor of a phone number, creating it on first use.
+// The caller must hold p.mu.
+func (p *ClientPool) governor(phoneNumberID string) *Governor {
+	g, ok := p.governors[phoneNumberID]
+	if !ok {
+		g = NewGovernor(p.base.Throughput)
+		p.governors[phoneNumberID] = g
+	}
+	return g
+}
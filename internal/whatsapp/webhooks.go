@@ -34,6 +34,9 @@ func ParseMessageEvent(payload *WebhookPayload) ([]*MessageEvent, error) {
 				if err != nil {
 					return nil, err
 				}
+				event.BusinessAccountID = entry.ID
+				event.PhoneNumberID = change.Value.Metadata.PhoneNumberID
+				event.DisplayPhoneNumber = change.Value.Metadata.DisplayPhoneNumber
 				events = append(events, event)
 			}
 		}
@@ -64,6 +67,10 @@ func parseMessageValue(msg *MessageValue, contacts []ContactValue) (*MessageEven
 		}
 	}
 
+	if msg.Context != nil {
+		event.ReplyTo = msg.Context.ID
+	}
+
 	// Extract content based on message type
 	switch msg.Type {
 	case "text":
@@ -71,6 +78,12 @@ func parseMessageValue(msg *MessageValue, contacts []ContactValue) (*MessageEven
 			event.Content = msg.Text.Body
 		}
 
+	case "reaction":
+		if msg.Reaction != nil {
+			event.ReactionTo = msg.Reaction.MessageID
+			event.Emoji = msg.Reaction.Emoji
+		}
+
 	case "image":
 		if msg.Image != nil {
 			event.MediaID = msg.Image.ID
@@ -114,6 +127,8 @@ func ParseStatusEvent(payload *WebhookPayload) ([]*StatusEvent, error) {
 				if err != nil {
 					return nil, err
 				}
+				event.BusinessAccountID = entry.ID
+				event.PhoneNumberID = change.Value.Metadata.PhoneNumberID
 				events = append(events, event)
 			}
 		}
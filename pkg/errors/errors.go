@@ -2,7 +2,9 @@ package errors
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 )
 
 // Error codes
@@ -17,12 +19,25 @@ const (
 	ErrInvalidMessageType = "invalid_message_type"
 	ErrWhatsAppAPI        = "whatsapp_api_error"
 	ErrRateLimitExceeded  = "rate_limit_exceeded"
+	ErrQuotaExceeded      = "quota_exceeded"
+	ErrSendThrottled      = "send_throttled"
 	ErrValidationFailed   = "validation_failed"
 	ErrDatabaseError      = "database_error"
 	ErrMediaUploadFailed  = "media_upload_failed"
 	ErrTemplateNotFound   = "template_not_found"
 	ErrAPIKeyExpired      = "api_key_expired"
 	ErrAPIKeyInvalid      = "api_key_invalid"
+	ErrAPIKeyRevoked      = "api_key_revoked"
+	ErrContactBlocked     = "contact_blocked"
+
+	// WhatsApp API errors by category
+	ErrWhatsAppAuth                 = "whatsapp_auth_error"
+	ErrWhatsAppRateLimited          = "whatsapp_rate_limited"
+	ErrWhatsAppRecipientUnavailable = "whatsapp_recipient_unavailable"
+	ErrWhatsAppReengagementRequired = "whatsapp_reengagement_required"
+	ErrWhatsAppTemplatePaused       = "whatsapp_template_paused"
+	ErrWhatsAppInvalidParameter     = "whatsapp_invalid_parameter"
+	ErrWhatsAppTransient            = "whatsapp_transient_error"
 )
 
 // AppError represents an application error with additional context
@@ -30,6 +45,7 @@ type AppError struct {
 	Code       string                 `json:"code"`
 	Message    string                 `json:"message"`
 	Details    map[string]interface{} `json:"details,omitempty"`
+	Retryable  bool                   `json:"retryable,omitempty"`
 	StatusCode int                    `json:"-"`
 	Err        error                  `json:"-"`
 }
@@ -153,6 +169,22 @@ func NewRateLimitError() *AppError {
 	return NewAppError(ErrRateLimitExceeded, "Rate limit exceeded", http.StatusTooManyRequests)
 }
 
+// NewQuotaExceededError creates a send quota exceeded error for the given
+// period, e.g. "daily"
+func NewQuotaExceededError(period string) *AppError {
+	return NewAppError(ErrQuotaExceeded, "Send quota exceeded", http.StatusTooManyRequests).
+		WithDetail("period", period)
+}
+
+// NewSendThrottledError creates an error for sends held back to stay within
+// WhatsApp messaging limits. retryAfter is when capacity is expected back.
+func NewSendThrottledError(message string, retryAfter time.Duration) *AppError {
+	appErr := NewAppError(ErrSendThrottled, message, http.StatusTooManyRequests).
+		WithDetail("retry_after", int(math.Ceil(retryAfter.Seconds())))
+	appErr.Retryable = true
+	return appErr
+}
+
 // NewInvalidPhoneNumberError creates an invalid phone number error
 func NewInvalidPhoneNumberError(phone string) *AppError {
 	return NewAppError(
@@ -162,6 +194,15 @@ func NewInvalidPhoneNumberError(phone string) *AppError {
 	).WithDetail("phone", phone)
 }
 
+// NewContactBlockedError creates an error for sends to a blocked contact
+func NewContactBlockedError(phone string) *AppError {
+	return NewAppError(
+		ErrContactBlocked,
+		"The contact is blocked and cannot be messaged",
+		http.StatusForbidden,
+	).WithDetail("phone", phone)
+}
+
 // NewDatabaseError creates a database error
 func NewDatabaseError(err error) *AppError {
 	return NewAppError(
@@ -187,6 +228,14 @@ func IsValidationError(err error) bool {
 	return false
 }
 
+// IsRetryable checks if the failed operation may succeed when tried again
+func IsRetryable(err error) bool {
+	if appErr, ok := err.(*AppError); ok {
+		return appErr.Retryable
+	}
+	return false
+}
+
 // IsUnauthorized checks if the error is an unauthorized error
 func IsUnauthorized(err error) bool {
 	if appErr, ok := err.(*AppError); ok {
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"os"
 	"strings"
 
@@ -76,6 +77,28 @@ func WithRequestID(requestID string) *zap.Logger {
 	return GetLogger().With(zap.String("request_id", requestID))
 }
 
+// requestIDKey is the context key of the request ID
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext returns a child of logger tagged with the request ID carried by ctx
+func FromContext(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return logger.With(zap.String("request_id", requestID))
+	}
+	return logger
+}
+
 // With creates a child logger with additional zap fields
 func With(fields ...zap.Field) *zap.Logger {
 	return GetLogger().With(fields...)
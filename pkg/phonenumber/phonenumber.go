@@ -0,0 +1,253 @@
+// Package phonenumber parses phone numbers into E.164 form. Numbers written
+// in national form are read against a small built-in table of countries;
+// nothing is looked up over the network.
+package phonenumber
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Number is a parsed phone number
+type Number struct {
+	// CountryCode is the calling code without the +, such as "44". It is
+	// empty for calling codes missing from the table, whose digits are all
+	// in NationalNumber.
+	CountryCode string
+	// NationalNumber is the number within the country, without the trunk
+	// prefix dialed before it at home
+	NationalNumber string
+}
+
+// E164 returns the number in E.164 form, such as +447700900123
+func (n *Number) E164() string {
+	return "+" + n.CountryCode + n.NationalNumber
+}
+
+// WhatsAppID returns the number the way WhatsApp reports senders: the E.164
+// form without the +
+func (n *Number) WhatsAppID() string {
+	return n.CountryCode + n.NationalNumber
+}
+
+// ErrInvalidNumber is wrapped by the errors Parse returns for numbers it
+// cannot read
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+const (
+	// E.164 numbers have at most 15 digits, including the calling code
+	maxDigits = 15
+	// minDigits is the shortest number accepted for a calling code missing
+	// from the table
+	minDigits = 8
+)
+
+// Parse reads raw as a phone number. Numbers in international form start
+// with +, 00 or, in North America, 011; anything else is read as a national
+// number of region, an ISO 3166 country code such as "GB". Without a region
+// every number is read as international, with or without its +, the way
+// WhatsApp sends them. Spaces, dashes, dots, slashes and brackets are
+// ignored.
+func Parse(raw, region string) (*Number, error) {
+	digits, plus, err := clean(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var home *country
+	if region != "" {
+		if home = regions[strings.ToUpper(region)]; home == nil {
+			return nil, fmt.Errorf("unknown region %q", region)
+		}
+	}
+
+	switch {
+	case plus:
+		return parseInternational(digits)
+	case strings.HasPrefix(digits, "00"):
+		return parseInternational(digits[2:])
+	case home != nil && home.callingCode == "1" && strings.HasPrefix(digits, "011"):
+		return parseInternational(digits[3:])
+	case home == nil:
+		return parseInternational(digits)
+	}
+
+	if national, ok := home.national(digits); ok {
+		return &Number{CountryCode: home.callingCode, NationalNumber: national}, nil
+	}
+	// Numbers already carrying the region's calling code, without the +
+	if rest, found := strings.CutPrefix(digits, home.callingCode); found {
+		if national, ok := home.national(rest); ok {
+			return &Number{CountryCode: home.callingCode, NationalNumber: national}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q is not a valid number for %s", ErrInvalidNumber, raw, strings.ToUpper(region))
+}
+
+// Normalize returns raw in E.164 form, reading national numbers as numbers
+// of region
+func Normalize(raw, region string) (string, error) {
+	number, err := Parse(raw, region)
+	if err != nil {
+		return "", err
+	}
+	return number.E164(), nil
+}
+
+// SupportedRegion reports whether Parse can read national numbers of region
+func SupportedRegion(region string) bool {
+	return regions[strings.ToUpper(region)] != nil
+}
+
+// clean returns the digits of raw and whether it starts with a +
+func clean(raw string) (string, bool, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false, fmt.Errorf("%w: the number is empty", ErrInvalidNumber)
+	}
+	plus := strings.HasPrefix(raw, "+")
+
+	var digits strings.Builder
+	for i, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+		case strings.ContainsRune(" -.()/", r):
+		default:
+			return "", false, fmt.Errorf("%w: %q contains %q", ErrInvalidNumber, raw, r)
+		}
+	}
+	if digits.Len() == 0 {
+		return "", false, fmt.Errorf("%w: %q has no digits", ErrInvalidNumber, raw)
+	}
+	return digits.String(), plus, nil
+}
+
+// parseInternational reads digits that start with a calling code
+func parseInternational(digits string) (*Number, error) {
+	if len(digits) > maxDigits {
+		return nil, fmt.Errorf("%w: +%s is longer than %d digits", ErrInvalidNumber, digits, maxDigits)
+	}
+	if strings.HasPrefix(digits, "0") {
+		return nil, fmt.Errorf("%w: +%s does not start with a calling code", ErrInvalidNumber, digits)
+	}
+
+	// Calling codes are prefix-free, so at most one of these matches
+	for size := 1; size <= 3 && size < len(digits); size++ {
+		home := callingCodes[digits[:size]]
+		if home == nil {
+			continue
+		}
+		national, ok := home.national(digits[size:])
+		if !ok {
+			return nil, fmt.Errorf("%w: +%s is not a valid number for +%s", ErrInvalidNumber, digits, home.callingCode)
+		}
+		return &Number{CountryCode: home.callingCode, NationalNumber: national}, nil
+	}
+
+	// Calling codes missing from the table are accepted on length alone,
+	// so messages from anywhere still reach a contact
+	if len(digits) < minDigits {
+		return nil, fmt.Errorf("%w: +%s is shorter than %d digits", ErrInvalidNumber, digits, minDigits)
+	}
+	return &Number{NationalNumber: digits}, nil
+}
+
+// country is what Parse knows about the numbers of a country
+type country struct {
+	callingCode string
+	// trunkPrefix is dialed before national numbers at home, such as the 0
+	// in 020 7946 0000
+	trunkPrefix string
+	// minLength and maxLength bound the national number, without the trunk
+	// prefix
+	minLength, maxLength int
+}
+
+// national returns digits without the trunk prefix and whether the result
+// is a valid length. The trunk prefix is optional, as people also write it
+// after the calling code, as in +44 (0)20.
+func (c *country) national(digits string) (string, bool) {
+	if c.trunkPrefix != "" {
+		if rest, found := strings.CutPrefix(digits, c.trunkPrefix); found && c.validLength(rest) {
+			return rest, true
+		}
+	}
+	return digits, c.validLength(digits)
+}
+
+func (c *country) validLength(digits string) bool {
+	return len(digits) >= c.minLength && len(digits) <= c.maxLength
+}
+
+// regions maps ISO 3166 country codes to their numbering plan. Countries
+// sharing a calling code share one plan.
+var regions = map[string]*country{}
+
+// callingCodes maps calling codes to their numbering plan
+var callingCodes = map[string]*country{}
+
+func init() {
+	for _, entry := range []struct {
+		regions []string
+		country
+	}{
+		{[]string{"US", "CA", "PR"}, country{"1", "1", 10, 10}},
+		{[]string{"RU", "KZ"}, country{"7", "8", 10, 10}},
+		{[]string{"EG"}, country{"20", "0", 8, 10}},
+		{[]string{"ZA"}, country{"27", "0", 9, 9}},
+		{[]string{"GR"}, country{"30", "", 10, 10}},
+		{[]string{"NL"}, country{"31", "0", 9, 9}},
+		{[]string{"BE"}, country{"32", "0", 8, 9}},
+		{[]string{"FR"}, country{"33", "0", 9, 9}},
+		{[]string{"ES"}, country{"34", "", 9, 9}},
+		{[]string{"HU"}, country{"36", "06", 8, 9}},
+		{[]string{"IT"}, country{"39", "", 6, 11}},
+		{[]string{"RO"}, country{"40", "0", 9, 9}},
+		{[]string{"CH"}, country{"41", "0", 9, 9}},
+		{[]string{"AT"}, country{"43", "0", 4, 13}},
+		{[]string{"GB"}, country{"44", "0", 9, 10}},
+		{[]string{"DK"}, country{"45", "", 8, 8}},
+		{[]string{"SE"}, country{"46", "0", 7, 9}},
+		{[]string{"NO"}, country{"47", "", 8, 8}},
+		{[]string{"PL"}, country{"48", "", 9, 9}},
+		{[]string{"DE"}, country{"49", "0", 6, 13}},
+		{[]string{"PE"}, country{"51", "0", 8, 9}},
+		{[]string{"MX"}, country{"52", "", 10, 10}},
+		{[]string{"AR"}, country{"54", "0", 10, 11}},
+		{[]string{"BR"}, country{"55", "0", 10, 11}},
+		{[]string{"CL"}, country{"56", "", 9, 9}},
+		{[]string{"CO"}, country{"57", "", 10, 10}},
+		{[]string{"MY"}, country{"60", "0", 8, 10}},
+		{[]string{"AU"}, country{"61", "0", 9, 9}},
+		{[]string{"ID"}, country{"62", "0", 8, 12}},
+		{[]string{"PH"}, country{"63", "0", 10, 10}},
+		{[]string{"NZ"}, country{"64", "0", 8, 10}},
+		{[]string{"SG"}, country{"65", "", 8, 8}},
+		{[]string{"TH"}, country{"66", "0", 8, 9}},
+		{[]string{"JP"}, country{"81", "0", 9, 10}},
+		{[]string{"KR"}, country{"82", "0", 8, 10}},
+		{[]string{"VN"}, country{"84", "0", 9, 10}},
+		{[]string{"CN"}, country{"86", "0", 10, 11}},
+		{[]string{"TR"}, country{"90", "0", 10, 10}},
+		{[]string{"IN"}, country{"91", "0", 10, 10}},
+		{[]string{"PK"}, country{"92", "0", 9, 10}},
+		{[]string{"NG"}, country{"234", "0", 8, 10}},
+		{[]string{"KE"}, country{"254", "0", 9, 9}},
+		{[]string{"PT"}, country{"351", "", 9, 9}},
+		{[]string{"IE"}, country{"353", "0", 7, 9}},
+		{[]string{"UA"}, country{"380", "0", 9, 9}},
+		{[]string{"CZ"}, country{"420", "", 9, 9}},
+		{[]string{"SA"}, country{"966", "0", 9, 9}},
+		{[]string{"AE"}, country{"971", "0", 8, 9}},
+		{[]string{"IL"}, country{"972", "0", 8, 9}},
+	} {
+		plan := entry.country
+		callingCodes[plan.callingCode] = &plan
+		for _, region := range entry.regions {
+			regions[region] = &plan
+		}
+	}
+}
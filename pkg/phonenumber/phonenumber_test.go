@@ -0,0 +1,90 @@
+package phonenumber
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw, region, want string
+	}{
+		// International forms
+		{"+14155550100", "", "+14155550100"},
+		{"14155550100", "", "+14155550100"},
+		{"+1 (415) 555-0100", "", "+14155550100"},
+		{"0044 7700 900123", "", "+447700900123"},
+		{"+44 (0)7700 900123", "", "+447700900123"},
+		{"+49 0151 23456789", "", "+4915123456789"},
+		{"011 44 7700 900123", "US", "+447700900123"},
+		{"+44 7700 900123", "DE", "+447700900123"},
+		// National forms
+		{"(415) 555-0100", "US", "+14155550100"},
+		{"1 415 555 0100", "us", "+14155550100"},
+		{"07700 900123", "GB", "+447700900123"},
+		{"7700 900123", "GB", "+447700900123"},
+		{"0151 23456789", "DE", "+4915123456789"},
+		{"06 12 34 56 78", "FR", "+33612345678"},
+		{"347 123 4567", "IT", "+393471234567"},
+		{"06 1234 5678", "IT", "+390612345678"},
+		{"8 912 345-67-89", "RU", "+79123456789"},
+		{"06 30 123 4567", "HU", "+36301234567"},
+		{"098765 43210", "IN", "+919876543210"},
+		// The region's calling code without the +
+		{"447700900123", "GB", "+447700900123"},
+		// Calling codes missing from the table
+		{"+3591234567", "", "+3591234567"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.raw, tt.region)
+		if err != nil {
+			t.Errorf("Normalize(%q, %q): %v", tt.raw, tt.region, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Normalize(%q, %q) = %s, want %s", tt.raw, tt.region, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejectsInvalidNumbers(t *testing.T) {
+	tests := []struct {
+		raw, region string
+	}{
+		{"", ""},
+		{"not-a-phone", ""},
+		{"+1 415 CALL NOW", ""},
+		{"+", ""},
+		{"+1415555010", ""},
+		{"+4477009001234567", ""},
+		{"+0441234567", ""},
+		{"+3591234", ""},
+		{"415 555 010", "US"},
+		{"0151 2345 6789 0123", "DE"},
+	}
+	for _, tt := range tests {
+		if number, err := Parse(tt.raw, tt.region); !errors.Is(err, ErrInvalidNumber) {
+			t.Errorf("Parse(%q, %q) = %+v, %v; want an invalid number", tt.raw, tt.region, number, err)
+		}
+	}
+
+	if _, err := Parse("07700 900123", "XX"); err == nil || errors.Is(err, ErrInvalidNumber) {
+		t.Errorf("unknown regions should be reported, got %v", err)
+	}
+	if !SupportedRegion("gb") || SupportedRegion("XX") {
+		t.Error("SupportedRegion should know the table's regions")
+	}
+}
+
+func TestNumberForms(t *testing.T) {
+	number, err := Parse("+44 7700 900123", "")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if number.CountryCode != "44" || number.NationalNumber != "7700900123" {
+		t.Errorf("unexpected number %+v", number)
+	}
+	if number.E164() != "+447700900123" || number.WhatsAppID() != "447700900123" {
+		t.Errorf("unexpected forms %s %s", number.E164(), number.WhatsAppID())
+	}
+}
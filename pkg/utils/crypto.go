@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -36,6 +37,24 @@ func CompareAPIKey(hashedKey, key string) bool {
 	return err == nil
 }
 
+// apiKeyDigestPrefix marks key hashes produced by DigestAPIKey
+const apiKeyDigestPrefix = "hmac-sha256:"
+
+// DigestAPIKey computes an HMAC-SHA256 digest of an API key keyed by a
+// server-side salt. Keys are 256-bit random values, so unlike passwords they
+// don't need a slow hash; the digest is cheap enough to compute on every
+// request and is looked up directly instead of compared against candidates.
+func DigestAPIKey(key, salt string) string {
+	h := hmac.New(sha256.New, []byte(salt))
+	h.Write([]byte(key))
+	return apiKeyDigestPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// IsBcryptHash returns true if a stored key hash was produced by HashAPIKey
+func IsBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2")
+}
+
 // GenerateID generates a prefixed ID (e.g., "msg_abc123")
 func GenerateID(prefix string) string {
 	id := uuid.New().String()
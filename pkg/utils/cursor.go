@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/ashoksahoo/whatsapp-business-platform/pkg/errors"
+)
+
+// Cursor is a position in a cursor-paginated listing: the sort value and ID
+// of the row at the edge of a page. Clients get cursors encoded and pass
+// them back unchanged.
+type Cursor struct {
+	// Key identifies the sort order the cursor was issued for
+	Key string `json:"k"`
+	// Value is the sort value of the row, nil when it is NULL
+	Value *string `json:"v"`
+	ID    string  `json:"id"`
+	// Backward cursors return the page before the row instead of after it
+	Backward bool `json:"b,omitempty"`
+}
+
+// Encode returns the opaque form of the cursor
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by Encode
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor()
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Key == "" || cursor.ID == "" {
+		return nil, ErrInvalidCursor()
+	}
+	return &cursor, nil
+}
+
+// ErrInvalidCursor is the error for cursors that are malformed or were
+// issued for a different listing or sort order
+func ErrInvalidCursor() *errors.AppError {
+	return errors.NewBadRequest("Invalid pagination cursor")
+}
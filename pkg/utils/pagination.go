@@ -4,18 +4,34 @@ import "gorm.io/gorm"
 
 // Pagination represents pagination parameters
 type Pagination struct {
-	Limit  int   `json:"limit"`
-	Offset int   `json:"offset"`
-	Total  int64 `json:"total"`
-	HasMore bool `json:"has_more"`
-}
-
-// PaginationResponse represents pagination in API responses
-type PaginationResponse struct {
 	Limit   int   `json:"limit"`
 	Offset  int   `json:"offset"`
 	Total   int64 `json:"total"`
 	HasMore bool  `json:"has_more"`
+
+	// Cursor continues a cursor-paginated listing from the page that
+	// returned it. Offset is ignored when it is set.
+	Cursor string `json:"-"`
+	// SkipTotal skips counting the matching rows, which listings with
+	// cursors do not need
+	SkipTotal bool `json:"-"`
+
+	// NextCursor and PrevCursor are set by listings that support cursors
+	NextCursor string `json:"-"`
+	PrevCursor string `json:"-"`
+
+	counted bool
+}
+
+// PaginationResponse represents pagination in API responses. Total is only
+// present when the rows were counted.
+type PaginationResponse struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      *int64 `json:"total,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 // NewPagination creates a new Pagination instance with defaults
@@ -44,16 +60,28 @@ func (p *Pagination) ApplyToQuery(db *gorm.DB) *gorm.DB {
 func (p *Pagination) SetTotal(total int64) {
 	p.Total = total
 	p.HasMore = int64(p.Offset+p.Limit) < total
+	p.counted = true
+}
+
+// Counted reports whether SetTotal was called
+func (p *Pagination) Counted() bool {
+	return p.counted
 }
 
 // ToResponse converts Pagination to PaginationResponse
 func (p *Pagination) ToResponse() PaginationResponse {
-	return PaginationResponse{
-		Limit:   p.Limit,
-		Offset:  p.Offset,
-		Total:   p.Total,
-		HasMore: p.HasMore,
+	response := PaginationResponse{
+		Limit:      p.Limit,
+		Offset:     p.Offset,
+		HasMore:    p.HasMore,
+		NextCursor: p.NextCursor,
+		PrevCursor: p.PrevCursor,
+	}
+	if p.counted {
+		total := p.Total
+		response.Total = &total
 	}
+	return response
 }
 
 // GetPage calculates the current page number (1-indexed)
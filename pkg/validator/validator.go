@@ -40,7 +40,7 @@ func ValidateURL(urlStr string) error {
 
 // ValidateMessageType validates a message type
 func ValidateMessageType(msgType string) error {
-	validTypes := []string{"text", "image", "video", "audio", "document", "location", "template"}
+	validTypes := []string{"text", "image", "video", "audio", "document", "sticker", "location", "location_request", "contacts", "template"}
 	for _, validType := range validTypes {
 		if msgType == validType {
 			return nil
@@ -49,6 +49,28 @@ func ValidateMessageType(msgType string) error {
 	return fmt.Errorf("invalid message type: %s (must be one of: %s)", msgType, strings.Join(validTypes, ", "))
 }
 
+// ValidateMediaType validates the type of a media message
+func ValidateMediaType(mediaType string) error {
+	validTypes := []string{"image", "video", "audio", "document", "sticker"}
+	for _, validType := range validTypes {
+		if mediaType == validType {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid media type: %s (must be one of: %s)", mediaType, strings.Join(validTypes, ", "))
+}
+
+// ValidateCoordinates validates a latitude and longitude in degrees
+func ValidateCoordinates(latitude, longitude float64) error {
+	if latitude < -90 || latitude > 90 {
+		return fmt.Errorf("invalid latitude: %v (must be between -90 and 90)", latitude)
+	}
+	if longitude < -180 || longitude > 180 {
+		return fmt.Errorf("invalid longitude: %v (must be between -180 and 180)", longitude)
+	}
+	return nil
+}
+
 // ValidateStatus validates a message status
 func ValidateStatus(status string) error {
 	validStatuses := []string{"queued", "sent", "delivered", "read", "failed"}